@@ -0,0 +1,184 @@
+// Command cachecheck verifies that cached search results stay isolated
+// between tenants: processed results are only served back to the tenant
+// they were built for, while raw provider data is shared by everyone.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/cache"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// isolationCase stores results and checks what each namespace reads back.
+type isolationCase struct {
+	name string
+	run  func(ctx context.Context, c cache.ResultCache) error
+}
+
+func main() {
+	redisAddr := flag.String("redis", "", "also check the Redis result cache at host:port, using -db")
+	db := flag.Int("db", 15, "Redis database to write check entries to")
+	flag.Parse()
+
+	ctx := context.Background()
+	ttl := time.Minute
+	targets := map[string]cache.ResultCache{"memory": cache.NewMemoryResultCache(ttl)}
+
+	var raw *cache.RedisCache
+	if *redisAddr != "" {
+		client := redis.NewClient(&redis.Options{Addr: *redisAddr, DB: *db})
+		if err := client.Ping(ctx).Err(); err != nil {
+			log.Fatalf("redis %s: %v", *redisAddr, err)
+		}
+		targets["redis"] = cache.NewRedisResultCache(client, ttl, "cachecheck", cache.GzipJSON)
+		host, port, err := net.SplitHostPort(*redisAddr)
+		if err != nil {
+			log.Fatalf("invalid -redis: %v", err)
+		}
+		if raw, err = cache.NewRedisCache(cache.RedisConfig{Host: host, Port: port, DB: *db, TTL: ttl, Region: "cachecheck"}); err != nil {
+			log.Fatal(err)
+		}
+		defer raw.Close()
+	}
+
+	total, failed := 0, 0
+	for _, target := range []string{"memory", "redis"} {
+		c, ok := targets[target]
+		if !ok {
+			continue
+		}
+		for _, tc := range isolationCases {
+			total++
+			if err := tc.run(ctx, c); err != nil {
+				failed++
+				fmt.Printf("FAIL %s/%s: %v\n", target, tc.name, err)
+			}
+		}
+	}
+	if raw != nil {
+		total++
+		if err := checkRawShared(ctx, raw); err != nil {
+			failed++
+			fmt.Printf("FAIL redis/raw-shared: %v\n", err)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("FAIL: %d of %d cases\n", failed, total)
+		os.Exit(1)
+	}
+	fmt.Printf("PASS: %d cases\n", total)
+}
+
+var isolationCases = []isolationCase{
+	{"tenant-not-served-to-other-tenant", func(ctx context.Context, c cache.ResultCache) error {
+		key := uniqueKey("other-tenant")
+		if err := c.Set(ctx, cache.TenantNamespace("acme"), key, flights("acme")); err != nil {
+			return err
+		}
+		if _, ok := c.Get(ctx, cache.TenantNamespace("globex"), key); ok {
+			return fmt.Errorf("globex read acme's results")
+		}
+		return expect(ctx, c, cache.TenantNamespace("acme"), key, "acme")
+	}},
+	{"tenant-and-anonymous-apart", func(ctx context.Context, c cache.ResultCache) error {
+		key := uniqueKey("anonymous")
+		if err := c.Set(ctx, cache.TenantNamespace("acme"), key, flights("acme")); err != nil {
+			return err
+		}
+		if _, ok := c.Get(ctx, cache.TenantNamespace(""), key); ok {
+			return fmt.Errorf("anonymous caller read acme's results")
+		}
+		if err := c.Set(ctx, cache.AnonymousNamespace, key, flights("anonymous")); err != nil {
+			return err
+		}
+		if err := expect(ctx, c, cache.TenantNamespace("acme"), key, "acme"); err != nil {
+			return err
+		}
+		return expect(ctx, c, cache.AnonymousNamespace, key, "anonymous")
+	}},
+	{"same-key-kept-per-tenant", func(ctx context.Context, c cache.ResultCache) error {
+		key := uniqueKey("same-key")
+		for _, tenant := range []string{"acme", "globex", "initech"} {
+			if err := c.Set(ctx, cache.TenantNamespace(tenant), key, flights(tenant)); err != nil {
+				return err
+			}
+		}
+		for _, tenant := range []string{"acme", "globex", "initech"} {
+			if err := expect(ctx, c, cache.TenantNamespace(tenant), key, tenant); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{"separators-in-tenant-names", func(ctx context.Context, c cache.ResultCache) error {
+		// Unescaped, tenant "acme:x" with key k and tenant "acme" with key
+		// "x:k" would share an entry.
+		key := uniqueKey("separators")
+		if err := c.Set(ctx, cache.TenantNamespace("acme:x"), key, flights("acme:x")); err != nil {
+			return err
+		}
+		if _, ok := c.Get(ctx, cache.TenantNamespace("acme"), "x:"+key); ok {
+			return fmt.Errorf(`tenant "acme" read tenant "acme:x"'s results`)
+		}
+		if _, ok := c.Get(ctx, cache.TenantNamespace("anonymous"), key); ok {
+			return fmt.Errorf(`tenant "anonymous" is the anonymous namespace`)
+		}
+		return nil
+	}},
+	{"hits-are-copies", func(ctx context.Context, c cache.ResultCache) error {
+		key := uniqueKey("copies")
+		if err := c.Set(ctx, cache.TenantNamespace("acme"), key, flights("acme")); err != nil {
+			return err
+		}
+		got, _ := c.Get(ctx, cache.TenantNamespace("acme"), key)
+		if len(got) > 0 {
+			got[0].ID = "changed"
+		}
+		return expect(ctx, c, cache.TenantNamespace("acme"), key, "acme")
+	}},
+}
+
+// checkRawShared checks that raw provider data has no namespace: an entry
+// written while serving one tenant answers every caller.
+func checkRawShared(ctx context.Context, raw *cache.RedisCache) error {
+	req := models.SearchRequest{Origin: "CGK", Destination: "DPS", DepartureDate: uniqueKey("raw"), Passengers: 1, CabinClass: "economy"}
+	if err := raw.Set(ctx, req, cache.Entry{Flights: flights("raw")}); err != nil {
+		return err
+	}
+	entry, ok := raw.Get(ctx, req)
+	if !ok || len(entry.Flights) != 1 || entry.Flights[0].ID != "raw" {
+		return fmt.Errorf("raw entry not shared")
+	}
+	return nil
+}
+
+func flights(id string) []models.Flight {
+	return []models.Flight{{ID: id, Provider: "cachecheck"}}
+}
+
+func expect(ctx context.Context, c cache.ResultCache, ns cache.Namespace, key, id string) error {
+	got, ok := c.Get(ctx, ns, key)
+	if !ok {
+		return fmt.Errorf("%s: results missing", ns)
+	}
+	if len(got) != 1 || got[0].ID != id {
+		return fmt.Errorf("%s: got %+v, want flight %s", ns, got, id)
+	}
+	return nil
+}
+
+// uniqueKey keeps runs against a shared Redis from reading each other's
+// entries.
+func uniqueKey(name string) string {
+	return cache.ResultKey(name, time.Now().Format(time.RFC3339Nano))
+}
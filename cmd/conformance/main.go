@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/conformance"
+)
+
+func main() {
+	target := flag.String("target", "http://localhost:8080", "base URL of the deployment under test")
+	suitePath := flag.String("suite", "", "suite of cases to run; the built-in suite when empty")
+	date := flag.String("date", "", "departure date searches use (YYYY-MM-DD); 30 days from today when empty")
+	adminToken := flag.String("admin-token", os.Getenv("ADMIN_TOKEN"), "admin token; admin cases are skipped without one")
+	apiKey := flag.String("api-key", "", "API key sent with every request")
+	run := flag.String("run", "", "only run cases whose name matches this regular expression")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout per request")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	departure := time.Now().AddDate(0, 0, 30)
+	if *date != "" {
+		d, err := time.Parse("2006-01-02", *date)
+		if err != nil {
+			log.Fatalf("invalid -date %q, expected YYYY-MM-DD", *date)
+		}
+		departure = d
+	}
+
+	cases, err := conformance.LoadSuite(*suitePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *run != "" {
+		pattern, err := regexp.Compile(*run)
+		if err != nil {
+			log.Fatalf("invalid -run: %v", err)
+		}
+		var selected []conformance.Case
+		for _, c := range cases {
+			if pattern.MatchString(c.Name) {
+				selected = append(selected, c)
+			}
+		}
+		cases = selected
+	}
+
+	report := conformance.Run(context.Background(), conformance.Target{
+		BaseURL:    *target,
+		Client:     &http.Client{Timeout: *timeout},
+		Date:       departure,
+		AdminToken: *adminToken,
+		APIKey:     *apiKey,
+	}, cases)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		printReport(report)
+	}
+
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
+
+func printReport(r conformance.Report) {
+	for _, res := range r.Results {
+		switch {
+		case res.Skipped != "":
+			fmt.Printf("SKIP %s: %s\n", res.Case, res.Skipped)
+		case len(res.Failures) > 0:
+			fmt.Printf("FAIL %s\n", res.Case)
+			for _, f := range res.Failures {
+				fmt.Printf("  %s\n", f)
+			}
+		default:
+			fmt.Printf("PASS %s\n", res.Case)
+		}
+	}
+	status := "PASS"
+	if !r.OK() {
+		status = "FAIL"
+	}
+	fmt.Printf("%s: %s, %d passed, %d failed, %d skipped\n", status, r.Target, r.Passed, r.Failed, r.Skipped)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/providercheck"
+	"github.com/dharmasatrya/flightsearch/internal/providers"
+	"github.com/dharmasatrya/flightsearch/internal/vcr"
+)
+
+func main() {
+	provider := flag.String("provider", "", "provider to check (garuda, lionair, batikair, airasia, citilink, superairjet, amadeus, sabre); all when empty")
+	routes := flag.String("routes", "CGK-DPS,DPS-CGK,CGK-SUB", "comma-separated ORIGIN-DESTINATION pairs")
+	dates := flag.String("dates", "2025-12-15,2025-12-16", "comma-separated departure dates (YYYY-MM-DD)")
+	cabins := flag.String("cabins", "economy,business", "comma-separated cabin classes")
+	attempts := flag.Int("attempts", 3, "attempts per case before a provider error is reported")
+	timeout := flag.Duration("timeout", 2*time.Second, "timeout per provider call")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	seed := flag.Int64("seed", 1, "seed for the mock providers' latency and failures")
+	record := flag.String("record", "", "directory to record the providers' responses to")
+	replay := flag.String("replay", "", "directory of recorded responses to check instead of calling the providers")
+	golden := flag.String("golden", "internal/providercheck/testdata/golden", "directory of approved normalized output; empty skips the comparison")
+	update := flag.Bool("update", false, "approve the current output as the golden snapshot")
+	flag.Parse()
+	if *record != "" && *replay != "" {
+		log.Fatal("-record and -replay are mutually exclusive")
+	}
+	if *update && *golden == "" {
+		log.Fatal("-update needs a -golden directory")
+	}
+
+	matrix := providercheck.Matrix{
+		Dates:    splitList(*dates),
+		Cabins:   splitList(*cabins),
+		Timeout:  *timeout,
+		Attempts: *attempts,
+		Contract: *replay == "",
+	}
+	for _, r := range splitList(*routes) {
+		origin, destination, ok := strings.Cut(strings.ToUpper(r), "-")
+		if !ok {
+			log.Fatalf("invalid route %q, expected ORIGIN-DESTINATION", r)
+		}
+		matrix.Routes = append(matrix.Routes, [2]string{origin, destination})
+	}
+
+	adapters, err := loadProviders(*provider, *seed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i, p := range adapters {
+		switch {
+		case *record != "":
+			adapters[i] = vcr.Wrap(p, vcr.ModeRecord, *record)
+		case *replay != "":
+			adapters[i] = vcr.Wrap(p, vcr.ModeReplay, *replay)
+		}
+	}
+
+	passed := true
+	var reports []providercheck.Report
+	for _, p := range adapters {
+		report := providercheck.Run(context.Background(), p, matrix)
+		if *golden != "" {
+			if err := compareGolden(&report, *golden, *update); err != nil {
+				log.Fatal(err)
+			}
+		}
+		reports = append(reports, report)
+		passed = passed && report.Passed()
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		for _, r := range reports {
+			printReport(r)
+		}
+	}
+
+	if !passed {
+		os.Exit(1)
+	}
+}
+
+// compareGolden records report's drift from its provider's snapshot, or
+// with update merges report's output into the snapshot instead.
+func compareGolden(report *providercheck.Report, dir string, update bool) error {
+	path := providercheck.GoldenPath(dir, report.Provider)
+	snapshot, err := providercheck.LoadSnapshot(path)
+	if err != nil {
+		return err
+	}
+	if !update {
+		report.Drift = providercheck.Drift(snapshot, *report)
+		return nil
+	}
+	if report.Errors > 0 {
+		return fmt.Errorf("%s: not updating %s, %d cases failed", report.Provider, path, report.Errors)
+	}
+	maps.Copy(snapshot, providercheck.SnapshotOf(*report))
+	if err := snapshot.Write(path); err != nil {
+		return err
+	}
+	log.Printf("%s: approved %d cases in %s", report.Provider, len(report.Cases), path)
+	return nil
+}
+
+func loadProviders(name string, seed int64) ([]providers.Provider, error) {
+	constructors := []func() (providers.Provider, error){
+		func() (providers.Provider, error) {
+			return providers.NewGarudaProvider(providers.NewRand(providers.ModeMock, seed, "garuda"))
+		},
+		func() (providers.Provider, error) {
+			return providers.NewLionAirProvider(providers.NewRand(providers.ModeMock, seed, "lionair"))
+		},
+		func() (providers.Provider, error) {
+			return providers.NewBatikAirProvider(providers.NewRand(providers.ModeMock, seed, "batikair"))
+		},
+		func() (providers.Provider, error) {
+			return providers.NewAirAsiaProvider(providers.NewRand(providers.ModeMock, seed, "airasia"))
+		},
+		func() (providers.Provider, error) {
+			return providers.NewCitilinkProvider(providers.NewRand(providers.ModeMock, seed, "citilink"))
+		},
+		func() (providers.Provider, error) {
+			return providers.NewSuperAirJetProvider(providers.NewRand(providers.ModeMock, seed, "superairjet"))
+		},
+	}
+	// Amadeus and Sabre are real APIs, checked only when credentials are
+	// given.
+	if id := os.Getenv("AMADEUS_CLIENT_ID"); id != "" {
+		constructors = append(constructors, func() (providers.Provider, error) {
+			return providers.NewAmadeusProvider(providers.AmadeusConfig{
+				ClientID:     id,
+				ClientSecret: os.Getenv("AMADEUS_CLIENT_SECRET"),
+				BaseURL:      os.Getenv("AMADEUS_BASE_URL"),
+			})
+		})
+	}
+	if id := os.Getenv("SABRE_USER_ID"); id != "" {
+		constructors = append(constructors, func() (providers.Provider, error) {
+			return providers.NewSabreProvider(providers.SabreConfig{
+				UserID:   id,
+				Password: os.Getenv("SABRE_PASSWORD"),
+				PCC:      os.Getenv("SABRE_PCC"),
+				Domain:   os.Getenv("SABRE_DOMAIN"),
+				BaseURL:  os.Getenv("SABRE_BASE_URL"),
+			})
+		})
+	}
+
+	var result []providers.Provider
+	for _, newProvider := range constructors {
+		p, err := newProvider()
+		if err != nil {
+			return nil, err
+		}
+		if name == "" || strings.EqualFold(p.Name(), name) {
+			result = append(result, p)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("unknown provider %q", name)
+	}
+	return result, nil
+}
+
+func printReport(r providercheck.Report) {
+	status := "PASS"
+	if !r.Passed() {
+		status = "FAIL"
+	}
+	fmt.Printf("%s %s: %d cases, %d flights checked, %d violations, %d errors, %d contract, %d drift\n",
+		status, r.Provider, len(r.Cases), r.Flights, r.Violations, r.Errors, len(r.Contract), len(r.Drift))
+
+	for _, v := range r.Contract {
+		fmt.Printf("  contract: %s: %s\n", v.Rule, v.Detail)
+	}
+	for _, v := range r.Drift {
+		fmt.Printf("  golden: %s: %s\n", strings.TrimSpace(v.Rule+" "+v.FlightID), v.Detail)
+	}
+
+	for _, c := range r.Cases {
+		label := fmt.Sprintf("%s-%s %s %s", c.Case.Origin, c.Case.Destination, c.Case.Date, c.Case.CabinClass)
+		if c.Error != "" {
+			fmt.Printf("  %s: error after %d attempts: %s\n", label, c.Attempts, c.Error)
+			continue
+		}
+		for _, v := range c.Violations {
+			fmt.Printf("  %s: [%s] %s %s: %s\n", label, v.Category, v.FlightID, v.Rule, v.Detail)
+		}
+	}
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/providers"
+	"github.com/dharmasatrya/flightsearch/internal/rankcheck"
+	"github.com/dharmasatrya/flightsearch/internal/ranking"
+)
+
+func main() {
+	corpusPath := flag.String("corpus", "internal/rankcheck/testdata/corpus.json", "corpus of searches to run")
+	goldenPath := flag.String("golden", "internal/rankcheck/testdata/golden.json", "approved rankings to compare against")
+	update := flag.Bool("update", false, "approve the current rankings by rewriting the golden file")
+	weights := flag.String("weights", "", "default ranking weights as price,duration,stops; the server defaults when empty")
+	tolerance := flag.Float64("tolerance", 0.01, "largest score change not reported as drift")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	seed := flag.Int64("seed", 1, "seed for the mock providers' latency and failures")
+	flag.Parse()
+
+	defaults := ranking.DefaultWeights()
+	if *weights != "" {
+		w, err := parseWeights(*weights)
+		if err == nil {
+			err = w.Validate()
+		}
+		if err != nil {
+			log.Fatalf("invalid -weights: %v", err)
+		}
+		defaults = ranking.WeightsFromModel(w)
+	}
+
+	cases, err := rankcheck.LoadCorpus(*corpusPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	adapters, err := loadProviders(*seed)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// AirAsia fails one call in ten; retry enough that a run is never
+	// partial in practice.
+	agg := aggregator.NewAggregator(adapters, aggregator.Config{
+		Timeout:        10 * time.Second,
+		MaxRetries:     5,
+		RetryBaseDelay: 10 * time.Millisecond,
+		RetryMaxDelay:  10 * time.Millisecond,
+	})
+	// The aggregator logs every provider failure.
+	log.SetOutput(io.Discard)
+	results := rankcheck.Run(context.Background(), agg, cases, defaults)
+	log.SetOutput(os.Stderr)
+
+	if *update {
+		golden, err := rankcheck.Approve(results)
+		if err != nil {
+			log.Fatalf("not updating %s: %v", *goldenPath, err)
+		}
+		if err := golden.Write(*goldenPath); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("approved %d cases in %s\n", len(golden), *goldenPath)
+		return
+	}
+
+	golden, err := rankcheck.LoadGolden(*goldenPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report := rankcheck.Compare(golden, results, *tolerance)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		printReport(report)
+	}
+
+	if !report.Passed() {
+		os.Exit(1)
+	}
+}
+
+// loadProviders runs the providers in mock mode, so a run's provider
+// failures repeat along with its rankings.
+func loadProviders(seed int64) ([]providers.Provider, error) {
+	garuda, err := providers.NewGarudaProvider(providers.NewRand(providers.ModeMock, seed, "garuda"))
+	if err != nil {
+		return nil, err
+	}
+	lionAir, err := providers.NewLionAirProvider(providers.NewRand(providers.ModeMock, seed, "lionair"))
+	if err != nil {
+		return nil, err
+	}
+	batikAir, err := providers.NewBatikAirProvider(providers.NewRand(providers.ModeMock, seed, "batikair"))
+	if err != nil {
+		return nil, err
+	}
+	airAsia, err := providers.NewAirAsiaProvider(providers.NewRand(providers.ModeMock, seed, "airasia"))
+	if err != nil {
+		return nil, err
+	}
+	citilink, err := providers.NewCitilinkProvider(providers.NewRand(providers.ModeMock, seed, "citilink"))
+	if err != nil {
+		return nil, err
+	}
+	superAirJet, err := providers.NewSuperAirJetProvider(providers.NewRand(providers.ModeMock, seed, "superairjet"))
+	if err != nil {
+		return nil, err
+	}
+	return []providers.Provider{garuda, lionAir, batikAir, airAsia, citilink, superAirJet}, nil
+}
+
+func parseWeights(s string) (models.RankingWeights, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return models.RankingWeights{}, fmt.Errorf("expected price,duration,stops, got %q", s)
+	}
+	var nums [3]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return models.RankingWeights{}, err
+		}
+		nums[i] = f
+	}
+	return models.RankingWeights{Price: nums[0], Duration: nums[1], Stops: nums[2]}, nil
+}
+
+func printReport(r rankcheck.Report) {
+	status := "PASS"
+	if !r.Passed() {
+		status = "FAIL"
+	}
+	fmt.Printf("%s: %d cases, %d drift findings\n", status, r.Cases, len(r.Drift))
+	for _, d := range r.Drift {
+		fmt.Printf("  %s: [%s] %s\n", d.Case, d.Kind, d.Detail)
+	}
+	for _, name := range r.Stale {
+		fmt.Printf("  %s: golden output has no corpus case\n", name)
+	}
+}
@@ -0,0 +1,203 @@
+// Command refimport validates OpenFlights or OurAirports dumps and shows
+// what importing them would change. With -target it uploads them to a
+// running deployment's admin API, and with -activate serves them.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/handler"
+	"github.com/dharmasatrya/flightsearch/internal/refdata"
+)
+
+// maxListed bounds the codes printed per change list.
+const maxListed = 20
+
+func main() {
+	airports := flag.String("airports", "", "OpenFlights airports.dat or OurAirports airports.csv")
+	airlines := flag.String("airlines", "", "OpenFlights airlines.dat")
+	target := flag.String("target", "", "base URL of the deployment to import into; only validates locally when empty")
+	adminToken := flag.String("admin-token", os.Getenv("ADMIN_TOKEN"), "admin token of the -target deployment")
+	activate := flag.Bool("activate", false, "activate the import on -target once it is staged")
+	asJSON := flag.Bool("json", false, "print the import as JSON")
+	timeout := flag.Duration("timeout", time.Minute, "timeout per request")
+	flag.Parse()
+	if *airports == "" && *airlines == "" {
+		log.Fatal("nothing to import, set -airports or -airlines")
+	}
+	if *activate && *target == "" {
+		log.Fatal("-activate needs a -target")
+	}
+
+	client := &adminClient{base: strings.TrimRight(*target, "/"), token: *adminToken, http: &http.Client{Timeout: *timeout}}
+	var imp handler.RefDataImport
+	var err error
+	if *target == "" {
+		imp, err = importLocal(*airports, *airlines)
+	} else {
+		err = client.do(http.MethodPost, "/admin/refdata/imports", func(w *multipart.Writer) error {
+			return attach(w, map[string]string{"airports": *airports, "airlines": *airlines})
+		}, &imp)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(imp); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		printImport(imp)
+	}
+
+	if *activate {
+		var active handler.RefDataSummary
+		if err := client.do(http.MethodPost, "/admin/refdata/imports/"+imp.Version+"/activate", nil, &active); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Activated %s: %d airports, %d airlines\n", active.Version, active.Airports, active.Airlines)
+	}
+}
+
+// importLocal imports against the built-in tables, as a deployment that
+// never imported would.
+func importLocal(airportsPath, airlinesPath string) (handler.RefDataImport, error) {
+	var readers [2]io.Reader
+	for i, path := range []string{airportsPath, airlinesPath} {
+		if path == "" {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return handler.RefDataImport{}, err
+		}
+		defer f.Close()
+		readers[i] = f
+	}
+
+	base := refdata.Builtin()
+	next, report, err := refdata.Import(base, readers[0], readers[1], time.Now().UTC())
+	if err != nil {
+		return handler.RefDataImport{}, err
+	}
+	return handler.RefDataImport{
+		RefDataSummary: handler.RefDataSummary{Version: next.Version, ImportedAt: next.ImportedAt, Airports: len(next.Airports), Airlines: len(next.Airlines)},
+		Active:         base.Version,
+		Changes:        refdata.Diff(base, next),
+		Report:         report,
+	}, nil
+}
+
+func printImport(imp handler.RefDataImport) {
+	fmt.Printf("Version %s (replacing %s): %d airports, %d airlines\n", imp.Version, imp.Active, imp.Airports, imp.Airlines)
+	if imp.Report.AirportFormat != "" {
+		fmt.Printf("Airports read as %s\n", imp.Report.AirportFormat)
+	}
+	for _, t := range []struct {
+		name    string
+		changes refdata.TableChanges
+	}{{"airports", imp.Changes.Airports}, {"airlines", imp.Changes.Airlines}} {
+		fmt.Printf("%s: %d added, %d removed, %d changed\n", t.name, len(t.changes.Added), len(t.changes.Removed), len(t.changes.Changed))
+		printCodes("added", t.changes.Added)
+		printCodes("removed", t.changes.Removed)
+		printCodes("changed", t.changes.Changed)
+	}
+	fmt.Printf("%d rows ignored, %d rejected\n", imp.Report.Ignored, len(imp.Report.Rejected))
+	for _, p := range imp.Report.Rejected {
+		fmt.Printf("  %s:%d %s %s\n", p.File, p.Line, p.Code, p.Reason)
+	}
+}
+
+func printCodes(label string, codes []string) {
+	if len(codes) == 0 {
+		return
+	}
+	more := ""
+	if len(codes) > maxListed {
+		more = fmt.Sprintf(" and %d more", len(codes)-maxListed)
+		codes = codes[:maxListed]
+	}
+	fmt.Printf("  %s: %s%s\n", label, strings.Join(codes, " "), more)
+}
+
+type adminClient struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+// do sends a multipart body built by form, if any, and decodes the
+// response into out.
+func (c *adminClient) do(method, path string, form func(*multipart.Writer) error, out any) error {
+	var body bytes.Buffer
+	contentType := ""
+	if form != nil {
+		w := multipart.NewWriter(&body)
+		if err := form(w); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		contentType = w.FormDataContentType()
+	}
+
+	req, err := http.NewRequest(method, c.base+path, &body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, out)
+}
+
+// attach adds the files by form field, skipping empty paths.
+func attach(w *multipart.Writer, files map[string]string) error {
+	for field, path := range files {
+		if path == "" {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		part, err := w.CreateFormFile(field, filepath.Base(path))
+		if err == nil {
+			_, err = io.Copy(part, f)
+		}
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,83 +1,898 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
 	"log"
+	"net/http"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/alerts"
+	"github.com/dharmasatrya/flightsearch/internal/analytics"
+	"github.com/dharmasatrya/flightsearch/internal/audit"
+	"github.com/dharmasatrya/flightsearch/internal/brownout"
+	"github.com/dharmasatrya/flightsearch/internal/bus"
 	"github.com/dharmasatrya/flightsearch/internal/cache"
+	"github.com/dharmasatrya/flightsearch/internal/configsnap"
+	"github.com/dharmasatrya/flightsearch/internal/controls"
+	"github.com/dharmasatrya/flightsearch/internal/crosssell"
+	"github.com/dharmasatrya/flightsearch/internal/deeplink"
+	"github.com/dharmasatrya/flightsearch/internal/demo"
+	"github.com/dharmasatrya/flightsearch/internal/farelock"
+	"github.com/dharmasatrya/flightsearch/internal/faults"
+	"github.com/dharmasatrya/flightsearch/internal/fields"
+	"github.com/dharmasatrya/flightsearch/internal/groundtransport"
 	"github.com/dharmasatrya/flightsearch/internal/handler"
+	"github.com/dharmasatrya/flightsearch/internal/health"
+	"github.com/dharmasatrya/flightsearch/internal/idempotency"
+	"github.com/dharmasatrya/flightsearch/internal/leader"
+	"github.com/dharmasatrya/flightsearch/internal/markup"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/notify"
+	"github.com/dharmasatrya/flightsearch/internal/offsets"
+	"github.com/dharmasatrya/flightsearch/internal/ontime"
+	"github.com/dharmasatrya/flightsearch/internal/pricehistory"
+	"github.com/dharmasatrya/flightsearch/internal/promotions"
 	"github.com/dharmasatrya/flightsearch/internal/providers"
+	"github.com/dharmasatrya/flightsearch/internal/ranking"
 	"github.com/dharmasatrya/flightsearch/internal/ratelimit"
+	"github.com/dharmasatrya/flightsearch/internal/recording"
+	"github.com/dharmasatrya/flightsearch/internal/refdata"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/internal/retention"
+	"github.com/dharmasatrya/flightsearch/internal/scheduler"
+	"github.com/dharmasatrya/flightsearch/internal/snapshot"
+	"github.com/dharmasatrya/flightsearch/internal/subscriptions"
+	"github.com/dharmasatrya/flightsearch/internal/telemetry"
+	"github.com/dharmasatrya/flightsearch/internal/vcr"
+)
+
+const (
+	DeploymentSingle    = "single"
+	DeploymentClustered = "clustered"
+)
+
+const (
+	providerTimeout = 2 * time.Second
+	maxRetries      = 3
+	// writeGrace is how long a response may take to write once the
+	// request's time is up.
+	writeGrace = 5 * time.Second
+)
+
+// Inbound limits of a public demo unless INBOUND_RATE_LIMIT and
+// INBOUND_BURST say otherwise.
+const (
+	demoRateLimit = 0.5
+	demoBurst     = 5
+)
+
+var providerLimits = map[string]configsnap.RateLimit{
+	"garuda":      {RequestsPerSecond: 20, BurstSize: 30},
+	"lionair":     {RequestsPerSecond: 15, BurstSize: 25},
+	"batikair":    {RequestsPerSecond: 15, BurstSize: 25},
+	"airasia":     {RequestsPerSecond: 10, BurstSize: 20},
+	"citilink":    {RequestsPerSecond: 15, BurstSize: 25},
+	"superairjet": {RequestsPerSecond: 10, BurstSize: 20},
+}
+
+// amadeusLimit is the Self-Service test environment's quota of 10
+// transactions per second, one of which may be a token request.
+var amadeusLimit = configsnap.RateLimit{RequestsPerSecond: 9, BurstSize: 9}
+
+// sabreLimit stays well under Bargain Finder Max's per-PCC throttle, which
+// search volume rather than users pushes against.
+var sabreLimit = configsnap.RateLimit{RequestsPerSecond: 5, BurstSize: 5}
+
+// Endpoints that release route counts, as named in ANALYTICS_PRIVACY.
+const (
+	privacyRoutes        = "routes"
+	privacyAdminOverview = "admin_overview"
 )
 
 type Config struct {
-	Port         string
-	CacheEnabled bool
-	RedisHost    string
-	RedisPort    string
-	RedisTTL     time.Duration
+	Port           string
+	DeploymentMode string
+	Region         string
+	CacheEnabled   bool
+	Redis          cache.RedisConfig
+	CacheReplicas  map[string]string
+	ProviderMaxAge cache.Freshness
+	AdminToken     string
+	APIKeys        map[string]reqctx.Key
+	FeatureFlags   reqctx.Flags
+	FieldPolicies  map[string]fields.Policy
+	// Demo serves the fixtures with synthetic prices, marked as such, for a
+	// public playground.
+	Demo bool
+
+	ProviderEndpoints providers.Endpoints
+	ProviderMode      string
+	ProviderSeed      int
+	ProviderVCR       string
+	ProviderVCRDir    string
+	// Amadeus and Sabre are searched as well as the airline fixtures when
+	// their credentials are set.
+	Amadeus providers.AmadeusConfig
+	Sabre   providers.SabreConfig
+	// ProviderFallbacks are searched when a provider's quota is spent or its
+	// circuit is open; StandbyProviders are only searched that way.
+	ProviderFallbacks map[string][]aggregator.Fallback
+	StandbyProviders  []string
+	// BookingURLTemplates override or add providers' booking URL templates;
+	// an empty one turns a provider's links off. AffiliateCodes fill in
+	// their {affiliate} placeholder.
+	BookingURLTemplates map[string]string
+	AffiliateCodes      map[string]string
+	// Promotions discount displayed fares; nil without PROMOTIONS_FILE.
+	Promotions *promotions.Engine
+	// Markups price flights for white-label tenants.
+	Markups map[string]markup.Policy
+	// OnTime rates flights' reliability: the built-in dataset, or the one
+	// in ONTIME_DATA_FILE.
+	OnTime ontime.Source
+
+	AnalyticsPrivacy map[string]analytics.PrivacyPolicy
+	// SearchEvents records every search for the internal dashboards, kept
+	// for SearchEventsRetention.
+	SearchEvents          bool
+	SearchEventsRetention time.Duration
+	// AuditSink, when set, receives a record of every search: appended to
+	// AuditFile, or posted to AuditURL, an HTTP endpoint or Kafka REST
+	// Proxy.
+	AuditSink       string
+	AuditFile       string
+	AuditURL        string
+	AuditToken      string
+	AuditKafkaTopic string
+	Audit           audit.Config
+	// EventBus, when set, receives a search.completed event after every
+	// aggregator run, on EventBusSubject (a NATS subject or Kafka topic).
+	EventBus           string
+	EventBusURL        string
+	EventBusSubject    string
+	EventBusToken      string
+	EventBusBufferSize int
+
+	SearchPublicMaxAge time.Duration
+	SearchSnapshotTTL  time.Duration
+	ResultCacheTTL     time.Duration
+	RecordingTTL       time.Duration
+	IdempotencyTTL     time.Duration
+	LeaderLeaseTTL     time.Duration
+	JobSchedules       map[string]string
+
+	RankingWeights models.RankingWeights
+	ProviderCap    int
+	ResultMerge    string
+
+	RetryBudget        int
+	RetryBaseDelay     time.Duration
+	RetryMaxDelay      time.Duration
+	ProviderRetryRatio float64
+	MaxProviderCalls   int
+
+	MinProvidersSucceeded int
+	FailEmptyPartial      bool
+	PartialResultsStatus  int
+
+	BrownoutEnabled     bool
+	BrownoutThresholds  []float64
+	BrownoutCooldown    time.Duration
+	BrownoutMaxSearches int
+	BrownoutTier1       []string
+
+	FaultInjection bool
+
+	OTLPMetricsEndpoint   string
+	MetricsExportInterval time.Duration
+
+	RankingModelURL     string
+	RankingModelTimeout time.Duration
+	RankingFeatureLog   string
+
+	InboundRateLimit        float64
+	InboundBurst            int
+	ProviderPriorityReserve float64
+
+	RequestTimeout    time.Duration
+	MaxBodyBytes      int
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	IdleTimeout       time.Duration
+
+	GroundTransportTTL time.Duration
+	CrossSellTTL       time.Duration
+
+	OutageWebhookURL     string
+	OutageNotifyInterval time.Duration
+	CircuitOpenDuration  time.Duration
+
+	PriceHistoryTTL time.Duration
+
+	FareLockMaxHold time.Duration
+
+	AlertCheckInterval time.Duration
+	OffsetPricePerTon  float64
+	SMTP               alerts.SMTPConfig
+
+	SubscriptionRefreshInterval time.Duration
+
+	ArchiveDir        string
+	RetentionInterval time.Duration
+	SearchRetention   retention.Policy
+	BookingRetention  retention.Policy
+
+	RefDataPollInterval  time.Duration
+	ControlsPollInterval time.Duration
 }
 
 func main() {
 	cfg := loadConfig()
+	if err := cfg.RankingWeights.Validate(); err != nil {
+		log.Fatalf("Invalid RANKING_WEIGHTS: %v", err)
+	}
+	switch cfg.DeploymentMode {
+	case DeploymentSingle:
+	case DeploymentClustered:
+		if !cfg.CacheEnabled {
+			log.Fatalf("DEPLOYMENT_MODE=%s needs Redis, set CACHE_ENABLED=true", DeploymentClustered)
+		}
+		if cfg.LeaderLeaseTTL < time.Second {
+			log.Fatalf("LEADER_LEASE_TTL must be at least 1s, got %v", cfg.LeaderLeaseTTL)
+		}
+		if cfg.RefDataPollInterval <= 0 {
+			log.Fatalf("REFDATA_POLL_INTERVAL must be positive, got %v", cfg.RefDataPollInterval)
+		}
+		if cfg.ControlsPollInterval <= 0 {
+			log.Fatalf("CONTROLS_POLL_INTERVAL must be positive, got %v", cfg.ControlsPollInterval)
+		}
+	default:
+		log.Fatalf("Invalid DEPLOYMENT_MODE %q (want %s or %s)", cfg.DeploymentMode, DeploymentSingle, DeploymentClustered)
+	}
+	if cfg.ProviderRetryRatio < 0 {
+		log.Fatalf("PROVIDER_RETRY_RATIO must not be negative, got %v", cfg.ProviderRetryRatio)
+	}
+	if cfg.MaxProviderCalls < 0 {
+		log.Fatalf("MAX_PROVIDER_CALLS must not be negative, got %d", cfg.MaxProviderCalls)
+	}
+	if cfg.MinProvidersSucceeded < 1 {
+		log.Fatalf("MIN_PROVIDERS_SUCCEEDED must be at least 1, got %d", cfg.MinProvidersSucceeded)
+	}
+	if cfg.PartialResultsStatus != http.StatusOK && cfg.PartialResultsStatus != http.StatusPartialContent {
+		log.Fatalf("PARTIAL_RESULTS_STATUS must be %d or %d, got %d", http.StatusOK, http.StatusPartialContent, cfg.PartialResultsStatus)
+	}
+	if cfg.RequestTimeout <= 0 || cfg.ReadHeaderTimeout <= 0 || cfg.ReadTimeout <= 0 || cfg.IdleTimeout <= 0 {
+		log.Fatalf("REQUEST_TIMEOUT, READ_HEADER_TIMEOUT, READ_TIMEOUT and IDLE_TIMEOUT must be positive")
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		log.Fatalf("MAX_BODY_BYTES must be positive, got %d", cfg.MaxBodyBytes)
+	}
+	if cfg.RequestTimeout < 2*providerTimeout {
+		log.Printf("REQUEST_TIMEOUT %v is shorter than round trip searches take at most (%v); they answer with the providers that replied in time", cfg.RequestTimeout, 2*providerTimeout)
+	}
+	if cfg.BrownoutEnabled && cfg.BrownoutMaxSearches <= 0 {
+		log.Fatalf("BROWNOUT_MAX_SEARCHES must be positive, got %d", cfg.BrownoutMaxSearches)
+	}
+	if cfg.OTLPMetricsEndpoint != "" && cfg.MetricsExportInterval <= 0 {
+		log.Fatalf("METRICS_EXPORT_INTERVAL must be positive, got %v", cfg.MetricsExportInterval)
+	}
+	if cfg.IdempotencyTTL <= 0 {
+		log.Fatalf("IDEMPOTENCY_TTL must be positive, got %v", cfg.IdempotencyTTL)
+	}
+	if cfg.AuditSink != "" {
+		if !slices.Contains(audit.Sinks, cfg.AuditSink) {
+			log.Fatalf("Invalid AUDIT_SINK %q (want one of %s)", cfg.AuditSink, strings.Join(audit.Sinks, ", "))
+		}
+		if cfg.AuditSink != audit.SinkFile && cfg.AuditURL == "" {
+			log.Fatalf("AUDIT_SINK=%s needs AUDIT_URL", cfg.AuditSink)
+		}
+		if cfg.Audit.BufferSize < 1 || cfg.Audit.BatchSize < 1 || cfg.Audit.FlushInterval <= 0 {
+			log.Fatalf("AUDIT_BUFFER_SIZE, AUDIT_BATCH_SIZE and AUDIT_FLUSH_INTERVAL must be positive")
+		}
+	}
+	if cfg.EventBus != "" {
+		if !slices.Contains(bus.Buses, cfg.EventBus) {
+			log.Fatalf("Invalid EVENT_BUS %q (want one of %s)", cfg.EventBus, strings.Join(bus.Buses, ", "))
+		}
+		if cfg.EventBusURL == "" {
+			log.Fatalf("EVENT_BUS=%s needs EVENT_BUS_URL", cfg.EventBus)
+		}
+		if cfg.EventBusBufferSize < 1 {
+			log.Fatalf("EVENT_BUS_BUFFER_SIZE must be positive, got %d", cfg.EventBusBufferSize)
+		}
+	}
+	if cfg.ProviderCap < 0 {
+		log.Fatalf("PROVIDER_RESULT_CAP must not be negative, got %d", cfg.ProviderCap)
+	}
+	if !slices.Contains(providers.Modes, cfg.ProviderMode) {
+		log.Fatalf("Invalid PROVIDER_MODE %q (want one of %s)", cfg.ProviderMode, strings.Join(providers.Modes, ", "))
+	}
+	if !slices.Contains(vcr.Modes, cfg.ProviderVCR) {
+		log.Fatalf("Invalid PROVIDER_VCR %q (want one of %s)", cfg.ProviderVCR, strings.Join(vcr.Modes, ", "))
+	}
+	if !slices.Contains(aggregator.MergeStrategies, cfg.ResultMerge) {
+		log.Fatalf("Invalid RESULT_MERGE %q (want one of %s)", cfg.ResultMerge, strings.Join(aggregator.MergeStrategies, ", "))
+	}
+	if cfg.Demo {
+		if cfg.InboundRateLimit <= 0 {
+			log.Fatalf("DEMO_MODE must be rate limited, INBOUND_RATE_LIMIT must be positive")
+		}
+		if cfg.ProviderVCR != vcr.ModeOff {
+			log.Fatalf("DEMO_MODE serves fixture data only, unset PROVIDER_VCR")
+		}
+		if len(cfg.ProviderEndpoints) > 0 {
+			log.Fatalf("DEMO_MODE serves fixture data only, unset PROVIDER_ENDPOINTS")
+		}
+		if cfg.Amadeus.ClientID != "" {
+			log.Fatalf("DEMO_MODE serves fixture data only, unset AMADEUS_CLIENT_ID")
+		}
+		if cfg.Sabre.UserID != "" {
+			log.Fatalf("DEMO_MODE serves fixture data only, unset SABRE_USER_ID")
+		}
+	}
+	if (cfg.Amadeus.ClientID == "") != (cfg.Amadeus.ClientSecret == "") {
+		log.Fatalf("AMADEUS_CLIENT_ID and AMADEUS_CLIENT_SECRET must be set together")
+	}
+	if cfg.Sabre.UserID != "" || cfg.Sabre.Password != "" || cfg.Sabre.PCC != "" {
+		if cfg.Sabre.UserID == "" || cfg.Sabre.Password == "" || cfg.Sabre.PCC == "" {
+			log.Fatalf("SABRE_USER_ID, SABRE_PASSWORD and SABRE_PCC must be set together")
+		}
+	}
+	if len(cfg.CacheReplicas) > 0 {
+		if !cfg.CacheEnabled {
+			log.Fatalf("CACHE_REPLICAS needs Redis, set CACHE_ENABLED=true")
+		}
+		if cfg.Region == "" {
+			log.Fatalf("CACHE_REPLICAS needs REGION, so replicated entries can be told apart")
+		}
+		if _, ok := cfg.CacheReplicas[cfg.Region]; ok {
+			log.Fatalf("Invalid CACHE_REPLICAS: lists this deployment's own region %q", cfg.Region)
+		}
+	}
 	e := echo.New()
+	e.JSONSerializer = handler.JSONSerializer{}
+	// Slow clients get as long as the limits below: a response can take
+	// reading the body, the request timeout and writeGrace to write.
+	// Event streams set their own deadlines.
+	e.Server.ReadHeaderTimeout = cfg.ReadHeaderTimeout
+	e.Server.ReadTimeout = cfg.ReadTimeout
+	e.Server.WriteTimeout = cfg.ReadTimeout + cfg.RequestTimeout + writeGrace
+	e.Server.IdleTimeout = cfg.IdleTimeout
+
+	var metrics *telemetry.Telemetry
+	if cfg.OTLPMetricsEndpoint != "" {
+		m, err := telemetry.New(context.Background(), telemetry.Config{
+			Endpoint: cfg.OTLPMetricsEndpoint,
+			Interval: cfg.MetricsExportInterval,
+			Service:  "flightsearch",
+			Region:   cfg.Region,
+		})
+		if err != nil {
+			log.Fatalf("Invalid OTLP_METRICS_ENDPOINT: %v", err)
+		}
+		metrics = m
+		httpMetrics, err := metrics.HTTPServer()
+		if err != nil {
+			log.Fatalf("Failed to create HTTP server metrics: %v", err)
+		}
+		e.Use(httpMetrics.Middleware())
+		e.Server.ConnState = httpMetrics.ConnState
+		log.Printf("Exporting metrics to %s every %v", cfg.OTLPMetricsEndpoint, cfg.MetricsExportInterval)
+	}
 
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORS())
+	e.Use(middleware.GzipWithConfig(middleware.GzipConfig{
+		MinLength: 1024,
+		// Event streams are flushed per event; compressing them only adds latency.
+		Skipper: func(c echo.Context) bool {
+			return strings.HasSuffix(c.Path(), "/stream")
+		},
+	}))
 	e.Use(middleware.RequestID())
+	e.Use(handler.RequestTimeout(cfg.RequestTimeout))
+	e.Use(handler.BodyLimit(int64(cfg.MaxBodyBytes)))
+	if cfg.Demo {
+		e.Use(handler.DemoWatermark())
+	}
+	e.Use(handler.RequestContext(cfg.APIKeys, cfg.FeatureFlags))
+	e.Use(handler.ResponseFields(cfg.FieldPolicies))
 
-	providerList, err := initializeProviders()
+	providerList, err := initializeProviders(cfg.ProviderMode, int64(cfg.ProviderSeed))
 	if err != nil {
 		log.Fatalf("Failed to initialize providers: %v", err)
 	}
-	log.Printf("Initialized %d flight providers", len(providerList))
-
-	rateLimiter := ratelimit.NewProviderLimiterWithDefaults()
-	rateLimiter.SetProviderLimit("garuda", 20, 30)
-	rateLimiter.SetProviderLimit("lionair", 15, 25)
-	rateLimiter.SetProviderLimit("batikair", 15, 25)
-	rateLimiter.SetProviderLimit("airasia", 10, 20)
-
-	aggConfig := aggregator.Config{
-		Timeout:    2 * time.Second,
-		MaxRetries: 3,
-		RetryDelays: []time.Duration{
-			100 * time.Millisecond,
-			200 * time.Millisecond,
-			400 * time.Millisecond,
-		},
-		RateLimiter: rateLimiter,
+	if cfg.Amadeus.ClientID != "" {
+		amadeus, err := providers.NewAmadeusProvider(cfg.Amadeus)
+		if err != nil {
+			log.Fatalf("Failed to initialize providers: %v", err)
+		}
+		providerList = append(providerList, amadeus)
+		providerLimits[amadeus.Name()] = amadeusLimit
+		log.Printf("Amadeus enabled (%s)", cfg.Amadeus.BaseURL)
+	}
+	if cfg.Sabre.UserID != "" {
+		sabre, err := providers.NewSabreProvider(cfg.Sabre)
+		if err != nil {
+			log.Fatalf("Failed to initialize providers: %v", err)
+		}
+		providerList = append(providerList, sabre)
+		providerLimits[sabre.Name()] = sabreLimit
+		log.Printf("Sabre enabled (%s, PCC %s)", cfg.Sabre.BaseURL, cfg.Sabre.PCC)
+	}
+	log.Printf("Initialized %d flight providers (%s mode)", len(providerList), cfg.ProviderMode)
+	if cfg.Region != "" {
+		applied := cfg.ProviderEndpoints.Apply(cfg.Region, providerList)
+		log.Printf("Region %s: regional endpoints for %d provider(s) %v", cfg.Region, len(applied), applied)
+	}
+	if cfg.ProviderVCR != vcr.ModeOff {
+		for i, p := range providerList {
+			providerList[i] = vcr.Wrap(p, cfg.ProviderVCR, cfg.ProviderVCRDir)
+		}
+		log.Printf("Provider responses: %s (%s)", cfg.ProviderVCR, cfg.ProviderVCRDir)
+	}
+	if cfg.Demo {
+		for i, p := range providerList {
+			providerList[i] = demo.Wrap(p, int64(cfg.ProviderSeed))
+		}
+		log.Printf("Demo mode: synthetic prices (seed %d)", cfg.ProviderSeed)
 	}
-	agg := aggregator.NewAggregator(providerList, aggConfig)
 
-	var flightCache cache.Cache
+	var baseCache cache.Cache
+	var redisClient redis.UniversalClient
 	if cfg.CacheEnabled {
-		redisCache, err := cache.NewRedisCache(cache.RedisConfig{
-			Host: cfg.RedisHost,
-			Port: cfg.RedisPort,
-			TTL:  cfg.RedisTTL,
-		})
+		redisCfg := cfg.Redis
+		redisCfg.Region = cfg.Region
+		redisCache, err := cache.NewRedisCache(redisCfg)
 		if err != nil {
 			log.Fatalf("Failed to connect to Redis: %v", err)
 		}
-		flightCache = redisCache
-		log.Printf("Redis cache enabled (host: %s:%s, TTL: %v)", cfg.RedisHost, cfg.RedisPort, cfg.RedisTTL)
+		for region, addr := range cfg.CacheReplicas {
+			replica := replicaConfig(cfg.Redis, addr).Client()
+			redisCache.AddReplica(region, replica)
+			observeRedisPool(metrics, region, replica)
+			log.Printf("Replicating cache entries to region %s (%s)", region, addr)
+		}
+		baseCache = redisCache
+		redisClient = redisCache.Client()
+		observeRedisPool(metrics, "primary", redisClient)
+		log.Printf("Redis cache enabled (%s %s, TTL: %v, negative TTL: %v, %s encoding, schema %s)", cfg.Redis.Topology(), redisAddrs(cfg.Redis), cfg.Redis.TTL, cfg.Redis.NegativeTTL, cfg.Redis.Encoding, cache.Schema)
 	} else {
-		flightCache = cache.NewNoOpCache()
+		baseCache = cache.NewNoOpCache()
 		log.Println("Cache disabled")
 	}
+	flightCache := cache.NewInstrumentedCache(baseCache)
+	searchCounter := analytics.NewSearchCounter()
+
+	// Clustered replicas share rate limits, circuit state and subscription
+	// diffs through Redis; a single node keeps them in process.
+	clustered := cfg.DeploymentMode == DeploymentClustered
+	log.Printf("Deployment mode: %s", cfg.DeploymentMode)
+
+	if cfg.ProviderPriorityReserve < 0 || cfg.ProviderPriorityReserve >= 1 {
+		log.Fatalf("PROVIDER_PRIORITY_RESERVE must be in [0, 1), got %v", cfg.ProviderPriorityReserve)
+	}
+	limitCfg := ratelimit.DefaultConfig()
+	limitCfg.PriorityReserve = cfg.ProviderPriorityReserve
+	var rateLimiter ratelimit.Limiter
+	if clustered {
+		rateLimiter = ratelimit.NewRedisLimiter(redisClient, limitCfg)
+	} else {
+		rateLimiter = ratelimit.NewProviderLimiter(limitCfg)
+	}
+	for name, l := range providerLimits {
+		rateLimiter.SetProviderLimit(name, l.RequestsPerSecond, l.BurstSize)
+	}
+
+	if cfg.InboundRateLimit > 0 {
+		if cfg.InboundBurst < 1 {
+			log.Fatalf("INBOUND_BURST must be at least 1, got %d", cfg.InboundBurst)
+		}
+		inboundCfg := ratelimit.RateLimitConfig{RequestsPerSecond: cfg.InboundRateLimit, BurstSize: cfg.InboundBurst}
+		var clientLimiter ratelimit.ClientLimiter
+		if clustered {
+			clientLimiter = ratelimit.NewRedisClientLimiter(redisClient, inboundCfg)
+		} else {
+			clientLimiter = ratelimit.NewMemoryClientLimiter(inboundCfg)
+		}
+		e.Use(handler.InboundRateLimit(clientLimiter))
+		log.Printf("Inbound rate limit: %.1f req/s per client, burst %d", cfg.InboundRateLimit, cfg.InboundBurst)
+	}
+
+	healthCfg := health.DefaultConfig()
+	healthCfg.OpenDuration = cfg.CircuitOpenDuration
+	var healthTracker *health.Tracker
+	if clustered {
+		stateTTL := healthCfg.Window + healthCfg.OpenDuration
+		healthTracker = health.NewTrackerWithStore(healthCfg, health.NewRedisStateStore(redisClient, stateTTL))
+	} else {
+		healthTracker = health.NewTracker(healthCfg)
+	}
+	var outageNotifier *notify.OutageNotifier
+	if cfg.OutageWebhookURL != "" {
+		outageNotifier = notify.NewOutageNotifier(cfg.OutageWebhookURL, cfg.OutageNotifyInterval)
+		healthTracker.Subscribe(outageNotifier.Handle)
+		log.Println("Provider outage notifications enabled")
+	}
+
+	aggConfig := aggregator.Config{
+		Timeout:            providerTimeout,
+		MaxRetries:         maxRetries,
+		RetryBaseDelay:     cfg.RetryBaseDelay,
+		RetryMaxDelay:      cfg.RetryMaxDelay,
+		ProviderRetryRatio: cfg.ProviderRetryRatio,
+		RetryBudget:        cfg.RetryBudget,
+		RateLimiter:        rateLimiter,
+		Health:             healthTracker,
+		ProviderCap:        cfg.ProviderCap,
+		Merge:              cfg.ResultMerge,
+		MaxConcurrentCalls: cfg.MaxProviderCalls,
+		Fallbacks:          cfg.ProviderFallbacks,
+		Standby:            cfg.StandbyProviders,
+		MinSucceeded:       cfg.MinProvidersSucceeded,
+		FailEmptyPartial:   cfg.FailEmptyPartial,
+		OnTime:             cfg.OnTime,
+	}
+	if cfg.FaultInjection {
+		aggConfig.Faults = faults.NewInjector(providers.NewRand(cfg.ProviderMode, int64(cfg.ProviderSeed), "faults"))
+		log.Println("Fault injection enabled")
+	}
+	agg := aggregator.NewAggregator(providerList, aggConfig)
+	validateFallbacks(agg, cfg.ProviderFallbacks, cfg.StandbyProviders)
 
 	searchHandler := handler.NewSearchHandler(agg, flightCache)
+	searchHandler.SetDefaultWeights(ranking.WeightsFromModel(cfg.RankingWeights))
+	searchHandler.SetSearchCounter(searchCounter)
+	searchHandler.SetPublicMaxAge(cfg.SearchPublicMaxAge)
+	searchHandler.SetRegion(cfg.Region)
+	searchHandler.SetDemo(cfg.Demo)
+	searchHandler.SetBookingLinks(newBookingLinks(agg, cfg))
+	searchHandler.SetPromotions(cfg.Promotions)
+	searchHandler.SetPartialStatus(cfg.PartialResultsStatus)
+	searchHandler.SetMarkups(cfg.Markups)
+	if cfg.CacheEnabled {
+		searchHandler.SetQuoteTTL(cfg.Redis.TTL)
+	}
+	if len(cfg.ProviderMaxAge) > 0 {
+		for name := range cfg.ProviderMaxAge {
+			if _, ok := agg.Provider(name); !ok {
+				log.Fatalf("Invalid PROVIDER_MAX_AGE: unknown provider %q", name)
+			}
+		}
+		searchHandler.SetFreshness(cfg.ProviderMaxAge)
+		log.Printf("Cached flights re-fetched past their max age: %v", cfg.ProviderMaxAge)
+	}
+	if cfg.SearchSnapshotTTL > 0 {
+		if redisClient != nil {
+			searchHandler.SetSnapshots(snapshot.NewRedisStore(redisClient, cfg.SearchSnapshotTTL))
+		} else {
+			searchHandler.SetSnapshots(snapshot.NewMemoryStore(cfg.SearchSnapshotTTL))
+		}
+	}
+	if cfg.ResultCacheTTL > 0 {
+		if redisClient != nil {
+			searchHandler.SetResultCache(cache.NewRedisResultCache(redisClient, cfg.ResultCacheTTL, cfg.Region, cfg.Redis.Encoding))
+		} else {
+			searchHandler.SetResultCache(cache.NewMemoryResultCache(cfg.ResultCacheTTL))
+		}
+		log.Printf("Ranked results cached per tenant for %v", cfg.ResultCacheTTL)
+	}
+	if cfg.RankingModelURL != "" || cfg.RankingFeatureLog != "" {
+		var scorer ranking.Scorer
+		if cfg.RankingModelURL != "" {
+			scorer = ranking.NewHTTPScorer(cfg.RankingModelURL)
+			log.Printf("Ranking model enabled at %s (timeout %s)", cfg.RankingModelURL, cfg.RankingModelTimeout)
+		}
+		model := ranking.NewModelRanker(scorer, cfg.RankingModelTimeout)
+		if cfg.RankingFeatureLog != "" {
+			featureLog, err := ranking.NewFileFeatureLog(cfg.RankingFeatureLog)
+			if err != nil {
+				log.Fatalf("Invalid RANKING_FEATURE_LOG: %v", err)
+			}
+			model.SetFeatureSink(featureLog)
+			log.Printf("Logging ranking features to %s", cfg.RankingFeatureLog)
+		}
+		searchHandler.SetRankingModel(model)
+	}
+
+	var brownoutController *brownout.Controller
+	if cfg.BrownoutEnabled {
+		for _, name := range cfg.BrownoutTier1 {
+			if _, ok := agg.Provider(name); !ok {
+				log.Fatalf("Invalid BROWNOUT_TIER1_PROVIDERS: unknown provider %q", name)
+			}
+		}
+		searches := brownout.NewInFlight(cfg.BrownoutMaxSearches)
+		signals := []brownout.Signal{searches.Saturation}
+		if _, bounded := agg.CallPoolStats(); bounded {
+			signals = append(signals, func() float64 {
+				s, _ := agg.CallPoolStats()
+				return float64(int64(s.InFlight)+s.Waiting) / float64(s.Capacity)
+			})
+		}
+		brownoutController = brownout.NewController(cfg.BrownoutThresholds, cfg.BrownoutCooldown, cfg.BrownoutTier1, signals...)
+		go brownoutController.Run(context.Background(), time.Second)
+		searchHandler.SetBrownout(brownoutController, searches)
+		log.Printf("Brownout enabled (thresholds %v, tier-1 providers %v)", cfg.BrownoutThresholds, cfg.BrownoutTier1)
+	}
+
+	var refStore refdata.Store
+	if redisClient != nil {
+		refStore = refdata.NewRedisStore(redisClient)
+	} else {
+		refStore = refdata.NewMemoryStore()
+	}
+	refCtx, cancelRef := context.WithTimeout(context.Background(), 2*time.Second)
+	if err := refdata.Sync(refCtx, refStore); err != nil {
+		log.Printf("Failed to load reference data, serving %s: %v", refdata.Active().Version, err)
+	}
+	cancelRef()
+	if clustered {
+		go refdata.Watch(context.Background(), refStore, cfg.RefDataPollInterval)
+	}
+
+	var controlStore controls.Store
+	if redisClient != nil {
+		controlStore = controls.NewRedisStore(redisClient)
+	} else {
+		controlStore = controls.NewMemoryStore()
+	}
+	defaultLimit := configsnap.RateLimit{RequestsPerSecond: limitCfg.RequestsPerSecond, BurstSize: limitCfg.BurstSize}
+	providerControls := controls.New(controlStore, agg, rateLimiter, providerLimits, defaultLimit)
+	controlsCtx, cancelControls := context.WithTimeout(context.Background(), 2*time.Second)
+	if err := providerControls.Sync(controlsCtx); err != nil {
+		log.Printf("Failed to load provider controls: %v", err)
+	}
+	cancelControls()
+	if clustered {
+		go controls.Watch(context.Background(), providerControls, cfg.ControlsPollInterval)
+	}
+
+	effective := effectiveConfig(cfg)
+	searchHandler.SetConfig(effective)
+	var configHistory configsnap.History
+	if redisClient != nil {
+		configHistory = configsnap.NewRedisHistory(redisClient)
+	} else {
+		configHistory = configsnap.NewMemoryHistory()
+	}
+	historyCtx, cancelHistory := context.WithTimeout(context.Background(), 2*time.Second)
+	changed, err := configHistory.Record(historyCtx, effective, time.Now())
+	cancelHistory()
+	switch {
+	case err != nil:
+		log.Printf("Failed to record config version %s: %v", effective.Version(), err)
+	case changed:
+		log.Printf("Config version %s recorded as a change", effective.Version())
+	default:
+		log.Printf("Config version %s", effective.Version())
+	}
+
+	if cfg.RecordingTTL > 0 {
+		var recordings recording.Store
+		if redisClient != nil {
+			recordings = recording.NewRedisStore(redisClient, cfg.RecordingTTL)
+		} else {
+			recordings = recording.NewMemoryStore(cfg.RecordingTTL)
+		}
+		searchHandler.SetRecordings(recordings)
+		log.Printf("Recording searches for replay for %v", cfg.RecordingTTL)
+	}
+	searchHandler.SetGroundTransport(groundtransport.NewCachedProvider(
+		groundtransport.NewStaticProvider(),
+		cfg.GroundTransportTTL,
+	))
+	searchHandler.SetHotelSupplier(crosssell.NewCachedSupplier(
+		crosssell.NewStaticSupplier(),
+		cfg.CrossSellTTL,
+	))
+
+	var historyStore pricehistory.Store
+	if redisClient != nil {
+		historyStore = pricehistory.NewRedisStore(redisClient, cfg.PriceHistoryTTL)
+	} else {
+		historyStore = pricehistory.NewMemoryStore()
+	}
+	agg.AddObserver(pricehistory.NewRecorder(historyStore))
+	historyHandler := handler.NewHistoryHandler(historyStore)
+	historyHandler.SetMarkups(cfg.Markups)
+	searchHandler.SetPriceHistory(historyStore)
+
+	var alertStore alerts.Store
+	if redisClient != nil {
+		alertStore = alerts.NewRedisStore(redisClient)
+	} else {
+		alertStore = alerts.NewMemoryStore()
+	}
+	var emailNotifier alerts.Notifier
+	if cfg.SMTP.Host != "" {
+		emailNotifier = alerts.NewEmailNotifier(cfg.SMTP)
+	}
+	alertScheduler := alerts.NewScheduler(
+		alertStore,
+		agg,
+		alerts.NewDispatcher(alerts.NewWebhookNotifier(), emailNotifier),
+	)
+	alertHandler := handler.NewAlertHandler(alertStore)
+
+	var subscriptionStore subscriptions.Store
+	if redisClient != nil {
+		subscriptionStore = subscriptions.NewRedisStore(redisClient)
+	} else {
+		subscriptionStore = subscriptions.NewMemoryStore()
+	}
+	subscriptionHub := subscriptions.NewHub(subscriptionStore, subscriptions.NewWebhookSender())
+	if clustered {
+		subscriptionHub.SetRouteStates(subscriptions.NewRedisRouteStates(redisClient))
+		subscriptionHub.SetFanout(subscriptions.NewRedisFanout(redisClient))
+	}
+	go subscriptionHub.Start(context.Background())
+	agg.AddObserver(subscriptionHub)
+	var eventBus *bus.Bus
+	if cfg.EventBus != "" {
+		eventBus = bus.New(newEventPublisher(cfg), cfg.Region, cfg.EventBusBufferSize)
+		go eventBus.Run(context.Background())
+		agg.AddObserver(eventBus)
+		log.Printf("Publishing search events to %s (%s)", cfg.EventBus, cfg.EventBusSubject)
+	}
+	refresher := subscriptions.NewRefresher(subscriptionStore, agg)
+	subscriptionHandler := handler.NewSubscriptionHandler(subscriptionStore, subscriptionHub)
+
+	var offsetStore offsets.Store
+	if redisClient != nil {
+		offsetStore = offsets.NewRedisStore(redisClient)
+	} else {
+		offsetStore = offsets.NewMemoryStore()
+	}
+	offsetService := offsets.NewService(offsets.NewMockSupplier(cfg.OffsetPricePerTon), offsetStore)
+	offsetHandler := handler.NewOffsetHandler(agg, offsetService)
+
+	var lockStore farelock.Store
+	if redisClient != nil {
+		lockStore = farelock.NewRedisStore(redisClient)
+	} else {
+		lockStore = farelock.NewMemoryStore()
+	}
+	lockService := farelock.NewService(lockStore, cfg.FareLockMaxHold)
+	lockHandler := handler.NewFareLockHandler(agg, lockService)
+	refundHandler := handler.NewRefundHandler(agg)
+	providerHandler := handler.NewProviderHandler(agg, healthTracker, rateLimiter)
+	analyticsHandler := handler.NewAnalyticsHandler(searchCounter, cfg.AnalyticsPrivacy[privacyRoutes])
+	if cfg.SearchEvents {
+		if cfg.AdminToken == "" {
+			log.Fatalf("SEARCH_EVENTS_ENABLED needs ADMIN_TOKEN, which guards the dashboards' endpoints")
+		}
+		if cfg.SearchEventsRetention < time.Hour {
+			log.Fatalf("SEARCH_EVENTS_RETENTION must be at least 1h, got %v", cfg.SearchEventsRetention)
+		}
+		var events analytics.EventStore
+		if redisClient != nil {
+			events = analytics.NewRedisEventStore(redisClient, cfg.SearchEventsRetention)
+		} else {
+			events = analytics.NewMemoryEventStore(cfg.SearchEventsRetention)
+		}
+		searchHandler.SetSearchEvents(events)
+		analyticsHandler.SetEvents(events, cfg.SearchEventsRetention)
+		log.Printf("Recording search events for %v", cfg.SearchEventsRetention)
+	}
+
+	var auditLog *audit.Logger
+	if cfg.AuditSink != "" {
+		auditLog = audit.NewLogger(newAuditSink(cfg), cfg.Audit)
+		go auditLog.Run(context.Background())
+		searchHandler.SetAudit(auditLog)
+		log.Printf("Auditing searches to %s", cfg.AuditSink)
+	}
 
-	api := e.Group("/api/v1")
-	api.POST("/flights/search", searchHandler.Search)
-	e.GET("/health", handler.HealthHandler)
+	janitor := retention.NewJanitor(retention.NewFileArchiver(cfg.ArchiveDir))
+	janitor.Register("searches", cfg.SearchRetention, historyStore.Sweep)
+	janitor.Register("fare_locks", cfg.BookingRetention, lockStore.Sweep)
+	janitor.Register("offset_purchases", cfg.BookingRetention, offsetStore.SweepPurchases)
+
+	var jobStore scheduler.Store
+	if clustered {
+		jobStore = scheduler.NewRedisStore(redisClient)
+	} else {
+		jobStore = scheduler.NewMemoryStore()
+	}
+	jobs := scheduler.New(jobStore)
+	overridden := make(map[string]bool)
+	registerJob := func(name string, interval time.Duration, fn scheduler.Func) {
+		spec, ok := cfg.JobSchedules[name]
+		if ok {
+			overridden[name] = true
+		} else {
+			spec = "@every " + interval.String()
+		}
+		if err := jobs.Register(name, spec, fn); err != nil {
+			log.Fatalf("Invalid JOB_SCHEDULES: %v", err)
+		}
+	}
+	registerJob("price_alerts", cfg.AlertCheckInterval, alertScheduler.CheckAll)
+	registerJob("subscription_refresh", cfg.SubscriptionRefreshInterval, refresher.RefreshAll)
+	registerJob("fare_lock_expiry", time.Minute, lockService.ExpireDue)
+	registerJob("retention", cfg.RetentionInterval, janitor.RunOnce)
+	for name := range cfg.JobSchedules {
+		if !overridden[name] {
+			log.Fatalf("Invalid JOB_SCHEDULES: unknown job %q", name)
+		}
+	}
+	if outageNotifier != nil {
+		jobs.SetFailureHandler(outageNotifier.HandleJobFailure)
+	}
+
+	// Background jobs must run once per cluster, otherwise alerts are sent
+	// and fare locks expired by every replica.
+	var elector *leader.Elector
+	if clustered {
+		elector = leader.NewElector(redisClient, "background-jobs", cfg.LeaderLeaseTTL)
+		go elector.Run(context.Background(), func(ctx context.Context) {
+			go jobs.Start(ctx)
+		})
+	} else {
+		go jobs.Start(context.Background())
+	}
+
+	routes := handlers{
+		search:       searchHandler,
+		history:      historyHandler,
+		lock:         lockHandler,
+		refund:       refundHandler,
+		alert:        alertHandler,
+		subscription: subscriptionHandler,
+		offset:       offsetHandler,
+		provider:     providerHandler,
+		analytics:    analyticsHandler,
+		searchEvents: cfg.SearchEvents,
+	}
+	var idempotencyStore idempotency.Store
+	if redisClient != nil {
+		idempotencyStore = idempotency.NewRedisStore(redisClient)
+	} else {
+		idempotencyStore = idempotency.NewMemoryStore()
+	}
+	routes.idempotency = handler.Idempotency(idempotencyStore, cfg.IdempotencyTTL)
+	if cfg.AdminToken != "" {
+		adminHandler := handler.NewAdminHandler(healthTracker, flightCache, searchCounter, rateLimiter)
+		adminHandler.SetRetention(janitor)
+		adminHandler.SetLeader(elector)
+		adminHandler.SetScheduler(jobs)
+		adminHandler.SetAggregator(agg)
+		if brownoutController != nil {
+			adminHandler.SetBrownout(brownoutController)
+		}
+		adminHandler.SetTopRoutesPolicy(cfg.AnalyticsPrivacy[privacyAdminOverview])
+		adminHandler.SetConfigHistory(configHistory, effective)
+		adminHandler.SetRefData(refStore)
+		adminHandler.SetControls(providerControls)
+		if auditLog != nil {
+			adminHandler.SetAudit(auditLog)
+		}
+		if eventBus != nil {
+			adminHandler.SetEventBus(eventBus)
+		}
+		if aggConfig.Faults != nil {
+			adminHandler.SetFaults(aggConfig.Faults)
+		}
+		routes.admin = adminHandler
+		routes.adminToken = cfg.AdminToken
+	} else {
+		log.Println("Admin endpoints disabled (ADMIN_TOKEN not set)")
+	}
+	registerRoutes(e, routes)
 
 	log.Printf("Starting flight aggregator server on port %s", cfg.Port)
 
@@ -86,18 +901,281 @@ func main() {
 	}
 }
 
+func newAuditSink(cfg Config) audit.Sink {
+	switch cfg.AuditSink {
+	case audit.SinkHTTP:
+		return audit.NewHTTPSink(cfg.AuditURL, cfg.AuditToken)
+	case audit.SinkKafka:
+		return audit.NewKafkaSink(cfg.AuditURL, cfg.AuditKafkaTopic, cfg.AuditToken)
+	}
+	sink, err := audit.NewFileSink(cfg.AuditFile)
+	if err != nil {
+		log.Fatalf("Failed to open AUDIT_FILE: %v", err)
+	}
+	return sink
+}
+
+func newEventPublisher(cfg Config) bus.Publisher {
+	if cfg.EventBus == bus.Kafka {
+		return bus.NewKafkaPublisher(cfg.EventBusURL, cfg.EventBusSubject, cfg.EventBusToken)
+	}
+	p, err := bus.NewNATSPublisher(cfg.EventBusURL, cfg.EventBusSubject)
+	if err != nil {
+		log.Fatalf("Invalid EVENT_BUS_URL: %v", err)
+	}
+	return p
+}
+
 func loadConfig() Config {
 	cfg := Config{
-		Port:         getEnv("PORT", "8080"),
-		CacheEnabled: getEnvBool("CACHE_ENABLED", true),
-		RedisHost:    getEnv("REDIS_HOST", "localhost"),
-		RedisPort:    getEnv("REDIS_PORT", "6379"),
-		RedisTTL:     getEnvDuration("REDIS_TTL", 5*time.Minute),
+		Port:           getEnv("PORT", "8080"),
+		DeploymentMode: getEnv("DEPLOYMENT_MODE", DeploymentSingle),
+		Region:         strings.ToLower(getEnv("REGION", "")),
+		CacheEnabled:   getEnvBool("CACHE_ENABLED", true),
+		Redis:          getEnvRedis(),
+		CacheReplicas:  getEnvReplicas("CACHE_REPLICAS"),
+		ProviderMaxAge: getEnvMaxAges("PROVIDER_MAX_AGE"),
+		AdminToken:     getEnv("ADMIN_TOKEN", ""),
+		APIKeys:        getEnvAPIKeys("API_KEYS"),
+		FeatureFlags:   getEnvFlags("FEATURE_FLAGS"),
+		FieldPolicies:  getEnvFieldPolicies("FIELD_POLICIES_FILE"),
+		Demo:           getEnvBool("DEMO_MODE", false),
+
+		ProviderEndpoints: getEnvEndpoints("PROVIDER_ENDPOINTS"),
+		ProviderMode:      getEnv("PROVIDER_MODE", providers.ModeLive),
+		ProviderSeed:      getEnvInt("PROVIDER_SEED", 1),
+		ProviderVCR:       getEnv("PROVIDER_VCR", vcr.ModeOff),
+		ProviderVCRDir:    getEnv("PROVIDER_VCR_DIR", "cassettes"),
+		Amadeus: providers.AmadeusConfig{
+			ClientID:     getEnv("AMADEUS_CLIENT_ID", ""),
+			ClientSecret: getEnv("AMADEUS_CLIENT_SECRET", ""),
+			BaseURL:      getEnv("AMADEUS_BASE_URL", providers.AmadeusTestURL),
+		},
+		Sabre: providers.SabreConfig{
+			UserID:   getEnv("SABRE_USER_ID", ""),
+			Password: getEnv("SABRE_PASSWORD", ""),
+			PCC:      getEnv("SABRE_PCC", ""),
+			Domain:   getEnv("SABRE_DOMAIN", "AA"),
+			BaseURL:  getEnv("SABRE_BASE_URL", providers.SabreCertURL),
+		},
+		ProviderFallbacks: getEnvFallbacks("PROVIDER_FALLBACKS"),
+		StandbyProviders:  getEnvList("STANDBY_PROVIDERS", nil),
+		OnTime:            getEnvOnTime("ONTIME_DATA_FILE"),
+
+		BookingURLTemplates: getEnvByProvider("BOOKING_URL_TEMPLATES"),
+		AffiliateCodes:      getEnvByProvider("AFFILIATE_CODES"),
+		Promotions:          getEnvPromotions("PROMOTIONS_FILE"),
+		Markups:             getEnvMarkups("MARKUP_POLICIES_FILE"),
+
+		AnalyticsPrivacy:      getEnvPrivacy("ANALYTICS_PRIVACY", getEnvDuration("ANALYTICS_NOISE_WINDOW", time.Hour), noiseSecret()),
+		SearchEvents:          getEnvBool("SEARCH_EVENTS_ENABLED", false),
+		SearchEventsRetention: getEnvDuration("SEARCH_EVENTS_RETENTION", 7*24*time.Hour),
+
+		AuditSink:       getEnv("AUDIT_SINK", ""),
+		AuditFile:       getEnv("AUDIT_FILE", "audit.jsonl"),
+		AuditURL:        getEnv("AUDIT_URL", ""),
+		AuditToken:      getEnv("AUDIT_TOKEN", ""),
+		AuditKafkaTopic: getEnv("AUDIT_KAFKA_TOPIC", "flight-searches"),
+		Audit: audit.Config{
+			BufferSize:    getEnvInt("AUDIT_BUFFER_SIZE", 10000),
+			BatchSize:     getEnvInt("AUDIT_BATCH_SIZE", 100),
+			FlushInterval: getEnvDuration("AUDIT_FLUSH_INTERVAL", time.Second),
+		},
+
+		EventBus:           getEnv("EVENT_BUS", ""),
+		EventBusURL:        getEnv("EVENT_BUS_URL", ""),
+		EventBusSubject:    getEnv("EVENT_BUS_SUBJECT", bus.TypeSearchCompleted),
+		EventBusToken:      getEnv("EVENT_BUS_TOKEN", ""),
+		EventBusBufferSize: getEnvInt("EVENT_BUS_BUFFER_SIZE", 10000),
+
+		SearchPublicMaxAge: getEnvDuration("SEARCH_PUBLIC_MAX_AGE", time.Minute),
+		SearchSnapshotTTL:  getEnvDuration("SEARCH_SNAPSHOT_TTL", 30*time.Minute),
+		ResultCacheTTL:     getEnvDuration("RESULT_CACHE_TTL", 0),
+		RecordingTTL:       getEnvDuration("RECORDING_TTL", 7*24*time.Hour),
+		IdempotencyTTL:     getEnvDuration("IDEMPOTENCY_TTL", time.Hour),
+		LeaderLeaseTTL:     getEnvDuration("LEADER_LEASE_TTL", 15*time.Second),
+		JobSchedules:       getEnvSchedules("JOB_SCHEDULES"),
+
+		RankingWeights: getEnvWeights("RANKING_WEIGHTS", models.RankingWeights{
+			Price:    ranking.PriceWeight,
+			Duration: ranking.DurationWeight,
+			Stops:    ranking.StopsWeight,
+		}),
+		ProviderCap: getEnvInt("PROVIDER_RESULT_CAP", 0),
+		ResultMerge: getEnv("RESULT_MERGE", aggregator.MergeArrival),
+
+		RetryBudget:        getEnvInt("RETRY_BUDGET", 6),
+		RetryBaseDelay:     getEnvDuration("RETRY_BASE_DELAY", 100*time.Millisecond),
+		RetryMaxDelay:      getEnvDuration("RETRY_MAX_DELAY", time.Second),
+		ProviderRetryRatio: getEnvFloat("PROVIDER_RETRY_RATIO", 0.2),
+		MaxProviderCalls:   getEnvInt("MAX_PROVIDER_CALLS", 64),
+
+		MinProvidersSucceeded: getEnvInt("MIN_PROVIDERS_SUCCEEDED", 1),
+		FailEmptyPartial:      getEnvBool("FAIL_EMPTY_PARTIAL", false),
+		PartialResultsStatus:  getEnvInt("PARTIAL_RESULTS_STATUS", http.StatusOK),
+
+		FaultInjection:      getEnvBool("FAULT_INJECTION_ENABLED", false),
+		BrownoutEnabled:     getEnvBool("BROWNOUT_ENABLED", true),
+		BrownoutThresholds:  getEnvThresholds("BROWNOUT_THRESHOLDS", brownout.DefaultThresholds),
+		BrownoutCooldown:    getEnvDuration("BROWNOUT_COOLDOWN", 30*time.Second),
+		BrownoutMaxSearches: getEnvInt("BROWNOUT_MAX_SEARCHES", 200),
+		BrownoutTier1:       getEnvList("BROWNOUT_TIER1_PROVIDERS", []string{"garuda", "lionair"}),
+
+		OTLPMetricsEndpoint:   getEnv("OTLP_METRICS_ENDPOINT", ""),
+		MetricsExportInterval: getEnvDuration("METRICS_EXPORT_INTERVAL", 30*time.Second),
+
+		RankingModelURL:     getEnv("RANKING_MODEL_URL", ""),
+		RankingModelTimeout: getEnvDuration("RANKING_MODEL_TIMEOUT", 150*time.Millisecond),
+		RankingFeatureLog:   getEnv("RANKING_FEATURE_LOG", ""),
+
+		InboundRateLimit:        getEnvFloat("INBOUND_RATE_LIMIT", 0),
+		InboundBurst:            getEnvInt("INBOUND_BURST", 20),
+		ProviderPriorityReserve: getEnvFloat("PROVIDER_PRIORITY_RESERVE", 0),
+
+		RequestTimeout:    getEnvDuration("REQUEST_TIMEOUT", 10*time.Second),
+		MaxBodyBytes:      getEnvInt("MAX_BODY_BYTES", 1<<20),
+		ReadHeaderTimeout: getEnvDuration("READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       getEnvDuration("READ_TIMEOUT", 15*time.Second),
+		IdleTimeout:       getEnvDuration("IDLE_TIMEOUT", time.Minute),
+
+		GroundTransportTTL: getEnvDuration("GROUND_TRANSPORT_TTL", time.Hour),
+		CrossSellTTL:       getEnvDuration("CROSS_SELL_TTL", 6*time.Hour),
+
+		OutageWebhookURL:     getEnv("OUTAGE_WEBHOOK_URL", ""),
+		OutageNotifyInterval: getEnvDuration("OUTAGE_NOTIFY_INTERVAL", 10*time.Minute),
+		CircuitOpenDuration:  getEnvDuration("CIRCUIT_OPEN_DURATION", 30*time.Second),
+
+		PriceHistoryTTL: getEnvDuration("PRICE_HISTORY_TTL", 90*24*time.Hour),
+
+		FareLockMaxHold: getEnvDuration("FARE_LOCK_MAX_HOLD", 72*time.Hour),
+
+		AlertCheckInterval: getEnvDuration("ALERT_CHECK_INTERVAL", 15*time.Minute),
+		OffsetPricePerTon:  getEnvFloat("OFFSET_PRICE_PER_TONNE", 150000),
+		SMTP: alerts.SMTPConfig{
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", "alerts@flightsearch.local"),
+		},
+
+		SubscriptionRefreshInterval: getEnvDuration("SUBSCRIPTION_REFRESH_INTERVAL", 5*time.Minute),
+
+		ArchiveDir:        getEnv("ARCHIVE_DIR", "./archive"),
+		RetentionInterval: getEnvDuration("RETENTION_INTERVAL", time.Hour),
+
+		RefDataPollInterval:  getEnvDuration("REFDATA_POLL_INTERVAL", 30*time.Second),
+		ControlsPollInterval: getEnvDuration("CONTROLS_POLL_INTERVAL", 5*time.Second),
+		SearchRetention: retention.Policy{
+			ArchiveAfter: getEnvDuration("SEARCH_ARCHIVE_AFTER", 30*24*time.Hour),
+			PurgeAfter:   getEnvDuration("SEARCH_PURGE_AFTER", 90*24*time.Hour),
+		},
+		BookingRetention: retention.Policy{
+			ArchiveAfter: getEnvDuration("BOOKING_ARCHIVE_AFTER", 365*24*time.Hour),
+			PurgeAfter:   getEnvDuration("BOOKING_PURGE_AFTER", 7*365*24*time.Hour),
+		},
+	}
+	if cfg.Demo {
+		cfg.InboundRateLimit = getEnvFloat("INBOUND_RATE_LIMIT", demoRateLimit)
+		cfg.InboundBurst = getEnvInt("INBOUND_BURST", demoBurst)
 	}
 
 	return cfg
 }
 
+// replicaConfig connects to another region's Redis at addr as a single
+// server, with the credentials, TLS and pool settings of this region's.
+func replicaConfig(cfg cache.RedisConfig, addr string) cache.RedisConfig {
+	cfg.Addrs = []string{addr}
+	cfg.MasterName = ""
+	cfg.Cluster = false
+	return cfg
+}
+
+func redisAddrs(cfg cache.RedisConfig) string {
+	if len(cfg.Addrs) > 0 {
+		return strings.Join(cfg.Addrs, ",")
+	}
+	return cfg.Host + ":" + cfg.Port
+}
+
+func observeRedisPool(metrics *telemetry.Telemetry, pool string, client redis.UniversalClient) {
+	if metrics == nil {
+		return
+	}
+	if err := metrics.ObserveRedisPool(pool, client); err != nil {
+		log.Printf("Failed to observe Redis pool %s: %v", pool, err)
+	}
+}
+
+// effectiveConfig is the part of cfg that shapes search results, as
+// recorded with each search.
+func effectiveConfig(cfg Config) configsnap.Snapshot {
+	snap := configsnap.Snapshot{
+		Region:             cfg.Region,
+		RankingWeights:     cfg.RankingWeights,
+		RankingModel:       cfg.RankingModelURL,
+		ProviderCap:        cfg.ProviderCap,
+		ResultMerge:        cfg.ResultMerge,
+		ProviderMode:       cfg.ProviderMode,
+		MaxRetries:         maxRetries,
+		RetryBudget:        cfg.RetryBudget,
+		RetryBaseDelay:     cfg.RetryBaseDelay.String(),
+		RetryMaxDelay:      cfg.RetryMaxDelay.String(),
+		ProviderRetryRatio: cfg.ProviderRetryRatio,
+		ProviderTimeout:    providerTimeout.String(),
+		MaxProviderCalls:   cfg.MaxProviderCalls,
+		ProviderLimits:     providerLimits,
+		SearchSnapshotTTL:  cfg.SearchSnapshotTTL.String(),
+		FeatureFlags:       []string{},
+		Demo:               cfg.Demo,
+	}
+	// The seed picks demo prices as well as mock latency and failures.
+	if cfg.ProviderMode == providers.ModeMock || cfg.Demo {
+		snap.ProviderSeed = int64(cfg.ProviderSeed)
+	}
+	if cfg.ProviderVCR == vcr.ModeReplay {
+		snap.ProviderReplay = cfg.ProviderVCRDir
+	}
+	for name, chain := range cfg.ProviderFallbacks {
+		if snap.ProviderFallbacks == nil {
+			snap.ProviderFallbacks = make(map[string][]string)
+		}
+		for _, fb := range chain {
+			snap.ProviderFallbacks[name] = append(snap.ProviderFallbacks[name], fb.String())
+		}
+	}
+	snap.StandbyProviders = cfg.StandbyProviders
+	if cfg.MinProvidersSucceeded > 1 {
+		snap.MinProvidersSucceeded = cfg.MinProvidersSucceeded
+	}
+	snap.FailEmptyPartial = cfg.FailEmptyPartial
+	snap.BookingURLTemplates = cfg.BookingURLTemplates
+	snap.AffiliateCodes = cfg.AffiliateCodes
+	snap.Promotions = cfg.Promotions.Rules()
+	snap.Markups = cfg.Markups
+	if cfg.BrownoutEnabled {
+		snap.BrownoutThresholds = cfg.BrownoutThresholds
+		snap.BrownoutTier1 = cfg.BrownoutTier1
+	}
+	if cfg.CacheEnabled {
+		snap.CacheTTL = cfg.Redis.TTL.String()
+		for name, age := range cfg.ProviderMaxAge {
+			if snap.ProviderMaxAge == nil {
+				snap.ProviderMaxAge = make(map[string]string)
+			}
+			snap.ProviderMaxAge[name] = age.String()
+		}
+	} else {
+		snap.CacheTTL = "disabled"
+	}
+	for name := range cfg.FeatureFlags {
+		snap.FeatureFlags = append(snap.FeatureFlags, name)
+	}
+	slices.Sort(snap.FeatureFlags)
+	return snap
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -113,6 +1191,461 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return value == "true" || value == "1" || value == "yes"
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return f
+}
+
+// getEnvWeights parses "price,duration,stops", e.g. "0.3,0.6,0.1".
+func getEnvWeights(key string, defaultValue models.RankingWeights) models.RankingWeights {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parts := strings.Split(value, ",")
+	if len(parts) != 3 {
+		return defaultValue
+	}
+	var nums [3]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return defaultValue
+		}
+		nums[i] = f
+	}
+	return models.RankingWeights{Price: nums[0], Duration: nums[1], Stops: nums[2]}
+}
+
+// getEnvSchedules parses "job=spec;job=spec", e.g.
+// "retention=0 3 * * *;price_alerts=@every 10m".
+func getEnvSchedules(key string) map[string]string {
+	result := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		name, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		result[strings.TrimSpace(name)] = strings.TrimSpace(spec)
+	}
+	return result
+}
+
+// getEnvAPIKeys parses "key=tenant:tier;key=tenant;key=tenant:tier:admin".
+// The tier defaults to standard; admin is the only scope.
+func getEnvAPIKeys(key string) map[string]reqctx.Key {
+	result := make(map[string]reqctx.Key)
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		apiKey, owner, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		tenant, rest, _ := strings.Cut(owner, ":")
+		tier, scope, _ := strings.Cut(rest, ":")
+		k := reqctx.Key{Tenant: strings.TrimSpace(tenant), Tier: reqctx.Tier(strings.TrimSpace(tier))}
+		if k.Tier == "" {
+			k.Tier = reqctx.TierStandard
+		}
+		switch strings.TrimSpace(scope) {
+		case "":
+		case "admin":
+			k.Admin = true
+		default:
+			log.Fatalf("Invalid %s: unknown scope %q for tenant %s", key, scope, k.Tenant)
+		}
+		result[strings.TrimSpace(apiKey)] = k
+	}
+	return result
+}
+
+// getEnvEndpoints parses "region:provider=url;region:provider=url", e.g.
+// "sg:garuda=https://sg.example/api;id:garuda=https://id.example/api".
+// Every region's deployment can share the one value.
+func getEnvEndpoints(key string) providers.Endpoints {
+	result := make(providers.Endpoints)
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		target, url, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		region, provider, ok := strings.Cut(target, ":")
+		if !ok {
+			continue
+		}
+		region = strings.ToLower(strings.TrimSpace(region))
+		if result[region] == nil {
+			result[region] = make(map[string]string)
+		}
+		result[region][strings.ToLower(strings.TrimSpace(provider))] = strings.TrimSpace(url)
+	}
+	return result
+}
+
+// getEnvByProvider parses "provider=value;provider=value". Values may be
+// empty.
+func getEnvByProvider(key string) map[string]string {
+	result := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		provider, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		result[strings.ToLower(strings.TrimSpace(provider))] = strings.TrimSpace(value)
+	}
+	return result
+}
+
+// getEnvReplicas parses "region=host:port;region=host:port".
+func getEnvReplicas(key string) map[string]string {
+	result := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		region, addr, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		result[strings.ToLower(strings.TrimSpace(region))] = strings.TrimSpace(addr)
+	}
+	return result
+}
+
+// getEnvFallbacks parses "provider=fallback[:airline/airline][,fallback...];...",
+// e.g. "garuda=amadeus:GA;lionair=amadeus:JT/IW". Each provider's
+// fallbacks are tried in order.
+func getEnvFallbacks(key string) map[string][]aggregator.Fallback {
+	result := make(map[string][]aggregator.Fallback)
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		provider, chain, ok := strings.Cut(entry, "=")
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if !ok || provider == "" || strings.TrimSpace(chain) == "" {
+			log.Fatalf("Invalid %s entry %q, expected provider=fallback[:airlines]", key, entry)
+		}
+		for _, spec := range strings.Split(chain, ",") {
+			name, airlines, _ := strings.Cut(spec, ":")
+			fb := aggregator.Fallback{Provider: strings.ToLower(strings.TrimSpace(name))}
+			for _, code := range strings.Split(airlines, "/") {
+				if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+					fb.Airlines = append(fb.Airlines, code)
+				}
+			}
+			result[provider] = append(result[provider], fb)
+		}
+	}
+	return result
+}
+
+// validateFallbacks exits unless every provider named is registered, no
+// provider falls back on itself, and every standby provider is some
+// provider's fallback.
+// newBookingLinks combines the providers' booking URL templates with the
+// configured ones.
+func newBookingLinks(agg *aggregator.Aggregator, cfg Config) *deeplink.Builder {
+	templates := make(map[string]string)
+	for _, p := range agg.Providers() {
+		if d, ok := p.(providers.DeepLinker); ok {
+			templates[p.Name()] = d.BookingURLTemplate()
+		}
+	}
+	for name, template := range cfg.BookingURLTemplates {
+		if _, ok := agg.Provider(name); !ok {
+			log.Fatalf("Invalid BOOKING_URL_TEMPLATES: unknown provider %q", name)
+		}
+		if template == "" {
+			delete(templates, name)
+			continue
+		}
+		templates[name] = template
+	}
+	for name := range cfg.AffiliateCodes {
+		if _, ok := agg.Provider(name); !ok {
+			log.Fatalf("Invalid AFFILIATE_CODES: unknown provider %q", name)
+		}
+	}
+	links, err := deeplink.New(templates, cfg.AffiliateCodes)
+	if err != nil {
+		log.Fatalf("Invalid BOOKING_URL_TEMPLATES: %v", err)
+	}
+	return links
+}
+
+func validateFallbacks(agg *aggregator.Aggregator, fallbacks map[string][]aggregator.Fallback, standby []string) {
+	used := make(map[string]bool)
+	for provider, chain := range fallbacks {
+		if _, ok := agg.Provider(provider); !ok {
+			log.Fatalf("Invalid PROVIDER_FALLBACKS: unknown provider %q", provider)
+		}
+		for _, fb := range chain {
+			if _, ok := agg.Provider(fb.Provider); !ok {
+				log.Fatalf("Invalid PROVIDER_FALLBACKS: unknown fallback %q for %s", fb.Provider, provider)
+			}
+			if fb.Provider == provider {
+				log.Fatalf("Invalid PROVIDER_FALLBACKS: %s falls back on itself", provider)
+			}
+			used[fb.Provider] = true
+		}
+		log.Printf("Provider %s falls back on %v", provider, chain)
+	}
+	for _, name := range standby {
+		if _, ok := agg.Provider(name); !ok {
+			log.Fatalf("Invalid STANDBY_PROVIDERS: unknown provider %q", name)
+		}
+		if !used[name] {
+			log.Fatalf("Invalid STANDBY_PROVIDERS: %s is no provider's fallback, so it would never be searched", name)
+		}
+	}
+}
+
+// getEnvMaxAges parses "provider=duration;provider=duration", e.g.
+// "airasia=10m;garuda=30m".
+func getEnvMaxAges(key string) cache.Freshness {
+	result := make(cache.Freshness)
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		provider, spec, _ := strings.Cut(entry, "=")
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		age, err := time.ParseDuration(strings.TrimSpace(spec))
+		if err != nil || age <= 0 {
+			log.Fatalf("Invalid %s: max age for %s must be a positive duration such as 10m", key, provider)
+		}
+		result[provider] = age
+	}
+	return result
+}
+
+// getEnvRedis reads the Redis connection: REDIS_HOST and REDIS_PORT for a
+// single server, or REDIS_ADDRS for a cluster or, with
+// REDIS_SENTINEL_MASTER, for the Sentinels.
+func getEnvRedis() cache.RedisConfig {
+	cfg := cache.DefaultRedisConfig()
+	cfg.Host = getEnv("REDIS_HOST", cfg.Host)
+	cfg.Port = getEnv("REDIS_PORT", cfg.Port)
+	cfg.Addrs = getEnvList("REDIS_ADDRS", nil)
+	cfg.MasterName = getEnv("REDIS_SENTINEL_MASTER", "")
+	cfg.SentinelPassword = getEnv("REDIS_SENTINEL_PASSWORD", "")
+	cfg.Cluster = getEnvBool("REDIS_CLUSTER", false)
+	cfg.Username = getEnv("REDIS_USERNAME", "")
+	cfg.Password = getEnv("REDIS_PASSWORD", "")
+	cfg.DB = getEnvInt("REDIS_DB", 0)
+	if getEnvBool("REDIS_TLS", false) {
+		cfg.TLS = getEnvTLS("REDIS_TLS_CA_FILE", "REDIS_TLS_SERVER_NAME")
+	}
+	cfg.PoolSize = getEnvInt("REDIS_POOL_SIZE", 0)
+	cfg.MinIdleConns = getEnvInt("REDIS_MIN_IDLE_CONNS", 0)
+	cfg.PoolTimeout = getEnvDuration("REDIS_POOL_TIMEOUT", 0)
+	cfg.TTL = getEnvDuration("REDIS_TTL", cfg.TTL)
+	cfg.NegativeTTL = getEnvDuration("NEGATIVE_CACHE_TTL", cfg.NegativeTTL)
+	cfg.Encoding = getEnvEncoding("CACHE_ENCODING", cfg.Encoding)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid Redis configuration: %v", err)
+	}
+	return cfg
+}
+
+// getEnvTLS verifies servers against the system roots, or the PEM
+// certificates in the file named by caKey, by the name in nameKey when
+// it differs from the address dialled.
+func getEnvTLS(caKey, nameKey string) *tls.Config {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: os.Getenv(nameKey)}
+	if path := os.Getenv(caKey); path != "" {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Invalid %s: %v", caKey, err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			log.Fatalf("Invalid %s: no PEM certificates in %s", caKey, path)
+		}
+	}
+	return cfg
+}
+
+func getEnvEncoding(key string, defaultValue cache.Encoding) cache.Encoding {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	encoding, err := cache.ParseEncoding(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return encoding
+}
+
+// getEnvPrivacy parses "endpoint=min_count:epsilon;...", e.g.
+// "routes=50:0.5;admin_overview=0:0". The public routes endpoint defaults
+// to 20:1; the admin overview to exact counts.
+func getEnvPrivacy(key string, window time.Duration, secret []byte) map[string]analytics.PrivacyPolicy {
+	result := map[string]analytics.PrivacyPolicy{
+		privacyRoutes:        analytics.NewPrivacyPolicy(20, 1, window, secret),
+		privacyAdminOverview: analytics.Exact,
+	}
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		endpoint, spec, _ := strings.Cut(entry, "=")
+		endpoint = strings.TrimSpace(endpoint)
+		if _, ok := result[endpoint]; !ok {
+			log.Fatalf("Invalid %s: unknown endpoint %q (want %s or %s)", key, endpoint, privacyRoutes, privacyAdminOverview)
+		}
+		minCount, epsilon, _ := strings.Cut(spec, ":")
+		var p analytics.PrivacyPolicy
+		var err error
+		if p.MinCount, err = strconv.ParseInt(strings.TrimSpace(minCount), 10, 64); err != nil || p.MinCount < 0 {
+			log.Fatalf("Invalid %s: min count for %s must be a non-negative integer", key, endpoint)
+		}
+		if epsilon != "" {
+			if p.Epsilon, err = strconv.ParseFloat(strings.TrimSpace(epsilon), 64); err != nil || p.Epsilon < 0 {
+				log.Fatalf("Invalid %s: epsilon for %s must be a non-negative number", key, endpoint)
+			}
+		}
+		result[endpoint] = analytics.NewPrivacyPolicy(p.MinCount, p.Epsilon, window, secret)
+	}
+	return result
+}
+
+// noiseSecret keys analytics noise. Replicas must share it to add the same
+// noise to a route, so it comes from ANALYTICS_NOISE_SECRET, else
+// ADMIN_TOKEN; without either each process picks its own.
+func noiseSecret() []byte {
+	for _, key := range []string{"ANALYTICS_NOISE_SECRET", "ADMIN_TOKEN"} {
+		if v := os.Getenv(key); v != "" {
+			return []byte(v)
+		}
+	}
+	log.Println("ANALYTICS_NOISE_SECRET is not set: analytics noise differs per replica")
+	secret := make([]byte, 16)
+	rand.Read(secret)
+	return secret
+}
+
+// getEnvThresholds parses one saturation per brownout level above normal,
+// e.g. "0.8,1,1.5,2".
+func getEnvThresholds(key string, defaultValue []float64) []float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parts := strings.Split(value, ",")
+	if len(parts) != len(defaultValue) {
+		log.Fatalf("Invalid %s: want %d comma-separated thresholds", key, len(defaultValue))
+	}
+	thresholds := make([]float64, len(parts))
+	for i, p := range parts {
+		t, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || t <= 0 || (i > 0 && t < thresholds[i-1]) {
+			log.Fatalf("Invalid %s: thresholds must be positive and increasing", key)
+		}
+		thresholds[i] = t
+	}
+	return thresholds
+}
+
+// getEnvList parses a comma-separated list.
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, strings.ToLower(item))
+		}
+	}
+	return list
+}
+
+// getEnvFieldPolicies loads per-tenant response policies from the JSON
+// file named by key. A broken file is fatal rather than silently exposing
+// fields a tenant must not see.
+func getEnvFieldPolicies(key string) map[string]fields.Policy {
+	path := os.Getenv(key)
+	if path == "" {
+		return nil
+	}
+	policies, err := fields.LoadPolicies(path)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return policies
+}
+
+// getEnvPromotions loads promotion rules from the JSON file named by key.
+func getEnvPromotions(key string) *promotions.Engine {
+	path := os.Getenv(key)
+	if path == "" {
+		return nil
+	}
+	engine, err := promotions.Load(path)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return engine
+}
+
+// getEnvMarkups loads per-tenant markup policies from the JSON file named
+// by key.
+func getEnvMarkups(key string) map[string]markup.Policy {
+	path := os.Getenv(key)
+	if path == "" {
+		return nil
+	}
+	policies, err := markup.LoadPolicies(path)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return policies
+}
+
+// getEnvOnTime loads on-time performance from the CSV file named by key,
+// or returns the built-in dataset.
+func getEnvOnTime(key string) ontime.Source {
+	path := os.Getenv(key)
+	if path == "" {
+		return ontime.Builtin()
+	}
+	data, err := ontime.Load(path)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return data
+}
+
+// getEnvFlags parses a comma-separated list of enabled feature flags.
+func getEnvFlags(key string) reqctx.Flags {
+	flags := make(reqctx.Flags)
+	for _, name := range strings.Split(os.Getenv(key), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			flags[name] = true
+		}
+	}
+	return flags
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	value := os.Getenv(key)
 	if value == "" {
@@ -125,32 +1658,47 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	return duration
 }
 
-func initializeProviders() ([]providers.Provider, error) {
+// initializeProviders gives each provider its own random source, so in
+// mock mode one provider's latency and failures don't depend on how calls
+// to the others interleave.
+func initializeProviders(mode string, seed int64) ([]providers.Provider, error) {
 	var providerList []providers.Provider
 
-	garuda, err := providers.NewGarudaProvider()
+	garuda, err := providers.NewGarudaProvider(providers.NewRand(mode, seed, "garuda"))
 	if err != nil {
 		return nil, err
 	}
 	providerList = append(providerList, garuda)
 
-	lionair, err := providers.NewLionAirProvider()
+	lionair, err := providers.NewLionAirProvider(providers.NewRand(mode, seed, "lionair"))
 	if err != nil {
 		return nil, err
 	}
 	providerList = append(providerList, lionair)
 
-	batikair, err := providers.NewBatikAirProvider()
+	batikair, err := providers.NewBatikAirProvider(providers.NewRand(mode, seed, "batikair"))
 	if err != nil {
 		return nil, err
 	}
 	providerList = append(providerList, batikair)
 
-	airasia, err := providers.NewAirAsiaProvider()
+	airasia, err := providers.NewAirAsiaProvider(providers.NewRand(mode, seed, "airasia"))
 	if err != nil {
 		return nil, err
 	}
 	providerList = append(providerList, airasia)
 
+	citilink, err := providers.NewCitilinkProvider(providers.NewRand(mode, seed, "citilink"))
+	if err != nil {
+		return nil, err
+	}
+	providerList = append(providerList, citilink)
+
+	superairjet, err := providers.NewSuperAirJetProvider(providers.NewRand(mode, seed, "superairjet"))
+	if err != nil {
+		return nil, err
+	}
+	providerList = append(providerList, superairjet)
+
 	return providerList, nil
 }
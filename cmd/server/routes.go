@@ -0,0 +1,347 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/configsnap"
+	"github.com/dharmasatrya/flightsearch/internal/faults"
+	"github.com/dharmasatrya/flightsearch/internal/handler"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/openapi"
+	"github.com/dharmasatrya/flightsearch/internal/retention"
+	"github.com/dharmasatrya/flightsearch/internal/scheduler"
+)
+
+type handlers struct {
+	search       *handler.SearchHandler
+	history      *handler.HistoryHandler
+	lock         *handler.FareLockHandler
+	refund       *handler.RefundHandler
+	alert        *handler.AlertHandler
+	subscription *handler.SubscriptionHandler
+	offset       *handler.OffsetHandler
+	provider     *handler.ProviderHandler
+	analytics    *handler.AnalyticsHandler
+	searchEvents bool
+	idempotency  echo.MiddlewareFunc
+	admin        *handler.AdminHandler
+	adminToken   string
+}
+
+// registerRoutes wires every endpoint and documents it in the OpenAPI
+// spec from the structs the handlers bind and return.
+func registerRoutes(e *echo.Echo, h handlers) {
+	spec := openapi.NewBuilder("Flight Search API", "1.0.0")
+	spec.SetErrorBody(models.ErrorResponse{})
+
+	api := spec.Router(e.Group("/api/v1"), "/api/v1")
+	idempotent := api.With(h.idempotency)
+
+	idempotent.POST("/flights/search", h.search.Search, openapi.Op{
+		Summary:     "Search one-way or round-trip flights",
+		Tag:         "Flights",
+		Body:        models.SearchRequest{},
+		QueryParams: []string{"fields"},
+		Headers:     []string{handler.HeaderIdempotencyKey, echo.HeaderCacheControl},
+		Responses: map[int]any{
+			http.StatusOK: openapi.OneOf{models.SearchResponse{}, models.RoundTripResponse{}},
+		},
+	})
+	api.GET("/flights/search", h.search.SearchQuery, openapi.Op{
+		Summary:     "Search flights with query parameters",
+		Tag:         "Flights",
+		Query:       []any{models.SearchRequest{}, models.SearchFilters{}, models.PassengerMix{}},
+		QueryParams: []string{"date", "ranking_weights", "fields"},
+		Headers:     []string{echo.HeaderCacheControl},
+		Responses: map[int]any{
+			http.StatusOK: openapi.OneOf{models.SearchResponse{}, models.RoundTripResponse{}},
+		},
+	})
+	api.GET("/providers", h.provider.List, openapi.Op{
+		Summary: "Registered providers with their capabilities, rate limits and health",
+		Tag:     "Providers",
+		Responses: map[int]any{http.StatusOK: struct {
+			Providers []handler.ProviderInfo `json:"providers"`
+		}{}},
+	})
+	api.GET("/airports", handler.SearchAirports, openapi.Op{
+		Summary:     "Airport autocomplete by code, city or name",
+		Tag:         "Airports",
+		QueryParams: []string{"q", "limit"},
+		Responses:   map[int]any{http.StatusOK: handler.AirportList{}},
+	})
+	api.GET("/airports/:code/nearby", handler.NearbyAirportsOf, openapi.Op{
+		Summary:     "Airports within a radius of an airport",
+		Tag:         "Airports",
+		QueryParams: []string{"radius_km"},
+		Responses:   map[int]any{http.StatusOK: handler.NearbyAirports{}},
+	})
+	api.GET("/analytics/routes", h.analytics.Routes, openapi.Op{
+		Summary:     "Most searched routes, with low-volume routes suppressed and noise added",
+		Tag:         "Analytics",
+		QueryParams: []string{"limit"},
+		Responses:   map[int]any{http.StatusOK: handler.RouteDemand{}},
+	})
+	api.GET("/flights/history", h.history.History, openapi.Op{
+		Summary:     "Lowest observed fare over time",
+		Tag:         "Flights",
+		QueryParams: []string{"origin", "destination", "date", "cabin_class", "interval", "from", "to"},
+		Responses:   map[int]any{http.StatusOK: models.PriceHistoryResponse{}},
+	})
+	api.GET("/flights/diff", h.search.Diff, openapi.Op{
+		Summary:     "Added, removed and repriced flights between two searches of the same route",
+		Tag:         "Flights",
+		QueryParams: []string{"from", "to", "fields"},
+		Responses:   map[int]any{http.StatusOK: models.SearchDiff{}},
+	})
+	idempotent.POST("/flights/:id/lock", h.lock.Lock, openapi.Op{
+		Summary:   "Hold a fare",
+		Tag:       "Fare Locks",
+		Body:      models.FareLockRequest{},
+		Headers:   []string{handler.HeaderIdempotencyKey},
+		Responses: map[int]any{http.StatusCreated: models.FareLock{}},
+	})
+	api.POST("/flights/:id/verify", h.search.Verify, openapi.Op{
+		Summary:   "Re-price a flight with its provider before checkout",
+		Tag:       "Flights",
+		Body:      models.FareVerifyRequest{},
+		Responses: map[int]any{http.StatusOK: models.FareVerification{}},
+	})
+	api.GET("/locks/:id", h.lock.Get, openapi.Op{
+		Summary:   "Get a fare lock",
+		Tag:       "Fare Locks",
+		Responses: map[int]any{http.StatusOK: models.FareLock{}},
+	})
+	api.POST("/flights/:id/refund-quote", h.refund.Quote, openapi.Op{
+		Summary:   "Quote a refund for a flight",
+		Tag:       "Flights",
+		Body:      models.RefundQuoteRequest{},
+		Responses: map[int]any{http.StatusOK: models.RefundQuote{}},
+	})
+	api.POST("/alerts", h.alert.Create, openapi.Op{
+		Summary:   "Create a price or seat alert",
+		Tag:       "Alerts",
+		Body:      models.CreateAlertRequest{},
+		Responses: map[int]any{http.StatusCreated: models.Alert{}},
+	})
+	api.GET("/alerts", h.alert.List, openapi.Op{
+		Summary: "List price alerts",
+		Tag:     "Alerts",
+		Responses: map[int]any{http.StatusOK: struct {
+			Alerts []models.Alert `json:"alerts"`
+		}{}},
+	})
+	api.GET("/alerts/:id", h.alert.Get, openapi.Op{
+		Summary:   "Get a price alert",
+		Tag:       "Alerts",
+		Responses: map[int]any{http.StatusOK: models.Alert{}},
+	})
+	api.DELETE("/alerts/:id", h.alert.Delete, openapi.Op{
+		Summary:   "Delete a price alert",
+		Tag:       "Alerts",
+		Responses: map[int]any{http.StatusNoContent: openapi.NoContent{}},
+	})
+	api.POST("/subscriptions", h.subscription.Create, openapi.Op{
+		Summary:   "Subscribe to fare changes on routes",
+		Tag:       "Subscriptions",
+		Body:      models.CreateSubscriptionRequest{},
+		Responses: map[int]any{http.StatusCreated: models.Subscription{}},
+	})
+	api.GET("/subscriptions/:id", h.subscription.Get, openapi.Op{
+		Summary:   "Get a subscription",
+		Tag:       "Subscriptions",
+		Responses: map[int]any{http.StatusOK: models.Subscription{}},
+	})
+	api.DELETE("/subscriptions/:id", h.subscription.Delete, openapi.Op{
+		Summary:   "Delete a subscription",
+		Tag:       "Subscriptions",
+		Responses: map[int]any{http.StatusNoContent: openapi.NoContent{}},
+	})
+	api.GET("/subscriptions/:id/stream", h.subscription.Stream, openapi.Op{
+		Summary:     "Stream fare events as server-sent events",
+		Tag:         "Subscriptions",
+		ContentType: "text/event-stream",
+		Responses:   map[int]any{http.StatusOK: models.FareEvent{}},
+	})
+	api.POST("/offsets/quotes", h.offset.Quote, openapi.Op{
+		Summary:   "Quote a carbon offset for a flight",
+		Tag:       "Carbon Offsets",
+		Body:      models.OffsetQuoteRequest{},
+		Responses: map[int]any{http.StatusCreated: models.OffsetQuote{}},
+	})
+	idempotent.POST("/offsets/purchases", h.offset.Purchase, openapi.Op{
+		Summary:   "Purchase a quoted carbon offset",
+		Tag:       "Carbon Offsets",
+		Body:      models.OffsetPurchaseRequest{},
+		Headers:   []string{handler.HeaderIdempotencyKey},
+		Responses: map[int]any{http.StatusCreated: models.OffsetPurchase{}},
+	})
+	api.GET("/offsets/purchases/:id", h.offset.GetPurchase, openapi.Op{
+		Summary:   "Get a carbon offset purchase",
+		Tag:       "Carbon Offsets",
+		Responses: map[int]any{http.StatusOK: models.OffsetPurchase{}},
+	})
+
+	root := spec.Router(e, "")
+	root.GET("/health", handler.HealthHandler, openapi.Op{
+		Summary: "Health check",
+		Tag:     "Health",
+		Responses: map[int]any{http.StatusOK: struct {
+			Status string `json:"status"`
+		}{}},
+	})
+
+	if h.admin != nil && h.searchEvents {
+		dashboards := spec.Router(e.Group("/api/v1/analytics", handler.AdminAuth(h.adminToken)), "/api/v1/analytics").WithAuth()
+		dashboards.GET("/popular-routes", h.analytics.PopularRoutes, openapi.Op{
+			Summary:     "Most searched routes with exact counts, result counts and cabins, for internal dashboards",
+			Tag:         "Analytics",
+			QueryParams: []string{"window", "limit"},
+			Responses:   map[int]any{http.StatusOK: handler.PopularRoutes{}},
+		})
+		dashboards.GET("/latency", h.analytics.Latency, openapi.Op{
+			Summary:     "Search latency percentiles, overall and by cache hit, for internal dashboards",
+			Tag:         "Analytics",
+			QueryParams: []string{"window", "origin", "destination"},
+			Responses:   map[int]any{http.StatusOK: handler.SearchLatency{}},
+		})
+	}
+
+	if h.admin != nil {
+		admin := spec.Router(e.Group("/admin", handler.AdminAuth(h.adminToken)), "/admin").WithAuth()
+		admin.GET("/overview", h.admin.Overview, openapi.Op{
+			Summary:   "Provider health, cache, traffic and quota overview",
+			Tag:       "Admin",
+			Responses: map[int]any{http.StatusOK: handler.AdminOverview{}},
+		})
+		admin.POST("/searches/:id/replay", h.search.Replay, openapi.Op{
+			Summary:   "Reproduce a past search from its recorded provider responses and configuration",
+			Tag:       "Admin",
+			Body:      models.SearchRequest{},
+			Responses: map[int]any{http.StatusOK: handler.SearchReplay{}},
+		})
+		admin.GET("/config/history", h.admin.ConfigHistory, openapi.Op{
+			Summary:   "Effective configuration versions, newest first",
+			Tag:       "Admin",
+			Responses: map[int]any{http.StatusOK: handler.ConfigHistory{}},
+		})
+		admin.GET("/config/diff", h.admin.ConfigDiff, openapi.Op{
+			Summary:     "Settings changed between two configuration versions",
+			Tag:         "Admin",
+			QueryParams: []string{"from", "to"},
+			Responses:   map[int]any{http.StatusOK: handler.ConfigDiff{}},
+		})
+		admin.GET("/refdata", h.admin.RefData, openapi.Op{
+			Summary:   "Active airport and airline reference data version",
+			Tag:       "Admin",
+			Responses: map[int]any{http.StatusOK: handler.RefDataSummary{}},
+		})
+		admin.POST("/refdata/imports", h.admin.ImportRefData, openapi.Op{
+			Summary:   "Validate OpenFlights or OurAirports dumps uploaded as multipart airports and airlines files, and stage them for activation",
+			Tag:       "Admin",
+			Responses: map[int]any{http.StatusCreated: handler.RefDataImport{}},
+		})
+		admin.POST("/refdata/imports/:version/activate", h.admin.ActivateRefData, openapi.Op{
+			Summary:   "Serve a staged reference data import on every replica",
+			Tag:       "Admin",
+			Responses: map[int]any{http.StatusOK: handler.RefDataSummary{}},
+		})
+		admin.GET("/faults", h.admin.Faults, openapi.Op{
+			Summary: "Faults injected into provider calls on this replica",
+			Tag:     "Admin",
+			Responses: map[int]any{http.StatusOK: struct {
+				Faults map[string]faults.Fault `json:"faults"`
+			}{}},
+		})
+		admin.PUT("/faults/:provider", h.admin.SetFault, openapi.Op{
+			Summary:   "Inject errors, latency or empty results into a provider's calls",
+			Tag:       "Admin",
+			Body:      handler.FaultRequest{},
+			Responses: map[int]any{http.StatusOK: faults.Fault{}},
+		})
+		admin.DELETE("/faults/:provider", h.admin.ClearFault, openapi.Op{
+			Summary:   "Stop injecting faults into a provider's calls",
+			Tag:       "Admin",
+			Responses: map[int]any{http.StatusNoContent: openapi.NoContent{}},
+		})
+		admin.DELETE("/faults", h.admin.ClearFaults, openapi.Op{
+			Summary:   "Stop injecting all faults",
+			Tag:       "Admin",
+			Responses: map[int]any{http.StatusNoContent: openapi.NoContent{}},
+		})
+		admin.GET("/providers", h.admin.ProviderControls, openapi.Op{
+			Summary: "Providers' rotation, circuit breaker and rate limit state",
+			Tag:     "Admin",
+			Responses: map[int]any{http.StatusOK: struct {
+				Providers []handler.ProviderControl `json:"providers"`
+			}{}},
+		})
+		admin.POST("/providers/:name/disable", h.admin.DisableProvider, openapi.Op{
+			Summary:   "Take a provider out of rotation on every replica",
+			Tag:       "Admin",
+			Body:      handler.DisableProviderRequest{},
+			Responses: map[int]any{http.StatusOK: handler.ProviderControl{}},
+		})
+		admin.POST("/providers/:name/enable", h.admin.EnableProvider, openapi.Op{
+			Summary:   "Put a provider back in rotation",
+			Tag:       "Admin",
+			Responses: map[int]any{http.StatusOK: handler.ProviderControl{}},
+		})
+		admin.PUT("/providers/:name/rate-limit", h.admin.SetProviderRateLimit, openapi.Op{
+			Summary:   "Override a provider's rate limit on every replica",
+			Tag:       "Admin",
+			Body:      configsnap.RateLimit{},
+			Responses: map[int]any{http.StatusOK: handler.ProviderControl{}},
+		})
+		admin.DELETE("/providers/:name/rate-limit", h.admin.ClearProviderRateLimit, openapi.Op{
+			Summary:   "Restore a provider's configured rate limit",
+			Tag:       "Admin",
+			Responses: map[int]any{http.StatusOK: handler.ProviderControl{}},
+		})
+		admin.DELETE("/cache", h.admin.FlushCache, openapi.Op{
+			Summary:   "Delete every cached search",
+			Tag:       "Admin",
+			Responses: map[int]any{http.StatusOK: handler.CacheFlush{}},
+		})
+		admin.POST("/cache/warm", h.admin.WarmCache, openapi.Op{
+			Summary:   "Search routes in the background to cache them ahead of traffic",
+			Tag:       "Admin",
+			Body:      handler.CacheWarmRequest{},
+			Responses: map[int]any{http.StatusAccepted: handler.CacheWarm{}},
+		})
+		admin.GET("/retention", h.admin.Retention, openapi.Op{
+			Summary: "Retention status per dataset",
+			Tag:     "Admin",
+			Responses: map[int]any{http.StatusOK: struct {
+				Datasets []retention.Status `json:"datasets"`
+			}{}},
+		})
+		admin.GET("/jobs", h.admin.Jobs, openapi.Op{
+			Summary: "Background job status",
+			Tag:     "Admin",
+			Responses: map[int]any{http.StatusOK: struct {
+				Jobs []scheduler.JobStatus `json:"jobs"`
+			}{}},
+		})
+		admin.POST("/jobs/:name/run", h.admin.RunJob, openapi.Op{
+			Summary:   "Queue an immediate job run",
+			Tag:       "Admin",
+			Responses: map[int]any{http.StatusAccepted: scheduler.JobStatus{}},
+		})
+		admin.POST("/jobs/:name/enable", h.admin.EnableJob, openapi.Op{
+			Summary:   "Enable scheduled runs of a job",
+			Tag:       "Admin",
+			Responses: map[int]any{http.StatusOK: scheduler.JobStatus{}},
+		})
+		admin.POST("/jobs/:name/disable", h.admin.DisableJob, openapi.Op{
+			Summary:   "Disable scheduled runs of a job",
+			Tag:       "Admin",
+			Responses: map[int]any{http.StatusOK: scheduler.JobStatus{}},
+		})
+	}
+
+	e.GET("/api/v1/openapi.json", spec.Handler())
+	e.GET("/api/v1/docs", spec.SwaggerUI("/api/v1/openapi.json"))
+}
@@ -2,25 +2,87 @@ package aggregator
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"math/rand/v2"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/dharmasatrya/flightsearch/internal/aircraft"
+	"github.com/dharmasatrya/flightsearch/internal/airports"
+	"github.com/dharmasatrya/flightsearch/internal/emissions"
+	"github.com/dharmasatrya/flightsearch/internal/faults"
+	"github.com/dharmasatrya/flightsearch/internal/health"
+	"github.com/dharmasatrya/flightsearch/internal/mct"
 	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/ontime"
 	"github.com/dharmasatrya/flightsearch/internal/providers"
 	"github.com/dharmasatrya/flightsearch/internal/ratelimit"
+	"github.com/dharmasatrya/flightsearch/internal/refdata"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/internal/timezone"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
 )
 
+var ErrFlightNotFound = errorsx.New(errorsx.NotFound, "flight not found")
+
 type Config struct {
-	Timeout     time.Duration
-	MaxRetries  int
-	RetryDelays []time.Duration
-	RateLimiter *ratelimit.ProviderLimiter
+	Timeout    time.Duration
+	MaxRetries int
+	// Retries back off exponentially from RetryBaseDelay, doubling up to
+	// RetryMaxDelay, with each wait drawn between half and all of its step.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// RetryBudget caps retries across all providers in one search. Zero
+	// leaves each provider with its own MaxRetries.
+	RetryBudget int
+	// ProviderRetryRatio caps each provider's retries across searches at
+	// this fraction of its searches. Zero leaves them uncapped.
+	ProviderRetryRatio float64
+	RateLimiter        ratelimit.Limiter
+	Health             *health.Tracker
+	// ProviderCap limits how many flights each provider contributes to a
+	// search, so one large source can't crowd out the rest. Zero is no cap.
+	ProviderCap int
+	// Merge is one of MergeStrategies; empty means MergeArrival.
+	Merge string
+	// MaxConcurrentCalls bounds provider calls in flight across all
+	// searches; calls beyond it wait for a slot. Zero is unbounded.
+	MaxConcurrentCalls int
+	// Faults, when set, injects failures into provider calls.
+	Faults *faults.Injector
+	// Fallbacks are searched in order when a provider's quota is spent or
+	// its circuit is open, by provider.
+	Fallbacks map[string][]Fallback
+	// Standby providers are kept for fallbacks: searches only query them
+	// directly when they name them.
+	Standby []string
+	// MinSucceeded fails searches fewer providers answered, fallbacks
+	// standing in included, with ErrPartialResults. Searches selecting
+	// fewer providers need every one. Zero or one only fails searches no
+	// provider answered.
+	MinSucceeded int
+	// FailEmptyPartial fails searches that found no flights while a
+	// provider failed, rather than answering as if nobody served the route.
+	FailEmptyPartial bool
+	// OnTime, when set, rates every flight's reliability.
+	OnTime ontime.Source
+}
+
+// Observer is notified after every completed aggregator run.
+type Observer interface {
+	ObserveSearch(ctx context.Context, req models.SearchRequest, result *Result)
 }
 
 type Aggregator struct {
-	providers []providers.Provider
-	config    Config
+	providers    []providers.Provider
+	config       Config
+	observers    []Observer
+	retryBudgets map[string]*providerRetryBudget
+	pool         *callPool
+	standby      map[string]bool
+	disabled     atomic.Pointer[map[string]bool]
 }
 
 type Result struct {
@@ -29,54 +91,160 @@ type Result struct {
 	ProvidersSucceeded int
 	ProvidersFailed    int
 	FailedProviders    []string
+	// Truncated counts the flights ProviderCap dropped, per provider.
+	Truncated map[string]int
+	// Fallbacks maps each failed provider a fallback stood in for to the
+	// provider searched instead.
+	Fallbacks map[string]string
+	// Responses holds every provider's answer before merging, in the
+	// order they answered.
+	Responses []ProviderResponse
+}
+
+type ProviderResponse struct {
+	Provider string          `json:"provider"`
+	Flights  []models.Flight `json:"flights,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	// ErrorClass sorts Error into one of the models.ProviderError classes.
+	ErrorClass string `json:"error_class,omitempty"`
+	// Retries counts the calls repeated after a failure, ElapsedMs the
+	// time spent on the provider, rate limit and retry waits included.
+	Retries   int   `json:"retries,omitempty"`
+	ElapsedMs int64 `json:"elapsed_ms,omitempty"`
+	// FallbackFor is the failed provider these flights stand in for.
+	FallbackFor string `json:"fallback_for,omitempty"`
 }
 
 func NewAggregator(providerList []providers.Provider, config Config) *Aggregator {
-	return &Aggregator{
+	a := &Aggregator{
 		providers: providerList,
 		config:    config,
+		pool:      newCallPool(config.MaxConcurrentCalls),
+		standby:   make(map[string]bool, len(config.Standby)),
+	}
+	for _, name := range config.Standby {
+		a.standby[name] = true
+	}
+	if config.ProviderRetryRatio > 0 {
+		a.retryBudgets = make(map[string]*providerRetryBudget, len(providerList))
+		for _, p := range providerList {
+			a.retryBudgets[p.Name()] = newProviderRetryBudget(config.ProviderRetryRatio)
+		}
 	}
+	return a
+}
+
+// ProviderCap is the configured per-provider result cap, zero if none.
+func (a *Aggregator) ProviderCap() int {
+	return a.config.ProviderCap
+}
+
+// CallPoolStats reports the provider call pool, false if calls are
+// unbounded.
+func (a *Aggregator) CallPoolStats() (PoolStats, bool) {
+	if a.pool == nil {
+		return PoolStats{}, false
+	}
+	return a.pool.stats(), true
+}
+
+func (a *Aggregator) Providers() []providers.Provider {
+	return a.providers
+}
+
+func (a *Aggregator) Provider(name string) (providers.Provider, bool) {
+	for _, p := range a.providers {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// SetDisabled takes providers out of rotation: searches and fallbacks
+// skip them, even when named, until a later call leaves them out.
+func (a *Aggregator) SetDisabled(names []string) {
+	disabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		disabled[name] = true
+	}
+	a.disabled.Store(&disabled)
+}
+
+func (a *Aggregator) Disabled(name string) bool {
+	disabled := a.disabled.Load()
+	return disabled != nil && (*disabled)[name]
+}
+
+// Selected returns the providers a search for req queries: those it names
+// or doesn't exclude, leaving out standby and disabled providers.
+func (a *Aggregator) Selected(req models.SearchRequest) []providers.Provider {
+	selected := make([]providers.Provider, 0, len(a.providers))
+	for _, p := range a.providers {
+		if req.QueriesProvider(p.Name()) && !a.reserved(p.Name(), req) && !a.Disabled(p.Name()) {
+			selected = append(selected, p)
+		}
+	}
+	return selected
+}
+
+func (a *Aggregator) AddObserver(o Observer) {
+	a.observers = append(a.observers, o)
 }
 
+// Search queries every selected provider at once, until ctx's deadline
+// at the latest. It fails when none answers, with ErrTimeout,
+// ErrRateLimited or ErrAllProvidersFailed, or when the partial results
+// policy rules out the answers, with ErrPartialResults; either is a
+// *SearchError. It fails with ErrInvalidRoute before querying any.
 func (a *Aggregator) Search(ctx context.Context, req models.SearchRequest) (*Result, error) {
-	searchCtx, cancel := context.WithTimeout(ctx, a.config.Timeout)
+	if err := checkRoute(req); err != nil {
+		return nil, err
+	}
+	searchCtx, cancel := withDeadline(ctx, a.config.Timeout)
 	defer cancel()
 
+	selected := a.Selected(req)
+	direct := make(map[string]bool, len(selected))
+	for _, p := range selected {
+		direct[p.Name()] = true
+	}
+
 	result := &Result{
 		Flights:          make([]models.Flight, 0),
-		ProvidersQueried: len(a.providers),
+		ProvidersQueried: len(selected),
 	}
 
 	type providerResult struct {
 		provider string
 		flights  []models.Flight
 		err      error
+		retries  int
+		elapsed  time.Duration
+		// fallback answered covered in place of provider.
+		fallback Fallback
+		covered  []models.Flight
 	}
 
-	resultCh := make(chan providerResult, len(a.providers))
+	resultCh := make(chan providerResult, len(selected))
+	budget := newRetryBudget(a.config.RetryBudget)
+	calls := newFallbackCalls()
 	var wg sync.WaitGroup
 
-	for _, p := range a.providers {
+	for _, p := range selected {
 		wg.Add(1)
 		go func(provider providers.Provider) {
 			defer wg.Done()
 
-			if a.config.RateLimiter != nil {
-				if err := a.config.RateLimiter.Wait(searchCtx, provider.Name()); err != nil {
-					resultCh <- providerResult{
-						provider: provider.Name(),
-						err:      err,
-					}
-					return
+			start := time.Now()
+			flights, retries, err := a.query(searchCtx, provider, req, budget)
+			pr := providerResult{provider: provider.Name(), flights: flights, err: err, retries: retries, elapsed: time.Since(start)}
+			if err != nil && needsFallback(err) && searchCtx.Err() == nil {
+				if fb, covered, ok := a.fallback(searchCtx, provider.Name(), req, budget, calls, direct); ok {
+					pr.fallback, pr.covered = fb, covered
 				}
 			}
-
-			flights, err := a.searchWithRetry(searchCtx, provider, req)
-			resultCh <- providerResult{
-				provider: provider.Name(),
-				flights:  flights,
-				err:      err,
-			}
+			resultCh <- pr
 		}(p)
 	}
 
@@ -85,59 +253,370 @@ func (a *Aggregator) Search(ctx context.Context, req models.SearchRequest) (*Res
 		close(resultCh)
 	}()
 
-	var mu sync.Mutex
+	var errs []error
 	for pr := range resultCh {
 		if pr.err != nil {
-			log.Printf("Provider %s failed: %v", pr.provider, pr.err)
-			mu.Lock()
+			reqctx.Logf(ctx, "Provider %s failed: %v", pr.provider, pr.err)
+			errs = append(errs, pr.err)
 			result.ProvidersFailed++
 			result.FailedProviders = append(result.FailedProviders, pr.provider)
-			mu.Unlock()
-		} else {
-			mu.Lock()
-			result.ProvidersSucceeded++
-			result.Flights = append(result.Flights, pr.flights...)
-			mu.Unlock()
+			result.Responses = append(result.Responses, ProviderResponse{
+				Provider:   pr.provider,
+				Error:      pr.err.Error(),
+				ErrorClass: ErrorClass(pr.err),
+				Retries:    pr.retries,
+				ElapsedMs:  pr.elapsed.Milliseconds(),
+			})
+			if pr.fallback.Provider != "" {
+				if result.Fallbacks == nil {
+					result.Fallbacks = make(map[string]string)
+				}
+				result.Fallbacks[pr.provider] = pr.fallback.Provider
+				resp := ProviderResponse{Provider: pr.fallback.Provider, FallbackFor: pr.provider}
+				resp.Flights = a.annotateAll(ctx, pr.fallback.Provider, pr.covered, []models.Flight{})
+				result.Responses = append(result.Responses, resp)
+			}
+			continue
+		}
+		result.ProvidersSucceeded++
+		resp := ProviderResponse{Provider: pr.provider, Retries: pr.retries, ElapsedMs: pr.elapsed.Milliseconds()}
+		resp.Flights = a.annotateAll(ctx, pr.provider, pr.flights, nil)
+		result.Responses = append(result.Responses, resp)
+	}
+	result.Flights, result.Truncated = a.Merge(result.Responses, a.config.ProviderCap, a.config.Merge)
+
+	// Observers track prices and availability, so they see every flight
+	// the providers returned, not just those within the cap.
+	observed := result
+	if result.Truncated != nil {
+		uncapped := *result
+		uncapped.Flights = nil
+		for _, r := range result.Responses {
+			uncapped.Flights = append(uncapped.Flights, r.Flights...)
 		}
+		observed = &uncapped
+	}
+	for _, o := range a.observers {
+		o.ObserveSearch(ctx, req, observed)
 	}
 
+	answered := result.ProvidersSucceeded + len(result.Fallbacks)
+	needed := min(a.config.MinSucceeded, len(selected))
+	switch {
+	case len(selected) == 0:
+	case answered == 0:
+		return nil, &SearchError{Result: result, Err: failure(result.FailedProviders, errs)}
+	case answered < needed:
+		return nil, &SearchError{Result: result, Err: partialFailure(fmt.Sprintf("%d of %d providers answered, %d needed", answered, len(selected), needed), result.FailedProviders, errs)}
+	case a.config.FailEmptyPartial && len(result.Flights) == 0 && result.ProvidersFailed > 0:
+		return nil, &SearchError{Result: result, Err: partialFailure(fmt.Sprintf("no flights found and %d of %d providers failed", result.ProvidersFailed, len(selected)), result.FailedProviders, errs)}
+	}
 	return result, nil
 }
 
-func (a *Aggregator) searchWithRetry(ctx context.Context, provider providers.Provider, req models.SearchRequest) ([]models.Flight, error) {
+// answerReserve is kept back from a caller's deadline to answer with
+// what the providers returned by then.
+const answerReserve = 100 * time.Millisecond
+
+// withDeadline bounds a search by timeout, ending it answerReserve before
+// ctx's deadline when that comes first, so a caller out of time still
+// gets the answers in hand rather than a dropped connection.
+func withDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Add(-answerReserve).Before(deadline) {
+		deadline = d.Add(-answerReserve)
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+func (a *Aggregator) FindFlight(ctx context.Context, req models.SearchRequest, id string) (models.Flight, error) {
+	result, err := a.Search(ctx, req)
+	if err != nil {
+		return models.Flight{}, err
+	}
+
+	for _, f := range result.Flights {
+		if f.ID == id {
+			return f, nil
+		}
+	}
+	return models.Flight{}, errorsx.Errorf(errorsx.NotFound, "flight %s: %w for the given route and date", id, ErrFlightNotFound)
+}
+
+// VerifyFlight searches provider alone, the one a flight came from, and
+// returns the flight as it prices it now. The provider failing is an error
+// rather than a missing flight, and a fallback's flights never count.
+func (a *Aggregator) VerifyFlight(ctx context.Context, req models.SearchRequest, provider, id string) (models.Flight, error) {
+	req.Providers = []string{provider}
+	result, err := a.Search(ctx, req)
+	if err != nil {
+		return models.Flight{}, err
+	}
+
+	for _, r := range result.Responses {
+		if r.Provider != provider || r.FallbackFor != "" {
+			continue
+		}
+		if r.Error != "" {
+			return models.Flight{}, errorsx.Errorf(errorsx.Unavailable, "provider %s: %s", provider, r.Error)
+		}
+		for _, f := range r.Flights {
+			if f.ID == id {
+				return f, nil
+			}
+		}
+		return models.Flight{}, errorsx.Errorf(errorsx.NotFound, "flight %s: %w for the given route and date", id, ErrFlightNotFound)
+	}
+	return models.Flight{}, errorsx.Errorf(errorsx.Unavailable, "provider %s is not being searched", provider)
+}
+
+// query searches provider unless its circuit is open, waiting for its rate
+// limit before each call. A wait that can't end before ctx does is
+// throttled. A nearby search calls provider once per route and fails only
+// if every route does. Retries are counted across routes.
+func (a *Aggregator) query(ctx context.Context, provider providers.Provider, req models.SearchRequest, budget *retryBudget) ([]models.Flight, int, error) {
+	if a.config.Health != nil && !a.config.Health.Allow(provider.Name()) {
+		return nil, 0, health.ErrCircuitOpen
+	}
+
+	var flights []models.Flight
 	var lastErr error
+	answered := false
+	retried := 0
+	for _, route := range routes(req) {
+		if a.config.RateLimiter != nil {
+			if err := a.config.RateLimiter.Wait(ctx, provider.Name()); err != nil {
+				if ctx.Err() == nil {
+					err = throttled{errorsx.Classify(err, errorsx.RateLimited)}
+				}
+				lastErr = err
+				break
+			}
+		}
+		found, n, err := a.searchWithRetry(ctx, provider, route, budget)
+		retried += n
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		flights = append(flights, found...)
+		answered = true
+	}
+	if !answered {
+		return nil, retried, lastErr
+	}
+	return flights, retried, nil
+}
+
+// maxNearbyAirports caps the airports added to each end of a nearby
+// search: every one multiplies the provider calls.
+const maxNearbyAirports = 2
+
+// routes are the searches req covers: itself, or with NearbyKm every
+// pairing of its origin and destination with their closest neighbours.
+func routes(req models.SearchRequest) []models.SearchRequest {
+	if req.NearbyKm <= 0 {
+		return []models.SearchRequest{req}
+	}
+	origins := withNearby(req.Origin, req.NearbyKm)
+	destinations := withNearby(req.Destination, req.NearbyKm)
+	out := make([]models.SearchRequest, 0, len(origins)*len(destinations))
+	for _, o := range origins {
+		for _, d := range destinations {
+			if o == d {
+				continue
+			}
+			r := req
+			r.Origin, r.Destination = o, d
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func withNearby(code string, km int) []string {
+	codes := []string{code}
+	for _, a := range airports.Nearby(code, float64(km)) {
+		if len(codes) > maxNearbyAirports {
+			break
+		}
+		codes = append(codes, a.IATA)
+	}
+	return codes
+}
+
+func (a *Aggregator) searchWithRetry(ctx context.Context, provider providers.Provider, req models.SearchRequest, budget *retryBudget) ([]models.Flight, int, error) {
+	var lastErr error
+	calls := 0
+	providerBudget := a.retryBudgets[provider.Name()]
+	providerBudget.deposit()
 
 	for attempt := 0; attempt <= a.config.MaxRetries; attempt++ {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return nil, retries(calls), ctx.Err()
 		default:
 		}
 
 		if attempt > 0 {
-			delayIdx := attempt - 1
-			if delayIdx >= len(a.config.RetryDelays) {
-				delayIdx = len(a.config.RetryDelays) - 1
+			if !budget.take(a.promising(provider.Name())) {
+				reqctx.Logf(ctx, "Provider %s: retry budget exhausted after %d attempts", provider.Name(), attempt)
+				return nil, retries(calls), lastErr
+			}
+			if !providerBudget.take() {
+				reqctx.Logf(ctx, "Provider %s: provider retry budget exhausted after %d attempts", provider.Name(), attempt)
+				return nil, retries(calls), lastErr
 			}
-			delay := a.config.RetryDelays[delayIdx]
 
 			select {
-			case <-time.After(delay):
+			case <-time.After(a.backoff(attempt)):
 			case <-ctx.Done():
-				return nil, ctx.Err()
+				return nil, retries(calls), ctx.Err()
 			}
 		}
 
-		flights, err := provider.Search(ctx, req)
+		if err := a.pool.acquire(ctx); err != nil {
+			reqctx.Logf(ctx, "Provider %s: no call slot before the search timed out", provider.Name())
+			return nil, retries(calls), err
+		}
+		start := time.Now()
+		calls++
+		flights, err := a.call(ctx, provider, req)
+		a.pool.release()
+		a.recordHealth(provider.Name(), err, time.Since(start))
 		if err == nil {
-			return flights, nil
+			return flights, retries(calls), nil
 		}
 
 		lastErr = err
-		log.Printf("Provider %s attempt %d failed: %v", provider.Name(), attempt+1, err)
+		reqctx.Logf(ctx, "Provider %s attempt %d failed: %v", provider.Name(), attempt+1, err)
+		if !errorsx.Retryable(err) {
+			return nil, retries(calls), err
+		}
+	}
+
+	return nil, retries(calls), lastErr
+}
+
+// retries is how many of calls repeated a failed one.
+func retries(calls int) int {
+	return max(calls-1, 0)
+}
+
+// call searches provider, with any fault injected for it. Injected
+// failures and latency count towards the provider's health like real ones.
+func (a *Aggregator) call(ctx context.Context, provider providers.Provider, req models.SearchRequest) ([]models.Flight, error) {
+	if a.config.Faults != nil {
+		empty, err := a.config.Faults.Inject(ctx, provider.Name())
+		if err != nil {
+			return nil, err
+		}
+		if empty {
+			reqctx.Logf(ctx, "Provider %s: injected empty result", provider.Name())
+			return []models.Flight{}, nil
+		}
+	}
+	return provider.Search(ctx, req)
+}
+
+// backoff is the wait before the given retry (1 for the first): the step
+// doubles from RetryBaseDelay up to RetryMaxDelay, and the wait is drawn
+// from its upper half so concurrent searches don't retry in lockstep.
+func (a *Aggregator) backoff(attempt int) time.Duration {
+	step := a.config.RetryBaseDelay
+	for i := 1; i < attempt && step < a.config.RetryMaxDelay; i++ {
+		step *= 2
+	}
+	if a.config.RetryMaxDelay > 0 && step > a.config.RetryMaxDelay {
+		step = a.config.RetryMaxDelay
+	}
+	if step <= 0 {
+		return 0
 	}
+	return step/2 + time.Duration(rand.Int64N(int64(step/2)+1))
+}
+
+// annotateAll appends provider's flights to into, annotated, and logs how
+// many durations the provider reported differently from its times.
+func (a *Aggregator) annotateAll(ctx context.Context, provider string, flights, into []models.Flight) []models.Flight {
+	recomputed := 0
+	for _, f := range flights {
+		f = a.annotate(f)
+		if f.Duration.ReportedMinutes != f.Duration.TotalMinutes {
+			recomputed++
+		}
+		into = append(into, f)
+	}
+	if recomputed > 0 {
+		reqctx.Logf(ctx, "Provider %s: %d of %d durations disagreed with the departure and arrival times and were recomputed", provider, recomputed, len(flights))
+	}
+	return into
+}
 
-	return nil, lastErr
+// annotate fills in fields derived from the provider data the same way for
+// every provider.
+func (a *Aggregator) annotate(f models.Flight) models.Flight {
+	f.Duration = TripDuration(f)
+	f.ArrivesNextDay = arrivesNextDay(f)
+	if f.Airline.Name == "" {
+		f.Airline.Name, _ = refdata.AirlineName(f.Airline.Code)
+	}
+	if f.Aircraft != nil {
+		if e, ok := aircraft.Normalize(*f.Aircraft); ok {
+			code := e.ICAO
+			f.AircraftCode = &code
+		}
+	}
+	f.DistanceKm, _ = emissions.DistanceKm(f)
+	f.EmissionsKgCO2 = emissions.EstimatePerPassenger(f)
+	f.Reliability = ontime.Reliability(a.config.OnTime, f)
+	return mct.Rate(f)
+}
+
+// DurationTolerance absorbs providers rounding their durations.
+const DurationTolerance = 1
+
+// TripDuration recomputes the duration from the departure and arrival
+// times, which always span the layovers, so every provider's durations
+// compare fairly. The provider's figure is kept as ReportedMinutes.
+func TripDuration(f models.Flight) models.Duration {
+	reported := f.Duration.TotalMinutes
+	total := int(f.Arrival.Time.Sub(f.Departure.Time).Minutes())
+	if f.Departure.Time.IsZero() || f.Arrival.Time.IsZero() || total <= 0 || abs(total-reported) <= DurationTolerance {
+		total = reported
+	}
+	return models.Duration{
+		Hours:           total / 60,
+		Minutes:         total % 60,
+		TotalMinutes:    total,
+		ReportedMinutes: reported,
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func arrivesNextDay(f models.Flight) bool {
+	dep := timezone.ConvertToTimezone(f.Departure.Time, f.Departure.Airport)
+	arr := timezone.ConvertToTimezone(f.Arrival.Time, f.Arrival.Airport)
+	depDay := time.Date(dep.Year(), dep.Month(), dep.Day(), 0, 0, 0, 0, time.UTC)
+	arrDay := time.Date(arr.Year(), arr.Month(), arr.Day(), 0, 0, 0, 0, time.UTC)
+	return arrDay.After(depDay)
+}
+
+func (a *Aggregator) promising(provider string) bool {
+	return a.config.Health == nil || !a.config.Health.Degraded(provider)
+}
+
+func (a *Aggregator) recordHealth(provider string, err error, latency time.Duration) {
+	if a.config.Health == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	a.config.Health.Record(provider, err, latency)
 }
 
 func (a *Aggregator) SearchRoundTrip(ctx context.Context, req models.SearchRequest) (*Result, *Result, error) {
@@ -146,7 +625,7 @@ func (a *Aggregator) SearchRoundTrip(ctx context.Context, req models.SearchReque
 		return outbound, nil, err
 	}
 
-	searchCtx, cancel := context.WithTimeout(ctx, a.config.Timeout*2)
+	searchCtx, cancel := withDeadline(ctx, a.config.Timeout*2)
 	defer cancel()
 
 	type searchResult struct {
@@ -172,6 +651,8 @@ func (a *Aggregator) SearchRoundTrip(ctx context.Context, req models.SearchReque
 			Filters:       req.Filters,
 			SortBy:        req.SortBy,
 			SortOrder:     req.SortOrder,
+			Providers:     req.Providers,
+			NearbyKm:      req.NearbyKm,
 		}
 		result, err := a.Search(searchCtx, returnReq)
 		resultCh <- searchResult{result: result, err: err, isReturn: true}
@@ -196,7 +677,7 @@ func (a *Aggregator) SearchRoundTrip(ctx context.Context, req models.SearchReque
 	}
 
 	if returnErr != nil {
-		log.Printf("Return flight search failed: %v", returnErr)
+		reqctx.Logf(ctx, "Return flight search failed: %v", returnErr)
 		return outbound, nil, nil
 	}
 
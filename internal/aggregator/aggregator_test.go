@@ -0,0 +1,78 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/health"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/ratelimit"
+)
+
+type stubProvider struct {
+	name  string
+	err   error
+	calls atomic.Int32
+}
+
+func (p *stubProvider) Name() string { return p.name }
+
+func (p *stubProvider) Search(ctx context.Context, req models.SearchRequest) ([]models.Flight, error) {
+	p.calls.Add(1)
+	return nil, p.err
+}
+
+// failingLimiter refuses every wait while fail is set.
+type failingLimiter struct {
+	fail atomic.Bool
+}
+
+func (l *failingLimiter) Wait(ctx context.Context, provider string) error {
+	if l.fail.Load() {
+		return ratelimit.ErrWaitExceedsDeadline
+	}
+	return nil
+}
+
+func (l *failingLimiter) SetProviderLimit(string, float64, int) {}
+
+func (l *failingLimiter) Snapshot() []ratelimit.LimitStatus { return nil }
+
+func TestHalfOpenProbeLostToRateLimiter(t *testing.T) {
+	cfg := health.DefaultConfig()
+	cfg.MinRequests = 1
+	cfg.OpenDuration = 20 * time.Millisecond
+	tracker := health.NewTracker(cfg)
+	tracker.Record("garuda", errors.New("down"), time.Millisecond)
+
+	p := &stubProvider{name: "garuda"}
+	limiter := &failingLimiter{}
+	a := NewAggregator(nil, Config{RateLimiter: limiter, Health: tracker})
+	ctx := context.Background()
+	req := models.SearchRequest{Origin: "CGK", Destination: "DPS"}
+
+	// The probe is let through but never reaches the provider.
+	time.Sleep(cfg.OpenDuration)
+	limiter.fail.Store(true)
+	if _, _, err := a.query(ctx, p, req, nil); !errors.Is(err, ratelimit.ErrWaitExceedsDeadline) {
+		t.Fatalf("query = %v, want the rate limiter's error", err)
+	}
+	limiter.fail.Store(false)
+	if _, _, err := a.query(ctx, p, req, nil); !errors.Is(err, health.ErrCircuitOpen) {
+		t.Fatalf("query = %v while the probe is out, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(cfg.OpenDuration)
+	if _, _, err := a.query(ctx, p, req, nil); err != nil {
+		t.Fatalf("query = %v after the probe expired, want a new probe", err)
+	}
+	if p.calls.Load() != 1 {
+		t.Errorf("provider called %d times, want 1", p.calls.Load())
+	}
+	if got := tracker.Stats("garuda").State; got != health.StateClosed {
+		t.Errorf("state = %s, want closed", got)
+	}
+}
@@ -0,0 +1,80 @@
+package aggregator
+
+import "sync"
+
+// retryBudget is shared by all providers in a single search so retries
+// don't multiply with the number of providers. Providers that are currently
+// degraded may only draw from the top half of the budget, leaving the rest
+// for providers that are likely to succeed on a retry.
+type retryBudget struct {
+	mu        sync.Mutex
+	total     int
+	remaining int
+}
+
+func newRetryBudget(total int) *retryBudget {
+	if total <= 0 {
+		return nil
+	}
+	return &retryBudget{total: total, remaining: total}
+}
+
+func (b *retryBudget) take(promising bool) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.remaining == 0 {
+		return false
+	}
+	if !promising && b.remaining*2 <= b.total {
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+const (
+	// providerRetryBurst is the most retries a provider can bank, and what
+	// it starts with.
+	providerRetryBurst = 10
+)
+
+// providerRetryBudget limits one provider's retries across searches to a
+// fraction of its searches: each search deposits ratio tokens and each
+// retry withdraws one, so a struggling provider isn't hit by a retry storm
+// from every concurrent search.
+type providerRetryBudget struct {
+	mu      sync.Mutex
+	ratio   float64
+	balance float64
+}
+
+func newProviderRetryBudget(ratio float64) *providerRetryBudget {
+	return &providerRetryBudget{ratio: ratio, balance: providerRetryBurst}
+}
+
+func (b *providerRetryBudget) deposit() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.balance = min(b.balance+b.ratio, providerRetryBurst)
+}
+
+func (b *providerRetryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.balance < 1 {
+		return false
+	}
+	b.balance--
+	return true
+}
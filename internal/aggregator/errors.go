@@ -0,0 +1,134 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/dharmasatrya/flightsearch/internal/airports"
+	"github.com/dharmasatrya/flightsearch/internal/health"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// throttled marks a call this service held back to keep within the
+// provider's rate limit, as opposed to the provider refusing it.
+type throttled struct {
+	err error
+}
+
+func (e throttled) Error() string {
+	return e.err.Error()
+}
+
+func (e throttled) Unwrap() error {
+	return e.err
+}
+
+func (e throttled) ErrorKind() errorsx.Kind {
+	return errorsx.RateLimited
+}
+
+// ErrorClass sorts a provider's failure into a models.ProviderError class.
+// Everything the provider answered with, its own rate limiting included,
+// is an upstream error.
+func ErrorClass(err error) string {
+	var t throttled
+	var netErr net.Error
+	switch {
+	case errors.As(err, &t):
+		return models.ProviderRateLimited
+	case errors.Is(err, health.ErrCircuitOpen):
+		return models.ProviderCircuitOpen
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return models.ProviderTimeout
+	}
+	return models.ProviderUpstreamError
+}
+
+// The ways a search fails as a whole. Each carries its error code, so
+// responses tell them apart from the operation failing.
+var (
+	// ErrAllProvidersFailed is every provider searched failing, for
+	// different reasons or none of those below.
+	ErrAllProvidersFailed error = &errorsx.Error{Kind: errorsx.Unavailable, Code: types.CodeAllProvidersFailed, Message: "all providers failed"}
+	// ErrTimeout is every provider failing to answer in time.
+	ErrTimeout error = &errorsx.Error{Kind: errorsx.Timeout, Code: types.CodeTimeout, Message: "no provider answered in time"}
+	// ErrRateLimited is every provider being rate limited, by this service
+	// or by the provider.
+	ErrRateLimited error = &errorsx.Error{Kind: errorsx.RateLimited, Code: types.CodeRateLimited, Message: "every provider is rate limited"}
+	// ErrPartialResults is a search some providers answered, but too few
+	// for Config.MinSucceeded or Config.FailEmptyPartial.
+	ErrPartialResults error = &errorsx.Error{Kind: errorsx.Unavailable, Code: types.CodePartialResults, Message: "partial results"}
+	// ErrInvalidRoute is a route no flight can serve, such as between two
+	// airports of the same city.
+	ErrInvalidRoute error = &errorsx.Error{Kind: errorsx.Unprocessable, Code: types.CodeInvalidRoute, Message: "invalid route"}
+)
+
+// SearchError is a search failing as a whole, with what every provider
+// answered.
+type SearchError struct {
+	Result *Result
+	Err    error
+}
+
+func (e *SearchError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SearchError) Unwrap() error {
+	return e.Err
+}
+
+// checkRoute rejects searches between airports serving the same city.
+func checkRoute(req models.SearchRequest) error {
+	from, ok := airports.Lookup(req.Origin)
+	if !ok {
+		return nil
+	}
+	to, ok := airports.Lookup(req.Destination)
+	if !ok || from.City == "" || from.City != to.City || from.Country != to.Country {
+		return nil
+	}
+	return fmt.Errorf("%w: %s and %s both serve %s", ErrInvalidRoute, from.IATA, to.IATA, from.City)
+}
+
+// failure is the error for a search none of whose providers answered,
+// given each one's error by provider, in the order they failed.
+func failure(providers []string, errs []error) error {
+	sentinel := ErrAllProvidersFailed
+	switch {
+	case every(errs, func(err error) bool { return ErrorClass(err) == models.ProviderTimeout }):
+		sentinel = ErrTimeout
+	case every(errs, func(err error) bool { return errorsx.KindOf(err) == errorsx.RateLimited }):
+		sentinel = ErrRateLimited
+	}
+	return fmt.Errorf("%w: %s", sentinel, describe(providers, errs))
+}
+
+// partialFailure is ErrPartialResults for reason, listing the providers
+// that failed.
+func partialFailure(reason string, providers []string, errs []error) error {
+	return fmt.Errorf("%w: %s: %s", ErrPartialResults, reason, describe(providers, errs))
+}
+
+func describe(providers []string, errs []error) string {
+	details := make([]string, len(errs))
+	for i, err := range errs {
+		details[i] = providers[i] + ": " + err.Error()
+	}
+	return strings.Join(details, "; ")
+}
+
+func every(errs []error, pred func(error) bool) bool {
+	for _, err := range errs {
+		if !pred(err) {
+			return false
+		}
+	}
+	return true
+}
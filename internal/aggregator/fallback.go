@@ -0,0 +1,126 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/dharmasatrya/flightsearch/internal/health"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+// Fallback is a provider searched in place of another whose quota is spent
+// or whose circuit is open, e.g. a GDS standing in for an airline's own
+// API. Only its flights of Airlines stand in for the other provider's.
+type Fallback struct {
+	Provider string
+	// Airlines are IATA codes; empty keeps every flight.
+	Airlines []string
+}
+
+// String formats f as configured, e.g. "amadeus:GA".
+func (f Fallback) String() string {
+	if len(f.Airlines) == 0 {
+		return f.Provider
+	}
+	return f.Provider + ":" + strings.Join(f.Airlines, "/")
+}
+
+func (f Fallback) keep(flights []models.Flight, primary string) []models.Flight {
+	kept := make([]models.Flight, 0, len(flights))
+	for _, fl := range flights {
+		if len(f.Airlines) > 0 && !containsFold(f.Airlines, fl.Airline.Code) {
+			continue
+		}
+		fl.FallbackFor = primary
+		kept = append(kept, fl)
+	}
+	return kept
+}
+
+// needsFallback reports whether a provider's failure is one its fallbacks
+// cover. Other failures are left to retries until the circuit opens.
+func needsFallback(err error) bool {
+	return errors.Is(err, health.ErrCircuitOpen) || errorsx.KindOf(err) == errorsx.RateLimited
+}
+
+// fallbackCalls shares each fallback provider's answer between the
+// providers it stands in for in one search, so it is called once however
+// many of them are down.
+type fallbackCalls struct {
+	mu    sync.Mutex
+	calls map[string]*fallbackCall
+}
+
+type fallbackCall struct {
+	done    chan struct{}
+	flights []models.Flight
+	err     error
+}
+
+func newFallbackCalls() *fallbackCalls {
+	return &fallbackCalls{calls: make(map[string]*fallbackCall)}
+}
+
+func (c *fallbackCalls) do(provider string, fn func() ([]models.Flight, error)) ([]models.Flight, error) {
+	c.mu.Lock()
+	call, ok := c.calls[provider]
+	if !ok {
+		call = &fallbackCall{done: make(chan struct{})}
+		c.calls[provider] = call
+	}
+	c.mu.Unlock()
+
+	if ok {
+		<-call.done
+	} else {
+		call.flights, call.err = fn()
+		close(call.done)
+	}
+	return call.flights, call.err
+}
+
+// fallback searches primary's fallbacks in order until one answers.
+// Fallbacks the search already queries directly are skipped, their flights
+// being in the results anyway, as are those it excludes and those out of
+// rotation.
+func (a *Aggregator) fallback(ctx context.Context, primary string, req models.SearchRequest, budget *retryBudget, calls *fallbackCalls, direct map[string]bool) (Fallback, []models.Flight, bool) {
+	for _, fb := range a.config.Fallbacks[primary] {
+		if direct[fb.Provider] || a.Disabled(fb.Provider) || (req.Filters != nil && containsFold(req.Filters.ExcludeProviders, fb.Provider)) {
+			continue
+		}
+		provider, ok := a.Provider(fb.Provider)
+		if !ok {
+			continue
+		}
+		flights, err := calls.do(fb.Provider, func() ([]models.Flight, error) {
+			flights, _, err := a.query(ctx, provider, req, budget)
+			return flights, err
+		})
+		if err != nil {
+			reqctx.Logf(ctx, "Provider %s: fallback %s failed: %v", primary, fb.Provider, err)
+			continue
+		}
+		reqctx.Logf(ctx, "Provider %s: searched %s instead", primary, fb)
+		return fb, fb.keep(flights, primary), true
+	}
+	return Fallback{}, nil, false
+}
+
+// reserved reports whether req leaves standby provider name to fallbacks.
+func (a *Aggregator) reserved(name string, req models.SearchRequest) bool {
+	if !a.standby[name] {
+		return false
+	}
+	return !containsFold(req.Providers, name)
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool {
+		return strings.EqualFold(v, s)
+	})
+}
@@ -0,0 +1,112 @@
+package aggregator
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Merge strategies, set in Config.Merge.
+const (
+	// MergeArrival appends each provider's flights as the provider answers.
+	MergeArrival = "arrival"
+	// MergeRoundRobin takes one flight from each provider in turn, each
+	// provider's cheapest first, so no single source leads the list.
+	MergeRoundRobin = "round_robin"
+)
+
+var MergeStrategies = []string{MergeArrival, MergeRoundRobin}
+
+// Merge combines the flights of the providers that answered, capping each
+// at limit (zero for no cap) and merging per strategy. It returns how many
+// flights each capped provider lost. Searches merge with the configured
+// cap and strategy; replays pass the ones recorded with the search.
+func (a *Aggregator) Merge(responses []ProviderResponse, limit int, strategy string) ([]models.Flight, map[string]int) {
+	var answered []ProviderResponse
+	for _, r := range responses {
+		if r.Error == "" {
+			answered = append(answered, r)
+		}
+	}
+
+	roundRobin := strategy == MergeRoundRobin
+	if roundRobin {
+		// Registration order, so the same results always merge the same way.
+		answered = a.registrationOrder(answered)
+	}
+
+	var truncated map[string]int
+	lists := make([][]models.Flight, 0, len(answered))
+	for _, r := range answered {
+		flights := r.Flights
+		if limit > 0 && len(flights) > limit {
+			// The cap keeps a provider's cheapest flights.
+			flights = cheapestFirst(flights)[:limit]
+			if truncated == nil {
+				truncated = make(map[string]int)
+			}
+			truncated[r.Provider] += len(r.Flights) - limit
+		} else if roundRobin {
+			flights = cheapestFirst(flights)
+		}
+		lists = append(lists, flights)
+	}
+
+	merged := make([]models.Flight, 0)
+	if !roundRobin {
+		for _, flights := range lists {
+			merged = append(merged, flights...)
+		}
+		return merged, truncated
+	}
+	for i := 0; ; i++ {
+		added := false
+		for _, flights := range lists {
+			if i < len(flights) {
+				merged = append(merged, flights[i])
+				added = true
+			}
+		}
+		if !added {
+			return merged, truncated
+		}
+	}
+}
+
+// registrationOrder sorts responses by their providers' registration. A
+// fallback's flights take the place of the provider they stand in for.
+func (a *Aggregator) registrationOrder(responses []ProviderResponse) []ProviderResponse {
+	rank := make(map[string]int, len(a.providers))
+	for i, p := range a.providers {
+		rank[p.Name()] = i
+	}
+	position := func(r ProviderResponse) int {
+		if i, ok := rank[r.slot()]; ok {
+			return i
+		}
+		return len(rank)
+	}
+	ordered := slices.Clone(responses)
+	slices.SortStableFunc(ordered, func(x, y ProviderResponse) int {
+		return cmp.Compare(position(x), position(y))
+	})
+	return ordered
+}
+
+// slot is the provider whose place r's flights take in the results.
+func (r ProviderResponse) slot() string {
+	if r.FallbackFor != "" {
+		return r.FallbackFor
+	}
+	return r.Provider
+}
+
+func cheapestFirst(flights []models.Flight) []models.Flight {
+	sorted := append([]models.Flight(nil), flights...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Price.Amount < sorted[j].Price.Amount
+	})
+	return sorted
+}
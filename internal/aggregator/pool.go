@@ -0,0 +1,106 @@
+package aggregator
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+type PoolStats struct {
+	// Capacity is the most provider calls in flight at once, across all
+	// searches.
+	Capacity int   `json:"capacity"`
+	InFlight int   `json:"in_flight"`
+	Waiting  int64 `json:"waiting"`
+	// Calls counts calls started since startup, Queued those that waited
+	// for a slot, and TimedOut those whose search ended while waiting.
+	Calls     int64   `json:"calls"`
+	Queued    int64   `json:"queued"`
+	TimedOut  int64   `json:"timed_out"`
+	AvgWaitMs float64 `json:"avg_wait_ms"`
+	MaxWaitMs float64 `json:"max_wait_ms"`
+}
+
+// callPool bounds the provider calls in flight across all searches, so a
+// burst of searches queues for slots instead of multiplying outbound
+// connections. A nil pool is unbounded.
+type callPool struct {
+	slots chan struct{}
+
+	waiting  atomic.Int64
+	calls    atomic.Int64
+	queued   atomic.Int64
+	timedOut atomic.Int64
+	waitNs   atomic.Int64
+	maxWait  atomic.Int64
+}
+
+func newCallPool(size int) *callPool {
+	if size <= 0 {
+		return nil
+	}
+	return &callPool{slots: make(chan struct{}, size)}
+}
+
+// acquire waits for a free slot until ctx ends. Callers release the slot
+// when the call returns.
+func (p *callPool) acquire(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		p.calls.Add(1)
+		return nil
+	default:
+	}
+
+	start := time.Now()
+	p.waiting.Add(1)
+	defer p.waiting.Add(-1)
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		p.timedOut.Add(1)
+		return ctx.Err()
+	}
+
+	wait := int64(time.Since(start))
+	p.calls.Add(1)
+	p.queued.Add(1)
+	p.waitNs.Add(wait)
+	for {
+		prev := p.maxWait.Load()
+		if wait <= prev || p.maxWait.CompareAndSwap(prev, wait) {
+			break
+		}
+	}
+	return nil
+}
+
+func (p *callPool) release() {
+	if p == nil {
+		return
+	}
+	<-p.slots
+}
+
+func (p *callPool) stats() PoolStats {
+	s := PoolStats{
+		Capacity:  cap(p.slots),
+		InFlight:  len(p.slots),
+		Waiting:   p.waiting.Load(),
+		Calls:     p.calls.Load(),
+		Queued:    p.queued.Load(),
+		TimedOut:  p.timedOut.Load(),
+		MaxWaitMs: float64(p.maxWait.Load()) / float64(time.Millisecond),
+	}
+	// The average is over all calls, so it reflects the wait a typical
+	// call sees, including those that found a free slot.
+	if s.Calls > 0 {
+		s.AvgWaitMs = float64(p.waitNs.Load()) / float64(s.Calls) / float64(time.Millisecond)
+	}
+	return s
+}
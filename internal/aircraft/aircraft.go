@@ -0,0 +1,115 @@
+package aircraft
+
+import "strings"
+
+type Category string
+
+const (
+	Narrowbody  Category = "narrowbody"
+	Widebody    Category = "widebody"
+	RegionalJet Category = "regional_jet"
+	Turboprop   Category = "turboprop"
+)
+
+type Equipment struct {
+	ICAO     string
+	Name     string
+	Category Category
+}
+
+var equipment = map[string]Equipment{
+	"B738": {"B738", "Boeing 737-800", Narrowbody},
+	"B739": {"B739", "Boeing 737-900", Narrowbody},
+	"B38M": {"B38M", "Boeing 737 MAX 8", Narrowbody},
+	"A320": {"A320", "Airbus A320", Narrowbody},
+	"A20N": {"A20N", "Airbus A320neo", Narrowbody},
+	"A321": {"A321", "Airbus A321", Narrowbody},
+	"A21N": {"A21N", "Airbus A321neo", Narrowbody},
+	"A332": {"A332", "Airbus A330-200", Widebody},
+	"A333": {"A333", "Airbus A330-300", Widebody},
+	"A339": {"A339", "Airbus A330-900neo", Widebody},
+	"B77W": {"B77W", "Boeing 777-300ER", Widebody},
+	"B788": {"B788", "Boeing 787-8", Widebody},
+	"B789": {"B789", "Boeing 787-9", Widebody},
+	"CRJ9": {"CRJ9", "Bombardier CRJ-900", RegionalJet},
+	"CRJX": {"CRJX", "Bombardier CRJ-1000", RegionalJet},
+	"AT72": {"AT72", "ATR 72", Turboprop},
+	"AT76": {"AT76", "ATR 72-600", Turboprop},
+	"DH8D": {"DH8D", "De Havilland Dash 8-400", Turboprop},
+}
+
+// aliases maps compacted free-text names (see compact) to ICAO codes.
+var aliases = map[string]string{
+	"737800":     "B738",
+	"738":        "B738",
+	"737900":     "B739",
+	"737900ER":   "B739",
+	"739":        "B739",
+	"737MAX8":    "B38M",
+	"7M8":        "B38M",
+	"320":        "A320",
+	"A320CEO":    "A320",
+	"A320NEO":    "A20N",
+	"32N":        "A20N",
+	"321":        "A321",
+	"A321NEO":    "A21N",
+	"A330200":    "A332",
+	"A330300":    "A333",
+	"A330900":    "A339",
+	"A330900NEO": "A339",
+	"A330NEO":    "A339",
+	"777300ER":   "B77W",
+	"77W":        "B77W",
+	"7878":       "B788",
+	"787800":     "B788",
+	"7879":       "B789",
+	"787900":     "B789",
+	"CRJ900":     "CRJ9",
+	"CRJ1000":    "CRJX",
+	"ATR72":      "AT72",
+	"ATR72500":   "AT72",
+	"ATR72600":   "AT76",
+	"Q400":       "DH8D",
+	"DASH8400":   "DH8D",
+	"DHC8400":    "DH8D",
+}
+
+// Normalize maps a provider's free-text aircraft description ("B738",
+// "Boeing 737-800", "737-800") to a known equipment type.
+func Normalize(raw string) (Equipment, bool) {
+	key := compact(raw)
+	if key == "" {
+		return Equipment{}, false
+	}
+	if e, ok := equipment[key]; ok {
+		return e, true
+	}
+	if code, ok := aliases[key]; ok {
+		return equipment[code], true
+	}
+	if strings.HasPrefix(key, "B") {
+		if code, ok := aliases[key[1:]]; ok {
+			return equipment[code], true
+		}
+	}
+	return Equipment{}, false
+}
+
+// Matches reports whether the equipment satisfies a filter value, which
+// may be an ICAO code ("B738") or a category ("widebody").
+func (e Equipment) Matches(value string) bool {
+	return strings.EqualFold(e.ICAO, value) || strings.EqualFold(string(e.Category), value)
+}
+
+func compact(raw string) string {
+	s := strings.ToUpper(raw)
+	for _, prefix := range []string{"BOEING", "AIRBUS", "BOMBARDIER", "DE HAVILLAND"} {
+		s = strings.ReplaceAll(s, prefix, "")
+	}
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '_' || r == '/' {
+			return -1
+		}
+		return r
+	}, s)
+}
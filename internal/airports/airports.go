@@ -0,0 +1,216 @@
+// Package airports is the airport database: codes, names, cities,
+// coordinates and timezones. It ships with an embedded dataset, which
+// imported reference data replaces.
+package airports
+
+import (
+	"bytes"
+	"cmp"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+type Airport struct {
+	IATA    string `json:"iata"`
+	ICAO    string `json:"icao,omitempty"`
+	Name    string `json:"name,omitempty"`
+	City    string `json:"city,omitempty"`
+	Country string `json:"country,omitempty"`
+	// Latitude and Longitude are in degrees; both zero when unknown.
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+	// Timezone is an IANA zone name, e.g. "Asia/Makassar".
+	Timezone string `json:"timezone"`
+}
+
+// HasLocation reports whether the airport's coordinates are known.
+func (a Airport) HasLocation() bool {
+	return a.Latitude != 0 || a.Longitude != 0
+}
+
+//go:embed airports.csv
+var airportsCSV []byte
+
+var (
+	builtin map[string]Airport
+	active  atomic.Pointer[map[string]Airport]
+)
+
+func init() {
+	var err error
+	if builtin, err = parse(airportsCSV); err != nil {
+		panic(err)
+	}
+	Set(builtin)
+}
+
+// parse reads rows of IATA code, ICAO code, name, city, ISO country code,
+// latitude, longitude and IANA zone, after a header.
+func parse(data []byte) (map[string]Airport, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("airports.csv: %w", err)
+	}
+	table := make(map[string]Airport, len(records))
+	for i, r := range records[1:] {
+		line := i + 2
+		if len(r) != 8 || r[0] == "" || r[7] == "" {
+			return nil, fmt.Errorf("airports.csv line %d: want 8 columns with an IATA code and a timezone", line)
+		}
+		lat, err1 := strconv.ParseFloat(r[5], 64)
+		lon, err2 := strconv.ParseFloat(r[6], 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("airports.csv line %d: invalid coordinates", line)
+		}
+		a := Airport{IATA: strings.ToUpper(r[0]), ICAO: r[1], Name: r[2], City: r[3], Country: r[4], Latitude: lat, Longitude: lon, Timezone: r[7]}
+		table[a.IATA] = a
+	}
+	return table, nil
+}
+
+// Builtin returns the airports the service ships with, by IATA code.
+func Builtin() map[string]Airport {
+	return maps.Clone(builtin)
+}
+
+// Set replaces the known airports with table, keyed by IATA code. The
+// table is swapped at once, so a search sees either the old airports or
+// the new ones.
+func Set(table map[string]Airport) {
+	t := make(map[string]Airport, len(table))
+	for code, a := range table {
+		t[strings.ToUpper(code)] = a
+	}
+	active.Store(&t)
+}
+
+func Lookup(code string) (Airport, bool) {
+	a, ok := (*active.Load())[strings.ToUpper(code)]
+	return a, ok
+}
+
+// Known reports whether code is an airport this service can search.
+func Known(code string) bool {
+	_, ok := Lookup(code)
+	return ok
+}
+
+// matchKind ranks, best first, how an autocomplete query can match an
+// airport.
+type matchKind int
+
+const (
+	matchCode matchKind = iota
+	matchCodePrefix
+	matchCity
+	matchName
+)
+
+// Search is autocomplete over the known airports: the airports whose
+// IATA or ICAO code, city or name matches query, best matches first. City
+// and name match on the start of any word, ignoring case.
+func Search(query string, limit int) []Airport {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return []Airport{}
+	}
+
+	type scored struct {
+		airport Airport
+		match   matchKind
+	}
+	var found []scored
+	for _, a := range *active.Load() {
+		if m, ok := matchOf(a, query); ok {
+			found = append(found, scored{a, m})
+		}
+	}
+	slices.SortFunc(found, func(x, y scored) int {
+		return cmp.Or(cmp.Compare(x.match, y.match), strings.Compare(x.airport.IATA, y.airport.IATA))
+	})
+
+	out := make([]Airport, 0, min(len(found), limit))
+	for _, s := range found {
+		if len(out) == limit {
+			break
+		}
+		out = append(out, s.airport)
+	}
+	return out
+}
+
+func matchOf(a Airport, query string) (matchKind, bool) {
+	switch {
+	case strings.ToLower(a.IATA) == query || strings.ToLower(a.ICAO) == query:
+		return matchCode, true
+	case strings.HasPrefix(strings.ToLower(a.IATA), query):
+		return matchCodePrefix, true
+	case wordPrefix(a.City, query):
+		return matchCity, true
+	case wordPrefix(a.Name, query):
+		return matchName, true
+	}
+	return 0, false
+}
+
+func wordPrefix(s, query string) bool {
+	s = strings.ToLower(s)
+	if strings.HasPrefix(s, query) {
+		return true
+	}
+	for i, r := range s {
+		if (r == ' ' || r == '-') && strings.HasPrefix(s[i+1:], query) {
+			return true
+		}
+	}
+	return false
+}
+
+const earthRadiusKm = 6371.0
+
+// DistanceKm is the great-circle distance between two airports.
+func DistanceKm(a, b Airport) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(b.Longitude - a.Longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+type NearbyAirport struct {
+	Airport
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// Nearby lists the other airports within radiusKm of code, closest
+// first. Airports without coordinates are never near anything.
+func Nearby(code string, radiusKm float64) []NearbyAirport {
+	from, ok := Lookup(code)
+	if !ok || !from.HasLocation() {
+		return []NearbyAirport{}
+	}
+	near := []NearbyAirport{}
+	for _, a := range *active.Load() {
+		if a.IATA == from.IATA || !a.HasLocation() {
+			continue
+		}
+		if d := DistanceKm(from, a); d <= radiusKm {
+			near = append(near, NearbyAirport{Airport: a, DistanceKm: math.Round(d*10) / 10})
+		}
+	}
+	slices.SortFunc(near, func(x, y NearbyAirport) int {
+		return cmp.Or(cmp.Compare(x.DistanceKm, y.DistanceKm), strings.Compare(x.IATA, y.IATA))
+	})
+	return near
+}
@@ -0,0 +1,146 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/safeurl"
+	"github.com/dharmasatrya/flightsearch/pkg/currency"
+)
+
+// Kind says which of an alert's conditions a notification is for.
+type Kind string
+
+const (
+	KindPriceDrop     Kind = "price_drop"
+	KindAlmostSoldOut Kind = "almost_sold_out"
+)
+
+type Notification struct {
+	Kind         Kind          `json:"kind"`
+	Alert        models.Alert  `json:"alert"`
+	LowestPrice  float64       `json:"lowest_price,omitempty"`
+	LowestFlight models.Flight `json:"lowest_flight"`
+	// AvailableSeats is the total left across all providers, for
+	// almost_sold_out notifications.
+	AvailableSeats int       `json:"available_seats,omitempty"`
+	TriggeredAt    time.Time `json:"triggered_at"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+// NewWebhookNotifier only connects to public addresses, since webhook URLs
+// come from clients.
+func NewWebhookNotifier() *WebhookNotifier {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: 5 * time.Second, Control: safeurl.Control}).DialContext
+	return &WebhookNotifier{client: &http.Client{Timeout: 10 * time.Second, Transport: transport}}
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.Alert.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type EmailNotifier struct {
+	cfg SMTPConfig
+}
+
+func NewEmailNotifier(cfg SMTPConfig) *EmailNotifier {
+	return &EmailNotifier{cfg: cfg}
+}
+
+func (e *EmailNotifier) Notify(ctx context.Context, n Notification) error {
+	subject, body := emailText(n)
+	msg := "From: " + e.cfg.From + "\r\n" +
+		"To: " + n.Alert.Email + "\r\n" +
+		"Subject: " + subject + "\r\n\r\n" +
+		body + "\r\n"
+
+	var auth smtp.Auth
+	if e.cfg.Username != "" {
+		auth = smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)
+	}
+	return smtp.SendMail(e.cfg.Host+":"+e.cfg.Port, auth, e.cfg.From, []string{n.Alert.Email}, []byte(msg))
+}
+
+func emailText(n Notification) (subject, body string) {
+	route := fmt.Sprintf("%s to %s on %s", n.Alert.Origin, n.Alert.Destination, n.Alert.DepartureDate)
+	if n.Kind == KindAlmostSoldOut {
+		return "Almost sold out: " + route,
+			fmt.Sprintf("Only %d seats are left across all airlines, below your threshold of %d.", n.AvailableSeats, n.Alert.MinSeats)
+	}
+	return "Price drop: " + route,
+		fmt.Sprintf("The lowest fare is now %s (%s %s), below your target of %s.",
+			currency.FormatIDR(n.LowestPrice),
+			n.LowestFlight.Airline.Name,
+			n.LowestFlight.FlightNumber,
+			currency.FormatIDR(n.Alert.TargetPrice),
+		)
+}
+
+// Dispatcher routes a notification to every channel configured on the alert.
+type Dispatcher struct {
+	webhook Notifier
+	email   Notifier
+}
+
+func NewDispatcher(webhook, email Notifier) *Dispatcher {
+	return &Dispatcher{webhook: webhook, email: email}
+}
+
+func (d *Dispatcher) Notify(ctx context.Context, n Notification) error {
+	var firstErr error
+	if n.Alert.WebhookURL != "" && d.webhook != nil {
+		if err := d.webhook.Notify(ctx, n); err != nil {
+			firstErr = err
+		}
+	}
+	if n.Alert.Email != "" && d.email != nil {
+		if err := d.email.Notify(ctx, n); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,227 @@
+package alerts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/timezone"
+)
+
+type Searcher interface {
+	Search(ctx context.Context, req models.SearchRequest) (*aggregator.Result, error)
+}
+
+type Scheduler struct {
+	store    Store
+	searcher Searcher
+	notifier Notifier
+}
+
+func NewScheduler(store Store, searcher Searcher, notifier Notifier) *Scheduler {
+	return &Scheduler{
+		store:    store,
+		searcher: searcher,
+		notifier: notifier,
+	}
+}
+
+// CheckAll re-checks every alert. Individual failures are logged and
+// reported as one error once all alerts were tried.
+func (s *Scheduler) CheckAll(ctx context.Context) error {
+	alerts, err := s.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list alerts: %w", err)
+	}
+
+	failed := 0
+	for _, alert := range alerts {
+		if err := s.check(ctx, alert); err != nil {
+			log.Printf("Alert %s check failed: %v", alert.ID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d alert checks failed", failed, len(alerts))
+	}
+	return nil
+}
+
+// check re-runs an alert's search and notifies on crossings. Alerts whose
+// departure date has passed at the origin are deleted instead.
+func (s *Scheduler) check(ctx context.Context, alert models.Alert) error {
+	now := time.Now()
+	if departed(alert, now) {
+		if err := s.store.Delete(ctx, alert.ID); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return nil
+	}
+
+	result, err := s.searcher.Search(ctx, alert.SearchRequest())
+	if err != nil {
+		return err
+	}
+
+	alert.LastCheckedAt = &now
+
+	var firstErr error
+	if alert.TargetPrice > 0 {
+		firstErr = s.checkPrice(ctx, &alert, result, now)
+	}
+	if alert.MinSeats > 0 {
+		if err := s.checkSeats(ctx, &alert, result, now); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// The alert may have been deleted while its search ran; don't bring it
+	// back.
+	if err := s.store.Update(ctx, alert); err != nil && !errors.Is(err, ErrNotFound) && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// departed reports whether the alert's departure date is before today at
+// its origin.
+func departed(alert models.Alert, now time.Time) bool {
+	today := timezone.ConvertToTimezone(now, alert.Origin).Format("2006-01-02")
+	return alert.DepartureDate < today
+}
+
+// checkPrice notifies once the lowest fare crosses below the target, and
+// re-arms when it goes back above.
+func (s *Scheduler) checkPrice(ctx context.Context, alert *models.Alert, result *aggregator.Result, now time.Time) error {
+	lowest, found := lowestFlight(result.Flights)
+	if !found {
+		return nil
+	}
+
+	previous := alert.LastLowestPrice
+	price := lowest.Price.Amount
+	alert.LastLowestPrice = &price
+
+	crossed := price <= alert.TargetPrice && (previous == nil || *previous > alert.TargetPrice)
+	if price > alert.TargetPrice {
+		alert.TriggeredAt = nil
+	}
+	if !crossed {
+		return nil
+	}
+
+	err := s.notifier.Notify(ctx, Notification{
+		Kind:         KindPriceDrop,
+		Alert:        *alert,
+		LowestPrice:  price,
+		LowestFlight: lowest,
+		TriggeredAt:  now,
+	})
+	if err != nil {
+		// Keep the previous price so the next run retries the notification.
+		alert.LastLowestPrice = previous
+		return err
+	}
+	alert.TriggeredAt = &now
+	return nil
+}
+
+// checkSeats notifies once fewer than MinSeats seats are left across all
+// providers, and re-arms when seats come back. A run where a provider
+// failed undercounts, so it is skipped rather than read as a sell-out.
+func (s *Scheduler) checkSeats(ctx context.Context, alert *models.Alert, result *aggregator.Result, now time.Time) error {
+	if result.ProvidersFailed > 0 {
+		return nil
+	}
+
+	previous := alert.LastSeats
+	seats := totalSeats(result.Flights)
+	alert.LastSeats = &seats
+
+	crossed := seats < alert.MinSeats && (previous == nil || *previous >= alert.MinSeats)
+	if seats >= alert.MinSeats {
+		alert.SeatsTriggeredAt = nil
+	}
+	if !crossed {
+		return nil
+	}
+
+	n := Notification{
+		Kind:           KindAlmostSoldOut,
+		Alert:          *alert,
+		AvailableSeats: seats,
+		TriggeredAt:    now,
+	}
+	if lowest, found := lowestFlight(result.Flights); found {
+		n.LowestPrice = lowest.Price.Amount
+		n.LowestFlight = lowest
+	}
+	if err := s.notifier.Notify(ctx, n); err != nil {
+		alert.LastSeats = previous
+		return err
+	}
+	alert.SeatsTriggeredAt = &now
+	return nil
+}
+
+// totalSeats adds up the seats left on every flight. A flight sold by
+// several providers is counted once, with the most seats any of them
+// reports.
+func totalSeats(flights []models.Flight) int {
+	type flightKey struct {
+		airline, number string
+		departure       int64
+	}
+	seats := make(map[flightKey]int, len(flights))
+	for _, f := range flights {
+		k := flightKey{f.Airline.Code, f.FlightNumber, f.Departure.Time.Unix()}
+		seats[k] = max(seats[k], f.AvailableSeats)
+	}
+	total := 0
+	for _, n := range seats {
+		total += n
+	}
+	return total
+}
+
+func lowestFlight(flights []models.Flight) (models.Flight, bool) {
+	if len(flights) == 0 {
+		return models.Flight{}, false
+	}
+	lowest := flights[0]
+	for _, f := range flights[1:] {
+		if f.Price.Amount < lowest.Price.Amount {
+			lowest = f
+		}
+	}
+	return lowest, true
+}
+
+func NewAlert(req models.CreateAlertRequest, owner string) models.Alert {
+	return models.Alert{
+		ID:            newID(),
+		Owner:         owner,
+		Origin:        req.Origin,
+		Destination:   req.Destination,
+		DepartureDate: req.DepartureDate,
+		Passengers:    req.Passengers,
+		CabinClass:    req.CabinClass,
+		TargetPrice:   req.TargetPrice,
+		MinSeats:      req.MinSeats,
+		WebhookURL:    req.WebhookURL,
+		Email:         req.Email,
+		CreatedAt:     time.Now(),
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "alert_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,75 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// searchFunc adapts a function to Searcher.
+type searchFunc func(ctx context.Context, req models.SearchRequest) (*aggregator.Result, error)
+
+func (f searchFunc) Search(ctx context.Context, req models.SearchRequest) (*aggregator.Result, error) {
+	return f(ctx, req)
+}
+
+type countingNotifier struct{ sent int }
+
+func (n *countingNotifier) Notify(context.Context, Notification) error {
+	n.sent++
+	return nil
+}
+
+func testAlert(date string) models.Alert {
+	return models.Alert{ID: "alert_1", Origin: "CGK", Destination: "DPS", DepartureDate: date, Passengers: 1, CabinClass: "economy", TargetPrice: 900000}
+}
+
+func TestCheckKeepsAlertDeletedDuringRun(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	alert := testAlert(time.Now().AddDate(0, 0, 7).Format("2006-01-02"))
+	if err := store.Save(ctx, alert); err != nil {
+		t.Fatal(err)
+	}
+
+	searcher := searchFunc(func(ctx context.Context, req models.SearchRequest) (*aggregator.Result, error) {
+		if err := store.Delete(ctx, alert.ID); err != nil {
+			t.Fatal(err)
+		}
+		return &aggregator.Result{Flights: []models.Flight{{ID: "GA-001", Price: models.Price{Amount: 800000}}}}, nil
+	})
+	if err := NewScheduler(store, searcher, &countingNotifier{}).CheckAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, alert.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("alert deleted during its check came back (err %v)", err)
+	}
+}
+
+func TestCheckExpiresDepartedAlerts(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	alert := testAlert(time.Now().AddDate(0, 0, -2).Format("2006-01-02"))
+	if err := store.Save(ctx, alert); err != nil {
+		t.Fatal(err)
+	}
+
+	searcher := searchFunc(func(context.Context, models.SearchRequest) (*aggregator.Result, error) {
+		t.Error("searched for a departed alert")
+		return &aggregator.Result{}, nil
+	})
+	notifier := &countingNotifier{}
+	if err := NewScheduler(store, searcher, notifier).CheckAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, alert.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("departed alert was kept (err %v)", err)
+	}
+	if notifier.sent != 0 {
+		t.Errorf("sent %d notifications for a departed alert", notifier.sent)
+	}
+}
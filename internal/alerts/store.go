@@ -0,0 +1,178 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+var ErrNotFound = errorsx.New(errorsx.NotFound, "alert not found")
+
+type Store interface {
+	Save(ctx context.Context, alert models.Alert) error
+	// Update replaces a stored alert, or returns ErrNotFound if it was
+	// deleted meanwhile.
+	Update(ctx context.Context, alert models.Alert) error
+	Get(ctx context.Context, id string) (models.Alert, error)
+	List(ctx context.Context) ([]models.Alert, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type MemoryStore struct {
+	mu     sync.RWMutex
+	alerts map[string]models.Alert
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{alerts: make(map[string]models.Alert)}
+}
+
+func (s *MemoryStore) Save(ctx context.Context, alert models.Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts[alert.ID] = alert
+	return nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, alert models.Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.alerts[alert.ID]; !ok {
+		return ErrNotFound
+	}
+	s.alerts[alert.ID] = alert
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (models.Alert, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	alert, ok := s.alerts[id]
+	if !ok {
+		return models.Alert{}, ErrNotFound
+	}
+	return alert, nil
+}
+
+func (s *MemoryStore) List(ctx context.Context) ([]models.Alert, error) {
+	s.mu.RLock()
+	result := make([]models.Alert, 0, len(s.alerts))
+	for _, a := range s.alerts {
+		result = append(result, a)
+	}
+	s.mu.RUnlock()
+
+	sortByCreated(result)
+	return result, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.alerts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.alerts, id)
+	return nil
+}
+
+const redisAlertsKey = "alerts"
+
+// updateScript writes an alert only if it still exists.
+var updateScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Save(ctx context.Context, alert models.Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, redisAlertsKey, alert.ID, data).Err()
+}
+
+func (s *RedisStore) Update(ctx context.Context, alert models.Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	n, err := updateScript.Run(ctx, s.client, []string{redisAlertsKey}, alert.ID, data).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (models.Alert, error) {
+	data, err := s.client.HGet(ctx, redisAlertsKey, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return models.Alert{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Alert{}, err
+	}
+
+	var alert models.Alert
+	if err := json.Unmarshal(data, &alert); err != nil {
+		return models.Alert{}, err
+	}
+	return alert, nil
+}
+
+func (s *RedisStore) List(ctx context.Context) ([]models.Alert, error) {
+	values, err := s.client.HGetAll(ctx, redisAlertsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.Alert, 0, len(values))
+	for _, v := range values {
+		var alert models.Alert
+		if err := json.Unmarshal([]byte(v), &alert); err != nil {
+			continue
+		}
+		result = append(result, alert)
+	}
+
+	sortByCreated(result)
+	return result, nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	n, err := s.client.HDel(ctx, redisAlertsKey, id).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func sortByCreated(alerts []models.Alert) {
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].CreatedAt.Before(alerts[j].CreatedAt)
+	})
+}
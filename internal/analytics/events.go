@@ -0,0 +1,138 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Event is one answered search, with nothing that identifies who made
+// it: no tenant, key, address or party, and its time only to the minute.
+type Event struct {
+	Time          time.Time `json:"time"`
+	Origin        string    `json:"origin"`
+	Destination   string    `json:"destination"`
+	DepartureDate string    `json:"departure_date"`
+	ReturnDate    string    `json:"return_date,omitempty"`
+	CabinClass    string    `json:"cabin_class"`
+	Results       int       `json:"results"`
+	LatencyMs     int64     `json:"latency_ms"`
+	CacheHit      bool      `json:"cache_hit,omitempty"`
+}
+
+func NewEvent(req models.SearchRequest, meta models.SearchMetadata, now time.Time) Event {
+	e := Event{
+		Time:          now.UTC().Truncate(time.Minute),
+		Origin:        strings.ToUpper(req.Origin),
+		Destination:   strings.ToUpper(req.Destination),
+		DepartureDate: req.DepartureDate,
+		CabinClass:    req.CabinClass,
+		Results:       meta.TotalResults,
+		LatencyMs:     meta.SearchTimeMs,
+		CacheHit:      meta.CacheHit,
+	}
+	if req.ReturnDate != nil {
+		e.ReturnDate = *req.ReturnDate
+	}
+	return e
+}
+
+// EventStore keeps search events for a retention window.
+type EventStore interface {
+	Record(ctx context.Context, e Event) error
+	// Since returns the events from since on, oldest first.
+	Since(ctx context.Context, since time.Time) ([]Event, error)
+}
+
+// Events kept in memory at most, whatever the retention.
+const maxMemoryEvents = 100000
+
+type MemoryEventStore struct {
+	retention time.Duration
+
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewMemoryEventStore(retention time.Duration) *MemoryEventStore {
+	return &MemoryEventStore{retention: retention}
+}
+
+func (s *MemoryEventStore) Record(ctx context.Context, e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-s.retention)
+	drop := 0
+	for drop < len(s.events) && s.events[drop].Time.Before(cutoff) {
+		drop++
+	}
+	if over := len(s.events) - drop + 1 - maxMemoryEvents; over > 0 {
+		drop += over
+	}
+	s.events = append(s.events[drop:], e)
+	return nil
+}
+
+func (s *MemoryEventStore) Since(ctx context.Context, since time.Time) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []Event
+	for _, e := range s.events {
+		if !e.Time.Before(since) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+const redisEventsKey = "analytics:events"
+
+// RedisEventStore appends events to a stream, trimmed to the retention
+// window on every write, so all replicas report the same figures.
+type RedisEventStore struct {
+	client    redis.UniversalClient
+	retention time.Duration
+}
+
+func NewRedisEventStore(client redis.UniversalClient, retention time.Duration) *RedisEventStore {
+	return &RedisEventStore{client: client, retention: retention}
+}
+
+func (s *RedisEventStore) Record(ctx context.Context, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: redisEventsKey,
+		MinID:  strconv.FormatInt(time.Now().Add(-s.retention).UnixMilli(), 10),
+		Approx: true,
+		Values: map[string]any{"event": data},
+	}).Err()
+}
+
+func (s *RedisEventStore) Since(ctx context.Context, since time.Time) ([]Event, error) {
+	msgs, err := s.client.XRange(ctx, redisEventsKey, strconv.FormatInt(since.UnixMilli(), 10), "+").Result()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Event, 0, len(msgs))
+	for _, m := range msgs {
+		data, _ := m.Values["event"].(string)
+		var e Event
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package analytics
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PrivacyPolicy controls how route counts are released outside the team,
+// so no individual traveller's searches can be inferred from them.
+type PrivacyPolicy struct {
+	// MinCount suppresses routes with fewer searches after noise, so rare
+	// routes, where a count could point at one traveller, never show.
+	MinCount int64 `json:"min_count"`
+	// Epsilon is the privacy budget of each release: every count gets
+	// Laplace noise of scale 1/Epsilon. Zero adds no noise.
+	Epsilon float64 `json:"epsilon,omitempty"`
+	// Window keeps each route's noise fixed for this long, so repeating a
+	// query can't average the noise away.
+	Window time.Duration `json:"-"`
+	// Secret keys the noise, so it can't be predicted from the route and
+	// window, and replicas sharing it add the same noise to a route.
+	Secret []byte `json:"-"`
+
+	releases *releases
+}
+
+// Exact releases counts as they are, for internal endpoints.
+var Exact = PrivacyPolicy{}
+
+// NewPrivacyPolicy returns a policy whose noisy releases are fixed for
+// each window: the first read in a window is what every later read in it
+// gets, so the difference of two reads never counts the searches between
+// them.
+func NewPrivacyPolicy(minCount int64, epsilon float64, window time.Duration, secret []byte) PrivacyPolicy {
+	return PrivacyPolicy{MinCount: minCount, Epsilon: epsilon, Window: window, Secret: secret, releases: &releases{}}
+}
+
+// releases holds the routes released in the latest window.
+type releases struct {
+	mu     sync.Mutex
+	window int64
+	routes []RouteCount
+}
+
+// Apply returns routes with noise added and low-volume routes suppressed,
+// most searched first.
+func (p PrivacyPolicy) Apply(routes []RouteCount, now time.Time) []RouteCount {
+	window := p.window(now)
+	if p.Epsilon <= 0 || p.Window <= 0 || p.releases == nil {
+		return p.release(routes, window)
+	}
+
+	p.releases.mu.Lock()
+	defer p.releases.mu.Unlock()
+	if p.releases.routes == nil || p.releases.window != window {
+		p.releases.window = window
+		p.releases.routes = p.release(routes, window)
+	}
+	return slices.Clone(p.releases.routes)
+}
+
+func (p PrivacyPolicy) window(now time.Time) int64 {
+	window := now.UnixNano()
+	if p.Window > 0 {
+		window /= int64(p.Window)
+	}
+	return window
+}
+
+func (p PrivacyPolicy) release(routes []RouteCount, window int64) []RouteCount {
+	result := make([]RouteCount, 0, len(routes))
+	for _, r := range routes {
+		if p.Epsilon > 0 {
+			r.Searches += int64(math.Round(laplace(1/p.Epsilon, p.uniform(r, window))))
+		}
+		if r.Searches < p.MinCount || r.Searches <= 0 {
+			continue
+		}
+		result = append(result, r)
+	}
+	sortRoutes(result)
+	return result
+}
+
+// uniform is a value in (-0.5, 0.5) fixed for the route and window.
+func (p PrivacyPolicy) uniform(r RouteCount, window int64) float64 {
+	h := fnv.New64a()
+	h.Write(p.Secret)
+	h.Write([]byte(r.Origin + "-" + r.Destination))
+	binary.Write(h, binary.BigEndian, window)
+	// 53 bits fill a float64 mantissa; the half step keeps it off ±0.5.
+	return (float64(h.Sum64()>>11)+0.5)/(1<<53) - 0.5
+}
+
+// laplace maps u in (-0.5, 0.5) to a Laplace(0, scale) sample.
+func laplace(scale, u float64) float64 {
+	return -scale * math.Copysign(1, u) * math.Log(1-2*math.Abs(u))
+}
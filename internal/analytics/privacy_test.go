@@ -0,0 +1,44 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyFixesReleasesWithinWindow(t *testing.T) {
+	now := time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC)
+	for n := int64(100); n < 200; n++ {
+		p := NewPrivacyPolicy(0, 0.5, time.Hour, []byte("secret"))
+		first := p.Apply([]RouteCount{{Origin: "CGK", Destination: "DPS", Searches: n}}, now)
+		second := p.Apply([]RouteCount{{Origin: "CGK", Destination: "DPS", Searches: n + 1}}, now.Add(time.Minute))
+		if len(first) != 1 || len(second) != 1 {
+			t.Fatalf("n=%d: released %d then %d routes, want 1", n, len(first), len(second))
+		}
+		if diff := second[0].Searches - first[0].Searches; diff == 1 {
+			t.Fatalf("n=%d: releases differ by exactly the one new search (%d then %d)", n, first[0].Searches, second[0].Searches)
+		}
+	}
+}
+
+func TestApplyReleasesAgainNextWindow(t *testing.T) {
+	now := time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC)
+	p := NewPrivacyPolicy(0, 0, time.Hour, nil)
+	p.Epsilon = 0.5
+	p.Apply([]RouteCount{{Origin: "CGK", Destination: "DPS", Searches: 100}}, now)
+	next := p.Apply([]RouteCount{{Origin: "SUB", Destination: "DPS", Searches: 100}}, now.Add(time.Hour))
+	if len(next) != 1 || next[0].Origin != "SUB" {
+		t.Fatalf("next window released %+v, want the SUB-DPS count", next)
+	}
+}
+
+func TestApplySameSecretSameNoise(t *testing.T) {
+	now := time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC)
+	routes := []RouteCount{{Origin: "CGK", Destination: "DPS", Searches: 1000}, {Origin: "SUB", Destination: "UPG", Searches: 500}}
+	a := NewPrivacyPolicy(0, 0.5, time.Hour, []byte("shared")).Apply(routes, now)
+	b := NewPrivacyPolicy(0, 0.5, time.Hour, []byte("shared")).Apply(routes, now)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Errorf("replicas sharing the secret released %+v and %+v", a[i], b[i])
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package analytics
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+type PopularRoute struct {
+	Origin      string  `json:"origin"`
+	Destination string  `json:"destination"`
+	Searches    int64   `json:"searches"`
+	AvgResults  float64 `json:"avg_results"`
+	// NoResults counts the searches that found nothing.
+	NoResults int64 `json:"no_results"`
+	// Cabins counts the searches per cabin class.
+	Cabins map[string]int64 `json:"cabins"`
+}
+
+// PopularRoutes ranks the routes of events by searches, at most limit.
+func PopularRoutes(events []Event, limit int) []PopularRoute {
+	byRoute := make(map[string]*PopularRoute)
+	results := make(map[string]int64)
+	for _, e := range events {
+		key := e.Origin + "-" + e.Destination
+		r, ok := byRoute[key]
+		if !ok {
+			r = &PopularRoute{Origin: e.Origin, Destination: e.Destination, Cabins: make(map[string]int64)}
+			byRoute[key] = r
+		}
+		r.Searches++
+		r.Cabins[e.CabinClass]++
+		results[key] += int64(e.Results)
+		if e.Results == 0 {
+			r.NoResults++
+		}
+	}
+
+	routes := make([]PopularRoute, 0, len(byRoute))
+	for key, r := range byRoute {
+		r.AvgResults = math.Round(float64(results[key])/float64(r.Searches)*10) / 10
+		routes = append(routes, *r)
+	}
+	slices.SortFunc(routes, func(a, b PopularRoute) int {
+		return cmp.Or(
+			cmp.Compare(b.Searches, a.Searches),
+			cmp.Compare(a.Origin, b.Origin),
+			cmp.Compare(a.Destination, b.Destination),
+		)
+	})
+	if len(routes) > limit {
+		routes = routes[:limit]
+	}
+	return routes
+}
+
+// LatencyStats summarizes search latencies in milliseconds. Percentiles
+// are nearest-rank.
+type LatencyStats struct {
+	Searches int64   `json:"searches"`
+	MeanMs   float64 `json:"mean_ms"`
+	P50Ms    int64   `json:"p50_ms"`
+	P90Ms    int64   `json:"p90_ms"`
+	P99Ms    int64   `json:"p99_ms"`
+	MaxMs    int64   `json:"max_ms"`
+}
+
+type LatencyReport struct {
+	All LatencyStats `json:"all"`
+	// CacheHits and CacheMisses split All by whether the providers were
+	// called.
+	CacheHits   LatencyStats `json:"cache_hits"`
+	CacheMisses LatencyStats `json:"cache_misses"`
+}
+
+func Latency(events []Event) LatencyReport {
+	var all, hits, misses []int64
+	for _, e := range events {
+		all = append(all, e.LatencyMs)
+		if e.CacheHit {
+			hits = append(hits, e.LatencyMs)
+		} else {
+			misses = append(misses, e.LatencyMs)
+		}
+	}
+	return LatencyReport{All: latencyStats(all), CacheHits: latencyStats(hits), CacheMisses: latencyStats(misses)}
+}
+
+func latencyStats(ms []int64) LatencyStats {
+	if len(ms) == 0 {
+		return LatencyStats{}
+	}
+	slices.Sort(ms)
+	var sum int64
+	for _, v := range ms {
+		sum += v
+	}
+	return LatencyStats{
+		Searches: int64(len(ms)),
+		MeanMs:   math.Round(float64(sum)/float64(len(ms))*10) / 10,
+		P50Ms:    percentile(ms, 50),
+		P90Ms:    percentile(ms, 90),
+		P99Ms:    percentile(ms, 99),
+		MaxMs:    ms[len(ms)-1],
+	}
+}
+
+// percentile of sorted ms.
+func percentile(ms []int64, p int) int64 {
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(ms))))
+	return ms[max(rank, 1)-1]
+}
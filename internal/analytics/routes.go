@@ -0,0 +1,95 @@
+package analytics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+type RouteCount struct {
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+	Searches    int64  `json:"searches"`
+}
+
+type SearchSummary struct {
+	Total     int64   `json:"total"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// SearchCounter keeps in-process counters of searches per route.
+type SearchCounter struct {
+	mu     sync.Mutex
+	routes map[string]*RouteCount
+	total  int64
+	errors int64
+}
+
+func NewSearchCounter() *SearchCounter {
+	return &SearchCounter{routes: make(map[string]*RouteCount)}
+}
+
+func (c *SearchCounter) Record(req models.SearchRequest, failed bool) {
+	origin := strings.ToUpper(req.Origin)
+	dest := strings.ToUpper(req.Destination)
+	key := origin + "-" + dest
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.total++
+	if failed {
+		c.errors++
+	}
+
+	rc, ok := c.routes[key]
+	if !ok {
+		rc = &RouteCount{Origin: origin, Destination: dest}
+		c.routes[key] = rc
+	}
+	rc.Searches++
+}
+
+func (c *SearchCounter) TopRoutes(n int) []RouteCount {
+	result := c.Routes()
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// Routes returns every route searched, most searched first.
+func (c *SearchCounter) Routes() []RouteCount {
+	c.mu.Lock()
+	result := make([]RouteCount, 0, len(c.routes))
+	for _, rc := range c.routes {
+		result = append(result, *rc)
+	}
+	c.mu.Unlock()
+
+	sortRoutes(result)
+	return result
+}
+
+func sortRoutes(routes []RouteCount) {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Searches != routes[j].Searches {
+			return routes[i].Searches > routes[j].Searches
+		}
+		return routes[i].Origin+routes[i].Destination < routes[j].Origin+routes[j].Destination
+	})
+}
+
+func (c *SearchCounter) Summary() SearchSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := SearchSummary{Total: c.total, Errors: c.errors}
+	if c.total > 0 {
+		s.ErrorRate = float64(c.errors) / float64(c.total)
+	}
+	return s
+}
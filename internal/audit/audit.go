@@ -0,0 +1,210 @@
+// Package audit writes a record of every search to a sink for compliance
+// and billing. Records are queued and written in batches by a background
+// goroutine, so a slow sink never holds up a search.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+)
+
+// Record is one search: who made it, what they asked for and what they
+// got.
+type Record struct {
+	Time      time.Time            `json:"time"`
+	RequestID string               `json:"request_id,omitempty"`
+	Client    Client               `json:"client"`
+	Request   models.SearchRequest `json:"request"`
+	Result    Result               `json:"result"`
+}
+
+type Client struct {
+	Tenant string      `json:"tenant,omitempty"`
+	Tier   reqctx.Tier `json:"tier"`
+	Admin  bool        `json:"admin,omitempty"`
+	// KeyID identifies the API key without revealing it.
+	KeyID string `json:"key_id,omitempty"`
+	IP    string `json:"ip"`
+}
+
+type Result struct {
+	// Status is the HTTP status the search was answered with.
+	Status       int    `json:"status"`
+	SearchID     string `json:"search_id,omitempty"`
+	TotalResults int    `json:"total_results"`
+	CacheHit     bool   `json:"cache_hit,omitempty"`
+	SearchTimeMs int64  `json:"search_time_ms"`
+	// Providers are those whose flights were returned, FailedProviders
+	// those that answered with an error.
+	Providers        []string `json:"providers,omitempty"`
+	ProvidersQueried int      `json:"providers_queried,omitempty"`
+	FailedProviders  []string `json:"failed_providers,omitempty"`
+}
+
+// NewRecord records a search answered with results and status; flights
+// are every leg's flights before filtering.
+func NewRecord(ctx context.Context, ip string, req models.SearchRequest, meta models.SearchMetadata, status int, now time.Time, flights ...[]models.Flight) Record {
+	r := newRecord(ctx, ip, req, now)
+	r.Result = Result{
+		Status:           status,
+		SearchID:         meta.SearchID,
+		TotalResults:     meta.TotalResults,
+		CacheHit:         meta.CacheHit,
+		SearchTimeMs:     meta.SearchTimeMs,
+		ProvidersQueried: meta.ProvidersQueried,
+		FailedProviders:  meta.FailedProviders,
+	}
+	for _, leg := range flights {
+		for _, f := range leg {
+			if !slices.Contains(r.Result.Providers, f.Provider) {
+				r.Result.Providers = append(r.Result.Providers, f.Provider)
+			}
+		}
+	}
+	slices.Sort(r.Result.Providers)
+	return r
+}
+
+// NewStatusRecord records a search answered with status instead of
+// results: shed under load, failed, or not modified since the client's
+// ETag.
+func NewStatusRecord(ctx context.Context, ip string, req models.SearchRequest, status int, elapsed time.Duration, now time.Time) Record {
+	r := newRecord(ctx, ip, req, now)
+	r.Result = Result{Status: status, SearchTimeMs: elapsed.Milliseconds()}
+	return r
+}
+
+func newRecord(ctx context.Context, ip string, req models.SearchRequest, now time.Time) Record {
+	info := reqctx.From(ctx)
+	r := Record{
+		Time:      now.UTC(),
+		RequestID: info.RequestID,
+		Client:    Client{Tenant: info.Tenant, Tier: info.Tier, Admin: info.Admin, IP: ip},
+		Request:   req,
+	}
+	if info.APIKey != "" {
+		sum := sha256.Sum256([]byte(info.APIKey))
+		r.Client.KeyID = hex.EncodeToString(sum[:8])
+	}
+	return r
+}
+
+type Config struct {
+	// BufferSize records are queued at most; more are dropped.
+	BufferSize    int
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// Stats counts records since the logger started.
+type Stats struct {
+	Sink    string `json:"sink"`
+	Queued  int    `json:"queued"`
+	Written int64  `json:"written"`
+	// Dropped records found the buffer full, Failed ones the sink failing
+	// every attempt.
+	Dropped int64 `json:"dropped"`
+	Failed  int64 `json:"failed"`
+}
+
+const writeAttempts = 3
+
+type Logger struct {
+	sink    Sink
+	cfg     Config
+	records chan Record
+
+	written atomic.Int64
+	dropped atomic.Int64
+	failed  atomic.Int64
+}
+
+func NewLogger(sink Sink, cfg Config) *Logger {
+	return &Logger{
+		sink:    sink,
+		cfg:     cfg,
+		records: make(chan Record, cfg.BufferSize),
+	}
+}
+
+// Log queues r without blocking.
+func (l *Logger) Log(r Record) {
+	select {
+	case l.records <- r:
+	default:
+		if l.dropped.Add(1)%1000 == 1 {
+			log.Printf("Audit buffer full, %d records dropped so far", l.dropped.Load())
+		}
+	}
+}
+
+// Run writes queued records until ctx is done, a batch when BatchSize are
+// queued or every FlushInterval.
+func (l *Logger) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, l.cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			for len(l.records) > 0 {
+				batch = append(batch, <-l.records)
+			}
+			l.write(context.Background(), batch)
+			return
+		case r := <-l.records:
+			batch = append(batch, r)
+			if len(batch) < l.cfg.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		l.write(ctx, batch)
+		batch = batch[:0]
+	}
+}
+
+// write retries a failing sink with backoff; records keep queueing
+// meanwhile.
+func (l *Logger) write(ctx context.Context, batch []Record) {
+	if len(batch) == 0 {
+		return
+	}
+	var err error
+	for attempt := 0; attempt < writeAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+		writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		err = l.sink.Write(writeCtx, batch)
+		cancel()
+		if err == nil {
+			l.written.Add(int64(len(batch)))
+			return
+		}
+	}
+	l.failed.Add(int64(len(batch)))
+	log.Printf("Failed to write %d audit records to %s: %v", len(batch), l.sink.Name(), err)
+}
+
+func (l *Logger) Stats() Stats {
+	return Stats{
+		Sink:    l.sink.Name(),
+		Queued:  len(l.records),
+		Written: l.written.Load(),
+		Dropped: l.dropped.Load(),
+		Failed:  l.failed.Load(),
+	}
+}
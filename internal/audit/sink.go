@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	SinkFile  = "file"
+	SinkHTTP  = "http"
+	SinkKafka = "kafka"
+)
+
+// Sinks lists the sinks AUDIT_SINK can name.
+var Sinks = []string{SinkFile, SinkHTTP, SinkKafka}
+
+// Sink stores batches of records. Write is called from one goroutine at a
+// time.
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, records []Record) error
+}
+
+// FileSink appends records to a file as JSON lines.
+type FileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{f: f}, nil
+}
+
+func (s *FileSink) Name() string {
+	return SinkFile
+}
+
+func (s *FileSink) Write(_ context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := bufio.NewWriter(s.f)
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+type httpBatch struct {
+	Records []Record `json:"records"`
+}
+
+// HTTPSink posts each batch as {"records": [...]}, with token as bearer
+// token when set.
+type HTTPSink struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewHTTPSink(url, token string) *HTTPSink {
+	return &HTTPSink{url: url, token: token, client: &http.Client{}}
+}
+
+func (s *HTTPSink) Name() string {
+	return SinkHTTP
+}
+
+func (s *HTTPSink) Write(ctx context.Context, records []Record) error {
+	return post(ctx, s.client, s.url, "application/json", s.token, httpBatch{Records: records})
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key,omitempty"`
+	Value Record `json:"value"`
+}
+
+type kafkaBatch struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+// KafkaSink produces records to a topic through a Kafka REST Proxy (v2
+// API), keyed by tenant so each tenant's records stay in order.
+type KafkaSink struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewKafkaSink(proxyURL, topic, token string) *KafkaSink {
+	return &KafkaSink{
+		url:    strings.TrimSuffix(proxyURL, "/") + "/topics/" + url.PathEscape(topic),
+		token:  token,
+		client: &http.Client{},
+	}
+}
+
+func (s *KafkaSink) Name() string {
+	return SinkKafka
+}
+
+func (s *KafkaSink) Write(ctx context.Context, records []Record) error {
+	batch := kafkaBatch{Records: make([]kafkaRecord, len(records))}
+	for i, r := range records {
+		batch.Records[i] = kafkaRecord{Key: r.Client.Tenant, Value: r}
+	}
+	return post(ctx, s.client, s.url, "application/vnd.kafka.json.v2+json", s.token, batch)
+}
+
+func post(ctx context.Context, client *http.Client, target, contentType, token string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d", target, resp.StatusCode)
+	}
+	return nil
+}
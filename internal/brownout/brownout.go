@@ -0,0 +1,213 @@
+// Package brownout sheds search features step by step as the service
+// saturates, so the core search stays up during traffic spikes.
+package brownout
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Level is a step on the shedding ladder. Each level also sheds what the
+// levels below it shed.
+type Level int
+
+const (
+	Normal Level = iota
+	// NoEnrichment drops price trend, ground transport and cross-sell.
+	NoEnrichment
+	// NoExplain ranks with the heuristic instead of the external model
+	// and drops score_details.
+	NoExplain
+	// Tier1Only queries only the tier-1 providers on cache misses.
+	Tier1Only
+	// CacheOnly serves cached results and rejects cache misses.
+	CacheOnly
+)
+
+var (
+	stages = []string{"normal", "no_enrichment", "no_explain", "tier1_only", "cache_only"}
+	shed   = []string{"enrichment", "ranking_explain", "provider_fanout", "live_search"}
+)
+
+// DefaultThresholds are the saturations that enter each level above
+// Normal.
+var DefaultThresholds = []float64{0.8, 1, 1.5, 2}
+
+func (l Level) String() string {
+	return stages[l]
+}
+
+// Report describes the level for search metadata, nil at Normal.
+func (l Level) Report() *models.Brownout {
+	if l == Normal {
+		return nil
+	}
+	return &models.Brownout{Level: int(l), Stage: l.String(), Shed: shed[:l]}
+}
+
+// Signal reports how saturated a resource is: 1 is at capacity.
+type Signal func() float64
+
+type Status struct {
+	Level      int       `json:"level"`
+	Stage      string    `json:"stage"`
+	Shed       []string  `json:"shed"`
+	Saturation float64   `json:"saturation"`
+	Since      time.Time `json:"since"`
+}
+
+// Controller moves up the ladder as soon as saturation crosses a level's
+// threshold, and down one level at a time once saturation has stayed
+// below the current level's threshold for the cool-down.
+type Controller struct {
+	thresholds []float64
+	cooldown   time.Duration
+	tier1      []string
+	signals    []Signal
+
+	level atomic.Int32
+
+	mu         sync.Mutex
+	saturation float64
+	since      time.Time
+	calmSince  time.Time
+}
+
+// NewController takes one threshold per level above Normal, in
+// increasing order.
+func NewController(thresholds []float64, cooldown time.Duration, tier1 []string, signals ...Signal) *Controller {
+	return &Controller{
+		thresholds: thresholds,
+		cooldown:   cooldown,
+		tier1:      tier1,
+		signals:    signals,
+		since:      time.Now(),
+	}
+}
+
+// Level is the current level; Normal without a controller.
+func (c *Controller) Level() Level {
+	if c == nil {
+		return Normal
+	}
+	return Level(c.level.Load())
+}
+
+// Run samples the signals every interval until ctx is done.
+func (c *Controller) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.evaluate(now)
+		}
+	}
+}
+
+func (c *Controller) evaluate(now time.Time) {
+	var saturation float64
+	for _, s := range c.signals {
+		saturation = max(saturation, s())
+	}
+
+	target := Normal
+	for i, t := range c.thresholds {
+		if saturation >= t {
+			target = Level(i + 1)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.saturation = saturation
+
+	current := c.Level()
+	switch {
+	case target > current:
+		c.set(current, target, saturation, now)
+	case target == current:
+		c.calmSince = time.Time{}
+	case c.calmSince.IsZero():
+		c.calmSince = now
+	case now.Sub(c.calmSince) >= c.cooldown:
+		c.set(current, current-1, saturation, now)
+	}
+}
+
+func (c *Controller) set(from, to Level, saturation float64, now time.Time) {
+	c.level.Store(int32(to))
+	c.since = now
+	c.calmSince = time.Time{}
+	log.Printf("Brownout: %s -> %s (saturation %.2f)", from, to, saturation)
+}
+
+func (c *Controller) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l := c.Level()
+	return Status{
+		Level:      int(l),
+		Stage:      l.String(),
+		Shed:       shed[:l],
+		Saturation: c.saturation,
+		Since:      c.since,
+	}
+}
+
+// Tier1 narrows req to the tier-1 providers it would query. ok is false
+// when it queries none of them.
+func (c *Controller) Tier1(req models.SearchRequest) (models.SearchRequest, bool) {
+	var narrowed []string
+	for _, name := range c.tier1 {
+		if req.QueriesProvider(name) {
+			narrowed = append(narrowed, name)
+		}
+	}
+	if len(narrowed) == 0 {
+		return req, false
+	}
+	req.Providers = narrowed
+	return req, true
+}
+
+// InFlight counts requests in progress; its Saturation is relative to
+// limit.
+type InFlight struct {
+	n     atomic.Int64
+	limit int64
+}
+
+func NewInFlight(limit int) *InFlight {
+	return &InFlight{limit: int64(limit)}
+}
+
+// Enter counts a request until the returned func is called.
+func (g *InFlight) Enter() func() {
+	g.n.Add(1)
+	return func() { g.n.Add(-1) }
+}
+
+func (g *InFlight) Saturation() float64 {
+	return float64(g.n.Load()) / float64(g.limit)
+}
+
+type levelKey struct{}
+
+// WithLevel fixes the level for one request, so all of its steps shed
+// the same features.
+func WithLevel(ctx context.Context, l Level) context.Context {
+	return context.WithValue(ctx, levelKey{}, l)
+}
+
+func LevelFrom(ctx context.Context) Level {
+	l, _ := ctx.Value(levelKey{}).(Level)
+	return l
+}
@@ -0,0 +1,176 @@
+// Package bus publishes an event to a message bus after every aggregator
+// run, for downstream services that would otherwise poll the API.
+// Publishing happens in the background, so a slow bus never holds up a
+// search.
+package bus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+const (
+	NATS  = "nats"
+	Kafka = "kafka"
+)
+
+// Buses lists the buses EVENT_BUS can name.
+var Buses = []string{NATS, Kafka}
+
+const TypeSearchCompleted = "search.completed"
+
+// SearchCompleted is one aggregator run: one leg of a round trip, with
+// nothing that identifies who searched.
+type SearchCompleted struct {
+	Type          string    `json:"type"`
+	ID            string    `json:"id"`
+	Time          time.Time `json:"time"`
+	Region        string    `json:"region,omitempty"`
+	Origin        string    `json:"origin"`
+	Destination   string    `json:"destination"`
+	DepartureDate string    `json:"departure_date"`
+	CabinClass    string    `json:"cabin_class"`
+	Passengers    int       `json:"passengers"`
+	Results       int       `json:"results"`
+	// LowestPrice is unset when no flight was found.
+	LowestPrice         *models.Price `json:"lowest_price,omitempty"`
+	LowestPriceProvider string        `json:"lowest_price_provider,omitempty"`
+	// Providers answered, fallbacks included; FailedProviders didn't.
+	Providers       []string `json:"providers"`
+	FailedProviders []string `json:"failed_providers,omitempty"`
+	// Restricted searches named their providers, so LowestPrice may not
+	// be the route's.
+	Restricted bool `json:"restricted,omitempty"`
+	// LatencyMs is the slowest provider's time, which the run waited for.
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+func NewSearchCompleted(req models.SearchRequest, result *aggregator.Result, region string, now time.Time) SearchCompleted {
+	e := SearchCompleted{
+		Type:            TypeSearchCompleted,
+		ID:              newID(),
+		Time:            now.UTC(),
+		Region:          region,
+		Origin:          req.Origin,
+		Destination:     req.Destination,
+		DepartureDate:   req.DepartureDate,
+		CabinClass:      req.CabinClass,
+		Passengers:      req.Passengers,
+		Results:         len(result.Flights),
+		Providers:       []string{},
+		FailedProviders: slices.Clone(result.FailedProviders),
+		Restricted:      req.RestrictsProviders(),
+	}
+	for _, f := range result.Flights {
+		if e.LowestPrice == nil || f.Price.Amount < e.LowestPrice.Amount {
+			price := f.Price
+			e.LowestPrice, e.LowestPriceProvider = &price, f.Provider
+		}
+	}
+	for _, r := range result.Responses {
+		e.LatencyMs = max(e.LatencyMs, r.ElapsedMs)
+		if r.Error == "" && !slices.Contains(e.Providers, r.Provider) {
+			e.Providers = append(e.Providers, r.Provider)
+		}
+	}
+	slices.Sort(e.Providers)
+	return e
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Publisher sends events to a bus. Publish is called from one goroutine
+// at a time.
+type Publisher interface {
+	Name() string
+	Publish(ctx context.Context, events []SearchCompleted) error
+}
+
+// Stats counts events since the bus started.
+type Stats struct {
+	Bus       string `json:"bus"`
+	Queued    int    `json:"queued"`
+	Published int64  `json:"published"`
+	// Dropped events found the buffer full, Failed ones the bus failing.
+	Dropped int64 `json:"dropped"`
+	Failed  int64 `json:"failed"`
+}
+
+// maxBatch caps the events published at once.
+const maxBatch = 100
+
+// Bus observes the aggregator and queues an event per run for its
+// publisher.
+type Bus struct {
+	publisher Publisher
+	region    string
+	events    chan SearchCompleted
+
+	published atomic.Int64
+	dropped   atomic.Int64
+	failed    atomic.Int64
+}
+
+// New queues up to buffer events; more are dropped.
+func New(p Publisher, region string, buffer int) *Bus {
+	return &Bus{publisher: p, region: region, events: make(chan SearchCompleted, buffer)}
+}
+
+func (b *Bus) ObserveSearch(ctx context.Context, req models.SearchRequest, result *aggregator.Result) {
+	select {
+	case b.events <- NewSearchCompleted(req, result, b.region, time.Now()):
+	default:
+		if b.dropped.Add(1)%1000 == 1 {
+			log.Printf("Event bus buffer full, %d events dropped so far", b.dropped.Load())
+		}
+	}
+}
+
+// Run publishes queued events until ctx is done, as many at once as are
+// waiting.
+func (b *Bus) Run(ctx context.Context) {
+	batch := make([]SearchCompleted, 0, maxBatch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-b.events:
+			batch = append(batch[:0], e)
+		}
+		for len(batch) < maxBatch && len(b.events) > 0 {
+			batch = append(batch, <-b.events)
+		}
+
+		pubCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err := b.publisher.Publish(pubCtx, batch)
+		cancel()
+		if err != nil {
+			b.failed.Add(int64(len(batch)))
+			log.Printf("Failed to publish %d events to %s: %v", len(batch), b.publisher.Name(), err)
+			continue
+		}
+		b.published.Add(int64(len(batch)))
+	}
+}
+
+func (b *Bus) Stats() Stats {
+	return Stats{
+		Bus:       b.publisher.Name(),
+		Queued:    len(b.events),
+		Published: b.published.Load(),
+		Dropped:   b.dropped.Load(),
+		Failed:    b.failed.Load(),
+	}
+}
@@ -0,0 +1,70 @@
+package bus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type kafkaRecord struct {
+	Key   string          `json:"key"`
+	Value SearchCompleted `json:"value"`
+}
+
+type kafkaBatch struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+// KafkaPublisher produces events to a topic through a Kafka REST Proxy
+// (v2 API), keyed by route so each route's events stay in order.
+type KafkaPublisher struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewKafkaPublisher(proxyURL, topic, token string) *KafkaPublisher {
+	return &KafkaPublisher{
+		url:    strings.TrimSuffix(proxyURL, "/") + "/topics/" + url.PathEscape(topic),
+		token:  token,
+		client: &http.Client{},
+	}
+}
+
+func (p *KafkaPublisher) Name() string {
+	return Kafka
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, events []SearchCompleted) error {
+	batch := kafkaBatch{Records: make([]kafkaRecord, len(events))}
+	for i, e := range events {
+		batch.Records[i] = kafkaRecord{Key: e.Origin + "-" + e.Destination, Value: e}
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("kafka REST proxy returned status %d", resp.StatusCode)
+	}
+	return nil
+}
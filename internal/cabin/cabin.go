@@ -0,0 +1,81 @@
+// Package cabin maps the cabin labels providers and clients use ("Economy",
+// "Y", "premium economy") onto one set of classes.
+package cabin
+
+import (
+	"strings"
+
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+type Class = types.CabinClass
+
+const (
+	Economy        = types.CabinEconomy
+	PremiumEconomy = types.CabinPremiumEconomy
+	Business       = types.CabinBusiness
+	First          = types.CabinFirst
+
+	// Any is only valid in requests: it searches every cabin.
+	Any = types.CabinAny
+)
+
+// Classes are the concrete cabins, lowest first.
+var Classes = types.CabinClasses
+
+// aliases are keyed by the label lowercased, with spaces and dashes turned
+// into underscores and any "_class" suffix dropped. Single letters are the
+// IATA cabin codes.
+var aliases = map[string]Class{
+	"economy":         Economy,
+	"eco":             Economy,
+	"coach":           Economy,
+	"y":               Economy,
+	"premium_economy": PremiumEconomy,
+	"premium":         PremiumEconomy,
+	"premium_eco":     PremiumEconomy,
+	"w":               PremiumEconomy,
+	"business":        Business,
+	"biz":             Business,
+	"c":               Business,
+	"j":               Business,
+	"first":           First,
+	"f":               First,
+}
+
+// Parse maps a provider or client label to a concrete class.
+func Parse(label string) (Class, bool) {
+	key := strings.ToLower(strings.TrimSpace(label))
+	key = strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+	key = strings.TrimSuffix(key, "_class")
+	c, ok := aliases[key]
+	return c, ok
+}
+
+// ParseRequested is Parse that also accepts Any.
+func ParseRequested(label string) (Class, bool) {
+	if strings.EqualFold(strings.TrimSpace(label), string(Any)) {
+		return Any, true
+	}
+	return Parse(label)
+}
+
+// Matches reports whether a flight sold in the cabin labelled label
+// satisfies a request for requested. Unknown labels never match.
+func Matches(requested, label string) bool {
+	want, ok := ParseRequested(requested)
+	if !ok {
+		return false
+	}
+	got, ok := Parse(label)
+	return ok && (want == Any || want == got)
+}
+
+// Names returns every class a request may ask for, for error messages.
+func Names() []string {
+	names := make([]string, 0, len(Classes)+1)
+	for _, c := range Classes {
+		names = append(names, string(c))
+	}
+	return append(names, string(Any))
+}
@@ -3,8 +3,14 @@ package cache
 import (
 	"context"
 	"crypto/sha256"
+	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,125 +18,377 @@ import (
 	"github.com/dharmasatrya/flightsearch/internal/models"
 )
 
+const replicateTimeout = 2 * time.Second
+
 type Cache interface {
-	Get(ctx context.Context, req models.SearchRequest) ([]models.Flight, bool)
-	Set(ctx context.Context, req models.SearchRequest, flights []models.Flight) error
+	Get(ctx context.Context, req models.SearchRequest) (Entry, bool)
+	Set(ctx context.Context, req models.SearchRequest, entry Entry) error
+	// Flush deletes every entry, returning how many there were.
+	Flush(ctx context.Context) (int64, error)
 	Close() error
 }
 
+// Entry is the cached answer to a search. FetchedAt records when each
+// provider that answered was called, including those with no flights.
+type Entry struct {
+	Flights   []models.Flight      `json:"flights"`
+	FetchedAt map[string]time.Time `json:"fetched_at"`
+}
+
+// Negative reports whether the entry records a search that found no
+// flights.
+func (e Entry) Negative() bool {
+	return len(e.Flights) == 0
+}
+
 type RedisCache struct {
-	client *redis.Client
-	ttl    time.Duration
+	client      redis.UniversalClient
+	ttl         time.Duration
+	negativeTTL time.Duration
+	region      string
+	encoding    Encoding
+
+	replicas []replica
+}
+
+// replica is another region's Redis that cache entries are copied to.
+type replica struct {
+	region string
+	client redis.UniversalClient
 }
 
 type RedisConfig struct {
-	Host     string
-	Port     string
+	Host string
+	Port string
+	// Addrs replaces Host and Port when set: the seed nodes of a Redis
+	// Cluster, or the Sentinels that MasterName is discovered through.
+	Addrs            []string
+	MasterName       string
+	SentinelPassword string
+	// Cluster treats a single address as a cluster seed. Several addresses
+	// without a MasterName always are.
+	Cluster  bool
+	Username string
 	Password string
-	DB       int
-	TTL      time.Duration
+	// DB is not supported by Redis Cluster, which only has database 0.
+	DB int
+	// TLS, when set, encrypts connections to every node.
+	TLS *tls.Config
+	// PoolSize, MinIdleConns and PoolTimeout tune the connection pool to
+	// each node; zero keeps the go-redis defaults.
+	PoolSize     int
+	MinIdleConns int
+	PoolTimeout  time.Duration
+
+	TTL time.Duration
+	// NegativeTTL is how long searches with no flights are cached, short
+	// so a route providers start serving shows up soon. Zero leaves them
+	// uncached.
+	NegativeTTL time.Duration
+	// Encoding serializes new entries; entries in any encoding are read.
+	Encoding Encoding
+	// Region tags every key, so regions sharing a Redis, or replicating
+	// into each other's, keep their providers' results apart.
+	Region string
 }
 
 func DefaultRedisConfig() RedisConfig {
 	return RedisConfig{
-		Host:     "localhost",
-		Port:     "6379",
-		Password: "",
-		DB:       0,
-		TTL:      5 * time.Minute,
+		Host:        "localhost",
+		Port:        "6379",
+		Password:    "",
+		DB:          0,
+		TTL:         5 * time.Minute,
+		NegativeTTL: time.Minute,
+		Encoding:    GzipJSON,
 	}
 }
 
-func NewRedisCache(cfg RedisConfig) (*RedisCache, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     cfg.Host + ":" + cfg.Port,
-		Password: cfg.Password,
-		DB:       cfg.DB,
+// Topology is "sentinel", "cluster" or "standalone".
+func (cfg RedisConfig) Topology() string {
+	switch {
+	case cfg.MasterName != "":
+		return "sentinel"
+	case cfg.Cluster || len(cfg.Addrs) > 1:
+		return "cluster"
+	}
+	return "standalone"
+}
+
+// Validate rejects combinations the topology can't serve.
+func (cfg RedisConfig) Validate() error {
+	switch cfg.Topology() {
+	case "sentinel":
+		if len(cfg.Addrs) == 0 {
+			return fmt.Errorf("sentinel master %q needs the sentinels' addresses", cfg.MasterName)
+		}
+		if cfg.Cluster {
+			return fmt.Errorf("a sentinel master can't also be a cluster")
+		}
+	case "cluster":
+		if cfg.DB != 0 {
+			return fmt.Errorf("redis cluster only has database 0, got %d", cfg.DB)
+		}
+	}
+	return nil
+}
+
+// Client connects to the Redis cfg describes, without checking that it
+// is reachable.
+func (cfg RedisConfig) Client() redis.UniversalClient {
+	addrs := cfg.Addrs
+	if len(addrs) == 0 {
+		addrs = []string{cfg.Host + ":" + cfg.Port}
+	}
+	return redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:            addrs,
+		MasterName:       cfg.MasterName,
+		SentinelPassword: cfg.SentinelPassword,
+		IsClusterMode:    cfg.Cluster,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		DB:               cfg.DB,
+		TLSConfig:        cfg.TLS,
+		PoolSize:         cfg.PoolSize,
+		MinIdleConns:     cfg.MinIdleConns,
+		PoolTimeout:      cfg.PoolTimeout,
 	})
+}
+
+func NewRedisCache(cfg RedisConfig) (*RedisCache, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	client := cfg.Client()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, err
 	}
 
 	return &RedisCache{
-		client: client,
-		ttl:    cfg.TTL,
+		client:      client,
+		ttl:         cfg.TTL,
+		negativeTTL: cfg.NegativeTTL,
+		region:      cfg.Region,
+		encoding:    cfg.Encoding,
 	}, nil
 }
 
-func (c *RedisCache) Get(ctx context.Context, req models.SearchRequest) ([]models.Flight, bool) {
-	key := generateKey(req)
+// AddReplica copies every entry this cache writes to another region's
+// Redis. Replication is symmetric: entries the other region copies here
+// answer searches that miss this region's own entries.
+func (c *RedisCache) AddReplica(region string, client redis.UniversalClient) {
+	c.replicas = append(c.replicas, replica{region: region, client: client})
+}
 
+func (c *RedisCache) Get(ctx context.Context, req models.SearchRequest) (Entry, bool) {
+	if entry, ok := c.get(ctx, regionKey(c.region, req)); ok {
+		return entry, true
+	}
+	for _, r := range c.replicas {
+		if entry, ok := c.get(ctx, regionKey(r.region, req)); ok {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// get treats entries in the old format, a bare list of flights, as misses.
+func (c *RedisCache) get(ctx context.Context, key string) (Entry, bool) {
 	data, err := c.client.Get(ctx, key).Bytes()
 	if err != nil {
-		return nil, false
+		return Entry{}, false
 	}
 
-	var flights []models.Flight
-	if err := json.Unmarshal(data, &flights); err != nil {
-		return nil, false
+	var entry Entry
+	if err := unmarshal(data, &entry); err != nil {
+		return Entry{}, false
 	}
 
-	return flights, true
+	return entry, true
 }
 
-func (c *RedisCache) Set(ctx context.Context, req models.SearchRequest, flights []models.Flight) error {
-	key := generateKey(req)
+func (c *RedisCache) Set(ctx context.Context, req models.SearchRequest, entry Entry) error {
+	key := regionKey(c.region, req)
+	ttl := c.ttl
+	if entry.Negative() {
+		if c.negativeTTL <= 0 {
+			return nil
+		}
+		ttl = c.negativeTTL
+	}
 
-	data, err := json.Marshal(flights)
+	data, err := c.encoding.marshal(entry)
 	if err != nil {
 		return err
 	}
 
-	return c.client.Set(ctx, key, data, c.ttl).Err()
+	if err := c.client.Set(ctx, key, data, ttl).Err(); err != nil {
+		return err
+	}
+	for _, r := range c.replicas {
+		go c.replicate(r, key, data, ttl)
+	}
+	return nil
+}
+
+// replicate runs after the search has been answered, so a slow or
+// unreachable region never delays it.
+func (c *RedisCache) replicate(r replica, key string, data []byte, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), replicateTimeout)
+	defer cancel()
+	if err := r.client.Set(ctx, key, data, ttl).Err(); err != nil {
+		log.Printf("Failed to replicate cache entry to region %s: %v", r.region, err)
+	}
+}
+
+// Flush deletes every entry in this region's Redis: its own, those other
+// regions copied to it, and those of older Schemas. Copies of its entries
+// in other regions are theirs to flush.
+func (c *RedisCache) Flush(ctx context.Context) (int64, error) {
+	var deleted atomic.Int64
+	flush := func(ctx context.Context, client *redis.Client) error {
+		iter := client.Scan(ctx, 0, "flight:*", 100).Iterator()
+		pipe := client.Pipeline()
+		for iter.Next(ctx) {
+			pipe.Unlink(ctx, iter.Val())
+			if pipe.Len() == 100 {
+				if _, err := pipe.Exec(ctx); err != nil {
+					return err
+				}
+				deleted.Add(100)
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+		n := int64(pipe.Len())
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		deleted.Add(n)
+		return nil
+	}
+
+	var err error
+	switch client := c.client.(type) {
+	case *redis.ClusterClient:
+		err = client.ForEachMaster(ctx, flush)
+	case *redis.Client:
+		err = flush(ctx, client)
+	}
+	return deleted.Load(), err
 }
 
 func (c *RedisCache) Close() error {
+	for _, r := range c.replicas {
+		r.client.Close()
+	}
 	return c.client.Close()
 }
 
+// Client is shared by every other Redis-backed store.
+func (c *RedisCache) Client() redis.UniversalClient {
+	return c.client
+}
+
 type NoOpCache struct{}
 
 func NewNoOpCache() *NoOpCache {
 	return &NoOpCache{}
 }
 
-func (c *NoOpCache) Get(ctx context.Context, req models.SearchRequest) ([]models.Flight, bool) {
-	return nil, false
+func (c *NoOpCache) Get(ctx context.Context, req models.SearchRequest) (Entry, bool) {
+	return Entry{}, false
 }
 
-func (c *NoOpCache) Set(ctx context.Context, req models.SearchRequest, flights []models.Flight) error {
+func (c *NoOpCache) Set(ctx context.Context, req models.SearchRequest, entry Entry) error {
 	return nil
 }
 
+func (c *NoOpCache) Flush(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
 func (c *NoOpCache) Close() error {
 	return nil
 }
 
-func generateKey(req models.SearchRequest) string {
+// EntryHash identifies the flights stored for a search. It hashes their
+// JSON, so it changes exactly when they do.
+func EntryHash(flights []models.Flight) string {
+	data, _ := json.Marshal(flights)
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// KeyFor identifies the search req makes. Searches with the same key are
+// answered with the same flights, whatever the Schema and region of the
+// entry holding them.
+func KeyFor(req models.SearchRequest) string {
+	return "flight:" + searchHash(req)
+}
+
+// regionKey is the key req's entry is stored under:
+// "flight:<schema>:<region>:<hash>", without the region for deployments
+// that have none.
+func regionKey(region string, req models.SearchRequest) string {
+	if region == "" {
+		return "flight:" + Schema + ":" + searchHash(req)
+	}
+	return "flight:" + Schema + ":" + region + ":" + searchHash(req)
+}
+
+// searchHash covers every field that changes what the providers return.
+// Filters, sorting and ranking are applied to the cached flights and stay
+// out of it, apart from excluded providers, which are never called.
+func searchHash(req models.SearchRequest) string {
 	keyData := struct {
-		Origin        string
-		Destination   string
-		DepartureDate string
-		ReturnDate    string
-		Passengers    int
-		CabinClass    string
+		Origin           string
+		Destination      string
+		DepartureDate    string
+		ReturnDate       string
+		Passengers       int
+		CabinClass       string
+		Providers        []string `json:",omitempty"`
+		ExcludeProviders []string `json:",omitempty"`
+		NearbyKm         int      `json:",omitempty"`
 	}{
-		Origin:        req.Origin,
-		Destination:   req.Destination,
+		Origin:        strings.ToUpper(req.Origin),
+		Destination:   strings.ToUpper(req.Destination),
 		DepartureDate: req.DepartureDate,
 		Passengers:    req.Passengers,
-		CabinClass:    req.CabinClass,
+		CabinClass:    strings.ToLower(req.CabinClass),
+		NearbyKm:      req.NearbyKm,
 	}
 
 	if req.ReturnDate != nil {
 		keyData.ReturnDate = *req.ReturnDate
 	}
 
+	// A search restricted to some providers only holds their flights, so it
+	// must not share an entry with the full search.
+	if req.RestrictsProviders() {
+		keyData.Providers = sortedLower(req.Providers)
+		if req.Filters != nil {
+			keyData.ExcludeProviders = sortedLower(req.Filters.ExcludeProviders)
+		}
+	}
+
 	data, _ := json.Marshal(keyData)
 	hash := sha256.Sum256(data)
-	return "flight:" + hex.EncodeToString(hash[:])
+	return hex.EncodeToString(hash[:])
+}
+
+func sortedLower(list []string) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = strings.ToLower(s)
+	}
+	sort.Strings(out)
+	return out
 }
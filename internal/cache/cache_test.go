@@ -0,0 +1,247 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+func searchReq() models.SearchRequest {
+	return models.SearchRequest{Origin: "CGK", Destination: "DPS", DepartureDate: "2025-12-15", Passengers: 1, CabinClass: "economy"}
+}
+
+func TestRegionKeySeparatesEntries(t *testing.T) {
+	base := searchReq()
+	allowed := searchReq()
+	allowed.Providers = []string{"garuda"}
+	excluded := searchReq()
+	excluded.Filters = &models.SearchFilters{ExcludeProviders: []string{"garuda"}}
+	filtered := searchReq()
+	filtered.Filters = &models.SearchFilters{Airlines: []string{"GA"}}
+
+	keys := map[string]string{
+		"base":              regionKey("", base),
+		"region":            regionKey("sg", base),
+		"other region":      regionKey("jkt", base),
+		"allowlist":         regionKey("", allowed),
+		"excluded provider": regionKey("", excluded),
+	}
+	seen := make(map[string]string)
+	for name, key := range keys {
+		if other, ok := seen[key]; ok {
+			t.Errorf("%s and %s share key %s", name, other, key)
+		}
+		seen[key] = name
+	}
+
+	if regionKey("", filtered) != keys["base"] {
+		t.Error("filters other than excluded providers changed the key")
+	}
+	if !strings.HasPrefix(keys["region"], "flight:"+Schema+":sg:") {
+		t.Errorf("region key %s lacks the schema and region", keys["region"])
+	}
+
+	old := Schema
+	Schema = "v0.test"
+	defer func() { Schema = old }()
+	if regionKey("sg", base) == keys["region"] {
+		t.Error("schema change kept the key")
+	}
+}
+
+func TestSearchHashNormalizesProviders(t *testing.T) {
+	a, b := searchReq(), searchReq()
+	a.Providers = []string{"Garuda", "lionair"}
+	b.Providers = []string{"LIONAIR", "garuda"}
+	if searchHash(a) != searchHash(b) {
+		t.Error("provider order and case changed the hash")
+	}
+
+	c := searchReq()
+	c.Origin, c.CabinClass = "cgk", "ECONOMY"
+	if searchHash(c) != searchHash(searchReq()) {
+		t.Error("airport and cabin case changed the hash")
+	}
+}
+
+func TestFlushOnlyDeletesFlights(t *testing.T) {
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: fakeRedis(t), Protocol: 2})
+	c := &RedisCache{client: client, ttl: time.Minute, region: "sg", encoding: JSON}
+	defer c.Close()
+
+	flights := Entry{Flights: []models.Flight{{ID: "GA-001"}}}
+	for i := range 150 {
+		req := searchReq()
+		req.Passengers = i + 1
+		if err := c.Set(ctx, req, flights); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range []string{"ratelimit:garuda", "pricehistory:CGK-DPS", "subscription:1"} {
+		if err := client.Set(ctx, key, "x", 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := c.Flush(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 150 {
+		t.Errorf("deleted %d entries, want 150", deleted)
+	}
+	if _, ok := c.Get(ctx, searchReq()); ok {
+		t.Error("entry survived the flush")
+	}
+	for _, key := range []string{"ratelimit:garuda", "pricehistory:CGK-DPS", "subscription:1"} {
+		if err := client.Get(ctx, key).Err(); err != nil {
+			t.Errorf("%s: %v", key, err)
+		}
+	}
+}
+
+// fakeStore holds the keys of a fake Redis.
+type fakeStore struct {
+	data map[string]string
+	// order lists keys as first set, so SCAN cursors stay valid while keys
+	// are deleted, as Redis guarantees.
+	order []string
+}
+
+// fakeRedis serves the handful of commands the cache sends over RESP2,
+// scanning a few keys at a time to exercise pagination.
+func fakeRedis(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no loopback listener: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var mu sync.Mutex
+	store := &fakeStore{data: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+				for {
+					args, err := readCommand(r)
+					if err != nil {
+						return
+					}
+					mu.Lock()
+					store.serve(w, args)
+					mu.Unlock()
+					if w.Flush() != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func (s *fakeStore) serve(w *bufio.Writer, args []string) {
+	data := s.data
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		w.WriteString("+PONG\r\n")
+	case "SET":
+		if _, ok := data[args[1]]; !ok {
+			s.order = append(s.order, args[1])
+		}
+		data[args[1]] = args[2]
+		w.WriteString("+OK\r\n")
+	case "GET":
+		v, ok := data[args[1]]
+		if !ok {
+			w.WriteString("$-1\r\n")
+			return
+		}
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case "UNLINK":
+		n := 0
+		for _, k := range args[1:] {
+			if _, ok := data[k]; ok {
+				delete(data, k)
+				n++
+			}
+		}
+		fmt.Fprintf(w, ":%d\r\n", n)
+	case "SCAN":
+		cursor, _ := strconv.Atoi(args[1])
+		match := "*"
+		for i := 2; i+1 < len(args); i += 2 {
+			if strings.EqualFold(args[i], "MATCH") {
+				match = args[i+1]
+			}
+		}
+		end := min(cursor+7, len(s.order))
+		var page []string
+		for _, k := range s.order[min(cursor, end):end] {
+			if _, ok := data[k]; !ok {
+				continue
+			}
+			if ok, _ := path.Match(match, k); ok {
+				page = append(page, k)
+			}
+		}
+		next := strconv.Itoa(end)
+		if end == len(s.order) {
+			next = "0"
+		}
+		fmt.Fprintf(w, "*2\r\n$%d\r\n%s\r\n*%d\r\n", len(next), next, len(page))
+		for _, k := range page {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(k), k)
+		}
+	default:
+		fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+		if err != nil {
+			return nil, err
+		}
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(r, arg); err != nil {
+			return nil, err
+		}
+		args[i] = string(arg[:size])
+	}
+	return args, nil
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Encoding is how entries are serialized in Redis. Every entry starts
+// with its encoding's format byte, so a deployment can switch encodings
+// without invalidating what is cached: entries are read in whatever
+// format they were written.
+type Encoding byte
+
+const (
+	// JSON stores entries as written by encoding/json.
+	JSON Encoding = 1
+	// GzipJSON compresses the JSON, typically to a tenth of its size for
+	// the repetitive flight lists of popular routes.
+	GzipJSON Encoding = 2
+)
+
+var encodings = map[string]Encoding{
+	"json": JSON,
+	"gzip": GzipJSON,
+}
+
+// ParseEncoding reads an encoding by name, "json" or "gzip".
+func ParseEncoding(name string) (Encoding, error) {
+	if e, ok := encodings[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return e, nil
+	}
+	names := make([]string, 0, len(encodings))
+	for n := range encodings {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return 0, fmt.Errorf("unknown cache encoding %q, want one of %s", name, strings.Join(names, ", "))
+}
+
+func (e Encoding) String() string {
+	for name, enc := range encodings {
+		if enc == e {
+			return name
+		}
+	}
+	return fmt.Sprintf("encoding(%d)", byte(e))
+}
+
+// marshal encodes v behind its format byte. The zero Encoding is JSON.
+func (e Encoding) marshal(v any) ([]byte, error) {
+	if e == 0 {
+		e = JSON
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(byte(e))
+	switch e {
+	case JSON:
+		if err := json.NewEncoder(&buf).Encode(v); err != nil {
+			return nil, err
+		}
+	case GzipJSON:
+		zw := gzip.NewWriter(&buf)
+		if err := json.NewEncoder(zw).Encode(v); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("cache: cannot encode with %s", e)
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshal decodes data in any encoding, and the bare JSON written before
+// entries had a format byte.
+func unmarshal(data []byte, v any) error {
+	if len(data) == 0 {
+		return fmt.Errorf("cache: empty entry")
+	}
+	var r io.Reader
+	switch Encoding(data[0]) {
+	case JSON:
+		r = bytes.NewReader(data[1:])
+	case GzipJSON:
+		zr, err := gzip.NewReader(bytes.NewReader(data[1:]))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return json.Unmarshal(data, v)
+	}
+	return json.NewDecoder(r).Decode(v)
+}
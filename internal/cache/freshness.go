@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"slices"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Freshness is the oldest each provider's cached flights may be served,
+// for providers whose fares move faster than the cache TTL. Providers
+// without a limit are served for as long as the entry lives.
+type Freshness map[string]time.Duration
+
+// Stale lists, sorted, the providers in entry whose flights are older
+// than their limit at now.
+func (f Freshness) Stale(entry Entry, now time.Time) []string {
+	var stale []string
+	for provider, fetched := range entry.FetchedAt {
+		if limit, ok := f[provider]; ok && now.Sub(fetched) > limit {
+			stale = append(stale, provider)
+		}
+	}
+	slices.Sort(stale)
+	return stale
+}
+
+// ByProvider splits the entry's flights by the provider that returned
+// them. Every provider in FetchedAt has a list, if an empty one.
+func (e Entry) ByProvider() map[string][]models.Flight {
+	lists := make(map[string][]models.Flight, len(e.FetchedAt))
+	for provider := range e.FetchedAt {
+		lists[provider] = []models.Flight{}
+	}
+	for _, f := range e.Flights {
+		lists[f.Provider] = append(lists[f.Provider], f)
+	}
+	return lists
+}
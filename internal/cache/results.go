@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Namespace scopes processed results. Raw provider data is shared by every
+// caller under the flight: keys, but results shaped for a tenant (ranked,
+// filtered, priced and redacted its way) must only be served back to it.
+type Namespace string
+
+// AnonymousNamespace holds the results of callers without an API key.
+const AnonymousNamespace Namespace = "anonymous"
+
+// TenantNamespace is the namespace of tenant's processed results. The name
+// is escaped, so no tenant can address another's entries by picking a name
+// with separators in it.
+func TenantNamespace(tenant string) Namespace {
+	if tenant == "" {
+		return AnonymousNamespace
+	}
+	return Namespace("tenant:" + url.QueryEscape(tenant))
+}
+
+// ResultCache keeps processed search results. Every lookup names its
+// namespace; entries never cross namespaces.
+type ResultCache interface {
+	Get(ctx context.Context, ns Namespace, key string) ([]models.Flight, bool)
+	Set(ctx context.Context, ns Namespace, key string, flights []models.Flight) error
+}
+
+// ResultKey identifies a processed result by everything it was built from,
+// typically the EntryHash of the raw flights and the shaping options. A
+// new raw entry thus never finds the results of the old one, and, as the
+// key covers the Schema, results of another Flight shape are never read.
+func ResultKey(parts ...string) string {
+	h := sha256.New()
+	h.Write([]byte(Schema))
+	h.Write([]byte{0})
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type resultSlot struct {
+	ns  Namespace
+	key string
+}
+
+type memoryResult struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+type MemoryResultCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[resultSlot]memoryResult
+	lastSweep time.Time
+}
+
+func NewMemoryResultCache(ttl time.Duration) *MemoryResultCache {
+	return &MemoryResultCache{ttl: ttl, entries: make(map[resultSlot]memoryResult), lastSweep: time.Now()}
+}
+
+// Get decodes a fresh copy on every hit, so callers may modify the
+// flights they are given.
+func (c *MemoryResultCache) Get(ctx context.Context, ns Namespace, key string) ([]models.Flight, bool) {
+	c.mu.Lock()
+	e, ok := c.entries[resultSlot{ns, key}]
+	c.mu.Unlock()
+	if !ok || time.Now().After(e.expiresAt) {
+		return nil, false
+	}
+	var flights []models.Flight
+	if err := json.Unmarshal(e.data, &flights); err != nil {
+		return nil, false
+	}
+	return flights, true
+}
+
+func (c *MemoryResultCache) Set(ctx context.Context, ns Namespace, key string, flights []models.Flight) error {
+	data, err := json.Marshal(flights)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastSweep) > c.ttl {
+		for slot, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, slot)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.entries[resultSlot{ns, key}] = memoryResult{data: data, expiresAt: now.Add(c.ttl)}
+	return nil
+}
+
+// RedisResultCache stores results under "results:<region>:<namespace>:<key>",
+// apart from the raw flight: entries, and is not replicated: another
+// region ranks its own copy of the raw entry.
+type RedisResultCache struct {
+	client   redis.UniversalClient
+	ttl      time.Duration
+	region   string
+	encoding Encoding
+}
+
+func NewRedisResultCache(client redis.UniversalClient, ttl time.Duration, region string, encoding Encoding) *RedisResultCache {
+	return &RedisResultCache{client: client, ttl: ttl, region: region, encoding: encoding}
+}
+
+func (c *RedisResultCache) redisKey(ns Namespace, key string) string {
+	if c.region == "" {
+		return "results:" + string(ns) + ":" + key
+	}
+	return "results:" + c.region + ":" + string(ns) + ":" + key
+}
+
+func (c *RedisResultCache) Get(ctx context.Context, ns Namespace, key string) ([]models.Flight, bool) {
+	data, err := c.client.Get(ctx, c.redisKey(ns, key)).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	var flights []models.Flight
+	if err := unmarshal(data, &flights); err != nil {
+		return nil, false
+	}
+	return flights, true
+}
+
+func (c *RedisResultCache) Set(ctx context.Context, ns Namespace, key string, flights []models.Flight) error {
+	data, err := c.encoding.marshal(flights)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, c.redisKey(ns, key), data, c.ttl).Err()
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// KeyVersion is bumped when the key layout, or what cached flights mean,
+// changes without the Flight type changing.
+const KeyVersion = 2
+
+// Schema tags every cache key with KeyVersion and a fingerprint of the
+// Flight type. A deployment whose flights encode differently never reads
+// another's entries, so JSON of an old shape is never decoded into the new
+// structs: those entries are missed and expire with their TTL.
+var Schema = fmt.Sprintf("v%d.%s", KeyVersion, fingerprint(reflect.TypeOf(models.Flight{})))
+
+func fingerprint(t reflect.Type) string {
+	h := sha256.New()
+	describe(h, t, make(map[reflect.Type]bool))
+	return hex.EncodeToString(h.Sum(nil))[:8]
+}
+
+var (
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// describe writes t's shape as encoding/json sees it: the exported fields
+// of structs with their JSON tags, recursively. Types that encode
+// themselves are described by name.
+func describe(w io.Writer, t reflect.Type, seen map[reflect.Type]bool) {
+	if t.Implements(jsonMarshaler) || t.Implements(textMarshaler) {
+		fmt.Fprint(w, t.String())
+		return
+	}
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		fmt.Fprint(w, t.Kind(), " ")
+		describe(w, t.Elem(), seen)
+	case reflect.Map:
+		fmt.Fprint(w, "map[", t.Key().Kind(), "]")
+		describe(w, t.Elem(), seen)
+	case reflect.Struct:
+		if seen[t] {
+			fmt.Fprint(w, t.String())
+			return
+		}
+		seen[t] = true
+		fmt.Fprint(w, "{")
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			fmt.Fprintf(w, "%s %q ", f.Name, f.Tag.Get("json"))
+			describe(w, f.Type, seen)
+			fmt.Fprint(w, ";")
+		}
+		fmt.Fprint(w, "}")
+	default:
+		fmt.Fprint(w, t.Kind())
+	}
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+type Stats struct {
+	Hits int64 `json:"hits"`
+	// NegativeHits are the hits on entries with no flights, counted in
+	// Hits too.
+	NegativeHits int64   `json:"negative_hits"`
+	Misses       int64   `json:"misses"`
+	Sets         int64   `json:"sets"`
+	Errors       int64   `json:"errors"`
+	HitRate      float64 `json:"hit_rate"`
+}
+
+// InstrumentedCache counts hits, misses and writes of the wrapped cache.
+type InstrumentedCache struct {
+	next     Cache
+	hits     atomic.Int64
+	negative atomic.Int64
+	misses   atomic.Int64
+	sets     atomic.Int64
+	errors   atomic.Int64
+}
+
+func NewInstrumentedCache(next Cache) *InstrumentedCache {
+	return &InstrumentedCache{next: next}
+}
+
+func (c *InstrumentedCache) Get(ctx context.Context, req models.SearchRequest) (Entry, bool) {
+	entry, found := c.next.Get(ctx, req)
+	if found {
+		c.hits.Add(1)
+		if entry.Negative() {
+			c.negative.Add(1)
+		}
+	} else {
+		c.misses.Add(1)
+	}
+	return entry, found
+}
+
+func (c *InstrumentedCache) Set(ctx context.Context, req models.SearchRequest, entry Entry) error {
+	err := c.next.Set(ctx, req, entry)
+	if err != nil {
+		c.errors.Add(1)
+	} else {
+		c.sets.Add(1)
+	}
+	return err
+}
+
+func (c *InstrumentedCache) Flush(ctx context.Context) (int64, error) {
+	return c.next.Flush(ctx)
+}
+
+func (c *InstrumentedCache) Close() error {
+	return c.next.Close()
+}
+
+func (c *InstrumentedCache) Stats() Stats {
+	s := Stats{
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negative.Load(),
+		Misses:       c.misses.Load(),
+		Sets:         c.sets.Load(),
+		Errors:       c.errors.Load(),
+	}
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRate = float64(s.Hits) / float64(total)
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package configsnap
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// Change is one setting that differs between two snapshots. Field is the
+// setting's JSON path, e.g. "provider_limits.garuda.burst_size"; From or
+// To is nil where the setting is absent.
+type Change struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// Diff lists the settings that differ from one snapshot to the other,
+// sorted by field.
+func Diff(from, to Snapshot) []Change {
+	before, after := flatten(from), flatten(to)
+
+	changes := make([]Change, 0)
+	for field, v := range before {
+		if w, ok := after[field]; !ok || !reflect.DeepEqual(v, w) {
+			changes = append(changes, Change{Field: field, From: v, To: after[field]})
+		}
+	}
+	for field, w := range after {
+		if _, ok := before[field]; !ok {
+			changes = append(changes, Change{Field: field, To: w})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// flatten maps each leaf of the snapshot's JSON encoding to its dotted
+// path. Lists such as feature_flags are compared as a whole.
+func flatten(s Snapshot) map[string]any {
+	data, _ := json.Marshal(s)
+	var doc map[string]any
+	_ = json.Unmarshal(data, &doc)
+
+	leaves := make(map[string]any)
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			leaves[prefix] = v
+			return
+		}
+		for k, child := range obj {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			walk(k, child)
+		}
+	}
+	walk("", doc)
+	return leaves
+}
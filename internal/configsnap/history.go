@@ -0,0 +1,146 @@
+package configsnap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+var ErrUnknownVersion = errorsx.New(errorsx.NotFound, "unknown config version")
+
+// maxHistory bounds the history; older versions are dropped.
+const maxHistory = 100
+
+type Entry struct {
+	Version string `json:"version"`
+	// RecordedAt is when a replica first served with this configuration.
+	RecordedAt time.Time `json:"recorded_at"`
+	Config     Snapshot  `json:"config"`
+}
+
+// History records each change of the effective configuration. Replicas
+// record their snapshot at startup; a snapshot equal to the latest entry
+// is not a change and is not recorded again.
+type History interface {
+	// Record reports whether snap was a change.
+	Record(ctx context.Context, snap Snapshot, at time.Time) (bool, error)
+	// List returns the recorded versions, oldest first.
+	List(ctx context.Context) ([]Entry, error)
+}
+
+// Find returns the entry of version in entries.
+func Find(entries []Entry, version string) (Entry, error) {
+	for _, e := range entries {
+		if e.Version == version {
+			return e, nil
+		}
+	}
+	return Entry{}, fmt.Errorf("%w: %s", ErrUnknownVersion, version)
+}
+
+type MemoryHistory struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{}
+}
+
+func (h *MemoryHistory) Record(ctx context.Context, snap Snapshot, at time.Time) (bool, error) {
+	version := snap.Version()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if n := len(h.entries); n > 0 && h.entries[n-1].Version == version {
+		return false, nil
+	}
+	h.entries = append(h.entries, Entry{Version: version, RecordedAt: at, Config: snap})
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	return true, nil
+}
+
+func (h *MemoryHistory) List(ctx context.Context) ([]Entry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Entry(nil), h.entries...), nil
+}
+
+const (
+	redisHistoryKey   = "config:history"
+	maxRecordAttempts = 5
+)
+
+// RedisHistory shares the history between replicas, so it shows every
+// configuration the deployment ran with.
+type RedisHistory struct {
+	client redis.UniversalClient
+}
+
+func NewRedisHistory(client redis.UniversalClient) *RedisHistory {
+	return &RedisHistory{client: client}
+}
+
+func (h *RedisHistory) Record(ctx context.Context, snap Snapshot, at time.Time) (bool, error) {
+	version := snap.Version()
+	data, err := json.Marshal(Entry{Version: version, RecordedAt: at, Config: snap})
+	if err != nil {
+		return false, err
+	}
+
+	for attempt := 0; attempt < maxRecordAttempts; attempt++ {
+		recorded := false
+		err := h.client.Watch(ctx, func(tx *redis.Tx) error {
+			latest, err := tx.LIndex(ctx, redisHistoryKey, -1).Bytes()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return err
+			}
+			if err == nil {
+				var e Entry
+				if err := json.Unmarshal(latest, &e); err != nil {
+					return err
+				}
+				if e.Version == version {
+					return nil
+				}
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.RPush(ctx, redisHistoryKey, data)
+				pipe.LTrim(ctx, redisHistoryKey, -maxHistory, -1)
+				return nil
+			})
+			recorded = err == nil
+			return err
+		}, redisHistoryKey)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return recorded, err
+		}
+	}
+	return false, errors.New("config history: too much contention")
+}
+
+func (h *RedisHistory) List(ctx context.Context) ([]Entry, error) {
+	items, err := h.client.LRange(ctx, redisHistoryKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(items))
+	for _, item := range items {
+		var e Entry
+		if err := json.Unmarshal([]byte(item), &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,85 @@
+// Package configsnap captures the effective configuration that shapes
+// search results, so a past search can be explained and reproduced with
+// the configuration it ran under.
+package configsnap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/dharmasatrya/flightsearch/internal/markup"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/promotions"
+)
+
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	BurstSize         int     `json:"burst_size"`
+}
+
+// Snapshot is the configuration a replica serves searches with. Only
+// settings that can change what a search returns belong here.
+type Snapshot struct {
+	Region         string                `json:"region,omitempty"`
+	RankingWeights models.RankingWeights `json:"ranking_weights"`
+	RankingModel   string                `json:"ranking_model,omitempty"`
+	ProviderCap    int                   `json:"provider_cap"`
+	ResultMerge    string                `json:"result_merge"`
+
+	// ProviderSeed is set in mock and demo mode only; live mode is never
+	// reproducible.
+	ProviderMode string `json:"provider_mode"`
+	ProviderSeed int64  `json:"provider_seed,omitempty"`
+	// ProviderReplay is the directory providers answer from when they
+	// replay recorded responses.
+	ProviderReplay string `json:"provider_replay,omitempty"`
+	// Demo deployments serve synthetic prices drawn from ProviderSeed.
+	Demo bool `json:"demo,omitempty"`
+
+	MaxRetries         int     `json:"max_retries"`
+	RetryBudget        int     `json:"retry_budget"`
+	RetryBaseDelay     string  `json:"retry_base_delay"`
+	RetryMaxDelay      string  `json:"retry_max_delay"`
+	ProviderRetryRatio float64 `json:"provider_retry_ratio"`
+	ProviderTimeout    string  `json:"provider_timeout"`
+	MaxProviderCalls   int     `json:"max_provider_calls"`
+
+	ProviderLimits map[string]RateLimit `json:"provider_limits"`
+	// ProviderFallbacks lists each provider's fallbacks in the order they
+	// are tried, e.g. "amadeus:GA".
+	ProviderFallbacks map[string][]string `json:"provider_fallbacks,omitempty"`
+	StandbyProviders  []string            `json:"standby_providers,omitempty"`
+	// MinProvidersSucceeded and FailEmptyPartial are the partial results
+	// policy; unset, only searches no provider answered fail.
+	MinProvidersSucceeded int  `json:"min_providers_succeeded,omitempty"`
+	FailEmptyPartial      bool `json:"fail_empty_partial,omitempty"`
+	// BookingURLTemplates are the configured overrides of the providers'
+	// own templates.
+	BookingURLTemplates map[string]string `json:"booking_url_templates,omitempty"`
+	AffiliateCodes      map[string]string `json:"affiliate_codes,omitempty"`
+	Promotions          []promotions.Rule `json:"promotions,omitempty"`
+	// Markups are the tenants' pricing policies.
+	Markups map[string]markup.Policy `json:"markups,omitempty"`
+
+	// Brownout settings are empty when brownout is disabled.
+	BrownoutThresholds []float64 `json:"brownout_thresholds,omitempty"`
+	BrownoutTier1      []string  `json:"brownout_tier1_providers,omitempty"`
+
+	CacheTTL          string `json:"cache_ttl"`
+	SearchSnapshotTTL string `json:"search_snapshot_ttl"`
+	// ProviderMaxAge is how old each listed provider's cached flights may
+	// be when served.
+	ProviderMaxAge map[string]string `json:"provider_max_age,omitempty"`
+
+	FeatureFlags []string `json:"feature_flags"`
+}
+
+// Version identifies the snapshot's contents: equal configurations share a
+// version on every replica and across restarts.
+func (s Snapshot) Version() string {
+	// Maps marshal with sorted keys, so the encoding is stable.
+	data, _ := json.Marshal(s)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,253 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Target is the deployment under test.
+type Target struct {
+	BaseURL string
+	Client  *http.Client
+	// Date is the departure date searches use. It should be a date the
+	// deployment has flights for, so filter semantics are checked on real
+	// results.
+	Date       time.Time
+	AdminToken string
+	// APIKey, when set, is sent with every request.
+	APIKey string
+}
+
+type Result struct {
+	Case     string   `json:"case"`
+	Status   int      `json:"status,omitempty"`
+	Skipped  string   `json:"skipped,omitempty"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+type Report struct {
+	Target  string   `json:"target"`
+	Results []Result `json:"results"`
+	Passed  int      `json:"passed"`
+	Failed  int      `json:"failed"`
+	Skipped int      `json:"skipped"`
+}
+
+func (r Report) OK() bool {
+	return r.Failed == 0
+}
+
+func Run(ctx context.Context, t Target, cases []Case) Report {
+	report := Report{Target: t.BaseURL}
+	vars := Vars{
+		"date":        t.Date.Format("2006-01-02"),
+		"return_date": t.Date.AddDate(0, 0, 7).Format("2006-01-02"),
+		"past_date":   time.Now().AddDate(0, 0, -2).Format("2006-01-02"),
+		"admin_token": t.AdminToken,
+	}
+	for _, c := range cases {
+		res := run(ctx, t, vars, c)
+		switch {
+		case res.Skipped != "":
+			report.Skipped++
+		case len(res.Failures) > 0:
+			report.Failed++
+		default:
+			report.Passed++
+		}
+		report.Results = append(report.Results, res)
+	}
+	return report
+}
+
+func run(ctx context.Context, t Target, vars Vars, c Case) Result {
+	res := Result{Case: c.Name}
+	if c.Admin && t.AdminToken == "" {
+		res.Skipped = "no admin token"
+		return res
+	}
+
+	var body io.Reader
+	if len(c.Body) > 0 {
+		body = strings.NewReader(vars.expand(c.body()))
+	}
+	method := c.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(t.BaseURL, "/")+vars.expand(c.Path), body)
+	if err != nil {
+		res.Failures = append(res.Failures, err.Error())
+		return res
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if t.APIKey != "" {
+		req.Header.Set("X-API-Key", t.APIKey)
+	}
+	for name, value := range c.Headers {
+		req.Header.Set(name, vars.expand(value))
+	}
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		res.Failures = append(res.Failures, err.Error())
+		return res
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		res.Failures = append(res.Failures, fmt.Sprintf("read body: %v", err))
+		return res
+	}
+
+	res.Status = resp.StatusCode
+	res.Failures = check(c.Expect, vars, resp.StatusCode, data)
+	return res
+}
+
+// check returns every way the response misses exp.
+func check(exp Expect, vars Vars, status int, data []byte) []string {
+	var failures []string
+	fail := func(format string, args ...any) {
+		failures = append(failures, fmt.Sprintf(format, args...))
+	}
+
+	if status != exp.Status {
+		fail("status %d, want %d: %s", status, exp.Status, snippet(data))
+		return failures
+	}
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		return failures
+	}
+
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		fail("body is not a JSON object: %v", err)
+		return failures
+	}
+
+	if status >= http.StatusBadRequest {
+		failures = append(failures, checkError(exp, status, doc)...)
+	}
+	for path, want := range exp.Values {
+		if w, ok := want.(string); ok {
+			want = vars.expand(w)
+		}
+		got, ok := lookup(doc, path)
+		if !ok {
+			fail("%s is missing", path)
+		} else if !equal(got, want) {
+			fail("%s is %v, want %v", path, got, want)
+		}
+	}
+	if exp.Search {
+		failures = append(failures, checkSearch(data, exp.MinFlights)...)
+	}
+	return failures
+}
+
+// checkError checks the error shape every endpoint shares:
+// {"error": code, "message": text, "code": status}.
+func checkError(exp Expect, status int, doc map[string]any) []string {
+	var failures []string
+	code, _ := doc["error"].(string)
+	if code == "" {
+		failures = append(failures, "error response has no error code")
+	} else if exp.Error != "" && code != exp.Error {
+		failures = append(failures, fmt.Sprintf("error is %q, want %q", code, exp.Error))
+	}
+	if msg, _ := doc["message"].(string); msg == "" {
+		failures = append(failures, "error response has no message")
+	}
+	if n, _ := doc["code"].(json.Number); n.String() != fmt.Sprint(status) {
+		failures = append(failures, fmt.Sprintf("error response code is %v, want %d", doc["code"], status))
+	}
+
+	if len(exp.Fields) == 0 {
+		return failures
+	}
+	listed := make(map[string][]string)
+	errs, _ := doc["errors"].([]any)
+	for _, e := range errs {
+		fe, _ := e.(map[string]any)
+		field, _ := fe["field"].(string)
+		fieldCode, _ := fe["code"].(string)
+		listed[field] = append(listed[field], fieldCode)
+	}
+	for field, want := range exp.Fields {
+		codes, ok := listed[field]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("errors do not list %s", field))
+			continue
+		}
+		found := false
+		for _, c := range codes {
+			found = found || c == want
+		}
+		if !found {
+			failures = append(failures, fmt.Sprintf("errors list %s as %v, want %s", field, codes, want))
+		}
+	}
+	return failures
+}
+
+// lookup follows a dotted path through objects; numeric segments index
+// arrays.
+func lookup(doc any, path string) (any, bool) {
+	cur := doc
+	for _, seg := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case map[string]any:
+			next, ok := v[seg]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case []any:
+			var i int
+			if _, err := fmt.Sscan(seg, &i); err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			cur = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// equal compares a decoded value with one from the suite, numbers by
+// value.
+func equal(got, want any) bool {
+	if n, ok := got.(json.Number); ok {
+		f, err := n.Float64()
+		w, isNum := want.(float64)
+		return err == nil && isNum && f == w
+	}
+	g, _ := json.Marshal(got)
+	w, _ := json.Marshal(want)
+	return bytes.Equal(g, w)
+}
+
+func snippet(data []byte) string {
+	const max = 200
+	s := strings.TrimSpace(string(data))
+	if len(s) > max {
+		s = s[:max] + "..."
+	}
+	return s
+}
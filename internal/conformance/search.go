@@ -0,0 +1,190 @@
+package conformance
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// searchResponse covers one-way and round-trip responses.
+type searchResponse struct {
+	SearchCriteria  *models.SearchCriteria `json:"search_criteria"`
+	Metadata        *models.SearchMetadata `json:"metadata"`
+	Flights         []models.Flight        `json:"flights"`
+	OutboundFlights []models.Flight        `json:"outbound_flights"`
+	ReturnFlights   []models.Flight        `json:"return_flights"`
+}
+
+// checkSearch checks a search response independently of the server's
+// own filtering: each flight must honour the filters and providers the
+// response echoes in search_criteria, and the flights must be in the
+// order its primary sort key asks for. There must be at least minFlights
+// flights across the lists.
+func checkSearch(data []byte, minFlights int) []string {
+	var raw map[string]json.RawMessage
+	var resp searchResponse
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return []string{fmt.Sprintf("not a search response: %v", err)}
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return []string{fmt.Sprintf("not a search response: %v", err)}
+	}
+
+	var failures []string
+	if resp.SearchCriteria == nil {
+		failures = append(failures, "search_criteria is missing")
+	}
+	if resp.Metadata == nil {
+		failures = append(failures, "metadata is missing")
+	}
+	if len(failures) > 0 {
+		return failures
+	}
+
+	lists := []string{"flights"}
+	if _, ok := raw["outbound_flights"]; ok {
+		lists = []string{"outbound_flights", "return_flights"}
+	}
+	total := 0
+	for _, name := range lists {
+		// Clients iterate the lists, so an empty result must be [].
+		if list, ok := raw[name]; !ok || string(list) == "null" {
+			failures = append(failures, name+" must be an array")
+			continue
+		}
+		flights := resp.Flights
+		switch name {
+		case "outbound_flights":
+			flights = resp.OutboundFlights
+		case "return_flights":
+			flights = resp.ReturnFlights
+		}
+		total += len(flights)
+		for i, f := range flights {
+			for _, v := range violations(*resp.SearchCriteria, f) {
+				failures = append(failures, fmt.Sprintf("%s[%d] %s: %s", name, i, f.ID, v))
+			}
+		}
+		failures = append(failures, checkOrder(name, *resp.SearchCriteria, flights)...)
+	}
+	if resp.Metadata.TotalResults != total {
+		failures = append(failures, fmt.Sprintf("metadata.total_results is %d for %d flights", resp.Metadata.TotalResults, total))
+	}
+	if total < minFlights {
+		failures = append(failures, fmt.Sprintf("%d flights, want at least %d; search a date the deployment has flights for", total, minFlights))
+	}
+	return failures
+}
+
+// violations lists the filters and provider restrictions f breaks.
+func violations(sc models.SearchCriteria, f models.Flight) []string {
+	var out []string
+	add := func(format string, args ...any) {
+		out = append(out, fmt.Sprintf(format, args...))
+	}
+
+	// A fallback's flights stand in for a named provider's.
+	if len(sc.Providers) > 0 && !containsFold(sc.Providers, f.Provider) && !containsFold(sc.Providers, f.FallbackFor) {
+		add("provider %s not in providers %v", f.Provider, sc.Providers)
+	}
+	fl := sc.Filters
+	if fl == nil {
+		return out
+	}
+	if containsFold(fl.ExcludeProviders, f.Provider) {
+		add("provider %s is excluded", f.Provider)
+	}
+	if fl.PriceMin != nil && f.Price.Amount < *fl.PriceMin {
+		add("price %.0f below price_min %.0f", f.Price.Amount, *fl.PriceMin)
+	}
+	if fl.PriceMax != nil && f.Price.Amount > *fl.PriceMax {
+		add("price %.0f above price_max %.0f", f.Price.Amount, *fl.PriceMax)
+	}
+	if fl.MaxStops != nil && f.Stops > *fl.MaxStops {
+		add("%d stops above max_stops %d", f.Stops, *fl.MaxStops)
+	}
+	if len(fl.Airlines) > 0 && !containsFold(fl.Airlines, f.Airline.Code) {
+		add("airline %s not in airlines %v", f.Airline.Code, fl.Airlines)
+	}
+	if containsFold(fl.ExcludeAirlines, f.Airline.Code) {
+		add("airline %s is excluded", f.Airline.Code)
+	}
+	if fl.MaxDuration != nil && f.Duration.TotalMinutes > *fl.MaxDuration {
+		add("duration %d min above max_duration %d", f.Duration.TotalMinutes, *fl.MaxDuration)
+	}
+	if !withinTimes(f.Departure.Time, fl.DepartureTimeMin, fl.DepartureTimeMax) {
+		add("departs at %s, outside the departure time filter", f.Departure.Time.Format("15:04"))
+	}
+	if !withinTimes(f.Arrival.Time, fl.ArrivalTimeMin, fl.ArrivalTimeMax) {
+		add("arrives at %s, outside the arrival time filter", f.Arrival.Time.Format("15:04"))
+	}
+	return out
+}
+
+// withinTimes compares the time of day as the flight reports it, in the
+// airport's local time.
+func withinTimes(t time.Time, min, max *string) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if m, ok := timeOfDay(min); ok && minute < m {
+		return false
+	}
+	if m, ok := timeOfDay(max); ok && minute > m {
+		return false
+	}
+	return true
+}
+
+func timeOfDay(s *string) (int, bool) {
+	if s == nil {
+		return 0, false
+	}
+	t, err := time.Parse("15:04", *s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// checkOrder checks the primary sort key only; ties are broken by the
+// keys after it. Best value and ranking profiles are scored by the
+// deployment and not checked.
+func checkOrder(list string, sc models.SearchCriteria, flights []models.Flight) []string {
+	token, _, _ := strings.Cut(sc.SortBy, ",")
+	field, order, hasOrder := strings.Cut(strings.ToLower(strings.TrimSpace(token)), ":")
+	if !hasOrder {
+		order = strings.ToLower(sc.SortOrder)
+	}
+	compare, ok := orderings[types.SortKey(field)]
+	if !ok {
+		return nil
+	}
+	for i := 1; i < len(flights); i++ {
+		c := compare(flights[i-1], flights[i])
+		if types.SortOrder(order) == types.SortDesc {
+			c = -c
+		}
+		if c > 0 {
+			return []string{fmt.Sprintf("%s not sorted by %s %s: %s before %s", list, field, cmp.Or(order, string(types.SortAsc)), flights[i-1].ID, flights[i].ID)}
+		}
+	}
+	return nil
+}
+
+var orderings = map[types.SortKey]func(a, b models.Flight) int{
+	types.SortPrice:     func(a, b models.Flight) int { return cmp.Compare(a.Price.Amount, b.Price.Amount) },
+	types.SortDuration:  func(a, b models.Flight) int { return cmp.Compare(a.Duration.TotalMinutes, b.Duration.TotalMinutes) },
+	types.SortDeparture: func(a, b models.Flight) int { return a.Departure.Time.Compare(b.Departure.Time) },
+	types.SortArrival:   func(a, b models.Flight) int { return a.Arrival.Time.Compare(b.Arrival.Time) },
+	types.SortStops:     func(a, b models.Flight) int { return cmp.Compare(a.Stops, b.Stops) },
+	types.SortEmissions: func(a, b models.Flight) int { return cmp.Compare(a.EmissionsKgCO2, b.EmissionsKgCO2) },
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool { return strings.EqualFold(v, s) })
+}
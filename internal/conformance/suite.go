@@ -0,0 +1,97 @@
+// Package conformance checks a running deployment of the search API from
+// the outside: endpoint behaviour, filter and sort semantics and error
+// shapes. Partners run it against their own deployment; it doubles as our
+// black-box regression suite.
+package conformance
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+//go:embed testdata/suite.json
+var defaultSuite []byte
+
+// Case is one request and what its response must satisfy. Path, headers
+// and body may use variables such as {{date}}; see Vars.
+type Case struct {
+	Name    string            `json:"name"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is sent as JSON. A string body is sent as is, for malformed
+	// requests.
+	Body json.RawMessage `json:"body,omitempty"`
+	// Admin cases need an admin token and are skipped without one.
+	Admin  bool   `json:"admin,omitempty"`
+	Expect Expect `json:"expect"`
+}
+
+type Expect struct {
+	Status int `json:"status"`
+	// Error is the error code of an error response. Every error response
+	// is also checked for the error, message and code fields.
+	Error string `json:"error,omitempty"`
+	// Fields maps each field a validation_error must list to its code.
+	Fields map[string]string `json:"fields,omitempty"`
+	// Values maps dotted JSON paths, e.g. "search_criteria.origin", to
+	// the values they must hold.
+	Values map[string]any `json:"values,omitempty"`
+	// Search checks the body is a search response whose flights honour
+	// the filters, sort and providers it echoes in search_criteria.
+	Search bool `json:"search,omitempty"`
+	// MinFlights is the fewest flights a search must return, so filter
+	// and sort semantics aren't passed vacuously on an empty result.
+	MinFlights int `json:"min_flights,omitempty"`
+}
+
+// LoadSuite reads the cases at path, or the built-in suite when path is
+// empty.
+func LoadSuite(path string) ([]Case, error) {
+	data := defaultSuite
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	var cases []Case
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("parse suite: %w", err)
+	}
+
+	seen := make(map[string]bool, len(cases))
+	for i, c := range cases {
+		if c.Name == "" {
+			return nil, fmt.Errorf("suite case %d has no name", i)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("duplicate suite case %q", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return cases, nil
+}
+
+func (c Case) body() string {
+	var s string
+	if json.Unmarshal(c.Body, &s) == nil {
+		return s
+	}
+	return string(c.Body)
+}
+
+// Vars are substituted for {{name}} in a case's path, headers and body.
+// The runner sets date, return_date and past_date from its search date,
+// and admin_token.
+type Vars map[string]string
+
+func (v Vars) expand(s string) string {
+	for name, value := range v {
+		s = strings.ReplaceAll(s, "{{"+name+"}}", value)
+	}
+	return s
+}
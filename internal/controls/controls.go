@@ -0,0 +1,172 @@
+// Package controls applies operators' runtime overrides of providers:
+// taking one out of rotation, or holding it to another rate limit than
+// configured, without a redeploy. Overrides are kept in a Store shared by
+// every replica; each applies them on Sync.
+package controls
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/configsnap"
+	"github.com/dharmasatrya/flightsearch/internal/ratelimit"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// validateRateLimit checks a rate limit an operator asks for.
+func validateRateLimit(l configsnap.RateLimit) error {
+	var errs errorsx.ValidationErrors
+	if l.RequestsPerSecond <= 0 {
+		errs.Add("requests_per_second", types.FieldOutOfRange, "requests_per_second must be positive")
+	}
+	if l.BurstSize < 1 {
+		errs.Add("burst_size", types.FieldOutOfRange, "burst_size must be at least 1")
+	}
+	return errs.Err()
+}
+
+type Controls struct {
+	store      Store
+	agg        *aggregator.Aggregator
+	limiter    ratelimit.Limiter
+	configured map[string]configsnap.RateLimit
+	defaults   configsnap.RateLimit
+
+	mu sync.Mutex
+	// limits are the overridden rate limits applied to the limiter.
+	limits map[string]configsnap.RateLimit
+}
+
+// New controls agg's providers and their limits in limiter. Clearing a
+// provider's rate limit override restores its configured limit, or
+// defaults if it has none.
+func New(store Store, agg *aggregator.Aggregator, limiter ratelimit.Limiter, configured map[string]configsnap.RateLimit, defaults configsnap.RateLimit) *Controls {
+	return &Controls{
+		store:      store,
+		agg:        agg,
+		limiter:    limiter,
+		configured: configured,
+		defaults:   defaults,
+		limits:     make(map[string]configsnap.RateLimit),
+	}
+}
+
+func (c *Controls) Overrides(ctx context.Context) (map[string]Override, error) {
+	return c.store.Load(ctx)
+}
+
+// Disable takes provider out of rotation on every replica.
+func (c *Controls) Disable(ctx context.Context, provider, reason string) (Override, error) {
+	return c.update(ctx, provider, func(o *Override) {
+		o.Disabled, o.Reason = true, reason
+	})
+}
+
+func (c *Controls) Enable(ctx context.Context, provider string) (Override, error) {
+	return c.update(ctx, provider, func(o *Override) {
+		o.Disabled, o.Reason = false, ""
+	})
+}
+
+func (c *Controls) SetRateLimit(ctx context.Context, provider string, l configsnap.RateLimit) (Override, error) {
+	if err := validateRateLimit(l); err != nil {
+		return Override{}, err
+	}
+	return c.update(ctx, provider, func(o *Override) {
+		o.RateLimit = &l
+	})
+}
+
+// ClearRateLimit restores provider's configured rate limit.
+func (c *Controls) ClearRateLimit(ctx context.Context, provider string) (Override, error) {
+	return c.update(ctx, provider, func(o *Override) {
+		o.RateLimit = nil
+	})
+}
+
+// update changes provider's override and applies it on this replica at
+// once; the others pick it up on their next Sync.
+func (c *Controls) update(ctx context.Context, provider string, fn func(*Override)) (Override, error) {
+	if _, ok := c.agg.Provider(provider); !ok {
+		return Override{}, errorsx.Errorf(errorsx.NotFound, "Unknown provider %s", provider)
+	}
+	var updated Override
+	err := c.store.Update(ctx, provider, func(o *Override) {
+		fn(o)
+		o.UpdatedAt = time.Now().UTC()
+		updated = *o
+	})
+	if err != nil {
+		return Override{}, err
+	}
+	return updated, c.Sync(ctx)
+}
+
+// Sync applies the store's overrides to this replica.
+func (c *Controls) Sync(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	overrides, err := c.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	var disabled []string
+	for name, o := range overrides {
+		if o.Disabled {
+			disabled = append(disabled, name)
+		}
+	}
+	slices.Sort(disabled)
+	c.agg.SetDisabled(disabled)
+
+	// The limiter starts a provider's bucket afresh when its limit is set,
+	// so only changed limits are.
+	for name, o := range overrides {
+		if o.RateLimit == nil {
+			continue
+		}
+		if applied, ok := c.limits[name]; !ok || applied != *o.RateLimit {
+			c.limiter.SetProviderLimit(name, o.RateLimit.RequestsPerSecond, o.RateLimit.BurstSize)
+			c.limits[name] = *o.RateLimit
+		}
+	}
+	for name := range c.limits {
+		if o, ok := overrides[name]; !ok || o.RateLimit == nil {
+			l := c.Configured(name)
+			c.limiter.SetProviderLimit(name, l.RequestsPerSecond, l.BurstSize)
+			delete(c.limits, name)
+		}
+	}
+	return nil
+}
+
+// Configured is provider's rate limit without overrides.
+func (c *Controls) Configured(provider string) configsnap.RateLimit {
+	if l, ok := c.configured[provider]; ok {
+		return l
+	}
+	return c.defaults
+}
+
+// Watch syncs c every interval until ctx is done.
+func Watch(ctx context.Context, c *Controls, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := c.Sync(ctx); err != nil {
+			log.Printf("Provider controls sync failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package controls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/configsnap"
+)
+
+// Override is what an operator changed about a provider at runtime. The
+// zero value changes nothing and is not stored.
+type Override struct {
+	Disabled bool `json:"disabled,omitempty"`
+	// Reason says why the provider was taken out of rotation.
+	Reason string `json:"reason,omitempty"`
+	// RateLimit replaces the provider's configured rate limit.
+	RateLimit *configsnap.RateLimit `json:"rate_limit,omitempty"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+func (o Override) empty() bool {
+	return !o.Disabled && o.RateLimit == nil
+}
+
+type Store interface {
+	Load(ctx context.Context) (map[string]Override, error)
+	// Update applies fn to provider's override, deleting it when fn leaves
+	// it empty.
+	Update(ctx context.Context, provider string, fn func(*Override)) error
+}
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	overrides map[string]Override
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{overrides: make(map[string]Override)}
+}
+
+func (s *MemoryStore) Load(ctx context.Context) (map[string]Override, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string]Override, len(s.overrides))
+	for name, o := range s.overrides {
+		result[name] = o
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, provider string, fn func(*Override)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.overrides[provider]
+	fn(&o)
+	if o.empty() {
+		delete(s.overrides, provider)
+	} else {
+		s.overrides[provider] = o
+	}
+	return nil
+}
+
+const (
+	redisOverridesKey = "controls:providers"
+	maxUpdateAttempts = 10
+)
+
+// RedisStore keeps every provider's override in one hash, so all replicas
+// apply the same ones.
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Load(ctx context.Context) (map[string]Override, error) {
+	values, err := s.client.HGetAll(ctx, redisOverridesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]Override, len(values))
+	for name, v := range values {
+		var o Override
+		if err := json.Unmarshal([]byte(v), &o); err != nil {
+			continue
+		}
+		result[name] = o
+	}
+	return result, nil
+}
+
+func (s *RedisStore) Update(ctx context.Context, provider string, fn func(*Override)) error {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+			var o Override
+			data, err := tx.HGet(ctx, redisOverridesKey, provider).Bytes()
+			switch {
+			case errors.Is(err, redis.Nil):
+			case err != nil:
+				return err
+			default:
+				if err := json.Unmarshal(data, &o); err != nil {
+					return err
+				}
+			}
+
+			fn(&o)
+			encoded, err := json.Marshal(o)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				if o.empty() {
+					pipe.HDel(ctx, redisOverridesKey, provider)
+				} else {
+					pipe.HSet(ctx, redisOverridesKey, provider, encoded)
+				}
+				return nil
+			})
+			return err
+		}, redisOverridesKey)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("override of %s: too much contention", provider)
+}
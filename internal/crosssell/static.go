@@ -0,0 +1,105 @@
+package crosssell
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/currency"
+)
+
+type staticHotel struct {
+	name    string
+	area    string
+	stars   float64
+	nightly float64
+}
+
+var staticHotels = map[string][]staticHotel{
+	"DPS": {
+		{"Kuta Beach Hotel", "Kuta", 3, 650000},
+		{"Seminyak Villas", "Seminyak", 4, 1450000},
+		{"Ubud Jungle Resort", "Ubud", 5, 2800000},
+		{"Jimbaran Bay Inn", "Jimbaran", 3.5, 900000},
+	},
+	"CGK": {
+		{"Sudirman Business Hotel", "Sudirman", 4, 1100000},
+		{"Airport Transit Hotel", "Soekarno-Hatta", 3, 550000},
+		{"Thamrin Grand", "Thamrin", 5, 2300000},
+	},
+	"SUB": {
+		{"Tunjungan Plaza Hotel", "Tunjungan", 4, 950000},
+		{"Juanda Airport Inn", "Sidoarjo", 3, 450000},
+	},
+	"JOG": {
+		{"Malioboro Heritage", "Malioboro", 4, 850000},
+		{"Prawirotaman Guesthouse", "Prawirotaman", 3, 400000},
+	},
+	"LOP": {
+		{"Senggigi Beach Resort", "Senggigi", 4, 1200000},
+		{"Kuta Lombok Surf Lodge", "Kuta Lombok", 3, 500000},
+	},
+}
+
+type StaticSupplier struct{}
+
+func NewStaticSupplier() *StaticSupplier {
+	return &StaticSupplier{}
+}
+
+func (s *StaticSupplier) Name() string {
+	return "static"
+}
+
+func (s *StaticSupplier) Offers(ctx context.Context, q HotelQuery) ([]models.HotelOffer, error) {
+	checkIn, err := time.Parse("2006-01-02", q.CheckIn)
+	if err != nil {
+		return nil, err
+	}
+	checkOut, err := time.Parse("2006-01-02", q.CheckOut)
+	if err != nil {
+		return nil, err
+	}
+
+	nights := int(checkOut.Sub(checkIn).Hours() / 24)
+	if nights < 1 {
+		nights = 1
+	}
+
+	// Two guests share a room.
+	rooms := (q.Guests + 1) / 2
+	if rooms < 1 {
+		rooms = 1
+	}
+
+	hotels := staticHotels[strings.ToUpper(q.Airport)]
+	offers := make([]models.HotelOffer, len(hotels))
+	for i, h := range hotels {
+		total := h.nightly * float64(nights*rooms)
+		offers[i] = models.HotelOffer{
+			Name:       h.name,
+			Area:       h.area,
+			StarRating: h.stars,
+			Nights:     nights,
+			NightlyRate: models.Price{
+				Amount:    h.nightly,
+				Currency:  "IDR",
+				Formatted: currency.FormatIDR(h.nightly),
+			},
+			TotalPrice: models.Price{
+				Amount:    total,
+				Currency:  "IDR",
+				Formatted: currency.FormatIDR(total),
+			},
+			Supplier: s.Name(),
+		}
+	}
+
+	sort.Slice(offers, func(i, j int) bool {
+		return offers[i].NightlyRate.Amount < offers[j].NightlyRate.Amount
+	})
+
+	return offers, nil
+}
@@ -0,0 +1,121 @@
+package crosssell
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+const maxHotelOffers = 3
+
+type HotelQuery struct {
+	Airport  string
+	CheckIn  string
+	CheckOut string
+	Guests   int
+}
+
+type HotelSupplier interface {
+	Name() string
+	Offers(ctx context.Context, q HotelQuery) ([]models.HotelOffer, error)
+}
+
+type CachedSupplier struct {
+	next    HotelSupplier
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[HotelQuery]cacheEntry
+	// lastSweep is when expired entries were last dropped.
+	lastSweep time.Time
+}
+
+type cacheEntry struct {
+	offers    []models.HotelOffer
+	expiresAt time.Time
+}
+
+func NewCachedSupplier(next HotelSupplier, ttl time.Duration) *CachedSupplier {
+	return &CachedSupplier{
+		next:      next,
+		ttl:       ttl,
+		entries:   make(map[HotelQuery]cacheEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (c *CachedSupplier) Name() string {
+	return c.next.Name()
+}
+
+func (c *CachedSupplier) Offers(ctx context.Context, q HotelQuery) ([]models.HotelOffer, error) {
+	q.Airport = strings.ToUpper(q.Airport)
+
+	c.mu.RLock()
+	entry, ok := c.entries[q]
+	c.mu.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.offers, nil
+	}
+
+	offers, err := c.next.Offers(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	if now.Sub(c.lastSweep) > c.ttl {
+		for k, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.entries[q] = cacheEntry{offers: offers, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+
+	return offers, nil
+}
+
+// Lookup builds the cross-sell block for a search. Without a return date we
+// assume a single night stay.
+func Lookup(ctx context.Context, s HotelSupplier, req models.SearchRequest) (*models.CrossSell, error) {
+	checkIn := req.DepartureDate
+	checkOut := ""
+	if req.ReturnDate != nil && *req.ReturnDate != "" {
+		checkOut = *req.ReturnDate
+	} else {
+		d, err := time.Parse("2006-01-02", checkIn)
+		if err != nil {
+			return nil, err
+		}
+		checkOut = d.AddDate(0, 0, 1).Format("2006-01-02")
+	}
+
+	offers, err := s.Offers(ctx, HotelQuery{
+		Airport:  req.Destination,
+		CheckIn:  checkIn,
+		CheckOut: checkOut,
+		Guests:   req.Passengers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(offers) > maxHotelOffers {
+		offers = offers[:maxHotelOffers]
+	}
+	if offers == nil {
+		offers = []models.HotelOffer{}
+	}
+
+	return &models.CrossSell{
+		CheckIn:  checkIn,
+		CheckOut: checkOut,
+		Hotels:   offers,
+	}, nil
+}
@@ -0,0 +1,123 @@
+// Package deeplink builds the URL that books a flight on its airline's
+// site, with the route, date, flight and party prefilled.
+package deeplink
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Placeholders lists what a template may use, each written in braces,
+// e.g. {origin}.
+var Placeholders = []string{
+	"origin", "destination", "date", "flight_number", "flight_id", "cabin",
+	"adults", "children", "infants", "affiliate",
+}
+
+var placeholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// Template is a booking URL with placeholders in its path or query.
+type Template struct {
+	u *url.URL
+}
+
+func Parse(raw string) (*Template, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an absolute http(s) URL", raw)
+	}
+	for _, m := range placeholder.FindAllStringSubmatch(raw, -1) {
+		if !slices.Contains(Placeholders, m[1]) {
+			return nil, fmt.Errorf("unknown placeholder {%s}, expected one of: %s", m[1], strings.Join(Placeholders, ", "))
+		}
+	}
+	return &Template{u: u}, nil
+}
+
+// Expand fills in the placeholders, escaping the values. Query parameters
+// made only of placeholders that are all empty, such as an unset
+// affiliate code, are left out.
+func (t *Template) Expand(vars map[string]string) string {
+	replace := func(s string) string {
+		return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+			return vars[m[1:len(m)-1]]
+		})
+	}
+
+	u := *t.u
+	u.Path, u.RawPath = replace(t.u.Path), ""
+	if t.u.RawQuery != "" {
+		query, _ := url.ParseQuery(t.u.RawQuery)
+		for key, values := range query {
+			kept := values[:0]
+			for _, v := range values {
+				if expanded := replace(v); expanded != "" || placeholder.ReplaceAllString(v, "") != "" {
+					kept = append(kept, expanded)
+				}
+			}
+			if len(kept) == 0 {
+				delete(query, key)
+				continue
+			}
+			query[key] = kept
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// Builder links flights to their provider's checkout.
+type Builder struct {
+	templates  map[string]*Template
+	affiliates map[string]string
+}
+
+// New parses templates, by provider; affiliates holds each provider's
+// affiliate code, if any.
+func New(templates, affiliates map[string]string) (*Builder, error) {
+	b := &Builder{templates: make(map[string]*Template, len(templates)), affiliates: affiliates}
+	for provider, raw := range templates {
+		t, err := Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("booking URL template for %s: %w", provider, err)
+		}
+		b.templates[provider] = t
+	}
+	return b, nil
+}
+
+// Apply sets the booking URL of every flight whose provider has a
+// template. flights must be a fresh copy, never the cached flights.
+func (b *Builder) Apply(flights []models.Flight, req models.SearchRequest) {
+	adults, children, infants := req.Passengers, 0, 0
+	if mix := req.PassengerMix; mix != nil {
+		adults, children, infants = mix.Adults, len(mix.ChildAges), len(mix.LapInfantAges)
+	}
+	for i, f := range flights {
+		t, ok := b.templates[f.Provider]
+		if !ok {
+			continue
+		}
+		flights[i].BookingURL = t.Expand(map[string]string{
+			"origin":        f.Departure.Airport,
+			"destination":   f.Arrival.Airport,
+			"date":          f.Departure.Time.Format("2006-01-02"),
+			"flight_number": strings.ReplaceAll(f.FlightNumber, " ", ""),
+			"flight_id":     f.ID,
+			"cabin":         f.CabinClass,
+			"adults":        strconv.Itoa(adults),
+			"children":      strconv.Itoa(children),
+			"infants":       strconv.Itoa(infants),
+			"affiliate":     b.affiliates[f.Provider],
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Package demo disguises fixture fares for the public playground, so
+// nothing it shows can be mistaken for, or scraped as, a real price.
+package demo
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/providers"
+	"github.com/dharmasatrya/flightsearch/pkg/currency"
+)
+
+// Prices move by up to PriceJitter either way, rounded to priceStep.
+const (
+	PriceJitter = 0.3
+	priceStep   = 1000
+)
+
+// Provider replaces the prices of the provider it wraps.
+type Provider struct {
+	providers.Provider
+	seed int64
+}
+
+// lockingProvider keeps a wrapped FareLocker locking fares natively.
+type lockingProvider struct {
+	*Provider
+	providers.FareLocker
+}
+
+// Wrap gives p's flights synthetic prices drawn from seed.
+func Wrap(p providers.Provider, seed int64) providers.Provider {
+	d := &Provider{Provider: p, seed: seed}
+	if locker, ok := p.(providers.FareLocker); ok {
+		return &lockingProvider{Provider: d, FareLocker: locker}
+	}
+	return d
+}
+
+// Capabilities are the wrapped provider's, if it describes any.
+func (p *Provider) Capabilities() providers.Capabilities {
+	if d, ok := p.Provider.(providers.Describer); ok {
+		return d.Capabilities()
+	}
+	return providers.Capabilities{}
+}
+
+func (p *Provider) Search(ctx context.Context, req models.SearchRequest) ([]models.Flight, error) {
+	flights, err := p.Provider.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.Flight, len(flights))
+	for i, f := range flights {
+		f.Price.Amount = Price(p.seed, f)
+		f.Price.Formatted = currency.FormatIDR(f.Price.Amount)
+		out[i] = f
+	}
+	return out, nil
+}
+
+// Price is f's synthetic price. It depends only on seed, the flight and
+// its real price, so a flight costs the same on every search and every
+// replica, and sorting by price still means something.
+func Price(seed int64, f models.Flight) float64 {
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, seed)
+	h.Write([]byte(f.Provider + "/" + f.ID + "/" + f.Departure.Time.UTC().Format("2006-01-02")))
+	unit := float64(h.Sum64()>>11) / (1 << 53)
+	factor := 1 - PriceJitter + 2*PriceJitter*unit
+	return math.Max(priceStep, math.Round(f.Price.Amount*factor/priceStep)*priceStep)
+}
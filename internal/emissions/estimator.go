@@ -0,0 +1,95 @@
+package emissions
+
+import (
+	"math"
+
+	"github.com/dharmasatrya/flightsearch/internal/aircraft"
+	"github.com/dharmasatrya/flightsearch/internal/airports"
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Average CO2 per economy passenger for a narrow-body jet at cruise, taken
+// from published ICAO calculator outputs for domestic Indonesian routes.
+// Used when the route's distance is unknown.
+const economyKgPerMinute = 1.5
+
+// Extra emissions for each take-off/landing cycle, attributed to layovers.
+const kgPerStop = 25.0
+
+// kgPerKm is the CO2 per economy passenger and great-circle kilometre, by
+// aircraft category, after the ICAO calculator's per-type fuel burn at
+// typical load factors. Turboprops burn less per seat on the short hops
+// they fly; regional jets carry fewer seats for a similar burn.
+var kgPerKm = map[aircraft.Category]float64{
+	aircraft.Narrowbody:  0.090,
+	aircraft.Widebody:    0.082,
+	aircraft.RegionalJet: 0.125,
+	aircraft.Turboprop:   0.075,
+}
+
+// Flights whose aircraft is unknown are estimated as narrow-bodies, the
+// bulk of the market.
+const defaultCategory = aircraft.Narrowbody
+
+var cabinMultipliers = map[cabin.Class]float64{
+	cabin.Economy:        1.0,
+	cabin.PremiumEconomy: 1.5,
+	cabin.Business:       2.9,
+	cabin.First:          4.0,
+}
+
+func CabinMultiplier(cabinClass string) float64 {
+	if class, ok := cabin.Parse(cabinClass); ok {
+		return cabinMultipliers[class]
+	}
+	return 1.0
+}
+
+// DistanceKm is the great-circle distance flown, through every layover
+// airport. It is false when an airport on the way has no coordinates.
+func DistanceKm(f models.Flight) (float64, bool) {
+	codes := []string{f.Departure.Airport}
+	for _, l := range f.Layovers {
+		codes = append(codes, l.Airport)
+	}
+	codes = append(codes, f.Arrival.Airport)
+
+	total := 0.0
+	for i := 1; i < len(codes); i++ {
+		from, ok1 := airports.Lookup(codes[i-1])
+		to, ok2 := airports.Lookup(codes[i])
+		if !ok1 || !ok2 || !from.HasLocation() || !to.HasLocation() {
+			return 0, false
+		}
+		total += airports.DistanceKm(from, to)
+	}
+	return math.Round(total), true
+}
+
+// EstimatePerPassenger returns the estimated kg of CO2 for one passenger:
+// the distance flown times the aircraft type's factor, or the flight time
+// when the distance is unknown.
+func EstimatePerPassenger(f models.Flight) float64 {
+	var kg float64
+	if km, ok := DistanceKm(f); ok {
+		kg = km * kgPerKm[category(f)]
+	} else {
+		kg = float64(f.Duration.TotalMinutes) * economyKgPerMinute
+	}
+	kg += float64(f.Stops) * kgPerStop
+	kg *= CabinMultiplier(f.CabinClass)
+	return math.Round(kg*10) / 10
+}
+
+func category(f models.Flight) aircraft.Category {
+	for _, raw := range []*string{f.AircraftCode, f.Aircraft} {
+		if raw == nil {
+			continue
+		}
+		if e, ok := aircraft.Normalize(*raw); ok {
+			return e.Category
+		}
+	}
+	return defaultCategory
+}
@@ -0,0 +1,109 @@
+package facets
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/timezone"
+)
+
+type timeBucket struct {
+	value string
+	label string
+	from  int // hour, inclusive
+	to    int // hour, exclusive
+}
+
+var departureBuckets = []timeBucket{
+	{"early_morning", "00:00-06:00", 0, 6},
+	{"morning", "06:00-12:00", 6, 12},
+	{"afternoon", "12:00-18:00", 12, 18},
+	{"evening", "18:00-24:00", 18, 24},
+}
+
+type counter struct {
+	order   []string
+	buckets map[string]*models.FacetBucket
+}
+
+func newCounter() *counter {
+	return &counter{buckets: make(map[string]*models.FacetBucket)}
+}
+
+func (c *counter) add(value, label string, price float64) {
+	b, ok := c.buckets[value]
+	if !ok {
+		b = &models.FacetBucket{Value: value, Label: label, MinPrice: price}
+		c.buckets[value] = b
+		c.order = append(c.order, value)
+	}
+	b.Count++
+	if price < b.MinPrice {
+		b.MinPrice = price
+	}
+}
+
+func (c *counter) list() []models.FacetBucket {
+	result := make([]models.FacetBucket, 0, len(c.order))
+	for _, v := range c.order {
+		result = append(result, *c.buckets[v])
+	}
+	return result
+}
+
+func Build(flights []models.Flight) *models.Facets {
+	airlines := newCounter()
+	stops := newCounter()
+	times := newCounter()
+	providers := newCounter()
+
+	for _, f := range flights {
+		price := f.Price.Amount
+		airlines.add(f.Airline.Code, f.Airline.Name, price)
+		stops.add(stopsValue(f.Stops), "", price)
+		providers.add(f.Provider, "", price)
+
+		hour := timezone.ConvertToTimezone(f.Departure.Time, f.Departure.Airport).Hour()
+		for _, b := range departureBuckets {
+			if hour >= b.from && hour < b.to {
+				times.add(b.value, b.label, price)
+				break
+			}
+		}
+	}
+
+	result := &models.Facets{
+		Airlines:       airlines.list(),
+		Stops:          stops.list(),
+		DepartureTimes: times.list(),
+		Providers:      providers.list(),
+	}
+
+	sort.Slice(result.Airlines, func(i, j int) bool { return result.Airlines[i].Value < result.Airlines[j].Value })
+	sort.Slice(result.Stops, func(i, j int) bool { return result.Stops[i].Value < result.Stops[j].Value })
+	sort.Slice(result.Providers, func(i, j int) bool { return result.Providers[i].Value < result.Providers[j].Value })
+	sort.Slice(result.DepartureTimes, func(i, j int) bool {
+		return bucketIndex(result.DepartureTimes[i].Value) < bucketIndex(result.DepartureTimes[j].Value)
+	})
+
+	return result
+}
+
+// stopsValue groups two or more stops together, matching how max_stops
+// is usually offered in a UI.
+func stopsValue(n int) string {
+	if n >= 2 {
+		return "2+"
+	}
+	return strconv.Itoa(n)
+}
+
+func bucketIndex(value string) int {
+	for i, b := range departureBuckets {
+		if b.value == value {
+			return i
+		}
+	}
+	return len(departureBuckets)
+}
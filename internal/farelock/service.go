@@ -0,0 +1,132 @@
+package farelock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"math"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/providers"
+	"github.com/dharmasatrya/flightsearch/pkg/currency"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+var ErrHoldTooLong = errorsx.New(errorsx.Unprocessable, "requested hold period exceeds maximum")
+
+// Synthetic locks cost 2% of the fare per started day, with a minimum fee.
+const (
+	syntheticDailyRate = 0.02
+	syntheticMinFee    = 25000
+)
+
+type Service struct {
+	store   Store
+	maxHold time.Duration
+}
+
+func NewService(store Store, maxHold time.Duration) *Service {
+	return &Service{store: store, maxHold: maxHold}
+}
+
+func (s *Service) Lock(ctx context.Context, provider providers.Provider, flight models.Flight, passengers int, hold time.Duration) (models.FareLock, error) {
+	if hold > s.maxHold {
+		return models.FareLock{}, ErrHoldTooLong
+	}
+
+	total := flight.Price.Amount * float64(passengers)
+
+	var terms providers.LockTerms
+	native := false
+	if locker, ok := provider.(providers.FareLocker); ok {
+		t, err := locker.LockFare(ctx, flight, passengers, hold)
+		if err != nil {
+			return models.FareLock{}, errorsx.Classify(err, errorsx.Unavailable)
+		}
+		terms = t
+		native = true
+	} else {
+		terms = syntheticTerms(total, hold)
+	}
+
+	now := time.Now()
+	lock := models.FareLock{
+		ID:           newID(),
+		FlightID:     flight.ID,
+		FlightNumber: flight.FlightNumber,
+		Provider:     provider.Name(),
+		Passengers:   passengers,
+		LockedPrice:  idr(total),
+		Fee:          idr(terms.Fee),
+		BalanceDue:   idr(max(total-terms.Fee, 0)),
+		Native:       native,
+		Reference:    terms.Reference,
+		Status:       models.FareLockActive,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(hold),
+	}
+
+	if err := s.store.Save(ctx, lock); err != nil {
+		return models.FareLock{}, err
+	}
+	return lock, nil
+}
+
+func (s *Service) Get(ctx context.Context, id string) (models.FareLock, error) {
+	lock, err := s.store.Get(ctx, id)
+	if err != nil {
+		return models.FareLock{}, err
+	}
+	if lock.Status == models.FareLockActive && time.Now().After(lock.ExpiresAt) {
+		lock.Status = models.FareLockExpired
+	}
+	return lock, nil
+}
+
+// ExpireDue marks active locks whose hold period has passed as expired.
+func (s *Service) ExpireDue(ctx context.Context) error {
+	locks, err := s.store.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, lock := range locks {
+		if now.Before(lock.ExpiresAt) {
+			continue
+		}
+		lock.Status = models.FareLockExpired
+		if err := s.store.Save(ctx, lock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func syntheticTerms(total float64, hold time.Duration) providers.LockTerms {
+	days := math.Ceil(hold.Hours() / 24)
+	if days < 1 {
+		days = 1
+	}
+
+	fee := math.Round(total * syntheticDailyRate * days)
+	if fee < syntheticMinFee {
+		fee = syntheticMinFee
+	}
+	return providers.LockTerms{Fee: fee}
+}
+
+func idr(amount float64) models.Price {
+	return models.Price{
+		Amount:    amount,
+		Currency:  "IDR",
+		Formatted: currency.FormatIDR(amount),
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "lock_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,184 @@
+package farelock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+var ErrNotFound = errorsx.New(errorsx.NotFound, "fare lock not found")
+
+type Store interface {
+	Save(ctx context.Context, lock models.FareLock) error
+	Get(ctx context.Context, id string) (models.FareLock, error)
+	ListActive(ctx context.Context) ([]models.FareLock, error)
+	// Sweep hands locks that are no longer active and expired before the
+	// cutoff to archive and then removes them.
+	Sweep(ctx context.Context, before time.Time, archive func(records []any) error) (int, error)
+}
+
+func sweepable(l models.FareLock, before time.Time) bool {
+	return l.Status != models.FareLockActive && l.ExpiresAt.Before(before)
+}
+
+type MemoryStore struct {
+	mu    sync.RWMutex
+	locks map[string]models.FareLock
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{locks: make(map[string]models.FareLock)}
+}
+
+func (s *MemoryStore) Save(ctx context.Context, lock models.FareLock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locks[lock.ID] = lock
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (models.FareLock, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	lock, ok := s.locks[id]
+	if !ok {
+		return models.FareLock{}, ErrNotFound
+	}
+	return lock, nil
+}
+
+func (s *MemoryStore) ListActive(ctx context.Context) ([]models.FareLock, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]models.FareLock, 0)
+	for _, l := range s.locks {
+		if l.Status == models.FareLockActive {
+			result = append(result, l)
+		}
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) Sweep(ctx context.Context, before time.Time, archive func(records []any) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	var records []any
+	for id, l := range s.locks {
+		if sweepable(l, before) {
+			ids = append(ids, id)
+			records = append(records, l)
+		}
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+	if err := archive(records); err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		delete(s.locks, id)
+	}
+	return len(ids), nil
+}
+
+const (
+	redisLocksKey  = "farelocks"
+	redisActiveKey = "farelocks:active"
+)
+
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Save(ctx context.Context, lock models.FareLock) error {
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+
+	// Not a transaction: on a Redis Cluster the two keys may live on
+	// different nodes. ListActive checks each lock's own status.
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, redisLocksKey, lock.ID, data)
+		if lock.Status == models.FareLockActive {
+			pipe.SAdd(ctx, redisActiveKey, lock.ID)
+		} else {
+			pipe.SRem(ctx, redisActiveKey, lock.ID)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (models.FareLock, error) {
+	data, err := s.client.HGet(ctx, redisLocksKey, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return models.FareLock{}, ErrNotFound
+	}
+	if err != nil {
+		return models.FareLock{}, err
+	}
+
+	var lock models.FareLock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return models.FareLock{}, err
+	}
+	return lock, nil
+}
+
+func (s *RedisStore) ListActive(ctx context.Context) ([]models.FareLock, error) {
+	ids, err := s.client.SMembers(ctx, redisActiveKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.FareLock, 0, len(ids))
+	for _, id := range ids {
+		lock, err := s.Get(ctx, id)
+		if err != nil || lock.Status != models.FareLockActive {
+			continue
+		}
+		result = append(result, lock)
+	}
+	return result, nil
+}
+
+func (s *RedisStore) Sweep(ctx context.Context, before time.Time, archive func(records []any) error) (int, error) {
+	values, err := s.client.HGetAll(ctx, redisLocksKey).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []string
+	var records []any
+	for id, v := range values {
+		var lock models.FareLock
+		if err := json.Unmarshal([]byte(v), &lock); err != nil {
+			continue
+		}
+		if sweepable(lock, before) {
+			ids = append(ids, id)
+			records = append(records, lock)
+		}
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+	if err := archive(records); err != nil {
+		return 0, err
+	}
+	return len(ids), s.client.HDel(ctx, redisLocksKey, ids...).Err()
+}
@@ -0,0 +1,68 @@
+package farerules
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/currency"
+)
+
+func QuoteRefund(flight models.Flight, paid float64, cancelAt time.Time) models.RefundQuote {
+	r := Lookup(flight.Airline.Code, flight.CabinClass)
+	hoursBefore := flight.Departure.Time.Sub(cancelAt).Hours()
+
+	var penalty, fee, refund float64
+	var summary string
+
+	switch {
+	case !r.Refundable:
+		penalty = paid
+		summary = "This fare is non-refundable"
+	case hoursBefore < 0 && !r.NoShowRefund:
+		penalty = paid
+		summary = "No refund after departure (no-show)"
+	default:
+		pct := penaltyPercent(r.PenaltyTiers, hoursBefore)
+		penalty = math.Round(paid * pct / 100)
+		fee = math.Min(r.CancellationFee, paid-penalty)
+		refund = paid - penalty - fee
+		summary = fmt.Sprintf("Cancel for %s in penalties and fees, %s refunded",
+			currency.FormatIDR(penalty+fee), currency.FormatIDR(refund))
+	}
+
+	return models.RefundQuote{
+		FlightID:        flight.ID,
+		FlightNumber:    flight.FlightNumber,
+		Airline:         flight.Airline,
+		FareRules:       r,
+		CancelAt:        cancelAt,
+		HoursBefore:     math.Round(hoursBefore*10) / 10,
+		PaidAmount:      idr(paid),
+		Penalty:         idr(penalty),
+		CancellationFee: idr(fee),
+		RefundAmount:    idr(refund),
+		Summary:         summary,
+	}
+}
+
+func penaltyPercent(tiers []models.PenaltyTier, hoursBefore float64) float64 {
+	for _, t := range tiers {
+		if hoursBefore >= float64(t.MinHoursBeforeDeparture) {
+			return t.PenaltyPercent
+		}
+	}
+	if len(tiers) > 0 {
+		return tiers[len(tiers)-1].PenaltyPercent
+	}
+	return 0
+}
+
+func idr(amount float64) models.Price {
+	return models.Price{
+		Amount:    amount,
+		Currency:  "IDR",
+		Formatted: currency.FormatIDR(amount),
+	}
+}
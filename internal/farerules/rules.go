@@ -0,0 +1,63 @@
+package farerules
+
+import (
+	"strings"
+
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Simplified published fare conditions per airline and cabin. Tiers are
+// checked in order; the first one whose MinHoursBeforeDeparture is met wins.
+var rules = map[string]models.FareRules{
+	"GA:economy": {
+		Refundable:      true,
+		CancellationFee: 150000,
+		PenaltyTiers: []models.PenaltyTier{
+			{MinHoursBeforeDeparture: 72, PenaltyPercent: 10},
+			{MinHoursBeforeDeparture: 24, PenaltyPercent: 25},
+			{MinHoursBeforeDeparture: 0, PenaltyPercent: 50},
+		},
+	},
+	"GA:business": {
+		Refundable:      true,
+		CancellationFee: 100000,
+		PenaltyTiers: []models.PenaltyTier{
+			{MinHoursBeforeDeparture: 24, PenaltyPercent: 5},
+			{MinHoursBeforeDeparture: 0, PenaltyPercent: 15},
+		},
+		NoShowRefund: true,
+	},
+	"ID:economy": {
+		Refundable:      true,
+		CancellationFee: 100000,
+		PenaltyTiers: []models.PenaltyTier{
+			{MinHoursBeforeDeparture: 72, PenaltyPercent: 25},
+			{MinHoursBeforeDeparture: 0, PenaltyPercent: 50},
+		},
+	},
+	"JT:economy": {
+		Refundable:      true,
+		CancellationFee: 150000,
+		PenaltyTiers: []models.PenaltyTier{
+			{MinHoursBeforeDeparture: 48, PenaltyPercent: 50},
+			{MinHoursBeforeDeparture: 0, PenaltyPercent: 90},
+		},
+	},
+	"QZ:economy": {
+		Refundable: false,
+	},
+}
+
+var nonRefundable = models.FareRules{Refundable: false}
+
+func Lookup(airlineCode, cabinClass string) models.FareRules {
+	if class, ok := cabin.Parse(cabinClass); ok {
+		cabinClass = string(class)
+	}
+	key := strings.ToUpper(airlineCode) + ":" + strings.ToLower(cabinClass)
+	if r, ok := rules[key]; ok {
+		return r
+	}
+	return nonRefundable
+}
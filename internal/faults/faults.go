@@ -0,0 +1,126 @@
+// Package faults injects failures into provider calls at runtime, so the
+// degraded-mode behaviour of the aggregator, circuit breakers and cache
+// can be rehearsed without editing code.
+package faults
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/providers"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// MaxLatency bounds injected latency; beyond it every call would time out
+// anyway.
+const MaxLatency = time.Minute
+
+// ErrInjected is what an injected failure returns. It is Unavailable, so
+// it is retried and counts against the provider's health like a real
+// outage.
+var ErrInjected = errorsx.New(errorsx.Unavailable, "injected fault")
+
+// Fault is what to inject into one provider's calls. Every call waits
+// LatencyMs first, then fails with probability ErrorRate; calls that don't
+// fail return no flights with probability EmptyRate.
+type Fault struct {
+	ErrorRate float64 `json:"error_rate"`
+	EmptyRate float64 `json:"empty_rate"`
+	LatencyMs int     `json:"latency_ms"`
+	// ExpiresAt lifts the fault, so one left behind doesn't outlive the
+	// exercise. Nil lasts until it is cleared.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (f Fault) Validate() error {
+	var errs errorsx.ValidationErrors
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		errs.Add("error_rate", types.FieldOutOfRange, "error_rate must be between 0 and 1")
+	}
+	if f.EmptyRate < 0 || f.EmptyRate > 1 {
+		errs.Add("empty_rate", types.FieldOutOfRange, "empty_rate must be between 0 and 1")
+	}
+	if f.LatencyMs < 0 || time.Duration(f.LatencyMs)*time.Millisecond > MaxLatency {
+		errs.Add("latency_ms", types.FieldOutOfRange, "latency_ms must be between 0 and %d", MaxLatency.Milliseconds())
+	}
+	return errs.Err()
+}
+
+func (f Fault) expired(now time.Time) bool {
+	return f.ExpiresAt != nil && !now.Before(*f.ExpiresAt)
+}
+
+// Injector holds the faults of one replica.
+type Injector struct {
+	rng *providers.Rand
+
+	mu     sync.RWMutex
+	faults map[string]Fault
+}
+
+// NewInjector draws from rng, so injected failures repeat in mock mode.
+func NewInjector(rng *providers.Rand) *Injector {
+	return &Injector{rng: rng, faults: make(map[string]Fault)}
+}
+
+// Set replaces provider's fault.
+func (i *Injector) Set(provider string, f Fault) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.faults[provider] = f
+}
+
+// Clear lifts provider's fault, reporting whether it had one.
+func (i *Injector) Clear(provider string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	f, ok := i.faults[provider]
+	delete(i.faults, provider)
+	return ok && !f.expired(time.Now())
+}
+
+func (i *Injector) ClearAll() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	clear(i.faults)
+}
+
+// Faults lists the faults in effect by provider.
+func (i *Injector) Faults() map[string]Fault {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	now := time.Now()
+	active := make(map[string]Fault, len(i.faults))
+	for p, f := range i.faults {
+		if !f.expired(now) {
+			active[p] = f
+		}
+	}
+	return active
+}
+
+// Inject applies provider's fault to one call. It waits out the added
+// latency and then either fails with ErrInjected or reports whether the
+// call should return no flights.
+func (i *Injector) Inject(ctx context.Context, provider string) (empty bool, err error) {
+	i.mu.RLock()
+	f, ok := i.faults[provider]
+	i.mu.RUnlock()
+	if !ok || f.expired(time.Now()) {
+		return false, nil
+	}
+
+	if f.LatencyMs > 0 {
+		select {
+		case <-time.After(time.Duration(f.LatencyMs) * time.Millisecond):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+	if f.ErrorRate > 0 && i.rng.Float64() < f.ErrorRate {
+		return false, ErrInjected
+	}
+	return f.EmptyRate > 0 && i.rng.Float64() < f.EmptyRate, nil
+}
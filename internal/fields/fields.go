@@ -0,0 +1,98 @@
+// Package fields rewrites JSON responses: client-selected masks of
+// dot-separated paths ("flights.id,flights.price.amount") and per-tenant
+// redact/rename policies.
+package fields
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Mask is a tree of selected field names. A node without children keeps
+// the whole value below it.
+type Mask map[string]Mask
+
+// Parse reads a comma-separated list of paths. Arrays are transparent:
+// "flights.id" selects the id of every flight.
+func Parse(spec string) (Mask, error) {
+	m := make(Mask)
+	for _, path := range strings.Split(spec, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		node := m
+		for _, name := range strings.Split(path, ".") {
+			if name == "" {
+				return nil, fmt.Errorf("invalid field path %q", path)
+			}
+			child, ok := node[name]
+			if !ok {
+				child = make(Mask)
+				node[name] = child
+			}
+			node = child
+		}
+	}
+	if len(m) == 0 {
+		return nil, fmt.Errorf("no fields selected")
+	}
+	return m, nil
+}
+
+// Decode turns v into its generic JSON form (maps, slices and
+// json.Number) so masks and policies can rewrite it.
+func Decode(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// Keep numbers as written rather than round-tripping through float64.
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Prune keeps only the selected fields of a decoded document. Paths that
+// don't exist in it are ignored.
+func (m Mask) Prune(v any) any {
+	if len(m) == 0 {
+		return v
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(m))
+		for name, child := range m {
+			if fv, ok := val[name]; ok {
+				out[name] = child.Prune(fv)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = m.Prune(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+type maskKey struct{}
+
+func WithMask(ctx context.Context, m Mask) context.Context {
+	return context.WithValue(ctx, maskKey{}, m)
+}
+
+func FromContext(ctx context.Context) (Mask, bool) {
+	m, ok := ctx.Value(maskKey{}).(Mask)
+	return m, ok
+}
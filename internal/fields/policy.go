@@ -0,0 +1,110 @@
+package fields
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Policy is a tenant's view of every response: Redact removes fields and
+// Rename maps a field to a new name in the same object. Paths use the same
+// dot syntax as masks, with arrays transparent.
+type Policy struct {
+	Redact []string          `json:"redact,omitempty"`
+	Rename map[string]string `json:"rename,omitempty"`
+}
+
+func (p Policy) Validate() error {
+	for _, path := range p.Redact {
+		if err := validatePath(path); err != nil {
+			return err
+		}
+	}
+	for path, name := range p.Rename {
+		if err := validatePath(path); err != nil {
+			return err
+		}
+		if name == "" || strings.Contains(name, ".") {
+			return fmt.Errorf("invalid new name %q for %s", name, path)
+		}
+	}
+	return nil
+}
+
+// Apply rewrites a decoded document in place. Redactions run before
+// renames, so a policy can't rename a field back into view.
+func (p Policy) Apply(doc any) any {
+	for _, path := range p.Redact {
+		walk(doc, strings.Split(path, "."), func(obj map[string]any, key string) {
+			delete(obj, key)
+		})
+	}
+	for path, name := range p.Rename {
+		walk(doc, strings.Split(path, "."), func(obj map[string]any, key string) {
+			if v, ok := obj[key]; ok {
+				delete(obj, key)
+				obj[name] = v
+			}
+		})
+	}
+	return doc
+}
+
+// walk calls fn with the object holding the last path segment, for every
+// match below arrays.
+func walk(v any, path []string, fn func(obj map[string]any, key string)) {
+	switch val := v.(type) {
+	case []any:
+		for _, item := range val {
+			walk(item, path, fn)
+		}
+	case map[string]any:
+		if len(path) == 1 {
+			fn(val, path[0])
+			return
+		}
+		if child, ok := val[path[0]]; ok {
+			walk(child, path[1:], fn)
+		}
+	}
+}
+
+func validatePath(path string) error {
+	for _, name := range strings.Split(path, ".") {
+		if name == "" {
+			return fmt.Errorf("invalid field path %q", path)
+		}
+	}
+	return nil
+}
+
+// LoadPolicies reads a JSON object of tenant name to Policy.
+func LoadPolicies(path string) (map[string]Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policies map[string]Policy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for tenant, p := range policies {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+	}
+	return policies, nil
+}
+
+type policyKey struct{}
+
+func WithPolicy(ctx context.Context, p Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, p)
+}
+
+func PolicyFromContext(ctx context.Context) (Policy, bool) {
+	p, ok := ctx.Value(policyKey{}).(Policy)
+	return p, ok
+}
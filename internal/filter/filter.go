@@ -1,24 +1,73 @@
 package filter
 
 import (
+	"cmp"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/dharmasatrya/flightsearch/internal/aircraft"
+	"github.com/dharmasatrya/flightsearch/internal/mct"
 	"github.com/dharmasatrya/flightsearch/internal/models"
 	"github.com/dharmasatrya/flightsearch/internal/ranking"
+	"github.com/dharmasatrya/flightsearch/internal/timezone"
+	"github.com/dharmasatrya/flightsearch/pkg/currency"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
 )
 
-func Apply(flights []models.Flight, filters *models.SearchFilters, sortBy, sortOrder string) []models.Flight {
-	filtered := applyFilters(flights, filters)
+func Apply(flights []models.Flight, filters *models.SearchFilters, sortBy, sortOrder string, weights ranking.Weights) []models.Flight {
+	return ApplyScored(flights, filters, sortBy, sortOrder, weights, ranking.CalculateScores)
+}
+
+// ApplyScored is Apply with best value scores computed by score, which is
+// only called when sorting by best value.
+func ApplyScored(flights []models.Flight, filters *models.SearchFilters, sortBy, sortOrder string, weights ranking.Weights, score ranking.ScoreFunc) []models.Flight {
+	filtered := applyFilters(priceWithBag(flights, filters), filters)
 
-	if sortBy == "best_value" {
-		filtered = ranking.CalculateScores(filtered)
+	keys := parseSortKeys(sortBy, sortOrder)
+	if !usesBestValue(keys) {
+		return ranking.AssignBadges(applySort(filtered, keys), weights)
 	}
 
-	sorted := applySort(filtered, sortBy, sortOrder)
+	sorted := applySort(score(filtered, weights), keys)
+	return ranking.AssignScoredBadges(sorted)
+}
+
+// SortsByBestValue reports whether sortBy ranks by best value score, so
+// whether a ranking model would be consulted.
+func SortsByBestValue(sortBy, sortOrder string) bool {
+	return usesBestValue(parseSortKeys(sortBy, sortOrder))
+}
+
+// priceWithBag adds the cheapest checked bag that brings each flight's
+// allowance to filters.IncludeBagKg to its price, and to its original
+// price when discounted, dropping flights that can't take that much. The
+// flights returned are copies.
+func priceWithBag(flights []models.Flight, filters *models.SearchFilters) []models.Flight {
+	if filters == nil || filters.IncludeBagKg == nil {
+		return flights
+	}
 
-	return sorted
+	result := make([]models.Flight, 0, len(flights))
+	for _, f := range flights {
+		cost, ok := f.CheckedBagCost(*filters.IncludeBagKg)
+		if !ok {
+			continue
+		}
+		if cost > 0 {
+			f.BagFee = &models.Price{Amount: cost, Currency: f.Price.Currency, Formatted: currency.Format(cost, f.Price.Currency)}
+			f.Price.Amount += cost
+			f.Price.Formatted = currency.Format(f.Price.Amount, f.Price.Currency)
+			if f.OriginalPrice != nil {
+				original := *f.OriginalPrice
+				original.Amount += cost
+				original.Formatted = currency.Format(original.Amount, original.Currency)
+				f.OriginalPrice = &original
+			}
+		}
+		result = append(result, f)
+	}
+	return result
 }
 
 func applyFilters(flights []models.Flight, filters *models.SearchFilters) []models.Flight {
@@ -62,6 +111,17 @@ func matchesFilters(f models.Flight, filters *models.SearchFilters) bool {
 		}
 	}
 
+	for _, airline := range filters.ExcludeAirlines {
+		if strings.EqualFold(f.Airline.Code, airline) {
+			return false
+		}
+	}
+	for _, provider := range filters.ExcludeProviders {
+		if strings.EqualFold(f.Provider, provider) {
+			return false
+		}
+	}
+
 	if filters.DepartureTimeMin != nil {
 		minTime, err := parseTimeOfDay(*filters.DepartureTimeMin)
 		if err == nil {
@@ -104,9 +164,118 @@ func matchesFilters(f models.Flight, filters *models.SearchFilters) bool {
 		return false
 	}
 
+	for _, amenity := range filters.Amenities {
+		if !hasAmenity(f, amenity) {
+			return false
+		}
+	}
+
+	if filters.MinCheckedBaggageKg != nil && f.Baggage.CheckedKg < *filters.MinCheckedBaggageKg {
+		return false
+	}
+	if filters.MinCabinBaggageKg != nil && f.Baggage.CabinKg < *filters.MinCabinBaggageKg {
+		return false
+	}
+
+	if len(filters.AircraftTypes) > 0 || len(filters.ExcludeAircraftTypes) > 0 {
+		e, known := flightEquipment(f)
+		if len(filters.AircraftTypes) > 0 && !(known && matchesAny(e, filters.AircraftTypes)) {
+			return false
+		}
+		if known && matchesAny(e, filters.ExcludeAircraftTypes) {
+			return false
+		}
+	}
+
+	if filters.ExcludeOvernight && isOvernight(f, filters) {
+		return false
+	}
+
+	if filters.MinReliability != nil && (f.Reliability == nil || float64(f.Reliability.Score) < *filters.MinReliability) {
+		return false
+	}
+
+	if filters.ExcludeInvalidConnections && !mct.ValidConnections(f) {
+		return false
+	}
+
+	for _, l := range f.Layovers {
+		if filters.MaxLayoverMinutes != nil && l.Duration > *filters.MaxLayoverMinutes {
+			return false
+		}
+		if filters.MinLayoverMinutes != nil && l.Duration < *filters.MinLayoverMinutes {
+			return false
+		}
+		for _, airport := range filters.ExcludeLayoverAirports {
+			if strings.EqualFold(l.Airport, airport) {
+				return false
+			}
+		}
+	}
+
 	return true
 }
 
+func hasAmenity(f models.Flight, amenity string) bool {
+	for _, a := range f.Amenities {
+		if strings.EqualFold(a, amenity) {
+			return true
+		}
+	}
+	return false
+}
+
+func flightEquipment(f models.Flight) (aircraft.Equipment, bool) {
+	if f.AircraftCode != nil {
+		return aircraft.Normalize(*f.AircraftCode)
+	}
+	if f.Aircraft != nil {
+		return aircraft.Normalize(*f.Aircraft)
+	}
+	return aircraft.Equipment{}, false
+}
+
+func matchesAny(e aircraft.Equipment, values []string) bool {
+	for _, v := range values {
+		if e.Matches(v) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	defaultOvernightStart = 23 * 60
+	defaultOvernightEnd   = 5 * 60
+)
+
+func isOvernight(f models.Flight, filters *models.SearchFilters) bool {
+	start, end := defaultOvernightStart, defaultOvernightEnd
+	if filters.OvernightStart != nil {
+		if t, err := parseTimeOfDay(*filters.OvernightStart); err == nil {
+			start = t
+		}
+	}
+	if filters.OvernightEnd != nil {
+		if t, err := parseTimeOfDay(*filters.OvernightEnd); err == nil {
+			end = t
+		}
+	}
+
+	dep := timezone.ConvertToTimezone(f.Departure.Time, f.Departure.Airport)
+	arr := timezone.ConvertToTimezone(f.Arrival.Time, f.Arrival.Airport)
+	return inWindow(dep.Hour()*60+dep.Minute(), start, end) ||
+		inWindow(arr.Hour()*60+arr.Minute(), start, end)
+}
+
+// inWindow handles windows that wrap past midnight, e.g. 23:00-05:00.
+func inWindow(minute, start, end int) bool {
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
 func parseTimeOfDay(s string) (int, error) {
 	t, err := time.Parse("15:04", s)
 	if err != nil {
@@ -115,68 +284,94 @@ func parseTimeOfDay(s string) (int, error) {
 	return t.Hour()*60 + t.Minute(), nil
 }
 
-func applySort(flights []models.Flight, sortBy, sortOrder string) []models.Flight {
-	if len(flights) == 0 {
-		return flights
-	}
+type sortKey struct {
+	field     types.SortKey
+	ascending bool
+}
 
-	ascending := strings.ToLower(sortOrder) != "desc"
+// parseSortKeys reads sort_by as a comma-separated list of fields, each
+// optionally suffixed with ":asc" or ":desc" ("price,duration:desc").
+// Fields without a suffix use sortOrder. Unknown fields are ignored.
+func parseSortKeys(sortBy, sortOrder string) []sortKey {
+	defaultAsc := types.SortOrder(strings.ToLower(sortOrder)) != types.SortDesc
 
-	switch strings.ToLower(sortBy) {
-	case "price":
-		sort.Slice(flights, func(i, j int) bool {
-			if ascending {
-				return flights[i].Price.Amount < flights[j].Price.Amount
-			}
-			return flights[i].Price.Amount > flights[j].Price.Amount
-		})
+	var keys []sortKey
+	for _, token := range strings.Split(sortBy, ",") {
+		token = strings.ToLower(strings.TrimSpace(token))
+		if strings.HasPrefix(token, ranking.ProfilePrefix) {
+			keys = append(keys, sortKey{field: types.SortBestValue, ascending: defaultAsc})
+			continue
+		}
 
-	case "duration":
-		sort.Slice(flights, func(i, j int) bool {
-			if ascending {
-				return flights[i].Duration.TotalMinutes < flights[j].Duration.TotalMinutes
-			}
-			return flights[i].Duration.TotalMinutes > flights[j].Duration.TotalMinutes
-		})
+		name, order, hasOrder := strings.Cut(token, ":")
+		field := types.SortKey(name)
+		if !field.Valid() {
+			continue
+		}
+		asc := defaultAsc
+		if hasOrder {
+			asc = types.SortOrder(order) != types.SortDesc
+		}
+		keys = append(keys, sortKey{field: field, ascending: asc})
+	}
+	return keys
+}
 
-	case "departure":
-		sort.Slice(flights, func(i, j int) bool {
-			if ascending {
-				return flights[i].Departure.Time.Before(flights[j].Departure.Time)
-			}
-			return flights[i].Departure.Time.After(flights[j].Departure.Time)
-		})
+func usesBestValue(keys []sortKey) bool {
+	for _, k := range keys {
+		if k.field == types.SortBestValue {
+			return true
+		}
+	}
+	return false
+}
 
-	case "arrival":
-		sort.Slice(flights, func(i, j int) bool {
-			if ascending {
-				return flights[i].Arrival.Time.Before(flights[j].Arrival.Time)
-			}
-			return flights[i].Arrival.Time.After(flights[j].Arrival.Time)
-		})
+// applySort orders by each key in turn and finally by flight ID, so the
+// same results always come back in the same order.
+func applySort(flights []models.Flight, keys []sortKey) []models.Flight {
+	if len(flights) == 0 {
+		return flights
+	}
 
-	case "best_value":
-		sort.Slice(flights, func(i, j int) bool {
-			if ascending {
-				return flights[i].BestValueScore < flights[j].BestValueScore
-			}
-			return flights[i].BestValueScore > flights[j].BestValueScore
-		})
+	if len(keys) == 0 {
+		// Default to price ascending
+		keys = []sortKey{{field: types.SortPrice, ascending: true}}
+	}
 
-	case "stops":
-		sort.Slice(flights, func(i, j int) bool {
-			if ascending {
-				return flights[i].Stops < flights[j].Stops
+	sort.SliceStable(flights, func(i, j int) bool {
+		for _, k := range keys {
+			c := compareField(flights[i], flights[j], k.field)
+			if c == 0 {
+				continue
+			}
+			if k.ascending {
+				return c < 0
 			}
-			return flights[i].Stops > flights[j].Stops
-		})
+			return c > 0
+		}
+		return flights[i].ID < flights[j].ID
+	})
 
+	return flights
+}
+
+func compareField(a, b models.Flight, field types.SortKey) int {
+	switch field {
+	case types.SortPrice:
+		return cmp.Compare(a.Price.Amount, b.Price.Amount)
+	case types.SortDuration:
+		return cmp.Compare(a.Duration.TotalMinutes, b.Duration.TotalMinutes)
+	case types.SortDeparture:
+		return a.Departure.Time.Compare(b.Departure.Time)
+	case types.SortArrival:
+		return a.Arrival.Time.Compare(b.Arrival.Time)
+	case types.SortBestValue:
+		return cmp.Compare(a.BestValueScore, b.BestValueScore)
+	case types.SortStops:
+		return cmp.Compare(a.Stops, b.Stops)
+	case types.SortEmissions:
+		return cmp.Compare(a.EmissionsKgCO2, b.EmissionsKgCO2)
 	default:
-		// Default to price ascending
-		sort.Slice(flights, func(i, j int) bool {
-			return flights[i].Price.Amount < flights[j].Price.Amount
-		})
+		return 0
 	}
-
-	return flights
 }
@@ -0,0 +1,111 @@
+package groundtransport
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+type Provider interface {
+	Name() string
+	Options(ctx context.Context, airport string) ([]models.GroundTransportOption, error)
+}
+
+type CachedProvider struct {
+	next    Provider
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+	// lastSweep is when expired entries were last dropped.
+	lastSweep time.Time
+}
+
+type cacheEntry struct {
+	options   []models.GroundTransportOption
+	expiresAt time.Time
+}
+
+func NewCachedProvider(next Provider, ttl time.Duration) *CachedProvider {
+	return &CachedProvider{
+		next:      next,
+		ttl:       ttl,
+		entries:   make(map[string]cacheEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (c *CachedProvider) Name() string {
+	return c.next.Name()
+}
+
+func (c *CachedProvider) Options(ctx context.Context, airport string) ([]models.GroundTransportOption, error) {
+	key := strings.ToUpper(airport)
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.options, nil
+	}
+
+	options, err := c.next.Options(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	if now.Sub(c.lastSweep) > c.ttl {
+		for k, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.entries[key] = cacheEntry{options: options, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+
+	return options, nil
+}
+
+func Lookup(ctx context.Context, p Provider, origin, destination string) (*models.GroundTransport, error) {
+	var (
+		wg                   sync.WaitGroup
+		originOpts, destOpts []models.GroundTransportOption
+		originErr, destErr   error
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		originOpts, originErr = p.Options(ctx, origin)
+	}()
+	go func() {
+		defer wg.Done()
+		destOpts, destErr = p.Options(ctx, destination)
+	}()
+	wg.Wait()
+
+	if originErr != nil {
+		return nil, originErr
+	}
+	if destErr != nil {
+		return nil, destErr
+	}
+
+	if originOpts == nil {
+		originOpts = []models.GroundTransportOption{}
+	}
+	if destOpts == nil {
+		destOpts = []models.GroundTransportOption{}
+	}
+
+	return &models.GroundTransport{
+		Origin:      originOpts,
+		Destination: destOpts,
+	}, nil
+}
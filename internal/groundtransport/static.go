@@ -0,0 +1,87 @@
+package groundtransport
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/currency"
+)
+
+type staticOption struct {
+	mode        string
+	operator    string
+	destination string
+	duration    int
+	price       float64
+}
+
+// Rough estimates for the busiest airports, good enough until we integrate a real transport API.
+var staticOptions = map[string][]staticOption{
+	"CGK": {
+		{"train", "Railink", "BNI City / Manggarai", 55, 70000},
+		{"bus", "DAMRI", "Gambir", 90, 80000},
+		{"ride_hail", "Grab / Gojek", "Central Jakarta", 60, 200000},
+	},
+	"HLP": {
+		{"ride_hail", "Grab / Gojek", "Central Jakarta", 40, 120000},
+	},
+	"DPS": {
+		{"taxi", "Airport Taxi", "Kuta", 20, 150000},
+		{"ride_hail", "Grab / Gojek", "Seminyak", 35, 120000},
+		{"shuttle", "Kura-Kura Bus", "Ubud", 90, 100000},
+	},
+	"SUB": {
+		{"bus", "DAMRI", "Purabaya Terminal", 40, 35000},
+		{"ride_hail", "Grab / Gojek", "Surabaya City Centre", 45, 130000},
+	},
+	"JOG": {
+		{"ride_hail", "Grab / Gojek", "Malioboro", 25, 60000},
+	},
+	"YIA": {
+		{"train", "KAI Bandara", "Yogyakarta Tugu", 40, 20000},
+		{"bus", "DAMRI", "Malioboro", 75, 80000},
+	},
+	"KNO": {
+		{"train", "Railink", "Medan Station", 35, 100000},
+		{"bus", "DAMRI", "Amplas", 75, 30000},
+	},
+	"UPG": {
+		{"bus", "DAMRI", "Makassar City", 50, 35000},
+	},
+	"LOP": {
+		{"bus", "DAMRI", "Mataram", 60, 40000},
+		{"taxi", "Airport Taxi", "Senggigi", 75, 300000},
+	},
+}
+
+type StaticProvider struct{}
+
+func NewStaticProvider() *StaticProvider {
+	return &StaticProvider{}
+}
+
+func (p *StaticProvider) Name() string {
+	return "static"
+}
+
+func (p *StaticProvider) Options(ctx context.Context, airport string) ([]models.GroundTransportOption, error) {
+	entries := staticOptions[strings.ToUpper(airport)]
+
+	options := make([]models.GroundTransportOption, len(entries))
+	for i, e := range entries {
+		options[i] = models.GroundTransportOption{
+			Mode:            e.mode,
+			Operator:        e.operator,
+			Destination:     e.destination,
+			DurationMinutes: e.duration,
+			EstimatedPrice: models.Price{
+				Amount:    e.price,
+				Currency:  "IDR",
+				Formatted: currency.FormatIDR(e.price),
+			},
+		}
+	}
+
+	return options, nil
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/analytics"
+	"github.com/dharmasatrya/flightsearch/internal/audit"
+	"github.com/dharmasatrya/flightsearch/internal/brownout"
+	"github.com/dharmasatrya/flightsearch/internal/bus"
+	"github.com/dharmasatrya/flightsearch/internal/cache"
+	"github.com/dharmasatrya/flightsearch/internal/configsnap"
+	"github.com/dharmasatrya/flightsearch/internal/controls"
+	"github.com/dharmasatrya/flightsearch/internal/faults"
+	"github.com/dharmasatrya/flightsearch/internal/health"
+	"github.com/dharmasatrya/flightsearch/internal/leader"
+	"github.com/dharmasatrya/flightsearch/internal/ratelimit"
+	"github.com/dharmasatrya/flightsearch/internal/refdata"
+	"github.com/dharmasatrya/flightsearch/internal/retention"
+	"github.com/dharmasatrya/flightsearch/internal/scheduler"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+type ErrorRates struct {
+	Searches  analytics.SearchSummary `json:"searches"`
+	Providers map[string]float64      `json:"providers"`
+}
+
+type AdminOverview struct {
+	GeneratedAt    time.Time               `json:"generated_at"`
+	ProviderHealth []health.Stats          `json:"provider_health"`
+	Cache          cache.Stats             `json:"cache"`
+	TopRoutes      []analytics.RouteCount  `json:"top_routes"`
+	ErrorRates     ErrorRates              `json:"error_rates"`
+	QuotaUsage     []ratelimit.LimitStatus `json:"quota_usage"`
+	Leader         *leader.Status          `json:"leader,omitempty"`
+	// ProviderCalls is set when provider calls are bounded.
+	ProviderCalls *aggregator.PoolStats `json:"provider_calls,omitempty"`
+	Brownout      *brownout.Status      `json:"brownout,omitempty"`
+	// Faults lists the faults injected into this replica's provider calls.
+	Faults map[string]faults.Fault `json:"faults,omitempty"`
+	// Audit counts this replica's audit records when the audit log is on.
+	Audit *audit.Stats `json:"audit,omitempty"`
+	// EventBus counts this replica's search.completed events when they are
+	// published.
+	EventBus *bus.Stats `json:"event_bus,omitempty"`
+}
+
+type AdminHandler struct {
+	health   *health.Tracker
+	cache    *cache.InstrumentedCache
+	searches *analytics.SearchCounter
+	limiter  ratelimit.Limiter
+	janitor  *retention.Janitor
+	elector  *leader.Elector
+	jobs     *scheduler.Scheduler
+	routes   analytics.PrivacyPolicy
+	agg      *aggregator.Aggregator
+	brownout *brownout.Controller
+	configs  configsnap.History
+	config   configsnap.Snapshot
+	faults   *faults.Injector
+	refdata  refdata.Store
+	controls *controls.Controls
+	audit    *audit.Logger
+	eventBus *bus.Bus
+	warming  atomic.Bool
+}
+
+func NewAdminHandler(h *health.Tracker, c *cache.InstrumentedCache, s *analytics.SearchCounter, l ratelimit.Limiter) *AdminHandler {
+	return &AdminHandler{
+		health:   h,
+		cache:    c,
+		searches: s,
+		limiter:  l,
+	}
+}
+
+func (h *AdminHandler) SetAudit(l *audit.Logger) {
+	h.audit = l
+}
+
+func (h *AdminHandler) SetEventBus(b *bus.Bus) {
+	h.eventBus = b
+}
+
+func (h *AdminHandler) SetRetention(j *retention.Janitor) {
+	h.janitor = j
+}
+
+// SetLeader reports this replica's background job leadership in the
+// overview (clustered deployments only).
+func (h *AdminHandler) SetLeader(e *leader.Elector) {
+	h.elector = e
+}
+
+// SetTopRoutesPolicy protects the overview's top routes like the public
+// analytics endpoint. Counts are exact by default.
+func (h *AdminHandler) SetTopRoutesPolicy(p analytics.PrivacyPolicy) {
+	h.routes = p
+}
+
+// SetAggregator reports the provider call pool in the overview.
+func (h *AdminHandler) SetAggregator(a *aggregator.Aggregator) {
+	h.agg = a
+}
+
+func (h *AdminHandler) SetBrownout(c *brownout.Controller) {
+	h.brownout = c
+}
+
+func (h *AdminHandler) SetScheduler(s *scheduler.Scheduler) {
+	h.jobs = s
+}
+
+func (h *AdminHandler) Jobs(c echo.Context) error {
+	statuses, err := h.jobs.Status(c.Request().Context())
+	if err != nil {
+		return jobError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"jobs": statuses,
+	})
+}
+
+// RunJob queues an immediate run; the scheduler picks it up within a
+// second on whichever replica runs background jobs.
+func (h *AdminHandler) RunJob(c echo.Context) error {
+	ctx := c.Request().Context()
+	if err := h.jobs.RequestRun(ctx, c.Param("name")); err != nil {
+		return jobError(c, err)
+	}
+	status, err := h.jobs.JobStatus(ctx, c.Param("name"))
+	if err != nil {
+		return jobError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, status)
+}
+
+func (h *AdminHandler) EnableJob(c echo.Context) error {
+	return h.setJobEnabled(c, true)
+}
+
+func (h *AdminHandler) DisableJob(c echo.Context) error {
+	return h.setJobEnabled(c, false)
+}
+
+func (h *AdminHandler) setJobEnabled(c echo.Context, enabled bool) error {
+	ctx := c.Request().Context()
+	if err := h.jobs.SetEnabled(ctx, c.Param("name"), enabled); err != nil {
+		return jobError(c, err)
+	}
+	status, err := h.jobs.JobStatus(ctx, c.Param("name"))
+	if err != nil {
+		return jobError(c, err)
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+func jobError(c echo.Context, err error) error {
+	return errorResponse(c, err, types.CodeJobError, "Failed to access job state")
+}
+
+func (h *AdminHandler) Retention(c echo.Context) error {
+	if h.janitor == nil {
+		return errorResponse(c, errorsx.New(errorsx.NotFound, "Retention is not configured"), "", "")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"datasets": h.janitor.Status(),
+	})
+}
+
+func (h *AdminHandler) Overview(c echo.Context) error {
+	stats := h.health.AllStats()
+
+	providerErrors := make(map[string]float64, len(stats))
+	for _, s := range stats {
+		providerErrors[s.Provider] = s.FailureRate
+	}
+
+	now := time.Now()
+	topRoutes := h.routes.Apply(h.searches.Routes(), now)
+	if len(topRoutes) > 10 {
+		topRoutes = topRoutes[:10]
+	}
+
+	overview := AdminOverview{
+		GeneratedAt:    now,
+		ProviderHealth: stats,
+		Cache:          h.cache.Stats(),
+		TopRoutes:      topRoutes,
+		ErrorRates: ErrorRates{
+			Searches:  h.searches.Summary(),
+			Providers: providerErrors,
+		},
+		QuotaUsage: h.limiter.Snapshot(),
+	}
+	if h.elector != nil {
+		status := h.elector.Status()
+		overview.Leader = &status
+	}
+	if h.brownout != nil {
+		status := h.brownout.Status()
+		overview.Brownout = &status
+	}
+	if h.agg != nil {
+		if pool, ok := h.agg.CallPoolStats(); ok {
+			overview.ProviderCalls = &pool
+		}
+	}
+	if h.faults != nil {
+		overview.Faults = h.faults.Faults()
+	}
+	if h.audit != nil {
+		stats := h.audit.Stats()
+		overview.Audit = &stats
+	}
+	if h.eventBus != nil {
+		stats := h.eventBus.Stats()
+		overview.EventBus = &stats
+	}
+
+	return c.JSON(http.StatusOK, overview)
+}
+
+// AdminAuth guards admin routes with a static bearer token.
+func AdminAuth(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			got := c.Request().Header.Get("Authorization")
+			if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+token)) != 1 {
+				return errorResponse(c, errorsx.New(errorsx.Unauthorized, "Missing or invalid admin token"), "", "")
+			}
+			return next(c)
+		}
+	}
+}
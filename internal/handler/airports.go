@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/airports"
+	"github.com/dharmasatrya/flightsearch/internal/i18n"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+const maxAirportResults = 50
+
+type AirportList struct {
+	Airports []airports.Airport `json:"airports"`
+}
+
+type NearbyAirports struct {
+	Airport  airports.Airport         `json:"airport"`
+	RadiusKm int                      `json:"radius_km"`
+	Nearby   []airports.NearbyAirport `json:"nearby"`
+}
+
+// SearchAirports is autocomplete for airport pickers: ?q= matches codes,
+// cities and airport names.
+func SearchAirports(c echo.Context) error {
+	q := strings.TrimSpace(c.QueryParam("q"))
+	if q == "" {
+		return validationError(c, errorsx.ValidationErrors{{Field: "q", Code: types.FieldRequired, Message: "q is required"}}, nil)
+	}
+	limit := 10
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAirportResults {
+			return validationError(c, errorsx.ValidationErrors{{Field: "limit", Code: types.FieldOutOfRange, Message: "limit must be between 1 and " + strconv.Itoa(maxAirportResults)}}, nil)
+		}
+		limit = n
+	}
+	list := airports.Search(q, limit)
+	catalog := i18n.For(searchOptions(c).Locale)
+	for i := range list {
+		localizeAirport(&list[i], catalog)
+	}
+	return c.JSON(http.StatusOK, AirportList{Airports: list})
+}
+
+// NearbyAirportsOf lists the airports within ?radius_km= of an airport,
+// the ones a search with the same nearby_km may add.
+func NearbyAirportsOf(c echo.Context) error {
+	code := strings.ToUpper(c.Param("code"))
+	airport, ok := airports.Lookup(code)
+	if !ok {
+		return errorResponse(c, errorsx.New(errorsx.NotFound, "Unknown airport "+code), "", "")
+	}
+	radius := 100
+	if v := c.QueryParam("radius_km"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > models.MaxNearbyKm {
+			return validationError(c, errorsx.ValidationErrors{{Field: "radius_km", Code: types.FieldOutOfRange, Message: "radius_km must be between 1 and " + strconv.Itoa(models.MaxNearbyKm)}}, nil)
+		}
+		radius = n
+	}
+	nearby := airports.Nearby(code, float64(radius))
+	catalog := i18n.For(searchOptions(c).Locale)
+	localizeAirport(&airport, catalog)
+	for i := range nearby {
+		localizeAirport(&nearby[i].Airport, catalog)
+	}
+	return c.JSON(http.StatusOK, NearbyAirports{
+		Airport:  airport,
+		RadiusKm: radius,
+		Nearby:   nearby,
+	})
+}
+
+// localizeAirport names the airport's city in the request's locale.
+func localizeAirport(a *airports.Airport, catalog *i18n.Catalog) {
+	a.City = catalog.City(a.IATA, a.City)
+}
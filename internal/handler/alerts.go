@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/alerts"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+type AlertHandler struct {
+	store alerts.Store
+}
+
+func NewAlertHandler(store alerts.Store) *AlertHandler {
+	return &AlertHandler{store: store}
+}
+
+func (h *AlertHandler) Create(c echo.Context) error {
+	var req models.CreateAlertRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(c, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return validationError(c, err, nil)
+	}
+
+	alert := alerts.NewAlert(req, alertOwner(c))
+	if err := h.store.Save(c.Request().Context(), alert); err != nil {
+		return errorResponse(c, err, types.CodeAlertError, "Failed to save alert")
+	}
+
+	return c.JSON(http.StatusCreated, alert)
+}
+
+// List returns the caller's alerts.
+func (h *AlertHandler) List(c echo.Context) error {
+	all, err := h.store.List(c.Request().Context())
+	if err != nil {
+		return errorResponse(c, err, types.CodeAlertError, "Failed to list alerts")
+	}
+
+	owner := alertOwner(c)
+	list := make([]models.Alert, 0, len(all))
+	for _, a := range all {
+		if a.Owner == owner {
+			list = append(list, a)
+		}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"alerts": list,
+	})
+}
+
+func (h *AlertHandler) Get(c echo.Context) error {
+	alert, err := h.owned(c)
+	if err != nil {
+		return errorResponse(c, err, types.CodeAlertError, "Failed to get alert")
+	}
+	return c.JSON(http.StatusOK, alert)
+}
+
+func (h *AlertHandler) Delete(c echo.Context) error {
+	ctx := c.Request().Context()
+	alert, err := h.owned(c)
+	if err == nil {
+		err = h.store.Delete(ctx, alert.ID)
+	}
+	if err != nil {
+		return errorResponse(c, err, types.CodeAlertError, "Failed to delete alert")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// owned loads the alert named in the path. Other callers' alerts are
+// reported as not found, so IDs can't be probed.
+func (h *AlertHandler) owned(c echo.Context) (models.Alert, error) {
+	alert, err := h.store.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return models.Alert{}, err
+	}
+	if alert.Owner != alertOwner(c) {
+		return models.Alert{}, alerts.ErrNotFound
+	}
+	return alert, nil
+}
+
+// alertOwner identifies the caller: the tenant of its API key, the key
+// itself when it has no tenant, or else the client IP. It is hashed so
+// neither keys nor addresses end up in the store.
+func alertOwner(c echo.Context) string {
+	info := reqctx.From(c.Request().Context())
+	owner := "ip:" + c.RealIP()
+	switch {
+	case info.Tenant != "":
+		owner = "tenant:" + info.Tenant
+	case info.APIKey != "":
+		owner = "key:" + info.APIKey
+	}
+	sum := sha256.Sum256([]byte(owner))
+	return hex.EncodeToString(sum[:16])
+}
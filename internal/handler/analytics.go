@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/analytics"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+const maxRouteDemandLimit = 100
+
+type RouteDemand struct {
+	GeneratedAt time.Time              `json:"generated_at"`
+	Routes      []analytics.RouteCount `json:"routes"`
+	// Privacy tells consumers how the counts were protected.
+	Privacy analytics.PrivacyPolicy `json:"privacy"`
+}
+
+// AnalyticsHandler serves route demand to partners. Counts are released
+// under a privacy policy, never exactly.
+type AnalyticsHandler struct {
+	searches *analytics.SearchCounter
+	policy   analytics.PrivacyPolicy
+	events   analytics.EventStore
+	// retention is the longest window events can be reported over.
+	retention time.Duration
+}
+
+func NewAnalyticsHandler(s *analytics.SearchCounter, policy analytics.PrivacyPolicy) *AnalyticsHandler {
+	return &AnalyticsHandler{searches: s, policy: policy}
+}
+
+// SetEvents serves the internal dashboards from search events kept for
+// retention.
+func (h *AnalyticsHandler) SetEvents(store analytics.EventStore, retention time.Duration) {
+	h.events = store
+	h.retention = retention
+}
+
+func (h *AnalyticsHandler) Routes(c echo.Context) error {
+	limit, err := limitParam(c)
+	if err != nil {
+		return errorResponse(c, err, "", "")
+	}
+
+	now := time.Now()
+	routes := h.policy.Apply(h.searches.Routes(), now)
+	if len(routes) > limit {
+		routes = routes[:limit]
+	}
+	return c.JSON(http.StatusOK, RouteDemand{
+		GeneratedAt: now,
+		Routes:      routes,
+		Privacy:     h.policy,
+	})
+}
+
+func limitParam(c echo.Context) (int, error) {
+	v := c.QueryParam("limit")
+	if v == "" {
+		return 20, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxRouteDemandLimit {
+		return 0, errorsx.Errorf(errorsx.Invalid, "limit must be between 1 and %d", maxRouteDemandLimit)
+	}
+	return n, nil
+}
+
+type PopularRoutes struct {
+	GeneratedAt time.Time                `json:"generated_at"`
+	Window      string                   `json:"window"`
+	Routes      []analytics.PopularRoute `json:"routes"`
+}
+
+type SearchLatency struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Window      string    `json:"window"`
+	analytics.LatencyReport
+}
+
+// PopularRoutes reports exact search counts from the event store, for
+// internal dashboards only.
+func (h *AnalyticsHandler) PopularRoutes(c echo.Context) error {
+	limit, err := limitParam(c)
+	if err != nil {
+		return errorResponse(c, err, "", "")
+	}
+	events, window, now, err := h.eventsIn(c)
+	if err != nil {
+		return errorResponse(c, err, types.CodeAnalyticsError, "load search events")
+	}
+	return c.JSON(http.StatusOK, PopularRoutes{
+		GeneratedAt: now,
+		Window:      window.String(),
+		Routes:      analytics.PopularRoutes(events, limit),
+	})
+}
+
+// Latency reports search latency percentiles, optionally for one route.
+func (h *AnalyticsHandler) Latency(c echo.Context) error {
+	events, window, now, err := h.eventsIn(c)
+	if err != nil {
+		return errorResponse(c, err, types.CodeAnalyticsError, "load search events")
+	}
+	origin := strings.ToUpper(c.QueryParam("origin"))
+	dest := strings.ToUpper(c.QueryParam("destination"))
+	if origin != "" || dest != "" {
+		kept := events[:0]
+		for _, e := range events {
+			if (origin == "" || e.Origin == origin) && (dest == "" || e.Destination == dest) {
+				kept = append(kept, e)
+			}
+		}
+		events = kept
+	}
+	return c.JSON(http.StatusOK, SearchLatency{
+		GeneratedAt:   now,
+		Window:        window.String(),
+		LatencyReport: analytics.Latency(events),
+	})
+}
+
+// eventsIn loads the events of the window query parameter, 24 hours by
+// default.
+func (h *AnalyticsHandler) eventsIn(c echo.Context) ([]analytics.Event, time.Duration, time.Time, error) {
+	window := 24 * time.Hour
+	if v := c.QueryParam("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Minute || d > h.retention {
+			return nil, 0, time.Time{}, errorsx.Errorf(errorsx.Invalid, "window must be a duration between 1m and %v", h.retention)
+		}
+		window = d
+	}
+	window = min(window, h.retention)
+	now := time.Now()
+	events, err := h.events.Since(c.Request().Context(), now.Add(-window))
+	return events, window, now, err
+}
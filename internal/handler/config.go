@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/configsnap"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+type ConfigHistory struct {
+	CurrentVersion string `json:"current_version"`
+	// Versions are newest first.
+	Versions []configsnap.Entry `json:"versions"`
+}
+
+type ConfigDiff struct {
+	From    string              `json:"from"`
+	To      string              `json:"to"`
+	Changes []configsnap.Change `json:"changes"`
+}
+
+// SetConfigHistory serves the configuration history, current being the
+// configuration this replica runs with.
+func (h *AdminHandler) SetConfigHistory(history configsnap.History, current configsnap.Snapshot) {
+	h.configs = history
+	h.config = current
+}
+
+func (h *AdminHandler) ConfigHistory(c echo.Context) error {
+	entries, err := h.configs.List(c.Request().Context())
+	if err != nil {
+		return configError(c, err)
+	}
+	slices.Reverse(entries)
+	return c.JSON(http.StatusOK, ConfigHistory{
+		CurrentVersion: h.config.Version(),
+		Versions:       entries,
+	})
+}
+
+// ConfigDiff compares the versions in the from and to query parameters; to
+// defaults to the current version.
+func (h *AdminHandler) ConfigDiff(c echo.Context) error {
+	fromVersion, toVersion := c.QueryParam("from"), c.QueryParam("to")
+	if fromVersion == "" {
+		return invalidRequest(c, "from is required", nil)
+	}
+	if toVersion == "" {
+		toVersion = h.config.Version()
+	}
+
+	entries, err := h.configs.List(c.Request().Context())
+	if err != nil {
+		return configError(c, err)
+	}
+	from, err := configsnap.Find(entries, fromVersion)
+	if err != nil {
+		return configError(c, err)
+	}
+	to, err := configsnap.Find(entries, toVersion)
+	if err != nil {
+		return configError(c, err)
+	}
+	return c.JSON(http.StatusOK, ConfigDiff{
+		From:    from.Version,
+		To:      to.Version,
+		Changes: configsnap.Diff(from.Config, to.Config),
+	})
+}
+
+func configError(c echo.Context, err error) error {
+	return errorResponse(c, err, types.CodeConfigError, "Failed to load config history")
+}
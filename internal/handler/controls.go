@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/brownout"
+	"github.com/dharmasatrya/flightsearch/internal/configsnap"
+	"github.com/dharmasatrya/flightsearch/internal/controls"
+	"github.com/dharmasatrya/flightsearch/internal/health"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/ratelimit"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// ProviderControl is a provider's runtime state.
+type ProviderControl struct {
+	Name     string             `json:"name"`
+	Enabled  bool               `json:"enabled"`
+	Override *controls.Override `json:"override,omitempty"`
+	Circuit  health.Stats       `json:"circuit"`
+	// RateLimit is the limit in force on this replica, ConfiguredRateLimit
+	// the one clearing an override restores.
+	RateLimit           *ratelimit.LimitStatus `json:"rate_limit,omitempty"`
+	ConfiguredRateLimit configsnap.RateLimit   `json:"configured_rate_limit"`
+}
+
+type DisableProviderRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// SetControls enables the provider and cache controls.
+func (h *AdminHandler) SetControls(c *controls.Controls) {
+	h.controls = c
+}
+
+func (h *AdminHandler) ProviderControls(c echo.Context) error {
+	overrides, err := h.controls.Overrides(c.Request().Context())
+	if err != nil {
+		return controlError(c, err)
+	}
+	limits := h.limits()
+
+	list := h.agg.Providers()
+	result := make([]ProviderControl, 0, len(list))
+	for _, p := range list {
+		result = append(result, h.providerControl(p.Name(), overrides, limits))
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"providers": result,
+	})
+}
+
+func (h *AdminHandler) DisableProvider(c echo.Context) error {
+	var req DisableProviderRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(c, err)
+	}
+	if _, err := h.controls.Disable(c.Request().Context(), c.Param("name"), req.Reason); err != nil {
+		return controlError(c, err)
+	}
+	log.Printf("Provider %s taken out of rotation: %q", c.Param("name"), req.Reason)
+	return h.providerControlResponse(c)
+}
+
+func (h *AdminHandler) EnableProvider(c echo.Context) error {
+	if _, err := h.controls.Enable(c.Request().Context(), c.Param("name")); err != nil {
+		return controlError(c, err)
+	}
+	log.Printf("Provider %s back in rotation", c.Param("name"))
+	return h.providerControlResponse(c)
+}
+
+func (h *AdminHandler) SetProviderRateLimit(c echo.Context) error {
+	var req configsnap.RateLimit
+	if err := c.Bind(&req); err != nil {
+		return bindError(c, err)
+	}
+	if _, err := h.controls.SetRateLimit(c.Request().Context(), c.Param("name"), req); err != nil {
+		var errs errorsx.ValidationErrors
+		if errors.As(err, &errs) {
+			return validationError(c, err, nil)
+		}
+		return controlError(c, err)
+	}
+	log.Printf("Provider %s rate limit overridden: %v rps, burst %d", c.Param("name"), req.RequestsPerSecond, req.BurstSize)
+	return h.providerControlResponse(c)
+}
+
+func (h *AdminHandler) ClearProviderRateLimit(c echo.Context) error {
+	if _, err := h.controls.ClearRateLimit(c.Request().Context(), c.Param("name")); err != nil {
+		return controlError(c, err)
+	}
+	log.Printf("Provider %s rate limit override cleared", c.Param("name"))
+	return h.providerControlResponse(c)
+}
+
+func (h *AdminHandler) providerControlResponse(c echo.Context) error {
+	overrides, err := h.controls.Overrides(c.Request().Context())
+	if err != nil {
+		return controlError(c, err)
+	}
+	return c.JSON(http.StatusOK, h.providerControl(c.Param("name"), overrides, h.limits()))
+}
+
+func (h *AdminHandler) providerControl(name string, overrides map[string]controls.Override, limits map[string]ratelimit.LimitStatus) ProviderControl {
+	pc := ProviderControl{
+		Name:                name,
+		Enabled:             !h.agg.Disabled(name),
+		Circuit:             h.health.Stats(name),
+		ConfiguredRateLimit: h.controls.Configured(name),
+	}
+	if o, ok := overrides[name]; ok {
+		pc.Override = &o
+	}
+	if l, ok := limits[name]; ok {
+		pc.RateLimit = &l
+	}
+	return pc
+}
+
+func (h *AdminHandler) limits() map[string]ratelimit.LimitStatus {
+	limits := make(map[string]ratelimit.LimitStatus)
+	for _, s := range h.limiter.Snapshot() {
+		limits[s.Provider] = s
+	}
+	return limits
+}
+
+func controlError(c echo.Context, err error) error {
+	return errorResponse(c, err, types.CodeControlError, "Failed to access provider controls")
+}
+
+type CacheFlush struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// FlushCache deletes every cached search, so the next search of each
+// calls the providers.
+func (h *AdminHandler) FlushCache(c echo.Context) error {
+	deleted, err := h.cache.Flush(c.Request().Context())
+	if err != nil {
+		return errorResponse(c, err, types.CodeCacheError, "Failed to flush cache")
+	}
+	log.Printf("Cache flushed: %d entries deleted", deleted)
+	return c.JSON(http.StatusOK, CacheFlush{Deleted: deleted})
+}
+
+const (
+	maxWarmDays      = 14
+	maxWarmTopRoutes = 50
+	maxWarmSearches  = 200
+)
+
+type WarmRoute struct {
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+}
+
+// CacheWarmRequest names the routes to cache ahead of traffic. Each is
+// searched the way most searches are made, one-way for one passenger, on
+// each of Days departure dates from tomorrow (one by default).
+type CacheWarmRequest struct {
+	Routes []WarmRoute `json:"routes,omitempty"`
+	// TopRoutes adds this many of the routes searched most on this replica.
+	TopRoutes  int    `json:"top_routes,omitempty"`
+	Days       int    `json:"days,omitempty"`
+	CabinClass string `json:"cabin_class,omitempty"`
+}
+
+type CacheWarm struct {
+	Searches int `json:"searches"`
+}
+
+// WarmCache searches the requested routes in the background and caches
+// what the providers answer. One warm-up runs at a time per replica.
+func (h *AdminHandler) WarmCache(c echo.Context) error {
+	var req CacheWarmRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(c, err)
+	}
+	searches, err := h.warmSearches(req, time.Now())
+	if err != nil {
+		return validationError(c, err, nil)
+	}
+	if !h.warming.CompareAndSwap(false, true) {
+		return errorResponse(c, errorsx.New(errorsx.Unprocessable, "Cache warming is already running"), "", "")
+	}
+
+	go h.warm(searches)
+	log.Printf("Cache warming started: %d searches", len(searches))
+	return c.JSON(http.StatusAccepted, CacheWarm{Searches: len(searches)})
+}
+
+func (h *AdminHandler) warmSearches(req CacheWarmRequest, now time.Time) ([]models.SearchRequest, error) {
+	var errs errorsx.ValidationErrors
+	if req.Days == 0 {
+		req.Days = 1
+	}
+	if req.Days < 1 || req.Days > maxWarmDays {
+		errs.Add("days", types.FieldOutOfRange, "days must be between 1 and %d", maxWarmDays)
+	}
+	if req.TopRoutes < 0 || req.TopRoutes > maxWarmTopRoutes {
+		errs.Add("top_routes", types.FieldOutOfRange, "top_routes must be between 0 and %d", maxWarmTopRoutes)
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	routes := req.Routes
+	for _, r := range h.searches.TopRoutes(req.TopRoutes) {
+		routes = append(routes, WarmRoute{Origin: r.Origin, Destination: r.Destination})
+	}
+	if len(routes) == 0 {
+		errs.Add("routes", types.FieldRequired, "routes or top_routes must name at least one route")
+		return nil, errs.Err()
+	}
+
+	seen := make(map[string]bool)
+	var searches []models.SearchRequest
+	for i, r := range routes {
+		for day := 1; day <= req.Days; day++ {
+			search := models.SearchRequest{
+				Origin:        r.Origin,
+				Destination:   r.Destination,
+				DepartureDate: now.UTC().AddDate(0, 0, day).Format("2006-01-02"),
+				Passengers:    1,
+				CabinClass:    req.CabinClass,
+			}
+			search.Normalize()
+			// Requested routes that fail are reported, top routes skipped.
+			if err := search.Validate(); err != nil {
+				var fieldErrs errorsx.ValidationErrors
+				errors.As(err, &fieldErrs)
+				for _, fe := range fieldErrs {
+					if i < len(req.Routes) {
+						fe.Field = fmt.Sprintf("routes[%d].%s", i, fe.Field)
+						errs = append(errs, fe)
+					}
+				}
+				break
+			}
+			key := search.Origin + "-" + search.Destination + "-" + search.DepartureDate
+			if !seen[key] {
+				seen[key] = true
+				searches = append(searches, search)
+			}
+		}
+	}
+	if len(searches) > maxWarmSearches {
+		errs.Add("routes", types.FieldOutOfRange, "routes times days must be at most %d searches", maxWarmSearches)
+	}
+	return searches, errs.Err()
+}
+
+// warm runs the searches one at a time, stopping early under brownout: it
+// is never worth shedding real traffic for.
+func (h *AdminHandler) warm(searches []models.SearchRequest) {
+	defer h.warming.Store(false)
+
+	ctx := context.Background()
+	cached := 0
+	for i, req := range searches {
+		if h.brownout.Level() > brownout.Normal {
+			log.Printf("Cache warming stopped by brownout after %d of %d searches", i, len(searches))
+			break
+		}
+		result, err := h.agg.Search(ctx, req)
+		if err != nil {
+			continue
+		}
+		if len(result.Flights) == 0 && result.ProvidersFailed > 0 {
+			continue
+		}
+		if err := h.cache.Set(ctx, req, cacheEntry(result, time.Now())); err == nil {
+			cached++
+		}
+	}
+	log.Printf("Cache warming done: %d of %d searches cached", cached, len(searches))
+}
@@ -0,0 +1,16 @@
+package handler
+
+import "github.com/labstack/echo/v4"
+
+// HeaderDemo marks every response of a demo deployment, so its data can't
+// be passed off as real even where the body doesn't say so.
+const HeaderDemo = "X-Demo"
+
+func DemoWatermark() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Set(HeaderDemo, "true")
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/snapshot"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// Diff compares the results of two searches of the same route by their
+// search_ids: the flights the later one added and removed, and the price
+// changes of the rest.
+func (h *SearchHandler) Diff(c echo.Context) error {
+	fromID, toID := c.QueryParam("from"), c.QueryParam("to")
+	var fieldErrs errorsx.ValidationErrors
+	if fromID == "" {
+		fieldErrs = append(fieldErrs, errorsx.FieldError{Field: "from", Code: types.FieldRequired, Message: "from is required"})
+	}
+	if toID == "" {
+		fieldErrs = append(fieldErrs, errorsx.FieldError{Field: "to", Code: types.FieldRequired, Message: "to is required"})
+	}
+	if len(fieldErrs) > 0 {
+		return validationError(c, fieldErrs, nil)
+	}
+
+	from, err := h.storedSearch(c, fromID)
+	if err != nil {
+		return errorResponse(c, err, types.CodeDiffError, "Failed to load search")
+	}
+	to, err := h.storedSearch(c, toID)
+	if err != nil {
+		return errorResponse(c, err, types.CodeDiffError, "Failed to load search")
+	}
+	if from.Key != to.Key {
+		return validationError(c, errorsx.ValidationErrors{{
+			Field:   "to",
+			Code:    types.FieldMismatch,
+			Message: "to is a search for a different route, dates, cabin or passenger count than from",
+		}}, nil)
+	}
+
+	// Partners compare their own prices, never the providers'.
+	ctx := c.Request().Context()
+	for _, s := range []*snapshot.Snapshot{&from, &to} {
+		s.Flights = h.markUp(ctx, s.Flights)
+		s.ReturnFlights = h.markUp(ctx, s.ReturnFlights)
+	}
+	return c.JSON(http.StatusOK, snapshot.Diff(from, to))
+}
+
+// storedSearch is the snapshot of the search with id. Without a snapshot
+// store no search_id was ever issued, so every id has expired.
+func (h *SearchHandler) storedSearch(c echo.Context, id string) (snapshot.Snapshot, error) {
+	err := snapshot.ErrNotFound
+	var snap snapshot.Snapshot
+	if h.snapshots != nil {
+		snap, err = h.snapshots.Get(c.Request().Context(), id)
+	}
+	if errors.Is(err, snapshot.ErrNotFound) {
+		err = &errorsx.Error{
+			Kind:    errorsx.Expired,
+			Code:    types.CodeSearchExpired,
+			Message: "Search " + id + " has expired",
+		}
+	}
+	return snap, err
+}
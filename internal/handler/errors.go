@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// errorResponse renders err with the status and error code errorsx maps
+// its kind to. code and action name the failed operation, e.g.
+// types.CodeLockError and "Failed to lock fare", for errors that are not the
+// caller's to fix.
+func errorResponse(c echo.Context, err error, code types.ErrorCode, action string) error {
+	status, body := errorBody(err, code, action)
+	return c.JSON(status, body)
+}
+
+// errorBody is the status and body errorResponse renders. A request that
+// ran out of time is a timeout whichever call noticed.
+func errorBody(err error, code types.ErrorCode, action string) (int, models.ErrorResponse) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = errorsx.Classify(err, errorsx.Timeout)
+	}
+	status := errorsx.HTTPStatus(err)
+	msg := err.Error()
+	if !errorsx.ClientError(err) && action != "" {
+		msg = action + ": " + msg
+	}
+	return status, models.ErrorResponse{
+		Error:   errorsx.HTTPCode(err, code),
+		Message: msg,
+		Code:    status,
+	}
+}
+
+// invalidRequest renders a request that can't be read, as opposed to one
+// that reads but fails validation. Either message or cause may be empty.
+func invalidRequest(c echo.Context, message string, cause error) error {
+	return errorResponse(c, &errorsx.Error{
+		Kind:    errorsx.Invalid,
+		Code:    types.CodeInvalidRequest,
+		Message: message,
+		Err:     cause,
+	}, "", "")
+}
+
+// bindError renders a body that doesn't parse, or that BodyLimit cut off.
+func bindError(c echo.Context, err error) error {
+	var tooLong *http.MaxBytesError
+	if errors.As(err, &tooLong) {
+		return errorResponse(c, tooLarge(tooLong.Limit), "", "")
+	}
+	return invalidRequest(c, "Failed to parse request body", err)
+}
+
+// validationError renders a validation_error, listing each invalid field
+// when err is an errorsx.ValidationErrors.
+func validationError(c echo.Context, err error, normalizations []models.Normalization) error {
+	err = errorsx.Classify(err, errorsx.Invalid)
+	status := errorsx.HTTPStatus(err)
+	resp := models.ErrorResponse{
+		Error:          errorsx.HTTPCode(err, ""),
+		Message:        err.Error(),
+		Code:           status,
+		Normalizations: normalizations,
+	}
+	var fieldErrs errorsx.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		resp.Errors = fieldErrs
+	}
+	return c.JSON(status, resp)
+}
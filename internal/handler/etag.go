@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/cache"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+)
+
+// searchETag is a weak validator for a GET one-way search: the hash of the
+// cache entry the response is built from, plus everything else that shapes
+// the body (criteria, filters, ranking, field selection, tenant, pricing
+// and the config version, which changes with the markup and promotion
+// rules themselves). Timing metadata differs between equivalent
+// responses, hence weak.
+func searchETag(c echo.Context, req models.SearchRequest, flights []models.Flight, pricing, configVersion string) string {
+	if c.Request().Method != http.MethodGet {
+		return ""
+	}
+
+	criteria, _ := json.Marshal(buildSearchCriteria(req))
+	h := sha256.New()
+	for _, part := range []string{
+		cache.EntryHash(flights),
+		string(criteria),
+		c.QueryParam("fields"),
+		reqctx.Tenant(c.Request().Context()),
+		models.SearchOptionsFrom(c.Request().Context()).Locale,
+		pricing,
+		configVersion,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// checkETag sets the ETag header and reports whether the client's
+// If-None-Match already matches it (weak comparison).
+func checkETag(c echo.Context, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	c.Response().Header().Set("ETag", etag)
+
+	header := c.Request().Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/farelock"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+type FareLockHandler struct {
+	aggregator *aggregator.Aggregator
+	service    *farelock.Service
+}
+
+func NewFareLockHandler(agg *aggregator.Aggregator, service *farelock.Service) *FareLockHandler {
+	return &FareLockHandler{
+		aggregator: agg,
+		service:    service,
+	}
+}
+
+func (h *FareLockHandler) Lock(c echo.Context) error {
+	ctx := c.Request().Context()
+	flightID := c.Param("id")
+
+	var req models.FareLockRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(c, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return validationError(c, err, nil)
+	}
+
+	flight, err := h.aggregator.FindFlight(ctx, req.SearchRequest(), flightID)
+	if err != nil {
+		return errorResponse(c, err, types.CodeSearchError, "Failed to look up flight")
+	}
+
+	provider, ok := h.aggregator.Provider(flight.Provider)
+	if !ok {
+		return errorResponse(c, errorsx.New(errorsx.Internal, "unknown provider "+flight.Provider), types.CodeLockError, "Failed to lock fare")
+	}
+
+	lock, err := h.service.Lock(ctx, provider, flight, req.Passengers, time.Duration(req.HoldHours)*time.Hour)
+	if err != nil {
+		return errorResponse(c, err, types.CodeLockError, "Failed to lock fare")
+	}
+
+	return c.JSON(http.StatusCreated, lock)
+}
+
+func (h *FareLockHandler) Get(c echo.Context) error {
+	lock, err := h.service.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return errorResponse(c, err, types.CodeLockError, "Failed to load fare lock")
+	}
+
+	return c.JSON(http.StatusOK, lock)
+}
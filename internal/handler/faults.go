@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/faults"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// FaultRequest sets a provider's fault. Duration, e.g. "15m", lifts it
+// automatically; without one it lasts until cleared.
+type FaultRequest struct {
+	ErrorRate float64 `json:"error_rate"`
+	EmptyRate float64 `json:"empty_rate"`
+	LatencyMs int     `json:"latency_ms"`
+	Duration  string  `json:"duration,omitempty"`
+}
+
+// SetFaults enables the fault injection endpoints.
+func (h *AdminHandler) SetFaults(i *faults.Injector) {
+	h.faults = i
+}
+
+func (h *AdminHandler) Faults(c echo.Context) error {
+	if h.faults == nil {
+		return faultsDisabled(c)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"faults": h.faults.Faults(),
+	})
+}
+
+func (h *AdminHandler) SetFault(c echo.Context) error {
+	if h.faults == nil {
+		return faultsDisabled(c)
+	}
+	provider := c.Param("provider")
+	if _, ok := h.agg.Provider(provider); !ok {
+		return errorResponse(c, errorsx.New(errorsx.NotFound, "Unknown provider "+provider), "", "")
+	}
+
+	var req FaultRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(c, err)
+	}
+	fault := faults.Fault{ErrorRate: req.ErrorRate, EmptyRate: req.EmptyRate, LatencyMs: req.LatencyMs}
+	var errs errorsx.ValidationErrors
+	errors.As(fault.Validate(), &errs)
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			errs.Add("duration", types.FieldInvalidFormat, "duration must be a positive duration such as 15m")
+		} else {
+			expires := time.Now().Add(d)
+			fault.ExpiresAt = &expires
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return validationError(c, err, nil)
+	}
+
+	h.faults.Set(provider, fault)
+	log.Printf("Fault injected into %s: error_rate=%v empty_rate=%v latency_ms=%d duration=%q", provider, req.ErrorRate, req.EmptyRate, req.LatencyMs, req.Duration)
+	return c.JSON(http.StatusOK, fault)
+}
+
+func (h *AdminHandler) ClearFault(c echo.Context) error {
+	if h.faults == nil {
+		return faultsDisabled(c)
+	}
+	if !h.faults.Clear(c.Param("provider")) {
+		return errorResponse(c, errorsx.New(errorsx.NotFound, "No fault injected into "+c.Param("provider")), "", "")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *AdminHandler) ClearFaults(c echo.Context) error {
+	if h.faults == nil {
+		return faultsDisabled(c)
+	}
+	h.faults.ClearAll()
+	return c.NoContent(http.StatusNoContent)
+}
+
+func faultsDisabled(c echo.Context) error {
+	return errorResponse(c, errorsx.New(errorsx.NotFound, "Fault injection is not enabled"), "", "")
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/markup"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/pricehistory"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+var historyIntervals = map[string]time.Duration{
+	"":     0,
+	"raw":  0,
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+}
+
+type HistoryHandler struct {
+	store   pricehistory.Store
+	markups map[string]markup.Policy
+}
+
+func NewHistoryHandler(store pricehistory.Store) *HistoryHandler {
+	return &HistoryHandler{store: store}
+}
+
+// SetMarkups sets each tenant's markup policy, which its history is
+// priced with.
+func (h *HistoryHandler) SetMarkups(policies map[string]markup.Policy) {
+	h.markups = policies
+}
+
+func (h *HistoryHandler) History(c echo.Context) error {
+	req := models.SearchRequest{
+		Origin:        c.QueryParam("origin"),
+		Destination:   c.QueryParam("destination"),
+		DepartureDate: c.QueryParam("date"),
+		CabinClass:    c.QueryParam("cabin_class"),
+	}
+	normalizations := req.Normalize()
+	if err := req.ValidateRoute(); err != nil {
+		return validationError(c, err, normalizations)
+	}
+
+	interval := strings.ToLower(c.QueryParam("interval"))
+	bucket, ok := historyIntervals[interval]
+	if !ok {
+		return errorResponse(c, errorsx.New(errorsx.Invalid, "interval must be one of raw, hour, day"), "", "")
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	if v := c.QueryParam("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return errorResponse(c, errorsx.New(errorsx.Invalid, "from must be an RFC3339 timestamp"), "", "")
+		}
+		from = t
+	}
+	if v := c.QueryParam("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return errorResponse(c, errorsx.New(errorsx.Invalid, "to must be an RFC3339 timestamp"), "", "")
+		}
+		to = t
+	}
+
+	key := pricehistory.NewRouteKey(req)
+	points, err := h.store.Range(c.Request().Context(), key, from, to)
+	if err != nil {
+		return errorResponse(c, err, types.CodeHistoryError, "Failed to load price history")
+	}
+	if policy, ok := h.markups[reqctx.Tenant(c.Request().Context())]; ok {
+		for i := range points {
+			points[i].MinPrice = policy.Gross(points[i].MinPrice)
+		}
+	}
+
+	return c.JSON(http.StatusOK, models.PriceHistoryResponse{
+		Origin:        key.Origin,
+		Destination:   key.Destination,
+		DepartureDate: key.DepartureDate,
+		CabinClass:    key.CabinClass,
+		Interval:      interval,
+		Points:        pricehistory.Downsample(points, bucket),
+	})
+}
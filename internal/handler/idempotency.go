@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/idempotency"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed marks a response answered from a previous
+	// request with the same key.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+const (
+	maxIdempotencyKeyLen = 255
+	// idempotencyPendingTTL is how long a request holds its key before a
+	// retry may run again, should its replica die mid-request.
+	idempotencyPendingTTL = time.Minute
+	idempotencyPoll       = 100 * time.Millisecond
+)
+
+// Idempotency answers a request repeating an earlier one's Idempotency-Key
+// with the earlier response, for ttl. Keys are scoped to the client (API
+// key, else IP) and the route, and must not be reused for a different
+// request. A retry arriving while the first request runs waits for its
+// response. Server errors and rate limiting aren't kept, so their retries
+// run again. It must run after RequestContext.
+func Idempotency(store idempotency.Store, ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			if !validIdempotencyKey(key) {
+				return invalidRequest(c, "Invalid Idempotency-Key header", fmt.Errorf("must be at most %d printable ASCII characters", maxIdempotencyKeyLen))
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return bindError(c, err)
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := c.Request().Context()
+			scope := idempotencyScope(c, key)
+			fingerprint := requestFingerprint(c, body)
+			for {
+				rec, claimed, err := store.Claim(ctx, scope, idempotency.Record{Fingerprint: fingerprint}, idempotencyPendingTTL)
+				if err != nil {
+					log.Printf("Idempotency store unavailable, serving request without it: %v", err)
+					return next(c)
+				}
+				if claimed {
+					return runIdempotent(c, next, store, scope, fingerprint, ttl)
+				}
+				if rec.Fingerprint != fingerprint {
+					return errorResponse(c, errorsx.New(errorsx.Unprocessable, "Idempotency-Key was already used for a different request"), "", "")
+				}
+				if !rec.Pending() {
+					return replay(c, rec)
+				}
+
+				rec, found, err := awaitIdempotent(ctx, store, scope)
+				switch {
+				case errors.Is(err, context.DeadlineExceeded):
+					return errorResponse(c, errorsx.New(errorsx.Conflict, "A request with this Idempotency-Key is still in progress"), "", "")
+				case err != nil:
+					log.Printf("Idempotency store unavailable, serving request without it: %v", err)
+					return next(c)
+				case found && rec.Fingerprint == fingerprint:
+					return replay(c, rec)
+				}
+				// The first request failed and freed the key, or another
+				// took it since: try to claim it again.
+			}
+		}
+	}
+}
+
+// runIdempotent serves a request that claimed scope and keeps the response.
+func runIdempotent(c echo.Context, next echo.HandlerFunc, store idempotency.Store, scope, fingerprint string, ttl time.Duration) error {
+	res := c.Response()
+	recorder := &recordingWriter{ResponseWriter: res.Writer}
+	res.Writer = recorder
+	err := next(c)
+	res.Writer = recorder.ResponseWriter
+
+	ctx := context.WithoutCancel(c.Request().Context())
+	if err != nil || res.Status >= http.StatusInternalServerError || res.Status == http.StatusTooManyRequests {
+		if err := store.Release(ctx, scope); err != nil {
+			log.Printf("Failed to release idempotency key: %v", err)
+		}
+		return err
+	}
+	rec := idempotency.Record{
+		Fingerprint: fingerprint,
+		Status:      res.Status,
+		ContentType: res.Header().Get(echo.HeaderContentType),
+		Body:        recorder.body.Bytes(),
+	}
+	if err := store.Complete(ctx, scope, rec, ttl); err != nil {
+		log.Printf("Failed to store idempotent response: %v", err)
+	}
+	return nil
+}
+
+// awaitIdempotent polls scope until its request is answered or frees it,
+// or the pending TTL passes.
+func awaitIdempotent(ctx context.Context, store idempotency.Store, scope string) (idempotency.Record, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, idempotencyPendingTTL)
+	defer cancel()
+	ticker := time.NewTicker(idempotencyPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return idempotency.Record{}, false, ctx.Err()
+		case <-ticker.C:
+		}
+		rec, found, err := store.Get(ctx, scope)
+		if err != nil || !found || !rec.Pending() {
+			return rec, found, err
+		}
+	}
+}
+
+func replay(c echo.Context, rec idempotency.Record) error {
+	c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+	return c.Blob(rec.Status, rec.ContentType, rec.Body)
+}
+
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLen {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// idempotencyScope hashes the client, route and key, so neither API keys
+// nor client keys end up in the store.
+func idempotencyScope(c echo.Context, key string) string {
+	client := "ip:" + c.RealIP()
+	if apiKey := reqctx.APIKey(c.Request().Context()); apiKey != "" {
+		client = "key:" + apiKey
+	}
+	sum := sha256.Sum256([]byte(client + "\x00" + c.Request().Method + " " + c.Request().URL.Path + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// requestFingerprint covers what else shapes the response: the query and
+// body.
+func requestFingerprint(c echo.Context, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(c.Request().URL.RawQuery))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter copies the response body as it is written.
+type recordingWriter struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+// RequestTimeout gives every request until timeout to be answered, as the
+// deadline of its context. Searches end before it so that they answer with
+// what they have. Event streams are exempt.
+func RequestTimeout(timeout time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if strings.HasSuffix(c.Path(), "/stream") {
+				return next(c)
+			}
+			ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// BodyLimit rejects request bodies over limit bytes with 413
+// request_too_large, whether or not they declare their length. Admin
+// endpoints are exempt, uploads there having limits of their own.
+func BodyLimit(limit int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if strings.HasPrefix(c.Path(), "/admin/") {
+				return next(c)
+			}
+			req := c.Request()
+			if req.ContentLength > limit {
+				return errorResponse(c, tooLarge(limit), "", "")
+			}
+			req.Body = http.MaxBytesReader(c.Response(), req.Body, limit)
+			return next(c)
+		}
+	}
+}
+
+func tooLarge(limit int64) error {
+	return errorsx.Errorf(errorsx.TooLarge, "request body exceeds %d bytes", limit)
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/offsets"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+type OffsetHandler struct {
+	aggregator *aggregator.Aggregator
+	service    *offsets.Service
+}
+
+func NewOffsetHandler(agg *aggregator.Aggregator, service *offsets.Service) *OffsetHandler {
+	return &OffsetHandler{
+		aggregator: agg,
+		service:    service,
+	}
+}
+
+func (h *OffsetHandler) Quote(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req models.OffsetQuoteRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(c, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return validationError(c, err, nil)
+	}
+
+	flight, err := h.aggregator.FindFlight(ctx, req.SearchRequest(), req.FlightID)
+	if err != nil {
+		return errorResponse(c, err, types.CodeSearchError, "Failed to look up flight")
+	}
+
+	quote, err := h.service.Quote(ctx, flight, req.Passengers)
+	if err != nil {
+		return errorResponse(c, err, types.CodeOffsetError, "Failed to quote carbon offset")
+	}
+
+	return c.JSON(http.StatusCreated, quote)
+}
+
+func (h *OffsetHandler) Purchase(c echo.Context) error {
+	var req models.OffsetPurchaseRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(c, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return validationError(c, err, nil)
+	}
+
+	purchase, err := h.service.Purchase(c.Request().Context(), req)
+	if err != nil {
+		return errorResponse(c, err, types.CodeOffsetError, "Failed to purchase carbon offset")
+	}
+
+	return c.JSON(http.StatusCreated, purchase)
+}
+
+func (h *OffsetHandler) GetPurchase(c echo.Context) error {
+	purchase, err := h.service.GetPurchase(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return errorResponse(c, err, types.CodeOffsetError, "Failed to load offset purchase")
+	}
+
+	return c.JSON(http.StatusOK, purchase)
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/i18n"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/pkg/currency"
+)
+
+// HeaderDebug asks providers to log how they answered; honoured for
+// trusted callers only.
+const HeaderDebug = "X-Debug"
+
+// searchOptions reads the per-request options: the locale from
+// Accept-Language, and debugging for trusted callers.
+func searchOptions(c echo.Context) models.SearchOptions {
+	ctx := c.Request().Context()
+	return models.SearchOptions{
+		Locale: negotiateLocale(c.Request().Header.Get("Accept-Language")),
+		Debug:  reqctx.Trusted(ctx) && c.Request().Header.Get(HeaderDebug) == "true",
+		Tier:   string(reqctx.ClientTier(ctx)),
+	}
+}
+
+// negotiateLocale picks the first supported language in an
+// Accept-Language header such as "en-US,en;q=0.9,id;q=0.8". Tags are
+// taken in the order listed.
+func negotiateLocale(header string) string {
+	for _, tag := range strings.Split(header, ",") {
+		tag, _, _ = strings.Cut(tag, ";")
+		lang, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+		lang = strings.ToLower(lang)
+		if i18n.For(lang) != nil {
+			return lang
+		}
+	}
+	return models.DefaultLocale
+}
+
+// localize presents flights in the request's locale; flights must be a
+// fresh copy.
+func localize(flights []models.Flight, locale string) {
+	localizePrices(flights, locale)
+	localizeLabels(flights, locale)
+}
+
+// localizePrices formats prices for the request's locale. Providers format
+// for the default locale, so cached flights stay locale-neutral; flights
+// must be a fresh copy.
+func localizePrices(flights []models.Flight, locale string) {
+	if locale == models.DefaultLocale {
+		return
+	}
+	for i := range flights {
+		f := &flights[i]
+		localizePrice(&f.Price, locale)
+		f.BagFee = localizedCopy(f.BagFee, locale)
+		f.OriginalPrice = localizedCopy(f.OriginalPrice, locale)
+		if f.Promotion != nil {
+			promo := *f.Promotion
+			localizePrice(&promo.Discount, locale)
+			f.Promotion = &promo
+		}
+		if f.Ancillaries != nil {
+			f.Ancillaries = localizeAncillaries(*f.Ancillaries, locale)
+		}
+		if f.PriceBreakdown != nil {
+			b := *f.PriceBreakdown
+			for _, p := range []*models.Price{&b.Fare, &b.Net, &b.Gross} {
+				localizePrice(p, locale)
+			}
+			b.Markup = localizedCopy(b.Markup, locale)
+			b.Commission = localizedCopy(b.Commission, locale)
+			f.PriceBreakdown = &b
+		}
+	}
+}
+
+func localizePrice(p *models.Price, locale string) {
+	if p.Currency == "IDR" {
+		p.Formatted = currency.FormatIDRFor(p.Amount, locale)
+	}
+}
+
+// localizedCopy formats a copy of p, which the cached flights may share.
+func localizedCopy(p *models.Price, locale string) *models.Price {
+	if p == nil {
+		return nil
+	}
+	price := *p
+	localizePrice(&price, locale)
+	return &price
+}
+
+// localizeAncillaries formats a copy of a, which the cached flights share.
+func localizeAncillaries(a models.Ancillaries, locale string) *models.Ancillaries {
+	a.CheckedBags = slices.Clone(a.CheckedBags)
+	for i := range a.CheckedBags {
+		localizePrice(&a.CheckedBags[i].Price, locale)
+	}
+	a.SeatSelection = localizedCopy(a.SeatSelection, locale)
+	a.Meal = localizedCopy(a.Meal, locale)
+	return &a
+}
+
+// localizeLabels names cities, cabins and amenities in the locale. City
+// names come from the catalog rather than providers, which name cities
+// differently; airports it doesn't know keep the provider's name.
+func localizeLabels(flights []models.Flight, locale string) {
+	catalog := i18n.For(locale)
+	for i := range flights {
+		f := &flights[i]
+		f.Departure.City = catalog.City(f.Departure.Airport, f.Departure.City)
+		f.Arrival.City = catalog.City(f.Arrival.Airport, f.Arrival.City)
+		if len(f.Layovers) > 0 {
+			f.Layovers = slices.Clone(f.Layovers)
+			for j := range f.Layovers {
+				f.Layovers[j].City = catalog.City(f.Layovers[j].Airport, f.Layovers[j].City)
+			}
+		}
+		f.CabinClassLabel = catalog.CabinClass(f.CabinClass)
+		f.AmenityLabels = nil
+		for _, a := range f.Amenities {
+			f.AmenityLabels = append(f.AmenityLabels, catalog.Amenity(a))
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/health"
+	"github.com/dharmasatrya/flightsearch/internal/providers"
+	"github.com/dharmasatrya/flightsearch/internal/ratelimit"
+)
+
+type ProviderHealth struct {
+	State        health.State `json:"state"`
+	Degraded     bool         `json:"degraded"`
+	Requests     int          `json:"requests"`
+	FailureRate  float64      `json:"failure_rate"`
+	AvgLatencyMs float64      `json:"avg_latency_ms"`
+}
+
+type ProviderRateLimit struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	BurstSize         int     `json:"burst_size"`
+}
+
+type ProviderInfo struct {
+	Name string `json:"name"`
+	providers.Capabilities
+	PassengerRules providers.PassengerRules `json:"passenger_rules"`
+	RateLimit      *ProviderRateLimit       `json:"rate_limit,omitempty"`
+	// Health covers the tracker's recent window; AvgLatencyMs is zero when
+	// the provider hasn't been called in it.
+	Health ProviderHealth `json:"health"`
+}
+
+type ProviderHandler struct {
+	aggregator *aggregator.Aggregator
+	health     *health.Tracker
+	limiter    ratelimit.Limiter
+}
+
+func NewProviderHandler(agg *aggregator.Aggregator, h *health.Tracker, l ratelimit.Limiter) *ProviderHandler {
+	return &ProviderHandler{
+		aggregator: agg,
+		health:     h,
+		limiter:    l,
+	}
+}
+
+func (h *ProviderHandler) List(c echo.Context) error {
+	limits := make(map[string]ratelimit.LimitStatus)
+	for _, s := range h.limiter.Snapshot() {
+		limits[s.Provider] = s
+	}
+
+	list := h.aggregator.Providers()
+	infos := make([]ProviderInfo, 0, len(list))
+	for _, p := range list {
+		name := p.Name()
+		stats := h.health.Stats(name)
+		info := ProviderInfo{
+			Name:           name,
+			Capabilities:   providers.CapabilitiesFor(p),
+			PassengerRules: providers.PassengerRulesFor(p),
+			Health: ProviderHealth{
+				State:        stats.State,
+				Degraded:     h.health.Degraded(name),
+				Requests:     stats.Requests,
+				FailureRate:  stats.FailureRate,
+				AvgLatencyMs: stats.AvgLatencyMs,
+			},
+		}
+		if l, ok := limits[name]; ok {
+			info.RateLimit = &ProviderRateLimit{RequestsPerSecond: l.RequestsPerSecond, BurstSize: l.BurstSize}
+		}
+		infos = append(infos, info)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"providers": infos,
+	})
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/ratelimit"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+// InboundRateLimit limits each client, identified by API key or else by
+// IP. Trusted callers and health checks are exempt. It must run after
+// RequestContext.
+func InboundRateLimit(limiter ratelimit.ClientLimiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			if reqctx.Trusted(ctx) || c.Path() == "/health" {
+				return next(c)
+			}
+
+			client := "ip:" + c.RealIP()
+			if key := reqctx.APIKey(ctx); key != "" {
+				client = "key:" + key
+			}
+
+			if ok, retryAfter := limiter.Allow(ctx, client); !ok {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				return errorResponse(c, errorsx.New(errorsx.RateLimited, "Too many requests, retry after "+retryAfter.Round(time.Millisecond).String()), "", "")
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/refdata"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// maxRefDataUpload bounds an import's files; the full OurAirports dump is
+// around 12MB.
+const maxRefDataUpload = 64 << 20
+
+type RefDataSummary struct {
+	Version    string    `json:"version"`
+	ImportedAt time.Time `json:"imported_at,omitzero"`
+	Airports   int       `json:"airports"`
+	Airlines   int       `json:"airlines"`
+}
+
+// RefDataImport is a staged import: what activating it would change.
+type RefDataImport struct {
+	RefDataSummary
+	Active  string          `json:"active_version"`
+	Changes refdata.Changes `json:"changes"`
+	Report  refdata.Report  `json:"report"`
+}
+
+func summarize(d *refdata.Dataset) RefDataSummary {
+	return RefDataSummary{Version: d.Version, ImportedAt: d.ImportedAt, Airports: len(d.Airports), Airlines: len(d.Airlines)}
+}
+
+// SetRefData enables reference data imports.
+func (h *AdminHandler) SetRefData(s refdata.Store) {
+	h.refdata = s
+}
+
+func (h *AdminHandler) RefData(c echo.Context) error {
+	return c.JSON(http.StatusOK, summarize(refdata.Active()))
+}
+
+// ImportRefData validates the uploaded "airports" and "airlines" dumps and
+// stages the result for activation. Either file may be left out to keep
+// the active table.
+func (h *AdminHandler) ImportRefData(c echo.Context) error {
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, maxRefDataUpload)
+
+	airports, err := formFile(c, "airports")
+	if err != nil {
+		return invalidRequest(c, "Invalid airports upload", err)
+	}
+	if airports != nil {
+		defer airports.Close()
+	}
+	airlines, err := formFile(c, "airlines")
+	if err != nil {
+		return invalidRequest(c, "Invalid airlines upload", err)
+	}
+	if airlines != nil {
+		defer airlines.Close()
+	}
+	if airports == nil && airlines == nil {
+		return invalidRequest(c, "Upload an airports or airlines file", nil)
+	}
+
+	active := refdata.Active()
+	next, report, err := refdata.Import(active, airports, airlines, time.Now().UTC())
+	if err != nil {
+		return errorResponse(c, err, types.CodeRefdataError, "Failed to import reference data")
+	}
+	if err := h.refdata.Stage(req.Context(), next); err != nil {
+		return errorResponse(c, err, types.CodeRefdataError, "Failed to stage reference data")
+	}
+	log.Printf("Reference data %s staged: %d airports, %d airlines, %d rows rejected", next.Version, len(next.Airports), len(next.Airlines), len(report.Rejected))
+	return c.JSON(http.StatusCreated, RefDataImport{
+		RefDataSummary: summarize(next),
+		Active:         active.Version,
+		Changes:        refdata.Diff(active, next),
+		Report:         report,
+	})
+}
+
+// ActivateRefData makes a staged import the dataset every replica serves.
+func (h *AdminHandler) ActivateRefData(c echo.Context) error {
+	d, err := h.refdata.Activate(c.Request().Context(), c.Param("version"))
+	if err != nil {
+		return errorResponse(c, err, types.CodeRefdataError, "Failed to activate reference data")
+	}
+	if err := refdata.Activate(d); err != nil {
+		return errorResponse(c, err, types.CodeRefdataError, "Failed to activate reference data")
+	}
+	return c.JSON(http.StatusOK, summarize(d))
+}
+
+// formFile opens the named upload, nil when there is none.
+func formFile(c echo.Context, name string) (io.ReadCloser, error) {
+	fh, err := c.FormFile(name)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fh.Open()
+}
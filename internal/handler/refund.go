@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/farerules"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+type RefundHandler struct {
+	aggregator *aggregator.Aggregator
+}
+
+func NewRefundHandler(agg *aggregator.Aggregator) *RefundHandler {
+	return &RefundHandler{aggregator: agg}
+}
+
+func (h *RefundHandler) Quote(c echo.Context) error {
+	flightID := c.Param("id")
+
+	var req models.RefundQuoteRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(c, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return validationError(c, err, nil)
+	}
+
+	flight, err := h.aggregator.FindFlight(c.Request().Context(), req.SearchRequest(), flightID)
+	if err != nil {
+		return errorResponse(c, err, types.CodeSearchError, "Failed to look up flight")
+	}
+
+	paid := flight.Price.Amount * float64(req.Passengers)
+	if req.PaidAmount != nil {
+		paid = *req.PaidAmount
+	}
+	cancelAt := time.Now()
+	if req.CancelAt != nil {
+		cancelAt = *req.CancelAt
+	}
+
+	return c.JSON(http.StatusOK, farerules.QuoteRefund(flight, paid, cancelAt))
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/cache"
+	"github.com/dharmasatrya/flightsearch/internal/configsnap"
+	"github.com/dharmasatrya/flightsearch/internal/filter"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/ranking"
+	"github.com/dharmasatrya/flightsearch/internal/recording"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// SearchReplay is a past search reproduced from what its providers
+// answered, under the configuration it ran with.
+type SearchReplay struct {
+	SearchID   string               `json:"search_id"`
+	RecordedAt time.Time            `json:"recorded_at"`
+	Request    models.SearchRequest `json:"request"`
+
+	ConfigVersion        string              `json:"config_version"`
+	CurrentConfigVersion string              `json:"current_config_version"`
+	Config               configsnap.Snapshot `json:"config"`
+
+	// Result is a SearchResponse, or a RoundTripResponse for round trips.
+	Result any `json:"result"`
+
+	Outbound []aggregator.ProviderResponse `json:"outbound_provider_responses"`
+	Return   []aggregator.ProviderResponse `json:"return_provider_responses,omitempty"`
+
+	// Notes list what the replay could not reproduce.
+	Notes []string `json:"notes,omitempty"`
+}
+
+// SetRecordings records what the providers answered for every search with
+// a search_id, along with the configuration, so admins can replay it.
+func (h *SearchHandler) SetRecordings(s recording.Store) {
+	h.recordings = s
+}
+
+func (h *SearchHandler) record(ctx context.Context, searchID string, req models.SearchRequest, outbound, returnResult *aggregator.Result) {
+	if h.recordings == nil || searchID == "" {
+		return
+	}
+	r := recording.Recording{
+		SearchID:      searchID,
+		RecordedAt:    time.Now(),
+		Request:       req,
+		ConfigVersion: h.configVersion,
+		Config:        h.config,
+		Outbound:      outbound.Responses,
+	}
+	if returnResult != nil {
+		r.Return = returnResult.Responses
+	}
+	if err := h.recordings.Save(ctx, r); err != nil {
+		reqctx.Logf(ctx, "Failed to record search %s: %v", searchID, err)
+	}
+}
+
+// Replay reproduces a recorded search. A body may change the filters, sort
+// or ranking of the recorded request, but not its route, dates, cabin or
+// passengers.
+func (h *SearchHandler) Replay(c echo.Context) error {
+	ctx := c.Request().Context()
+	if h.recordings == nil {
+		return errorResponse(c, errorsx.New(errorsx.NotFound, "Search recording is not enabled"), "", "")
+	}
+
+	rec, err := h.recordings.Get(ctx, c.Param("id"))
+	if err != nil {
+		return searchError(c, err)
+	}
+
+	req := rec.Request
+	if c.Request().ContentLength > 0 {
+		var override models.SearchRequest
+		if err := c.Bind(&override); err != nil {
+			return bindError(c, err)
+		}
+		normalizations := override.Normalize()
+		if cache.KeyFor(override) != cache.KeyFor(rec.Request) {
+			return validationError(c, errorsx.ValidationErrors{{
+				Field:   "search_id",
+				Code:    types.FieldMismatch,
+				Message: "the request's route, dates, cabin or passengers differ from the recorded search",
+			}}, normalizations)
+		}
+		req = override
+	}
+
+	cfg := rec.Config
+	defaults := ranking.WeightsFromModel(cfg.RankingWeights)
+	rank := func(flights []models.Flight) []models.Flight {
+		return h.rankWith(ctx, flights, req, defaults, nil)
+	}
+
+	roundTrip := req.ReturnDate != nil && *req.ReturnDate != ""
+	outboundLeg := ""
+	if roundTrip {
+		outboundLeg = legOutbound
+	}
+	outbound, meta := h.replayMerge(rec.Outbound, cfg, outboundLeg)
+	meta.SearchID = rec.SearchID
+	meta.ResultsAsOf = &rec.RecordedAt
+	meta.ConfigVersion = rec.ConfigVersion
+
+	replay := SearchReplay{
+		SearchID:             rec.SearchID,
+		RecordedAt:           rec.RecordedAt,
+		Request:              req,
+		ConfigVersion:        rec.ConfigVersion,
+		CurrentConfigVersion: h.configVersion,
+		Config:               cfg,
+		Outbound:             rec.Outbound,
+		Return:               rec.Return,
+		Notes:                replayNotes(req, cfg),
+	}
+
+	if roundTrip {
+		returnFlights := []models.Flight{}
+		if rec.Return != nil {
+			var returnMeta models.SearchMetadata
+			returnFlights, returnMeta = h.replayMerge(rec.Return, cfg, legReturn)
+			meta.ProvidersQueried += returnMeta.ProvidersQueried
+			meta.ProvidersSucceeded += returnMeta.ProvidersSucceeded
+			meta.ProvidersFailed += returnMeta.ProvidersFailed
+			meta.FailedProviders = uniqueStrings(append(meta.FailedProviders, returnMeta.FailedProviders...))
+			meta.ProviderErrors = append(meta.ProviderErrors, returnMeta.ProviderErrors...)
+			for name, n := range returnMeta.TruncatedProviders {
+				if meta.TruncatedProviders == nil {
+					meta.TruncatedProviders = make(map[string]int)
+				}
+				meta.TruncatedProviders[name] += n
+			}
+			meta.Fallbacks = mergeFallbacks(meta.Fallbacks, returnMeta.Fallbacks)
+		}
+		outboundRanked, returnRanked := rank(outbound), rank(returnFlights)
+		meta.TotalResults = len(outboundRanked) + len(returnRanked)
+		replay.Result = models.RoundTripResponse{
+			SearchCriteria:  buildSearchCriteria(req),
+			Metadata:        meta,
+			OutboundFlights: outboundRanked,
+			ReturnFlights:   returnRanked,
+		}
+		return c.JSON(http.StatusOK, replay)
+	}
+
+	ranked := rank(outbound)
+	meta.TotalResults = len(ranked)
+	replay.Result = models.SearchResponse{
+		SearchCriteria: buildSearchCriteria(req),
+		Metadata:       meta,
+		Flights:        ranked,
+		Facets:         buildFacets(req, outbound),
+	}
+	return c.JSON(http.StatusOK, replay)
+}
+
+// replayMerge merges recorded provider responses of leg with the recorded
+// cap and strategy, and rebuilds the provider counts and errors of the
+// metadata.
+func (h *SearchHandler) replayMerge(responses []aggregator.ProviderResponse, cfg configsnap.Snapshot, leg string) ([]models.Flight, models.SearchMetadata) {
+	flights, truncated := h.aggregator.Merge(responses, cfg.ProviderCap, cfg.ResultMerge)
+	meta := models.SearchMetadata{
+		ProviderCap:        cfg.ProviderCap,
+		TruncatedProviders: truncated,
+		Region:             cfg.Region,
+		ProviderErrors:     providerErrors(responses, leg),
+	}
+	for _, r := range responses {
+		switch {
+		case r.FallbackFor != "":
+			meta.Fallbacks = mergeFallbacks(meta.Fallbacks, map[string]string{r.FallbackFor: r.Provider})
+			continue
+		case r.Error != "":
+			meta.ProvidersFailed++
+			meta.FailedProviders = append(meta.FailedProviders, r.Provider)
+		default:
+			meta.ProvidersSucceeded++
+		}
+		meta.ProvidersQueried++
+	}
+	return flights, meta
+}
+
+func replayNotes(req models.SearchRequest, cfg configsnap.Snapshot) []string {
+	notes := []string{"price trend, ground transport and cross-sell are not replayed"}
+	if cfg.RankingModel != "" && filter.SortsByBestValue(req.SortBy, req.SortOrder) {
+		notes = append(notes, "ranked with the heuristic: the external ranking model's scores are not recorded")
+	}
+	if req.PassengerMix != nil {
+		notes = append(notes, "unbookable_reasons use the providers' current passenger rules")
+	}
+	if len(cfg.Promotions) > 0 {
+		notes = append(notes, "discounts are those of the promotions running now")
+	}
+	return notes
+}
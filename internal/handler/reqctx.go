@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+const HeaderAPIKey = "X-API-Key"
+
+// RequestContext resolves the caller once and stores it in the request
+// context for handlers, the aggregator and providers. It must run after
+// the RequestID middleware. Requests without an API key are served
+// anonymously; unknown keys are rejected.
+func RequestContext(keys map[string]reqctx.Key, flags reqctx.Flags) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			info := reqctx.Info{
+				RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+				Tier:      reqctx.TierAnonymous,
+				Flags:     flags,
+			}
+
+			if apiKey := c.Request().Header.Get(HeaderAPIKey); apiKey != "" {
+				key, ok := keys[apiKey]
+				if !ok {
+					return errorResponse(c, errorsx.New(errorsx.Unauthorized, "Unknown API key"), "", "")
+				}
+				info.APIKey = apiKey
+				info.Tenant = key.Tenant
+				info.Tier = key.Tier
+				info.Admin = key.Admin
+			}
+
+			req := c.Request()
+			c.SetRequest(req.WithContext(reqctx.With(req.Context(), info)))
+			return next(c)
+		}
+	}
+}
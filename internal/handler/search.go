@@ -1,151 +1,669 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 
 	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/analytics"
+	"github.com/dharmasatrya/flightsearch/internal/audit"
+	"github.com/dharmasatrya/flightsearch/internal/brownout"
 	"github.com/dharmasatrya/flightsearch/internal/cache"
+	"github.com/dharmasatrya/flightsearch/internal/configsnap"
+	"github.com/dharmasatrya/flightsearch/internal/crosssell"
+	"github.com/dharmasatrya/flightsearch/internal/deeplink"
+	"github.com/dharmasatrya/flightsearch/internal/facets"
 	"github.com/dharmasatrya/flightsearch/internal/filter"
+	"github.com/dharmasatrya/flightsearch/internal/groundtransport"
+	"github.com/dharmasatrya/flightsearch/internal/markup"
 	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/pricehistory"
+	"github.com/dharmasatrya/flightsearch/internal/promotions"
+	"github.com/dharmasatrya/flightsearch/internal/providers"
+	"github.com/dharmasatrya/flightsearch/internal/ranking"
+	"github.com/dharmasatrya/flightsearch/internal/recording"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/internal/snapshot"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
 )
 
 type SearchHandler struct {
-	aggregator *aggregator.Aggregator
-	cache      cache.Cache
+	aggregator      *aggregator.Aggregator
+	cache           cache.Cache
+	results         cache.ResultCache
+	groundTransport groundtransport.Provider
+	hotels          crosssell.HotelSupplier
+	priceHistory    pricehistory.Store
+	defaultWeights  ranking.Weights
+	searches        *analytics.SearchCounter
+	events          analytics.EventStore
+	audit           *audit.Logger
+	publicMaxAge    time.Duration
+	model           *ranking.ModelRanker
+	snapshots       snapshot.Store
+	recordings      recording.Store
+	config          configsnap.Snapshot
+	configVersion   string
+	region          string
+	brownout        *brownout.Controller
+	inFlight        *brownout.InFlight
+	freshness       cache.Freshness
+	quoteTTL        time.Duration
+	bookingLinks    *deeplink.Builder
+	promotions      *promotions.Engine
+	markups         map[string]markup.Policy
+	partialStatus   int
+	demo            bool
 }
 
 func NewSearchHandler(agg *aggregator.Aggregator, c cache.Cache) *SearchHandler {
 	return &SearchHandler{
-		aggregator: agg,
-		cache:      c,
+		aggregator:     agg,
+		cache:          c,
+		defaultWeights: ranking.DefaultWeights(),
 	}
 }
 
+func (h *SearchHandler) SetSearchCounter(sc *analytics.SearchCounter) {
+	h.searches = sc
+}
+
+// SetSearchEvents records an anonymous event for every answered search.
+func (h *SearchHandler) SetSearchEvents(store analytics.EventStore) {
+	h.events = store
+}
+
+// SetAudit records every search that passes validation to l.
+func (h *SearchHandler) SetAudit(l *audit.Logger) {
+	h.audit = l
+}
+
+func (h *SearchHandler) SetDefaultWeights(w ranking.Weights) {
+	h.defaultWeights = w
+}
+
+// SetRankingModel scores best value searches with an external model
+// instead of the heuristic.
+func (h *SearchHandler) SetRankingModel(m *ranking.ModelRanker) {
+	h.model = m
+}
+
+func (h *SearchHandler) SetGroundTransport(p groundtransport.Provider) {
+	h.groundTransport = p
+}
+
+func (h *SearchHandler) SetHotelSupplier(s crosssell.HotelSupplier) {
+	h.hotels = s
+}
+
+func (h *SearchHandler) SetPriceHistory(store pricehistory.Store) {
+	h.priceHistory = store
+}
+
+// SetPublicMaxAge lets shared caches (CDNs) keep successful GET search
+// responses for d. Zero disables the Cache-Control header.
+func (h *SearchHandler) SetPublicMaxAge(d time.Duration) {
+	h.publicMaxAge = d
+}
+
+// SetRegion names the deployment region reported in search metadata.
+func (h *SearchHandler) SetRegion(region string) {
+	h.region = region
+}
+
+// SetDemo marks search results as synthetic demo data.
+func (h *SearchHandler) SetDemo(demo bool) {
+	h.demo = demo
+}
+
+// SetConfig is the effective configuration searches run with; its version
+// is reported in search metadata and recorded for replay.
+func (h *SearchHandler) SetConfig(cfg configsnap.Snapshot) {
+	h.config = cfg
+	h.configVersion = cfg.Version()
+}
+
+// SetBrownout sheds features at the controller's level, and counts the
+// searches in progress in inFlight.
+func (h *SearchHandler) SetBrownout(c *brownout.Controller, inFlight *brownout.InFlight) {
+	h.brownout = c
+	h.inFlight = inFlight
+}
+
+// SetResultCache keeps ranked results per tenant, so repeated searches
+// over the same cached flights skip filtering and ranking.
+func (h *SearchHandler) SetResultCache(c cache.ResultCache) {
+	h.results = c
+}
+
+// SetFreshness re-fetches cached provider flights older than their limit
+// before serving them.
+func (h *SearchHandler) SetFreshness(f cache.Freshness) {
+	h.freshness = f
+}
+
+// SetQuoteTTL is how long a quoted price is served, the cache TTL; a
+// shorter provider freshness limit takes precedence.
+func (h *SearchHandler) SetQuoteTTL(d time.Duration) {
+	h.quoteTTL = d
+}
+
+func (h *SearchHandler) SetBookingLinks(b *deeplink.Builder) {
+	h.bookingLinks = b
+}
+
+func (h *SearchHandler) SetPromotions(e *promotions.Engine) {
+	h.promotions = e
+}
+
+// SetPartialStatus is the status of responses with degraded results,
+// http.StatusOK or http.StatusPartialContent.
+func (h *SearchHandler) SetPartialStatus(status int) {
+	h.partialStatus = status
+}
+
+// SetMarkups sets each tenant's markup policy.
+func (h *SearchHandler) SetMarkups(policies map[string]markup.Policy) {
+	h.markups = policies
+}
+
 func (h *SearchHandler) Search(c echo.Context) error {
 	startTime := time.Now()
-	ctx := c.Request().Context()
 
 	var req models.SearchRequest
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "invalid_request",
-			Message: "Failed to parse request body: " + err.Error(),
-			Code:    http.StatusBadRequest,
-		})
+		return bindError(c, err)
 	}
 
-	if err := req.Validate(); err != nil {
-		return c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error:   "validation_error",
-			Message: err.Error(),
-			Code:    http.StatusBadRequest,
+	return h.search(c, req, startTime)
+}
+
+// SearchQuery is the GET form of Search, taking the request as query
+// parameters so results can be linked to and cached by CDNs.
+func (h *SearchHandler) SearchQuery(c echo.Context) error {
+	startTime := time.Now()
+
+	req, err := parseSearchQuery(c.QueryParams())
+	if err != nil {
+		return invalidRequest(c, "", err)
+	}
+
+	if h.publicMaxAge > 0 {
+		res := c.Response()
+		res.Before(func() {
+			if res.Status == http.StatusOK || res.Status == http.StatusNotModified {
+				res.Header().Set(echo.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(h.publicMaxAge.Seconds())))
+				// Tenants may see different fields, and locales different
+				// prices and labels, for the same URL.
+				res.Header().Add(echo.HeaderVary, HeaderAPIKey)
+				res.Header().Add(echo.HeaderVary, "Accept-Language")
+			}
 		})
 	}
 
+	return h.search(c, req, startTime)
+}
+
+func (h *SearchHandler) search(c echo.Context, req models.SearchRequest, startTime time.Time) error {
+	ctx := c.Request().Context()
+
+	normalizations := req.Normalize()
+	var errs errorsx.ValidationErrors
+	if err := req.Validate(); err != nil && !errors.As(err, &errs) {
+		return validationError(c, err, normalizations)
+	}
+	for i, p := range req.Providers {
+		if _, ok := h.aggregator.Provider(p); !ok {
+			errs = append(errs, errorsx.FieldError{
+				Field:   fmt.Sprintf("providers[%d]", i),
+				Code:    types.FieldUnknownValue,
+				Message: models.ErrUnknownProvider.Error() + ": " + p,
+			})
+		}
+	}
+	if name, ok := ranking.ProfileName(req.SortBy); ok {
+		if _, found := ranking.LookupProfile(name); !found {
+			errs = append(errs, errorsx.FieldError{
+				Field:   "sort_by",
+				Code:    types.FieldUnknownValue,
+				Message: "unknown ranking profile " + name + ", expected one of: " + strings.Join(ranking.ProfileNames(), ", "),
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return validationError(c, errs, normalizations)
+	}
+
+	if h.searches != nil {
+		defer func() {
+			h.searches.Record(req, c.Response().Status >= http.StatusInternalServerError)
+		}()
+	}
+	if h.audit != nil {
+		defer func() {
+			if status := c.Response().Status; status != http.StatusOK && status != http.StatusPartialContent {
+				h.audit.Log(audit.NewStatusRecord(ctx, c.RealIP(), req, status, time.Since(startTime), time.Now()))
+			}
+		}()
+	}
+	if h.inFlight != nil {
+		defer h.inFlight.Enter()()
+	}
+	level := h.brownout.Level()
+	ctx = brownout.WithLevel(ctx, level)
+	ctx = models.WithSearchOptions(ctx, searchOptions(c))
+	c.SetRequest(c.Request().WithContext(ctx))
+	if noCache(c) {
+		req.Refresh = true
+	}
+	if level >= brownout.CacheOnly {
+		req.Refresh = false
+	}
+
+	if req.SearchID != "" && !req.Refresh && h.snapshots != nil {
+		return h.replaySnapshot(c, req, normalizations, startTime)
+	}
+
+	if req.ReturnDate != nil && *req.ReturnDate != "" {
+		if level >= brownout.Tier1Only {
+			narrowed, ok := h.brownout.Tier1(req)
+			if !ok || level >= brownout.CacheOnly {
+				return overloaded(c, level)
+			}
+			req = narrowed
+		}
+		return h.handleRoundTrip(c, req, normalizations, startTime)
+	}
+
+	cached, found := h.cachedEntry(ctx, req)
+	if !found && level >= brownout.Tier1Only {
+		// The full search may still be cached; only misses are narrowed.
+		narrowed, ok := h.brownout.Tier1(req)
+		if !ok {
+			return overloaded(c, level)
+		}
+		req = narrowed
+		cached, found = h.cachedEntry(ctx, req)
+	}
+	if !found && level >= brownout.CacheOnly {
+		return overloaded(c, level)
+	}
+
+	var result *aggregator.Result
+	var fetchedAt map[string]time.Time
+	var staleProviders []string
 	cacheHit := false
-	if cachedFlights, found := h.cache.Get(ctx, req); found {
+	if found {
 		cacheHit = true
-		filtered := filter.Apply(cachedFlights, req.Filters, req.SortBy, req.SortOrder)
-
-		return c.JSON(http.StatusOK, models.SearchResponse{
-			SearchCriteria: buildSearchCriteria(req),
-			Metadata: models.SearchMetadata{
-				TotalResults:       len(filtered),
-				ProvidersQueried:   4,
-				ProvidersSucceeded: 4,
-				ProvidersFailed:    0,
-				SearchTimeMs:       time.Since(startTime).Milliseconds(),
-				CacheHit:           cacheHit,
-			},
-			Flights: filtered,
-		})
+		if stale := h.freshness.Stale(cached, time.Now()); len(stale) > 0 {
+			cached.Flights, staleProviders = h.freshen(ctx, req, cached, stale)
+		}
+		result = h.cachedResult(req, cached, staleProviders)
+		fetchedAt = cached.FetchedAt
+	} else {
+		var err error
+		result, err = h.aggregator.Search(ctx, req)
+		if err != nil {
+			return searchError(c, err)
+		}
+		entry := cacheEntry(result, time.Now())
+		fetchedAt = entry.FetchedAt
+		// No flights only means the route isn't served when every
+		// provider answered.
+		if len(result.Flights) > 0 || result.ProvidersFailed == 0 {
+			_ = h.cache.Set(ctx, req, entry)
+		}
 	}
+	result.Flights = h.quote(result.Flights, fetchedAt)
 
-	if req.ReturnDate != nil && *req.ReturnDate != "" {
-		return h.handleRoundTrip(c, req, startTime)
+	if checkETag(c, searchETag(c, req, result.Flights, h.pricingKey(ctx), h.configVersion)) {
+		return c.NoContent(http.StatusNotModified)
 	}
 
-	result, err := h.aggregator.Search(ctx, req)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "search_error",
-			Message: "Failed to search flights: " + err.Error(),
-			Code:    http.StatusInternalServerError,
+	meta := models.SearchMetadata{
+		ProvidersQueried:   result.ProvidersQueried,
+		ProvidersSucceeded: result.ProvidersSucceeded,
+		ProvidersFailed:    result.ProvidersFailed,
+		FailedProviders:    result.FailedProviders,
+		ProviderErrors:     providerErrors(result.Responses, ""),
+		Degraded:           result.ProvidersFailed > 0 || len(staleProviders) > 0,
+		CacheHit:           cacheHit,
+		NegativeCacheHit:   cacheHit && cached.Negative(),
+		TruncatedProviders: result.Truncated,
+		StaleProviders:     staleProviders,
+		Fallbacks:          result.Fallbacks,
+	}
+	meta.SearchID = h.saveSnapshot(ctx, req, result.Flights, nil, meta)
+	if !cacheHit {
+		h.record(ctx, meta.SearchID, req, result, nil)
+	}
+	return h.respond(c, req, result.Flights, meta, normalizations, startTime)
+}
+
+// Legs of a round trip, as reported in models.ProviderError.
+const (
+	legOutbound = "outbound"
+	legReturn   = "return"
+)
+
+// providerErrors lists the failed providers among responses, those of leg
+// in a round trip. Responses recorded before failures were classified are
+// left out.
+func providerErrors(responses []aggregator.ProviderResponse, leg string) []models.ProviderError {
+	var errs []models.ProviderError
+	for _, r := range responses {
+		if r.Error == "" || r.ErrorClass == "" {
+			continue
+		}
+		errs = append(errs, models.ProviderError{
+			Provider:  r.Provider,
+			Leg:       leg,
+			Class:     r.ErrorClass,
+			Retries:   r.Retries,
+			ElapsedMs: r.ElapsedMs,
 		})
 	}
+	return errs
+}
+
+// cachedEntry skips the cache when the client asked for a refresh.
+func (h *SearchHandler) cachedEntry(ctx context.Context, req models.SearchRequest) (cache.Entry, bool) {
+	if req.Refresh {
+		return cache.Entry{}, false
+	}
+	return h.cache.Get(ctx, req)
+}
+
+// cachedResult answers req from entry, counting the providers req selects
+// as queried and those among them the entry holds an answer from, other
+// than the dropped ones, as succeeded.
+func (h *SearchHandler) cachedResult(req models.SearchRequest, entry cache.Entry, dropped []string) *aggregator.Result {
+	result := &aggregator.Result{Flights: entry.Flights}
+	for _, p := range h.aggregator.Selected(req) {
+		result.ProvidersQueried++
+		if _, ok := entry.FetchedAt[p.Name()]; ok && !slices.Contains(dropped, p.Name()) {
+			result.ProvidersSucceeded++
+		}
+	}
+	return result
+}
+
+// cacheEntry records every provider that answered as fetched at now.
+func cacheEntry(result *aggregator.Result, now time.Time) cache.Entry {
+	entry := cache.Entry{Flights: result.Flights, FetchedAt: make(map[string]time.Time)}
+	for _, r := range result.Responses {
+		if r.Error == "" {
+			entry.FetchedAt[r.Provider] = now
+		}
+	}
+	return entry
+}
+
+// quote stamps a copy of flights with when their provider's prices were
+// fetched and when they expire. Providers missing from fetchedAt, cached
+// before it was kept, count as fetched with the oldest.
+func (h *SearchHandler) quote(flights []models.Flight, fetchedAt map[string]time.Time) []models.Flight {
+	if len(fetchedAt) == 0 {
+		return flights
+	}
+	var oldest time.Time
+	for _, t := range fetchedAt {
+		if oldest.IsZero() || t.Before(oldest) {
+			oldest = t
+		}
+	}
 
-	_ = h.cache.Set(ctx, req, result.Flights)
-	filtered := filter.Apply(result.Flights, req.Filters, req.SortBy, req.SortOrder)
-
-	return c.JSON(http.StatusOK, models.SearchResponse{
-		SearchCriteria: buildSearchCriteria(req),
-		Metadata: models.SearchMetadata{
-			TotalResults:       len(filtered),
-			ProvidersQueried:   result.ProvidersQueried,
-			ProvidersSucceeded: result.ProvidersSucceeded,
-			ProvidersFailed:    result.ProvidersFailed,
-			FailedProviders:    result.FailedProviders,
-			SearchTimeMs:       time.Since(startTime).Milliseconds(),
-			CacheHit:           cacheHit,
-		},
-		Flights: filtered,
+	quoted := slices.Clone(flights)
+	for i := range quoted {
+		f := &quoted[i]
+		at, ok := fetchedAt[f.Provider]
+		if !ok {
+			at = oldest
+		}
+		at = at.UTC()
+		f.QuotedAt = &at
+		ttl := h.quoteTTL
+		if limit, ok := h.freshness[f.Provider]; ok && limit > 0 && (ttl <= 0 || limit < ttl) {
+			ttl = limit
+		}
+		if ttl > 0 {
+			expires := at.Add(ttl)
+			f.ExpiresAt = &expires
+		}
+	}
+	return quoted
+}
+
+// noCache reports whether the request carries Cache-Control: no-cache or
+// max-age=0, asking for prices fetched now rather than cached ones.
+func noCache(c echo.Context) bool {
+	for _, v := range c.Request().Header.Values(echo.HeaderCacheControl) {
+		for _, d := range strings.Split(v, ",") {
+			switch strings.ToLower(strings.TrimSpace(d)) {
+			case "no-cache", "max-age=0":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// freshen re-fetches the stale providers of a cached entry and merges
+// their flights with the rest. Providers that can't be re-fetched, because
+// the call failed or brownout rules it out, are left out of the flights
+// and returned rather than served stale; the cache keeps their old flights
+// so the next search tries again.
+func (h *SearchHandler) freshen(ctx context.Context, req models.SearchRequest, entry cache.Entry, stale []string) ([]models.Flight, []string) {
+	lists := entry.ByProvider()
+	refetched := make(map[string]bool)
+	if level := brownout.LevelFrom(ctx); level < brownout.CacheOnly {
+		refetch := req
+		refetch.Providers = stale
+		ok := true
+		if level >= brownout.Tier1Only {
+			refetch, ok = h.brownout.Tier1(refetch)
+		}
+		if ok {
+			if result, err := h.aggregator.Search(ctx, refetch); err == nil {
+				now := time.Now()
+				for _, r := range result.Responses {
+					if r.Error == "" {
+						// A provider standing in for several others answers
+						// once for each.
+						if !refetched[r.Provider] {
+							lists[r.Provider] = nil
+						}
+						lists[r.Provider] = append(lists[r.Provider], r.Flights...)
+						entry.FetchedAt[r.Provider] = now
+						refetched[r.Provider] = true
+					}
+				}
+			}
+		}
+	}
+
+	var all, served []aggregator.ProviderResponse
+	var dropped []string
+	for _, name := range slices.Sorted(maps.Keys(lists)) {
+		r := aggregator.ProviderResponse{Provider: name, Flights: lists[name]}
+		all = append(all, r)
+		if slices.Contains(stale, name) && !refetched[name] {
+			dropped = append(dropped, name)
+			continue
+		}
+		served = append(served, r)
+	}
+	if len(dropped) > 0 {
+		reqctx.Logf(ctx, "Dropping stale cached flights of %s: older than their freshness limit and not re-fetched", strings.Join(dropped, ", "))
+	}
+
+	limit := h.aggregator.ProviderCap()
+	if len(refetched) > 0 {
+		entry.Flights, _ = h.aggregator.Merge(all, limit, h.config.ResultMerge)
+		_ = h.cache.Set(ctx, req, entry)
+	}
+	flights, _ := h.aggregator.Merge(served, limit, h.config.ResultMerge)
+	return flights, dropped
+}
+
+func (h *SearchHandler) respond(c echo.Context, req models.SearchRequest, flights []models.Flight, meta models.SearchMetadata, normalizations []models.Normalization, startTime time.Time) error {
+	ctx := c.Request().Context()
+	filtered := h.rank(ctx, flights, req)
+
+	meta.TotalResults = len(filtered)
+	meta.SearchTimeMs = time.Since(startTime).Milliseconds()
+	meta.Region = h.region
+	meta.Demo = h.demo
+	meta.ConfigVersion = h.configVersion
+	meta.ProviderCap = h.aggregator.ProviderCap()
+	meta.Brownout = brownout.LevelFrom(ctx).Report()
+	h.recordEvent(ctx, req, meta)
+	status := h.status(meta)
+	if h.audit != nil {
+		h.audit.Log(audit.NewRecord(ctx, c.RealIP(), req, meta, status, time.Now(), flights))
+	}
+	return c.JSON(status, models.SearchResponse{
+		SearchCriteria:  buildSearchCriteria(req),
+		Metadata:        meta,
+		Flights:         filtered,
+		PriceTrend:      h.lookupPriceTrend(ctx, req),
+		Facets:          buildFacets(req, h.markUp(ctx, flights)),
+		GroundTransport: h.lookupGroundTransport(ctx, req),
+		CrossSell:       h.lookupCrossSell(ctx, req),
+		Normalizations:  normalizations,
 	})
 }
 
-func (h *SearchHandler) handleRoundTrip(c echo.Context, req models.SearchRequest, startTime time.Time) error {
+func (h *SearchHandler) recordEvent(ctx context.Context, req models.SearchRequest, meta models.SearchMetadata) {
+	if h.events == nil {
+		return
+	}
+	if err := h.events.Record(ctx, analytics.NewEvent(req, meta, time.Now())); err != nil {
+		reqctx.Logf(ctx, "Failed to record search event: %v", err)
+	}
+}
+
+func (h *SearchHandler) handleRoundTrip(c echo.Context, req models.SearchRequest, normalizations []models.Normalization, startTime time.Time) error {
 	ctx := c.Request().Context()
 
 	outbound, returnResult, err := h.aggregator.SearchRoundTrip(ctx, req)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:   "search_error",
-			Message: "Failed to search flights: " + err.Error(),
-			Code:    http.StatusInternalServerError,
-		})
+		return searchError(c, err)
 	}
 
-	outboundFiltered := filter.Apply(outbound.Flights, req.Filters, req.SortBy, req.SortOrder)
+	meta := models.SearchMetadata{
+		ProvidersQueried:   outbound.ProvidersQueried,
+		ProvidersSucceeded: outbound.ProvidersSucceeded,
+		ProvidersFailed:    outbound.ProvidersFailed,
+		FailedProviders:    outbound.FailedProviders,
+		ProviderErrors:     providerErrors(outbound.Responses, legOutbound),
+		TruncatedProviders: outbound.Truncated,
+		Fallbacks:          outbound.Fallbacks,
+	}
+	returnFlights := []models.Flight{}
+	if returnResult != nil {
+		returnFlights = returnResult.Flights
+		meta.ProvidersQueried += returnResult.ProvidersQueried
+		meta.ProvidersSucceeded += returnResult.ProvidersSucceeded
+		meta.ProvidersFailed += returnResult.ProvidersFailed
+		meta.FailedProviders = append(meta.FailedProviders, returnResult.FailedProviders...)
+		meta.ProviderErrors = append(meta.ProviderErrors, providerErrors(returnResult.Responses, legReturn)...)
+		for name, n := range returnResult.Truncated {
+			if meta.TruncatedProviders == nil {
+				meta.TruncatedProviders = make(map[string]int)
+			}
+			meta.TruncatedProviders[name] += n
+		}
+		meta.Fallbacks = mergeFallbacks(meta.Fallbacks, returnResult.Fallbacks)
+	}
+	meta.FailedProviders = uniqueStrings(meta.FailedProviders)
+	meta.Degraded = meta.ProvidersFailed > 0 || returnResult == nil
 
-	var returnFiltered []models.Flight
-	var returnMeta *aggregator.Result
+	outbound.Flights = h.quote(outbound.Flights, cacheEntry(outbound, time.Now()).FetchedAt)
 	if returnResult != nil {
-		returnFiltered = filter.Apply(returnResult.Flights, req.Filters, req.SortBy, req.SortOrder)
-		returnMeta = returnResult
-	}
-
-	totalQueried := outbound.ProvidersQueried
-	totalSucceeded := outbound.ProvidersSucceeded
-	totalFailed := outbound.ProvidersFailed
-	failedProviders := outbound.FailedProviders
-
-	if returnMeta != nil {
-		totalQueried += returnMeta.ProvidersQueried
-		totalSucceeded += returnMeta.ProvidersSucceeded
-		totalFailed += returnMeta.ProvidersFailed
-		failedProviders = append(failedProviders, returnMeta.FailedProviders...)
-	}
-
-	failedProviders = uniqueStrings(failedProviders)
-
-	return c.JSON(http.StatusOK, models.RoundTripResponse{
-		SearchCriteria: buildSearchCriteria(req),
-		Metadata: models.SearchMetadata{
-			TotalResults:       len(outboundFiltered) + len(returnFiltered),
-			ProvidersQueried:   totalQueried,
-			ProvidersSucceeded: totalSucceeded,
-			ProvidersFailed:    totalFailed,
-			FailedProviders:    failedProviders,
-			SearchTimeMs:       time.Since(startTime).Milliseconds(),
-			CacheHit:           false,
-		},
+		returnFlights = h.quote(returnFlights, cacheEntry(returnResult, time.Now()).FetchedAt)
+	}
+
+	meta.SearchID = h.saveSnapshot(ctx, req, outbound.Flights, returnFlights, meta)
+	h.record(ctx, meta.SearchID, req, outbound, returnResult)
+	return h.respondRoundTrip(c, req, outbound.Flights, returnFlights, meta, normalizations, startTime)
+}
+
+func (h *SearchHandler) respondRoundTrip(c echo.Context, req models.SearchRequest, outbound, returnFlights []models.Flight, meta models.SearchMetadata, normalizations []models.Normalization, startTime time.Time) error {
+	ctx := c.Request().Context()
+	outboundFiltered := h.rank(ctx, outbound, req)
+	returnFiltered := h.rank(ctx, returnFlights, req)
+
+	meta.TotalResults = len(outboundFiltered) + len(returnFiltered)
+	meta.SearchTimeMs = time.Since(startTime).Milliseconds()
+	meta.Region = h.region
+	meta.Demo = h.demo
+	meta.ConfigVersion = h.configVersion
+	meta.ProviderCap = h.aggregator.ProviderCap()
+	meta.Brownout = brownout.LevelFrom(ctx).Report()
+	h.recordEvent(ctx, req, meta)
+	status := h.status(meta)
+	if h.audit != nil {
+		h.audit.Log(audit.NewRecord(ctx, c.RealIP(), req, meta, status, time.Now(), outbound, returnFlights))
+	}
+	return c.JSON(status, models.RoundTripResponse{
+		SearchCriteria:  buildSearchCriteria(req),
+		Metadata:        meta,
 		OutboundFlights: outboundFiltered,
 		ReturnFlights:   returnFiltered,
+		GroundTransport: h.lookupGroundTransport(ctx, req),
+		CrossSell:       h.lookupCrossSell(ctx, req),
+		Normalizations:  normalizations,
 	})
 }
 
+// overloaded rejects a search that would need what the brownout level
+// sheds.
+func overloaded(c echo.Context, level brownout.Level) error {
+	c.Response().Header().Set("Retry-After", "5")
+	return errorResponse(c, errorsx.New(errorsx.Overloaded, "Search is shedding load ("+level.String()+") and has no cached results for this request; retry shortly"), "", "")
+}
+
+// status is the status of a successful search, the partial status for
+// degraded results when one is set.
+func (h *SearchHandler) status(meta models.SearchMetadata) int {
+	if meta.Degraded && h.partialStatus != 0 {
+		return h.partialStatus
+	}
+	return http.StatusOK
+}
+
+// searchError renders a failed search, with what its providers answered
+// when it got that far.
+func searchError(c echo.Context, err error) error {
+	status, body := errorBody(err, types.CodeSearchError, "Failed to search flights")
+	var failed *aggregator.SearchError
+	if errors.As(err, &failed) {
+		r := failed.Result
+		body.Metadata = &models.SearchMetadata{
+			ProvidersQueried:   r.ProvidersQueried,
+			ProvidersSucceeded: r.ProvidersSucceeded,
+			ProvidersFailed:    r.ProvidersFailed,
+			FailedProviders:    r.FailedProviders,
+			Degraded:           true,
+			ProviderErrors:     providerErrors(r.Responses, ""),
+			Fallbacks:          r.Fallbacks,
+		}
+	}
+	return c.JSON(status, body)
+}
+
 func buildSearchCriteria(req models.SearchRequest) models.SearchCriteria {
 	return models.SearchCriteria{
 		Origin:        req.Origin,
@@ -157,9 +675,163 @@ func buildSearchCriteria(req models.SearchRequest) models.SearchCriteria {
 		Filters:       req.Filters,
 		SortBy:        req.SortBy,
 		SortOrder:     req.SortOrder,
+		PassengerMix:  req.PassengerMix,
+		Providers:     req.Providers,
+		NearbyKm:      req.NearbyKm,
+
+		RankingWeights: req.RankingWeights,
+		PromoCode:      req.PromoCode,
+
+		IncludeGroundTransport: req.IncludeGroundTransport,
+		IncludeCrossSell:       req.IncludeCrossSell,
+		IncludeFacets:          req.IncludeFacets,
 	}
 }
 
+func buildFacets(req models.SearchRequest, flights []models.Flight) *models.Facets {
+	if !req.IncludeFacets {
+		return nil
+	}
+	return facets.Build(flights)
+}
+
+// rank serves ranked flights from the tenant's result cache when it has
+// them. The raw flights are shared by all tenants; what is made of them
+// is not.
+func (h *SearchHandler) rank(ctx context.Context, flights []models.Flight, req models.SearchRequest) []models.Flight {
+	if h.results == nil {
+		return h.rankFlights(ctx, flights, req)
+	}
+	ns := cache.TenantNamespace(reqctx.Tenant(ctx))
+	key := h.resultKey(ctx, req, flights)
+	if ranked, ok := h.results.Get(ctx, ns, key); ok {
+		return ranked
+	}
+	ranked := h.rankFlights(ctx, flights, req)
+	if err := h.results.Set(ctx, ns, key, ranked); err != nil {
+		reqctx.Logf(ctx, "Failed to cache ranked results: %v", err)
+	}
+	return ranked
+}
+
+// resultKey covers everything rankFlights depends on besides the tenant.
+func (h *SearchHandler) resultKey(ctx context.Context, req models.SearchRequest, flights []models.Flight) string {
+	criteria, _ := json.Marshal(buildSearchCriteria(req))
+	opts := models.SearchOptionsFrom(ctx)
+	return cache.ResultKey(
+		cache.EntryHash(flights),
+		string(criteria),
+		opts.Locale,
+		strconv.FormatBool(opts.Debug),
+		strconv.FormatBool(brownout.LevelFrom(ctx) >= brownout.NoExplain),
+		h.configVersion,
+		h.pricingKey(ctx),
+	)
+}
+
+func (h *SearchHandler) rankFlights(ctx context.Context, flights []models.Flight, req models.SearchRequest) []models.Flight {
+	if brownout.LevelFrom(ctx) >= brownout.NoExplain {
+		ranked := h.rankWith(ctx, flights, req, h.defaultWeights, nil)
+		for i := range ranked {
+			ranked[i].ScoreDetails = nil
+		}
+		return ranked
+	}
+	return h.rankWith(ctx, flights, req, h.defaultWeights, h.model)
+}
+
+// rankWith filters and sorts with the given default weights and, if not
+// nil, ranking model. Prices are set first, so filters and ranking see
+// what the caller pays.
+func (h *SearchHandler) rankWith(ctx context.Context, flights []models.Flight, req models.SearchRequest, defaults ranking.Weights, model *ranking.ModelRanker) []models.Flight {
+	weights := ranking.WeightsFor(req, defaults)
+	flights = h.price(ctx, flights, req.PromoCode)
+	var ranked []models.Flight
+	if model == nil {
+		ranked = filter.Apply(flights, req.Filters, req.SortBy, req.SortOrder, weights)
+	} else {
+		ranked = filter.ApplyScored(flights, req.Filters, req.SortBy, req.SortOrder, weights, model.ScoreFunc(ctx))
+	}
+	if req.PassengerMix != nil {
+		h.markUnbookable(ranked, *req.PassengerMix)
+	}
+	localize(ranked, models.SearchOptionsFrom(ctx).Locale)
+	if h.bookingLinks != nil {
+		h.bookingLinks.Apply(ranked, req)
+	}
+	return ranked
+}
+
+// price marks up the providers' fares, then applies the promotions.
+func (h *SearchHandler) price(ctx context.Context, flights []models.Flight, promoCode string) []models.Flight {
+	return h.promotions.Apply(h.markUp(ctx, flights), promoCode, time.Now())
+}
+
+// markUp applies the tenant's markup policy, with the breakdown for its
+// admin keys.
+func (h *SearchHandler) markUp(ctx context.Context, flights []models.Flight) []models.Flight {
+	if policy, ok := h.markups[reqctx.Tenant(ctx)]; ok {
+		return policy.Apply(flights, reqctx.Admin(ctx))
+	}
+	return flights
+}
+
+// pricingKey identifies how price prices the caller's flights now, for
+// the caches and ETags of results.
+func (h *SearchHandler) pricingKey(ctx context.Context) string {
+	policy, ok := h.markups[reqctx.Tenant(ctx)]
+	if !ok {
+		return h.promotions.Key(time.Now())
+	}
+	return fmt.Sprintf("%s|%+v|%t", h.promotions.Key(time.Now()), policy, reqctx.Admin(ctx))
+}
+
+// markUnbookable flags flights whose airline can't take the party or that
+// don't have enough seats left. ranked is a fresh copy, never the cached
+// flights.
+func (h *SearchHandler) markUnbookable(ranked []models.Flight, mix models.PassengerMix) {
+	reasons := make(map[string][]string)
+	for i, f := range ranked {
+		r, ok := reasons[f.Provider]
+		if !ok {
+			if p, found := h.aggregator.Provider(f.Provider); found {
+				r = providers.PassengerRulesFor(p).Check(mix)
+			}
+			reasons[f.Provider] = r
+		}
+		if f.AvailableSeats < mix.Seats() {
+			r = append(slices.Clip(r), fmt.Sprintf("only %d seat(s) left for %d seated passenger(s)", f.AvailableSeats, mix.Seats()))
+		}
+		ranked[i].UnbookableReasons = r
+	}
+}
+
+func (h *SearchHandler) lookupGroundTransport(ctx context.Context, req models.SearchRequest) *models.GroundTransport {
+	if !req.IncludeGroundTransport || h.groundTransport == nil || brownout.LevelFrom(ctx) >= brownout.NoEnrichment {
+		return nil
+	}
+
+	gt, err := groundtransport.Lookup(ctx, h.groundTransport, req.Origin, req.Destination)
+	if err != nil {
+		reqctx.Logf(ctx, "Ground transport lookup failed: %v", err)
+		return nil
+	}
+	return gt
+}
+
+// mergeFallbacks adds more to fallbacks, either of which may be nil.
+func mergeFallbacks(fallbacks, more map[string]string) map[string]string {
+	if len(more) == 0 {
+		return fallbacks
+	}
+	merged := maps.Clone(fallbacks)
+	if merged == nil {
+		merged = make(map[string]string, len(more))
+	}
+	maps.Copy(merged, more)
+	return merged
+}
+
 func uniqueStrings(s []string) []string {
 	seen := make(map[string]bool)
 	result := make([]string, 0, len(s))
@@ -177,3 +849,29 @@ func HealthHandler(c echo.Context) error {
 		"status": "ok",
 	})
 }
+
+func (h *SearchHandler) lookupCrossSell(ctx context.Context, req models.SearchRequest) *models.CrossSell {
+	if !req.IncludeCrossSell || h.hotels == nil || brownout.LevelFrom(ctx) >= brownout.NoEnrichment {
+		return nil
+	}
+
+	cs, err := crosssell.Lookup(ctx, h.hotels, req)
+	if err != nil {
+		reqctx.Logf(ctx, "Hotel cross-sell lookup failed: %v", err)
+		return nil
+	}
+	return cs
+}
+
+func (h *SearchHandler) lookupPriceTrend(ctx context.Context, req models.SearchRequest) *models.PriceTrend {
+	if h.priceHistory == nil || brownout.LevelFrom(ctx) >= brownout.NoEnrichment {
+		return nil
+	}
+
+	trend, err := pricehistory.Trend(ctx, h.priceHistory, pricehistory.NewRouteKey(req), 7)
+	if err != nil {
+		reqctx.Logf(ctx, "Price trend lookup failed: %v", err)
+		return nil
+	}
+	return trend
+}
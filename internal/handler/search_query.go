@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// parseSearchQuery builds a search request from GET query parameters. Names
+// match the JSON body, with filters flattened to the top level and `date`
+// accepted for departure_date. Lists are comma-separated or repeated.
+func parseSearchQuery(q url.Values) (models.SearchRequest, error) {
+	p := queryParser{q: q}
+
+	req := models.SearchRequest{
+		Origin:        q.Get("origin"),
+		Destination:   q.Get("destination"),
+		DepartureDate: q.Get("date"),
+		ReturnDate:    p.str("return_date"),
+		CabinClass:    q.Get("cabin_class"),
+		SortBy:        q.Get("sort_by"),
+		SortOrder:     q.Get("sort_order"),
+		Providers:     p.list("providers"),
+		SearchID:      q.Get("search_id"),
+		Refresh:       p.bool("refresh"),
+		PromoCode:     q.Get("promo_code"),
+
+		IncludeGroundTransport: p.bool("include_ground_transport"),
+		IncludeCrossSell:       p.bool("include_cross_sell"),
+		IncludeFacets:          p.bool("include_facets"),
+	}
+	if req.DepartureDate == "" {
+		req.DepartureDate = q.Get("departure_date")
+	}
+	if n := p.int("passengers"); n != nil {
+		req.Passengers = *n
+	}
+	if n := p.int("nearby_km"); n != nil {
+		req.NearbyKm = *n
+	}
+	req.RankingWeights = p.weights("ranking_weights")
+
+	// passenger_mix is flattened like filters: adults, child_ages and
+	// lap_infant_ages.
+	p.touched = false
+	mix := models.PassengerMix{
+		ChildAges:     p.ints("child_ages"),
+		LapInfantAges: p.ints("lap_infant_ages"),
+	}
+	if n := p.int("adults"); n != nil {
+		mix.Adults = *n
+	}
+	if p.touched {
+		req.PassengerMix = &mix
+	}
+
+	// Only allocate filters when at least one filter parameter was given.
+	p.touched = false
+	f := models.SearchFilters{
+		PriceMin:         p.float("price_min"),
+		PriceMax:         p.float("price_max"),
+		MaxStops:         p.int("max_stops"),
+		Stops:            p.str("stops"),
+		Airlines:         p.list("airlines"),
+		DepartureTimeMin: p.str("departure_time_min"),
+		DepartureTimeMax: p.str("departure_time_max"),
+		ArrivalTimeMin:   p.str("arrival_time_min"),
+		ArrivalTimeMax:   p.str("arrival_time_max"),
+		MaxDuration:      p.int("max_duration"),
+
+		Amenities:           p.list("amenities"),
+		MinCheckedBaggageKg: p.float("min_checked_baggage_kg"),
+		MinCabinBaggageKg:   p.float("min_cabin_baggage_kg"),
+		IncludeBagKg:        p.float("include_bag_kg"),
+
+		MaxLayoverMinutes:      p.int("max_layover_minutes"),
+		MinLayoverMinutes:      p.int("min_layover_minutes"),
+		ExcludeLayoverAirports: p.list("exclude_layover_airports"),
+
+		ExcludeInvalidConnections: p.bool("exclude_invalid_connections"),
+
+		ExcludeAirlines:  p.list("exclude_airlines"),
+		ExcludeProviders: p.list("exclude_providers"),
+
+		AircraftTypes:        p.list("aircraft_types"),
+		ExcludeAircraftTypes: p.list("exclude_aircraft_types"),
+
+		ExcludeOvernight: p.bool("exclude_overnight"),
+		OvernightStart:   p.str("overnight_start"),
+		OvernightEnd:     p.str("overnight_end"),
+
+		MinReliability: p.float("min_reliability"),
+	}
+	if p.err != nil {
+		return models.SearchRequest{}, p.err
+	}
+	if p.touched {
+		req.Filters = &f
+	}
+	return req, nil
+}
+
+// queryParser reads typed query parameters, keeping the first error so
+// callers can check once at the end.
+type queryParser struct {
+	q       url.Values
+	err     error
+	touched bool
+}
+
+func (p *queryParser) raw(name string) (string, bool) {
+	v := strings.TrimSpace(p.q.Get(name))
+	if v == "" {
+		return "", false
+	}
+	p.touched = true
+	return v, true
+}
+
+func (p *queryParser) fail(name, want string) {
+	if p.err == nil {
+		p.err = fmt.Errorf("invalid value for %s: must be %s", name, want)
+	}
+}
+
+func (p *queryParser) str(name string) *string {
+	v, ok := p.raw(name)
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+func (p *queryParser) list(name string) []string {
+	var result []string
+	for _, v := range p.q[name] {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+	if len(result) > 0 {
+		p.touched = true
+	}
+	return result
+}
+
+func (p *queryParser) int(name string) *int {
+	v, ok := p.raw(name)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		p.fail(name, "an integer")
+		return nil
+	}
+	return &n
+}
+
+func (p *queryParser) ints(name string) []int {
+	var result []int
+	for _, item := range p.list(name) {
+		n, err := strconv.Atoi(item)
+		if err != nil {
+			p.fail(name, "comma-separated integers")
+			return nil
+		}
+		result = append(result, n)
+	}
+	return result
+}
+
+func (p *queryParser) float(name string) *float64 {
+	v, ok := p.raw(name)
+	if !ok {
+		return nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		p.fail(name, "a number")
+		return nil
+	}
+	return &f
+}
+
+func (p *queryParser) bool(name string) bool {
+	v, ok := p.raw(name)
+	if !ok {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		p.fail(name, "true or false")
+		return false
+	}
+	return b
+}
+
+// weights parses "price,duration,stops", the same format as RANKING_WEIGHTS.
+func (p *queryParser) weights(name string) *models.RankingWeights {
+	v, ok := p.raw(name)
+	if !ok {
+		return nil
+	}
+	parts := strings.Split(v, ",")
+	if len(parts) != 3 {
+		p.fail(name, "three comma-separated numbers (price,duration,stops)")
+		return nil
+	}
+	var nums [3]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			p.fail(name, "three comma-separated numbers (price,duration,stops)")
+			return nil
+		}
+		nums[i] = f
+	}
+	return &models.RankingWeights{Price: nums[0], Duration: nums[1], Stops: nums[2]}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/fields"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+)
+
+// ResponseFields resolves how responses to this request are rewritten: the
+// tenant's field policy and the client's optional ?fields= mask. It must
+// run after RequestContext.
+func ResponseFields(policies map[string]fields.Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			if policy, ok := policies[reqctx.Tenant(ctx)]; ok {
+				ctx = fields.WithPolicy(ctx, policy)
+			}
+
+			if spec := c.QueryParam("fields"); spec != "" {
+				mask, err := fields.Parse(spec)
+				if err != nil {
+					return invalidRequest(c, "Invalid fields parameter", err)
+				}
+				ctx = fields.WithMask(ctx, mask)
+			}
+
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// JSONSerializer encodes every JSON response through shapeResponse.
+type JSONSerializer struct {
+	echo.DefaultJSONSerializer
+}
+
+func (s JSONSerializer) Serialize(c echo.Context, i any, indent string) error {
+	shaped, err := shapeResponse(c, i, c.Response().Status)
+	if err != nil {
+		return err
+	}
+	return s.DefaultJSONSerializer.Serialize(c, shaped, indent)
+}
+
+// shapeResponse applies the tenant policy to every body, then the client's
+// mask (which therefore uses renamed field names) to successful ones.
+// Handlers that write JSON themselves, like event streams, must call it.
+func shapeResponse(c echo.Context, v any, status int) (any, error) {
+	ctx := c.Request().Context()
+	policy, hasPolicy := fields.PolicyFromContext(ctx)
+	mask, hasMask := fields.FromContext(ctx)
+	hasMask = hasMask && status < http.StatusBadRequest
+	if !hasPolicy && !hasMask {
+		return v, nil
+	}
+
+	doc, err := fields.Decode(v)
+	if err != nil {
+		return nil, err
+	}
+	if hasPolicy {
+		doc = policy.Apply(doc)
+	}
+	if hasMask {
+		doc = mask.Prune(doc)
+	}
+	return doc, nil
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/cache"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/internal/snapshot"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// SetSnapshots keeps every search's results so clients can replay them by
+// search_id. Without a store no search_id is issued.
+func (h *SearchHandler) SetSnapshots(s snapshot.Store) {
+	h.snapshots = s
+}
+
+func (h *SearchHandler) saveSnapshot(ctx context.Context, req models.SearchRequest, flights, returnFlights []models.Flight, meta models.SearchMetadata) string {
+	if h.snapshots == nil {
+		return ""
+	}
+	snap := snapshot.New(cache.KeyFor(req), flights, returnFlights, meta)
+	if err := h.snapshots.Save(ctx, snap); err != nil {
+		reqctx.Logf(ctx, "Failed to save search snapshot: %v", err)
+		return ""
+	}
+	return snap.ID
+}
+
+// replaySnapshot answers from the results captured under req.SearchID,
+// applying the request's current filters and sort.
+func (h *SearchHandler) replaySnapshot(c echo.Context, req models.SearchRequest, normalizations []models.Normalization, startTime time.Time) error {
+	ctx := c.Request().Context()
+
+	snap, err := h.snapshots.Get(ctx, req.SearchID)
+	if errors.Is(err, snapshot.ErrNotFound) {
+		err = &errorsx.Error{
+			Kind:    errorsx.Expired,
+			Code:    types.CodeSearchExpired,
+			Message: "Search " + req.SearchID + " has expired, search again with refresh set",
+		}
+	}
+	if err != nil {
+		return searchError(c, err)
+	}
+	if snap.Key != cache.KeyFor(req) {
+		return validationError(c, errorsx.ValidationErrors{{
+			Field:   "search_id",
+			Code:    types.FieldMismatch,
+			Message: "search_id belongs to a search for a different route, dates, cabin or passenger count",
+		}}, normalizations)
+	}
+
+	meta := snap.Metadata
+	meta.CacheHit = true
+	meta.SearchID = snap.ID
+	meta.ResultsAsOf = &snap.CreatedAt
+
+	if snap.ReturnFlights != nil {
+		return h.respondRoundTrip(c, req, snap.Flights, snap.ReturnFlights, meta, normalizations, startTime)
+	}
+	if checkETag(c, searchETag(c, req, snap.Flights, h.pricingKey(c.Request().Context()), h.configVersion)) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return h.respond(c, req, snap.Flights, meta, normalizations, startTime)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/cache"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/snapshot"
+)
+
+func TestParallelReplaysOfOneSearch(t *testing.T) {
+	date := time.Now().AddDate(0, 0, 7).Format(time.DateOnly)
+	req := models.SearchRequest{Origin: "CGK", Destination: "DPS", DepartureDate: date, Passengers: 1, CabinClass: "economy"}
+	req.Normalize()
+
+	dep, _ := time.Parse(time.DateOnly, date)
+	flights := make([]models.Flight, 50)
+	for i := range flights {
+		flights[i] = models.Flight{
+			ID:         fmt.Sprintf("GA-%03d", i),
+			Provider:   "garuda",
+			CabinClass: "economy",
+			Price:      models.Price{Amount: float64((i * 7919) % 50 * 10000), Currency: "IDR"},
+			Duration:   models.Duration{TotalMinutes: 100 + (i*31)%50},
+			Departure:  models.Location{Airport: "CGK", Time: dep.Add(time.Duration(i) * time.Minute)},
+			Arrival:    models.Location{Airport: "DPS", Time: dep.Add(3 * time.Hour)},
+		}
+	}
+
+	ids := make([]string, len(flights))
+	for i, f := range flights {
+		ids[i] = f.ID
+	}
+
+	store := snapshot.NewMemoryStore(time.Minute)
+	snap := snapshot.New(cache.KeyFor(req), flights, nil, models.SearchMetadata{})
+	if err := store.Save(context.Background(), snap); err != nil {
+		t.Fatal(err)
+	}
+	h := NewSearchHandler(aggregator.NewAggregator(nil, aggregator.Config{}), cache.NewNoOpCache())
+	h.SetSnapshots(store)
+
+	e := echo.New()
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order := "asc"
+			if i%2 == 1 {
+				order = "desc"
+			}
+			body := fmt.Sprintf(`{"origin":"CGK","destination":"DPS","departure_date":%q,"passengers":1,"cabin_class":"economy","search_id":%q,"sort_by":"price","sort_order":%q}`, date, snap.ID, order)
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/flights/search", strings.NewReader(body))
+			r.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			if err := h.Search(e.NewContext(r, rec)); err != nil {
+				t.Error(err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Errorf("replay answered %d: %s", rec.Code, rec.Body)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := store.Get(context.Background(), snap.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range got.Flights {
+		if f.ID != ids[i] {
+			t.Fatalf("stored flight %d is %s after replays, want %s", i, f.ID, ids[i])
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/subscriptions"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+const streamHeartbeat = 30 * time.Second
+
+// streamWriteTimeout is how long a stream waits for the client to take
+// each write before dropping it.
+const streamWriteTimeout = 10 * time.Second
+
+type SubscriptionHandler struct {
+	store subscriptions.Store
+	hub   *subscriptions.Hub
+}
+
+func NewSubscriptionHandler(store subscriptions.Store, hub *subscriptions.Hub) *SubscriptionHandler {
+	return &SubscriptionHandler{store: store, hub: hub}
+}
+
+func (h *SubscriptionHandler) Create(c echo.Context) error {
+	var req models.CreateSubscriptionRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(c, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return validationError(c, err, nil)
+	}
+
+	sub := subscriptions.NewSubscription(req)
+	if err := h.store.Save(c.Request().Context(), sub); err != nil {
+		return errorResponse(c, err, types.CodeSubscriptionError, "Failed to save subscription")
+	}
+
+	return c.JSON(http.StatusCreated, sub)
+}
+
+func (h *SubscriptionHandler) Get(c echo.Context) error {
+	sub, err := h.store.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.storeError(c, err)
+	}
+	return c.JSON(http.StatusOK, sub)
+}
+
+func (h *SubscriptionHandler) Delete(c echo.Context) error {
+	if err := h.store.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return h.storeError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Stream sends the subscription's fare events as server-sent events until
+// the client disconnects.
+func (h *SubscriptionHandler) Stream(c echo.Context) error {
+	ctx := c.Request().Context()
+	sub, err := h.store.Get(ctx, c.Param("id"))
+	if err != nil {
+		return h.storeError(c, err)
+	}
+
+	events, stop := h.hub.Stream(sub.ID)
+	defer stop()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	// The server's read and write timeouts are for requests, not streams:
+	// reads stay open and each write gets its own deadline instead.
+	rc := http.NewResponseController(res)
+	_ = rc.SetReadDeadline(time.Time{})
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-heartbeat.C:
+			_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case e := <-events:
+			shaped, err := shapeResponse(c, e, http.StatusOK)
+			if err != nil {
+				continue
+			}
+			data, err := json.Marshal(shaped)
+			if err != nil {
+				continue
+			}
+			_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if _, err := fmt.Fprintf(res, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+func (h *SubscriptionHandler) storeError(c echo.Context, err error) error {
+	return errorResponse(c, err, types.CodeSubscriptionError, "Failed to load subscription")
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/currency"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// Verify re-prices one flight with its own provider before checkout,
+// bypassing the cache, and compares the price with the one quoted.
+func (h *SearchHandler) Verify(c echo.Context) error {
+	ctx := c.Request().Context()
+	flightID := c.Param("id")
+
+	var req models.FareVerifyRequest
+	if err := c.Bind(&req); err != nil {
+		return bindError(c, err)
+	}
+	if err := req.Validate(); err != nil {
+		return validationError(c, err, nil)
+	}
+	if _, ok := h.aggregator.Provider(req.Provider); !ok {
+		return validationError(c, errorsx.ValidationErrors{{
+			Field:   "provider",
+			Code:    types.FieldUnknownValue,
+			Message: "unknown provider " + req.Provider,
+		}}, nil)
+	}
+
+	flight, err := h.aggregator.VerifyFlight(ctx, req.SearchRequest(), req.Provider, flightID)
+	if err != nil {
+		return errorResponse(c, err, types.CodeSearchError, "Failed to verify fare")
+	}
+	now := time.Now()
+	quoted := h.quote([]models.Flight{flight}, map[string]time.Time{req.Provider: now})
+	quoted = h.price(ctx, quoted, req.PromoCode)
+	localize(quoted, searchOptions(c).Locale)
+	if h.bookingLinks != nil {
+		h.bookingLinks.Apply(quoted, req.SearchRequest())
+	}
+	flight = quoted[0]
+
+	v := models.FareVerification{
+		Status:     models.FareConfirmed,
+		FlightID:   flight.ID,
+		Provider:   flight.Provider,
+		Passengers: req.Passengers,
+		Flight:     flight,
+		VerifiedAt: now.UTC(),
+	}
+	if change := fareChange(*req.Price, flight.Price); change != nil {
+		v.Status, v.PriceChange = models.FarePriceChanged, change
+	}
+	if flight.AvailableSeats < req.Passengers {
+		v.Status = models.FareSoldOut
+	}
+	return c.JSON(http.StatusOK, v)
+}
+
+// fareChange compares the quoted price with the current one, nil when
+// they are the same.
+func fareChange(quoted, current models.Price) *models.FareChange {
+	if quoted.Currency == "" {
+		quoted.Currency = current.Currency
+	}
+	if quoted.Currency == current.Currency && quoted.Amount == current.Amount {
+		return nil
+	}
+	if quoted.Formatted == "" {
+		quoted.Formatted = currency.Format(quoted.Amount, quoted.Currency)
+	}
+
+	c := &models.FareChange{From: quoted, To: current}
+	// A change of currency has no meaningful amount.
+	if quoted.Currency == current.Currency {
+		c.Change = current.Amount - quoted.Amount
+		c.ChangePercent = math.Round(c.Change/quoted.Amount*1000) / 10
+	}
+	return c
+}
@@ -0,0 +1,217 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// StateStore holds per-provider circuit state. Update must apply fn
+// atomically with respect to other updates of the same provider; fn may be
+// called more than once.
+type StateStore interface {
+	Update(ctx context.Context, provider string, fn func(*providerState)) error
+	Load(ctx context.Context, provider string) (providerState, error)
+	All(ctx context.Context) (map[string]providerState, error)
+}
+
+func newProviderState() providerState {
+	return providerState{state: StateClosed}
+}
+
+type MemoryStateStore struct {
+	mu        sync.Mutex
+	providers map[string]*providerState
+}
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{providers: make(map[string]*providerState)}
+}
+
+func (s *MemoryStateStore) Update(ctx context.Context, provider string, fn func(*providerState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.get(provider))
+	return nil
+}
+
+func (s *MemoryStateStore) Load(ctx context.Context, provider string) (providerState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(provider).clone(), nil
+}
+
+func (s *MemoryStateStore) All(ctx context.Context) (map[string]providerState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string]providerState, len(s.providers))
+	for name, ps := range s.providers {
+		result[name] = ps.clone()
+	}
+	return result, nil
+}
+
+func (s *MemoryStateStore) get(provider string) *providerState {
+	ps, ok := s.providers[provider]
+	if !ok {
+		state := newProviderState()
+		ps = &state
+		s.providers[provider] = ps
+	}
+	return ps
+}
+
+func (ps providerState) clone() providerState {
+	ps.outcomes = append([]outcome(nil), ps.outcomes...)
+	return ps
+}
+
+const (
+	redisStateKeyPrefix = "health:"
+	redisProvidersKey   = "health:providers"
+	maxUpdateAttempts   = 10
+)
+
+// RedisStateStore shares circuit state between replicas. Each provider's
+// state is a JSON document updated with optimistic locking, so exactly one
+// replica observes each state transition and sends its notification.
+type RedisStateStore struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+// NewRedisStateStore expires a provider's state after ttl without calls;
+// it should exceed the tracker's window and open duration.
+func NewRedisStateStore(client redis.UniversalClient, ttl time.Duration) *RedisStateStore {
+	return &RedisStateStore{client: client, ttl: ttl}
+}
+
+func (s *RedisStateStore) Update(ctx context.Context, provider string, fn func(*providerState)) error {
+	key := redisStateKeyPrefix + provider
+
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+			ps, err := s.load(ctx, tx, key)
+			if err != nil {
+				return err
+			}
+			fn(&ps)
+
+			data, err := json.Marshal(toWire(ps))
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, data, s.ttl)
+				return nil
+			})
+			return err
+		}, key)
+		if err == nil {
+			// Outside the transaction: on a Redis Cluster the set may
+			// live on another node than the state.
+			return s.client.SAdd(ctx, redisProvidersKey, provider).Err()
+		}
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("health state for %s: too much contention", provider)
+}
+
+func (s *RedisStateStore) Load(ctx context.Context, provider string) (providerState, error) {
+	return s.load(ctx, s.client, redisStateKeyPrefix+provider)
+}
+
+func (s *RedisStateStore) All(ctx context.Context) (map[string]providerState, error) {
+	names, err := s.client.SMembers(ctx, redisProvidersKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]providerState, len(names))
+	for _, name := range names {
+		ps, err := s.Load(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = ps
+	}
+	return result, nil
+}
+
+func (s *RedisStateStore) load(ctx context.Context, c redis.Cmdable, key string) (providerState, error) {
+	data, err := c.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return newProviderState(), nil
+	}
+	if err != nil {
+		return newProviderState(), err
+	}
+
+	var w wireState
+	if err := json.Unmarshal(data, &w); err != nil {
+		return newProviderState(), err
+	}
+	return w.state(), nil
+}
+
+type wireOutcome struct {
+	At        time.Time `json:"at"`
+	Failed    bool      `json:"failed"`
+	LatencyMs int64     `json:"latency_ms"`
+}
+
+type wireState struct {
+	Outcomes      []wireOutcome `json:"outcomes"`
+	State         State         `json:"state"`
+	OpenedAt      time.Time     `json:"opened_at"`
+	Probing       bool          `json:"probing"`
+	ProbeStarted  time.Time     `json:"probe_started"`
+	Degraded      bool          `json:"degraded"`
+	LastError     string        `json:"last_error,omitempty"`
+	LastFailureAt *time.Time    `json:"last_failure_at,omitempty"`
+}
+
+func toWire(ps providerState) wireState {
+	w := wireState{
+		Outcomes:      make([]wireOutcome, len(ps.outcomes)),
+		State:         ps.state,
+		OpenedAt:      ps.openedAt,
+		Probing:       ps.probing,
+		ProbeStarted:  ps.probeStarted,
+		Degraded:      ps.degraded,
+		LastError:     ps.lastError,
+		LastFailureAt: ps.lastFailureAt,
+	}
+	for i, o := range ps.outcomes {
+		w.Outcomes[i] = wireOutcome{At: o.at, Failed: o.failed, LatencyMs: o.latency.Milliseconds()}
+	}
+	return w
+}
+
+func (w wireState) state() providerState {
+	ps := providerState{
+		outcomes:      make([]outcome, len(w.Outcomes)),
+		state:         w.State,
+		openedAt:      w.OpenedAt,
+		probing:       w.Probing,
+		probeStarted:  w.ProbeStarted,
+		degraded:      w.Degraded,
+		lastError:     w.LastError,
+		lastFailureAt: w.LastFailureAt,
+	}
+	if ps.state == "" {
+		ps.state = StateClosed
+	}
+	for i, o := range w.Outcomes {
+		ps.outcomes[i] = outcome{at: o.At, failed: o.Failed, latency: time.Duration(o.LatencyMs) * time.Millisecond}
+	}
+	return ps
+}
@@ -0,0 +1,282 @@
+package health
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+var ErrCircuitOpen = errorsx.New(errorsx.Unavailable, "circuit breaker open")
+
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half_open"
+)
+
+type EventKind string
+
+const (
+	EventDegraded      EventKind = "failure_rate_exceeded"
+	EventCircuitOpened EventKind = "circuit_opened"
+	EventRecovered     EventKind = "circuit_closed"
+)
+
+type Event struct {
+	Provider string
+	Kind     EventKind
+	Stats    Stats
+	At       time.Time
+}
+
+type Config struct {
+	Window            time.Duration
+	MinRequests       int
+	DegradedThreshold float64
+	OpenThreshold     float64
+	OpenDuration      time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Window:            5 * time.Minute,
+		MinRequests:       10,
+		DegradedThreshold: 0.3,
+		OpenThreshold:     0.5,
+		OpenDuration:      30 * time.Second,
+	}
+}
+
+type Stats struct {
+	Provider      string     `json:"provider"`
+	State         State      `json:"state"`
+	Requests      int        `json:"requests"`
+	Failures      int        `json:"failures"`
+	FailureRate   float64    `json:"failure_rate"`
+	AvgLatencyMs  float64    `json:"avg_latency_ms"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+}
+
+type outcome struct {
+	at      time.Time
+	failed  bool
+	latency time.Duration
+}
+
+type providerState struct {
+	outcomes []outcome
+	state    State
+	openedAt time.Time
+	probing  bool
+	// probeStarted is when the probe was let through. A probe that never
+	// reports, because the call was given up before reaching the
+	// provider, is replaced after OpenDuration.
+	probeStarted  time.Time
+	degraded      bool
+	lastError     string
+	lastFailureAt *time.Time
+}
+
+type Tracker struct {
+	cfg       Config
+	store     StateStore
+	mu        sync.Mutex
+	listeners []func(Event)
+}
+
+func NewTracker(cfg Config) *Tracker {
+	return NewTrackerWithStore(cfg, NewMemoryStateStore())
+}
+
+// NewTrackerWithStore keeps circuit state in the given store, e.g. Redis so
+// that all replicas share one circuit per provider.
+func NewTrackerWithStore(cfg Config, store StateStore) *Tracker {
+	return &Tracker{
+		cfg:   cfg,
+		store: store,
+	}
+}
+
+func (t *Tracker) Subscribe(fn func(Event)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.listeners = append(t.listeners, fn)
+}
+
+// Allow reports whether a call to the provider may proceed. Once the open
+// period has elapsed a single probe is let through in the half-open state,
+// and another if it hasn't been recorded within the open period.
+// If the state store is unavailable calls are allowed.
+func (t *Tracker) Allow(provider string) bool {
+	allowed := true
+	err := t.store.Update(context.Background(), provider, func(ps *providerState) {
+		switch ps.state {
+		case StateOpen:
+			if time.Since(ps.openedAt) < t.cfg.OpenDuration {
+				allowed = false
+				return
+			}
+			ps.state = StateHalfOpen
+			ps.probing = true
+			ps.probeStarted = time.Now()
+			allowed = true
+		case StateHalfOpen:
+			if ps.probing && time.Since(ps.probeStarted) < t.cfg.OpenDuration {
+				allowed = false
+				return
+			}
+			ps.probing = true
+			ps.probeStarted = time.Now()
+			allowed = true
+		default:
+			allowed = true
+		}
+	})
+	if err != nil {
+		log.Printf("Health state unavailable for %s: %v", provider, err)
+		return true
+	}
+	return allowed
+}
+
+func (t *Tracker) Record(provider string, err error, latency time.Duration) {
+	now := time.Now()
+
+	var events []Event
+	storeErr := t.store.Update(context.Background(), provider, func(ps *providerState) {
+		// The store may run this more than once when an update races
+		// with another replica, so start from scratch each time.
+		events = nil
+
+		ps.outcomes = append(ps.outcomes, outcome{at: now, failed: err != nil, latency: latency})
+		t.prune(ps, now)
+
+		if err != nil {
+			ps.lastError = err.Error()
+			ps.lastFailureAt = &now
+		}
+
+		stats := t.stats(provider, ps)
+
+		switch ps.state {
+		case StateHalfOpen:
+			ps.probing = false
+			if err != nil {
+				ps.state = StateOpen
+				ps.openedAt = now
+			} else {
+				ps.state = StateClosed
+				ps.outcomes = nil
+				ps.degraded = false
+				stats = t.stats(provider, ps)
+				events = append(events, Event{Provider: provider, Kind: EventRecovered, Stats: stats, At: now})
+			}
+		case StateClosed:
+			if stats.Requests >= t.cfg.MinRequests {
+				if stats.FailureRate >= t.cfg.OpenThreshold {
+					ps.state = StateOpen
+					ps.openedAt = now
+					stats.State = StateOpen
+					events = append(events, Event{Provider: provider, Kind: EventCircuitOpened, Stats: stats, At: now})
+				} else if stats.FailureRate >= t.cfg.DegradedThreshold && !ps.degraded {
+					ps.degraded = true
+					events = append(events, Event{Provider: provider, Kind: EventDegraded, Stats: stats, At: now})
+				} else if stats.FailureRate < t.cfg.DegradedThreshold {
+					ps.degraded = false
+				}
+			}
+		}
+	})
+	if storeErr != nil {
+		log.Printf("Failed to record health for %s: %v", provider, storeErr)
+		return
+	}
+
+	t.mu.Lock()
+	listeners := t.listeners
+	t.mu.Unlock()
+
+	for _, e := range events {
+		for _, fn := range listeners {
+			fn(e)
+		}
+	}
+}
+
+// Degraded reports whether the provider's recent failure rate crossed the
+// degraded threshold or its circuit is not closed.
+func (t *Tracker) Degraded(provider string) bool {
+	ps, err := t.store.Load(context.Background(), provider)
+	if err != nil {
+		return false
+	}
+	return ps.state != StateClosed || ps.degraded
+}
+
+func (t *Tracker) Stats(provider string) Stats {
+	ps, err := t.store.Load(context.Background(), provider)
+	if err != nil {
+		log.Printf("Health state unavailable for %s: %v", provider, err)
+	}
+	t.prune(&ps, time.Now())
+	return t.stats(provider, &ps)
+}
+
+func (t *Tracker) AllStats() []Stats {
+	all, err := t.store.All(context.Background())
+	if err != nil {
+		log.Printf("Health state unavailable: %v", err)
+	}
+
+	now := time.Now()
+	result := make([]Stats, 0, len(all))
+	for name, ps := range all {
+		t.prune(&ps, now)
+		result = append(result, t.stats(name, &ps))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Provider < result[j].Provider
+	})
+	return result
+}
+
+func (t *Tracker) prune(ps *providerState, now time.Time) {
+	cutoff := now.Add(-t.cfg.Window)
+	i := 0
+	for i < len(ps.outcomes) && ps.outcomes[i].at.Before(cutoff) {
+		i++
+	}
+	ps.outcomes = ps.outcomes[i:]
+}
+
+func (t *Tracker) stats(provider string, ps *providerState) Stats {
+	s := Stats{
+		Provider:      provider,
+		State:         ps.state,
+		Requests:      len(ps.outcomes),
+		LastError:     ps.lastError,
+		LastFailureAt: ps.lastFailureAt,
+	}
+
+	var totalLatency time.Duration
+	for _, o := range ps.outcomes {
+		if o.failed {
+			s.Failures++
+		}
+		totalLatency += o.latency
+	}
+
+	if s.Requests > 0 {
+		s.FailureRate = float64(s.Failures) / float64(s.Requests)
+		s.AvgLatencyMs = float64(totalLatency.Milliseconds()) / float64(s.Requests)
+	}
+	return s
+}
@@ -0,0 +1,50 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func openCircuit(t *testing.T, tr *Tracker, provider string) {
+	t.Helper()
+	for range tr.cfg.MinRequests {
+		tr.Record(provider, errors.New("down"), time.Millisecond)
+	}
+	if got := tr.Stats(provider).State; got != StateOpen {
+		t.Fatalf("state = %s, want open", got)
+	}
+}
+
+func TestHalfOpenProbeExpires(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OpenDuration = 20 * time.Millisecond
+	tr := NewTracker(cfg)
+	openCircuit(t, tr, "garuda")
+
+	time.Sleep(cfg.OpenDuration)
+	if !tr.Allow("garuda") {
+		t.Fatal("no probe after the open period")
+	}
+	if tr.Allow("garuda") {
+		t.Fatal("second probe while the first is out")
+	}
+
+	// The probe is given up without reporting.
+	time.Sleep(cfg.OpenDuration)
+	if !tr.Allow("garuda") {
+		t.Fatal("circuit stuck half-open after an unreported probe")
+	}
+	tr.Record("garuda", nil, time.Millisecond)
+	if got := tr.Stats("garuda").State; got != StateClosed {
+		t.Errorf("state = %s after a successful probe, want closed", got)
+	}
+}
+
+func TestHalfOpenProbeSurvivesRedisWire(t *testing.T) {
+	ps := providerState{state: StateHalfOpen, probing: true, probeStarted: time.Now().Round(0)}
+	got := toWire(ps).state()
+	if !got.probeStarted.Equal(ps.probeStarted) || !got.probing {
+		t.Errorf("probe %v, %t after a round trip, want %v, true", got.probeStarted, got.probing, ps.probeStarted)
+	}
+}
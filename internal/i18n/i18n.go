@@ -0,0 +1,142 @@
+// Package i18n names what responses show people, cities, cabin classes
+// and amenities, in each supported locale. The translation tables are
+// embedded, one JSON file per locale.
+package i18n
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+)
+
+//go:embed locales/*.json
+var files embed.FS
+
+// Catalog is one locale's translations. Cities are keyed by airport code,
+// cabin classes and amenities by their codes.
+type Catalog struct {
+	CabinClasses map[string]string `json:"cabin_classes"`
+	Amenities    map[string]string `json:"amenities"`
+	Cities       map[string]string `json:"cities"`
+}
+
+var catalogs map[string]*Catalog
+
+func init() {
+	var err error
+	if catalogs, err = load(files); err != nil {
+		panic(err)
+	}
+}
+
+// load reads locales/<locale>.json for each locale, which must all
+// translate the same things.
+func load(fsys fs.FS) (map[string]*Catalog, error) {
+	names, err := fs.Glob(fsys, "locales/*.json")
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*Catalog, len(names))
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		var c Catalog
+		if err := dec.Decode(&c); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		result[strings.TrimSuffix(path.Base(name), ".json")] = &c
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("locales: no translation tables")
+	}
+
+	locales := slices.Sorted(maps.Keys(result))
+	first := result[locales[0]].tables()
+	for _, l := range locales[1:] {
+		for table, entries := range result[l].tables() {
+			if key, ok := missing(first[table], entries); ok {
+				return nil, fmt.Errorf("locales: %s %s is not in both %s.json and %s.json", table, key, locales[0], l)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (c *Catalog) tables() map[string]map[string]string {
+	return map[string]map[string]string{
+		"cabin_classes": c.CabinClasses,
+		"amenities":     c.Amenities,
+		"cities":        c.Cities,
+	}
+}
+
+// missing finds a key in only one of a and b.
+func missing(a, b map[string]string) (string, bool) {
+	for _, m := range [][2]map[string]string{{a, b}, {b, a}} {
+		for k := range m[0] {
+			if _, ok := m[1][k]; !ok {
+				return k, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Locales lists the supported locales, e.g. "en" and "id".
+func Locales() []string {
+	return slices.Sorted(maps.Keys(catalogs))
+}
+
+// For returns locale's catalog, nil when it isn't supported. A nil
+// Catalog translates nothing.
+func For(locale string) *Catalog {
+	return catalogs[locale]
+}
+
+// City names the city an airport serves, or is fallback when the catalog
+// doesn't know the airport.
+func (c *Catalog) City(airport, fallback string) string {
+	if c == nil {
+		return fallback
+	}
+	if name, ok := c.Cities[strings.ToUpper(airport)]; ok {
+		return name
+	}
+	return fallback
+}
+
+// CabinClass labels a canonical cabin class, or is the class itself when
+// there is no label for it.
+func (c *Catalog) CabinClass(class string) string {
+	if c == nil {
+		return class
+	}
+	return labelOr(c.CabinClasses, class)
+}
+
+// Amenity labels an amenity code, or is the code itself when there is no
+// label for it.
+func (c *Catalog) Amenity(code string) string {
+	if c == nil {
+		return code
+	}
+	return labelOr(c.Amenities, code)
+}
+
+func labelOr(labels map[string]string, code string) string {
+	if l, ok := labels[code]; ok {
+		return l
+	}
+	return code
+}
@@ -0,0 +1,168 @@
+// Package idempotency remembers the responses to requests sent with an
+// Idempotency-Key, so a client retrying one is answered with the first
+// response instead of the work being done again.
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Record is what is kept under a key: the request's fingerprint and, once
+// it was answered, the response.
+type Record struct {
+	Fingerprint string `json:"fingerprint"`
+	// Status is zero while the first request is in progress.
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+func (r Record) Pending() bool {
+	return r.Status == 0
+}
+
+type Store interface {
+	// Claim stores r under key unless key already has a record, which it
+	// returns instead.
+	Claim(ctx context.Context, key string, r Record, ttl time.Duration) (Record, bool, error)
+	Get(ctx context.Context, key string) (Record, bool, error)
+	// Complete replaces key's pending record with the answered one.
+	Complete(ctx context.Context, key string, r Record, ttl time.Duration) error
+	// Release forgets key, so the request may be retried.
+	Release(ctx context.Context, key string) error
+}
+
+type memoryRecord struct {
+	Record
+	expires time.Time
+}
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]memoryRecord
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]memoryRecord)}
+}
+
+func (s *MemoryStore) Claim(ctx context.Context, key string, r Record, ttl time.Duration) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	if existing, ok := s.records[key]; ok && now.Before(existing.expires) {
+		return existing.Record, false, nil
+	}
+	s.records[key] = memoryRecord{Record: r, expires: now.Add(ttl)}
+	return r, true, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[key]
+	if !ok || !time.Now().Before(r.expires) {
+		return Record{}, false, nil
+	}
+	return r.Record, true, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, key string, r Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[key] = memoryRecord{Record: r, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// sweep drops expired records, at most once a minute.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, r := range s.records {
+		if !now.Before(r.expires) {
+			delete(s.records, key)
+		}
+	}
+}
+
+const redisKeyPrefix = "idempotency:"
+
+// RedisStore keeps records in Redis, so a retry is recognised whichever
+// replica it reaches.
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Claim(ctx context.Context, key string, r Record, ttl time.Duration) (Record, bool, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return Record{}, false, err
+	}
+	// A record expiring between SETNX and GET is claimed on the next try.
+	for attempt := 0; attempt < 2; attempt++ {
+		ok, err := s.client.SetNX(ctx, redisKeyPrefix+key, data, ttl).Result()
+		if err != nil {
+			return Record{}, false, err
+		}
+		if ok {
+			return r, true, nil
+		}
+		existing, found, err := s.Get(ctx, key)
+		if err != nil || found {
+			return existing, false, err
+		}
+	}
+	return Record{}, false, errors.New("idempotency key claimed and released concurrently")
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (Record, bool, error) {
+	data, err := s.client.Get(ctx, redisKeyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Record{}, false, nil
+	}
+	if err != nil {
+		return Record{}, false, err
+	}
+	var r Record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Record{}, false, err
+	}
+	return r, true, nil
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key string, r Record, ttl time.Duration) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisKeyPrefix+key, data, ttl).Err()
+}
+
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, redisKeyPrefix+key).Err()
+}
@@ -0,0 +1,153 @@
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "leader:"
+
+// renewScript extends the lease only if this replica still holds it.
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Elector campaigns for a Redis lease so that work started through Run
+// happens on one replica at a time. The lease is renewed every third of
+// its TTL; if a renewal fails the replica steps down straight away, so a
+// partitioned leader stops before another replica can take over.
+type Elector struct {
+	client redis.UniversalClient
+	key    string
+	id     string
+	ttl    time.Duration
+
+	mu      sync.RWMutex
+	leading bool
+	since   time.Time
+}
+
+func NewElector(client redis.UniversalClient, name string, ttl time.Duration) *Elector {
+	return &Elector{
+		client: client,
+		key:    redisKeyPrefix + name,
+		id:     candidateID(),
+		ttl:    ttl,
+	}
+}
+
+type Status struct {
+	Candidate string     `json:"candidate"`
+	Leader    bool       `json:"leader"`
+	Since     *time.Time `json:"since,omitempty"`
+}
+
+func (e *Elector) Status() Status {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	s := Status{Candidate: e.id, Leader: e.leading}
+	if e.leading {
+		since := e.since
+		s.Since = &since
+	}
+	return s
+}
+
+// Run campaigns until ctx is done. Each time this replica becomes leader,
+// lead is called with a context that is cancelled when leadership is lost;
+// it should start its work in goroutines and return.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	var cancel context.CancelFunc
+	stepDown := func(reason string) {
+		if cancel == nil {
+			return
+		}
+		cancel()
+		cancel = nil
+		e.setLeading(false)
+		log.Printf("Stepped down as %s leader: %s", e.key, reason)
+	}
+
+	for {
+		if cancel == nil {
+			ok, err := e.client.SetNX(ctx, e.key, e.id, e.ttl).Result()
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Leader election for %s failed: %v", e.key, err)
+			}
+			if ok {
+				var leadCtx context.Context
+				leadCtx, cancel = context.WithCancel(ctx)
+				e.setLeading(true)
+				log.Printf("Became %s leader (%s)", e.key, e.id)
+				lead(leadCtx)
+			}
+		} else {
+			n, err := renewScript.Run(ctx, e.client, []string{e.key}, e.id, e.ttl.Milliseconds()).Int()
+			switch {
+			case err != nil && ctx.Err() == nil:
+				stepDown("renewal failed: " + err.Error())
+			case err == nil && n == 0:
+				stepDown("lease taken over")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if cancel != nil {
+				stepDown("shutting down")
+				e.release()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) setLeading(leading bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leading = leading
+	e.since = time.Now()
+}
+
+// release hands the lease back so another replica can take over without
+// waiting for it to expire.
+func (e *Elector) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := releaseScript.Run(ctx, e.client, []string{e.key}, e.id).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("Failed to release %s lease: %v", e.key, err)
+	}
+}
+
+func candidateID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,103 @@
+// Package markup prices flights for white-label partners: each tenant's
+// policy adds a markup to the providers' fares and records the commission
+// the partner earns, so its customers see the partner's prices.
+package markup
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/currency"
+)
+
+// Policy is a tenant's pricing, per passenger in the fare's currency.
+type Policy struct {
+	// MarkupPercent of the fare plus MarkupFixed is added to the price.
+	MarkupPercent float64 `json:"markup_percent,omitempty"`
+	MarkupFixed   float64 `json:"markup_fixed,omitempty"`
+	// CommissionPercent of the fare is the partner's, taken off what it
+	// pays; its customers' price doesn't change.
+	CommissionPercent float64 `json:"commission_percent,omitempty"`
+	// RoundTo rounds marked-up prices up to a multiple of it, e.g. 1000.
+	RoundTo float64 `json:"round_to,omitempty"`
+}
+
+func (p Policy) Validate() error {
+	if p.MarkupPercent < 0 || p.MarkupPercent > 100 {
+		return fmt.Errorf("markup_percent must be between 0 and 100")
+	}
+	if p.CommissionPercent < 0 || p.CommissionPercent > 100 {
+		return fmt.Errorf("commission_percent must be between 0 and 100")
+	}
+	if p.MarkupFixed < 0 || p.RoundTo < 0 {
+		return fmt.Errorf("markup_fixed and round_to must not be negative")
+	}
+	return nil
+}
+
+// Gross is the partner's price for a fare. It never orders two fares
+// differently than the fares themselves.
+func (p Policy) Gross(fare float64) float64 {
+	gross := fare + math.Round(fare*p.MarkupPercent/100) + p.MarkupFixed
+	if p.RoundTo > 0 {
+		gross = math.Ceil(gross/p.RoundTo) * p.RoundTo
+	}
+	return gross
+}
+
+// Apply prices copies of flights, with their breakdown when breakdown is
+// set.
+func (p Policy) Apply(flights []models.Flight, breakdown bool) []models.Flight {
+	result := slices.Clone(flights)
+	for i, f := range result {
+		fare := f.Price
+		gross := p.Gross(fare.Amount)
+		commission := math.Round(fare.Amount * p.CommissionPercent / 100)
+
+		result[i].Price = price(gross, fare.Currency)
+		if !breakdown {
+			continue
+		}
+		b := &models.PriceBreakdown{
+			Fare:  fare,
+			Net:   price(fare.Amount-commission, fare.Currency),
+			Gross: result[i].Price,
+		}
+		if gross > fare.Amount {
+			markup := price(gross-fare.Amount, fare.Currency)
+			b.Markup = &markup
+		}
+		if commission > 0 {
+			c := price(commission, fare.Currency)
+			b.Commission = &c
+		}
+		result[i].PriceBreakdown = b
+	}
+	return result
+}
+
+func price(amount float64, currencyCode string) models.Price {
+	return models.Price{Amount: amount, Currency: currencyCode, Formatted: currency.Format(amount, currencyCode)}
+}
+
+// LoadPolicies reads a JSON object of tenant name to Policy.
+func LoadPolicies(path string) (map[string]Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policies map[string]Policy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for tenant, p := range policies {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+	}
+	return policies, nil
+}
@@ -0,0 +1,151 @@
+// Package mct holds airports' minimum connection times and rates each
+// layover against them.
+package mct
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dharmasatrya/flightsearch/internal/airports"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Minimum connection times at airports without figures of their own.
+const (
+	DefaultDomestic      = 45
+	DefaultInternational = 60
+)
+
+const (
+	// TightMargin is how close to the minimum a connection is still
+	// legal but tight.
+	TightMargin = 15
+	// LongMinutes starts a long layover, ExcessiveMinutes one nobody would
+	// book as a connection.
+	LongMinutes      = 6 * 60
+	ExcessiveMinutes = 24 * 60
+)
+
+// Connection qualities, from worst to best. TooTight and Excessive
+// connections are invalid.
+const (
+	TooTight    = "too_tight"
+	Excessive   = "excessive"
+	Tight       = "tight"
+	Long        = "long"
+	Comfortable = "comfortable"
+)
+
+type minimums struct {
+	domestic, international int
+}
+
+//go:embed mct.csv
+var mctCSV []byte
+
+var table map[string]minimums
+
+func init() {
+	var err error
+	if table, err = parse(mctCSV); err != nil {
+		panic(err)
+	}
+}
+
+// parse reads rows of airport, domestic and international minimum
+// connection time in minutes, after a header.
+func parse(data []byte) (map[string]minimums, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("mct.csv: %w", err)
+	}
+	t := make(map[string]minimums, len(records))
+	for i, r := range records[1:] {
+		dom, err1 := strconv.Atoi(r[1])
+		intl, err2 := strconv.Atoi(r[2])
+		if r[0] == "" || err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("mct.csv line %d: want an airport and two minute counts", i+2)
+		}
+		t[strings.ToUpper(r[0])] = minimums{dom, intl}
+	}
+	return t, nil
+}
+
+// Minimum is the minimum connection time at airport, in minutes.
+func Minimum(airport string, international bool) int {
+	m, ok := table[strings.ToUpper(airport)]
+	switch {
+	case !ok && international:
+		return DefaultInternational
+	case !ok:
+		return DefaultDomestic
+	case international:
+		return m.international
+	}
+	return m.domestic
+}
+
+// Quality rates a connection of minutes against its minimum.
+func Quality(minutes, minimum int) string {
+	switch {
+	case minutes < minimum:
+		return TooTight
+	case minutes > ExcessiveMinutes:
+		return Excessive
+	case minutes < minimum+TightMargin:
+		return Tight
+	case minutes >= LongMinutes:
+		return Long
+	}
+	return Comfortable
+}
+
+// Valid reports whether a connection of this quality can be made.
+func Valid(quality string) bool {
+	return quality != TooTight && quality != Excessive
+}
+
+// Rate sets every layover's minimum connection time and quality. A
+// connection is international when the flight into or out of it crosses
+// a border; airports of unknown country count as domestic.
+func Rate(f models.Flight) models.Flight {
+	if len(f.Layovers) == 0 {
+		return f
+	}
+	codes := make([]string, 0, len(f.Layovers)+2)
+	codes = append(codes, f.Departure.Airport)
+	for _, l := range f.Layovers {
+		codes = append(codes, l.Airport)
+	}
+	codes = append(codes, f.Arrival.Airport)
+
+	layovers := make([]models.Layover, len(f.Layovers))
+	for i, l := range f.Layovers {
+		intl := crossesBorder(codes[i], l.Airport) || crossesBorder(l.Airport, codes[i+2])
+		l.MinConnectionMinutes = Minimum(l.Airport, intl)
+		l.ConnectionQuality = Quality(l.Duration, l.MinConnectionMinutes)
+		layovers[i] = l
+	}
+	f.Layovers = layovers
+	return f
+}
+
+func crossesBorder(from, to string) bool {
+	a, ok1 := airports.Lookup(from)
+	b, ok2 := airports.Lookup(to)
+	return ok1 && ok2 && a.Country != "" && b.Country != "" && a.Country != b.Country
+}
+
+// ValidConnections reports whether every layover of f can be made.
+func ValidConnections(f models.Flight) bool {
+	for _, l := range f.Layovers {
+		if !Valid(l.ConnectionQuality) {
+			return false
+		}
+	}
+	return true
+}
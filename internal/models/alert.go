@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/safeurl"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+type Alert struct {
+	ID string `json:"id"`
+	// Owner is an opaque hash of the caller that created the alert; only
+	// the same caller can list or delete it.
+	Owner         string  `json:"owner,omitempty"`
+	Origin        string  `json:"origin"`
+	Destination   string  `json:"destination"`
+	DepartureDate string  `json:"departure_date"`
+	Passengers    int     `json:"passengers"`
+	CabinClass    string  `json:"cabin_class"`
+	TargetPrice   float64 `json:"target_price,omitempty"`
+	// MinSeats, when set, alerts once fewer seats than this are left on the
+	// route and date across all providers.
+	MinSeats         int        `json:"min_seats,omitempty"`
+	WebhookURL       string     `json:"webhook_url,omitempty"`
+	Email            string     `json:"email,omitempty"`
+	LastLowestPrice  *float64   `json:"last_lowest_price,omitempty"`
+	LastSeats        *int       `json:"last_seats,omitempty"`
+	LastCheckedAt    *time.Time `json:"last_checked_at,omitempty"`
+	TriggeredAt      *time.Time `json:"triggered_at,omitempty"`
+	SeatsTriggeredAt *time.Time `json:"seats_triggered_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+func (a Alert) SearchRequest() SearchRequest {
+	return SearchRequest{
+		Origin:        a.Origin,
+		Destination:   a.Destination,
+		DepartureDate: a.DepartureDate,
+		Passengers:    a.Passengers,
+		CabinClass:    a.CabinClass,
+		SortBy:        string(types.SortPrice),
+		SortOrder:     string(types.SortAsc),
+	}
+}
+
+type CreateAlertRequest struct {
+	Origin        string  `json:"origin"`
+	Destination   string  `json:"destination"`
+	DepartureDate string  `json:"departure_date"`
+	Passengers    int     `json:"passengers"`
+	CabinClass    string  `json:"cabin_class"`
+	TargetPrice   float64 `json:"target_price,omitempty"`
+	MinSeats      int     `json:"min_seats,omitempty"`
+	WebhookURL    string  `json:"webhook_url,omitempty"`
+	Email         string  `json:"email,omitempty"`
+}
+
+// Validate checks the route and date like a search, and that the
+// notification channels are usable: a well-formed email and a webhook on a
+// public http(s) host.
+func (r *CreateAlertRequest) Validate() error {
+	if r.Passengers <= 0 {
+		r.Passengers = 1
+	}
+	r.CabinClass = normalizeCabin(r.CabinClass)
+	r.Origin = strings.ToUpper(r.Origin)
+	r.Destination = strings.ToUpper(r.Destination)
+
+	var errs errorsx.ValidationErrors
+	route := SearchRequest{Origin: r.Origin, Destination: r.Destination, DepartureDate: r.DepartureDate, CabinClass: r.CabinClass}
+	route.validateRoute(&errs, time.Now(), true)
+	if r.Passengers > MaxPassengers {
+		errs.Add("passengers", types.FieldOutOfRange, "passengers must be between 1 and %d", MaxPassengers)
+	}
+
+	if r.TargetPrice < 0 {
+		errs.Add("target_price", types.FieldOutOfRange, "%s", ErrInvalidTargetPrice)
+	}
+	if r.MinSeats < 0 {
+		errs.Add("min_seats", types.FieldOutOfRange, "%s", ErrInvalidMinSeats)
+	}
+	if r.TargetPrice == 0 && r.MinSeats == 0 {
+		errs.Add("target_price", types.FieldRequired, "%s", ErrMissingAlertCondition)
+	}
+
+	if r.WebhookURL == "" && r.Email == "" {
+		errs.Add("webhook_url", types.FieldRequired, "%s", ErrMissingAlertChannel)
+	}
+	if r.Email != "" {
+		if addr, err := mail.ParseAddress(r.Email); err != nil || addr.Address != r.Email {
+			errs.Add("email", types.FieldInvalidFormat, "email must be a plain email address")
+		}
+	}
+	if r.WebhookURL != "" {
+		if err := safeurl.Check(r.WebhookURL); errors.Is(err, safeurl.ErrNotPublic) {
+			errs.Add("webhook_url", types.FieldNotPublic, "webhook_url must not point to a private or loopback address")
+		} else if err != nil {
+			errs.Add("webhook_url", types.FieldInvalidFormat, "webhook_url %s", err)
+		}
+	}
+	return errs.Err()
+}
+
+const (
+	ErrInvalidTargetPrice    errorsx.ValidationError = "target_price must be greater than 0"
+	ErrInvalidMinSeats       errorsx.ValidationError = "min_seats must be greater than 0"
+	ErrMissingAlertCondition errorsx.ValidationError = "target_price or min_seats is required"
+	ErrMissingAlertChannel   errorsx.ValidationError = "webhook_url or email is required"
+)
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+type sortAlias struct {
+	sortBy    types.SortKey
+	sortOrder types.SortOrder
+}
+
+// sortAliases maps friendly sort_by values to their canonical sort_by and
+// sort_order. The order only applies when the client didn't send one.
+var sortAliases = map[string]sortAlias{
+	"cheapest":       {types.SortPrice, types.SortAsc},
+	"lowest_price":   {types.SortPrice, types.SortAsc},
+	"most_expensive": {types.SortPrice, types.SortDesc},
+	"fastest":        {types.SortDuration, types.SortAsc},
+	"shortest":       {types.SortDuration, types.SortAsc},
+	"earliest":       {types.SortDeparture, types.SortAsc},
+	"latest":         {types.SortDeparture, types.SortDesc},
+	"fewest_stops":   {types.SortStops, types.SortAsc},
+	"best":           {types.SortBestValue, types.SortAsc},
+	"recommended":    {types.SortBestValue, types.SortAsc},
+}
+
+var sortOrderAliases = map[string]types.SortOrder{
+	"ascending":   types.SortAsc,
+	"descending":  types.SortDesc,
+	"low_to_high": types.SortAsc,
+	"high_to_low": types.SortDesc,
+}
+
+// stopsAliases maps filters.stops to the canonical filters.max_stops.
+var stopsAliases = map[string]int{
+	"direct":   0,
+	"nonstop":  0,
+	"non_stop": 0,
+	"one_stop": 1,
+	"1_stop":   1,
+}
+
+func (r *SearchRequest) normalizeAliases(change func(field string, from, to any, reason string)) {
+	if alias, ok := sortAliases[strings.ToLower(r.SortBy)]; ok {
+		change("sort_by", r.SortBy, string(alias.sortBy), "sort_by alias")
+		r.SortBy = string(alias.sortBy)
+		if r.SortOrder == "" {
+			change("sort_order", r.SortOrder, string(alias.sortOrder), "implied by sort_by alias")
+			r.SortOrder = string(alias.sortOrder)
+		}
+	}
+	if order, ok := sortOrderAliases[strings.ToLower(r.SortOrder)]; ok {
+		change("sort_order", r.SortOrder, string(order), "sort_order alias")
+		r.SortOrder = string(order)
+	}
+
+	if r.Filters != nil && r.Filters.Stops != nil {
+		if maxStops, ok := stopsAliases[strings.ToLower(*r.Filters.Stops)]; ok {
+			change("filters.max_stops", r.Filters.MaxStops, maxStops, "filters.stops alias "+*r.Filters.Stops)
+			r.Filters.MaxStops = &maxStops
+			r.Filters.Stops = nil
+		}
+	}
+}
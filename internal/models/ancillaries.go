@@ -0,0 +1,40 @@
+package models
+
+// Ancillaries are the extras an airline sells with a flight, each priced
+// per passenger in the fare's currency.
+type Ancillaries struct {
+	// CheckedBags are bags bought on top of Baggage.CheckedKg, lightest
+	// first.
+	CheckedBags []BagOption `json:"checked_bags,omitempty"`
+	// SeatSelection is the cheapest seat that can be chosen in advance;
+	// unset when choosing is free or not offered.
+	SeatSelection *Price `json:"seat_selection,omitempty"`
+	// Meal is unset when a meal is included or none is sold.
+	Meal *Price `json:"meal,omitempty"`
+}
+
+type BagOption struct {
+	Kg    float64 `json:"kg"`
+	Price Price   `json:"price"`
+}
+
+// CheckedBagCost is the cheapest way to check kg of baggage on f: zero
+// when the fare includes it, and false when no bag sold is big enough.
+func (f Flight) CheckedBagCost(kg float64) (float64, bool) {
+	if f.Baggage.CheckedKg >= kg {
+		return 0, true
+	}
+	if f.Ancillaries == nil {
+		return 0, false
+	}
+	best, found := 0.0, false
+	for _, bag := range f.Ancillaries.CheckedBags {
+		if f.Baggage.CheckedKg+bag.Kg < kg || bag.Price.Currency != f.Price.Currency {
+			continue
+		}
+		if !found || bag.Price.Amount < best {
+			best, found = bag.Price.Amount, true
+		}
+	}
+	return best, found
+}
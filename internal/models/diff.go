@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// SearchDiff is what changed between two searches for the same route,
+// dates, cabin and passengers.
+type SearchDiff struct {
+	From DiffSearch `json:"from"`
+	To   DiffSearch `json:"to"`
+	FlightChanges
+	// Return lists the return flights' changes of round-trip searches.
+	Return *FlightChanges `json:"return,omitempty"`
+}
+
+type DiffSearch struct {
+	SearchID     string    `json:"search_id"`
+	ResultsAsOf  time.Time `json:"results_as_of"`
+	TotalResults int       `json:"total_results"`
+}
+
+// FlightChanges matches flights by provider and ID. A flight whose price
+// didn't change counts as unchanged even if its seats or other fields did.
+type FlightChanges struct {
+	Added        []Flight      `json:"added"`
+	Removed      []Flight      `json:"removed"`
+	PriceChanges []PriceChange `json:"price_changes"`
+	Unchanged    int           `json:"unchanged"`
+}
+
+type PriceChange struct {
+	// Flight is the flight as the later search returned it.
+	Flight        Flight  `json:"flight"`
+	From          Price   `json:"from"`
+	To            Price   `json:"to"`
+	Change        float64 `json:"change"`
+	ChangePercent float64 `json:"change_percent"`
+}
@@ -0,0 +1,30 @@
+package models
+
+type GroundTransportOption struct {
+	Mode            string `json:"mode"`
+	Operator        string `json:"operator"`
+	Destination     string `json:"destination"`
+	DurationMinutes int    `json:"duration_minutes"`
+	EstimatedPrice  Price  `json:"estimated_price"`
+}
+
+type GroundTransport struct {
+	Origin      []GroundTransportOption `json:"origin"`
+	Destination []GroundTransportOption `json:"destination"`
+}
+
+type HotelOffer struct {
+	Name        string  `json:"name"`
+	Area        string  `json:"area"`
+	StarRating  float64 `json:"star_rating"`
+	Nights      int     `json:"nights"`
+	NightlyRate Price   `json:"nightly_rate"`
+	TotalPrice  Price   `json:"total_price"`
+	Supplier    string  `json:"supplier"`
+}
+
+type CrossSell struct {
+	CheckIn  string       `json:"check_in"`
+	CheckOut string       `json:"check_out"`
+	Hotels   []HotelOffer `json:"hotels"`
+}
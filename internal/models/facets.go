@@ -0,0 +1,17 @@
+package models
+
+type FacetBucket struct {
+	Value    string  `json:"value"`
+	Label    string  `json:"label,omitempty"`
+	Count    int     `json:"count"`
+	MinPrice float64 `json:"min_price"`
+}
+
+// Facets summarize the unfiltered result set so UIs can render filter
+// options with counts and "from" prices.
+type Facets struct {
+	Airlines       []FacetBucket `json:"airlines"`
+	Stops          []FacetBucket `json:"stops"`
+	DepartureTimes []FacetBucket `json:"departure_times"`
+	Providers      []FacetBucket `json:"providers"`
+}
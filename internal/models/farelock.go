@@ -0,0 +1,75 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+const (
+	FareLockActive  = "active"
+	FareLockExpired = "expired"
+)
+
+type FareLockRequest struct {
+	Origin        string `json:"origin"`
+	Destination   string `json:"destination"`
+	DepartureDate string `json:"departure_date"`
+	CabinClass    string `json:"cabin_class"`
+	Passengers    int    `json:"passengers"`
+	HoldHours     int    `json:"hold_hours"`
+}
+
+func (r *FareLockRequest) Validate() error {
+	if r.Origin == "" {
+		return ErrMissingOrigin
+	}
+	if r.Destination == "" {
+		return ErrMissingDestination
+	}
+	if r.DepartureDate == "" {
+		return ErrMissingDepartureDate
+	}
+	if r.HoldHours < 0 {
+		return ErrInvalidHoldHours
+	}
+	if r.HoldHours == 0 {
+		r.HoldHours = 24
+	}
+	if r.Passengers <= 0 {
+		r.Passengers = 1
+	}
+	r.CabinClass = normalizeCabin(r.CabinClass)
+	r.Origin = strings.ToUpper(r.Origin)
+	r.Destination = strings.ToUpper(r.Destination)
+	return nil
+}
+
+func (r FareLockRequest) SearchRequest() SearchRequest {
+	return SearchRequest{
+		Origin:        r.Origin,
+		Destination:   r.Destination,
+		DepartureDate: r.DepartureDate,
+		Passengers:    r.Passengers,
+		CabinClass:    r.CabinClass,
+	}
+}
+
+type FareLock struct {
+	ID           string    `json:"id"`
+	FlightID     string    `json:"flight_id"`
+	FlightNumber string    `json:"flight_number"`
+	Provider     string    `json:"provider"`
+	Passengers   int       `json:"passengers"`
+	LockedPrice  Price     `json:"locked_price"`
+	Fee          Price     `json:"fee"`
+	BalanceDue   Price     `json:"balance_due"`
+	Native       bool      `json:"native"`
+	Reference    string    `json:"reference,omitempty"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+const ErrInvalidHoldHours errorsx.ValidationError = "hold_hours must not be negative"
@@ -15,16 +15,26 @@ type Location struct {
 	Timezone string    `json:"timezone"`
 }
 
+// Duration is the whole trip, layovers included, as the departure and
+// arrival times span. ReportedMinutes is what the provider said, which
+// some count without layovers.
 type Duration struct {
-	Hours        int `json:"hours"`
-	Minutes      int `json:"minutes"`
-	TotalMinutes int `json:"total_minutes"`
+	Hours           int `json:"hours"`
+	Minutes         int `json:"minutes"`
+	TotalMinutes    int `json:"total_minutes"`
+	ReportedMinutes int `json:"reported_minutes,omitempty"`
 }
 
 type Layover struct {
 	Airport  string `json:"airport"`
 	City     string `json:"city"`
 	Duration int    `json:"duration_minutes"`
+
+	// MinConnectionMinutes is the airport's minimum connection time for
+	// this connection, and ConnectionQuality how Duration compares:
+	// "too_tight", "tight", "comfortable", "long" or "excessive".
+	MinConnectionMinutes int    `json:"min_connection_minutes,omitempty"`
+	ConnectionQuality    string `json:"connection_quality,omitempty"`
 }
 
 type Price struct {
@@ -38,6 +48,24 @@ type Baggage struct {
 	CheckedKg float64 `json:"checked_kg"`
 }
 
+// AppliedPromotion is the campaign that discounted a flight's price.
+type AppliedPromotion struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Discount Price  `json:"discount"`
+}
+
+// PriceBreakdown shows how a partner's price is made up from the
+// provider's Fare: Gross is what its customers pay before any promotion,
+// Net what the partner pays.
+type PriceBreakdown struct {
+	Fare       Price  `json:"fare"`
+	Markup     *Price `json:"markup,omitempty"`
+	Commission *Price `json:"commission,omitempty"`
+	Net        Price  `json:"net"`
+	Gross      Price  `json:"gross"`
+}
+
 type Flight struct {
 	ID             string    `json:"id"`
 	Provider       string    `json:"provider"`
@@ -46,13 +74,89 @@ type Flight struct {
 	Departure      Location  `json:"departure"`
 	Arrival        Location  `json:"arrival"`
 	Duration       Duration  `json:"duration"`
+	ArrivesNextDay bool      `json:"arrives_next_day"`
 	Stops          int       `json:"stops"`
 	Layovers       []Layover `json:"layovers,omitempty"`
 	Price          Price     `json:"price"`
 	AvailableSeats int       `json:"available_seats"`
 	CabinClass     string    `json:"cabin_class"`
 	Aircraft       *string   `json:"aircraft,omitempty"`
+	AircraftCode   *string   `json:"aircraft_code,omitempty"`
 	Amenities      []string  `json:"amenities,omitempty"`
 	Baggage        Baggage   `json:"baggage"`
 	BestValueScore float64   `json:"best_value_score,omitempty"`
+
+	// CabinClassLabel and AmenityLabels, in the order of Amenities, name
+	// the codes in the request's locale.
+	CabinClassLabel string   `json:"cabin_class_label,omitempty"`
+	AmenityLabels   []string `json:"amenity_labels,omitempty"`
+
+	// Ancillaries is unset when the provider doesn't sell extras or
+	// doesn't say what they cost.
+	Ancillaries *Ancillaries `json:"ancillaries,omitempty"`
+	// BagFee is the checked bag priced in for filters.include_bag_kg,
+	// already part of Price.
+	BagFee *Price `json:"bag_fee,omitempty"`
+	// OriginalPrice is Price before Promotion's discount; both are unset
+	// when no promotion applies.
+	OriginalPrice *Price            `json:"original_price,omitempty"`
+	Promotion     *AppliedPromotion `json:"promotion,omitempty"`
+	// PriceBreakdown is only shown to the admin keys of a tenant with a
+	// markup policy.
+	PriceBreakdown *PriceBreakdown `json:"price_breakdown,omitempty"`
+
+	// DistanceKm is the great-circle distance flown, through any layovers;
+	// zero when an airport's coordinates are unknown.
+	DistanceKm float64 `json:"distance_km,omitempty"`
+	// EmissionsKgCO2 is the estimated CO2 for one passenger in this cabin.
+	EmissionsKgCO2 float64 `json:"emissions_kg_co2,omitempty"`
+
+	// Reliability is unset when there is no on-time data for the airline.
+	Reliability *Reliability `json:"reliability,omitempty"`
+
+	// QuotedAt is when the provider priced the flight, which for a cached
+	// search can be well before the request, and ExpiresAt when that
+	// price stops being served.
+	QuotedAt  *time.Time `json:"quoted_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// BookingURL opens the airline's checkout with this flight selected,
+	// when its provider has a booking URL template.
+	BookingURL string `json:"booking_url,omitempty"`
+
+	ScoreDetails *ScoreDetails `json:"score_details,omitempty"`
+	Badges       []string      `json:"badges,omitempty"`
+
+	// UnbookableReasons is set when the airline can't take the requested
+	// passenger mix. The flight is still listed but can't be booked.
+	UnbookableReasons []string `json:"unbookable_reasons,omitempty"`
+
+	// FallbackFor is the provider this flight stands in for: it couldn't
+	// be searched, so Provider was searched for its airlines instead.
+	FallbackFor string `json:"fallback_for,omitempty"`
+}
+
+// Reliability rates how dependably the airline keeps to schedule on the
+// flight's route.
+type Reliability struct {
+	// Score runs from 0 to 100, higher being more reliable.
+	Score           int     `json:"score"`
+	OnTimePct       float64 `json:"on_time_pct"`
+	AvgDelayMinutes int     `json:"avg_delay_minutes"`
+	// Basis is "route", or "airline" when the figures are network-wide.
+	Basis string `json:"basis"`
+}
+
+// ScoreDetails breaks a best value score down into its weighted components.
+// Components sum to Total (give or take rounding).
+type ScoreDetails struct {
+	Price         float64 `json:"price"`
+	Duration      float64 `json:"duration"`
+	Stops         float64 `json:"stops"`
+	Layover       float64 `json:"layover,omitempty"`
+	DepartureTime float64 `json:"departure_time,omitempty"`
+	Baggage       float64 `json:"baggage,omitempty"`
+	Aircraft      float64 `json:"aircraft,omitempty"`
+	Reliability   float64 `json:"reliability,omitempty"`
+	Total         float64 `json:"total"`
 }
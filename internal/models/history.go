@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type PricePoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	MinPrice  float64   `json:"min_price"`
+	FlightID  string    `json:"flight_id,omitempty"`
+	Provider  string    `json:"provider,omitempty"`
+}
+
+type PriceHistoryResponse struct {
+	Origin        string       `json:"origin"`
+	Destination   string       `json:"destination"`
+	DepartureDate string       `json:"departure_date"`
+	CabinClass    string       `json:"cabin_class"`
+	Interval      string       `json:"interval,omitempty"`
+	Points        []PricePoint `json:"points"`
+}
+
+type PriceTrend struct {
+	Direction     string  `json:"direction"`
+	ChangePercent float64 `json:"change_percent"`
+	WindowDays    int     `json:"window_days"`
+	Samples       int     `json:"samples"`
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+type OffsetQuoteRequest struct {
+	FlightID      string `json:"flight_id"`
+	Origin        string `json:"origin"`
+	Destination   string `json:"destination"`
+	DepartureDate string `json:"departure_date"`
+	CabinClass    string `json:"cabin_class"`
+	Passengers    int    `json:"passengers"`
+}
+
+func (r *OffsetQuoteRequest) Validate() error {
+	if r.FlightID == "" {
+		return ErrMissingFlightID
+	}
+	if r.Origin == "" {
+		return ErrMissingOrigin
+	}
+	if r.Destination == "" {
+		return ErrMissingDestination
+	}
+	if r.DepartureDate == "" {
+		return ErrMissingDepartureDate
+	}
+	if r.Passengers <= 0 {
+		r.Passengers = 1
+	}
+	if r.CabinClass == "" {
+		r.CabinClass = "economy"
+	}
+	r.Origin = strings.ToUpper(r.Origin)
+	r.Destination = strings.ToUpper(r.Destination)
+	return nil
+}
+
+func (r OffsetQuoteRequest) SearchRequest() SearchRequest {
+	return SearchRequest{
+		Origin:        r.Origin,
+		Destination:   r.Destination,
+		DepartureDate: r.DepartureDate,
+		Passengers:    r.Passengers,
+		CabinClass:    r.CabinClass,
+	}
+}
+
+type OffsetQuote struct {
+	ID           string    `json:"id"`
+	FlightID     string    `json:"flight_id"`
+	FlightNumber string    `json:"flight_number"`
+	Origin       string    `json:"origin"`
+	Destination  string    `json:"destination"`
+	Passengers   int       `json:"passengers"`
+	EmissionsKg  float64   `json:"emissions_kg_co2"`
+	Price        Price     `json:"price"`
+	Supplier     string    `json:"supplier"`
+	Project      string    `json:"project"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type OffsetPurchaseRequest struct {
+	QuoteID   string `json:"quote_id"`
+	Reference string `json:"reference,omitempty"`
+}
+
+func (r *OffsetPurchaseRequest) Validate() error {
+	if r.QuoteID == "" {
+		return ErrMissingQuoteID
+	}
+	return nil
+}
+
+type OffsetPurchase struct {
+	ID             string      `json:"id"`
+	Quote          OffsetQuote `json:"quote"`
+	CertificateRef string      `json:"certificate_ref"`
+	Reference      string      `json:"reference,omitempty"`
+	PurchasedAt    time.Time   `json:"purchased_at"`
+}
+
+const (
+	ErrMissingFlightID errorsx.ValidationError = "flight_id is required"
+	ErrMissingQuoteID  errorsx.ValidationError = "quote_id is required"
+)
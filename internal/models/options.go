@@ -0,0 +1,34 @@
+package models
+
+import "context"
+
+// DefaultLocale is used when the caller's locale isn't supported.
+const DefaultLocale = "id"
+
+// SearchOptions are per-request settings that don't change which flights
+// match a search, only how they are fetched and presented. They travel in
+// the request context alongside the SearchRequest, so providers and
+// normalizers can adjust to the caller instead of global configuration.
+type SearchOptions struct {
+	// Locale is the language for presentation, e.g. "id" or "en".
+	Locale string
+	// Debug asks providers to log how they answered.
+	Debug bool
+	// Tier is the caller's client tier, e.g. "internal".
+	Tier string
+}
+
+type searchOptionsKey struct{}
+
+func WithSearchOptions(ctx context.Context, o SearchOptions) context.Context {
+	return context.WithValue(ctx, searchOptionsKey{}, o)
+}
+
+// SearchOptionsFrom returns the request's options, or the defaults when
+// ctx carries none (background jobs).
+func SearchOptionsFrom(ctx context.Context) SearchOptions {
+	if o, ok := ctx.Value(searchOptionsKey{}).(SearchOptions); ok {
+		return o
+	}
+	return SearchOptions{Locale: DefaultLocale}
+}
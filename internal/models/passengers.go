@@ -0,0 +1,49 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// MaxChildAge is the oldest age accepted for a child or infant. Whether a
+// passenger that age still counts as a child depends on the airline.
+const MaxChildAge = 17
+
+// PassengerMix describes who is travelling when the party isn't just
+// adults. Ages are on the departure date.
+type PassengerMix struct {
+	Adults int `json:"adults"`
+	// ChildAges holds the age of each child with their own seat, including
+	// infants in a car seat.
+	ChildAges []int `json:"child_ages,omitempty"`
+	// LapInfantAges holds the age of each infant travelling on an adult's lap.
+	LapInfantAges []int `json:"lap_infant_ages,omitempty"`
+}
+
+// Seats is how many seats the party occupies; lap infants don't take one.
+func (m PassengerMix) Seats() int {
+	return m.Adults + len(m.ChildAges)
+}
+
+func (m PassengerMix) validate(errs *errorsx.ValidationErrors) {
+	if m.Adults < 0 {
+		errs.Add("passenger_mix.adults", types.FieldOutOfRange, "passenger_mix.adults must not be negative")
+	}
+	if m.Seats() < 1 || m.Seats() > MaxPassengers {
+		errs.Add("passenger_mix", types.FieldOutOfRange, "passenger_mix must have between 1 and %d seated passengers", MaxPassengers)
+	}
+	if len(m.LapInfantAges) > MaxPassengers {
+		errs.Add("passenger_mix.lap_infant_ages", types.FieldOutOfRange, "passenger_mix must have at most %d lap infants", MaxPassengers)
+	}
+	checkAges := func(field string, ages []int) {
+		for i, age := range ages {
+			if age < 0 || age > MaxChildAge {
+				errs.Add(fmt.Sprintf("%s[%d]", field, i), types.FieldOutOfRange, "%s[%d] must be between 0 and %d", field, i, MaxChildAge)
+			}
+		}
+	}
+	checkAges("passenger_mix.child_ages", m.ChildAges)
+	checkAges("passenger_mix.lap_infant_ages", m.LapInfantAges)
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+type RefundQuoteRequest struct {
+	Origin        string     `json:"origin"`
+	Destination   string     `json:"destination"`
+	DepartureDate string     `json:"departure_date"`
+	CabinClass    string     `json:"cabin_class"`
+	Passengers    int        `json:"passengers"`
+	PaidAmount    *float64   `json:"paid_amount,omitempty"`
+	CancelAt      *time.Time `json:"cancel_at,omitempty"`
+}
+
+func (r *RefundQuoteRequest) Validate() error {
+	if r.Origin == "" {
+		return ErrMissingOrigin
+	}
+	if r.Destination == "" {
+		return ErrMissingDestination
+	}
+	if r.DepartureDate == "" {
+		return ErrMissingDepartureDate
+	}
+	if r.PaidAmount != nil && *r.PaidAmount < 0 {
+		return ErrInvalidPaidAmount
+	}
+	if r.Passengers <= 0 {
+		r.Passengers = 1
+	}
+	if r.CabinClass == "" {
+		r.CabinClass = "economy"
+	}
+	r.Origin = strings.ToUpper(r.Origin)
+	r.Destination = strings.ToUpper(r.Destination)
+	return nil
+}
+
+func (r RefundQuoteRequest) SearchRequest() SearchRequest {
+	return SearchRequest{
+		Origin:        r.Origin,
+		Destination:   r.Destination,
+		DepartureDate: r.DepartureDate,
+		Passengers:    r.Passengers,
+		CabinClass:    r.CabinClass,
+	}
+}
+
+type PenaltyTier struct {
+	MinHoursBeforeDeparture int     `json:"min_hours_before_departure"`
+	PenaltyPercent          float64 `json:"penalty_percent"`
+}
+
+type FareRules struct {
+	Refundable      bool          `json:"refundable"`
+	CancellationFee float64       `json:"cancellation_fee"`
+	PenaltyTiers    []PenaltyTier `json:"penalty_tiers,omitempty"`
+	NoShowRefund    bool          `json:"no_show_refund"`
+}
+
+type RefundQuote struct {
+	FlightID        string    `json:"flight_id"`
+	FlightNumber    string    `json:"flight_number"`
+	Airline         Airline   `json:"airline"`
+	FareRules       FareRules `json:"fare_rules"`
+	CancelAt        time.Time `json:"cancel_at"`
+	HoursBefore     float64   `json:"hours_before_departure"`
+	PaidAmount      Price     `json:"paid_amount"`
+	Penalty         Price     `json:"penalty"`
+	CancellationFee Price     `json:"cancellation_fee"`
+	RefundAmount    Price     `json:"refund_amount"`
+	Summary         string    `json:"summary"`
+}
+
+const ErrInvalidPaidAmount errorsx.ValidationError = "paid_amount must not be negative"
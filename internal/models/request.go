@@ -1,5 +1,17 @@
 package models
 
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
 type SearchFilters struct {
 	PriceMin         *float64 `json:"price_min,omitempty"`
 	PriceMax         *float64 `json:"price_max,omitempty"`
@@ -10,6 +22,72 @@ type SearchFilters struct {
 	ArrivalTimeMin   *string  `json:"arrival_time_min,omitempty"`
 	ArrivalTimeMax   *string  `json:"arrival_time_max,omitempty"`
 	MaxDuration      *int     `json:"max_duration,omitempty"`
+
+	Amenities           []string `json:"amenities,omitempty"`
+	MinCheckedBaggageKg *float64 `json:"min_checked_baggage_kg,omitempty"`
+	MinCabinBaggageKg   *float64 `json:"min_cabin_baggage_kg,omitempty"`
+	// IncludeBagKg prices every flight with this much checked baggage,
+	// adding the cheapest bag that tops up what the fare includes, so
+	// low-cost fares compare fairly with full-service ones. Price filters,
+	// sorting and scores see that price; flights that can't take the bag
+	// are dropped.
+	IncludeBagKg *float64 `json:"include_bag_kg,omitempty"`
+
+	MaxLayoverMinutes      *int     `json:"max_layover_minutes,omitempty"`
+	MinLayoverMinutes      *int     `json:"min_layover_minutes,omitempty"`
+	ExcludeLayoverAirports []string `json:"exclude_layover_airports,omitempty"`
+	// ExcludeInvalidConnections drops flights with a connection shorter
+	// than the airport's minimum connection time or longer than a day.
+	ExcludeInvalidConnections bool `json:"exclude_invalid_connections,omitempty"`
+
+	ExcludeAirlines  []string `json:"exclude_airlines,omitempty"`
+	ExcludeProviders []string `json:"exclude_providers,omitempty"`
+
+	// AircraftTypes and ExcludeAircraftTypes take ICAO codes ("B738") or
+	// categories ("widebody", "turboprop").
+	AircraftTypes        []string `json:"aircraft_types,omitempty"`
+	ExcludeAircraftTypes []string `json:"exclude_aircraft_types,omitempty"`
+
+	// ExcludeOvernight drops flights departing or arriving inside the
+	// overnight window (local time), 23:00-05:00 unless overridden.
+	ExcludeOvernight bool    `json:"exclude_overnight,omitempty"`
+	OvernightStart   *string `json:"overnight_start,omitempty"`
+	OvernightEnd     *string `json:"overnight_end,omitempty"`
+
+	// MinReliability keeps flights whose reliability score is at least
+	// this (0-100). Flights without on-time data are dropped.
+	MinReliability *float64 `json:"min_reliability,omitempty"`
+
+	// Stops is an alias for MaxStops ("direct", "one_stop"); it is resolved
+	// into MaxStops by Normalize.
+	Stops *string `json:"stops,omitempty"`
+}
+
+func sortKeyNames() []string {
+	names := make([]string, len(types.SortKeys))
+	for i, k := range types.SortKeys {
+		names[i] = string(k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type RankingWeights struct {
+	Price       float64 `json:"price"`
+	Duration    float64 `json:"duration"`
+	Stops       float64 `json:"stops"`
+	Reliability float64 `json:"reliability,omitempty"`
+}
+
+// Validate checks the weights are non-negative and add up to 1.0.
+func (w RankingWeights) Validate() error {
+	if w.Price < 0 || w.Duration < 0 || w.Stops < 0 || w.Reliability < 0 {
+		return ErrNegativeRankingWeight
+	}
+	if math.Abs(w.Price+w.Duration+w.Stops+w.Reliability-1.0) > 0.001 {
+		return ErrRankingWeightsSum
+	}
+	return nil
 }
 
 type SearchRequest struct {
@@ -22,41 +100,175 @@ type SearchRequest struct {
 	Filters       *SearchFilters `json:"filters,omitempty"`
 	SortBy        string         `json:"sort_by,omitempty"`
 	SortOrder     string         `json:"sort_order,omitempty"`
+
+	// PassengerMix, when set, replaces Passengers with its seated count and
+	// marks flights whose airline can't take the party.
+	PassengerMix *PassengerMix `json:"passenger_mix,omitempty"`
+
+	// SearchID replays the results of an earlier search, so re-filtering
+	// and re-sorting within a session never switches datasets. Refresh
+	// fetches fresh results and issues a new SearchID instead.
+	SearchID string `json:"search_id,omitempty"`
+	Refresh  bool   `json:"refresh,omitempty"`
+
+	// Providers limits the search to these providers; all are queried when empty.
+	Providers []string `json:"providers,omitempty"`
+
+	// NearbyKm also searches from and to the airports within this many
+	// kilometres of the origin and destination. Flights report the
+	// airports they actually use.
+	NearbyKm int `json:"nearby_km,omitempty"`
+
+	RankingWeights *RankingWeights `json:"ranking_weights,omitempty"`
+
+	// PromoCode unlocks the promotions that require it.
+	PromoCode string `json:"promo_code,omitempty"`
+
+	IncludeGroundTransport bool `json:"include_ground_transport,omitempty"`
+	IncludeCrossSell       bool `json:"include_cross_sell,omitempty"`
+	IncludeFacets          bool `json:"include_facets,omitempty"`
 }
 
+// Validate checks a normalized request before searching and reports every
+// problem as a errorsx.FieldError (the error is errorsx.ValidationErrors).
 func (r *SearchRequest) Validate() error {
-	if r.Origin == "" {
-		return ErrMissingOrigin
+	var errs errorsx.ValidationErrors
+	r.validateRoute(&errs, time.Now(), true)
+
+	if r.PassengerMix != nil {
+		r.PassengerMix.validate(&errs)
+	} else if r.Passengers < 1 || r.Passengers > MaxPassengers {
+		errs.Add("passengers", types.FieldOutOfRange, "passengers must be between 1 and %d", MaxPassengers)
 	}
-	if r.Destination == "" {
-		return ErrMissingDestination
+	r.validateSort(&errs)
+	if r.NearbyKm < 0 || r.NearbyKm > MaxNearbyKm {
+		errs.Add("nearby_km", types.FieldOutOfRange, "nearby_km must be between 0 and %d", MaxNearbyKm)
 	}
-	if r.DepartureDate == "" {
-		return ErrMissingDepartureDate
+	if r.RankingWeights != nil {
+		if err := r.RankingWeights.Validate(); err != nil {
+			errs.Add("ranking_weights", types.FieldOutOfRange, "%s", err)
+		}
 	}
-	if r.Passengers <= 0 {
+	if r.PromoCode != "" && !promoCode.MatchString(r.PromoCode) {
+		errs.Add("promo_code", types.FieldInvalidFormat, "promo_code must be up to 32 letters, digits, dashes or underscores")
+	}
+	if r.Filters != nil {
+		r.Filters.validate(&errs)
+	}
+	return errs.Err()
+}
+
+// ValidateRoute checks only the route, date and cabin, and accepts past
+// dates, for lookups of recorded data such as price history.
+func (r *SearchRequest) ValidateRoute() error {
+	var errs errorsx.ValidationErrors
+	r.validateRoute(&errs, time.Now(), false)
+	return errs.Err()
+}
+
+// QueriesProvider reports whether the provider should be queried at all,
+// honouring both the providers allowlist and filters.exclude_providers.
+func (r SearchRequest) QueriesProvider(name string) bool {
+	if len(r.Providers) > 0 && !containsFold(r.Providers, name) {
+		return false
+	}
+	if r.Filters != nil && containsFold(r.Filters.ExcludeProviders, name) {
+		return false
+	}
+	return true
+}
+
+// RestrictsProviders reports whether only a subset of providers is queried.
+func (r SearchRequest) RestrictsProviders() bool {
+	return len(r.Providers) > 0 || (r.Filters != nil && len(r.Filters.ExcludeProviders) > 0)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalization records a change the server made to a request before
+// searching, so clients aren't surprised by silently rewritten input.
+type Normalization struct {
+	Field  string `json:"field"`
+	From   any    `json:"from"`
+	To     any    `json:"to"`
+	Reason string `json:"reason"`
+}
+
+// Normalize fills in defaults and canonicalizes the request in place,
+// returning a report of everything it changed. Call it before Validate.
+func (r *SearchRequest) Normalize() []Normalization {
+	var changes []Normalization
+	change := func(field string, from, to any, reason string) {
+		changes = append(changes, Normalization{Field: field, From: from, To: to, Reason: reason})
+	}
+
+	if code := strings.ToUpper(strings.TrimSpace(r.Origin)); code != r.Origin {
+		change("origin", r.Origin, code, "airport codes are uppercased")
+		r.Origin = code
+	}
+	if code := strings.ToUpper(strings.TrimSpace(r.Destination)); code != r.Destination {
+		change("destination", r.Destination, code, "airport codes are uppercased")
+		r.Destination = code
+	}
+	for i, p := range r.Providers {
+		if name := strings.ToLower(strings.TrimSpace(p)); name != p {
+			change(fmt.Sprintf("providers[%d]", i), p, name, "provider names are lowercased")
+			r.Providers[i] = name
+		}
+	}
+	r.normalizeAliases(change)
+	if r.PassengerMix != nil && r.Passengers != r.PassengerMix.Seats() {
+		change("passengers", r.Passengers, r.PassengerMix.Seats(), "set to the seats passenger_mix needs")
+		r.Passengers = r.PassengerMix.Seats()
+	}
+	if r.Passengers == 0 {
+		change("passengers", r.Passengers, 1, "defaulted to 1 passenger")
 		r.Passengers = 1
 	}
 	if r.CabinClass == "" {
-		r.CabinClass = "economy"
+		change("cabin_class", r.CabinClass, string(types.CabinEconomy), "defaulted to economy")
+		r.CabinClass = string(types.CabinEconomy)
+	} else if class, ok := cabin.ParseRequested(r.CabinClass); ok && string(class) != r.CabinClass {
+		change("cabin_class", r.CabinClass, string(class), "cabin classes are mapped to their canonical name")
+		r.CabinClass = string(class)
 	}
 	if r.SortBy == "" {
-		r.SortBy = "best_value"
+		change("sort_by", r.SortBy, string(types.SortBestValue), "defaulted to best_value")
+		r.SortBy = string(types.SortBestValue)
 	}
 	if r.SortOrder == "" {
-		r.SortOrder = "asc"
+		change("sort_order", r.SortOrder, string(types.SortAsc), "defaulted to asc")
+		r.SortOrder = string(types.SortAsc)
 	}
-	return nil
+	return changes
 }
 
-type ValidationError string
-
-func (e ValidationError) Error() string {
-	return string(e)
+// normalizeCabin defaults an empty cabin to economy and maps known labels
+// to their canonical name, leaving unknown ones for the search to reject.
+func normalizeCabin(label string) string {
+	if label == "" {
+		return string(cabin.Economy)
+	}
+	if class, ok := cabin.ParseRequested(label); ok {
+		return string(class)
+	}
+	return label
 }
 
 const (
-	ErrMissingOrigin        ValidationError = "origin is required"
-	ErrMissingDestination   ValidationError = "destination is required"
-	ErrMissingDepartureDate ValidationError = "departure_date is required"
+	ErrMissingOrigin        errorsx.ValidationError = "origin is required"
+	ErrMissingDestination   errorsx.ValidationError = "destination is required"
+	ErrMissingDepartureDate errorsx.ValidationError = "departure_date is required"
+
+	ErrNegativeRankingWeight errorsx.ValidationError = "ranking_weights must not be negative"
+	ErrRankingWeightsSum     errorsx.ValidationError = "ranking_weights must sum to 1.0"
+	ErrUnknownProvider       errorsx.ValidationError = "providers contains an unknown provider"
+	ErrUnknownStopsAlias     errorsx.ValidationError = "filters.stops must be one of direct, nonstop, one_stop"
 )
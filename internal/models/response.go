@@ -1,13 +1,84 @@
 package models
 
+import (
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
 type SearchMetadata struct {
 	TotalResults       int      `json:"total_results"`
 	ProvidersQueried   int      `json:"providers_queried"`
 	ProvidersSucceeded int      `json:"providers_succeeded"`
 	ProvidersFailed    int      `json:"providers_failed"`
 	FailedProviders    []string `json:"failed_providers,omitempty"`
-	SearchTimeMs       int64    `json:"search_time_ms"`
-	CacheHit           bool     `json:"cache_hit"`
+	// Degraded is set when the flights may be incomplete: a provider
+	// failed, its cached flights were too stale to serve, or a round trip's
+	// return leg failed.
+	Degraded     bool  `json:"degraded"`
+	SearchTimeMs int64 `json:"search_time_ms"`
+	CacheHit     bool  `json:"cache_hit"`
+	// NegativeCacheHit is set when the cache answered that an earlier
+	// search found no flights, without calling the providers.
+	NegativeCacheHit bool `json:"negative_cache_hit,omitempty"`
+
+	// SearchID identifies these results for replay; see SearchRequest.
+	SearchID string `json:"search_id,omitempty"`
+	// ResultsAsOf is when replayed results were first captured.
+	ResultsAsOf *time.Time `json:"results_as_of,omitempty"`
+	// ProviderCap is the most flights any one provider contributes, and
+	// TruncatedProviders how many each capped provider had beyond it.
+	ProviderCap        int            `json:"provider_cap,omitempty"`
+	TruncatedProviders map[string]int `json:"truncated_providers,omitempty"`
+	// StaleProviders had cached flights older than their freshness limit
+	// that could not be re-fetched; their flights are left out.
+	StaleProviders []string `json:"stale_providers,omitempty"`
+	// Fallbacks names, for failed providers whose quota was spent or whose
+	// circuit was open, the provider searched in their place.
+	Fallbacks map[string]string `json:"fallbacks,omitempty"`
+	// ProviderErrors says why each of FailedProviders failed.
+	ProviderErrors []ProviderError `json:"provider_errors,omitempty"`
+	// Region is the deployment region that served the search.
+	Region string `json:"region,omitempty"`
+	// Demo is set when the flights and prices are synthetic.
+	Demo bool `json:"demo,omitempty"`
+	// ConfigVersion identifies the configuration that served the search;
+	// see GET /admin/config/history.
+	ConfigVersion string `json:"config_version,omitempty"`
+	// Brownout is set when features were shed because of load.
+	Brownout *Brownout `json:"brownout,omitempty"`
+}
+
+// Classes of ProviderError.
+const (
+	// ProviderTimeout is a provider that didn't answer in time.
+	ProviderTimeout = "timeout"
+	// ProviderRateLimited is a provider this service didn't call, its rate
+	// limit leaving no call before the search timed out.
+	ProviderRateLimited = "rate_limited"
+	// ProviderUpstreamError is a provider that answered with an error.
+	ProviderUpstreamError = "upstream_error"
+	// ProviderCircuitOpen is a provider skipped after failing repeatedly.
+	ProviderCircuitOpen = "circuit_open"
+)
+
+type ProviderError struct {
+	Provider string `json:"provider"`
+	// Leg is "outbound" or "return" in round-trip searches.
+	Leg   string `json:"leg,omitempty"`
+	Class string `json:"class"`
+	// Retries counts the calls repeated after a failure.
+	Retries int `json:"retries"`
+	// ElapsedMs is the time spent on the provider, rate limit and retry
+	// waits included.
+	ElapsedMs int64 `json:"elapsed_ms"`
+}
+
+type Brownout struct {
+	Level int      `json:"level"`
+	Stage string   `json:"stage"`
+	Shed  []string `json:"shed"`
 }
 
 type SearchCriteria struct {
@@ -20,12 +91,29 @@ type SearchCriteria struct {
 	Filters       *SearchFilters `json:"filters,omitempty"`
 	SortBy        string         `json:"sort_by"`
 	SortOrder     string         `json:"sort_order"`
+	PassengerMix  *PassengerMix  `json:"passenger_mix,omitempty"`
+	Providers     []string       `json:"providers,omitempty"`
+	NearbyKm      int            `json:"nearby_km,omitempty"`
+
+	RankingWeights *RankingWeights `json:"ranking_weights,omitempty"`
+	PromoCode      string          `json:"promo_code,omitempty"`
+
+	IncludeGroundTransport bool `json:"include_ground_transport,omitempty"`
+	IncludeCrossSell       bool `json:"include_cross_sell,omitempty"`
+	IncludeFacets          bool `json:"include_facets,omitempty"`
 }
 
 type SearchResponse struct {
 	SearchCriteria SearchCriteria `json:"search_criteria"`
 	Metadata       SearchMetadata `json:"metadata"`
 	Flights        []Flight       `json:"flights"`
+	PriceTrend     *PriceTrend    `json:"price_trend,omitempty"`
+	Facets         *Facets        `json:"facets,omitempty"`
+
+	Normalizations []Normalization `json:"normalizations,omitempty"`
+
+	GroundTransport *GroundTransport `json:"ground_transport,omitempty"`
+	CrossSell       *CrossSell       `json:"cross_sell,omitempty"`
 }
 
 type RoundTripResponse struct {
@@ -33,10 +121,21 @@ type RoundTripResponse struct {
 	Metadata        SearchMetadata `json:"metadata"`
 	OutboundFlights []Flight       `json:"outbound_flights"`
 	ReturnFlights   []Flight       `json:"return_flights"`
+
+	Normalizations []Normalization `json:"normalizations,omitempty"`
+
+	GroundTransport *GroundTransport `json:"ground_transport,omitempty"`
+	CrossSell       *CrossSell       `json:"cross_sell,omitempty"`
 }
 
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Code    int    `json:"code"`
+	Error   types.ErrorCode `json:"error"`
+	Message string          `json:"message"`
+	Code    int             `json:"code"`
+
+	// Errors lists each invalid field of a validation_error.
+	Errors         []errorsx.FieldError `json:"errors,omitempty"`
+	Normalizations []Normalization      `json:"normalizations,omitempty"`
+	// Metadata reports what the providers of a failed search answered.
+	Metadata *SearchMetadata `json:"metadata,omitempty"`
 }
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+type SubscriptionRoute struct {
+	Origin        string `json:"origin"`
+	Destination   string `json:"destination"`
+	DepartureDate string `json:"departure_date"`
+	CabinClass    string `json:"cabin_class"`
+}
+
+func (r SubscriptionRoute) Key() string {
+	return r.Origin + "-" + r.Destination + ":" + r.DepartureDate + ":" + r.CabinClass
+}
+
+func (r SubscriptionRoute) SearchRequest() SearchRequest {
+	return SearchRequest{
+		Origin:        r.Origin,
+		Destination:   r.Destination,
+		DepartureDate: r.DepartureDate,
+		Passengers:    1,
+		CabinClass:    r.CabinClass,
+		SortBy:        string(types.SortPrice),
+		SortOrder:     string(types.SortAsc),
+	}
+}
+
+func SubscriptionRouteFor(req SearchRequest) SubscriptionRoute {
+	return SubscriptionRoute{
+		Origin:        strings.ToUpper(req.Origin),
+		Destination:   strings.ToUpper(req.Destination),
+		DepartureDate: req.DepartureDate,
+		CabinClass:    strings.ToLower(req.CabinClass),
+	}
+}
+
+type Subscription struct {
+	ID         string              `json:"id"`
+	Partner    string              `json:"partner"`
+	Routes     []SubscriptionRoute `json:"routes"`
+	WebhookURL string              `json:"webhook_url,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+func (s Subscription) Covers(route SubscriptionRoute) bool {
+	for _, r := range s.Routes {
+		if r.Key() == route.Key() {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateSubscriptionRequest struct {
+	Partner    string              `json:"partner"`
+	Routes     []SubscriptionRoute `json:"routes"`
+	WebhookURL string              `json:"webhook_url,omitempty"`
+}
+
+func (r *CreateSubscriptionRequest) Validate() error {
+	if r.Partner == "" {
+		return ErrMissingPartner
+	}
+	if len(r.Routes) == 0 {
+		return ErrMissingRoutes
+	}
+	for i := range r.Routes {
+		route := &r.Routes[i]
+		if route.Origin == "" {
+			return ErrMissingOrigin
+		}
+		if route.Destination == "" {
+			return ErrMissingDestination
+		}
+		if route.DepartureDate == "" {
+			return ErrMissingDepartureDate
+		}
+		if route.CabinClass == "" {
+			route.CabinClass = "economy"
+		}
+		route.Origin = strings.ToUpper(route.Origin)
+		route.Destination = strings.ToUpper(route.Destination)
+		route.CabinClass = strings.ToLower(route.CabinClass)
+	}
+	return nil
+}
+
+type FareEventType string
+
+const (
+	FareEventNewLowest   FareEventType = "new_lowest_price"
+	FareEventSoldOut     FareEventType = "sold_out"
+	FareEventBackInStock FareEventType = "back_in_stock"
+)
+
+type FareEvent struct {
+	ID             string            `json:"id"`
+	Type           FareEventType     `json:"type"`
+	Route          SubscriptionRoute `json:"route"`
+	FlightID       string            `json:"flight_id"`
+	Provider       string            `json:"provider"`
+	Price          float64           `json:"price,omitempty"`
+	PreviousPrice  *float64          `json:"previous_price,omitempty"`
+	AvailableSeats int               `json:"available_seats"`
+	OccurredAt     time.Time         `json:"occurred_at"`
+}
+
+const (
+	ErrMissingPartner errorsx.ValidationError = "partner is required"
+	ErrMissingRoutes  errorsx.ValidationError = "routes must not be empty"
+)
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/airports"
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
+	"github.com/dharmasatrya/flightsearch/internal/timezone"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+const (
+	MaxPassengers = 9
+	// BookingHorizonDays is how far ahead airlines open their schedules.
+	BookingHorizonDays = 330
+	// MaxNearbyKm bounds nearby airport searches to one metropolitan area.
+	MaxNearbyKm = 150
+
+	dateLayout = "2006-01-02"
+)
+
+var (
+	iataPattern      = regexp.MustCompile(`^[A-Z]{3}$`)
+	timeOfDayPattern = regexp.MustCompile(`^([01]?[0-9]|2[0-3]):[0-5][0-9]$`)
+	promoCode        = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+)
+
+// validateRoute checks the fields that identify a route and date. With
+// checkWindow the date must also be bookable: not before today at the
+// origin and within the booking horizon.
+func (r *SearchRequest) validateRoute(errs *errorsx.ValidationErrors, now time.Time, checkWindow bool) {
+	validateAirport(errs, "origin", r.Origin)
+	validateAirport(errs, "destination", r.Destination)
+	if r.Origin != "" && r.Origin == r.Destination {
+		errs.Add("destination", types.FieldSameAirport, "destination must differ from origin")
+	}
+
+	if _, ok := cabin.ParseRequested(r.CabinClass); !ok {
+		errs.Add("cabin_class", types.FieldUnknownValue, "cabin_class must be one of %s", strings.Join(cabin.Names(), ", "))
+	}
+
+	if r.DepartureDate == "" {
+		errs.Add("departure_date", types.FieldRequired, "%s", ErrMissingDepartureDate)
+		return
+	}
+	departure, err := time.Parse(dateLayout, r.DepartureDate)
+	if err != nil {
+		errs.Add("departure_date", types.FieldInvalidFormat, "departure_date must be a YYYY-MM-DD date")
+		return
+	}
+	if !checkWindow {
+		return
+	}
+
+	today, _ := time.Parse(dateLayout, timezone.ConvertToTimezone(now, r.Origin).Format(dateLayout))
+	if departure.Before(today) {
+		errs.Add("departure_date", types.FieldDateInPast, "departure_date must not be in the past")
+	} else if departure.After(today.AddDate(0, 0, BookingHorizonDays)) {
+		errs.Add("departure_date", types.FieldBeyondHorizon, "departure_date must be within %d days", BookingHorizonDays)
+	}
+
+	if r.ReturnDate != nil && *r.ReturnDate != "" {
+		ret, err := time.Parse(dateLayout, *r.ReturnDate)
+		switch {
+		case err != nil:
+			errs.Add("return_date", types.FieldInvalidFormat, "return_date must be a YYYY-MM-DD date")
+		case ret.Before(departure):
+			errs.Add("return_date", types.FieldReturnBeforeDeparture, "return_date must not be before departure_date")
+		case ret.After(today.AddDate(0, 0, BookingHorizonDays)):
+			errs.Add("return_date", types.FieldBeyondHorizon, "return_date must be within %d days", BookingHorizonDays)
+		}
+	}
+}
+
+func validateAirport(errs *errorsx.ValidationErrors, field, code string) {
+	switch {
+	case code == "":
+		errs.Add(field, types.FieldRequired, "%s is required", field)
+	case !iataPattern.MatchString(code):
+		errs.Add(field, types.FieldInvalidFormat, "%s must be a 3-letter IATA airport code", field)
+	case !airports.Known(code):
+		errs.Add(field, types.FieldUnknownAirport, "%s %s is not a supported airport", field, code)
+	}
+}
+
+func (r *SearchRequest) validateSort(errs *errorsx.ValidationErrors) {
+	for _, token := range strings.Split(r.SortBy, ",") {
+		token = strings.ToLower(strings.TrimSpace(token))
+		// Ranking profiles are checked by the handler, which knows them.
+		if strings.HasPrefix(token, "profile:") {
+			continue
+		}
+		field, order, hasOrder := strings.Cut(token, ":")
+		if !types.SortKey(field).Valid() {
+			errs.Add("sort_by", types.FieldUnknownValue, "sort_by field %q must be one of %s", field, strings.Join(sortKeyNames(), ", "))
+		}
+		if hasOrder && !types.SortOrder(order).Valid() {
+			errs.Add("sort_by", types.FieldUnknownValue, "sort_by order %q must be asc or desc", order)
+		}
+	}
+	if !types.SortOrder(strings.ToLower(r.SortOrder)).Valid() {
+		errs.Add("sort_order", types.FieldUnknownValue, "sort_order must be asc or desc")
+	}
+}
+
+func (f *SearchFilters) validate(errs *errorsx.ValidationErrors) {
+	nonNegative := func(field string, v *float64) {
+		if v != nil && *v < 0 {
+			errs.Add(field, types.FieldOutOfRange, "%s must not be negative", field)
+		}
+	}
+	nonNegativeInt := func(field string, v *int) {
+		if v != nil && *v < 0 {
+			errs.Add(field, types.FieldOutOfRange, "%s must not be negative", field)
+		}
+	}
+	timeOfDay := func(field string, v *string) {
+		if v != nil && !timeOfDayPattern.MatchString(*v) {
+			errs.Add(field, types.FieldInvalidFormat, "%s must be an HH:MM time", field)
+		}
+	}
+
+	nonNegative("filters.price_min", f.PriceMin)
+	nonNegative("filters.price_max", f.PriceMax)
+	if f.PriceMin != nil && f.PriceMax != nil && *f.PriceMin > *f.PriceMax {
+		errs.Add("filters.price_min", types.FieldInvalidRange, "filters.price_min must not exceed filters.price_max")
+	}
+	nonNegativeInt("filters.max_stops", f.MaxStops)
+	nonNegativeInt("filters.max_duration", f.MaxDuration)
+	nonNegative("filters.min_checked_baggage_kg", f.MinCheckedBaggageKg)
+	nonNegative("filters.min_cabin_baggage_kg", f.MinCabinBaggageKg)
+	if f.IncludeBagKg != nil && *f.IncludeBagKg <= 0 {
+		errs.Add("filters.include_bag_kg", types.FieldOutOfRange, "filters.include_bag_kg must be positive")
+	}
+	nonNegativeInt("filters.max_layover_minutes", f.MaxLayoverMinutes)
+	nonNegativeInt("filters.min_layover_minutes", f.MinLayoverMinutes)
+	if f.MinLayoverMinutes != nil && f.MaxLayoverMinutes != nil && *f.MinLayoverMinutes > *f.MaxLayoverMinutes {
+		errs.Add("filters.min_layover_minutes", types.FieldInvalidRange, "filters.min_layover_minutes must not exceed filters.max_layover_minutes")
+	}
+
+	if f.MinReliability != nil && (*f.MinReliability < 0 || *f.MinReliability > 100) {
+		errs.Add("filters.min_reliability", types.FieldOutOfRange, "filters.min_reliability must be between 0 and 100")
+	}
+
+	timeOfDay("filters.departure_time_min", f.DepartureTimeMin)
+	timeOfDay("filters.departure_time_max", f.DepartureTimeMax)
+	timeOfDay("filters.arrival_time_min", f.ArrivalTimeMin)
+	timeOfDay("filters.arrival_time_max", f.ArrivalTimeMax)
+	timeOfDay("filters.overnight_start", f.OvernightStart)
+	timeOfDay("filters.overnight_end", f.OvernightEnd)
+
+	if f.Stops != nil {
+		errs.Add("filters.stops", types.FieldUnknownValue, "%s", ErrUnknownStopsAlias)
+	}
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+// FareVerifyRequest names the search a flight came from, its provider and
+// the price the client was quoted, all as the search returned them.
+type FareVerifyRequest struct {
+	Origin        string `json:"origin"`
+	Destination   string `json:"destination"`
+	DepartureDate string `json:"departure_date"`
+	CabinClass    string `json:"cabin_class"`
+	Passengers    int    `json:"passengers"`
+	Provider      string `json:"provider"`
+	// Price is per passenger; its currency defaults to the flight's.
+	Price *Price `json:"price"`
+	// PromoCode is the one searched with, so the quoted discount is kept.
+	PromoCode string `json:"promo_code,omitempty"`
+}
+
+func (r *FareVerifyRequest) Validate() error {
+	if r.Origin == "" {
+		return ErrMissingOrigin
+	}
+	if r.Destination == "" {
+		return ErrMissingDestination
+	}
+	if r.DepartureDate == "" {
+		return ErrMissingDepartureDate
+	}
+	if r.Provider == "" {
+		return ErrMissingProvider
+	}
+	if r.Price == nil || r.Price.Amount <= 0 {
+		return ErrInvalidQuotedPrice
+	}
+	if r.Passengers <= 0 {
+		r.Passengers = 1
+	}
+	r.CabinClass = normalizeCabin(r.CabinClass)
+	r.Origin = strings.ToUpper(r.Origin)
+	r.Destination = strings.ToUpper(r.Destination)
+	r.Provider = strings.ToLower(r.Provider)
+	return nil
+}
+
+func (r FareVerifyRequest) SearchRequest() SearchRequest {
+	return SearchRequest{
+		Origin:        r.Origin,
+		Destination:   r.Destination,
+		DepartureDate: r.DepartureDate,
+		Passengers:    r.Passengers,
+		CabinClass:    r.CabinClass,
+		Providers:     []string{r.Provider},
+		PromoCode:     r.PromoCode,
+	}
+}
+
+const (
+	FareConfirmed    = "confirmed"
+	FarePriceChanged = "price_changed"
+	// FareSoldOut has fewer seats left than passengers, whatever the price.
+	FareSoldOut = "sold_out"
+)
+
+type FareVerification struct {
+	Status     string `json:"status"`
+	FlightID   string `json:"flight_id"`
+	Provider   string `json:"provider"`
+	Passengers int    `json:"passengers"`
+	// Flight is the flight as its provider prices it now.
+	Flight Flight `json:"flight"`
+	// PriceChange is set when the price differs from the one quoted.
+	PriceChange *FareChange `json:"price_change,omitempty"`
+	VerifiedAt  time.Time   `json:"verified_at"`
+}
+
+type FareChange struct {
+	From Price `json:"from"`
+	To   Price `json:"to"`
+	// Change is zero when the currencies differ.
+	Change        float64 `json:"change"`
+	ChangePercent float64 `json:"change_percent"`
+}
+
+const (
+	ErrMissingProvider    errorsx.ValidationError = "provider is required"
+	ErrInvalidQuotedPrice errorsx.ValidationError = "price.amount must be positive"
+)
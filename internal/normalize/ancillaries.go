@@ -0,0 +1,35 @@
+package normalize
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+func Bag(kg, amount float64, currencyCode string) models.BagOption {
+	return models.BagOption{Kg: kg, Price: Price(amount, currencyCode)}
+}
+
+// OptionalPrice is nil for an amount that isn't positive, which providers
+// send for extras they don't sell.
+func OptionalPrice(amount float64, currencyCode string) *models.Price {
+	if amount <= 0 {
+		return nil
+	}
+	p := Price(amount, currencyCode)
+	return &p
+}
+
+func ancillaries(bags []models.BagOption, seat, meal *models.Price) *models.Ancillaries {
+	bags = slices.DeleteFunc(bags, func(b models.BagOption) bool {
+		return b.Kg <= 0 || b.Price.Amount <= 0
+	})
+	if len(bags) == 0 && seat == nil && meal == nil {
+		return nil
+	}
+	slices.SortFunc(bags, func(a, b models.BagOption) int {
+		return cmp.Compare(a.Kg, b.Kg)
+	})
+	return &models.Ancillaries{CheckedBags: bags, SeatSelection: seat, Meal: meal}
+}
@@ -0,0 +1,180 @@
+// Package normalize builds the models.Flight every provider adapter
+// returns, so adapters only map their own fields. The builder converts
+// times to each airport's timezone, splits durations, leaves empty
+// terminals and aircraft unset and formats prices; the parsers read the
+// weights and durations providers send as text.
+package normalize
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/timezone"
+	"github.com/dharmasatrya/flightsearch/pkg/currency"
+)
+
+// Builder assembles one flight. Setters that can fail record the first
+// error, which Build returns, so adapters check once.
+type Builder struct {
+	flight      models.Flight
+	stops       *int
+	hasDuration bool
+	err         error
+}
+
+// New starts the flight provider returns with id.
+func New(provider, id string) *Builder {
+	return &Builder{flight: models.Flight{ID: id, Provider: provider}}
+}
+
+// Fail records err unless an earlier error was.
+func (b *Builder) Fail(err error) *Builder {
+	if b.err == nil {
+		b.err = err
+	}
+	return b
+}
+
+// Failf is Fail with a formatted error.
+func (b *Builder) Failf(format string, args ...any) *Builder {
+	return b.Fail(fmt.Errorf(format, args...))
+}
+
+// Time reads a timestamp with a UTC offset, or without one as local time
+// in tzName (UTC when empty). A timestamp that can't be read fails the
+// flight.
+func (b *Builder) Time(s, tzName string) time.Time {
+	t, err := timezone.ParseTimeWithOffset(s, tzName)
+	b.Fail(err)
+	return t
+}
+
+func (b *Builder) SetAirline(code, name string) *Builder {
+	b.flight.Airline = models.Airline{Code: code, Name: name}
+	return b
+}
+
+func (b *Builder) SetFlightNumber(number string) *Builder {
+	b.flight.FlightNumber = number
+	return b
+}
+
+// SetCabin maps the provider's cabin label to its class; an unknown label
+// fails the flight.
+func (b *Builder) SetCabin(label string) *Builder {
+	class, ok := cabin.Parse(label)
+	if !ok {
+		return b.Failf("unknown cabin class %q", label)
+	}
+	b.flight.CabinClass = string(class)
+	return b
+}
+
+// SetDeparture places the departure at airport, reporting t in the
+// airport's timezone. An empty terminal is left unset.
+func (b *Builder) SetDeparture(airport, city, terminal string, t time.Time) *Builder {
+	b.flight.Departure = location(airport, city, terminal, t)
+	return b
+}
+
+// SetArrival is SetDeparture for the arrival.
+func (b *Builder) SetArrival(airport, city, terminal string, t time.Time) *Builder {
+	b.flight.Arrival = location(airport, city, terminal, t)
+	return b
+}
+
+func location(airport, city, terminal string, t time.Time) models.Location {
+	l := models.Location{
+		Airport:  airport,
+		City:     city,
+		Time:     timezone.ConvertToTimezone(t, airport),
+		Timezone: timezone.GetTimezoneByAirport(airport),
+	}
+	if terminal != "" {
+		l.Terminal = &terminal
+	}
+	return l
+}
+
+// SetDuration is the trip's length as the provider reports it. Without
+// one, Build takes it from the departure and arrival times.
+func (b *Builder) SetDuration(minutes int) *Builder {
+	b.flight.Duration = duration(minutes)
+	b.hasDuration = true
+	return b
+}
+
+func duration(minutes int) models.Duration {
+	return models.Duration{Hours: minutes / 60, Minutes: minutes % 60, TotalMinutes: minutes}
+}
+
+func (b *Builder) AddLayover(airport, city string, minutes int) *Builder {
+	b.flight.Layovers = append(b.flight.Layovers, models.Layover{Airport: airport, City: city, Duration: minutes})
+	return b
+}
+
+// SetStops is for providers counting stops themselves; otherwise every
+// layover is a stop.
+func (b *Builder) SetStops(n int) *Builder {
+	b.stops = &n
+	return b
+}
+
+func (b *Builder) SetPrice(amount float64, currencyCode string) *Builder {
+	b.flight.Price = Price(amount, currencyCode)
+	return b
+}
+
+func (b *Builder) SetSeats(n int) *Builder {
+	b.flight.AvailableSeats = n
+	return b
+}
+
+// SetAircraft is the provider's description of the equipment; empty
+// leaves it unset.
+func (b *Builder) SetAircraft(name string) *Builder {
+	if name != "" {
+		b.flight.Aircraft = &name
+	}
+	return b
+}
+
+func (b *Builder) SetAmenities(codes []string) *Builder {
+	b.flight.Amenities = codes
+	return b
+}
+
+func (b *Builder) SetBaggage(cabinKg, checkedKg float64) *Builder {
+	b.flight.Baggage = models.Baggage{CabinKg: cabinKg, CheckedKg: checkedKg}
+	return b
+}
+
+// SetAncillaries lists the extras sold, leaving Ancillaries unset when
+// nothing is. Bags that aren't priced are dropped.
+func (b *Builder) SetAncillaries(bags []models.BagOption, seat, meal *models.Price) *Builder {
+	b.flight.Ancillaries = ancillaries(bags, seat, meal)
+	return b
+}
+
+// Build returns the flight, or the first error recorded.
+func (b *Builder) Build() (models.Flight, error) {
+	if b.err != nil {
+		return models.Flight{}, b.err
+	}
+	f := b.flight
+	if !b.hasDuration {
+		f.Duration = duration(int(f.Arrival.Time.Sub(f.Departure.Time).Minutes()))
+	}
+	f.Stops = len(f.Layovers)
+	if b.stops != nil {
+		f.Stops = *b.stops
+	}
+	return f, nil
+}
+
+// Price is amount in currencyCode, formatted for that currency.
+func Price(amount float64, currencyCode string) models.Price {
+	return models.Price{Amount: amount, Currency: currencyCode, Formatted: currency.Format(amount, currencyCode)}
+}
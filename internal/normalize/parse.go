@@ -0,0 +1,64 @@
+package normalize
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	kg        = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*kg`)
+	kgLabel   = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*kg\s*([a-z]+)`)
+	hoursMins = regexp.MustCompile(`^(?:(\d+)h)?\s*(?:(\d+)m)?$`)
+)
+
+// Kg reads the first weight in text such as "7 kg" or "Cabin baggage only
+// (7kg)", case-insensitively.
+func Kg(s string) (float64, bool) {
+	return firstKg(kg, s)
+}
+
+// LabelledKg reads the weight given before label, e.g. the checked
+// allowance of "7kg cabin, 20kg checked" with label "checked".
+func LabelledKg(s, label string) (float64, bool) {
+	label = strings.ToLower(label)
+	for _, m := range kgLabel.FindAllStringSubmatch(strings.ToLower(s), -1) {
+		if strings.HasPrefix(m[2], label) {
+			v, err := strconv.ParseFloat(m[1], 64)
+			return v, err == nil
+		}
+	}
+	return 0, false
+}
+
+func firstKg(re *regexp.Regexp, s string) (float64, bool) {
+	matches := re.FindStringSubmatch(strings.ToLower(s))
+	if matches == nil {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(matches[1], 64)
+	return v, err == nil
+}
+
+// HoursMinutes reads "1h 45m", "2h" or "50m" as minutes.
+func HoursMinutes(s string) (int, error) {
+	matches := hoursMins.FindStringSubmatch(strings.TrimSpace(s))
+	if matches == nil || matches[1] == "" && matches[2] == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	hours, _ := strconv.Atoi(matches[1])
+	mins, _ := strconv.Atoi(matches[2])
+	return hours*60 + mins, nil
+}
+
+// Clock reads "HH:MM" as minutes.
+func Clock(s string) (int, error) {
+	h, m, ok := strings.Cut(s, ":")
+	hours, err1 := strconv.Atoi(h)
+	mins, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return hours*60 + mins, nil
+}
@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/health"
+	"github.com/dharmasatrya/flightsearch/internal/scheduler"
+)
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// OutageNotifier posts Slack-compatible messages for provider health events.
+// Repeated events of the same kind for a provider are dropped until
+// MinInterval has passed, so a flapping provider doesn't flood the channel.
+type OutageNotifier struct {
+	url         string
+	minInterval time.Duration
+	client      *http.Client
+
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+func NewOutageNotifier(url string, minInterval time.Duration) *OutageNotifier {
+	return &OutageNotifier{
+		url:         url,
+		minInterval: minInterval,
+		client:      &http.Client{Timeout: 5 * time.Second},
+		sent:        make(map[string]time.Time),
+	}
+}
+
+func (n *OutageNotifier) Handle(e health.Event) {
+	key := e.Provider + ":" + string(e.Kind)
+
+	n.mu.Lock()
+	if last, ok := n.sent[key]; ok && e.At.Sub(last) < n.minInterval {
+		n.mu.Unlock()
+		return
+	}
+	n.sent[key] = e.At
+	n.mu.Unlock()
+
+	go func() {
+		if err := n.post(formatEvent(e)); err != nil {
+			log.Printf("Failed to send outage notification for %s: %v", e.Provider, err)
+		}
+	}()
+}
+
+// HandleJobFailure reports a failed background job run, with the same
+// per-job rate limiting as provider events.
+func (n *OutageNotifier) HandleJobFailure(s scheduler.JobStatus) {
+	key := "job:" + s.Name
+	now := time.Now()
+
+	n.mu.Lock()
+	if last, ok := n.sent[key]; ok && now.Sub(last) < n.minInterval {
+		n.mu.Unlock()
+		return
+	}
+	n.sent[key] = now
+	n.mu.Unlock()
+
+	text := fmt.Sprintf(":x: Background job *%s* failed (%d in a row): %s", s.Name, s.ConsecutiveFailures, s.LastError)
+	go func() {
+		if err := n.post(text); err != nil {
+			log.Printf("Failed to send job failure notification for %s: %v", s.Name, err)
+		}
+	}()
+}
+
+func (n *OutageNotifier) post(text string) error {
+	body, err := json.Marshal(slackPayload{Text: text})
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func formatEvent(e health.Event) string {
+	rate := e.Stats.FailureRate * 100
+	switch e.Kind {
+	case health.EventCircuitOpened:
+		return fmt.Sprintf(":rotating_light: Provider *%s* circuit opened - %.0f%% of %d recent calls failed (last error: %s)",
+			e.Provider, rate, e.Stats.Requests, e.Stats.LastError)
+	case health.EventDegraded:
+		return fmt.Sprintf(":warning: Provider *%s* is degraded - %.0f%% of %d recent calls failed (last error: %s)",
+			e.Provider, rate, e.Stats.Requests, e.Stats.LastError)
+	case health.EventRecovered:
+		return fmt.Sprintf(":white_check_mark: Provider *%s* recovered, circuit closed", e.Provider)
+	default:
+		return fmt.Sprintf("Provider %s: %s", e.Provider, e.Kind)
+	}
+}
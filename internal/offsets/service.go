@@ -0,0 +1,101 @@
+package offsets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/emissions"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/currency"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+const quoteValidity = 30 * time.Minute
+
+type Service struct {
+	supplier Supplier
+	store    Store
+}
+
+func NewService(supplier Supplier, store Store) *Service {
+	return &Service{supplier: supplier, store: store}
+}
+
+func (s *Service) Quote(ctx context.Context, flight models.Flight, passengers int) (models.OffsetQuote, error) {
+	kg := emissions.EstimatePerPassenger(flight) * float64(passengers)
+
+	sq, err := s.supplier.Quote(ctx, kg)
+	if err != nil {
+		return models.OffsetQuote{}, errorsx.Classify(err, errorsx.Unavailable)
+	}
+
+	now := time.Now()
+	q := models.OffsetQuote{
+		ID:           newID("oq_"),
+		FlightID:     flight.ID,
+		FlightNumber: flight.FlightNumber,
+		Origin:       flight.Departure.Airport,
+		Destination:  flight.Arrival.Airport,
+		Passengers:   passengers,
+		EmissionsKg:  kg,
+		Price: models.Price{
+			Amount:    sq.Amount,
+			Currency:  "IDR",
+			Formatted: currency.FormatIDR(sq.Amount),
+		},
+		Supplier:  s.supplier.Name(),
+		Project:   sq.Project,
+		CreatedAt: now,
+		ExpiresAt: now.Add(quoteValidity),
+	}
+
+	if err := s.store.SaveQuote(ctx, q); err != nil {
+		return models.OffsetQuote{}, err
+	}
+	return q, nil
+}
+
+// Purchase claims the quote before buying, so a quote is bought at most
+// once. If the supplier fails the quote is put back for a retry.
+func (s *Service) Purchase(ctx context.Context, req models.OffsetPurchaseRequest) (models.OffsetPurchase, error) {
+	q, err := s.store.ClaimQuote(ctx, req.QuoteID)
+	if err != nil {
+		return models.OffsetPurchase{}, err
+	}
+
+	cert, err := s.supplier.Purchase(ctx, q.EmissionsKg, q.Price.Amount)
+	if err != nil {
+		if time.Now().Before(q.ExpiresAt) {
+			if serr := s.store.SaveQuote(ctx, q); serr != nil {
+				log.Printf("Offset quote %s not restored after failed purchase: %v", q.ID, serr)
+			}
+		}
+		return models.OffsetPurchase{}, errorsx.Classify(err, errorsx.Unavailable)
+	}
+
+	p := models.OffsetPurchase{
+		ID:             newID("op_"),
+		Quote:          q,
+		CertificateRef: cert,
+		Reference:      req.Reference,
+		PurchasedAt:    time.Now(),
+	}
+
+	if err := s.store.SavePurchase(ctx, p); err != nil {
+		return models.OffsetPurchase{}, err
+	}
+	return p, nil
+}
+
+func (s *Service) GetPurchase(ctx context.Context, id string) (models.OffsetPurchase, error) {
+	return s.store.GetPurchase(ctx, id)
+}
+
+func newID(prefix string) string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
@@ -0,0 +1,98 @@
+package offsets
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// countingSupplier counts purchases and fails while fail is set.
+type countingSupplier struct {
+	purchases atomic.Int32
+	fail      atomic.Bool
+}
+
+func (s *countingSupplier) Name() string { return "test" }
+
+func (s *countingSupplier) Quote(ctx context.Context, kg float64) (SupplierQuote, error) {
+	return SupplierQuote{Amount: kg * 150, Project: "test"}, nil
+}
+
+func (s *countingSupplier) Purchase(ctx context.Context, kg, amount float64) (string, error) {
+	if s.fail.Load() {
+		return "", errors.New("supplier down")
+	}
+	s.purchases.Add(1)
+	return "CERT-1", nil
+}
+
+func TestPurchaseBuysQuoteOnce(t *testing.T) {
+	ctx := context.Background()
+	supplier := &countingSupplier{}
+	svc := NewService(supplier, NewMemoryStore())
+	q, err := svc.Quote(ctx, models.Flight{ID: "GA-001"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var ok atomic.Int32
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.Purchase(ctx, models.OffsetPurchaseRequest{QuoteID: q.ID}); err == nil {
+				ok.Add(1)
+			} else if !errors.Is(err, ErrQuoteNotFound) {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if ok.Load() != 1 || supplier.purchases.Load() != 1 {
+		t.Errorf("%d purchases succeeded and %d reached the supplier, want 1", ok.Load(), supplier.purchases.Load())
+	}
+}
+
+func TestPurchaseKeepsQuoteWhenSupplierFails(t *testing.T) {
+	ctx := context.Background()
+	supplier := &countingSupplier{}
+	svc := NewService(supplier, NewMemoryStore())
+	q, err := svc.Quote(ctx, models.Flight{ID: "GA-001"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	supplier.fail.Store(true)
+	if _, err := svc.Purchase(ctx, models.OffsetPurchaseRequest{QuoteID: q.ID}); err == nil {
+		t.Fatal("purchase succeeded with the supplier down")
+	}
+	supplier.fail.Store(false)
+	if _, err := svc.Purchase(ctx, models.OffsetPurchaseRequest{QuoteID: q.ID}); err != nil {
+		t.Errorf("retry after the supplier failed: %v", err)
+	}
+}
+
+func TestSaveQuoteDropsExpiredQuotes(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	expired := models.OffsetQuote{ID: "oq_old", ExpiresAt: time.Now().Add(-time.Minute)}
+	if err := store.SaveQuote(ctx, expired); err != nil {
+		t.Fatal(err)
+	}
+	store.lastSweep = time.Now().Add(-2 * quoteValidity)
+	if err := store.SaveQuote(ctx, models.OffsetQuote{ID: "oq_new", ExpiresAt: time.Now().Add(quoteValidity)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.quotes["oq_old"]; ok {
+		t.Error("expired quote was kept")
+	}
+	if _, ok := store.quotes["oq_new"]; !ok {
+		t.Error("new quote was dropped")
+	}
+}
@@ -0,0 +1,206 @@
+package offsets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+var (
+	ErrQuoteNotFound    = errorsx.New(errorsx.NotFound, "offset quote not found, expired or already used")
+	ErrPurchaseNotFound = errorsx.New(errorsx.NotFound, "offset purchase not found")
+)
+
+type Store interface {
+	SaveQuote(ctx context.Context, q models.OffsetQuote) error
+	// ClaimQuote removes and returns an unexpired quote, so that of
+	// concurrent purchases of one quote only one gets it.
+	ClaimQuote(ctx context.Context, id string) (models.OffsetQuote, error)
+	SavePurchase(ctx context.Context, p models.OffsetPurchase) error
+	GetPurchase(ctx context.Context, id string) (models.OffsetPurchase, error)
+	// SweepPurchases hands purchases made before the cutoff to archive and
+	// then removes them.
+	SweepPurchases(ctx context.Context, before time.Time, archive func(records []any) error) (int, error)
+}
+
+type MemoryStore struct {
+	mu        sync.RWMutex
+	quotes    map[string]models.OffsetQuote
+	purchases map[string]models.OffsetPurchase
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		quotes:    make(map[string]models.OffsetQuote),
+		purchases: make(map[string]models.OffsetPurchase),
+		lastSweep: time.Now(),
+	}
+}
+
+// SaveQuote also drops expired quotes, at most once per quote validity,
+// since most quotes are never purchased.
+func (s *MemoryStore) SaveQuote(ctx context.Context, q models.OffsetQuote) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > quoteValidity {
+		for id, old := range s.quotes {
+			if now.After(old.ExpiresAt) {
+				delete(s.quotes, id)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.quotes[q.ID] = q
+	return nil
+}
+
+func (s *MemoryStore) ClaimQuote(ctx context.Context, id string) (models.OffsetQuote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.quotes[id]
+	delete(s.quotes, id)
+	if !ok || time.Now().After(q.ExpiresAt) {
+		return models.OffsetQuote{}, ErrQuoteNotFound
+	}
+	return q, nil
+}
+
+func (s *MemoryStore) SavePurchase(ctx context.Context, p models.OffsetPurchase) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purchases[p.ID] = p
+	return nil
+}
+
+func (s *MemoryStore) GetPurchase(ctx context.Context, id string) (models.OffsetPurchase, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.purchases[id]
+	if !ok {
+		return models.OffsetPurchase{}, ErrPurchaseNotFound
+	}
+	return p, nil
+}
+
+func (s *MemoryStore) SweepPurchases(ctx context.Context, before time.Time, archive func(records []any) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	var records []any
+	for id, p := range s.purchases {
+		if p.PurchasedAt.Before(before) {
+			ids = append(ids, id)
+			records = append(records, p)
+		}
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+	if err := archive(records); err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		delete(s.purchases, id)
+	}
+	return len(ids), nil
+}
+
+const (
+	redisQuotePrefix  = "offset:quote:"
+	redisPurchasesKey = "offset:purchases"
+)
+
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) SaveQuote(ctx context.Context, q models.OffsetQuote) error {
+	data, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisQuotePrefix+q.ID, data, time.Until(q.ExpiresAt)).Err()
+}
+
+func (s *RedisStore) ClaimQuote(ctx context.Context, id string) (models.OffsetQuote, error) {
+	data, err := s.client.GetDel(ctx, redisQuotePrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return models.OffsetQuote{}, ErrQuoteNotFound
+	}
+	if err != nil {
+		return models.OffsetQuote{}, err
+	}
+
+	var q models.OffsetQuote
+	if err := json.Unmarshal(data, &q); err != nil {
+		return models.OffsetQuote{}, err
+	}
+	return q, nil
+}
+
+func (s *RedisStore) SavePurchase(ctx context.Context, p models.OffsetPurchase) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, redisPurchasesKey, p.ID, data).Err()
+}
+
+func (s *RedisStore) GetPurchase(ctx context.Context, id string) (models.OffsetPurchase, error) {
+	data, err := s.client.HGet(ctx, redisPurchasesKey, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return models.OffsetPurchase{}, ErrPurchaseNotFound
+	}
+	if err != nil {
+		return models.OffsetPurchase{}, err
+	}
+
+	var p models.OffsetPurchase
+	if err := json.Unmarshal(data, &p); err != nil {
+		return models.OffsetPurchase{}, err
+	}
+	return p, nil
+}
+
+func (s *RedisStore) SweepPurchases(ctx context.Context, before time.Time, archive func(records []any) error) (int, error) {
+	values, err := s.client.HGetAll(ctx, redisPurchasesKey).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []string
+	var records []any
+	for id, v := range values {
+		var p models.OffsetPurchase
+		if err := json.Unmarshal([]byte(v), &p); err != nil {
+			continue
+		}
+		if p.PurchasedAt.Before(before) {
+			ids = append(ids, id)
+			records = append(records, p)
+		}
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+	if err := archive(records); err != nil {
+		return 0, err
+	}
+	return len(ids), s.client.HDel(ctx, redisPurchasesKey, ids...).Err()
+}
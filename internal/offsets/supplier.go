@@ -0,0 +1,52 @@
+package offsets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"math"
+	"strings"
+)
+
+type SupplierQuote struct {
+	PricePerTonne float64
+	Amount        float64
+	Project       string
+}
+
+type Supplier interface {
+	Name() string
+	Quote(ctx context.Context, kgCO2 float64) (SupplierQuote, error)
+	Purchase(ctx context.Context, kgCO2 float64, amount float64) (certificateRef string, err error)
+}
+
+type MockSupplier struct {
+	pricePerTonne float64
+	project       string
+}
+
+func NewMockSupplier(pricePerTonne float64) *MockSupplier {
+	return &MockSupplier{
+		pricePerTonne: pricePerTonne,
+		project:       "Katingan Mentaya Peatland Restoration",
+	}
+}
+
+func (s *MockSupplier) Name() string {
+	return "mock"
+}
+
+func (s *MockSupplier) Quote(ctx context.Context, kgCO2 float64) (SupplierQuote, error) {
+	amount := math.Ceil(kgCO2 / 1000 * s.pricePerTonne)
+	return SupplierQuote{
+		PricePerTonne: s.pricePerTonne,
+		Amount:        amount,
+		Project:       s.project,
+	}, nil
+}
+
+func (s *MockSupplier) Purchase(ctx context.Context, kgCO2 float64, amount float64) (string, error) {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return "CERT-" + strings.ToUpper(hex.EncodeToString(b)), nil
+}
@@ -0,0 +1,158 @@
+// Package ontime provides airlines' on-time performance and the
+// reliability score derived from it. The service ships with a static
+// dataset; other sources plug in through Source.
+package ontime
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Basis says what a performance figure was measured over.
+type Basis string
+
+const (
+	// BasisRoute is the airline's record on the flight's route.
+	BasisRoute Basis = "route"
+	// BasisAirline is the airline's record across its network, used when
+	// the route has no figures of its own.
+	BasisAirline Basis = "airline"
+)
+
+type Performance struct {
+	// OnTimePct is the share of flights arriving within 15 minutes of
+	// schedule, 0-100.
+	OnTimePct float64
+	// AvgDelayMinutes is the average arrival delay of the late flights.
+	AvgDelayMinutes int
+	Basis           Basis
+}
+
+// Source looks up an airline's on-time performance on a route.
+type Source interface {
+	Lookup(airline, origin, destination string) (Performance, bool)
+}
+
+// delayPointMinutes is how many minutes of average delay cost one point of
+// reliability: two airlines late equally often are not equally reliable
+// when one is late by ten minutes and the other by an hour.
+const delayPointMinutes = 6
+
+// Score is a reliability score from 0 to 100: the on-time percentage, less
+// a point for every delayPointMinutes of average delay.
+func Score(p Performance) int {
+	score := p.OnTimePct - float64(p.AvgDelayMinutes)/delayPointMinutes
+	return int(math.Round(math.Max(0, math.Min(100, score))))
+}
+
+// Reliability is the flight's reliability from its airline's performance
+// on its route, or nil when source has no figures for the airline.
+func Reliability(source Source, f models.Flight) *models.Reliability {
+	if source == nil {
+		return nil
+	}
+	p, ok := source.Lookup(f.Airline.Code, f.Departure.Airport, f.Arrival.Airport)
+	if !ok {
+		return nil
+	}
+	return &models.Reliability{
+		Score:           Score(p),
+		OnTimePct:       p.OnTimePct,
+		AvgDelayMinutes: p.AvgDelayMinutes,
+		Basis:           string(p.Basis),
+	}
+}
+
+type route struct {
+	airline, origin, destination string
+}
+
+// Static is a fixed table of performance figures by airline and route.
+type Static struct {
+	routes   map[route]Performance
+	airlines map[string]Performance
+}
+
+//go:embed ontime.csv
+var builtinCSV []byte
+
+var builtin *Static
+
+func init() {
+	var err error
+	if builtin, err = Parse(bytes.NewReader(builtinCSV)); err != nil {
+		panic(err)
+	}
+}
+
+// Builtin returns the dataset the service ships with.
+func Builtin() *Static {
+	return builtin
+}
+
+// Load reads a dataset in the format of Parse from a file.
+func Load(path string) (*Static, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads rows of airline, origin, destination, on-time percentage and
+// average delay in minutes, after a header. A row without origin and
+// destination is the airline's network-wide record.
+func Parse(r io.Reader) (*Static, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("on-time data: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("on-time data: missing header")
+	}
+	s := &Static{routes: make(map[route]Performance), airlines: make(map[string]Performance)}
+	for i, rec := range records[1:] {
+		line := i + 2
+		if len(rec) != 5 || rec[0] == "" || (rec[1] == "") != (rec[2] == "") {
+			return nil, fmt.Errorf("on-time data line %d: want airline, origin, destination, on_time_pct, avg_delay_minutes", line)
+		}
+		pct, err := strconv.ParseFloat(rec[3], 64)
+		if err != nil || pct < 0 || pct > 100 {
+			return nil, fmt.Errorf("on-time data line %d: on_time_pct must be between 0 and 100", line)
+		}
+		delay, err := strconv.Atoi(rec[4])
+		if err != nil || delay < 0 {
+			return nil, fmt.Errorf("on-time data line %d: avg_delay_minutes must be a non-negative integer", line)
+		}
+
+		airline := strings.ToUpper(rec[0])
+		if rec[1] == "" {
+			s.airlines[airline] = Performance{OnTimePct: pct, AvgDelayMinutes: delay, Basis: BasisAirline}
+			continue
+		}
+		k := route{airline, strings.ToUpper(rec[1]), strings.ToUpper(rec[2])}
+		s.routes[k] = Performance{OnTimePct: pct, AvgDelayMinutes: delay, Basis: BasisRoute}
+	}
+	return s, nil
+}
+
+// Lookup prefers the airline's record on the route and falls back to its
+// network-wide record.
+func (s *Static) Lookup(airline, origin, destination string) (Performance, bool) {
+	airline = strings.ToUpper(airline)
+	if p, ok := s.routes[route{airline, strings.ToUpper(origin), strings.ToUpper(destination)}]; ok {
+		return p, true
+	}
+	p, ok := s.airlines[airline]
+	return p, ok
+}
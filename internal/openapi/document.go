@@ -0,0 +1,234 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Style    string  `json:"style,omitempty"`
+	Explode  *bool   `json:"explode,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Op describes a route. Body and response values are zero values of the
+// structs the handler binds and returns; Query values are structs whose
+// scalar and list fields become query parameters. Headers are optional
+// request headers.
+type Op struct {
+	Summary     string
+	Tag         string
+	Body        any
+	Query       []any
+	QueryParams []string
+	Headers     []string
+	Responses   map[int]any
+	// ContentType overrides application/json for successful responses.
+	ContentType string
+	Auth        bool
+}
+
+// OneOf documents a response that is one of several structs.
+type OneOf []any
+
+// NoContent documents a response without a body.
+type NoContent struct{}
+
+const bearerAuth = "bearerAuth"
+
+type Builder struct {
+	doc       Document
+	names     map[reflect.Type]string
+	errorType reflect.Type
+}
+
+func NewBuilder(title, version string) *Builder {
+	return &Builder{
+		doc: Document{
+			OpenAPI: "3.0.3",
+			Info:    Info{Title: title, Version: version},
+			Paths:   make(map[string]map[string]*Operation),
+			Components: Components{
+				Schemas: make(map[string]*Schema),
+			},
+		},
+		names: make(map[reflect.Type]string),
+	}
+}
+
+// SetErrorBody documents v as the default (error) response of every
+// operation added afterwards.
+func (b *Builder) SetErrorBody(v any) {
+	b.errorType = reflect.TypeOf(v)
+}
+
+func (b *Builder) Document() Document {
+	return b.doc
+}
+
+// Add documents a route. Echo path parameters (":id") become {id}.
+func (b *Builder) Add(method, path string, op Op) {
+	var params []Parameter
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if name, ok := strings.CutPrefix(seg, ":"); ok {
+			segments[i] = "{" + name + "}"
+			params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	path = strings.Join(segments, "/")
+
+	for _, q := range op.Query {
+		params = append(params, b.queryParams(reflect.TypeOf(q))...)
+	}
+	for _, name := range op.QueryParams {
+		params = append(params, Parameter{Name: name, In: "query", Schema: &Schema{Type: "string"}})
+	}
+	for _, name := range op.Headers {
+		params = append(params, Parameter{Name: name, In: "header", Schema: &Schema{Type: "string"}})
+	}
+
+	o := &Operation{
+		OperationID: operationID(method, path),
+		Summary:     op.Summary,
+		Parameters:  params,
+		Responses:   make(map[string]*Response),
+	}
+	if op.Tag != "" {
+		o.Tags = []string{op.Tag}
+	}
+	if op.Body != nil {
+		o.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: b.schemaFor(reflect.TypeOf(op.Body))}},
+		}
+	}
+
+	contentType := op.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	for status, v := range op.Responses {
+		r := &Response{Description: http.StatusText(status)}
+		switch body := v.(type) {
+		case NoContent:
+		case OneOf:
+			s := &Schema{}
+			for _, alt := range body {
+				s.OneOf = append(s.OneOf, b.schemaFor(reflect.TypeOf(alt)))
+			}
+			r.Content = map[string]*MediaType{contentType: {Schema: s}}
+		default:
+			r.Content = map[string]*MediaType{contentType: {Schema: b.schemaFor(reflect.TypeOf(v))}}
+		}
+		o.Responses[strconv.Itoa(status)] = r
+	}
+	if b.errorType != nil {
+		o.Responses["default"] = &Response{
+			Description: "Error",
+			Content:     map[string]*MediaType{"application/json": {Schema: b.schemaFor(b.errorType)}},
+		}
+	}
+
+	if op.Auth {
+		o.Security = []map[string][]string{{bearerAuth: {}}}
+		if b.doc.Components.SecuritySchemes == nil {
+			b.doc.Components.SecuritySchemes = map[string]*SecurityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer"},
+			}
+		}
+	}
+
+	if b.doc.Paths[path] == nil {
+		b.doc.Paths[path] = make(map[string]*Operation)
+	}
+	b.doc.Paths[path][strings.ToLower(method)] = o
+}
+
+// queryParams flattens a struct's scalar and list fields into query
+// parameters; lists are comma-separated.
+func (b *Builder) queryParams(t reflect.Type) []Parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var params []Parameter
+	for _, f := range jsonFields(t) {
+		s := b.schemaFor(f.typ)
+		if s.Ref != "" || s.Type == "object" {
+			continue
+		}
+		p := Parameter{Name: f.name, In: "query", Schema: s}
+		if s.Type == "array" {
+			explode := false
+			p.Style = "form"
+			p.Explode = &explode
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// operationID turns "GET /api/v1/flights/{id}/lock" into
+// "getApiV1FlightsIdLock".
+func operationID(method, path string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '-' || r == '_'
+	}) {
+		sb.WriteString(upperFirst(part))
+	}
+	return sb.String()
+}
@@ -0,0 +1,110 @@
+package openapi
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+type routeAdder interface {
+	Add(method, path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+}
+
+// Router registers echo routes and documents them in one call, so the
+// spec can't list a route the server doesn't have.
+type Router struct {
+	spec   *Builder
+	routes routeAdder
+	prefix string
+	auth   bool
+	mw     []echo.MiddlewareFunc
+}
+
+// Router wraps an echo instance or group; prefix must match the group's.
+func (b *Builder) Router(routes routeAdder, prefix string) *Router {
+	return &Router{spec: b, routes: routes, prefix: prefix}
+}
+
+// WithAuth marks every route added through the returned router as
+// requiring the bearer token.
+func (r *Router) WithAuth() *Router {
+	authed := *r
+	authed.auth = true
+	return &authed
+}
+
+// With runs mw for every route added through the returned router.
+func (r *Router) With(mw ...echo.MiddlewareFunc) *Router {
+	wrapped := *r
+	wrapped.mw = append(slices.Clip(r.mw), mw...)
+	return &wrapped
+}
+
+func (r *Router) GET(path string, h echo.HandlerFunc, op Op) {
+	r.add(http.MethodGet, path, h, op)
+}
+
+func (r *Router) POST(path string, h echo.HandlerFunc, op Op) {
+	r.add(http.MethodPost, path, h, op)
+}
+
+func (r *Router) PUT(path string, h echo.HandlerFunc, op Op) {
+	r.add(http.MethodPut, path, h, op)
+}
+
+func (r *Router) DELETE(path string, h echo.HandlerFunc, op Op) {
+	r.add(http.MethodDelete, path, h, op)
+}
+
+func (r *Router) add(method, path string, h echo.HandlerFunc, op Op) {
+	r.routes.Add(method, path, h, r.mw...)
+	op.Auth = op.Auth || r.auth
+	r.spec.Add(method, r.prefix+path, op)
+}
+
+// Handler serves the document. It is encoded on first use, after all
+// routes were registered.
+func (b *Builder) Handler() echo.HandlerFunc {
+	var once sync.Once
+	var body []byte
+	var err error
+	return func(c echo.Context) error {
+		once.Do(func() {
+			body, err = json.Marshal(b.Document())
+		})
+		if err != nil {
+			return err
+		}
+		return c.JSONBlob(http.StatusOK, body)
+	}
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{TITLE}}</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => { window.ui = SwaggerUIBundle({ url: "{{SPEC_URL}}", dom_id: "#swagger-ui" }); };
+  </script>
+</body>
+</html>`
+
+// SwaggerUI serves a Swagger UI page (assets from unpkg) for the document
+// at specURL.
+func (b *Builder) SwaggerUI(specURL string) echo.HandlerFunc {
+	page := strings.NewReplacer("{{TITLE}}", html.EscapeString(b.doc.Info.Title), "{{SPEC_URL}}", specURL).Replace(swaggerUIPage)
+	return func(c echo.Context) error {
+		return c.HTML(http.StatusOK, page)
+	}
+}
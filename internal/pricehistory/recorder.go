@@ -0,0 +1,69 @@
+package pricehistory
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Recorder stores the cheapest fare of every aggregator run.
+type Recorder struct {
+	store Store
+}
+
+func NewRecorder(store Store) *Recorder {
+	return &Recorder{store: store}
+}
+
+func (r *Recorder) ObserveSearch(ctx context.Context, req models.SearchRequest, result *aggregator.Result) {
+	// A search limited to some providers doesn't tell us the route's low.
+	if len(result.Flights) == 0 || req.RestrictsProviders() {
+		return
+	}
+
+	cheapest := result.Flights[0]
+	for _, f := range result.Flights[1:] {
+		if f.Price.Amount < cheapest.Price.Amount {
+			cheapest = f
+		}
+	}
+
+	point := models.PricePoint{
+		Timestamp: time.Now(),
+		MinPrice:  cheapest.Price.Amount,
+		FlightID:  cheapest.ID,
+		Provider:  cheapest.Provider,
+	}
+	if err := r.store.Record(ctx, NewRouteKey(req), point); err != nil {
+		log.Printf("Failed to record price history: %v", err)
+	}
+}
+
+// Downsample keeps the lowest price per interval bucket.
+func Downsample(points []models.PricePoint, interval time.Duration) []models.PricePoint {
+	if interval <= 0 || len(points) == 0 {
+		return points
+	}
+
+	result := make([]models.PricePoint, 0)
+	var bucket time.Time
+	for _, p := range points {
+		b := p.Timestamp.Truncate(interval)
+		if len(result) == 0 || !b.Equal(bucket) {
+			bucket = b
+			p.Timestamp = b
+			result = append(result, p)
+			continue
+		}
+		last := &result[len(result)-1]
+		if p.MinPrice < last.MinPrice {
+			last.MinPrice = p.MinPrice
+			last.FlightID = p.FlightID
+			last.Provider = p.Provider
+		}
+	}
+	return result
+}
@@ -0,0 +1,235 @@
+package pricehistory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Points kept per route key in memory; Redis keeps everything within the TTL.
+const maxMemoryPoints = 5000
+
+type RouteKey struct {
+	Origin        string
+	Destination   string
+	DepartureDate string
+	CabinClass    string
+}
+
+func NewRouteKey(req models.SearchRequest) RouteKey {
+	return RouteKey{
+		Origin:        strings.ToUpper(req.Origin),
+		Destination:   strings.ToUpper(req.Destination),
+		DepartureDate: req.DepartureDate,
+		CabinClass:    strings.ToLower(req.CabinClass),
+	}
+}
+
+func (k RouteKey) String() string {
+	return k.Origin + ":" + k.Destination + ":" + k.DepartureDate + ":" + k.CabinClass
+}
+
+type Store interface {
+	Record(ctx context.Context, key RouteKey, point models.PricePoint) error
+	Range(ctx context.Context, key RouteKey, from, to time.Time) ([]models.PricePoint, error)
+	// Sweep hands points recorded before the cutoff to archive and then
+	// removes them.
+	Sweep(ctx context.Context, before time.Time, archive func(records []any) error) (int, error)
+}
+
+// ArchivedPoint is the record written to cold storage for a swept point.
+type ArchivedPoint struct {
+	Route string `json:"route"`
+	models.PricePoint
+}
+
+type MemoryStore struct {
+	mu     sync.RWMutex
+	points map[RouteKey][]models.PricePoint
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{points: make(map[RouteKey][]models.PricePoint)}
+}
+
+func (s *MemoryStore) Record(ctx context.Context, key RouteKey, point models.PricePoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	points := append(s.points[key], point)
+	if len(points) > maxMemoryPoints {
+		points = points[len(points)-maxMemoryPoints:]
+	}
+	s.points[key] = points
+	return nil
+}
+
+func (s *MemoryStore) Range(ctx context.Context, key RouteKey, from, to time.Time) ([]models.PricePoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]models.PricePoint, 0)
+	for _, p := range s.points[key] {
+		if p.Timestamp.Before(from) || p.Timestamp.After(to) {
+			continue
+		}
+		result = append(result, p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+	return result, nil
+}
+
+func (s *MemoryStore) Sweep(ctx context.Context, before time.Time, archive func(records []any) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []any
+	for key, points := range s.points {
+		for _, p := range points {
+			if p.Timestamp.Before(before) {
+				records = append(records, ArchivedPoint{Route: key.String(), PricePoint: p})
+			}
+		}
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+	if err := archive(records); err != nil {
+		return 0, err
+	}
+
+	for key, points := range s.points {
+		kept := points[:0]
+		for _, p := range points {
+			if !p.Timestamp.Before(before) {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.points, key)
+		} else {
+			s.points[key] = kept
+		}
+	}
+	return len(records), nil
+}
+
+type RedisStore struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+func NewRedisStore(client redis.UniversalClient, ttl time.Duration) *RedisStore {
+	return &RedisStore{client: client, ttl: ttl}
+}
+
+func (s *RedisStore) Record(ctx context.Context, key RouteKey, point models.PricePoint) error {
+	data, err := json.Marshal(point)
+	if err != nil {
+		return err
+	}
+
+	redisKey := "pricehistory:" + key.String()
+	pipe := s.client.TxPipeline()
+	pipe.ZAdd(ctx, redisKey, redis.Z{
+		Score:  float64(point.Timestamp.UnixMilli()),
+		Member: data,
+	})
+	pipe.Expire(ctx, redisKey, s.ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) Range(ctx context.Context, key RouteKey, from, to time.Time) ([]models.PricePoint, error) {
+	values, err := s.client.ZRangeByScore(ctx, "pricehistory:"+key.String(), &redis.ZRangeBy{
+		Min: strconv.FormatInt(from.UnixMilli(), 10),
+		Max: strconv.FormatInt(to.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.PricePoint, 0, len(values))
+	for _, v := range values {
+		var p models.PricePoint
+		if err := json.Unmarshal([]byte(v), &p); err != nil {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+func (s *RedisStore) Sweep(ctx context.Context, before time.Time, archive func(records []any) error) (int, error) {
+	max := "(" + strconv.FormatInt(before.UnixMilli(), 10)
+
+	keys, err := s.keys(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var records []any
+	for _, key := range keys {
+		values, err := s.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: "-inf", Max: max}).Result()
+		if err != nil {
+			return 0, err
+		}
+		route := strings.TrimPrefix(key, "pricehistory:")
+		for _, v := range values {
+			var p models.PricePoint
+			if err := json.Unmarshal([]byte(v), &p); err != nil {
+				continue
+			}
+			records = append(records, ArchivedPoint{Route: route, PricePoint: p})
+		}
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+	if err := archive(records); err != nil {
+		return 0, err
+	}
+
+	for _, key := range keys {
+		if err := s.client.ZRemRangeByScore(ctx, key, "-inf", max).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return len(records), nil
+}
+
+// keys lists every route's key. A Redis Cluster spreads them over its
+// masters, and SCAN only sees one node's keys, so each master is scanned.
+func (s *RedisStore) keys(ctx context.Context) ([]string, error) {
+	var mu sync.Mutex
+	var keys []string
+	scan := func(ctx context.Context, client *redis.Client) error {
+		iter := client.Scan(ctx, 0, "pricehistory:*", 100).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			keys = append(keys, iter.Val())
+			mu.Unlock()
+		}
+		return iter.Err()
+	}
+
+	var err error
+	switch c := s.client.(type) {
+	case *redis.ClusterClient:
+		err = c.ForEachMaster(ctx, scan)
+	case *redis.Client:
+		err = scan(ctx, c)
+	}
+	return keys, err
+}
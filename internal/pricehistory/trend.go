@@ -0,0 +1,55 @@
+package pricehistory
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+const (
+	TrendRising  = "rising"
+	TrendFalling = "falling"
+	TrendStable  = "stable"
+)
+
+// Changes smaller than this percentage are reported as stable.
+const stableThresholdPercent = 3.0
+
+// Trend compares the daily low at the start of the window with the latest
+// observed daily low. Returns nil when there isn't enough history yet.
+func Trend(ctx context.Context, store Store, key RouteKey, windowDays int) (*models.PriceTrend, error) {
+	now := time.Now()
+	points, err := store.Range(ctx, key, now.AddDate(0, 0, -windowDays), now)
+	if err != nil {
+		return nil, err
+	}
+
+	daily := Downsample(points, 24*time.Hour)
+	if len(daily) < 2 {
+		return nil, nil
+	}
+
+	first := daily[0].MinPrice
+	last := daily[len(daily)-1].MinPrice
+	if first <= 0 {
+		return nil, nil
+	}
+
+	change := math.Round((last-first)/first*10000) / 100
+
+	direction := TrendStable
+	if change >= stableThresholdPercent {
+		direction = TrendRising
+	} else if change <= -stableThresholdPercent {
+		direction = TrendFalling
+	}
+
+	return &models.PriceTrend{
+		Direction:     direction,
+		ChangePercent: change,
+		WindowDays:    windowDays,
+		Samples:       len(points),
+	}, nil
+}
@@ -0,0 +1,218 @@
+// Package promotions discounts the fares shown to searchers by campaign
+// rules: a percentage off, optionally capped, for chosen airlines, routes
+// and cabins while the campaign runs, some only for searches with a promo
+// code. Providers' prices are never changed; the discount is applied to
+// each response.
+package promotions
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/currency"
+)
+
+var (
+	airlineCode = regexp.MustCompile(`^[A-Z0-9]{2}$`)
+	route       = regexp.MustCompile(`^[A-Z]{3}-[A-Z]{3}$`)
+)
+
+// Rule is one campaign. Airlines, Routes ("CGK-DPS", one direction) and
+// CabinClasses narrow it down; each matches everything when empty.
+type Rule struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// Code limits the rule to searches sending it as promo_code, compared
+	// case-insensitively.
+	Code         string   `json:"code,omitempty"`
+	Airlines     []string `json:"airlines,omitempty"`
+	Routes       []string `json:"routes,omitempty"`
+	CabinClasses []string `json:"cabin_classes,omitempty"`
+
+	PercentOff float64 `json:"percent_off"`
+	// MaxDiscount caps the discount per passenger; zero means no cap.
+	MaxDiscount float64 `json:"max_discount,omitempty"`
+	// Currency is that of the fares discounted, IDR when unset.
+	Currency string `json:"currency,omitempty"`
+
+	// StartsAt and EndsAt bound when searches see the rule; either may be
+	// unset.
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+// normalize uppercases codes and canonicalizes cabins, reporting the
+// first problem.
+func (r *Rule) normalize() error {
+	if r.ID == "" {
+		return fmt.Errorf("rule has no id")
+	}
+	if r.PercentOff <= 0 || r.PercentOff > 100 {
+		return fmt.Errorf("rule %s: percent_off must be above 0 and at most 100", r.ID)
+	}
+	if r.MaxDiscount < 0 {
+		return fmt.Errorf("rule %s: max_discount must not be negative", r.ID)
+	}
+	if r.StartsAt != nil && r.EndsAt != nil && !r.EndsAt.After(*r.StartsAt) {
+		return fmt.Errorf("rule %s: ends_at must be after starts_at", r.ID)
+	}
+	r.Currency = strings.ToUpper(r.Currency)
+	if r.Currency == "" {
+		r.Currency = "IDR"
+	}
+	for i, a := range r.Airlines {
+		r.Airlines[i] = strings.ToUpper(a)
+		if !airlineCode.MatchString(r.Airlines[i]) {
+			return fmt.Errorf("rule %s: invalid airline code %q", r.ID, a)
+		}
+	}
+	for i, rt := range r.Routes {
+		r.Routes[i] = strings.ToUpper(rt)
+		if !route.MatchString(r.Routes[i]) {
+			return fmt.Errorf("rule %s: invalid route %q, expected e.g. CGK-DPS", r.ID, rt)
+		}
+	}
+	for i, c := range r.CabinClasses {
+		class, ok := cabin.Parse(c)
+		if !ok {
+			return fmt.Errorf("rule %s: unknown cabin class %q", r.ID, c)
+		}
+		r.CabinClasses[i] = string(class)
+	}
+	return nil
+}
+
+// Active reports whether searches at now see the rule.
+func (r Rule) Active(now time.Time) bool {
+	return (r.StartsAt == nil || !now.Before(*r.StartsAt)) && (r.EndsAt == nil || now.Before(*r.EndsAt))
+}
+
+func (r Rule) matches(f models.Flight, code string) bool {
+	if r.Code != "" && !strings.EqualFold(r.Code, code) {
+		return false
+	}
+	if f.Price.Currency != r.Currency {
+		return false
+	}
+	if len(r.Airlines) > 0 && !slices.Contains(r.Airlines, f.Airline.Code) {
+		return false
+	}
+	if len(r.Routes) > 0 && !slices.Contains(r.Routes, f.Departure.Airport+"-"+f.Arrival.Airport) {
+		return false
+	}
+	return len(r.CabinClasses) == 0 || slices.Contains(r.CabinClasses, f.CabinClass)
+}
+
+// discount is what the rule takes off amount, in whole currency units.
+func (r Rule) discount(amount float64) float64 {
+	d := math.Round(amount * r.PercentOff / 100)
+	if r.MaxDiscount > 0 {
+		d = min(d, r.MaxDiscount)
+	}
+	return d
+}
+
+// Engine applies a fixed set of rules.
+type Engine struct {
+	rules []Rule
+}
+
+// New checks the rules, whose IDs must be unique.
+func New(rules []Rule) (*Engine, error) {
+	seen := make(map[string]bool, len(rules))
+	for i := range rules {
+		if err := rules[i].normalize(); err != nil {
+			return nil, err
+		}
+		if seen[rules[i].ID] {
+			return nil, fmt.Errorf("duplicate rule id %s", rules[i].ID)
+		}
+		seen[rules[i].ID] = true
+	}
+	return &Engine{rules: rules}, nil
+}
+
+// Load reads a JSON array of rules.
+func Load(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return New(rules)
+}
+
+func (e *Engine) Rules() []Rule {
+	if e == nil {
+		return nil
+	}
+	return e.rules
+}
+
+// Key identifies the rules active at now, for caches of discounted
+// results. It is empty when none are.
+func (e *Engine) Key(now time.Time) string {
+	if e == nil {
+		return ""
+	}
+	var ids []string
+	for _, r := range e.rules {
+		if r.Active(now) {
+			ids = append(ids, r.ID)
+		}
+	}
+	return strings.Join(ids, ",")
+}
+
+// Apply discounts each flight by the largest of the rules active at now
+// that match it; rules don't stack. The flights discounted are copies,
+// reporting their OriginalPrice and Promotion.
+func (e *Engine) Apply(flights []models.Flight, code string, now time.Time) []models.Flight {
+	if e == nil {
+		return flights
+	}
+	var active []Rule
+	for _, r := range e.rules {
+		if r.Active(now) {
+			active = append(active, r)
+		}
+	}
+	if len(active) == 0 {
+		return flights
+	}
+
+	result := slices.Clone(flights)
+	for i, f := range result {
+		var best *Rule
+		var amount float64
+		for j, r := range active {
+			if d := r.discount(f.Price.Amount); r.matches(f, code) && d > amount {
+				best, amount = &active[j], d
+			}
+		}
+		if best == nil {
+			continue
+		}
+		original := f.Price
+		result[i].OriginalPrice = &original
+		result[i].Price.Amount -= amount
+		result[i].Price.Formatted = currency.Format(result[i].Price.Amount, f.Price.Currency)
+		result[i].Promotion = &models.AppliedPromotion{
+			ID:       best.ID,
+			Name:     best.Name,
+			Discount: models.Price{Amount: amount, Currency: f.Price.Currency, Formatted: currency.Format(amount, f.Price.Currency)},
+		}
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package providercheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/providers"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+// CategoryContract covers how an adapter behaves as a call rather than
+// what it returns.
+const CategoryContract Category = "contract"
+
+// cancelGrace is how long an adapter may take to notice its context is
+// done.
+const cancelGrace = 100 * time.Millisecond
+
+// CheckContract calls p the ways the aggregator relies on: a cancelled or
+// expired context returns the context's error promptly, so an abandoned
+// search frees its call slot; a malformed date is an Invalid error, so it
+// isn't retried; and a route the provider doesn't fly is no flights rather
+// than an error. req is any request the provider can answer.
+func CheckContract(ctx context.Context, p providers.Provider, req models.SearchRequest, attempts int) []Violation {
+	var out []Violation
+	add := func(rule, format string, args ...any) {
+		out = append(out, Violation{Category: CategoryContract, Rule: rule, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if detail := checkDone(cancelled, p, req, context.Canceled); detail != "" {
+		add("cancelled_context", "%s", detail)
+	}
+	expired, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+	defer cancel()
+	if detail := checkDone(expired, p, req, context.DeadlineExceeded); detail != "" {
+		add("expired_deadline", "%s", detail)
+	}
+
+	malformed := req
+	malformed.DepartureDate = "15/12/2025"
+	if _, err := retry(ctx, p, malformed, attempts); err == nil {
+		add("invalid_date_rejected", "departure date %q was accepted", malformed.DepartureDate)
+	} else if kind := errorsx.KindOf(err); kind != errorsx.Invalid {
+		add("invalid_date_rejected", "departure date %q failed as %s rather than invalid: %v", malformed.DepartureDate, kind, err)
+	}
+
+	unknown := req
+	unknown.Origin, unknown.Destination = "ZZA", "ZZB"
+	if flights, err := retry(ctx, p, unknown, attempts); err != nil {
+		add("unknown_route_empty", "route %s-%s failed: %v", unknown.Origin, unknown.Destination, err)
+	} else if len(flights) > 0 {
+		add("unknown_route_empty", "route %s-%s returned %d flights", unknown.Origin, unknown.Destination, len(flights))
+	}
+
+	return out
+}
+
+// checkDone describes how p failed to give up on a done ctx, empty when it
+// returned want in time.
+func checkDone(ctx context.Context, p providers.Provider, req models.SearchRequest, want error) string {
+	start := time.Now()
+	flights, err := p.Search(ctx, req)
+	elapsed := time.Since(start)
+	switch {
+	case err == nil:
+		return fmt.Sprintf("returned %d flights, expected %v", len(flights), want)
+	case !errors.Is(err, want):
+		return fmt.Sprintf("returned %q, expected %v", err, want)
+	case elapsed > cancelGrace:
+		return fmt.Sprintf("took %s to return %v", elapsed.Round(time.Millisecond), want)
+	}
+	return ""
+}
+
+// retry repeats calls failing with a retryable error, so simulated
+// flakiness isn't reported as a broken contract.
+func retry(ctx context.Context, p providers.Provider, req models.SearchRequest, attempts int) ([]models.Flight, error) {
+	var flights []models.Flight
+	var err error
+	for i := 0; i < max(attempts, 1); i++ {
+		if flights, err = p.Search(ctx, req); err == nil || !errorsx.Retryable(err) {
+			break
+		}
+	}
+	return flights, err
+}
@@ -0,0 +1,130 @@
+package providercheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// CategoryGolden covers differences from a provider's approved output.
+const CategoryGolden Category = "golden"
+
+// Snapshot is a provider's approved normalized output by case key.
+type Snapshot map[string][]models.Flight
+
+// GoldenPath is where provider's snapshot lives in dir.
+func GoldenPath(dir, provider string) string {
+	return filepath.Join(dir, provider+".json")
+}
+
+// LoadSnapshot reads a snapshot; a missing file is an empty one, so a new
+// provider reports every case as unapproved.
+func LoadSnapshot(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("golden snapshot %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s Snapshot) Write(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// SnapshotOf is the output of every case in r that ran.
+func SnapshotOf(r Report) Snapshot {
+	s := make(Snapshot, len(r.Cases))
+	for _, c := range r.Cases {
+		if c.Error == "" {
+			s[c.Case.Key()] = append([]models.Flight{}, c.Output...)
+		}
+	}
+	return s
+}
+
+// Drift compares the output of r's cases with golden, naming the fields
+// that changed in each flight. Cases golden doesn't have are reported as
+// unapproved; cases r didn't run are ignored.
+func Drift(golden Snapshot, r Report) []Violation {
+	var out []Violation
+	for _, c := range r.Cases {
+		if c.Error != "" {
+			continue
+		}
+		key := c.Case.Key()
+		want, ok := golden[key]
+		if !ok {
+			out = append(out, Violation{Category: CategoryGolden, Rule: "case_unapproved", Detail: fmt.Sprintf("case %s has no golden output", key)})
+			continue
+		}
+
+		got := make(map[string]models.Flight, len(c.Output))
+		for _, f := range c.Output {
+			got[f.ID] = f
+		}
+		for _, w := range want {
+			g, ok := got[w.ID]
+			if !ok {
+				out = append(out, Violation{FlightID: w.ID, Category: CategoryGolden, Rule: "flight_missing", Detail: fmt.Sprintf("case %s no longer returns the flight", key)})
+				continue
+			}
+			delete(got, w.ID)
+			if fields := changedFields(w, g); len(fields) > 0 {
+				out = append(out, Violation{FlightID: w.ID, Category: CategoryGolden, Rule: "flight_changed", Detail: fmt.Sprintf("case %s changed %v", key, fields)})
+			}
+		}
+		for _, f := range c.Output {
+			if _, ok := got[f.ID]; ok {
+				out = append(out, Violation{FlightID: f.ID, Category: CategoryGolden, Rule: "flight_added", Detail: fmt.Sprintf("case %s returns an unapproved flight", key)})
+			}
+		}
+	}
+	return out
+}
+
+// changedFields lists the top-level JSON fields that differ between two
+// flights, sorted.
+func changedFields(want, got models.Flight) []string {
+	w, g := fieldsOf(want), fieldsOf(got)
+	var changed []string
+	for k, v := range w {
+		if !bytes.Equal(v, g[k]) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range g {
+		if _, ok := w[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+func fieldsOf(f models.Flight) map[string]json.RawMessage {
+	data, _ := json.Marshal(f)
+	var fields map[string]json.RawMessage
+	json.Unmarshal(data, &fields)
+	return fields
+}
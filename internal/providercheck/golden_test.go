@@ -0,0 +1,66 @@
+package providercheck
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/providers"
+)
+
+// TestFixtureProviders runs the fixture providers through the same
+// matrix and seed as cmd/providercheck's defaults, so a change that
+// breaks an invariant, the call contract or the approved output fails
+// go test.
+func TestFixtureProviders(t *testing.T) {
+	constructors := map[string]func(*providers.Rand) (providers.Provider, error){
+		"garuda":      func(r *providers.Rand) (providers.Provider, error) { return providers.NewGarudaProvider(r) },
+		"lionair":     func(r *providers.Rand) (providers.Provider, error) { return providers.NewLionAirProvider(r) },
+		"batikair":    func(r *providers.Rand) (providers.Provider, error) { return providers.NewBatikAirProvider(r) },
+		"airasia":     func(r *providers.Rand) (providers.Provider, error) { return providers.NewAirAsiaProvider(r) },
+		"citilink":    func(r *providers.Rand) (providers.Provider, error) { return providers.NewCitilinkProvider(r) },
+		"superairjet": func(r *providers.Rand) (providers.Provider, error) { return providers.NewSuperAirJetProvider(r) },
+	}
+	matrix := Matrix{
+		Routes:   [][2]string{{"CGK", "DPS"}, {"DPS", "CGK"}, {"CGK", "SUB"}},
+		Dates:    []string{"2025-12-15", "2025-12-16"},
+		Cabins:   []string{"economy", "business"},
+		Timeout:  2 * time.Second,
+		Attempts: 3,
+		Contract: true,
+	}
+
+	for name, newProvider := range constructors {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			p, err := newProvider(providers.NewRand(providers.ModeMock, 1, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden, err := LoadSnapshot(GoldenPath("testdata/golden", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			report := Run(context.Background(), p, matrix)
+			report.Drift = Drift(golden, report)
+			if len(report.Cases) != len(golden) {
+				t.Errorf("ran %d cases, golden has %d", len(report.Cases), len(golden))
+			}
+			for _, c := range report.Cases {
+				if c.Error != "" {
+					t.Errorf("%s: %s", c.Case.Key(), c.Error)
+				}
+				for _, v := range c.Violations {
+					t.Errorf("%s: [%s] %s %s: %s", c.Case.Key(), v.Category, v.FlightID, v.Rule, v.Detail)
+				}
+			}
+			for _, v := range report.Contract {
+				t.Errorf("contract: %s: %s", v.Rule, v.Detail)
+			}
+			for _, v := range report.Drift {
+				t.Errorf("golden: %s %s: %s", v.Rule, v.FlightID, v.Detail)
+			}
+		})
+	}
+}
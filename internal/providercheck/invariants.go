@@ -0,0 +1,149 @@
+package providercheck
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/timezone"
+)
+
+type Category string
+
+const (
+	CategorySchema        Category = "schema"
+	CategoryTimezone      Category = "timezone"
+	CategoryNormalization Category = "normalization"
+)
+
+type Violation struct {
+	FlightID string   `json:"flight_id"`
+	Category Category `json:"category"`
+	Rule     string   `json:"rule"`
+	Detail   string   `json:"detail"`
+}
+
+var (
+	airportCode  = regexp.MustCompile(`^[A-Z]{3}$`)
+	airlineCode  = regexp.MustCompile(`^[A-Z0-9]{2}$`)
+	currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// Check returns every invariant a normalized flight breaks for the given
+// request. An adapter is compliant when no flight yields a violation.
+func Check(provider string, req models.SearchRequest, f models.Flight) []Violation {
+	var out []Violation
+	add := func(c Category, rule, format string, args ...any) {
+		out = append(out, Violation{FlightID: f.ID, Category: c, Rule: rule, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	if f.ID == "" {
+		add(CategorySchema, "id_required", "flight has no id")
+	}
+	if f.Provider != provider {
+		add(CategorySchema, "provider_name", "provider is %q, expected %q", f.Provider, provider)
+	}
+	if !airlineCode.MatchString(f.Airline.Code) {
+		add(CategorySchema, "airline_code", "airline code %q is not a 2-character IATA code", f.Airline.Code)
+	}
+	if f.Airline.Name == "" {
+		add(CategorySchema, "airline_name", "airline name is empty")
+	}
+	if f.FlightNumber == "" {
+		add(CategorySchema, "flight_number", "flight number is empty")
+	}
+	if f.Price.Amount <= 0 {
+		add(CategorySchema, "price_positive", "price %.2f is not positive", f.Price.Amount)
+	}
+	if !currencyCode.MatchString(f.Price.Currency) {
+		add(CategorySchema, "currency_code", "currency %q is not an ISO 4217 code", f.Price.Currency)
+	}
+	if f.Price.Formatted == "" {
+		add(CategorySchema, "price_formatted", "formatted price is empty")
+	}
+	if f.AvailableSeats < 0 {
+		add(CategorySchema, "seats_non_negative", "available seats is %d", f.AvailableSeats)
+	}
+	if f.Baggage.CabinKg < 0 || f.Baggage.CheckedKg < 0 {
+		add(CategorySchema, "baggage_non_negative", "baggage allowance is negative")
+	}
+	if a := f.Ancillaries; a != nil {
+		prices := []*models.Price{a.SeatSelection, a.Meal}
+		for i, bag := range a.CheckedBags {
+			if bag.Kg <= 0 {
+				add(CategorySchema, "bag_weight_positive", "checked bag %d weighs %.1f kg", i, bag.Kg)
+			}
+			prices = append(prices, &a.CheckedBags[i].Price)
+		}
+		for _, p := range prices {
+			if p != nil && (p.Amount <= 0 || p.Currency != f.Price.Currency) {
+				add(CategoryNormalization, "ancillary_price", "ancillary priced %.2f %s on a %s fare", p.Amount, p.Currency, f.Price.Currency)
+			}
+		}
+	}
+
+	if !airportCode.MatchString(f.Departure.Airport) || !airportCode.MatchString(f.Arrival.Airport) {
+		add(CategoryNormalization, "airport_code", "airport codes %q/%q are not uppercase IATA codes", f.Departure.Airport, f.Arrival.Airport)
+	}
+	if f.Departure.Airport != req.Origin || f.Arrival.Airport != req.Destination {
+		add(CategoryNormalization, "route_matches_request", "route %s-%s does not match request %s-%s", f.Departure.Airport, f.Arrival.Airport, req.Origin, req.Destination)
+	}
+	if class, ok := cabin.Parse(f.CabinClass); !ok || string(class) != f.CabinClass {
+		add(CategoryNormalization, "cabin_canonical", "cabin %q is not a canonical cabin class", f.CabinClass)
+	} else if !cabin.Matches(req.CabinClass, f.CabinClass) {
+		add(CategoryNormalization, "cabin_matches_request", "cabin %q does not match request %q", f.CabinClass, req.CabinClass)
+	}
+	if f.Duration.TotalMinutes != f.Duration.Hours*60+f.Duration.Minutes {
+		add(CategoryNormalization, "duration_consistent", "total_minutes %d != %dh%dm", f.Duration.TotalMinutes, f.Duration.Hours, f.Duration.Minutes)
+	}
+	if f.Stops < 0 {
+		add(CategoryNormalization, "stops_non_negative", "stops is %d", f.Stops)
+	}
+	if len(f.Layovers) > 0 && len(f.Layovers) != f.Stops {
+		add(CategoryNormalization, "layovers_match_stops", "%d layovers for %d stops", len(f.Layovers), f.Stops)
+	}
+
+	checkLocation(add, "departure", f.Departure)
+	checkLocation(add, "arrival", f.Arrival)
+
+	if !f.Arrival.Time.After(f.Departure.Time) {
+		add(CategoryTimezone, "arrival_after_departure", "arrival %s is not after departure %s", f.Arrival.Time, f.Departure.Time)
+	} else if elapsed, served := int(f.Arrival.Time.Sub(f.Departure.Time).Minutes()), aggregator.TripDuration(f); abs(elapsed-served.TotalMinutes) > aggregator.DurationTolerance {
+		// Providers may report flying time alone; the aggregator serves
+		// the span of the times instead, so that is what has to match.
+		add(CategoryTimezone, "duration_matches_times", "times span %d minutes but duration served is %d", elapsed, served.TotalMinutes)
+	}
+	if local := timezone.LocalDate(f.Departure.Time, f.Departure.Airport); local != req.DepartureDate {
+		add(CategoryTimezone, "departure_date_local", "departs %s local time, requested %s", local, req.DepartureDate)
+	}
+
+	return out
+}
+
+func checkLocation(add func(Category, string, string, ...any), which string, loc models.Location) {
+	airportLoc, err := timezone.LocationByAirport(loc.Airport)
+	if err != nil {
+		add(CategoryTimezone, which+"_airport_known", "%s airport %s has no known timezone", which, loc.Airport)
+		return
+	}
+
+	expected := timezone.GetTimezoneByAirport(loc.Airport)
+	if loc.Timezone != expected {
+		add(CategoryTimezone, which+"_timezone_label", "%s timezone is %q, expected %q for %s", which, loc.Timezone, expected, loc.Airport)
+	}
+
+	_, offset := loc.Time.Zone()
+	_, expectedOffset := loc.Time.In(airportLoc).Zone()
+	if offset != expectedOffset {
+		add(CategoryTimezone, which+"_utc_offset", "%s time %s has offset %+d, expected %+d for %s", which, loc.Time.Format("2006-01-02T15:04:05Z07:00"), offset/3600, expectedOffset/3600, loc.Airport)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
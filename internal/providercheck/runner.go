@@ -0,0 +1,135 @@
+package providercheck
+
+import (
+	"context"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/providers"
+)
+
+type Case struct {
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+	Date        string `json:"date"`
+	CabinClass  string `json:"cabin_class"`
+}
+
+// Key names the case in golden snapshots.
+func (c Case) Key() string {
+	return c.Origin + "-" + c.Destination + "_" + c.Date + "_" + c.CabinClass
+}
+
+type Matrix struct {
+	Routes  [][2]string
+	Dates   []string
+	Cabins  []string
+	Timeout time.Duration
+	// Attempts per case, so a provider's simulated flakiness isn't reported
+	// as non-compliance.
+	Attempts int
+	// Contract also checks how the provider handles cancellation and bad
+	// requests, which recorded responses can't answer.
+	Contract bool
+}
+
+func (m Matrix) Cases() []Case {
+	var cases []Case
+	for _, r := range m.Routes {
+		for _, d := range m.Dates {
+			for _, c := range m.Cabins {
+				cases = append(cases, Case{Origin: r[0], Destination: r[1], Date: d, CabinClass: c})
+			}
+		}
+	}
+	return cases
+}
+
+type CaseResult struct {
+	Case       Case        `json:"case"`
+	Flights    int         `json:"flights"`
+	Attempts   int         `json:"attempts"`
+	Error      string      `json:"error,omitempty"`
+	Violations []Violation `json:"violations,omitempty"`
+	// Output is the normalized flights, for comparing against a golden
+	// snapshot.
+	Output []models.Flight `json:"-"`
+}
+
+type Report struct {
+	Provider   string       `json:"provider"`
+	Cases      []CaseResult `json:"cases"`
+	Flights    int          `json:"flights_checked"`
+	Violations int          `json:"violations"`
+	Errors     int          `json:"errors"`
+	Contract   []Violation  `json:"contract,omitempty"`
+	// Drift is how the output differs from the approved golden snapshot.
+	Drift []Violation `json:"drift,omitempty"`
+}
+
+// Passed reports whether every case ran, no flight broke an invariant, the
+// provider kept its call contract and the output matches its snapshot.
+func (r Report) Passed() bool {
+	return r.Violations == 0 && r.Errors == 0 && len(r.Contract) == 0 && len(r.Drift) == 0
+}
+
+func Run(ctx context.Context, p providers.Provider, m Matrix) Report {
+	report := Report{Provider: p.Name()}
+	attempts := m.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for _, c := range m.Cases() {
+		req := models.SearchRequest{
+			Origin:        c.Origin,
+			Destination:   c.Destination,
+			DepartureDate: c.Date,
+			Passengers:    1,
+			CabinClass:    c.CabinClass,
+		}
+
+		cr := CaseResult{Case: c}
+		var flights []models.Flight
+		var err error
+		for cr.Attempts < attempts {
+			cr.Attempts++
+			flights, err = search(ctx, p, req, m.Timeout)
+			if err == nil {
+				break
+			}
+		}
+
+		if err != nil {
+			cr.Error = err.Error()
+			report.Errors++
+		} else {
+			cr.Flights = len(flights)
+			cr.Output = flights
+			for _, f := range flights {
+				cr.Violations = append(cr.Violations, Check(p.Name(), req, f)...)
+			}
+			report.Flights += len(flights)
+			report.Violations += len(cr.Violations)
+		}
+
+		report.Cases = append(report.Cases, cr)
+	}
+
+	if m.Contract && len(report.Cases) > 0 {
+		c := report.Cases[0].Case
+		req := models.SearchRequest{Origin: c.Origin, Destination: c.Destination, DepartureDate: c.Date, Passengers: 1, CabinClass: c.CabinClass}
+		report.Contract = CheckContract(ctx, p, req, attempts)
+	}
+
+	return report
+}
+
+func search(ctx context.Context, p providers.Provider, req models.SearchRequest, timeout time.Duration) ([]models.Flight, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return p.Search(ctx, req)
+}
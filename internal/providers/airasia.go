@@ -3,21 +3,19 @@ package providers
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"math"
-	"math/rand"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
 	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/normalize"
 	"github.com/dharmasatrya/flightsearch/internal/providers/data"
 	"github.com/dharmasatrya/flightsearch/internal/timezone"
-	"github.com/dharmasatrya/flightsearch/pkg/currency"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
 )
 
-var ErrAirAsiaTemporaryFailure = errors.New("temporary service unavailable")
+var ErrAirAsiaTemporaryFailure = errorsx.New(errorsx.Unavailable, "temporary service unavailable")
 
 type airasiaResponse struct {
 	FlightOffers []airasiaFlight `json:"flight_offers"`
@@ -40,6 +38,16 @@ type airasiaFlight struct {
 	Equipment        string          `json:"equipment"`
 	Perks            []string        `json:"perks"`
 	BaggageInfo      string          `json:"baggage_info"`
+	Addons           airasiaAddons   `json:"addons"`
+}
+
+type airasiaAddons struct {
+	CheckedBaggage []struct {
+		WeightKg float64 `json:"weight_kg"`
+		PriceIDR float64 `json:"price_idr"`
+	} `json:"checked_baggage"`
+	SeatFromIDR float64 `json:"seat_from_idr"`
+	MealIDR     float64 `json:"meal_idr"`
 }
 
 type airasiaCarrier struct {
@@ -59,33 +67,63 @@ type airasiaStop struct {
 }
 
 type AirAsiaProvider struct {
+	endpoint
+
 	flights []airasiaFlight
+	rng     *Rand
 }
 
-func NewAirAsiaProvider() (*AirAsiaProvider, error) {
+func NewAirAsiaProvider(rng *Rand) (*AirAsiaProvider, error) {
 	var resp airasiaResponse
 	if err := json.Unmarshal(data.AirAsiaData, &resp); err != nil {
 		return nil, err
 	}
-	return &AirAsiaProvider{flights: resp.FlightOffers}, nil
+	return &AirAsiaProvider{flights: resp.FlightOffers, rng: rng}, nil
 }
 
 func (p *AirAsiaProvider) Name() string {
 	return "airasia"
 }
 
+func (p *AirAsiaProvider) BookingURLTemplate() string {
+	return "https://www.airasia.com/flights/search/?origin={origin}&destination={destination}&departDate={date}&tripType=O&adult={adults}&child={children}&infant={infants}&flightNo={flight_number}&aff={affiliate}"
+}
+
+func (p *AirAsiaProvider) PassengerRules() PassengerRules {
+	return PassengerRules{
+		AdultAge:              12,
+		InfantAge:             2,
+		MaxLapInfantsPerAdult: 1,
+		MaxChildrenPerAdult:   2,
+	}
+}
+
+func (p *AirAsiaProvider) Capabilities() Capabilities {
+	var c catalog
+	for _, f := range p.flights {
+		c.add(f.From.IATA, f.To.IATA, f.TravelClass)
+	}
+	return c.capabilities()
+}
+
 func (p *AirAsiaProvider) Search(ctx context.Context, req models.SearchRequest) ([]models.Flight, error) {
-	delay := time.Duration(50+rand.Intn(100)) * time.Millisecond
+	delay := p.rng.delay(50, 100)
 	select {
 	case <-time.After(delay):
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 
-	if rand.Float64() < 0.1 {
+	if p.rng.Float64() < 0.1 {
 		return nil, ErrAirAsiaTemporaryFailure
 	}
 
+	date, err := time.Parse("2006-01-02", req.DepartureDate)
+	if err != nil {
+		return nil, errorsx.Errorf(errorsx.Invalid, "invalid departure date %q", req.DepartureDate)
+	}
+	shift := fixtureShift(date)
+
 	var results []models.Flight
 	for _, f := range p.flights {
 		if !strings.EqualFold(f.From.IATA, req.Origin) ||
@@ -93,7 +131,7 @@ func (p *AirAsiaProvider) Search(ctx context.Context, req models.SearchRequest)
 			continue
 		}
 
-		if !strings.EqualFold(f.TravelClass, req.CabinClass) {
+		if !cabin.Matches(req.CabinClass, f.TravelClass) {
 			continue
 		}
 
@@ -102,116 +140,56 @@ func (p *AirAsiaProvider) Search(ctx context.Context, req models.SearchRequest)
 			continue
 		}
 
-		reqDate, err := time.Parse("2006-01-02", req.DepartureDate)
-		if err != nil {
-			continue
-		}
-		if depTime.Year() != reqDate.Year() || depTime.Month() != reqDate.Month() || depTime.Day() != reqDate.Day() {
+		if timezone.LocalDate(depTime.AddDate(0, 0, shift), f.From.IATA) != req.DepartureDate {
 			continue
 		}
 
 		flight, err := p.normalize(f)
 		if err != nil {
+			debugf(ctx, "%s: skipping flight %s: %v", p.Name(), f.OfferID, err)
 			continue
 		}
+		shiftFlight(&flight, shift)
 		results = append(results, flight)
 	}
 
+	debugf(ctx, "%s: %d of %d flights match %s-%s on %s in %s", p.Name(), len(results), len(p.flights), req.Origin, req.Destination, req.DepartureDate, req.CabinClass)
 	return results, nil
 }
 
 func (p *AirAsiaProvider) normalize(f airasiaFlight) (models.Flight, error) {
-	depTime, err := timezone.ParseTimeWithOffset(f.DepartAt, "")
-	if err != nil {
-		return models.Flight{}, err
-	}
-
-	arrTime, err := timezone.ParseTimeWithOffset(f.ArriveAt, "")
-	if err != nil {
-		return models.Flight{}, err
-	}
-
-	depTime = timezone.ConvertToTimezone(depTime, f.From.IATA)
-	arrTime = timezone.ConvertToTimezone(arrTime, f.To.IATA)
-
-	totalMinutes := int(math.Round(f.DurationHours * 60))
-	hours := totalMinutes / 60
-	mins := totalMinutes % 60
-
 	stops := len(f.Stops)
 	if f.DirectFlight {
 		stops = 0
 	}
-
-	layovers := make([]models.Layover, len(f.Stops))
-	for i, s := range f.Stops {
-		layovers[i] = models.Layover{
-			Airport:  s.StopAirport,
-			City:     s.StopCity,
-			Duration: s.StopDurationMin,
-		}
+	// Only the cabin allowance is included; 7 kg when it isn't stated.
+	cabinKg, ok := normalize.Kg(f.BaggageInfo)
+	if !ok {
+		cabinKg = 7
 	}
 
-	cabinKg := parseAirAsiaBaggage(f.BaggageInfo)
-
-	var aircraft *string
-	if f.Equipment != "" {
-		a := f.Equipment
-		aircraft = &a
+	b := normalize.New(p.Name(), f.OfferID)
+	b.SetAirline(f.MarketingCarrier.AirlineCode, f.MarketingCarrier.AirlineName).
+		SetFlightNumber(f.FlightNum).
+		SetCabin(f.TravelClass).
+		SetDeparture(f.From.IATA, f.From.CityName, "", b.Time(f.DepartAt, "")).
+		SetArrival(f.To.IATA, f.To.CityName, "", b.Time(f.ArriveAt, "")).
+		SetDuration(int(math.Round(f.DurationHours*60))).
+		SetStops(stops).
+		SetPrice(f.PriceIDR, "IDR").
+		SetSeats(f.SeatsLeft).
+		SetAircraft(f.Equipment).
+		SetAmenities(f.Perks).
+		SetBaggage(cabinKg, 0)
+	for _, s := range f.Stops {
+		b.AddLayover(s.StopAirport, s.StopCity, s.StopDurationMin)
 	}
 
-	return models.Flight{
-		ID:       f.OfferID,
-		Provider: p.Name(),
-		Airline: models.Airline{
-			Code: f.MarketingCarrier.AirlineCode,
-			Name: f.MarketingCarrier.AirlineName,
-		},
-		FlightNumber: f.FlightNum,
-		Departure: models.Location{
-			Airport:  f.From.IATA,
-			City:     f.From.CityName,
-			Terminal: nil,
-			Time:     depTime,
-			Timezone: timezone.GetTimezoneByAirport(f.From.IATA),
-		},
-		Arrival: models.Location{
-			Airport:  f.To.IATA,
-			City:     f.To.CityName,
-			Terminal: nil,
-			Time:     arrTime,
-			Timezone: timezone.GetTimezoneByAirport(f.To.IATA),
-		},
-		Duration: models.Duration{
-			Hours:        hours,
-			Minutes:      mins,
-			TotalMinutes: totalMinutes,
-		},
-		Stops:    stops,
-		Layovers: layovers,
-		Price: models.Price{
-			Amount:    f.PriceIDR,
-			Currency:  "IDR",
-			Formatted: currency.FormatIDR(f.PriceIDR),
-		},
-		AvailableSeats: f.SeatsLeft,
-		CabinClass:     f.TravelClass,
-		Aircraft:       aircraft,
-		Amenities:      f.Perks,
-		Baggage: models.Baggage{
-			CabinKg:   cabinKg,
-			CheckedKg: 0,
-		},
-	}, nil
-}
-
-func parseAirAsiaBaggage(s string) float64 {
-	re := regexp.MustCompile(`(\d+(?:\.\d+)?)\s*kg`)
-	matches := re.FindStringSubmatch(strings.ToLower(s))
-	if len(matches) >= 2 {
-		if v, err := strconv.ParseFloat(matches[1], 64); err == nil {
-			return v
-		}
+	bags := make([]models.BagOption, len(f.Addons.CheckedBaggage))
+	for i, bag := range f.Addons.CheckedBaggage {
+		bags[i] = normalize.Bag(bag.WeightKg, bag.PriceIDR, "IDR")
 	}
-	return 7
+	seat := normalize.OptionalPrice(f.Addons.SeatFromIDR, "IDR")
+	meal := normalize.OptionalPrice(f.Addons.MealIDR, "IDR")
+	return b.SetAncillaries(bags, seat, meal).Build()
 }
@@ -0,0 +1,516 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/aircraft"
+	"github.com/dharmasatrya/flightsearch/internal/airports"
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/normalize"
+	"github.com/dharmasatrya/flightsearch/internal/timezone"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+// AmadeusTestURL is the Self-Service test environment, which serves
+// cached rather than live fares.
+const AmadeusTestURL = "https://test.api.amadeus.com"
+
+const (
+	// amadeusMaxOffers is the most offers asked for per search.
+	amadeusMaxOffers = 50
+	// amadeusTokenMargin renews an access token this long before it
+	// expires, so it doesn't expire mid-request.
+	amadeusTokenMargin = 30 * time.Second
+	// amadeusCheckedBagKg counts a checked bag allowed by piece, not
+	// weight, as the usual 23 kg.
+	amadeusCheckedBagKg = 23
+)
+
+var ErrAmadeusCredentials = errorsx.New(errorsx.Unavailable, "API credentials rejected")
+
+type AmadeusConfig struct {
+	ClientID     string
+	ClientSecret string
+	// BaseURL defaults to AmadeusTestURL.
+	BaseURL string
+	// Client defaults to http.DefaultClient; searches are bounded by their
+	// context.
+	Client *http.Client
+}
+
+type amadeusTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type amadeusErrorResponse struct {
+	Errors []struct {
+		Code   int    `json:"code"`
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+type amadeusOffersResponse struct {
+	Data         []amadeusOffer      `json:"data"`
+	Dictionaries amadeusDictionaries `json:"dictionaries"`
+}
+
+type amadeusDictionaries struct {
+	Carriers  map[string]string `json:"carriers"`
+	Aircraft  map[string]string `json:"aircraft"`
+	Locations map[string]struct {
+		CityCode string `json:"cityCode"`
+	} `json:"locations"`
+}
+
+type amadeusOffer struct {
+	ID                    string                   `json:"id"`
+	NumberOfBookableSeats int                      `json:"numberOfBookableSeats"`
+	Itineraries           []amadeusItinerary       `json:"itineraries"`
+	Price                 amadeusPrice             `json:"price"`
+	TravelerPricings      []amadeusTravelerPricing `json:"travelerPricings"`
+}
+
+type amadeusItinerary struct {
+	Duration string           `json:"duration"`
+	Segments []amadeusSegment `json:"segments"`
+}
+
+type amadeusSegment struct {
+	Departure   amadeusEndpoint `json:"departure"`
+	Arrival     amadeusEndpoint `json:"arrival"`
+	CarrierCode string          `json:"carrierCode"`
+	Number      string          `json:"number"`
+	Aircraft    struct {
+		Code string `json:"code"`
+	} `json:"aircraft"`
+	NumberOfStops int `json:"numberOfStops"`
+}
+
+type amadeusEndpoint struct {
+	IATACode string `json:"iataCode"`
+	Terminal string `json:"terminal"`
+	At       string `json:"at"`
+}
+
+type amadeusPrice struct {
+	Currency   string `json:"currency"`
+	Total      string `json:"total"`
+	GrandTotal string `json:"grandTotal"`
+}
+
+type amadeusTravelerPricing struct {
+	TravelerType string `json:"travelerType"`
+	Price        struct {
+		Total string `json:"total"`
+	} `json:"price"`
+	FareDetailsBySegment []amadeusFareDetails `json:"fareDetailsBySegment"`
+}
+
+type amadeusFareDetails struct {
+	Cabin               string         `json:"cabin"`
+	IncludedCheckedBags amadeusBaggage `json:"includedCheckedBags"`
+	IncludedCabinBags   amadeusBaggage `json:"includedCabinBags"`
+}
+
+type amadeusBaggage struct {
+	Quantity   int    `json:"quantity"`
+	Weight     int    `json:"weight"`
+	WeightUnit string `json:"weightUnit"`
+}
+
+// AmadeusProvider searches the Amadeus Self-Service Flight Offers Search
+// API. Unlike the airline adapters it calls a real API, so it needs
+// credentials and counts against their quota.
+type AmadeusProvider struct {
+	endpoint
+
+	config AmadeusConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func NewAmadeusProvider(cfg AmadeusConfig) (*AmadeusProvider, error) {
+	if cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return nil, fmt.Errorf("amadeus: client ID and secret are required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = AmadeusTestURL
+	}
+	if _, err := url.Parse(cfg.BaseURL); err != nil {
+		return nil, fmt.Errorf("amadeus: invalid base URL: %w", err)
+	}
+	client := cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &AmadeusProvider{config: cfg, client: client}, nil
+}
+
+func (p *AmadeusProvider) Name() string {
+	return "amadeus"
+}
+
+// Capabilities list no routes: Amadeus covers whatever its airlines fly.
+func (p *AmadeusProvider) Capabilities() Capabilities {
+	return Capabilities{Routes: []Route{}, CabinClasses: cabin.Classes}
+}
+
+func (p *AmadeusProvider) baseURL() string {
+	if u := p.Endpoint(); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	return strings.TrimSuffix(p.config.BaseURL, "/")
+}
+
+func (p *AmadeusProvider) Search(ctx context.Context, req models.SearchRequest) ([]models.Flight, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := time.Parse("2006-01-02", req.DepartureDate); err != nil {
+		return nil, errorsx.Errorf(errorsx.Invalid, "invalid departure date %q", req.DepartureDate)
+	}
+	// Amadeus rejects airports it doesn't know; searches only reach this
+	// service for airports it knows the timezone of.
+	if !airports.Known(req.Origin) || !airports.Known(req.Destination) {
+		return nil, nil
+	}
+
+	query := url.Values{
+		"originLocationCode":      {strings.ToUpper(req.Origin)},
+		"destinationLocationCode": {strings.ToUpper(req.Destination)},
+		"departureDate":           {req.DepartureDate},
+		"adults":                  {strconv.Itoa(max(req.Passengers, 1))},
+		"currencyCode":            {"IDR"},
+		"max":                     {strconv.Itoa(amadeusMaxOffers)},
+	}
+	if class, ok := cabin.Parse(req.CabinClass); ok {
+		query.Set("travelClass", strings.ToUpper(string(class)))
+	}
+
+	var resp amadeusOffersResponse
+	if err := p.get(ctx, "/v2/shopping/flight-offers?"+query.Encode(), &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]models.Flight, 0, len(resp.Data))
+	seen := make(map[string]int)
+	for _, offer := range resp.Data {
+		flight, err := p.normalize(offer, resp.Dictionaries)
+		if err != nil {
+			debugf(ctx, "%s: skipping offer %s: %v", p.Name(), offer.ID, err)
+			continue
+		}
+		if !cabin.Matches(req.CabinClass, flight.CabinClass) {
+			continue
+		}
+		// Fare families of one itinerary come as separate offers; the
+		// cheapest stands for the itinerary.
+		if i, ok := seen[flight.ID]; ok {
+			if flight.Price.Amount < results[i].Price.Amount {
+				results[i] = flight
+			}
+			continue
+		}
+		seen[flight.ID] = len(results)
+		results = append(results, flight)
+	}
+
+	debugf(ctx, "%s: %d of %d offers match %s-%s on %s in %s", p.Name(), len(results), len(resp.Data), req.Origin, req.Destination, req.DepartureDate, req.CabinClass)
+	return results, nil
+}
+
+// get calls path with a bearer token, renewing the token once if Amadeus
+// rejects it before it was due to expire.
+func (p *AmadeusProvider) get(ctx context.Context, path string, out any) error {
+	for attempt := 0; ; attempt++ {
+		token, err := p.accessToken(ctx)
+		if err != nil {
+			return err
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL()+path, nil)
+		if err != nil {
+			return err
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+		httpReq.Header.Set("Accept", "application/vnd.amadeus+json")
+
+		err = p.do(httpReq, out)
+		if errorsx.KindOf(err) == errorsx.Unauthorized && attempt == 0 {
+			p.forgetToken(token)
+			continue
+		}
+		if errorsx.KindOf(err) == errorsx.Unauthorized {
+			return fmt.Errorf("%w: %v", ErrAmadeusCredentials, err)
+		}
+		return err
+	}
+}
+
+func (p *AmadeusProvider) accessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Now().Before(p.expires) {
+		return p.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {p.config.ClientID},
+		"client_secret": {p.config.ClientSecret},
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL()+"/v1/security/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp amadeusTokenResponse
+	if err := p.do(httpReq, &resp); err != nil {
+		if k := errorsx.KindOf(err); k == errorsx.Unauthorized || k == errorsx.Invalid {
+			return "", fmt.Errorf("%w: %v", ErrAmadeusCredentials, err)
+		}
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", errorsx.New(errorsx.Unavailable, "empty access token issued")
+	}
+	p.token = resp.AccessToken
+	p.expires = time.Now().Add(time.Duration(resp.ExpiresIn)*time.Second - amadeusTokenMargin)
+	return p.token, nil
+}
+
+// forgetToken drops token unless another search has already renewed it.
+func (p *AmadeusProvider) forgetToken(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token == token {
+		p.token = ""
+	}
+}
+
+// do sends httpReq and decodes a successful response into out. Failures
+// are classified by status: the request's fault is Invalid, a rejected
+// token Unauthorized, and anything else on Amadeus's side Unavailable or
+// RateLimited.
+func (p *AmadeusProvider) do(httpReq *http.Request, out any) error {
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		if ctxErr := httpReq.Context().Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return errorsx.Classify(err, errorsx.Unavailable)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errorsx.Classify(err, errorsx.Unavailable)
+	}
+	if resp.StatusCode >= 300 {
+		kind := errorsx.Unavailable
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized:
+			kind = errorsx.Unauthorized
+		case resp.StatusCode == http.StatusTooManyRequests:
+			kind = errorsx.RateLimited
+		case resp.StatusCode == http.StatusBadRequest:
+			kind = errorsx.Invalid
+		}
+		return errorsx.Errorf(kind, "%s: %s", resp.Status, amadeusErrorDetail(body))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return errorsx.Errorf(errorsx.Unavailable, "malformed response: %w", err)
+	}
+	return nil
+}
+
+func amadeusErrorDetail(body []byte) string {
+	var resp amadeusErrorResponse
+	if json.Unmarshal(body, &resp) != nil || len(resp.Errors) == 0 {
+		return strings.TrimSpace(string(body))
+	}
+	msgs := make([]string, len(resp.Errors))
+	for i, e := range resp.Errors {
+		msgs[i] = strings.TrimSpace(fmt.Sprintf("%s (%d) %s", e.Title, e.Code, e.Detail))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (p *AmadeusProvider) normalize(o amadeusOffer, dict amadeusDictionaries) (models.Flight, error) {
+	if len(o.Itineraries) != 1 || len(o.Itineraries[0].Segments) == 0 {
+		return models.Flight{}, fmt.Errorf("want one itinerary with segments, got %d itineraries", len(o.Itineraries))
+	}
+	segments := o.Itineraries[0].Segments
+	first, last := segments[0], segments[len(segments)-1]
+
+	depTime, err := amadeusLocalTime(first.Departure)
+	if err != nil {
+		return models.Flight{}, err
+	}
+	arrTime, err := amadeusLocalTime(last.Arrival)
+	if err != nil {
+		return models.Flight{}, err
+	}
+	totalMinutes, err := parseISODuration(o.Itineraries[0].Duration)
+	if err != nil {
+		totalMinutes = int(arrTime.Sub(depTime).Minutes())
+	}
+
+	fares, amount, err := amadeusAdultFare(o)
+	if err != nil {
+		return models.Flight{}, err
+	}
+	if len(fares.FareDetailsBySegment) == 0 {
+		return models.Flight{}, fmt.Errorf("no fare details")
+	}
+	class, ok := cabin.Parse(fares.FareDetailsBySegment[0].Cabin)
+	if !ok {
+		return models.Flight{}, fmt.Errorf("unknown cabin class %q", fares.FareDetailsBySegment[0].Cabin)
+	}
+
+	stops := len(segments) - 1
+	layovers := make([]models.Layover, 0, stops)
+	ids := make([]string, len(segments))
+	for i, s := range segments {
+		stops += s.NumberOfStops
+		ids[i] = s.CarrierCode + s.Number
+		if i == 0 {
+			continue
+		}
+		arrived, err1 := amadeusLocalTime(segments[i-1].Arrival)
+		departs, err2 := amadeusLocalTime(s.Departure)
+		if err1 != nil || err2 != nil {
+			return models.Flight{}, fmt.Errorf("unparseable connection at %s", s.Departure.IATACode)
+		}
+		layovers = append(layovers, models.Layover{
+			Airport:  s.Departure.IATACode,
+			City:     dict.Locations[s.Departure.IATACode].CityCode,
+			Duration: int(departs.Sub(arrived).Minutes()),
+		})
+	}
+	// Technical stops have no connection to report, so layovers are only
+	// listed when they account for every stop.
+	if len(layovers) != stops {
+		layovers = nil
+	}
+
+	var planeName string
+	if code := first.Aircraft.Code; code != "" {
+		planeName = titleCase(dict.Aircraft[code])
+		if e, ok := aircraft.Normalize(code); ok {
+			planeName = e.Name
+		}
+	}
+
+	// Offer IDs only number the offers of one response; the flights flown,
+	// date and cabin identify an itinerary across searches.
+	id := "AMA-" + strings.Join(ids, "-") + "-" + depTime.Format("20060102") + "-" + strings.ToUpper(string(class))
+	b := normalize.New(p.Name(), id)
+	b.SetAirline(first.CarrierCode, titleCase(dict.Carriers[first.CarrierCode])).
+		SetFlightNumber(first.CarrierCode+" "+first.Number).
+		SetCabin(string(class)).
+		SetDeparture(first.Departure.IATACode, dict.Locations[first.Departure.IATACode].CityCode, first.Departure.Terminal, depTime).
+		SetArrival(last.Arrival.IATACode, dict.Locations[last.Arrival.IATACode].CityCode, last.Arrival.Terminal, arrTime).
+		SetDuration(totalMinutes).
+		SetStops(stops).
+		SetPrice(amount, o.Price.Currency).
+		SetSeats(o.NumberOfBookableSeats).
+		SetAircraft(planeName).
+		SetBaggage(
+			amadeusBaggageKg(fares.FareDetailsBySegment[0].IncludedCabinBags, 0),
+			amadeusBaggageKg(fares.FareDetailsBySegment[0].IncludedCheckedBags, amadeusCheckedBagKg),
+		)
+	for _, l := range layovers {
+		b.AddLayover(l.Airport, l.City, l.Duration)
+	}
+	return b.Build()
+}
+
+// amadeusAdultFare is the pricing of the offer's first adult and the
+// per-person fare, which is what every provider reports. Offers priced
+// without a breakdown fall back to the total per traveller.
+func amadeusAdultFare(o amadeusOffer) (amadeusTravelerPricing, float64, error) {
+	for _, tp := range o.TravelerPricings {
+		if tp.TravelerType != "ADULT" {
+			continue
+		}
+		amount, err := strconv.ParseFloat(tp.Price.Total, 64)
+		if err != nil {
+			return tp, 0, fmt.Errorf("invalid adult fare %q", tp.Price.Total)
+		}
+		return tp, amount, nil
+	}
+	if len(o.TravelerPricings) == 0 {
+		return amadeusTravelerPricing{}, 0, fmt.Errorf("no traveller pricing")
+	}
+	total, err := strconv.ParseFloat(o.Price.GrandTotal, 64)
+	if err != nil {
+		return amadeusTravelerPricing{}, 0, fmt.Errorf("invalid total %q", o.Price.GrandTotal)
+	}
+	return o.TravelerPricings[0], total / float64(len(o.TravelerPricings)), nil
+}
+
+func amadeusBaggageKg(b amadeusBaggage, perPiece float64) float64 {
+	if b.Weight > 0 {
+		if strings.EqualFold(b.WeightUnit, "LB") {
+			return float64(b.Weight) * 0.4536
+		}
+		return float64(b.Weight)
+	}
+	return float64(b.Quantity) * perPiece
+}
+
+// amadeusLocalTime reads a segment time, which Amadeus gives in the
+// airport's local time without an offset, so the airport must be known.
+func amadeusLocalTime(e amadeusEndpoint) (time.Time, error) {
+	loc, err := timezone.LocationByAirport(e.IATACode)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation("2006-01-02T15:04:05", e.At, loc)
+}
+
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)D)?T?(?:(\d+)H)?(?:(\d+)M)?$`)
+
+// parseISODuration reads durations such as "PT2H10M" or "P1DT3H" in
+// minutes.
+func parseISODuration(s string) (int, error) {
+	m := isoDuration.FindStringSubmatch(s)
+	if m == nil || s == "P" || s == "PT" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var minutes int
+	for i, scale := range []int{24 * 60, 60, 1} {
+		if m[i+1] != "" {
+			n, _ := strconv.Atoi(m[i+1])
+			minutes += n * scale
+		}
+	}
+	return minutes, nil
+}
+
+// titleCase turns Amadeus's upper-case names ("GARUDA INDONESIA") into
+// "Garuda Indonesia".
+func titleCase(s string) string {
+	words := strings.Fields(strings.ToLower(s))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
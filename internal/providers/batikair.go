@@ -3,16 +3,15 @@ package providers
 import (
 	"context"
 	"encoding/json"
-	"math/rand"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
 	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/normalize"
 	"github.com/dharmasatrya/flightsearch/internal/providers/data"
 	"github.com/dharmasatrya/flightsearch/internal/timezone"
-	"github.com/dharmasatrya/flightsearch/pkg/currency"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
 )
 
 type batikResponse struct {
@@ -36,6 +35,15 @@ type batikFlight struct {
 	AircraftType     string            `json:"aircraftType"`
 	IncludedServices []string          `json:"includedServices"`
 	BaggageAllowance string            `json:"baggageAllowance"`
+	AncillaryOffers  []batikAncillary  `json:"ancillaryOffers"`
+}
+
+// batikAncillary is an extra for sale; WeightKg is set for EXTRA_BAGGAGE.
+type batikAncillary struct {
+	ServiceType  string  `json:"serviceType"`
+	WeightKg     float64 `json:"weightKg"`
+	Price        float64 `json:"price"`
+	CurrencyCode string  `json:"currencyCode"`
 }
 
 type batikCarrier struct {
@@ -69,29 +77,59 @@ type batikFare struct {
 }
 
 type BatikAirProvider struct {
+	endpoint
+
 	flights []batikFlight
+	rng     *Rand
 }
 
-func NewBatikAirProvider() (*BatikAirProvider, error) {
+func NewBatikAirProvider(rng *Rand) (*BatikAirProvider, error) {
 	var resp batikResponse
 	if err := json.Unmarshal(data.BatikAirData, &resp); err != nil {
 		return nil, err
 	}
-	return &BatikAirProvider{flights: resp.Data.AvailableFlights}, nil
+	return &BatikAirProvider{flights: resp.Data.AvailableFlights, rng: rng}, nil
 }
 
 func (p *BatikAirProvider) Name() string {
 	return "batikair"
 }
 
+func (p *BatikAirProvider) BookingURLTemplate() string {
+	return "https://www.batikair.com/booking/select?from={origin}&to={destination}&date={date}&flight={flight_number}&cabin={cabin}&adt={adults}&chd={children}&inf={infants}&affiliate={affiliate}"
+}
+
+func (p *BatikAirProvider) PassengerRules() PassengerRules {
+	return PassengerRules{
+		AdultAge:              12,
+		InfantAge:             2,
+		MaxLapInfantsPerAdult: 1,
+		MaxChildrenPerAdult:   3,
+	}
+}
+
+func (p *BatikAirProvider) Capabilities() Capabilities {
+	var c catalog
+	for _, f := range p.flights {
+		c.add(f.DepartureInfo.AirportCode, f.ArrivalInfo.AirportCode, f.CabinType)
+	}
+	return c.capabilities()
+}
+
 func (p *BatikAirProvider) Search(ctx context.Context, req models.SearchRequest) ([]models.Flight, error) {
-	delay := time.Duration(200+rand.Intn(200)) * time.Millisecond
+	delay := p.rng.delay(200, 200)
 	select {
 	case <-time.After(delay):
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 
+	date, err := time.Parse("2006-01-02", req.DepartureDate)
+	if err != nil {
+		return nil, errorsx.Errorf(errorsx.Invalid, "invalid departure date %q", req.DepartureDate)
+	}
+	shift := fixtureShift(date)
+
 	var results []models.Flight
 	for _, f := range p.flights {
 		if !strings.EqualFold(f.DepartureInfo.AirportCode, req.Origin) ||
@@ -99,7 +137,7 @@ func (p *BatikAirProvider) Search(ctx context.Context, req models.SearchRequest)
 			continue
 		}
 
-		if !strings.EqualFold(f.CabinType, req.CabinClass) {
+		if !cabin.Matches(req.CabinClass, f.CabinType) {
 			continue
 		}
 
@@ -108,141 +146,63 @@ func (p *BatikAirProvider) Search(ctx context.Context, req models.SearchRequest)
 			continue
 		}
 
-		reqDate, err := time.Parse("2006-01-02", req.DepartureDate)
-		if err != nil {
-			continue
-		}
-		if depTime.Year() != reqDate.Year() || depTime.Month() != reqDate.Month() || depTime.Day() != reqDate.Day() {
+		if timezone.LocalDate(depTime.AddDate(0, 0, shift), f.DepartureInfo.AirportCode) != req.DepartureDate {
 			continue
 		}
 
 		flight, err := p.normalize(f)
 		if err != nil {
+			debugf(ctx, "%s: skipping flight %s: %v", p.Name(), f.FlightID, err)
 			continue
 		}
+		shiftFlight(&flight, shift)
 		results = append(results, flight)
 	}
 
+	debugf(ctx, "%s: %d of %d flights match %s-%s on %s in %s", p.Name(), len(results), len(p.flights), req.Origin, req.Destination, req.DepartureDate, req.CabinClass)
 	return results, nil
 }
 
 func (p *BatikAirProvider) normalize(f batikFlight) (models.Flight, error) {
-	depTime, err := timezone.ParseTimeWithOffset(f.DepartureInfo.DepartureTime, "")
-	if err != nil {
-		return models.Flight{}, err
+	cabinKg, _ := normalize.LabelledKg(f.BaggageAllowance, "cabin")
+	checkedKg, _ := normalize.LabelledKg(f.BaggageAllowance, "checked")
+
+	b := normalize.New(p.Name(), f.FlightID)
+	minutes, err := normalize.HoursMinutes(f.TravelTime)
+	b.Fail(err)
+	b.SetAirline(f.OperatingCarrier.CarrierCode, f.OperatingCarrier.CarrierName).
+		SetFlightNumber(f.FlightNo).
+		SetCabin(f.CabinType).
+		SetDeparture(f.DepartureInfo.AirportCode, f.DepartureInfo.CityName, f.DepartureInfo.TerminalNo, b.Time(f.DepartureInfo.DepartureTime, "")).
+		SetArrival(f.ArrivalInfo.AirportCode, f.ArrivalInfo.CityName, f.ArrivalInfo.TerminalNo, b.Time(f.ArrivalInfo.ArrivalTime, "")).
+		SetDuration(minutes).
+		SetStops(f.NumberOfStops).
+		SetPrice(f.Fare.TotalPrice, f.Fare.CurrencyCode).
+		SetSeats(f.SeatsAvailable).
+		SetAircraft(f.AircraftType).
+		SetAmenities(f.IncludedServices).
+		SetBaggage(cabinKg, checkedKg).
+		SetAncillaries(batikAncillaries(f.AncillaryOffers))
+	for _, c := range f.ConnectionPoints {
+		b.AddLayover(c.Airport, c.City, c.LayoverMinutes)
 	}
-
-	arrTime, err := timezone.ParseTimeWithOffset(f.ArrivalInfo.ArrivalTime, "")
-	if err != nil {
-		return models.Flight{}, err
-	}
-
-	depTime = timezone.ConvertToTimezone(depTime, f.DepartureInfo.AirportCode)
-	arrTime = timezone.ConvertToTimezone(arrTime, f.ArrivalInfo.AirportCode)
-
-	totalMinutes := parseTravelTime(f.TravelTime)
-	hours := totalMinutes / 60
-	mins := totalMinutes % 60
-
-	layovers := make([]models.Layover, len(f.ConnectionPoints))
-	for i, c := range f.ConnectionPoints {
-		layovers[i] = models.Layover{
-			Airport:  c.Airport,
-			City:     c.City,
-			Duration: c.LayoverMinutes,
-		}
-	}
-
-	cabinKg, checkedKg := parseBatikBaggage(f.BaggageAllowance)
-
-	var depTerminal, arrTerminal *string
-	if f.DepartureInfo.TerminalNo != "" {
-		t := f.DepartureInfo.TerminalNo
-		depTerminal = &t
-	}
-	if f.ArrivalInfo.TerminalNo != "" {
-		t := f.ArrivalInfo.TerminalNo
-		arrTerminal = &t
-	}
-
-	var aircraft *string
-	if f.AircraftType != "" {
-		a := f.AircraftType
-		aircraft = &a
-	}
-
-	return models.Flight{
-		ID:       f.FlightID,
-		Provider: p.Name(),
-		Airline: models.Airline{
-			Code: f.OperatingCarrier.CarrierCode,
-			Name: f.OperatingCarrier.CarrierName,
-		},
-		FlightNumber: f.FlightNo,
-		Departure: models.Location{
-			Airport:  f.DepartureInfo.AirportCode,
-			City:     f.DepartureInfo.CityName,
-			Terminal: depTerminal,
-			Time:     depTime,
-			Timezone: timezone.GetTimezoneByAirport(f.DepartureInfo.AirportCode),
-		},
-		Arrival: models.Location{
-			Airport:  f.ArrivalInfo.AirportCode,
-			City:     f.ArrivalInfo.CityName,
-			Terminal: arrTerminal,
-			Time:     arrTime,
-			Timezone: timezone.GetTimezoneByAirport(f.ArrivalInfo.AirportCode),
-		},
-		Duration: models.Duration{
-			Hours:        hours,
-			Minutes:      mins,
-			TotalMinutes: totalMinutes,
-		},
-		Stops:    f.NumberOfStops,
-		Layovers: layovers,
-		Price: models.Price{
-			Amount:    f.Fare.TotalPrice,
-			Currency:  f.Fare.CurrencyCode,
-			Formatted: currency.FormatIDR(f.Fare.TotalPrice),
-		},
-		AvailableSeats: f.SeatsAvailable,
-		CabinClass:     f.CabinType,
-		Aircraft:       aircraft,
-		Amenities:      f.IncludedServices,
-		Baggage: models.Baggage{
-			CabinKg:   cabinKg,
-			CheckedKg: checkedKg,
-		},
-	}, nil
-}
-
-func parseTravelTime(s string) int {
-	re := regexp.MustCompile(`(?:(\d+)h)?\s*(?:(\d+)m)?`)
-	matches := re.FindStringSubmatch(s)
-
-	var hours, mins int
-	if len(matches) >= 2 && matches[1] != "" {
-		hours, _ = strconv.Atoi(matches[1])
-	}
-	if len(matches) >= 3 && matches[2] != "" {
-		mins, _ = strconv.Atoi(matches[2])
-	}
-
-	return hours*60 + mins
+	return b.Build()
 }
 
-func parseBatikBaggage(s string) (cabin, checked float64) {
-	s = strings.ToLower(s)
-
-	cabinRe := regexp.MustCompile(`(\d+(?:\.\d+)?)\s*kg\s*cabin`)
-	checkedRe := regexp.MustCompile(`(\d+(?:\.\d+)?)\s*kg\s*checked`)
-
-	if matches := cabinRe.FindStringSubmatch(s); len(matches) >= 2 {
-		cabin, _ = strconv.ParseFloat(matches[1], 64)
-	}
-	if matches := checkedRe.FindStringSubmatch(s); len(matches) >= 2 {
-		checked, _ = strconv.ParseFloat(matches[1], 64)
+// batikAncillaries returns the bags, seat and meal offered; seats are
+// offered at several prices, of which the lowest is shown.
+func batikAncillaries(offers []batikAncillary) (bags []models.BagOption, seat, meal *models.Price) {
+	for _, o := range offers {
+		switch o.ServiceType {
+		case "EXTRA_BAGGAGE":
+			bags = append(bags, normalize.Bag(o.WeightKg, o.Price, o.CurrencyCode))
+		case "SEAT_SELECTION":
+			if p := normalize.OptionalPrice(o.Price, o.CurrencyCode); p != nil && (seat == nil || p.Amount < seat.Amount) {
+				seat = p
+			}
+		case "MEAL":
+			meal = normalize.OptionalPrice(o.Price, o.CurrencyCode)
+		}
 	}
-
-	return cabin, checked
+	return bags, seat, meal
 }
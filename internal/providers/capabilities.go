@@ -0,0 +1,78 @@
+package providers
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
+)
+
+type Route struct {
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+}
+
+// Capabilities describe what a provider can be searched and booked for.
+type Capabilities struct {
+	Routes       []Route       `json:"routes"`
+	CabinClasses []cabin.Class `json:"cabin_classes"`
+	// NativeRoundTrip is whether the provider prices round trips itself;
+	// otherwise the aggregator combines two one-way searches.
+	NativeRoundTrip bool `json:"native_round_trip"`
+	// FareLock is whether the provider can hold a fare natively.
+	FareLock bool `json:"fare_lock"`
+	// DeepLink is whether the provider has a booking URL template.
+	DeepLink bool `json:"deep_link"`
+}
+
+// Describer is implemented by providers that can list their routes and
+// cabins.
+type Describer interface {
+	Capabilities() Capabilities
+}
+
+func CapabilitiesFor(p Provider) Capabilities {
+	var caps Capabilities
+	if d, ok := p.(Describer); ok {
+		caps = d.Capabilities()
+	}
+	_, caps.FareLock = p.(FareLocker)
+	_, caps.DeepLink = p.(DeepLinker)
+	return caps
+}
+
+// catalog collects the routes and cabins a provider's flights cover.
+type catalog struct {
+	routes map[Route]bool
+	cabins map[cabin.Class]bool
+}
+
+func (c *catalog) add(origin, destination, cabinLabel string) {
+	if c.routes == nil {
+		c.routes = make(map[Route]bool)
+		c.cabins = make(map[cabin.Class]bool)
+	}
+	c.routes[Route{Origin: strings.ToUpper(origin), Destination: strings.ToUpper(destination)}] = true
+	if class, ok := cabin.Parse(cabinLabel); ok {
+		c.cabins[class] = true
+	}
+}
+
+func (c *catalog) capabilities() Capabilities {
+	caps := Capabilities{Routes: []Route{}, CabinClasses: []cabin.Class{}}
+	for r := range c.routes {
+		caps.Routes = append(caps.Routes, r)
+	}
+	sort.Slice(caps.Routes, func(i, j int) bool {
+		if caps.Routes[i].Origin != caps.Routes[j].Origin {
+			return caps.Routes[i].Origin < caps.Routes[j].Origin
+		}
+		return caps.Routes[i].Destination < caps.Routes[j].Destination
+	})
+	for _, class := range cabin.Classes {
+		if c.cabins[class] {
+			caps.CabinClasses = append(caps.CabinClasses, class)
+		}
+	}
+	return caps
+}
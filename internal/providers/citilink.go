@@ -0,0 +1,291 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/aircraft"
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/normalize"
+	"github.com/dharmasatrya/flightsearch/internal/providers/data"
+	"github.com/dharmasatrya/flightsearch/internal/timezone"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+// citilinkResponse is a Navitaire-style availability response: each
+// journey is a chain of segments sold under several fare bundles.
+type citilinkResponse struct {
+	Journeys []citilinkJourney `json:"Journeys"`
+	Stations map[string]string `json:"Stations"`
+	Carriers map[string]string `json:"Carriers"`
+}
+
+type citilinkJourney struct {
+	JourneySellKey string            `json:"JourneySellKey"`
+	Segments       []citilinkSegment `json:"Segments"`
+	Fares          []citilinkFare    `json:"Fares"`
+	AvailableSsrs  []citilinkSSR     `json:"AvailableSsrs"`
+}
+
+type citilinkSegment struct {
+	CarrierCode string `json:"CarrierCode"`
+	// FlightNumber is padded to four characters, e.g. " 710".
+	FlightNumber     string `json:"FlightNumber"`
+	DepartureStation string `json:"DepartureStation"`
+	ArrivalStation   string `json:"ArrivalStation"`
+	// STD and STA are local times; the offsets are in minutes east of UTC.
+	STD                string `json:"STD"`
+	STA                string `json:"STA"`
+	UTCOffsetDeparture int    `json:"UTCOffsetDeparture"`
+	UTCOffsetArrival   int    `json:"UTCOffsetArrival"`
+	Equipment          string `json:"Equipment"`
+	DepartureTerminal  string `json:"DepartureTerminal"`
+	ArrivalTerminal    string `json:"ArrivalTerminal"`
+}
+
+// citilinkSSR is a special service request sold with the journey: XB
+// followed by the weight for an extra bag, SEAT or MEAL.
+type citilinkSSR struct {
+	SsrCode      string  `json:"SsrCode"`
+	Amount       float64 `json:"Amount"`
+	CurrencyCode string  `json:"CurrencyCode"`
+}
+
+type citilinkFare struct {
+	FareSellKey    string            `json:"FareSellKey"`
+	ProductClass   string            `json:"ProductClass"`
+	ClassOfService string            `json:"ClassOfService"`
+	CabinOfService string            `json:"CabinOfService"`
+	AvailableCount int               `json:"AvailableCount"`
+	CurrencyCode   string            `json:"CurrencyCode"`
+	PaxFares       []citilinkPaxFare `json:"PaxFares"`
+}
+
+type citilinkPaxFare struct {
+	PaxType        string `json:"PaxType"`
+	ServiceCharges []struct {
+		ChargeType string  `json:"ChargeType"`
+		Amount     float64 `json:"Amount"`
+	} `json:"ServiceCharges"`
+}
+
+// citilinkBundle is what a fare's product class includes.
+type citilinkBundle struct {
+	baggage   models.Baggage
+	amenities []string
+}
+
+var citilinkBundles = map[string]citilinkBundle{
+	// Green
+	"GR": {baggage: models.Baggage{CabinKg: 7, CheckedKg: 0}, amenities: []string{}},
+	// Supergreen
+	"SG": {baggage: models.Baggage{CabinKg: 7, CheckedKg: 20}, amenities: []string{"meal", "seat_selection"}},
+}
+
+// citilinkFlight is one journey sold under one of its fares.
+type citilinkFlight struct {
+	journey citilinkJourney
+	fare    citilinkFare
+}
+
+type CitilinkProvider struct {
+	endpoint
+
+	flights  []citilinkFlight
+	stations map[string]string
+	carriers map[string]string
+	rng      *Rand
+}
+
+func NewCitilinkProvider(rng *Rand) (*CitilinkProvider, error) {
+	var resp citilinkResponse
+	if err := json.Unmarshal(data.CitilinkData, &resp); err != nil {
+		return nil, err
+	}
+	var flights []citilinkFlight
+	for _, j := range resp.Journeys {
+		if len(j.Segments) == 0 {
+			return nil, fmt.Errorf("citilink: journey %q has no segments", j.JourneySellKey)
+		}
+		for _, f := range j.Fares {
+			flights = append(flights, citilinkFlight{journey: j, fare: f})
+		}
+	}
+	return &CitilinkProvider{flights: flights, stations: resp.Stations, carriers: resp.Carriers, rng: rng}, nil
+}
+
+func (p *CitilinkProvider) Name() string {
+	return "citilink"
+}
+
+func (p *CitilinkProvider) BookingURLTemplate() string {
+	return "https://book.citilink.co.id/search?orig={origin}&dest={destination}&depart={date}&flight={flight_number}&adult={adults}&child={children}&infant={infants}&partner={affiliate}"
+}
+
+func (p *CitilinkProvider) PassengerRules() PassengerRules {
+	return PassengerRules{
+		AdultAge:              12,
+		InfantAge:             2,
+		MaxLapInfantsPerAdult: 1,
+		MaxChildrenPerAdult:   3,
+	}
+}
+
+func (p *CitilinkProvider) Capabilities() Capabilities {
+	var c catalog
+	for _, f := range p.flights {
+		c.add(f.origin().DepartureStation, f.destination().ArrivalStation, f.fare.CabinOfService)
+	}
+	return c.capabilities()
+}
+
+func (f citilinkFlight) origin() citilinkSegment {
+	return f.journey.Segments[0]
+}
+
+func (f citilinkFlight) destination() citilinkSegment {
+	return f.journey.Segments[len(f.journey.Segments)-1]
+}
+
+func (p *CitilinkProvider) Search(ctx context.Context, req models.SearchRequest) ([]models.Flight, error) {
+	delay := p.rng.delay(150, 150)
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	date, err := time.Parse("2006-01-02", req.DepartureDate)
+	if err != nil {
+		return nil, errorsx.Errorf(errorsx.Invalid, "invalid departure date %q", req.DepartureDate)
+	}
+	shift := fixtureShift(date)
+
+	var results []models.Flight
+	for _, f := range p.flights {
+		if !strings.EqualFold(f.origin().DepartureStation, req.Origin) ||
+			!strings.EqualFold(f.destination().ArrivalStation, req.Destination) {
+			continue
+		}
+
+		if !cabin.Matches(req.CabinClass, f.fare.CabinOfService) {
+			continue
+		}
+
+		first := f.origin()
+		depTime, err := citilinkTime(first.STD, first.UTCOffsetDeparture, first.DepartureStation)
+		if err != nil {
+			continue
+		}
+
+		if timezone.LocalDate(depTime.AddDate(0, 0, shift), first.DepartureStation) != req.DepartureDate {
+			continue
+		}
+
+		flight, err := p.normalize(f)
+		if err != nil {
+			debugf(ctx, "%s: skipping fare %s: %v", p.Name(), f.fare.FareSellKey, err)
+			continue
+		}
+		shiftFlight(&flight, shift)
+		results = append(results, flight)
+	}
+
+	debugf(ctx, "%s: %d of %d fares match %s-%s on %s in %s", p.Name(), len(results), len(p.flights), req.Origin, req.Destination, req.DepartureDate, req.CabinClass)
+	return results, nil
+}
+
+func (p *CitilinkProvider) normalize(f citilinkFlight) (models.Flight, error) {
+	bundle, ok := citilinkBundles[f.fare.ProductClass]
+	if !ok {
+		return models.Flight{}, fmt.Errorf("unknown product class %q", f.fare.ProductClass)
+	}
+
+	var price float64
+	for _, pax := range f.fare.PaxFares {
+		if pax.PaxType != "ADT" {
+			continue
+		}
+		for _, c := range pax.ServiceCharges {
+			price += c.Amount
+		}
+	}
+	if price <= 0 {
+		return models.Flight{}, fmt.Errorf("no adult fare")
+	}
+
+	segments := f.journey.Segments
+	departs := make([]time.Time, len(segments))
+	arrives := make([]time.Time, len(segments))
+	numbers := make([]string, len(segments))
+	for i, s := range segments {
+		var err error
+		if departs[i], err = citilinkTime(s.STD, s.UTCOffsetDeparture, s.DepartureStation); err != nil {
+			return models.Flight{}, err
+		}
+		if arrives[i], err = citilinkTime(s.STA, s.UTCOffsetArrival, s.ArrivalStation); err != nil {
+			return models.Flight{}, err
+		}
+		numbers[i] = s.CarrierCode + strings.TrimSpace(s.FlightNumber)
+	}
+	first, last := f.origin(), f.destination()
+
+	var planeName string
+	if e, ok := aircraft.Normalize(first.Equipment); ok {
+		planeName = e.Name
+	}
+
+	// Journeys don't have IDs; the flights flown, date and fare bundle
+	// identify one.
+	id := strings.Join(numbers, "-") + "-" + departs[0].Format("20060102") + "-" + f.fare.ProductClass
+	b := normalize.New(p.Name(), id)
+	b.SetAirline(first.CarrierCode, p.carriers[first.CarrierCode]).
+		SetFlightNumber(first.CarrierCode+" "+strings.TrimSpace(first.FlightNumber)).
+		SetCabin(f.fare.CabinOfService).
+		SetDeparture(first.DepartureStation, p.stations[first.DepartureStation], first.DepartureTerminal, departs[0]).
+		SetArrival(last.ArrivalStation, p.stations[last.ArrivalStation], last.ArrivalTerminal, arrives[len(arrives)-1]).
+		SetPrice(price, f.fare.CurrencyCode).
+		SetSeats(f.fare.AvailableCount).
+		SetAircraft(planeName).
+		SetAmenities(bundle.amenities).
+		SetBaggage(bundle.baggage.CabinKg, bundle.baggage.CheckedKg).
+		SetAncillaries(citilinkAncillaries(f.journey.AvailableSsrs, bundle))
+	for i := 1; i < len(segments); i++ {
+		b.AddLayover(segments[i].DepartureStation, p.stations[segments[i].DepartureStation], int(departs[i].Sub(arrives[i-1]).Minutes()))
+	}
+	return b.Build()
+}
+
+// citilinkAncillaries returns the bags, seat and meal offered, leaving
+// out the extras the fare's bundle includes.
+func citilinkAncillaries(ssrs []citilinkSSR, bundle citilinkBundle) (bags []models.BagOption, seat, meal *models.Price) {
+	for _, s := range ssrs {
+		switch {
+		case s.SsrCode == "SEAT" && !slices.Contains(bundle.amenities, "seat_selection"):
+			seat = normalize.OptionalPrice(s.Amount, s.CurrencyCode)
+		case s.SsrCode == "MEAL" && !slices.Contains(bundle.amenities, "meal"):
+			meal = normalize.OptionalPrice(s.Amount, s.CurrencyCode)
+		case strings.HasPrefix(s.SsrCode, "XB"):
+			if kg, err := strconv.ParseFloat(strings.TrimPrefix(s.SsrCode, "XB"), 64); err == nil {
+				bags = append(bags, normalize.Bag(kg, s.Amount, s.CurrencyCode))
+			}
+		}
+	}
+	return bags, seat, meal
+}
+
+// citilinkTime reads a local time with its offset in minutes and converts
+// it to airport's zone.
+func citilinkTime(local string, offsetMinutes int, airport string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02T15:04:05", local, time.FixedZone("", offsetMinutes*60))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return timezone.ConvertToTimezone(t, airport), nil
+}
@@ -15,3 +15,9 @@ var BatikAirData []byte
 
 //go:embed airasia.json
 var AirAsiaData []byte
+
+//go:embed citilink.json
+var CitilinkData []byte
+
+//go:embed superairjet.json
+var SuperAirJetData []byte
@@ -0,0 +1,42 @@
+package providers
+
+// Endpointer is implemented by providers whose API is served from more than
+// one location, so each deployment region can call the nearest one.
+type Endpointer interface {
+	SetEndpoint(url string)
+	Endpoint() string
+}
+
+// endpoint is embedded by providers to satisfy Endpointer. An empty URL
+// means the provider's default endpoint.
+type endpoint struct {
+	url string
+}
+
+func (e *endpoint) SetEndpoint(url string) {
+	e.url = url
+}
+
+func (e *endpoint) Endpoint() string {
+	return e.url
+}
+
+// Endpoints maps region to provider name to API endpoint.
+type Endpoints map[string]map[string]string
+
+// Apply points every provider with an endpoint configured for region at it,
+// and returns the names of the providers it changed.
+func (eps Endpoints) Apply(region string, list []Provider) []string {
+	var applied []string
+	for _, p := range list {
+		url, ok := eps[region][p.Name()]
+		if !ok {
+			continue
+		}
+		if ep, ok := p.(Endpointer); ok {
+			ep.SetEndpoint(url)
+			applied = append(applied, p.Name())
+		}
+	}
+	return applied
+}
@@ -0,0 +1,32 @@
+package providers
+
+import (
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// fixtureDate is the day the embedded fixtures are scheduled on. A few
+// overnight flights leave just after midnight, on the day after.
+var fixtureDate = time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC)
+
+// fixtureShift is how many days the fixtures move so that their schedule
+// also runs on date. The days the fixtures are written for are served as
+// they are; later dates get the fixtureDate schedule, so searches in mock
+// and live mode find flights on any future date.
+func fixtureShift(date time.Time) int {
+	days := int(date.Sub(fixtureDate).Hours() / 24)
+	if days <= 1 {
+		return 0
+	}
+	return days
+}
+
+// shiftFlight moves a normalized fixture flight by days.
+func shiftFlight(f *models.Flight, days int) {
+	if days == 0 {
+		return
+	}
+	f.Departure.Time = f.Departure.Time.AddDate(0, 0, days)
+	f.Arrival.Time = f.Arrival.Time.AddDate(0, 0, days)
+}
@@ -3,16 +3,21 @@ package providers
 import (
 	"context"
 	"encoding/json"
-	"math/rand"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
 	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/normalize"
 	"github.com/dharmasatrya/flightsearch/internal/providers/data"
 	"github.com/dharmasatrya/flightsearch/internal/timezone"
-	"github.com/dharmasatrya/flightsearch/pkg/currency"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
 )
 
+var ErrHoldTooLong = errorsx.New(errorsx.Unprocessable, "requested hold period exceeds provider maximum")
+
 type garudaResponse struct {
 	Flights []garudaFlight `json:"flights"`
 }
@@ -32,6 +37,7 @@ type garudaFlight struct {
 	Aircraft     string          `json:"aircraft"`
 	Amenities    []string        `json:"amenities"`
 	Baggage      garudaBaggage   `json:"baggage"`
+	Ancillaries  garudaExtras    `json:"ancillaries"`
 }
 
 type garudaAirline struct {
@@ -62,30 +68,69 @@ type garudaBaggage struct {
 	Checked int `json:"checked"`
 }
 
+// garudaExtras are priced in the fare's currency.
+type garudaExtras struct {
+	ExtraBaggage []struct {
+		Kg     float64 `json:"kg"`
+		Amount float64 `json:"amount"`
+	} `json:"extra_baggage"`
+	SeatSelection float64 `json:"seat_selection"`
+	Meal          float64 `json:"meal"`
+}
+
 type GarudaProvider struct {
+	endpoint
+
 	flights []garudaFlight
+	rng     *Rand
 }
 
-func NewGarudaProvider() (*GarudaProvider, error) {
+func NewGarudaProvider(rng *Rand) (*GarudaProvider, error) {
 	var resp garudaResponse
 	if err := json.Unmarshal(data.GarudaData, &resp); err != nil {
 		return nil, err
 	}
-	return &GarudaProvider{flights: resp.Flights}, nil
+	return &GarudaProvider{flights: resp.Flights, rng: rng}, nil
 }
 
 func (p *GarudaProvider) Name() string {
 	return "garuda"
 }
 
+func (p *GarudaProvider) BookingURLTemplate() string {
+	return "https://www.garuda-indonesia.com/booking/flights?origin={origin}&destination={destination}&departureDate={date}&flightNumber={flight_number}&cabin={cabin}&adult={adults}&child={children}&infant={infants}&ref={affiliate}"
+}
+
+func (p *GarudaProvider) PassengerRules() PassengerRules {
+	return PassengerRules{
+		AdultAge:              12,
+		InfantAge:             2,
+		MaxLapInfantsPerAdult: 1,
+	}
+}
+
+func (p *GarudaProvider) Capabilities() Capabilities {
+	var c catalog
+	for _, f := range p.flights {
+		c.add(f.Departure.Airport, f.Arrival.Airport, f.CabinClass)
+	}
+	return c.capabilities()
+}
+
 func (p *GarudaProvider) Search(ctx context.Context, req models.SearchRequest) ([]models.Flight, error) {
-	delay := time.Duration(50+rand.Intn(50)) * time.Millisecond
+	delay := p.rng.delay(50, 50)
 	select {
 	case <-time.After(delay):
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 
+	date, err := time.Parse("2006-01-02", req.DepartureDate)
+	if err != nil {
+		return nil, errorsx.Errorf(errorsx.Invalid, "invalid departure date %q", req.DepartureDate)
+	}
+	shift := fixtureShift(date)
+
 	var results []models.Flight
 	for _, f := range p.flights {
 		if !strings.EqualFold(f.Departure.Airport, req.Origin) ||
@@ -93,7 +138,7 @@ func (p *GarudaProvider) Search(ctx context.Context, req models.SearchRequest) (
 			continue
 		}
 
-		if !strings.EqualFold(f.CabinClass, req.CabinClass) {
+		if !cabin.Matches(req.CabinClass, f.CabinClass) {
 			continue
 		}
 
@@ -102,107 +147,63 @@ func (p *GarudaProvider) Search(ctx context.Context, req models.SearchRequest) (
 			continue
 		}
 
-		reqDate, err := time.Parse("2006-01-02", req.DepartureDate)
-		if err != nil {
-			continue
-		}
-		if depTime.Year() != reqDate.Year() || depTime.Month() != reqDate.Month() || depTime.Day() != reqDate.Day() {
+		if timezone.LocalDate(depTime.AddDate(0, 0, shift), f.Departure.Airport) != req.DepartureDate {
 			continue
 		}
 
 		flight, err := p.normalize(f)
 		if err != nil {
+			debugf(ctx, "%s: skipping flight %s: %v", p.Name(), f.FlightID, err)
 			continue
 		}
+		shiftFlight(&flight, shift)
 		results = append(results, flight)
 	}
 
+	debugf(ctx, "%s: %d of %d flights match %s-%s on %s in %s", p.Name(), len(results), len(p.flights), req.Origin, req.Destination, req.DepartureDate, req.CabinClass)
 	return results, nil
 }
 
-func (p *GarudaProvider) normalize(f garudaFlight) (models.Flight, error) {
-	depTime, err := timezone.ParseTimeWithOffset(f.Departure.Time, "")
-	if err != nil {
-		return models.Flight{}, err
-	}
-
-	arrTime, err := timezone.ParseTimeWithOffset(f.Arrival.Time, "")
-	if err != nil {
-		return models.Flight{}, err
+func (p *GarudaProvider) LockFare(ctx context.Context, flight models.Flight, passengers int, hold time.Duration) (LockTerms, error) {
+	// Garuda charges a flat 5% of the fare (min IDR 50.000) for holds up to 72 hours.
+	if hold > 72*time.Hour {
+		return LockTerms{}, ErrHoldTooLong
 	}
 
-	depTime = timezone.ConvertToTimezone(depTime, f.Departure.Airport)
-	arrTime = timezone.ConvertToTimezone(arrTime, f.Arrival.Airport)
-
-	layovers := make([]models.Layover, len(f.Layovers))
-	for i, l := range f.Layovers {
-		layovers[i] = models.Layover{
-			Airport:  l.Airport,
-			City:     l.City,
-			Duration: l.Duration,
-		}
+	fee := flight.Price.Amount * float64(passengers) * 0.05
+	if fee < 50000 {
+		fee = 50000
 	}
 
-	hours := f.Duration / 60
-	mins := f.Duration % 60
+	return LockTerms{
+		Fee:       math.Round(fee),
+		Reference: "GA-HOLD-" + strings.ReplaceAll(flight.FlightNumber, " ", "") + "-" + strconv.FormatInt(time.Now().Unix(), 36),
+	}, nil
+}
 
-	var depTerminal, arrTerminal *string
-	if f.Departure.Terminal != "" {
-		t := f.Departure.Terminal
-		depTerminal = &t
-	}
-	if f.Arrival.Terminal != "" {
-		t := f.Arrival.Terminal
-		arrTerminal = &t
+func (p *GarudaProvider) normalize(f garudaFlight) (models.Flight, error) {
+	b := normalize.New(p.Name(), f.FlightID)
+	b.SetAirline(f.Airline.Code, f.Airline.Name).
+		SetFlightNumber(f.FlightNumber).
+		SetCabin(f.CabinClass).
+		SetDeparture(f.Departure.Airport, f.Departure.City, f.Departure.Terminal, b.Time(f.Departure.Time, "")).
+		SetArrival(f.Arrival.Airport, f.Arrival.City, f.Arrival.Terminal, b.Time(f.Arrival.Time, "")).
+		SetDuration(f.Duration).
+		SetStops(f.Stops).
+		SetPrice(f.Price.Amount, f.Price.Currency).
+		SetSeats(f.Seats).
+		SetAircraft(f.Aircraft).
+		SetAmenities(f.Amenities).
+		SetBaggage(float64(f.Baggage.CarryOn), float64(f.Baggage.Checked))
+	for _, l := range f.Layovers {
+		b.AddLayover(l.Airport, l.City, l.Duration)
 	}
 
-	var aircraft *string
-	if f.Aircraft != "" {
-		a := f.Aircraft
-		aircraft = &a
+	bags := make([]models.BagOption, len(f.Ancillaries.ExtraBaggage))
+	for i, bag := range f.Ancillaries.ExtraBaggage {
+		bags[i] = normalize.Bag(bag.Kg, bag.Amount, f.Price.Currency)
 	}
-
-	return models.Flight{
-		ID:       f.FlightID,
-		Provider: p.Name(),
-		Airline: models.Airline{
-			Code: f.Airline.Code,
-			Name: f.Airline.Name,
-		},
-		FlightNumber: f.FlightNumber,
-		Departure: models.Location{
-			Airport:  f.Departure.Airport,
-			City:     f.Departure.City,
-			Terminal: depTerminal,
-			Time:     depTime,
-			Timezone: timezone.GetTimezoneByAirport(f.Departure.Airport),
-		},
-		Arrival: models.Location{
-			Airport:  f.Arrival.Airport,
-			City:     f.Arrival.City,
-			Terminal: arrTerminal,
-			Time:     arrTime,
-			Timezone: timezone.GetTimezoneByAirport(f.Arrival.Airport),
-		},
-		Duration: models.Duration{
-			Hours:        hours,
-			Minutes:      mins,
-			TotalMinutes: f.Duration,
-		},
-		Stops:    f.Stops,
-		Layovers: layovers,
-		Price: models.Price{
-			Amount:    f.Price.Amount,
-			Currency:  f.Price.Currency,
-			Formatted: currency.FormatIDR(f.Price.Amount),
-		},
-		AvailableSeats: f.Seats,
-		CabinClass:     f.CabinClass,
-		Aircraft:       aircraft,
-		Amenities:      f.Amenities,
-		Baggage: models.Baggage{
-			CabinKg:   float64(f.Baggage.CarryOn),
-			CheckedKg: float64(f.Baggage.Checked),
-		},
-	}, nil
+	seat := normalize.OptionalPrice(f.Ancillaries.SeatSelection, f.Price.Currency)
+	meal := normalize.OptionalPrice(f.Ancillaries.Meal, f.Price.Currency)
+	return b.SetAncillaries(bags, seat, meal).Build()
 }
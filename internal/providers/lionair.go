@@ -3,16 +3,15 @@ package providers
 import (
 	"context"
 	"encoding/json"
-	"math/rand"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
 	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/normalize"
 	"github.com/dharmasatrya/flightsearch/internal/providers/data"
 	"github.com/dharmasatrya/flightsearch/internal/timezone"
-	"github.com/dharmasatrya/flightsearch/pkg/currency"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
 )
 
 type lionResponse struct {
@@ -36,6 +35,17 @@ type lionFlight struct {
 	PlaneType   string         `json:"plane_type"`
 	Services    []string       `json:"services"`
 	Baggage     lionBaggage    `json:"baggage"`
+	Extras      lionExtras     `json:"extras"`
+}
+
+// lionExtras are priced in the fare's currency.
+type lionExtras struct {
+	ExcessBaggage []struct {
+		Weight string  `json:"weight"`
+		Price  float64 `json:"price"`
+	} `json:"excess_baggage"`
+	SeatSelection float64 `json:"seat_selection"`
+	Meal          float64 `json:"meal"`
 }
 
 type lionCarrier struct {
@@ -72,29 +82,59 @@ type lionBaggage struct {
 }
 
 type LionAirProvider struct {
+	endpoint
+
 	flights []lionFlight
+	rng     *Rand
 }
 
-func NewLionAirProvider() (*LionAirProvider, error) {
+func NewLionAirProvider(rng *Rand) (*LionAirProvider, error) {
 	var resp lionResponse
 	if err := json.Unmarshal(data.LionAirData, &resp); err != nil {
 		return nil, err
 	}
-	return &LionAirProvider{flights: resp.Results}, nil
+	return &LionAirProvider{flights: resp.Results, rng: rng}, nil
 }
 
 func (p *LionAirProvider) Name() string {
 	return "lionair"
 }
 
+func (p *LionAirProvider) BookingURLTemplate() string {
+	return "https://www.lionair.co.id/booking/availability?from={origin}&to={destination}&date={date}&flight={flight_number}&adult={adults}&child={children}&infant={infants}&agent={affiliate}"
+}
+
+func (p *LionAirProvider) PassengerRules() PassengerRules {
+	return PassengerRules{
+		AdultAge:              12,
+		InfantAge:             2,
+		MaxLapInfantsPerAdult: 1,
+		MaxChildrenPerAdult:   3,
+	}
+}
+
+func (p *LionAirProvider) Capabilities() Capabilities {
+	var c catalog
+	for _, f := range p.flights {
+		c.add(f.Origin.Code, f.Destination.Code, f.Class)
+	}
+	return c.capabilities()
+}
+
 func (p *LionAirProvider) Search(ctx context.Context, req models.SearchRequest) ([]models.Flight, error) {
-	delay := time.Duration(100+rand.Intn(100)) * time.Millisecond
+	delay := p.rng.delay(100, 100)
 	select {
 	case <-time.After(delay):
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 
+	date, err := time.Parse("2006-01-02", req.DepartureDate)
+	if err != nil {
+		return nil, errorsx.Errorf(errorsx.Invalid, "invalid departure date %q", req.DepartureDate)
+	}
+	shift := fixtureShift(date)
+
 	var results []models.Flight
 	for _, f := range p.flights {
 		if !strings.EqualFold(f.Origin.Code, req.Origin) ||
@@ -102,7 +142,7 @@ func (p *LionAirProvider) Search(ctx context.Context, req models.SearchRequest)
 			continue
 		}
 
-		if !strings.EqualFold(f.Class, req.CabinClass) {
+		if !cabin.Matches(req.CabinClass, f.Class) {
 			continue
 		}
 
@@ -111,125 +151,56 @@ func (p *LionAirProvider) Search(ctx context.Context, req models.SearchRequest)
 			continue
 		}
 
-		reqDate, err := time.Parse("2006-01-02", req.DepartureDate)
-		if err != nil {
-			continue
-		}
-		if depTime.Year() != reqDate.Year() || depTime.Month() != reqDate.Month() || depTime.Day() != reqDate.Day() {
+		if timezone.LocalDate(depTime.AddDate(0, 0, shift), f.Origin.Code) != req.DepartureDate {
 			continue
 		}
 
 		flight, err := p.normalize(f)
 		if err != nil {
+			debugf(ctx, "%s: skipping flight %s: %v", p.Name(), f.ID, err)
 			continue
 		}
+		shiftFlight(&flight, shift)
 		results = append(results, flight)
 	}
 
+	debugf(ctx, "%s: %d of %d flights match %s-%s on %s in %s", p.Name(), len(results), len(p.flights), req.Origin, req.Destination, req.DepartureDate, req.CabinClass)
 	return results, nil
 }
 
 func (p *LionAirProvider) normalize(f lionFlight) (models.Flight, error) {
-	depTime, err := timezone.ParseTimeWithOffset(f.Schedule.Departure, f.Schedule.Timezone)
-	if err != nil {
-		return models.Flight{}, err
-	}
-
-	arrTime, err := timezone.ParseTimeWithOffset(f.Schedule.Arrival, f.Schedule.Timezone)
-	if err != nil {
-		return models.Flight{}, err
-	}
-
-	arrTime = timezone.ConvertToTimezone(arrTime, f.Destination.Code)
-
 	stops := f.StopCount
 	if f.IsDirect {
 		stops = 0
 	}
-
-	layovers := make([]models.Layover, len(f.Stopovers))
-	for i, s := range f.Stopovers {
-		layovers[i] = models.Layover{
-			Airport:  s.AirportCode,
-			City:     s.CityName,
-			Duration: s.WaitTime,
-		}
+	cabinKg, _ := normalize.Kg(f.Baggage.Cabin)
+	checkedKg, _ := normalize.Kg(f.Baggage.Hold)
+
+	b := normalize.New(p.Name(), f.ID)
+	b.SetAirline(f.Carrier.IATA, f.Carrier.FullName).
+		SetFlightNumber(f.FlightCode).
+		SetCabin(f.Class).
+		// The schedule's timezone is the origin's; arrivals are local to
+		// the destination.
+		SetDeparture(f.Origin.Code, f.Origin.Name, f.Origin.Gate, b.Time(f.Schedule.Departure, f.Schedule.Timezone)).
+		SetArrival(f.Destination.Code, f.Destination.Name, f.Destination.Gate, b.Time(f.Schedule.Arrival, timezone.GetTimezoneByAirport(f.Destination.Code))).
+		SetDuration(f.FlightTime).
+		SetStops(stops).
+		SetPrice(f.Pricing.Total, f.Pricing.Currency).
+		SetSeats(f.Seats).
+		SetAircraft(f.PlaneType).
+		SetAmenities(f.Services).
+		SetBaggage(cabinKg, checkedKg)
+	for _, s := range f.Stopovers {
+		b.AddLayover(s.AirportCode, s.CityName, s.WaitTime)
 	}
 
-	hours := f.FlightTime / 60
-	mins := f.FlightTime % 60
-
-	cabinKg := parseBaggageWeight(f.Baggage.Cabin)
-	checkedKg := parseBaggageWeight(f.Baggage.Hold)
-
-	var depTerminal, arrTerminal *string
-	if f.Origin.Gate != "" {
-		t := f.Origin.Gate
-		depTerminal = &t
-	}
-	if f.Destination.Gate != "" {
-		t := f.Destination.Gate
-		arrTerminal = &t
-	}
-
-	var aircraft *string
-	if f.PlaneType != "" {
-		a := f.PlaneType
-		aircraft = &a
-	}
-
-	return models.Flight{
-		ID:       f.ID,
-		Provider: p.Name(),
-		Airline: models.Airline{
-			Code: f.Carrier.IATA,
-			Name: f.Carrier.FullName,
-		},
-		FlightNumber: f.FlightCode,
-		Departure: models.Location{
-			Airport:  f.Origin.Code,
-			City:     f.Origin.Name,
-			Terminal: depTerminal,
-			Time:     depTime,
-			Timezone: timezone.GetTimezoneByAirport(f.Origin.Code),
-		},
-		Arrival: models.Location{
-			Airport:  f.Destination.Code,
-			City:     f.Destination.Name,
-			Terminal: arrTerminal,
-			Time:     arrTime,
-			Timezone: timezone.GetTimezoneByAirport(f.Destination.Code),
-		},
-		Duration: models.Duration{
-			Hours:        hours,
-			Minutes:      mins,
-			TotalMinutes: f.FlightTime,
-		},
-		Stops:    stops,
-		Layovers: layovers,
-		Price: models.Price{
-			Amount:    f.Pricing.Total,
-			Currency:  f.Pricing.Currency,
-			Formatted: currency.FormatIDR(f.Pricing.Total),
-		},
-		AvailableSeats: f.Seats,
-		CabinClass:     f.Class,
-		Aircraft:       aircraft,
-		Amenities:      f.Services,
-		Baggage: models.Baggage{
-			CabinKg:   cabinKg,
-			CheckedKg: checkedKg,
-		},
-	}, nil
-}
-
-func parseBaggageWeight(s string) float64 {
-	re := regexp.MustCompile(`(\d+(?:\.\d+)?)\s*kg`)
-	matches := re.FindStringSubmatch(strings.ToLower(s))
-	if len(matches) >= 2 {
-		if v, err := strconv.ParseFloat(matches[1], 64); err == nil {
-			return v
-		}
+	bags := make([]models.BagOption, len(f.Extras.ExcessBaggage))
+	for i, bag := range f.Extras.ExcessBaggage {
+		kg, _ := normalize.Kg(bag.Weight)
+		bags[i] = normalize.Bag(kg, bag.Price, f.Pricing.Currency)
 	}
-	return 0
+	seat := normalize.OptionalPrice(f.Extras.SeatSelection, f.Pricing.Currency)
+	meal := normalize.OptionalPrice(f.Extras.Meal, f.Pricing.Currency)
+	return b.SetAncillaries(bags, seat, meal).Build()
 }
@@ -0,0 +1,58 @@
+package providers
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Provider modes. Both serve the embedded fixtures; they differ in where
+// the simulated latency and failures come from.
+const (
+	// ModeLive draws latency and failures from a clock-seeded source, so
+	// no two runs behave alike.
+	ModeLive = "live"
+	// ModeMock draws them from a seeded source, so integration tests and
+	// demos replay exactly.
+	ModeMock = "mock"
+)
+
+var Modes = []string{ModeLive, ModeMock}
+
+// Rand is the source a provider draws its simulated latency and failures
+// from. It is safe for concurrent use.
+type Rand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+// NewRand returns provider's source for mode. In mock mode it is seeded
+// from seed and the provider name, so each provider has its own sequence
+// and repeats it as long as its own calls arrive in the same order,
+// however calls to other providers interleave.
+func NewRand(mode string, seed int64, provider string) *Rand {
+	if mode != ModeMock {
+		seed = time.Now().UnixNano()
+	}
+	h := fnv.New64a()
+	h.Write([]byte(provider))
+	return &Rand{r: rand.New(rand.NewSource(seed ^ int64(h.Sum64())))}
+}
+
+func (r *Rand) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Intn(n)
+}
+
+func (r *Rand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Float64()
+}
+
+// delay is a simulated response time between base and base+spread.
+func (r *Rand) delay(base, spread int) time.Duration {
+	return time.Duration(base+r.Intn(spread)) * time.Millisecond
+}
@@ -0,0 +1,70 @@
+package providers
+
+import (
+	"fmt"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// PassengerRules are an airline's limits on who may travel together.
+type PassengerRules struct {
+	// AdultAge is the age from which a passenger counts as an adult.
+	AdultAge int `json:"adult_age"`
+	// InfantAge is the age below which a child may travel on a lap.
+	InfantAge             int `json:"infant_age"`
+	MaxLapInfantsPerAdult int `json:"max_lap_infants_per_adult"`
+	// MaxChildrenPerAdult caps seated children per adult; 0 means no cap.
+	MaxChildrenPerAdult int `json:"max_children_per_adult,omitempty"`
+}
+
+// DefaultPassengerRules apply to providers that don't publish their own.
+var DefaultPassengerRules = PassengerRules{
+	AdultAge:              12,
+	InfantAge:             2,
+	MaxLapInfantsPerAdult: 1,
+}
+
+// PassengerRuler is implemented by providers with their own passenger rules.
+type PassengerRuler interface {
+	PassengerRules() PassengerRules
+}
+
+func PassengerRulesFor(p Provider) PassengerRules {
+	if r, ok := p.(PassengerRuler); ok {
+		return r.PassengerRules()
+	}
+	return DefaultPassengerRules
+}
+
+// Check returns why the airline can't take mix, or nothing if it can.
+// Children at or above AdultAge count as adults.
+func (r PassengerRules) Check(mix models.PassengerMix) []string {
+	var reasons []string
+
+	adults, children := mix.Adults, 0
+	for _, age := range mix.ChildAges {
+		if age >= r.AdultAge {
+			adults++
+		} else {
+			children++
+		}
+	}
+
+	for _, age := range mix.LapInfantAges {
+		if age >= r.InfantAge {
+			reasons = append(reasons, fmt.Sprintf("lap infants must be under %d; a %d-year-old needs a seat", r.InfantAge, age))
+			break
+		}
+	}
+	if adults == 0 {
+		reasons = append(reasons, fmt.Sprintf("children under %d must travel with an adult", r.AdultAge))
+		return reasons
+	}
+	if n := len(mix.LapInfantAges); n > adults*r.MaxLapInfantsPerAdult {
+		reasons = append(reasons, fmt.Sprintf("at most %d lap infant(s) per adult, party has %d for %d adult(s)", r.MaxLapInfantsPerAdult, n, adults))
+	}
+	if r.MaxChildrenPerAdult > 0 && children > adults*r.MaxChildrenPerAdult {
+		reasons = append(reasons, fmt.Sprintf("at most %d child(ren) per adult, party has %d for %d adult(s)", r.MaxChildrenPerAdult, children, adults))
+	}
+	return reasons
+}
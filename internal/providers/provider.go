@@ -2,8 +2,10 @@ package providers
 
 import (
 	"context"
+	"time"
 
 	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
 )
 
 type Provider interface {
@@ -11,22 +13,25 @@ type Provider interface {
 	Search(ctx context.Context, req models.SearchRequest) ([]models.Flight, error)
 }
 
-type ProviderError struct {
-	Provider string
-	Err      error
+type LockTerms struct {
+	Fee       float64
+	Reference string
 }
 
-func (e *ProviderError) Error() string {
-	return e.Provider + ": " + e.Err.Error()
+// FareLocker is implemented by providers that can hold a fare natively.
+type FareLocker interface {
+	LockFare(ctx context.Context, flight models.Flight, passengers int, hold time.Duration) (LockTerms, error)
 }
 
-func (e *ProviderError) Unwrap() error {
-	return e.Err
+// DeepLinker is implemented by providers whose airline sells its flights
+// on its own site. The template is a deeplink.Template.
+type DeepLinker interface {
+	BookingURLTemplate() string
 }
 
-func NewProviderError(provider string, err error) *ProviderError {
-	return &ProviderError{
-		Provider: provider,
-		Err:      err,
+// debugf logs for searches whose options ask for debugging.
+func debugf(ctx context.Context, format string, args ...any) {
+	if models.SearchOptionsFrom(ctx).Debug {
+		reqctx.Logf(ctx, format, args...)
 	}
 }
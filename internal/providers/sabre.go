@@ -0,0 +1,692 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/aircraft"
+	"github.com/dharmasatrya/flightsearch/internal/airports"
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/normalize"
+	"github.com/dharmasatrya/flightsearch/internal/refdata"
+	"github.com/dharmasatrya/flightsearch/internal/timezone"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+// SabreCertURL is Sabre's certification environment.
+const SabreCertURL = "https://api.cert.platform.sabre.com"
+
+const (
+	// sabreRequestType asks Bargain Finder Max for up to 50 itineraries.
+	sabreRequestType = "50ITINS"
+	// sabreTokenMargin renews a session token this long before it
+	// expires, so it doesn't expire mid-request.
+	sabreTokenMargin = 5 * time.Minute
+	// sabreCheckedBagKg counts a checked bag allowed by piece, not weight,
+	// as the usual 23 kg.
+	sabreCheckedBagKg = 23
+)
+
+var ErrSabreCredentials = errorsx.New(errorsx.Unavailable, "API credentials rejected")
+
+// sabreCabins maps Sabre's cabin codes, which differ from IATA's for
+// premium economy, onto cabin classes.
+var sabreCabins = map[string]cabin.Class{
+	"Y": cabin.Economy,
+	"S": cabin.PremiumEconomy,
+	"C": cabin.Business,
+	"J": cabin.Business,
+	"F": cabin.First,
+	"P": cabin.First,
+}
+
+var sabreCabinCodes = map[cabin.Class]string{
+	cabin.Economy:        "Y",
+	cabin.PremiumEconomy: "S",
+	cabin.Business:       "C",
+	cabin.First:          "F",
+}
+
+type SabreConfig struct {
+	UserID   string
+	Password string
+	// PCC is the pseudo city code the searches are made from.
+	PCC string
+	// Domain defaults to "AA", Sabre's own.
+	Domain string
+	// BaseURL defaults to SabreCertURL.
+	BaseURL string
+	// Client defaults to http.DefaultClient; searches are bounded by their
+	// context.
+	Client *http.Client
+}
+
+// credentials are the client credentials Sabre's token endpoint takes: the
+// user ID, PCC and domain, and the password, each base64 encoded, then
+// encoded together.
+func (c SabreConfig) credentials() string {
+	enc := base64.StdEncoding.EncodeToString
+	client := enc([]byte("V1:" + c.UserID + ":" + c.PCC + ":" + c.Domain))
+	return enc([]byte(client + ":" + enc([]byte(c.Password))))
+}
+
+type sabreTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type sabreErrorResponse struct {
+	ErrorCode string `json:"errorCode"`
+	Message   string `json:"message"`
+	// The token endpoint answers in OAuth's format instead.
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type sabreSearchRequest struct {
+	Request sabreLowFareSearch `json:"OTA_AirLowFareSearchRQ"`
+}
+
+type sabreLowFareSearch struct {
+	Version                      string                   `json:"Version"`
+	POS                          sabrePOS                 `json:"POS"`
+	OriginDestinationInformation []sabreOriginDestination `json:"OriginDestinationInformation"`
+	TravelPreferences            sabreTravelPreferences   `json:"TravelPreferences"`
+	TravelerInfoSummary          sabreTravelerInfo        `json:"TravelerInfoSummary"`
+	TPAExtensions                sabreTPAExtensions       `json:"TPA_Extensions"`
+}
+
+type sabrePOS struct {
+	Source []struct {
+		PseudoCityCode string `json:"PseudoCityCode"`
+		RequestorID    struct {
+			Type        string `json:"Type"`
+			ID          string `json:"ID"`
+			CompanyName struct {
+				Code string `json:"Code"`
+			} `json:"CompanyName"`
+		} `json:"RequestorID"`
+	} `json:"Source"`
+}
+
+type sabreLocation struct {
+	LocationCode string `json:"LocationCode"`
+}
+
+type sabreOriginDestination struct {
+	RPH                 string        `json:"RPH"`
+	DepartureDateTime   string        `json:"DepartureDateTime"`
+	OriginLocation      sabreLocation `json:"OriginLocation"`
+	DestinationLocation sabreLocation `json:"DestinationLocation"`
+}
+
+type sabreTravelPreferences struct {
+	CabinPref []sabreCabinPref `json:"CabinPref,omitempty"`
+}
+
+type sabreCabinPref struct {
+	Cabin       string `json:"Cabin"`
+	PreferLevel string `json:"PreferLevel"`
+}
+
+type sabreTravelerInfo struct {
+	SeatsRequested   []int `json:"SeatsRequested"`
+	AirTravelerAvail []struct {
+		PassengerTypeQuantity []sabrePassengers `json:"PassengerTypeQuantity"`
+	} `json:"AirTravelerAvail"`
+	PriceRequestInformation struct {
+		CurrencyCode string `json:"CurrencyCode"`
+	} `json:"PriceRequestInformation"`
+}
+
+type sabrePassengers struct {
+	Code     string `json:"Code"`
+	Quantity int    `json:"Quantity"`
+}
+
+type sabreTPAExtensions struct {
+	IntelliSellTransaction struct {
+		RequestType struct {
+			Name string `json:"Name"`
+		} `json:"RequestType"`
+	} `json:"IntelliSellTransaction"`
+}
+
+// sabreSearchResponse is Bargain Finder Max's grouped itinerary response:
+// itineraries refer to legs, legs to schedules (segments) and fares to
+// baggage allowances by ID, so shared parts are listed once.
+type sabreSearchResponse struct {
+	Response struct {
+		Messages []struct {
+			Severity string `json:"severity"`
+			Code     string `json:"code"`
+			Text     string `json:"text"`
+		} `json:"messages"`
+		ScheduleDescs         []sabreSchedule         `json:"scheduleDescs"`
+		LegDescs              []sabreLeg              `json:"legDescs"`
+		BaggageAllowanceDescs []sabreBaggageAllowance `json:"baggageAllowanceDescs"`
+		ItineraryGroups       []sabreItineraryGroup   `json:"itineraryGroups"`
+	} `json:"groupedItineraryResponse"`
+}
+
+type sabreSchedule struct {
+	ID        int                   `json:"id"`
+	StopCount int                   `json:"stopCount"`
+	Departure sabreScheduleEndpoint `json:"departure"`
+	Arrival   sabreScheduleEndpoint `json:"arrival"`
+	Carrier   struct {
+		Marketing             string `json:"marketing"`
+		MarketingFlightNumber int    `json:"marketingFlightNumber"`
+		Equipment             struct {
+			Code string `json:"code"`
+		} `json:"equipment"`
+	} `json:"carrier"`
+}
+
+type sabreScheduleEndpoint struct {
+	Airport  string `json:"airport"`
+	City     string `json:"city"`
+	Terminal string `json:"terminal"`
+	// Time is local with its offset, e.g. "06:00:00+07:00".
+	Time string `json:"time"`
+	// DateAdjustment counts the days after the departure date an arrival
+	// falls on.
+	DateAdjustment int `json:"dateAdjustment"`
+}
+
+type sabreLeg struct {
+	ID          int `json:"id"`
+	ElapsedTime int `json:"elapsedTime"`
+	Schedules   []struct {
+		Ref int `json:"ref"`
+		// DepartureDateAdjustment counts the days after the leg's
+		// departure date this schedule departs on.
+		DepartureDateAdjustment int `json:"departureDateAdjustment"`
+	} `json:"schedules"`
+}
+
+type sabreBaggageAllowance struct {
+	ID         int    `json:"id"`
+	PieceCount int    `json:"pieceCount"`
+	Weight     int    `json:"weight"`
+	Unit       string `json:"unit"`
+}
+
+type sabreItineraryGroup struct {
+	GroupDescription struct {
+		LegDescriptions []struct {
+			DepartureDate string `json:"departureDate"`
+		} `json:"legDescriptions"`
+	} `json:"groupDescription"`
+	Itineraries []sabreItinerary `json:"itineraries"`
+}
+
+type sabreItinerary struct {
+	ID   int `json:"id"`
+	Legs []struct {
+		Ref int `json:"ref"`
+	} `json:"legs"`
+	PricingInformation []struct {
+		Fare struct {
+			PassengerInfoList []struct {
+				PassengerInfo sabrePassengerInfo `json:"passengerInfo"`
+			} `json:"passengerInfoList"`
+		} `json:"fare"`
+	} `json:"pricingInformation"`
+}
+
+type sabrePassengerInfo struct {
+	PassengerType  string `json:"passengerType"`
+	FareComponents []struct {
+		Segments []struct {
+			Segment struct {
+				CabinCode      string `json:"cabinCode"`
+				SeatsAvailable int    `json:"seatsAvailable"`
+			} `json:"segment"`
+		} `json:"segments"`
+	} `json:"fareComponents"`
+	PassengerTotalFare struct {
+		TotalFare float64 `json:"totalFare"`
+		Currency  string  `json:"currency"`
+	} `json:"passengerTotalFare"`
+	BaggageInformation []struct {
+		// ProvisionType A is checked baggage, B carry-on.
+		ProvisionType string `json:"provisionType"`
+		Allowance     struct {
+			Ref int `json:"ref"`
+		} `json:"allowance"`
+	} `json:"baggageInformation"`
+}
+
+// SabreProvider searches Sabre's Bargain Finder Max REST API. Like Amadeus
+// it calls a real GDS, so it needs credentials and counts against their
+// quota.
+type SabreProvider struct {
+	endpoint
+
+	config SabreConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func NewSabreProvider(cfg SabreConfig) (*SabreProvider, error) {
+	if cfg.UserID == "" || cfg.Password == "" || cfg.PCC == "" {
+		return nil, fmt.Errorf("sabre: user ID, password and PCC are required")
+	}
+	if cfg.Domain == "" {
+		cfg.Domain = "AA"
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = SabreCertURL
+	}
+	if _, err := url.Parse(cfg.BaseURL); err != nil {
+		return nil, fmt.Errorf("sabre: invalid base URL: %w", err)
+	}
+	client := cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &SabreProvider{config: cfg, client: client}, nil
+}
+
+func (p *SabreProvider) Name() string {
+	return "sabre"
+}
+
+// Capabilities list no routes: Sabre covers whatever its airlines fly.
+func (p *SabreProvider) Capabilities() Capabilities {
+	return Capabilities{Routes: []Route{}, CabinClasses: cabin.Classes}
+}
+
+func (p *SabreProvider) baseURL() string {
+	if u := p.Endpoint(); u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	return strings.TrimSuffix(p.config.BaseURL, "/")
+}
+
+func (p *SabreProvider) Search(ctx context.Context, req models.SearchRequest) ([]models.Flight, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := time.Parse("2006-01-02", req.DepartureDate); err != nil {
+		return nil, errorsx.Errorf(errorsx.Invalid, "invalid departure date %q", req.DepartureDate)
+	}
+	// Like Amadeus, Sabre rejects airports it doesn't know rather than
+	// finding no flights.
+	if !airports.Known(req.Origin) || !airports.Known(req.Destination) {
+		return nil, nil
+	}
+
+	var resp sabreSearchResponse
+	if err := p.post(ctx, "/v4/offers/shop", p.searchRequest(req), &resp); err != nil {
+		return nil, err
+	}
+	for _, m := range resp.Response.Messages {
+		if m.Severity == "Error" {
+			debugf(ctx, "%s: %s %s", p.Name(), m.Code, m.Text)
+		}
+	}
+
+	results := make([]models.Flight, 0)
+	seen := make(map[string]int)
+	offers := 0
+	for _, group := range resp.Response.ItineraryGroups {
+		if len(group.GroupDescription.LegDescriptions) != 1 {
+			continue
+		}
+		date := group.GroupDescription.LegDescriptions[0].DepartureDate
+		for _, it := range group.Itineraries {
+			offers++
+			flight, err := p.normalize(it, date, &resp)
+			if err != nil {
+				debugf(ctx, "%s: skipping itinerary %d: %v", p.Name(), it.ID, err)
+				continue
+			}
+			if !cabin.Matches(req.CabinClass, flight.CabinClass) {
+				continue
+			}
+			// The same flights may be priced in several itineraries; the
+			// cheapest stands for them.
+			if i, ok := seen[flight.ID]; ok {
+				if flight.Price.Amount < results[i].Price.Amount {
+					results[i] = flight
+				}
+				continue
+			}
+			seen[flight.ID] = len(results)
+			results = append(results, flight)
+		}
+	}
+
+	debugf(ctx, "%s: %d of %d itineraries match %s-%s on %s in %s", p.Name(), len(results), offers, req.Origin, req.Destination, req.DepartureDate, req.CabinClass)
+	return results, nil
+}
+
+func (p *SabreProvider) searchRequest(req models.SearchRequest) sabreSearchRequest {
+	seats := max(req.Passengers, 1)
+	rq := sabreLowFareSearch{
+		Version: "4",
+		OriginDestinationInformation: []sabreOriginDestination{{
+			RPH:                 "1",
+			DepartureDateTime:   req.DepartureDate + "T00:00:00",
+			OriginLocation:      sabreLocation{LocationCode: strings.ToUpper(req.Origin)},
+			DestinationLocation: sabreLocation{LocationCode: strings.ToUpper(req.Destination)},
+		}},
+	}
+	rq.POS.Source = make([]struct {
+		PseudoCityCode string `json:"PseudoCityCode"`
+		RequestorID    struct {
+			Type        string `json:"Type"`
+			ID          string `json:"ID"`
+			CompanyName struct {
+				Code string `json:"Code"`
+			} `json:"CompanyName"`
+		} `json:"RequestorID"`
+	}, 1)
+	rq.POS.Source[0].PseudoCityCode = p.config.PCC
+	rq.POS.Source[0].RequestorID.Type = "1"
+	rq.POS.Source[0].RequestorID.ID = "1"
+	rq.POS.Source[0].RequestorID.CompanyName.Code = "TN"
+	if class, ok := cabin.Parse(req.CabinClass); ok {
+		rq.TravelPreferences.CabinPref = []sabreCabinPref{{Cabin: sabreCabinCodes[class], PreferLevel: "Only"}}
+	}
+	rq.TravelerInfoSummary.SeatsRequested = []int{seats}
+	rq.TravelerInfoSummary.AirTravelerAvail = make([]struct {
+		PassengerTypeQuantity []sabrePassengers `json:"PassengerTypeQuantity"`
+	}, 1)
+	rq.TravelerInfoSummary.AirTravelerAvail[0].PassengerTypeQuantity = []sabrePassengers{{Code: "ADT", Quantity: seats}}
+	rq.TravelerInfoSummary.PriceRequestInformation.CurrencyCode = "IDR"
+	rq.TPAExtensions.IntelliSellTransaction.RequestType.Name = sabreRequestType
+	return sabreSearchRequest{Request: rq}
+}
+
+// post sends body to path with a session token, renewing the token once
+// if Sabre rejects it before it was due to expire.
+func (p *SabreProvider) post(ctx context.Context, path string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		token, err := p.accessToken(ctx)
+		if err != nil {
+			return err
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL()+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+		httpReq.Header.Set("Content-Type", "application/json")
+
+		err = p.do(httpReq, out)
+		if errorsx.KindOf(err) == errorsx.Unauthorized && attempt == 0 {
+			p.forgetToken(token)
+			continue
+		}
+		if errorsx.KindOf(err) == errorsx.Unauthorized {
+			return fmt.Errorf("%w: %v", ErrSabreCredentials, err)
+		}
+		return err
+	}
+}
+
+func (p *SabreProvider) accessToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Now().Before(p.expires) {
+		return p.token, nil
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL()+"/v2/auth/token", strings.NewReader("grant_type=client_credentials"))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Authorization", "Basic "+p.config.credentials())
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp sabreTokenResponse
+	if err := p.do(httpReq, &resp); err != nil {
+		if k := errorsx.KindOf(err); k == errorsx.Unauthorized || k == errorsx.Invalid {
+			return "", fmt.Errorf("%w: %v", ErrSabreCredentials, err)
+		}
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", errorsx.New(errorsx.Unavailable, "empty session token issued")
+	}
+	p.token = resp.AccessToken
+	p.expires = time.Now().Add(time.Duration(resp.ExpiresIn)*time.Second - sabreTokenMargin)
+	return p.token, nil
+}
+
+// forgetToken drops token unless another search has already renewed it.
+func (p *SabreProvider) forgetToken(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token == token {
+		p.token = ""
+	}
+}
+
+// do sends httpReq and decodes a successful response into out, classifying
+// failures by status like AmadeusProvider.do.
+func (p *SabreProvider) do(httpReq *http.Request, out any) error {
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		if ctxErr := httpReq.Context().Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return errorsx.Classify(err, errorsx.Unavailable)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errorsx.Classify(err, errorsx.Unavailable)
+	}
+	if resp.StatusCode >= 300 {
+		kind := errorsx.Unavailable
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized:
+			kind = errorsx.Unauthorized
+		case resp.StatusCode == http.StatusTooManyRequests:
+			kind = errorsx.RateLimited
+		case resp.StatusCode == http.StatusBadRequest:
+			kind = errorsx.Invalid
+		}
+		return errorsx.Errorf(kind, "%s: %s", resp.Status, sabreErrorDetail(body))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return errorsx.Errorf(errorsx.Unavailable, "malformed response: %w", err)
+	}
+	return nil
+}
+
+func sabreErrorDetail(body []byte) string {
+	var resp sabreErrorResponse
+	switch {
+	case json.Unmarshal(body, &resp) != nil:
+	case resp.ErrorCode != "":
+		return resp.ErrorCode + " " + resp.Message
+	case resp.Error != "":
+		return strings.TrimSpace(resp.Error + " " + resp.ErrorDescription)
+	}
+	return strings.TrimSpace(string(body))
+}
+
+func (p *SabreProvider) normalize(it sabreItinerary, date string, resp *sabreSearchResponse) (models.Flight, error) {
+	if len(it.Legs) != 1 {
+		return models.Flight{}, fmt.Errorf("want one leg, got %d", len(it.Legs))
+	}
+	leg, ok := findByID(resp.Response.LegDescs, it.Legs[0].Ref, func(l sabreLeg) int { return l.ID })
+	if !ok || len(leg.Schedules) == 0 {
+		return models.Flight{}, fmt.Errorf("unknown leg %d", it.Legs[0].Ref)
+	}
+	legDate, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return models.Flight{}, fmt.Errorf("invalid departure date %q", date)
+	}
+
+	type segment struct {
+		sabreSchedule
+		departs, arrives time.Time
+	}
+	segments := make([]segment, len(leg.Schedules))
+	for i, ref := range leg.Schedules {
+		s, ok := findByID(resp.Response.ScheduleDescs, ref.Ref, func(s sabreSchedule) int { return s.ID })
+		if !ok {
+			return models.Flight{}, fmt.Errorf("unknown schedule %d", ref.Ref)
+		}
+		day := legDate.AddDate(0, 0, ref.DepartureDateAdjustment)
+		departs, err1 := sabreLocalTime(day, s.Departure)
+		arrives, err2 := sabreLocalTime(day.AddDate(0, 0, s.Arrival.DateAdjustment), s.Arrival)
+		if err1 != nil || err2 != nil {
+			return models.Flight{}, fmt.Errorf("unparseable times of schedule %d", s.ID)
+		}
+		segments[i] = segment{s, departs, arrives}
+	}
+	first, last := segments[0], segments[len(segments)-1]
+
+	var fare *sabrePassengerInfo
+	for _, pi := range it.PricingInformation {
+		for _, pax := range pi.Fare.PassengerInfoList {
+			info := pax.PassengerInfo
+			if info.PassengerType == "ADT" && (fare == nil || info.PassengerTotalFare.TotalFare < fare.PassengerTotalFare.TotalFare) {
+				fare = &info
+			}
+		}
+	}
+	if fare == nil || len(fare.FareComponents) == 0 || len(fare.FareComponents[0].Segments) == 0 {
+		return models.Flight{}, fmt.Errorf("no adult fare")
+	}
+	cabinCode := fare.FareComponents[0].Segments[0].Segment.CabinCode
+	class, ok := sabreCabins[cabinCode]
+	if !ok {
+		return models.Flight{}, fmt.Errorf("unknown cabin code %q", cabinCode)
+	}
+	seats := -1
+	for _, fc := range fare.FareComponents {
+		for _, s := range fc.Segments {
+			if seats < 0 || s.Segment.SeatsAvailable < seats {
+				seats = s.Segment.SeatsAvailable
+			}
+		}
+	}
+
+	stops := len(segments) - 1
+	layovers := make([]models.Layover, 0, stops)
+	ids := make([]string, len(segments))
+	for i, s := range segments {
+		stops += s.StopCount
+		ids[i] = s.Carrier.Marketing + strconv.Itoa(s.Carrier.MarketingFlightNumber)
+		if i == 0 {
+			continue
+		}
+		layovers = append(layovers, models.Layover{
+			Airport:  s.Departure.Airport,
+			City:     s.Departure.City,
+			Duration: int(s.departs.Sub(segments[i-1].arrives).Minutes()),
+		})
+	}
+	// Technical stops have no connection to report, so layovers are only
+	// listed when they account for every stop.
+	if len(layovers) != stops {
+		layovers = nil
+	}
+
+	totalMinutes := leg.ElapsedTime
+	if totalMinutes <= 0 {
+		totalMinutes = int(last.arrives.Sub(first.departs).Minutes())
+	}
+
+	// Bargain Finder Max sends no airline names; airlines missing from the
+	// reference data are named by their code.
+	airlineName, ok := refdata.AirlineName(first.Carrier.Marketing)
+	if !ok {
+		airlineName = first.Carrier.Marketing
+	}
+
+	var planeName string
+	if e, ok := aircraft.Normalize(first.Carrier.Equipment.Code); ok {
+		planeName = e.Name
+	}
+
+	var checkedKg float64
+	for _, b := range fare.BaggageInformation {
+		if b.ProvisionType != "A" {
+			continue
+		}
+		if a, ok := findByID(resp.Response.BaggageAllowanceDescs, b.Allowance.Ref, func(a sabreBaggageAllowance) int { return a.ID }); ok {
+			checkedKg = sabreBaggageKg(a)
+		}
+		break
+	}
+
+	// Itinerary IDs only number the itineraries of one response; the
+	// flights flown, date and cabin identify one across searches.
+	id := "SBR-" + strings.Join(ids, "-") + "-" + first.departs.Format("20060102") + "-" + strings.ToUpper(string(class))
+	b := normalize.New(p.Name(), id)
+	b.SetAirline(first.Carrier.Marketing, airlineName).
+		SetFlightNumber(first.Carrier.Marketing+" "+strconv.Itoa(first.Carrier.MarketingFlightNumber)).
+		SetCabin(string(class)).
+		SetDeparture(first.Departure.Airport, first.Departure.City, first.Departure.Terminal, first.departs).
+		SetArrival(last.Arrival.Airport, last.Arrival.City, last.Arrival.Terminal, last.arrives).
+		SetDuration(totalMinutes).
+		SetStops(stops).
+		SetPrice(fare.PassengerTotalFare.TotalFare, fare.PassengerTotalFare.Currency).
+		SetSeats(max(seats, 0)).
+		SetAircraft(planeName).
+		SetBaggage(0, checkedKg)
+	for _, l := range layovers {
+		b.AddLayover(l.Airport, l.City, l.Duration)
+	}
+	return b.Build()
+}
+
+// findByID looks up the description a grouped itinerary response refers
+// to by id.
+func findByID[T any](descs []T, id int, idOf func(T) int) (T, bool) {
+	for _, d := range descs {
+		if idOf(d) == id {
+			return d, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// sabreLocalTime reads a schedule time on day. Sabre gives the offset, so
+// the time is right for any airport; known airports also get their zone.
+func sabreLocalTime(day time.Time, e sabreScheduleEndpoint) (time.Time, error) {
+	t, err := time.Parse("2006-01-02T15:04:05Z07:00", day.Format("2006-01-02")+"T"+e.Time)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return timezone.ConvertToTimezone(t, e.Airport), nil
+}
+
+func sabreBaggageKg(a sabreBaggageAllowance) float64 {
+	if a.Weight > 0 {
+		if strings.EqualFold(a.Unit, "lb") {
+			return float64(a.Weight) * 0.4536
+		}
+		return float64(a.Weight)
+	}
+	return float64(a.PieceCount) * sabreCheckedBagKg
+}
@@ -0,0 +1,248 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/cabin"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/normalize"
+	"github.com/dharmasatrya/flightsearch/internal/providers/data"
+	"github.com/dharmasatrya/flightsearch/internal/timezone"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+var ErrSuperAirJetTemporaryFailure = errorsx.New(errorsx.Unavailable, "gateway timeout")
+
+type superAirJetResponse struct {
+	RC   string              `json:"rc"`
+	Msg  string              `json:"msg"`
+	Data []superAirJetFlight `json:"data"`
+}
+
+// superAirJetFlight sends every value as a string, and times local to each
+// airport without an offset.
+type superAirJetFlight struct {
+	Ref     string               `json:"ref"`
+	FNo     string               `json:"fno"`
+	AL      string               `json:"al"`
+	ALName  string               `json:"alName"`
+	Dep     string               `json:"dep"`
+	DepCity string               `json:"depCity"`
+	Arr     string               `json:"arr"`
+	ArrCity string               `json:"arrCity"`
+	STD     string               `json:"std"`
+	STA     string               `json:"sta"`
+	Dur     string               `json:"dur"`
+	Trn     []superAirJetTransit `json:"trn"`
+	Fare    string               `json:"fare"`
+	Cur     string               `json:"cur"`
+	Seat    string               `json:"seat"`
+	Cls     string               `json:"cls"`
+	AC      string               `json:"ac"`
+	Bag     string               `json:"bag"`
+	// Bgs lists extra bags as "kg:price,..."; Sst and Mel are the seat
+	// and meal prices, empty when not sold.
+	Bgs string `json:"bgs"`
+	Sst string `json:"sst"`
+	Mel string `json:"mel"`
+}
+
+type superAirJetTransit struct {
+	Apt  string `json:"apt"`
+	City string `json:"city"`
+	Wait string `json:"wait"`
+}
+
+const superAirJetTimeLayout = "02/01/2006 15:04"
+
+type SuperAirJetProvider struct {
+	endpoint
+
+	flights []superAirJetFlight
+	rng     *Rand
+}
+
+func NewSuperAirJetProvider(rng *Rand) (*SuperAirJetProvider, error) {
+	var resp superAirJetResponse
+	if err := json.Unmarshal(data.SuperAirJetData, &resp); err != nil {
+		return nil, err
+	}
+	if resp.RC != "00" {
+		return nil, fmt.Errorf("superairjet: dataset has response code %s: %s", resp.RC, resp.Msg)
+	}
+	return &SuperAirJetProvider{flights: resp.Data, rng: rng}, nil
+}
+
+func (p *SuperAirJetProvider) Name() string {
+	return "superairjet"
+}
+
+func (p *SuperAirJetProvider) BookingURLTemplate() string {
+	return "https://www.superairjet.com/booking?origin={origin}&destination={destination}&date={date}&flight={flight_number}&adult={adults}&child={children}&infant={infants}&ref={affiliate}"
+}
+
+func (p *SuperAirJetProvider) PassengerRules() PassengerRules {
+	return PassengerRules{
+		AdultAge:              12,
+		InfantAge:             2,
+		MaxLapInfantsPerAdult: 1,
+		MaxChildrenPerAdult:   2,
+	}
+}
+
+func (p *SuperAirJetProvider) Capabilities() Capabilities {
+	var c catalog
+	for _, f := range p.flights {
+		c.add(f.Dep, f.Arr, f.Cls)
+	}
+	return c.capabilities()
+}
+
+func (p *SuperAirJetProvider) Search(ctx context.Context, req models.SearchRequest) ([]models.Flight, error) {
+	delay := p.rng.delay(80, 120)
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if p.rng.Float64() < 0.05 {
+		return nil, ErrSuperAirJetTemporaryFailure
+	}
+
+	reqDate, err := time.Parse("2006-01-02", req.DepartureDate)
+	if err != nil {
+		return nil, errorsx.Errorf(errorsx.Invalid, "invalid departure date %q", req.DepartureDate)
+	}
+	shift := fixtureShift(reqDate)
+	date := reqDate.AddDate(0, 0, -shift).Format("02/01/2006")
+
+	var results []models.Flight
+	for _, f := range p.flights {
+		if !strings.EqualFold(f.Dep, req.Origin) || !strings.EqualFold(f.Arr, req.Destination) {
+			continue
+		}
+
+		if !cabin.Matches(req.CabinClass, f.Cls) {
+			continue
+		}
+
+		if !strings.HasPrefix(f.STD, date) {
+			continue
+		}
+
+		flight, err := p.normalize(f)
+		if err != nil {
+			debugf(ctx, "%s: skipping flight %s: %v", p.Name(), f.Ref, err)
+			continue
+		}
+		shiftFlight(&flight, shift)
+		results = append(results, flight)
+	}
+
+	debugf(ctx, "%s: %d of %d flights match %s-%s on %s in %s", p.Name(), len(results), len(p.flights), req.Origin, req.Destination, req.DepartureDate, req.CabinClass)
+	return results, nil
+}
+
+func (p *SuperAirJetProvider) normalize(f superAirJetFlight) (models.Flight, error) {
+	b := normalize.New(p.Name(), f.Ref)
+	depTime, err := superAirJetTime(f.STD, f.Dep)
+	b.Fail(err)
+	arrTime, err := superAirJetTime(f.STA, f.Arr)
+	b.Fail(err)
+	price, err := strconv.ParseFloat(f.Fare, 64)
+	if err != nil {
+		b.Failf("invalid fare %q", f.Fare)
+	}
+	seats, err := strconv.Atoi(f.Seat)
+	if err != nil {
+		b.Failf("invalid seat count %q", f.Seat)
+	}
+	minutes, err := normalize.Clock(f.Dur)
+	b.Fail(err)
+	cabinKg, checkedKg := parseSuperAirJetBaggage(f.Bag)
+
+	b.SetAirline(f.AL, f.ALName).
+		SetFlightNumber(f.FNo).
+		SetCabin(f.Cls).
+		SetDeparture(f.Dep, f.DepCity, "", depTime).
+		SetArrival(f.Arr, f.ArrCity, "", arrTime).
+		SetDuration(minutes).
+		SetPrice(price, f.Cur).
+		SetSeats(seats).
+		SetAircraft(f.AC).
+		SetBaggage(cabinKg, checkedKg)
+	for _, t := range f.Trn {
+		wait, err := normalize.Clock(t.Wait)
+		if err != nil {
+			b.Failf("invalid transit wait %q", t.Wait)
+		}
+		b.AddLayover(t.Apt, t.City, wait)
+	}
+	superAirJetAncillaries(b, f)
+	return b.Build()
+}
+
+// superAirJetAncillaries sets the extras sold, failing the flight on one
+// it can't read.
+func superAirJetAncillaries(b *normalize.Builder, f superAirJetFlight) {
+	var bags []models.BagOption
+	if f.Bgs != "" {
+		for _, opt := range strings.Split(f.Bgs, ",") {
+			kg, price, ok := strings.Cut(opt, ":")
+			weight, kgErr := strconv.ParseFloat(kg, 64)
+			amount, priceErr := strconv.ParseFloat(price, 64)
+			if !ok || kgErr != nil || priceErr != nil {
+				b.Failf("invalid bag option %q", opt)
+				return
+			}
+			bags = append(bags, normalize.Bag(weight, amount, f.Cur))
+		}
+	}
+
+	prices := make([]*models.Price, 2)
+	for i, raw := range []string{f.Sst, f.Mel} {
+		if raw == "" {
+			continue
+		}
+		amount, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			b.Failf("invalid extra price %q", raw)
+			return
+		}
+		prices[i] = normalize.OptionalPrice(amount, f.Cur)
+	}
+	b.SetAncillaries(bags, prices[0], prices[1])
+}
+
+// superAirJetTime reads a time local to airport, which must therefore be
+// known.
+func superAirJetTime(local, airport string) (time.Time, error) {
+	loc, err := timezone.LocationByAirport(airport)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(superAirJetTimeLayout, local, loc)
+}
+
+var superAirJetBaggage = regexp.MustCompile(`^(\d+)KG(?:\+(\d+)KG)?$`)
+
+// parseSuperAirJetBaggage reads "7KG+20KG" as cabin and checked
+// allowances; "7KG" has no checked bag.
+func parseSuperAirJetBaggage(s string) (cabin, checked float64) {
+	matches := superAirJetBaggage.FindStringSubmatch(strings.ToUpper(strings.ReplaceAll(s, " ", "")))
+	if matches == nil {
+		return 7, 0
+	}
+	cabin, _ = strconv.ParseFloat(matches[1], 64)
+	if matches[2] != "" {
+		checked, _ = strconv.ParseFloat(matches[2], 64)
+	}
+	return cabin, checked
+}
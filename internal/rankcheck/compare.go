@@ -0,0 +1,148 @@
+package rankcheck
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+type DriftKind string
+
+const (
+	// DriftUnapproved is a corpus case without golden output.
+	DriftUnapproved DriftKind = "unapproved"
+	// DriftFiltering means flights were added to or dropped from a result.
+	DriftFiltering DriftKind = "filtering"
+	// DriftOrdering means flights present in both outputs changed places.
+	DriftOrdering DriftKind = "ordering"
+	DriftScore    DriftKind = "score"
+	DriftBadges   DriftKind = "badges"
+	DriftError    DriftKind = "error"
+)
+
+type Drift struct {
+	Case   string    `json:"case"`
+	Kind   DriftKind `json:"kind"`
+	Detail string    `json:"detail"`
+}
+
+type Report struct {
+	Cases int     `json:"cases"`
+	Drift []Drift `json:"drift,omitempty"`
+	// Stale lists golden entries no corpus case produces any more.
+	Stale []string `json:"stale,omitempty"`
+}
+
+// Passed reports whether every case matched its golden output.
+func (r Report) Passed() bool {
+	return len(r.Drift) == 0
+}
+
+// Compare checks results against golden output. Scores may differ by up to
+// tolerance before they count as drift.
+func Compare(golden Golden, results []Result, tolerance float64) Report {
+	report := Report{Cases: len(results)}
+	add := func(name string, kind DriftKind, format string, args ...any) {
+		report.Drift = append(report.Drift, Drift{Case: name, Kind: kind, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	ran := make(map[string]bool, len(results))
+	for _, r := range results {
+		ran[r.Case] = true
+		if r.Error != "" {
+			add(r.Case, DriftError, "%s", r.Error)
+			continue
+		}
+		want, ok := golden[r.Case]
+		if !ok {
+			add(r.Case, DriftUnapproved, "no golden output, %d flights returned", len(r.Flights))
+			continue
+		}
+
+		wantByID := byID(want)
+		gotByID := byID(r.Flights)
+
+		var added, dropped []string
+		for _, f := range r.Flights {
+			if _, ok := wantByID[f.ID]; !ok {
+				added = append(added, f.ID)
+			}
+		}
+		for _, f := range want {
+			if _, ok := gotByID[f.ID]; !ok {
+				dropped = append(dropped, f.ID)
+			}
+		}
+		if len(added) > 0 || len(dropped) > 0 {
+			add(r.Case, DriftFiltering, "added [%s], dropped [%s]", strings.Join(added, ", "), strings.Join(dropped, ", "))
+		}
+
+		// Order is compared over the flights both outputs share, so a
+		// filtering change isn't reported twice.
+		wantOrder := commonIDs(want, gotByID)
+		gotOrder := commonIDs(r.Flights, wantByID)
+		if pos := firstDifference(wantOrder, gotOrder); pos >= 0 {
+			add(r.Case, DriftOrdering, "first difference at rank %d: expected %s, got %s; %d of %d flights moved",
+				pos+1, wantOrder[pos], gotOrder[pos], moved(wantOrder, gotOrder), len(wantOrder))
+		}
+
+		for _, f := range r.Flights {
+			w, ok := wantByID[f.ID]
+			if !ok {
+				continue
+			}
+			if math.Abs(f.Score-w.Score) > tolerance {
+				add(r.Case, DriftScore, "%s scored %.2f, expected %.2f", f.ID, f.Score, w.Score)
+			}
+			if !slices.Equal(f.Badges, w.Badges) {
+				add(r.Case, DriftBadges, "%s has badges [%s], expected [%s]", f.ID, strings.Join(f.Badges, ", "), strings.Join(w.Badges, ", "))
+			}
+		}
+	}
+
+	for name := range golden {
+		if !ran[name] {
+			report.Stale = append(report.Stale, name)
+		}
+	}
+	slices.Sort(report.Stale)
+	return report
+}
+
+func byID(flights []Ranked) map[string]Ranked {
+	m := make(map[string]Ranked, len(flights))
+	for _, f := range flights {
+		m[f.ID] = f
+	}
+	return m
+}
+
+func commonIDs(flights []Ranked, other map[string]Ranked) []string {
+	var ids []string
+	for _, f := range flights {
+		if _, ok := other[f.ID]; ok {
+			ids = append(ids, f.ID)
+		}
+	}
+	return ids
+}
+
+func firstDifference(a, b []string) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+func moved(a, b []string) int {
+	n := 0
+	for i := range a {
+		if a[i] != b[i] {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,75 @@
+package rankcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Case is one representative search. Its name keys the approved output in
+// the golden file, so renaming a case needs a golden update.
+type Case struct {
+	Name    string               `json:"name"`
+	Request models.SearchRequest `json:"request"`
+}
+
+func LoadCorpus(path string) ([]Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("parse corpus %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool, len(cases))
+	for i, c := range cases {
+		if c.Name == "" {
+			return nil, fmt.Errorf("corpus case %d has no name", i)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("duplicate corpus case %q", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return cases, nil
+}
+
+// Ranked is one flight's place in a case's output.
+type Ranked struct {
+	ID     string   `json:"id"`
+	Score  float64  `json:"score,omitempty"`
+	Badges []string `json:"badges,omitempty"`
+}
+
+// Golden maps case names to their approved output, best first.
+type Golden map[string][]Ranked
+
+// LoadGolden treats a missing file as empty, so the first run with -update
+// can create it.
+func LoadGolden(path string) (Golden, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Golden{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var g Golden
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("parse golden %s: %w", path, err)
+	}
+	return g, nil
+}
+
+func (g Golden) Write(path string) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
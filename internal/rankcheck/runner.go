@@ -0,0 +1,66 @@
+package rankcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/filter"
+	"github.com/dharmasatrya/flightsearch/internal/ranking"
+)
+
+type Result struct {
+	Case    string   `json:"case"`
+	Flights []Ranked `json:"flights"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Run sends every case through the same normalization, aggregation,
+// filtering and ranking as the search endpoint. Cases are one-way: return
+// legs are not searched.
+func Run(ctx context.Context, agg *aggregator.Aggregator, cases []Case, defaults ranking.Weights) []Result {
+	results := make([]Result, 0, len(cases))
+	for _, c := range cases {
+		results = append(results, run(ctx, agg, c, defaults))
+	}
+	return results
+}
+
+func run(ctx context.Context, agg *aggregator.Aggregator, c Case, defaults ranking.Weights) Result {
+	res := Result{Case: c.Name}
+
+	req := c.Request
+	req.Normalize()
+	req.ReturnDate = nil
+
+	result, err := agg.Search(ctx, req)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	// A partial result would show up as filtering drift.
+	if result.ProvidersFailed > 0 {
+		res.Error = fmt.Sprintf("providers failed: %s", strings.Join(result.FailedProviders, ", "))
+		return res
+	}
+
+	flights := filter.Apply(result.Flights, req.Filters, req.SortBy, req.SortOrder, ranking.WeightsFor(req, defaults))
+	res.Flights = make([]Ranked, len(flights))
+	for i, f := range flights {
+		res.Flights[i] = Ranked{ID: f.ID, Score: f.BestValueScore, Badges: f.Badges}
+	}
+	return res
+}
+
+// Approve builds a golden file from the results of a clean run.
+func Approve(results []Result) (Golden, error) {
+	g := make(Golden, len(results))
+	for _, r := range results {
+		if r.Error != "" {
+			return nil, fmt.Errorf("case %s: %s", r.Case, r.Error)
+		}
+		g[r.Case] = r.Flights
+	}
+	return g, nil
+}
@@ -0,0 +1,102 @@
+package ranking
+
+import "github.com/dharmasatrya/flightsearch/internal/models"
+
+const (
+	BadgeBestPrice = "best_price"
+	BadgeFastest   = "fastest"
+	BadgeBestValue = "best_value"
+)
+
+// AssignBadges marks the single best flight per category. Ties are broken
+// deterministically so every client shows the same badge:
+//   - best_price: lowest price, then shortest duration, then earliest departure
+//   - fastest: shortest duration, then lowest price, then earliest departure
+//   - best_value: lowest best value score, then lowest price, then earliest departure
+//
+// Remaining ties fall back to the flight ID.
+func AssignBadges(flights []models.Flight, weights Weights) []models.Flight {
+	scores := make([]float64, len(flights))
+	for i, f := range CalculateScores(flights, weights) {
+		scores[i] = f.BestValueScore
+	}
+	return assignBadges(flights, scores)
+}
+
+// AssignScoredBadges is AssignBadges for flights whose BestValueScore is
+// already set, possibly by a ranking model.
+func AssignScoredBadges(flights []models.Flight) []models.Flight {
+	scores := make([]float64, len(flights))
+	for i, f := range flights {
+		scores[i] = f.BestValueScore
+	}
+	return assignBadges(flights, scores)
+}
+
+func assignBadges(flights []models.Flight, scores []float64) []models.Flight {
+	if len(flights) == 0 {
+		return flights
+	}
+
+	result := make([]models.Flight, len(flights))
+	copy(result, flights)
+	for i := range result {
+		result[i].Badges = nil
+	}
+
+	cheapest, fastest, bestValue := 0, 0, 0
+	for i := 1; i < len(result); i++ {
+		if lessPrice(result[i], result[cheapest]) {
+			cheapest = i
+		}
+		if lessDuration(result[i], result[fastest]) {
+			fastest = i
+		}
+		if lessValue(result[i], result[bestValue], scores[i], scores[bestValue]) {
+			bestValue = i
+		}
+	}
+
+	result[cheapest].Badges = append(result[cheapest].Badges, BadgeBestPrice)
+	result[fastest].Badges = append(result[fastest].Badges, BadgeFastest)
+	result[bestValue].Badges = append(result[bestValue].Badges, BadgeBestValue)
+
+	return result
+}
+
+func lessPrice(a, b models.Flight) bool {
+	if a.Price.Amount != b.Price.Amount {
+		return a.Price.Amount < b.Price.Amount
+	}
+	if a.Duration.TotalMinutes != b.Duration.TotalMinutes {
+		return a.Duration.TotalMinutes < b.Duration.TotalMinutes
+	}
+	return lessDeparture(a, b)
+}
+
+func lessDuration(a, b models.Flight) bool {
+	if a.Duration.TotalMinutes != b.Duration.TotalMinutes {
+		return a.Duration.TotalMinutes < b.Duration.TotalMinutes
+	}
+	if a.Price.Amount != b.Price.Amount {
+		return a.Price.Amount < b.Price.Amount
+	}
+	return lessDeparture(a, b)
+}
+
+func lessValue(a, b models.Flight, scoreA, scoreB float64) bool {
+	if scoreA != scoreB {
+		return scoreA < scoreB
+	}
+	if a.Price.Amount != b.Price.Amount {
+		return a.Price.Amount < b.Price.Amount
+	}
+	return lessDeparture(a, b)
+}
+
+func lessDeparture(a, b models.Flight) bool {
+	if !a.Departure.Time.Equal(b.Departure.Time) {
+		return a.Departure.Time.Before(b.Departure.Time)
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,135 @@
+package ranking
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+type badgeFlight struct {
+	id     string
+	price  float64
+	mins   int
+	depart string
+	score  float64
+}
+
+func (b badgeFlight) flight() models.Flight {
+	dep, _ := time.Parse("15:04", b.depart)
+	return models.Flight{
+		ID:        b.id,
+		Price:     models.Price{Amount: b.price},
+		Duration:  models.Duration{TotalMinutes: b.mins},
+		Departure: models.Location{Time: dep},
+	}
+}
+
+func TestAssignBadgesTies(t *testing.T) {
+	tests := []struct {
+		name    string
+		flights []badgeFlight
+		want    map[string]string
+	}{
+		{
+			name: "equal price goes to the shorter flight",
+			flights: []badgeFlight{
+				{"A", 1000, 120, "08:00", 0.5},
+				{"B", 1000, 90, "09:00", 0.6},
+				{"C", 1500, 60, "07:00", 0.7},
+			},
+			want: map[string]string{BadgeBestPrice: "B", BadgeFastest: "C", BadgeBestValue: "A"},
+		},
+		{
+			name: "equal price and duration goes to the earlier departure",
+			flights: []badgeFlight{
+				{"A", 1000, 90, "09:00", 0.5},
+				{"B", 1000, 90, "08:00", 0.5},
+			},
+			want: map[string]string{BadgeBestPrice: "B", BadgeFastest: "B", BadgeBestValue: "B"},
+		},
+		{
+			name: "equal duration goes to the cheaper flight",
+			flights: []badgeFlight{
+				{"A", 1200, 90, "07:00", 0.4},
+				{"B", 1000, 90, "09:00", 0.6},
+				{"C", 2000, 150, "06:00", 0.8},
+			},
+			want: map[string]string{BadgeBestPrice: "B", BadgeFastest: "B", BadgeBestValue: "A"},
+		},
+		{
+			name: "equal score goes to the cheaper flight",
+			flights: []badgeFlight{
+				{"A", 1200, 60, "07:00", 0.5},
+				{"B", 1000, 120, "09:00", 0.5},
+				{"C", 900, 180, "10:00", 0.9},
+			},
+			want: map[string]string{BadgeBestPrice: "C", BadgeFastest: "A", BadgeBestValue: "B"},
+		},
+		{
+			name: "identical flights fall back to the ID",
+			flights: []badgeFlight{
+				{"B", 1000, 90, "08:00", 0.5},
+				{"A", 1000, 90, "08:00", 0.5},
+				{"C", 1000, 90, "08:00", 0.5},
+			},
+			want: map[string]string{BadgeBestPrice: "A", BadgeFastest: "A", BadgeBestValue: "A"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The winner must not depend on the order flights arrive in.
+			for _, order := range permutations(len(tt.flights)) {
+				flights := make([]models.Flight, len(order))
+				scores := make([]float64, len(order))
+				for i, j := range order {
+					flights[i] = tt.flights[j].flight()
+					scores[i] = tt.flights[j].score
+				}
+				got := badgeWinners(assignBadges(flights, scores))
+				for badge, id := range tt.want {
+					if got[badge] != id {
+						t.Errorf("order %v: %s went to %q, want %q", order, badge, got[badge], id)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAssignBadgesLeavesInputAlone(t *testing.T) {
+	flights := []models.Flight{badgeFlight{"A", 1000, 90, "08:00", 0}.flight()}
+	flights[0].Badges = []string{"stale"}
+	got := assignBadges(flights, []float64{0})
+	if !slices.Equal(flights[0].Badges, []string{"stale"}) {
+		t.Errorf("input badges changed to %v", flights[0].Badges)
+	}
+	if want := []string{BadgeBestPrice, BadgeFastest, BadgeBestValue}; !slices.Equal(got[0].Badges, want) {
+		t.Errorf("badges = %v, want %v", got[0].Badges, want)
+	}
+}
+
+func badgeWinners(flights []models.Flight) map[string]string {
+	winners := make(map[string]string)
+	for _, f := range flights {
+		for _, b := range f.Badges {
+			winners[b] = f.ID
+		}
+	}
+	return winners
+}
+
+func permutations(n int) [][]int {
+	if n == 0 {
+		return [][]int{{}}
+	}
+	var out [][]int
+	for _, p := range permutations(n - 1) {
+		for i := 0; i <= len(p); i++ {
+			out = append(out, slices.Insert(slices.Clone(p), i, n-1))
+		}
+	}
+	return out
+}
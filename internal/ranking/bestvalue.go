@@ -3,6 +3,7 @@ package ranking
 import (
 	"math"
 
+	"github.com/dharmasatrya/flightsearch/internal/aircraft"
 	"github.com/dharmasatrya/flightsearch/internal/models"
 )
 
@@ -12,25 +13,69 @@ const (
 	StopsWeight    = 0.2
 )
 
-func CalculateScores(flights []models.Flight) []models.Flight {
+type Weights struct {
+	Price    float64
+	Duration float64
+	Stops    float64
+
+	// Comfort factors, only used by ranking profiles.
+	Layover       float64
+	DepartureTime float64
+	Baggage       float64
+	Aircraft      float64
+	Reliability   float64
+}
+
+func DefaultWeights() Weights {
+	return Weights{
+		Price:    PriceWeight,
+		Duration: DurationWeight,
+		Stops:    StopsWeight,
+	}
+}
+
+func WeightsFromModel(w models.RankingWeights) Weights {
+	return Weights{
+		Price:       w.Price,
+		Duration:    w.Duration,
+		Stops:       w.Stops,
+		Reliability: w.Reliability,
+	}
+}
+
+func CalculateScores(flights []models.Flight, weights Weights) []models.Flight {
 	if len(flights) == 0 {
 		return flights
 	}
 
 	maxPrice := findMaxPrice(flights)
 	maxDuration := findMaxDuration(flights)
+	maxLayover := findMaxLayover(flights)
+	maxBaggage := findMaxBaggage(flights)
 
 	result := make([]models.Flight, len(flights))
 	for i, f := range flights {
+		details := Explain(f, maxPrice, maxDuration, weights)
+		addComfort(&details, f, maxLayover, maxBaggage, weights)
+		details.Total = round2(details.Price + details.Duration + details.Stops +
+			details.Layover + details.DepartureTime + details.Baggage + details.Aircraft + details.Reliability)
+
 		result[i] = f
-		result[i].BestValueScore = CalculateBestValue(f, maxPrice, maxDuration)
+		result[i].BestValueScore = details.Total
+		result[i].ScoreDetails = &details
 	}
 
 	return result
 }
 
 // Lower score = better value
-func CalculateBestValue(flight models.Flight, maxPrice, maxDuration float64) float64 {
+func CalculateBestValue(flight models.Flight, maxPrice, maxDuration float64, weights Weights) float64 {
+	return Explain(flight, maxPrice, maxDuration, weights).Total
+}
+
+// Explain returns the weighted price, duration and stops components that
+// make up a flight's best value score.
+func Explain(flight models.Flight, maxPrice, maxDuration float64, weights Weights) models.ScoreDetails {
 	priceScore := 0.0
 	if maxPrice > 0 {
 		priceScore = (flight.Price.Amount / maxPrice) * 100
@@ -42,9 +87,106 @@ func CalculateBestValue(flight models.Flight, maxPrice, maxDuration float64) flo
 	}
 
 	stopsScore := float64(flight.Stops) * 15
-	score := (priceScore * PriceWeight) + (durationScore * DurationWeight) + (stopsScore * StopsWeight)
 
-	return math.Round(score*100) / 100
+	details := models.ScoreDetails{
+		Price:    round2(priceScore * weights.Price),
+		Duration: round2(durationScore * weights.Duration),
+		Stops:    round2(stopsScore * weights.Stops),
+	}
+	details.Total = round2(priceScore*weights.Price + durationScore*weights.Duration + stopsScore*weights.Stops)
+	return details
+}
+
+func addComfort(details *models.ScoreDetails, flight models.Flight, maxLayover, maxBaggage float64, weights Weights) {
+	layoverScore := 0.0
+	if maxLayover > 0 {
+		layoverScore = (float64(totalLayover(flight)) / maxLayover) * 100
+	}
+
+	baggageScore := 0.0
+	if maxBaggage > 0 {
+		baggageScore = 100 - (flight.Baggage.CheckedKg/maxBaggage)*100
+	}
+
+	details.Layover = round2(layoverScore * weights.Layover)
+	details.DepartureTime = round2(departureTimeScore(flight) * weights.DepartureTime)
+	details.Baggage = round2(baggageScore * weights.Baggage)
+	details.Aircraft = round2(aircraftScore(flight) * weights.Aircraft)
+	details.Reliability = round2(reliabilityScore(flight) * weights.Reliability)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// Daytime departures are preferred; early mornings and late evenings
+// are penalised, red-eyes the most.
+func departureTimeScore(flight models.Flight) float64 {
+	hour := flight.Departure.Time.Hour()
+	switch {
+	case hour >= 7 && hour < 20:
+		return 0
+	case hour >= 5 && hour < 7, hour >= 20 && hour < 23:
+		return 50
+	default:
+		return 100
+	}
+}
+
+func aircraftScore(flight models.Flight) float64 {
+	if flight.Aircraft == nil {
+		return 50
+	}
+	e, ok := aircraft.Normalize(*flight.Aircraft)
+	if !ok {
+		return 50
+	}
+	switch e.Category {
+	case aircraft.Widebody:
+		return 0
+	case aircraft.RegionalJet:
+		return 75
+	case aircraft.Turboprop:
+		return 100
+	default:
+		return 50
+	}
+}
+
+// Flights without on-time data rank as middling.
+func reliabilityScore(flight models.Flight) float64 {
+	if flight.Reliability == nil {
+		return 50
+	}
+	return float64(100 - flight.Reliability.Score)
+}
+
+func totalLayover(flight models.Flight) int {
+	total := 0
+	for _, l := range flight.Layovers {
+		total += l.Duration
+	}
+	return total
+}
+
+func findMaxLayover(flights []models.Flight) float64 {
+	maxLayover := 0.0
+	for _, f := range flights {
+		if l := float64(totalLayover(f)); l > maxLayover {
+			maxLayover = l
+		}
+	}
+	return maxLayover
+}
+
+func findMaxBaggage(flights []models.Flight) float64 {
+	maxBaggage := 0.0
+	for _, f := range flights {
+		if f.Baggage.CheckedKg > maxBaggage {
+			maxBaggage = f.Baggage.CheckedKg
+		}
+	}
+	return maxBaggage
 }
 
 func findMaxPrice(flights []models.Flight) float64 {
@@ -0,0 +1,201 @@
+package ranking
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/internal/timezone"
+)
+
+// ScoreFunc sets BestValueScore on every flight; lower is better.
+// CalculateScores is the heuristic one.
+type ScoreFunc func(flights []models.Flight, weights Weights) []models.Flight
+
+// Departure buckets, in the origin's local time.
+const (
+	BucketRedEye       = "red_eye"
+	BucketEarlyMorning = "early_morning"
+	BucketDaytime      = "daytime"
+	BucketEvening      = "evening"
+)
+
+// Features describe one flight to a ranking model. Percentiles are relative
+// to the other flights in the same result: 0 is the cheapest or shortest.
+type Features struct {
+	FlightID           string  `json:"flight_id"`
+	Airline            string  `json:"airline"`
+	Price              float64 `json:"price"`
+	PricePercentile    float64 `json:"price_percentile"`
+	DurationMinutes    int     `json:"duration_minutes"`
+	DurationPercentile float64 `json:"duration_percentile"`
+	Stops              int     `json:"stops"`
+	DepartureBucket    string  `json:"departure_bucket"`
+	AirlineQuality     float64 `json:"airline_quality"`
+	HeuristicScore     float64 `json:"heuristic_score"`
+}
+
+// airlineQuality is a 0-1 prior from on-time performance and reviews, for
+// models that don't learn airlines themselves.
+var airlineQuality = map[string]float64{
+	"GA": 0.9,
+	"ID": 0.75,
+	"QZ": 0.7,
+	"JT": 0.55,
+}
+
+const defaultAirlineQuality = 0.5
+
+// ExtractFeatures returns one feature vector per flight, in order.
+func ExtractFeatures(flights []models.Flight, weights Weights) []Features {
+	heuristic := CalculateScores(flights, weights)
+	features := make([]Features, len(flights))
+	for i, f := range flights {
+		quality, ok := airlineQuality[f.Airline.Code]
+		if !ok {
+			quality = defaultAirlineQuality
+		}
+		features[i] = Features{
+			FlightID:           f.ID,
+			Airline:            f.Airline.Code,
+			Price:              f.Price.Amount,
+			PricePercentile:    percentile(flights, i, func(f models.Flight) float64 { return f.Price.Amount }),
+			DurationMinutes:    f.Duration.TotalMinutes,
+			DurationPercentile: percentile(flights, i, func(f models.Flight) float64 { return float64(f.Duration.TotalMinutes) }),
+			Stops:              f.Stops,
+			DepartureBucket:    departureBucket(f),
+			AirlineQuality:     quality,
+			HeuristicScore:     heuristic[i].BestValueScore,
+		}
+	}
+	return features
+}
+
+// percentile is the share of the other flights with a strictly lower value.
+func percentile(flights []models.Flight, i int, value func(models.Flight) float64) float64 {
+	if len(flights) < 2 {
+		return 0
+	}
+	v := value(flights[i])
+	lower := 0
+	for _, f := range flights {
+		if value(f) < v {
+			lower++
+		}
+	}
+	return round2(float64(lower) / float64(len(flights)-1) * 100)
+}
+
+func departureBucket(f models.Flight) string {
+	hour := timezone.ConvertToTimezone(f.Departure.Time, f.Departure.Airport).Hour()
+	switch {
+	case hour >= 7 && hour < 20:
+		return BucketDaytime
+	case hour >= 5 && hour < 7:
+		return BucketEarlyMorning
+	case hour >= 20 && hour < 23:
+		return BucketEvening
+	default:
+		return BucketRedEye
+	}
+}
+
+// Scorer is an external ranking model. It returns one score per feature
+// vector, in order; lower is better, like best_value_score.
+type Scorer interface {
+	Score(ctx context.Context, features []Features) ([]float64, error)
+}
+
+// FeatureRecord is one ranked search, logged as training data. Labels
+// (clicks, bookings) are joined on RequestID and FlightID later.
+type FeatureRecord struct {
+	Time        time.Time  `json:"time"`
+	RequestID   string     `json:"request_id,omitempty"`
+	Features    []Features `json:"features"`
+	ModelScores []float64  `json:"model_scores,omitempty"`
+	// ServedBy is "model" or "heuristic".
+	ServedBy string `json:"served_by"`
+}
+
+type FeatureSink interface {
+	Record(ctx context.Context, rec FeatureRecord)
+}
+
+// ModelRanker scores best value searches with an external model and falls
+// back to the heuristic when the model fails, is slow or isn't configured.
+// With a feature sink and no scorer it only logs features, to build
+// training data before a model exists.
+type ModelRanker struct {
+	scorer  Scorer
+	timeout time.Duration
+	sink    FeatureSink
+}
+
+func NewModelRanker(scorer Scorer, timeout time.Duration) *ModelRanker {
+	return &ModelRanker{scorer: scorer, timeout: timeout}
+}
+
+func (m *ModelRanker) SetFeatureSink(s FeatureSink) {
+	m.sink = s
+}
+
+// ScoreFunc returns the scoring for one search.
+func (m *ModelRanker) ScoreFunc(ctx context.Context) ScoreFunc {
+	return func(flights []models.Flight, weights Weights) []models.Flight {
+		return m.score(ctx, flights, weights)
+	}
+}
+
+func (m *ModelRanker) score(ctx context.Context, flights []models.Flight, weights Weights) []models.Flight {
+	if len(flights) == 0 || (m.scorer == nil && m.sink == nil) {
+		return CalculateScores(flights, weights)
+	}
+
+	features := ExtractFeatures(flights, weights)
+	rec := FeatureRecord{Time: time.Now(), RequestID: reqctx.RequestID(ctx), Features: features, ServedBy: "heuristic"}
+
+	result := CalculateScores(flights, weights)
+	if m.scorer != nil {
+		scores, err := m.modelScores(ctx, features)
+		if err != nil {
+			reqctx.Logf(ctx, "Ranking model unavailable, using heuristic: %v", err)
+		} else {
+			for i := range result {
+				result[i].BestValueScore = round2(scores[i])
+				// The heuristic breakdown doesn't explain a model score.
+				result[i].ScoreDetails = nil
+			}
+			rec.ModelScores = scores
+			rec.ServedBy = "model"
+		}
+	}
+
+	if m.sink != nil {
+		m.sink.Record(ctx, rec)
+	}
+	return result
+}
+
+func (m *ModelRanker) modelScores(ctx context.Context, features []Features) ([]float64, error) {
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+	scores, err := m.scorer.Score(ctx, features)
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) != len(features) {
+		return nil, fmt.Errorf("model returned %d scores for %d flights", len(scores), len(features))
+	}
+	for _, s := range scores {
+		if math.IsNaN(s) || math.IsInf(s, 0) {
+			return nil, fmt.Errorf("model returned non-finite score %v", s)
+		}
+	}
+	return scores, nil
+}
@@ -0,0 +1,86 @@
+package ranking
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+const ProfilePrefix = "profile:"
+
+var profiles = map[string]Weights{
+	"cheapest": {
+		Price:    0.8,
+		Duration: 0.1,
+		Stops:    0.1,
+	},
+	"fastest": {
+		Price:    0.1,
+		Duration: 0.6,
+		Stops:    0.3,
+	},
+	"comfort": {
+		Price:         0.15,
+		Duration:      0.15,
+		Stops:         0.15,
+		Layover:       0.15,
+		DepartureTime: 0.1,
+		Baggage:       0.1,
+		Aircraft:      0.2,
+	},
+	"reliable": {
+		Price:       0.3,
+		Duration:    0.2,
+		Stops:       0.1,
+		Reliability: 0.4,
+	},
+	"business": {
+		Price:         0.05,
+		Duration:      0.35,
+		Stops:         0.2,
+		Layover:       0.15,
+		DepartureTime: 0.2,
+		Baggage:       0.05,
+	},
+}
+
+// ProfileName extracts the profile from a sort_by value such as
+// "profile:comfort" or "profile:comfort,departure".
+func ProfileName(sortBy string) (string, bool) {
+	for _, token := range strings.Split(sortBy, ",") {
+		token = strings.ToLower(strings.TrimSpace(token))
+		if strings.HasPrefix(token, ProfilePrefix) {
+			return token[len(ProfilePrefix):], true
+		}
+	}
+	return "", false
+}
+
+func LookupProfile(name string) (Weights, bool) {
+	w, ok := profiles[strings.ToLower(name)]
+	return w, ok
+}
+
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WeightsFor picks the weights a search ranks with: its profile, then its
+// custom ranking_weights, then defaults.
+func WeightsFor(req models.SearchRequest, defaults Weights) Weights {
+	if name, ok := ProfileName(req.SortBy); ok {
+		if w, found := LookupProfile(name); found {
+			return w
+		}
+	}
+	if req.RankingWeights != nil {
+		return WeightsFromModel(*req.RankingWeights)
+	}
+	return defaults
+}
@@ -0,0 +1,80 @@
+package ranking
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+)
+
+type scoreRequest struct {
+	Flights []Features `json:"flights"`
+}
+
+type scoreResponse struct {
+	Scores []float64 `json:"scores"`
+}
+
+// HTTPScorer calls a model server that takes {"flights": [features...]} and
+// answers {"scores": [...]}. The caller's context bounds each call.
+type HTTPScorer struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPScorer(url string) *HTTPScorer {
+	return &HTTPScorer{url: url, client: &http.Client{}}
+}
+
+func (s *HTTPScorer) Score(ctx context.Context, features []Features) ([]float64, error) {
+	body, err := json.Marshal(scoreRequest{Flights: features})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("model server returned status %d", resp.StatusCode)
+	}
+	var out scoreResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode model response: %w", err)
+	}
+	return out.Scores, nil
+}
+
+// FileFeatureLog appends feature records to a file as JSON lines.
+type FileFeatureLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewFileFeatureLog(path string) (*FileFeatureLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileFeatureLog{enc: json.NewEncoder(f)}, nil
+}
+
+func (l *FileFeatureLog) Record(_ context.Context, rec FeatureRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(rec); err != nil {
+		log.Printf("Failed to write ranking features: %v", err)
+	}
+}
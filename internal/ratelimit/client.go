@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// ClientLimiter limits inbound requests per client. Unlike Limiter it
+// rejects rather than delays: Allow reports how long the client should
+// wait before retrying.
+type ClientLimiter interface {
+	Allow(ctx context.Context, client string) (bool, time.Duration)
+}
+
+type clientBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// MemoryClientLimiter keeps one token bucket per client in process. Buckets
+// idle long enough to have refilled are dropped, so clients identified by
+// IP don't accumulate.
+type MemoryClientLimiter struct {
+	config RateLimitConfig
+	idle   time.Duration
+
+	mu        sync.Mutex
+	clients   map[string]*clientBucket
+	lastSweep time.Time
+}
+
+func NewMemoryClientLimiter(config RateLimitConfig) *MemoryClientLimiter {
+	return &MemoryClientLimiter{
+		config:    config,
+		idle:      time.Duration(float64(config.BurstSize)/config.RequestsPerSecond*float64(time.Second)) + time.Minute,
+		clients:   make(map[string]*clientBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *MemoryClientLimiter) Allow(_ context.Context, client string) (bool, time.Duration) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) > m.idle {
+		for id, b := range m.clients {
+			if now.Sub(b.lastSeen) > m.idle {
+				delete(m.clients, id)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	b, ok := m.clients[client]
+	if !ok {
+		b = &clientBucket{limiter: rate.NewLimiter(rate.Limit(m.config.RequestsPerSecond), m.config.BurstSize)}
+		m.clients[client] = b
+	}
+	b.lastSeen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+const redisClientKeyPrefix = "ratelimit:client:"
+
+// RedisClientLimiter shares each client's bucket across replicas. Like
+// RedisLimiter it fails open.
+type RedisClientLimiter struct {
+	client redis.UniversalClient
+	config RateLimitConfig
+}
+
+func NewRedisClientLimiter(client redis.UniversalClient, config RateLimitConfig) *RedisClientLimiter {
+	return &RedisClientLimiter{client: client, config: config}
+}
+
+func (r *RedisClientLimiter) Allow(ctx context.Context, client string) (bool, time.Duration) {
+	wait, taken, err := take(ctx, r.client, redisClientKeyPrefix+client, r.config, 0, 0, false)
+	if err != nil {
+		log.Printf("Client rate limiter unavailable, allowing request: %v", err)
+		return true, 0
+	}
+	return taken, wait
+}
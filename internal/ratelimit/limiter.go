@@ -2,20 +2,48 @@ package ratelimit
 
 import (
 	"context"
+	"sort"
 	"sync"
+	"time"
 
 	"golang.org/x/time/rate"
+
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
 )
 
+// Limiter throttles outbound calls per provider. Calls from trusted
+// callers (reqctx.Trusted) may use the priority reserve.
+type Limiter interface {
+	Wait(ctx context.Context, provider string) error
+	SetProviderLimit(provider string, rps float64, burst int)
+	Snapshot() []LimitStatus
+}
+
+// ProviderLimiter keeps one in-process token bucket per provider. Each
+// replica gets the full quota, so it is only suitable for single-node
+// deployments.
 type ProviderLimiter struct {
 	limiters map[string]*rate.Limiter
 	mu       sync.RWMutex
 	defaults RateLimitConfig
+
+	// takeMu makes the reserve check and the take one step for ordinary
+	// callers. Trusted callers don't take it, so the reserve can be dipped
+	// into by at most one token per concurrent trusted call.
+	takeMu sync.Mutex
 }
 
 type RateLimitConfig struct {
 	RequestsPerSecond float64
 	BurstSize         int
+	// PriorityReserve is the fraction of each provider's burst that only
+	// trusted callers may use.
+	PriorityReserve float64
+}
+
+// reserve is the number of tokens held back from ordinary callers.
+func (c RateLimitConfig) reserve(burst int) float64 {
+	return c.PriorityReserve * float64(burst)
 }
 
 func DefaultConfig() RateLimitConfig {
@@ -64,6 +92,81 @@ func (p *ProviderLimiter) SetProviderLimit(provider string, rps float64, burst i
 	p.limiters[provider] = rate.NewLimiter(rate.Limit(rps), burst)
 }
 
+type LimitStatus struct {
+	Provider          string  `json:"provider"`
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	BurstSize         int     `json:"burst_size"`
+	TokensAvailable   float64 `json:"tokens_available"`
+	Utilization       float64 `json:"utilization"`
+}
+
+// Snapshot reports the current token bucket state of every provider limiter.
+func (p *ProviderLimiter) Snapshot() []LimitStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := make([]LimitStatus, 0, len(p.limiters))
+	for name, l := range p.limiters {
+		tokens := l.Tokens()
+		status := LimitStatus{
+			Provider:          name,
+			RequestsPerSecond: float64(l.Limit()),
+			BurstSize:         l.Burst(),
+			TokensAvailable:   tokens,
+		}
+		if l.Burst() > 0 {
+			status.Utilization = 1 - tokens/float64(l.Burst())
+		}
+		result = append(result, status)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Provider < result[j].Provider
+	})
+	return result
+}
+
 func (p *ProviderLimiter) Wait(ctx context.Context, provider string) error {
-	return p.GetLimiter(provider).Wait(ctx)
+	limiter := p.GetLimiter(provider)
+	reserve := p.defaults.reserve(limiter.Burst())
+	if reserve <= 0 || reqctx.Trusted(ctx) || limiter.Limit() <= 0 {
+		return limiter.Wait(ctx)
+	}
+
+	// Ordinary callers don't queue for tokens, since a queued reservation
+	// would also hold up trusted callers; they wait until the bucket is
+	// above the reserve and try again.
+	for {
+		p.takeMu.Lock()
+		tokens := limiter.Tokens()
+		if tokens-1 >= reserve && limiter.Allow() {
+			p.takeMu.Unlock()
+			return nil
+		}
+		p.takeMu.Unlock()
+
+		wait := time.Duration((reserve + 1 - tokens) / float64(limiter.Limit()) * float64(time.Second))
+		if exceedsDeadline(ctx, wait) {
+			return ErrWaitExceedsDeadline
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+func exceedsDeadline(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return ok && time.Until(deadline) < d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,206 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/reqctx"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+var ErrWaitExceedsDeadline = errorsx.New(errorsx.RateLimited, "rate limit wait exceeds context deadline")
+
+const redisKeyPrefix = "ratelimit:"
+
+// takeScript takes one token from a bucket shared by all replicas. With
+// ARGV[5] = 1 the caller queues: the bucket may go negative and the caller
+// is told how long to wait for its reservation, like rate.Limiter.Wait.
+// Reservations that would wait longer than ARGV[3] milliseconds are not
+// taken. Without queueing, a token is only taken if one is available now.
+// Tokens below ARGV[4] are not available to the caller. Returns {wait ms,
+// taken}. Uses the Redis clock so replica clock skew does not matter.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local max_wait = tonumber(ARGV[3])
+local floor = tonumber(ARGV[4])
+local queue = ARGV[5] == '1'
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+	tokens = burst
+	ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local wait = 0
+if tokens - 1 < floor then
+	wait = math.ceil((floor + 1 - tokens) * 1000 / rate)
+end
+if wait > 0 and (not queue or (max_wait >= 0 and wait > max_wait)) then
+	return {wait, 0}
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens - 1), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {wait, 1}
+`)
+
+// take runs takeScript and reports the wait and whether a token was taken.
+func take(ctx context.Context, client redis.UniversalClient, key string, l RateLimitConfig, maxWait int64, floor float64, queue bool) (time.Duration, bool, error) {
+	queueArg := 0
+	if queue {
+		queueArg = 1
+	}
+	res, err := takeScript.Run(ctx, client, []string{key},
+		l.RequestsPerSecond, l.BurstSize, maxWait, floor, queueArg).Int64Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	if len(res) != 2 {
+		return 0, false, fmt.Errorf("unexpected rate limit script result %v", res)
+	}
+	return time.Duration(res[0]) * time.Millisecond, res[1] == 1, nil
+}
+
+// RedisLimiter shares each provider's token bucket across replicas so the
+// configured quota holds for the whole cluster.
+type RedisLimiter struct {
+	client   redis.UniversalClient
+	mu       sync.RWMutex
+	limits   map[string]RateLimitConfig
+	defaults RateLimitConfig
+}
+
+func NewRedisLimiter(client redis.UniversalClient, config RateLimitConfig) *RedisLimiter {
+	return &RedisLimiter{
+		client:   client,
+		limits:   make(map[string]RateLimitConfig),
+		defaults: config,
+	}
+}
+
+func (r *RedisLimiter) SetProviderLimit(provider string, rps float64, burst int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limits[provider] = RateLimitConfig{RequestsPerSecond: rps, BurstSize: burst}
+}
+
+func (r *RedisLimiter) limit(provider string) RateLimitConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if l, ok := r.limits[provider]; ok {
+		return l
+	}
+	return r.defaults
+}
+
+// Wait fails open when Redis is unreachable: losing the shared quota for a
+// moment is better than failing every search. Ordinary callers don't queue
+// when a priority reserve is configured (see ProviderLimiter.Wait).
+func (r *RedisLimiter) Wait(ctx context.Context, provider string) error {
+	l := r.limit(provider)
+	if l.RequestsPerSecond <= 0 {
+		return nil
+	}
+
+	reserve := r.defaults.reserve(l.BurstSize)
+	if reqctx.Trusted(ctx) {
+		reserve = 0
+	}
+
+	for {
+		maxWait := int64(-1)
+		if deadline, ok := ctx.Deadline(); ok {
+			maxWait = time.Until(deadline).Milliseconds()
+		}
+
+		wait, taken, err := take(ctx, r.client, redisKeyPrefix+provider, l, maxWait, reserve, reserve <= 0)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("Rate limiter unavailable for %s, allowing call: %v", provider, err)
+			return nil
+		}
+		if taken {
+			return sleep(ctx, wait)
+		}
+		if reserve <= 0 || exceedsDeadline(ctx, wait) {
+			return ErrWaitExceedsDeadline
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Snapshot reads the shared buckets of every configured provider. Buckets
+// that were idle long enough to expire are reported as full.
+func (r *RedisLimiter) Snapshot() []LimitStatus {
+	r.mu.RLock()
+	limits := make(map[string]RateLimitConfig, len(r.limits))
+	for name, l := range r.limits {
+		limits[name] = l
+	}
+	r.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	now := time.Now()
+	result := make([]LimitStatus, 0, len(limits))
+	for name, l := range limits {
+		status := LimitStatus{
+			Provider:          name,
+			RequestsPerSecond: l.RequestsPerSecond,
+			BurstSize:         l.BurstSize,
+			TokensAvailable:   float64(l.BurstSize),
+		}
+		if tokens, err := r.tokens(ctx, name, l, now); err == nil {
+			status.TokensAvailable = tokens
+		}
+		if l.BurstSize > 0 {
+			status.Utilization = 1 - status.TokensAvailable/float64(l.BurstSize)
+		}
+		result = append(result, status)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Provider < result[j].Provider
+	})
+	return result
+}
+
+func (r *RedisLimiter) tokens(ctx context.Context, provider string, l RateLimitConfig, now time.Time) (float64, error) {
+	vals, err := r.client.HMGet(ctx, redisKeyPrefix+provider, "tokens", "ts").Result()
+	if err != nil {
+		return 0, err
+	}
+	if vals[0] == nil || vals[1] == nil {
+		return float64(l.BurstSize), nil
+	}
+
+	tokens, err := strconv.ParseFloat(fmt.Sprint(vals[0]), 64)
+	if err != nil {
+		return 0, err
+	}
+	ts, err := strconv.ParseInt(fmt.Sprint(vals[1]), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	elapsed := float64(now.UnixMilli()-ts) / 1000
+	return math.Min(float64(l.BurstSize), tokens+math.Max(0, elapsed)*l.RequestsPerSecond), nil
+}
@@ -0,0 +1,124 @@
+// Package recording keeps what each search's providers answered and the
+// configuration it ran under, so the search can be replayed exactly when
+// investigating why results differed.
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/configsnap"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+var ErrNotFound = errorsx.New(errorsx.NotFound, "search recording not found")
+
+type Recording struct {
+	SearchID   string               `json:"search_id"`
+	RecordedAt time.Time            `json:"recorded_at"`
+	Request    models.SearchRequest `json:"request"`
+
+	ConfigVersion string              `json:"config_version"`
+	Config        configsnap.Snapshot `json:"config"`
+
+	Outbound []aggregator.ProviderResponse `json:"outbound"`
+	// Return is set for round trips, empty if the return search failed.
+	Return []aggregator.ProviderResponse `json:"return,omitempty"`
+}
+
+// Store keeps recordings for a fixed TTL. Searches sharing a search_id
+// keep the first recording, like their snapshot.
+type Store interface {
+	Save(ctx context.Context, r Recording) error
+	Get(ctx context.Context, searchID string) (Recording, error)
+}
+
+type memoryEntry struct {
+	recording Recording
+	expiresAt time.Time
+}
+
+type MemoryStore struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, entries: make(map[string]memoryEntry), lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Save(ctx context.Context, r Recording) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > s.ttl {
+		for id, e := range s.entries {
+			if now.After(e.expiresAt) {
+				delete(s.entries, id)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if e, ok := s.entries[r.SearchID]; ok && now.Before(e.expiresAt) {
+		return nil
+	}
+	s.entries[r.SearchID] = memoryEntry{recording: r, expiresAt: now.Add(s.ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, searchID string) (Recording, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[searchID]
+	if !ok || time.Now().After(e.expiresAt) {
+		return Recording{}, ErrNotFound
+	}
+	return e.recording, nil
+}
+
+const redisKeyPrefix = "recording:"
+
+type RedisStore struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+func NewRedisStore(client redis.UniversalClient, ttl time.Duration) *RedisStore {
+	return &RedisStore{client: client, ttl: ttl}
+}
+
+func (s *RedisStore) Save(ctx context.Context, r Recording) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.client.SetNX(ctx, redisKeyPrefix+r.SearchID, data, s.ttl).Err()
+}
+
+func (s *RedisStore) Get(ctx context.Context, searchID string) (Recording, error) {
+	data, err := s.client.Get(ctx, redisKeyPrefix+searchID).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Recording{}, ErrNotFound
+	}
+	if err != nil {
+		return Recording{}, err
+	}
+	var r Recording
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Recording{}, err
+	}
+	return r, nil
+}
@@ -0,0 +1,51 @@
+package refdata
+
+import (
+	"maps"
+	"slices"
+)
+
+// TableChanges lists the IATA codes an import adds, removes and changes in
+// one table, sorted.
+type TableChanges struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+func (t TableChanges) Empty() bool {
+	return len(t.Added)+len(t.Removed)+len(t.Changed) == 0
+}
+
+// Changes is what activating a dataset would change, for review before
+// it goes live.
+type Changes struct {
+	Airports TableChanges `json:"airports"`
+	Airlines TableChanges `json:"airlines"`
+}
+
+func Diff(from, to *Dataset) Changes {
+	return Changes{
+		Airports: diffTable(from.Airports, to.Airports),
+		Airlines: diffTable(from.Airlines, to.Airlines),
+	}
+}
+
+func diffTable[T comparable](from, to map[string]T) TableChanges {
+	c := TableChanges{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	for _, code := range slices.Sorted(maps.Keys(to)) {
+		old, ok := from[code]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, code)
+		case old != to[code]:
+			c.Changed = append(c.Changed, code)
+		}
+	}
+	for _, code := range slices.Sorted(maps.Keys(from)) {
+		if _, ok := to[code]; !ok {
+			c.Removed = append(c.Removed, code)
+		}
+	}
+	return c
+}
@@ -0,0 +1,258 @@
+package refdata
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+// Formats of the airport dumps Import reads. Airlines are only read in the
+// OpenFlights format; OurAirports has none.
+const (
+	FormatOpenFlights = "openflights"
+	FormatOurAirports = "ourairports"
+)
+
+var (
+	iataAirport = regexp.MustCompile(`^[A-Z]{3}$`)
+	iataAirline = regexp.MustCompile(`^[A-Z0-9]{2}$`)
+)
+
+// Problem is a row Import rejected.
+type Problem struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Code   string `json:"code,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Report describes what Import made of the dumps.
+type Report struct {
+	AirportFormat string `json:"airport_format,omitempty"`
+	// Ignored counts rows that aren't reference data: airports without an
+	// IATA code or closed, and airlines without one or no longer flying.
+	Ignored  int       `json:"ignored"`
+	Rejected []Problem `json:"rejected"`
+}
+
+// Import builds the dataset the dumps describe on top of base. A nil
+// reader keeps base's table. Rows that fail validation are rejected and
+// reported rather than failing the import; a dump that can't be read, or
+// leaves a table empty, is an Invalid error.
+func Import(base *Dataset, airports, airlines io.Reader, now time.Time) (*Dataset, Report, error) {
+	report := Report{Rejected: []Problem{}}
+	next := &Dataset{ImportedAt: now, Airports: base.Airports, Airlines: base.Airlines}
+
+	if airports != nil {
+		table, err := importAirports(base, airports, &report)
+		if err != nil {
+			return nil, report, errorsx.Errorf(errorsx.Invalid, "airports: %w", err)
+		}
+		if len(table) == 0 {
+			return nil, report, errorsx.New(errorsx.Invalid, "airports: no airport with an IATA code")
+		}
+		next.Airports = table
+	}
+	if airlines != nil {
+		table, err := importAirlines(airlines, &report)
+		if err != nil {
+			return nil, report, errorsx.Errorf(errorsx.Invalid, "airlines: %w", err)
+		}
+		if len(table) == 0 {
+			return nil, report, errorsx.New(errorsx.Invalid, "airlines: no active airline with an IATA code")
+		}
+		next.Airlines = table
+	}
+	next.seal()
+	return next, report, nil
+}
+
+// row is a dump's record by column name.
+type row func(column string) string
+
+func importAirports(base *Dataset, r io.Reader, report *Report) (map[string]Airport, error) {
+	records := newReader(r)
+	first, err := records.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	var get func(rec []string) row
+	switch {
+	case slices.Contains(first, "iata_code"):
+		report.AirportFormat = FormatOurAirports
+		get = byHeader(first)
+		first = nil
+	case len(first) == 14:
+		report.AirportFormat = FormatOpenFlights
+		get = byPosition("id", "name", "city", "country", "iata", "icao", "latitude", "longitude", "altitude", "utc_offset", "dst", "tz", "type", "source")
+	default:
+		return nil, errors.New("not an OpenFlights airports.dat or OurAirports airports.csv dump")
+	}
+
+	table := make(map[string]Airport)
+	validZone := zoneChecker()
+	reject := func(line int, code, format string, args ...any) {
+		report.Rejected = append(report.Rejected, Problem{File: "airports", Line: line, Code: code, Reason: fmt.Sprintf(format, args...)})
+	}
+	for rec := first; ; rec = nil {
+		if rec == nil {
+			if rec, err = records.Read(); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		line, _ := records.FieldPos(0)
+		col := get(rec)
+
+		var a Airport
+		switch report.AirportFormat {
+		case FormatOurAirports:
+			if col("iata_code") == "" || col("type") == "closed" {
+				report.Ignored++
+				continue
+			}
+			a = Airport{IATA: col("iata_code"), ICAO: col("gps_code"), Name: col("name"), City: col("municipality"), Country: col("iso_country")}
+			a.Latitude, a.Longitude = coordinates(col("latitude_deg"), col("longitude_deg"))
+			// OurAirports has no timezones; airports already known keep
+			// theirs.
+			a.Timezone = base.Airports[strings.ToUpper(a.IATA)].Timezone
+		default:
+			if col("iata") == "" || col("type") == "closed" {
+				report.Ignored++
+				continue
+			}
+			a = Airport{IATA: col("iata"), ICAO: col("icao"), Name: col("name"), City: col("city"), Country: col("country"), Timezone: col("tz")}
+			a.Latitude, a.Longitude = coordinates(col("latitude"), col("longitude"))
+		}
+
+		a.IATA = strings.ToUpper(a.IATA)
+		switch {
+		case !iataAirport.MatchString(a.IATA):
+			reject(line, a.IATA, "%q is not a 3-letter IATA airport code", a.IATA)
+		case a.Timezone == "":
+			reject(line, a.IATA, "no timezone")
+		case !validZone(a.Timezone):
+			reject(line, a.IATA, "unknown timezone %q", a.Timezone)
+		case table[a.IATA].IATA != "":
+			reject(line, a.IATA, "duplicate of %s", table[a.IATA].Name)
+		default:
+			table[a.IATA] = a
+		}
+	}
+	return table, nil
+}
+
+// coordinates reads a latitude and longitude in degrees; either being
+// missing or out of range leaves both unknown.
+func coordinates(lat, lon string) (float64, float64) {
+	la, err1 := strconv.ParseFloat(lat, 64)
+	lo, err2 := strconv.ParseFloat(lon, 64)
+	if err1 != nil || err2 != nil || math.Abs(la) > 90 || math.Abs(lo) > 180 {
+		return 0, 0
+	}
+	return la, lo
+}
+
+func importAirlines(r io.Reader, report *Report) (map[string]Airline, error) {
+	records := newReader(r)
+	get := byPosition("id", "name", "alias", "iata", "icao", "callsign", "country", "active")
+	table := make(map[string]Airline)
+	for {
+		rec, err := records.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) != 8 {
+			return nil, errors.New("not an OpenFlights airlines.dat dump")
+		}
+		line, _ := records.FieldPos(0)
+		col := get(rec)
+		// Defunct airlines' codes have been reassigned; only the current
+		// holder counts.
+		if col("iata") == "" || col("iata") == "-" || col("active") != "Y" {
+			report.Ignored++
+			continue
+		}
+
+		a := Airline{IATA: strings.ToUpper(col("iata")), ICAO: col("icao"), Name: col("name"), Country: col("country")}
+		switch {
+		case !iataAirline.MatchString(a.IATA):
+			report.Rejected = append(report.Rejected, Problem{File: "airlines", Line: line, Code: a.IATA, Reason: fmt.Sprintf("%q is not a 2-character IATA airline code", a.IATA)})
+		case a.Name == "":
+			report.Rejected = append(report.Rejected, Problem{File: "airlines", Line: line, Code: a.IATA, Reason: "no name"})
+		case table[a.IATA].IATA != "":
+			report.Rejected = append(report.Rejected, Problem{File: "airlines", Line: line, Code: a.IATA, Reason: "duplicate of " + table[a.IATA].Name})
+		default:
+			table[a.IATA] = a
+		}
+	}
+	return table, nil
+}
+
+func newReader(r io.Reader) *csv.Reader {
+	records := csv.NewReader(r)
+	records.FieldsPerRecord = -1
+	records.LazyQuotes = true
+	records.ReuseRecord = false
+	return records
+}
+
+// byHeader reads columns named in a header row.
+func byHeader(header []string) func([]string) row {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+	return func(rec []string) row {
+		return func(column string) string {
+			i, ok := index[column]
+			if !ok || i >= len(rec) {
+				return ""
+			}
+			return clean(rec[i])
+		}
+	}
+}
+
+// byPosition reads headerless columns in the given order.
+func byPosition(columns ...string) func([]string) row {
+	return byHeader(columns)
+}
+
+// clean trims a value and turns OpenFlights' \N for null into "".
+func clean(v string) string {
+	v = strings.TrimSpace(v)
+	if v == `\N` {
+		return ""
+	}
+	return v
+}
+
+// zoneChecker reports whether a timezone loads, remembering the answer:
+// a dump names the same few hundred zones thousands of times.
+func zoneChecker() func(name string) bool {
+	seen := make(map[string]bool)
+	return func(name string) bool {
+		ok, done := seen[name]
+		if !done {
+			_, err := time.LoadLocation(name)
+			ok = err == nil
+			seen[name] = ok
+		}
+		return ok
+	}
+}
@@ -0,0 +1,88 @@
+// Package refdata holds the airport and airline reference tables, which
+// admins replace by importing OpenFlights or OurAirports dumps instead of
+// editing code.
+package refdata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/airports"
+	"github.com/dharmasatrya/flightsearch/internal/timezone"
+)
+
+type Airport = airports.Airport
+
+type Airline struct {
+	IATA    string `json:"iata"`
+	ICAO    string `json:"icao,omitempty"`
+	Name    string `json:"name"`
+	Country string `json:"country,omitempty"`
+}
+
+// Dataset is one version of both tables, keyed by IATA code.
+type Dataset struct {
+	Version string `json:"version"`
+	// ImportedAt is zero for the built-in tables.
+	ImportedAt time.Time          `json:"imported_at,omitzero"`
+	Airports   map[string]Airport `json:"airports"`
+	Airlines   map[string]Airline `json:"airlines"`
+}
+
+// seal sets the dataset's version from its contents, so the same tables
+// imported twice share a version.
+func (d *Dataset) seal() {
+	// Maps marshal with sorted keys, so the encoding is stable.
+	data, _ := json.Marshal(struct {
+		Airports map[string]Airport `json:"airports"`
+		Airlines map[string]Airline `json:"airlines"`
+	}{d.Airports, d.Airlines})
+	sum := sha256.Sum256(data)
+	d.Version = hex.EncodeToString(sum[:6])
+}
+
+// Builtin is the dataset the service ships with: the embedded airport
+// database and no airlines.
+func Builtin() *Dataset {
+	d := &Dataset{Airports: airports.Builtin(), Airlines: make(map[string]Airline)}
+	d.seal()
+	return d
+}
+
+var active atomic.Pointer[Dataset]
+
+func init() {
+	active.Store(Builtin())
+}
+
+// Active is the dataset this replica serves.
+func Active() *Dataset {
+	return active.Load()
+}
+
+// Activate makes d the dataset this replica serves. Each table is swapped
+// whole, so a search never sees half an import.
+func Activate(d *Dataset) error {
+	tzs := make(map[string]string, len(d.Airports))
+	for code, a := range d.Airports {
+		tzs[code] = a.Timezone
+	}
+	if err := timezone.SetAirports(tzs); err != nil {
+		return err
+	}
+	airports.Set(d.Airports)
+	active.Store(d)
+	log.Printf("Reference data %s active: %d airports, %d airlines", d.Version, len(d.Airports), len(d.Airlines))
+	return nil
+}
+
+// AirlineName is the reference name of the airline with IATA code code.
+func AirlineName(code string) (string, bool) {
+	a, ok := Active().Airlines[strings.ToUpper(code)]
+	return a.Name, ok
+}
@@ -0,0 +1,160 @@
+package refdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+var ErrNotStaged = errorsx.New(errorsx.NotFound, "reference data version not staged")
+
+// StagedTTL is how long an import waits for activation before it is
+// dropped and has to be uploaded again.
+const StagedTTL = time.Hour
+
+// Store keeps imports between their preview and activation, and the
+// dataset activated last.
+type Store interface {
+	Stage(ctx context.Context, d *Dataset) error
+	// Activate records the staged version as the active dataset and
+	// returns it.
+	Activate(ctx context.Context, version string) (*Dataset, error)
+	// Active is nil until a dataset is activated.
+	Active(ctx context.Context) (*Dataset, error)
+}
+
+type staged struct {
+	dataset   *Dataset
+	expiresAt time.Time
+}
+
+type MemoryStore struct {
+	mu     sync.Mutex
+	staged map[string]staged
+	active *Dataset
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{staged: make(map[string]staged)}
+}
+
+func (s *MemoryStore) Stage(ctx context.Context, d *Dataset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for v, st := range s.staged {
+		if !now.Before(st.expiresAt) {
+			delete(s.staged, v)
+		}
+	}
+	s.staged[d.Version] = staged{dataset: d, expiresAt: now.Add(StagedTTL)}
+	return nil
+}
+
+func (s *MemoryStore) Activate(ctx context.Context, version string) (*Dataset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.staged[version]
+	if !ok || !time.Now().Before(st.expiresAt) {
+		return nil, fmt.Errorf("%w: %s", ErrNotStaged, version)
+	}
+	s.active = st.dataset
+	return st.dataset, nil
+}
+
+func (s *MemoryStore) Active(ctx context.Context) (*Dataset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active, nil
+}
+
+const (
+	redisStagedPrefix = "refdata:staged:"
+	redisActiveKey    = "refdata:active"
+)
+
+// RedisStore shares imports between replicas: any replica can activate
+// an import previewed on another, and Watch brings the rest along.
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Stage(ctx context.Context, d *Dataset) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisStagedPrefix+d.Version, data, StagedTTL).Err()
+}
+
+func (s *RedisStore) Activate(ctx context.Context, version string) (*Dataset, error) {
+	data, err := s.client.Get(ctx, redisStagedPrefix+version).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: %s", ErrNotStaged, version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var d Dataset
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+	if err := s.client.Set(ctx, redisActiveKey, data, 0).Err(); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (s *RedisStore) Active(ctx context.Context) (*Dataset, error) {
+	data, err := s.client.Get(ctx, redisActiveKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var d Dataset
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Watch activates the store's active dataset on this replica whenever it
+// differs from the one served, until ctx is done.
+func Watch(ctx context.Context, store Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := Sync(ctx, store); err != nil {
+			log.Printf("Reference data sync failed: %v", err)
+		}
+	}
+}
+
+// Sync activates the store's active dataset on this replica if it isn't
+// already served.
+func Sync(ctx context.Context, store Store) error {
+	d, err := store.Active(ctx)
+	if err != nil || d == nil || d.Version == Active().Version {
+		return err
+	}
+	return Activate(d)
+}
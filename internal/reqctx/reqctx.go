@@ -0,0 +1,99 @@
+// Package reqctx carries who is calling and under which flags through a
+// request's context, resolved once by the HTTP middleware.
+package reqctx
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+type Tier string
+
+const (
+	// TierAnonymous is used for requests without an API key.
+	TierAnonymous Tier = "anonymous"
+	TierStandard  Tier = "standard"
+	// TierInternal is for trusted internal systems that coordinate their
+	// own load: they skip inbound rate limits and may use the providers'
+	// priority reserve.
+	TierInternal Tier = "internal"
+)
+
+// Flags is the feature flag snapshot taken when the request started. It
+// is shared between requests and must not be modified.
+type Flags map[string]bool
+
+func (f Flags) Enabled(name string) bool {
+	return f[name]
+}
+
+// Key is what an API key resolves to. Admin keys belong to the tenant's
+// back office, which may see how its prices are made up.
+type Key struct {
+	Tenant string
+	Tier   Tier
+	Admin  bool
+}
+
+type Info struct {
+	RequestID string
+	APIKey    string
+	Tenant    string
+	Tier      Tier
+	Admin     bool
+	Flags     Flags
+}
+
+type infoKey struct{}
+
+func With(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+// From returns the request info, or an anonymous Info when ctx doesn't
+// come from an HTTP request (background jobs).
+func From(ctx context.Context) Info {
+	if info, ok := ctx.Value(infoKey{}).(Info); ok {
+		return info
+	}
+	return Info{Tier: TierAnonymous}
+}
+
+func RequestID(ctx context.Context) string {
+	return From(ctx).RequestID
+}
+
+func APIKey(ctx context.Context) string {
+	return From(ctx).APIKey
+}
+
+func Tenant(ctx context.Context) string {
+	return From(ctx).Tenant
+}
+
+func ClientTier(ctx context.Context) Tier {
+	return From(ctx).Tier
+}
+
+func Admin(ctx context.Context) bool {
+	return From(ctx).Admin
+}
+
+func Trusted(ctx context.Context) bool {
+	return ClientTier(ctx) == TierInternal
+}
+
+func FlagEnabled(ctx context.Context, name string) bool {
+	return From(ctx).Flags.Enabled(name)
+}
+
+// Logf logs with the request ID as prefix so lines from concurrent
+// searches can be told apart.
+func Logf(ctx context.Context, format string, args ...any) {
+	if id := RequestID(ctx); id != "" {
+		log.Printf("[%s] %s", id, fmt.Sprintf(format, args...))
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,141 @@
+package retention
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const batchTimeFormat = "20060102T150405Z"
+
+type ArchiveInfo struct {
+	Batches int
+	Oldest  *time.Time
+}
+
+// Archiver is the cold storage archived records are moved to.
+type Archiver interface {
+	Archive(ctx context.Context, dataset string, at time.Time, records []any) error
+	Purge(ctx context.Context, dataset string, before time.Time) (int, error)
+	Info(ctx context.Context, dataset string) (ArchiveInfo, error)
+}
+
+// FileArchiver writes each batch as gzipped JSON lines under
+// <dir>/<dataset>/<timestamp>.jsonl.gz. The directory can be a mounted
+// bucket or synced to object storage.
+type FileArchiver struct {
+	dir string
+}
+
+func NewFileArchiver(dir string) *FileArchiver {
+	return &FileArchiver{dir: dir}
+}
+
+func (a *FileArchiver) Archive(ctx context.Context, dataset string, at time.Time, records []any) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	dir := filepath.Join(a.dir, dataset)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	final := filepath.Join(dir, at.UTC().Format(batchTimeFormat)+".jsonl.gz")
+	tmp := final + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(f)
+	enc := json.NewEncoder(gz)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, final)
+}
+
+func (a *FileArchiver) Purge(ctx context.Context, dataset string, before time.Time) (int, error) {
+	batches, err := a.batches(dataset)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, b := range batches {
+		if !b.at.Before(before) {
+			continue
+		}
+		if err := os.Remove(b.path); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (a *FileArchiver) Info(ctx context.Context, dataset string) (ArchiveInfo, error) {
+	batches, err := a.batches(dataset)
+	if err != nil {
+		return ArchiveInfo{}, err
+	}
+
+	info := ArchiveInfo{Batches: len(batches)}
+	if len(batches) > 0 {
+		oldest := batches[0].at
+		info.Oldest = &oldest
+	}
+	return info, nil
+}
+
+type batch struct {
+	path string
+	at   time.Time
+}
+
+func (a *FileArchiver) batches(dataset string) ([]batch, error) {
+	entries, err := os.ReadDir(filepath.Join(a.dir, dataset))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result []batch
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".jsonl.gz")
+		if !ok {
+			continue
+		}
+		at, err := time.Parse(batchTimeFormat, name)
+		if err != nil {
+			continue
+		}
+		result = append(result, batch{path: filepath.Join(a.dir, dataset, e.Name()), at: at})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].at.Before(result[j].at)
+	})
+	return result, nil
+}
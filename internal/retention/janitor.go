@@ -0,0 +1,129 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// SweepFunc moves every record older than before out of a hot store. It
+// must hand the records to archive and only delete them once archive
+// succeeded, returning the number of records removed.
+type SweepFunc func(ctx context.Context, before time.Time, archive func(records []any) error) (int, error)
+
+type Policy struct {
+	// ArchiveAfter is how long records stay in the hot store.
+	ArchiveAfter time.Duration
+	// PurgeAfter is the total retention; archives are deleted once every
+	// record in them is older than this.
+	PurgeAfter time.Duration
+}
+
+type Status struct {
+	Dataset        string     `json:"dataset"`
+	ArchiveAfter   string     `json:"archive_after"`
+	PurgeAfter     string     `json:"purge_after"`
+	LastRunAt      *time.Time `json:"last_run_at,omitempty"`
+	LastArchived   int        `json:"last_archived"`
+	LastPurged     int        `json:"last_purged_archives"`
+	TotalArchived  int        `json:"total_archived"`
+	TotalPurged    int        `json:"total_purged_archives"`
+	LastError      string     `json:"last_error,omitempty"`
+	OldestArchive  *time.Time `json:"oldest_archive,omitempty"`
+	ArchiveBatches int        `json:"archive_batches"`
+}
+
+type dataset struct {
+	name   string
+	policy Policy
+	sweep  SweepFunc
+}
+
+type Janitor struct {
+	archiver Archiver
+	datasets []dataset
+
+	mu     sync.Mutex
+	status map[string]*Status
+}
+
+func NewJanitor(archiver Archiver) *Janitor {
+	return &Janitor{
+		archiver: archiver,
+		status:   make(map[string]*Status),
+	}
+}
+
+func (j *Janitor) Register(name string, policy Policy, sweep SweepFunc) {
+	j.datasets = append(j.datasets, dataset{name: name, policy: policy, sweep: sweep})
+	j.status[name] = &Status{
+		Dataset:      name,
+		ArchiveAfter: policy.ArchiveAfter.String(),
+		PurgeAfter:   policy.PurgeAfter.String(),
+	}
+}
+
+func (j *Janitor) RunOnce(ctx context.Context) error {
+	var errs []error
+	for _, ds := range j.datasets {
+		if err := j.run(ctx, ds); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ds.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (j *Janitor) run(ctx context.Context, ds dataset) error {
+	now := time.Now()
+
+	archived, err := ds.sweep(ctx, now.Add(-ds.policy.ArchiveAfter), func(records []any) error {
+		return j.archiver.Archive(ctx, ds.name, now, records)
+	})
+	if err != nil {
+		log.Printf("Retention: archiving %s failed: %v", ds.name, err)
+	}
+
+	// A batch written at time T only holds records older than
+	// T-ArchiveAfter, so it can go once T is PurgeAfter-ArchiveAfter old.
+	purged, purgeErr := j.archiver.Purge(ctx, ds.name, now.Add(-(ds.policy.PurgeAfter - ds.policy.ArchiveAfter)))
+	if purgeErr != nil {
+		log.Printf("Retention: purging %s archives failed: %v", ds.name, purgeErr)
+		if err == nil {
+			err = purgeErr
+		}
+	}
+
+	info, infoErr := j.archiver.Info(ctx, ds.name)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := j.status[ds.name]
+	s.LastRunAt = &now
+	s.LastArchived = archived
+	s.LastPurged = purged
+	s.TotalArchived += archived
+	s.TotalPurged += purged
+	s.LastError = ""
+	if err != nil {
+		s.LastError = err.Error()
+	}
+	if infoErr == nil {
+		s.ArchiveBatches = info.Batches
+		s.OldestArchive = info.Oldest
+	}
+	return err
+}
+
+func (j *Janitor) Status() []Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	result := make([]Status, 0, len(j.datasets))
+	for _, ds := range j.datasets {
+		result = append(result, *j.status[ds.name])
+	}
+	return result
+}
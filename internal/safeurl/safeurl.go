@@ -0,0 +1,80 @@
+// Package safeurl keeps requests to client-supplied URLs, such as alert
+// webhooks, from reaching the service's own network.
+package safeurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrNotPublic = errors.New("address is not public")
+
+// lookupTimeout bounds the DNS lookup when checking a URL.
+const lookupTimeout = 2 * time.Second
+
+// sharedAddressSpace is the carrier-grade NAT range, which net.IP doesn't
+// count as private.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// Public reports whether ip is routable on the internet: not loopback,
+// private, link-local, multicast or unspecified.
+func Public(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip))
+}
+
+// Check accepts absolute http(s) URLs whose host resolves only to public
+// addresses.
+func Check(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("must be an absolute http or https URL")
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !Public(ip) {
+			return ErrNotPublic
+		}
+		return nil
+	}
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return ErrNotPublic
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	addrs, err := lookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("host %s does not resolve", host)
+	}
+	for _, a := range addrs {
+		if !Public(a.IP) {
+			return ErrNotPublic
+		}
+	}
+	return nil
+}
+
+// Control is a net.Dialer Control func that refuses connections to
+// non-public addresses, so a host that resolved to a public address when
+// checked can't be pointed inside later.
+func Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !Public(ip) {
+		return fmt.Errorf("dial %s: %w", address, ErrNotPublic)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package safeurl
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	hosts := map[string][]string{
+		"hooks.example.com": {"93.184.216.34"},
+		"internal.example":  {"93.184.216.34", "10.0.0.5"},
+		"rebind.example":    {"127.0.0.1"},
+	}
+	old := lookupIPAddr
+	lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		var addrs []net.IPAddr
+		for _, a := range hosts[host] {
+			addrs = append(addrs, net.IPAddr{IP: net.ParseIP(a)})
+		}
+		if len(addrs) == 0 {
+			return nil, errors.New("no such host")
+		}
+		return addrs, nil
+	}
+	defer func() { lookupIPAddr = old }()
+
+	tests := []struct {
+		url       string
+		ok        bool
+		notPublic bool
+	}{
+		{"https://hooks.example.com/price-drop", true, false},
+		{"http://93.184.216.34:8080/hook", true, false},
+		{"ftp://hooks.example.com/hook", false, false},
+		{"/relative/hook", false, false},
+		{"https://unknown.example/hook", false, false},
+		{"http://127.0.0.1/hook", false, true},
+		{"http://[::1]/hook", false, true},
+		{"http://169.254.169.254/latest/meta-data", false, true},
+		{"http://192.168.1.10/hook", false, true},
+		{"http://100.64.0.1/hook", false, true},
+		{"http://localhost:8080/hook", false, true},
+		{"https://internal.example/hook", false, true},
+		{"https://rebind.example/hook", false, true},
+	}
+	for _, tt := range tests {
+		err := Check(tt.url)
+		if (err == nil) != tt.ok {
+			t.Errorf("Check(%s) = %v, want ok=%v", tt.url, err, tt.ok)
+		}
+		if errors.Is(err, ErrNotPublic) != tt.notPublic {
+			t.Errorf("Check(%s) = %v, want not public=%v", tt.url, err, tt.notPublic)
+		}
+	}
+}
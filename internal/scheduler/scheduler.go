@@ -0,0 +1,271 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+var ErrUnknownJob = errorsx.New(errorsx.NotFound, "unknown job")
+
+type Func func(ctx context.Context) error
+
+// JobStatus is a job's schedule plus its stored state. Running is derived
+// from the last start and finish times, so any replica can report it.
+type JobStatus struct {
+	Name     string     `json:"name"`
+	Schedule string     `json:"schedule"`
+	Enabled  bool       `json:"enabled"`
+	Running  bool       `json:"running"`
+	NextRun  *time.Time `json:"next_run,omitempty"`
+	State
+}
+
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	fn       Func
+}
+
+// Scheduler runs registered jobs on their schedules. Job state (enabled
+// flag, pending run-now requests, last run) lives in the Store, so admin
+// calls can be served by any replica while only the replica running Start
+// executes jobs.
+type Scheduler struct {
+	store     Store
+	tick      time.Duration
+	onFailure func(JobStatus)
+
+	mu      sync.Mutex
+	jobs    []*job
+	running map[string]bool
+}
+
+func New(store Store) *Scheduler {
+	return &Scheduler{
+		store:   store,
+		tick:    time.Second,
+		running: make(map[string]bool),
+	}
+}
+
+func (s *Scheduler) Register(name, spec string, fn Func) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.name == name {
+			return fmt.Errorf("job %s registered twice", name)
+		}
+	}
+	s.jobs = append(s.jobs, &job{name: name, spec: spec, schedule: schedule, fn: fn})
+	return nil
+}
+
+// SetFailureHandler is called after every failed run.
+func (s *Scheduler) SetFailureHandler(fn func(JobStatus)) {
+	s.onFailure = fn
+}
+
+// Start runs due and requested jobs until ctx is done. A job never
+// overlaps with itself; a run that comes due while the previous one is
+// still going is skipped.
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+
+	next := make(map[string]time.Time)
+	now := time.Now()
+	states, err := s.store.Load(ctx)
+	if err != nil {
+		log.Printf("Scheduler: failed to load job state: %v", err)
+	}
+	for _, j := range s.jobList() {
+		next[j.name] = s.nextRun(j, states[j.name], now)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+
+		states, err := s.store.Load(ctx)
+		if err != nil {
+			log.Printf("Scheduler: failed to load job state: %v", err)
+			continue
+		}
+
+		for _, j := range s.jobList() {
+			state := states[j.name]
+			due := !next[j.name].IsZero() && !now.Before(next[j.name])
+			if due {
+				next[j.name] = j.schedule.Next(now)
+			}
+			if (due && !state.Disabled) || state.RunRequested {
+				s.launch(ctx, j)
+			}
+		}
+	}
+}
+
+func (s *Scheduler) launch(ctx context.Context, j *job) {
+	s.mu.Lock()
+	if s.running[j.name] {
+		s.mu.Unlock()
+		return
+	}
+	s.running[j.name] = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, j.name)
+			s.mu.Unlock()
+		}()
+		s.run(ctx, j)
+	}()
+}
+
+func (s *Scheduler) run(ctx context.Context, j *job) {
+	started := time.Now()
+	err := s.store.Update(ctx, j.name, func(st *State) {
+		st.RunRequested = false
+		st.LastStartedAt = &started
+	})
+	if err != nil {
+		log.Printf("Scheduler: failed to record start of %s: %v", j.name, err)
+	}
+
+	runErr := j.fn(ctx)
+
+	finished := time.Now()
+	var final State
+	err = s.store.Update(context.WithoutCancel(ctx), j.name, func(st *State) {
+		st.LastFinishedAt = &finished
+		st.LastDurationMs = finished.Sub(started).Milliseconds()
+		if runErr != nil {
+			st.LastStatus = StatusFailed
+			st.LastError = runErr.Error()
+			st.ConsecutiveFailures++
+		} else {
+			st.LastStatus = StatusSucceeded
+			st.LastError = ""
+			st.ConsecutiveFailures = 0
+		}
+		final = *st
+	})
+	if err != nil {
+		log.Printf("Scheduler: failed to record result of %s: %v", j.name, err)
+	}
+
+	if runErr != nil {
+		log.Printf("Scheduler: job %s failed after %v: %v", j.name, finished.Sub(started), runErr)
+		if s.onFailure != nil {
+			s.onFailure(s.status(j, final, finished))
+		}
+	}
+}
+
+func (s *Scheduler) Status(ctx context.Context) ([]JobStatus, error) {
+	states, err := s.store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	jobs := s.jobList()
+	result := make([]JobStatus, 0, len(jobs))
+	for _, j := range jobs {
+		result = append(result, s.status(j, states[j.name], now))
+	}
+
+	sort.Slice(result, func(i, k int) bool {
+		return result[i].Name < result[k].Name
+	})
+	return result, nil
+}
+
+func (s *Scheduler) JobStatus(ctx context.Context, name string) (JobStatus, error) {
+	all, err := s.Status(ctx)
+	if err != nil {
+		return JobStatus{}, err
+	}
+	for _, st := range all {
+		if st.Name == name {
+			return st, nil
+		}
+	}
+	return JobStatus{}, fmt.Errorf("%w %s", ErrUnknownJob, name)
+}
+
+func (s *Scheduler) SetEnabled(ctx context.Context, name string, enabled bool) error {
+	if s.find(name) == nil {
+		return fmt.Errorf("%w %s", ErrUnknownJob, name)
+	}
+	return s.store.Update(ctx, name, func(st *State) {
+		st.Disabled = !enabled
+	})
+}
+
+// RequestRun asks for an immediate run, disabled jobs included. It is
+// picked up on the scheduler's next tick.
+func (s *Scheduler) RequestRun(ctx context.Context, name string) error {
+	if s.find(name) == nil {
+		return fmt.Errorf("%w %s", ErrUnknownJob, name)
+	}
+	return s.store.Update(ctx, name, func(st *State) {
+		st.RunRequested = true
+	})
+}
+
+func (s *Scheduler) status(j *job, st State, now time.Time) JobStatus {
+	status := JobStatus{
+		Name:     j.name,
+		Schedule: j.spec,
+		Enabled:  !st.Disabled,
+		Running:  st.LastStartedAt != nil && (st.LastFinishedAt == nil || st.LastStartedAt.After(*st.LastFinishedAt)),
+		State:    st,
+	}
+
+	if next := s.nextRun(j, st, now); !next.IsZero() && !st.Disabled {
+		status.NextRun = &next
+	}
+	return status
+}
+
+// nextRun counts @every intervals from the last start, so a restart or a
+// leader change doesn't push long-interval jobs back by a full interval.
+func (s *Scheduler) nextRun(j *job, st State, now time.Time) time.Time {
+	if _, ok := j.schedule.(everySchedule); ok && st.LastStartedAt != nil {
+		return j.schedule.Next(*st.LastStartedAt)
+	}
+	return j.schedule.Next(now)
+}
+
+func (s *Scheduler) find(name string) *job {
+	for _, j := range s.jobList() {
+		if j.name == name {
+			return j
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) jobList() []*job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*job(nil), s.jobs...)
+}
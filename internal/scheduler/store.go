@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// State is what the scheduler remembers about a job between runs. The zero
+// value is an enabled job that never ran.
+type State struct {
+	Disabled            bool       `json:"-"`
+	RunRequested        bool       `json:"run_requested,omitempty"`
+	LastStartedAt       *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt      *time.Time `json:"last_finished_at,omitempty"`
+	LastDurationMs      int64      `json:"last_duration_ms,omitempty"`
+	LastStatus          string     `json:"last_status,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+type Store interface {
+	Load(ctx context.Context) (map[string]State, error)
+	Update(ctx context.Context, name string, fn func(*State)) error
+}
+
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]State
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]State)}
+}
+
+func (s *MemoryStore) Load(ctx context.Context) (map[string]State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string]State, len(s.states))
+	for name, st := range s.states {
+		result[name] = st
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, name string, fn func(*State)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.states[name]
+	fn(&st)
+	s.states[name] = st
+	return nil
+}
+
+const (
+	redisJobsKey      = "jobs:state"
+	maxUpdateAttempts = 10
+)
+
+// redisState keeps Disabled in the stored document; it is hidden from API
+// responses in favour of JobStatus.Enabled.
+type redisState struct {
+	State
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// RedisStore keeps all job states in one hash so every replica reports the
+// same status and sees the same enable/run-now requests.
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Load(ctx context.Context) (map[string]State, error) {
+	values, err := s.client.HGetAll(ctx, redisJobsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]State, len(values))
+	for name, v := range values {
+		st, err := decodeState([]byte(v))
+		if err != nil {
+			continue
+		}
+		result[name] = st
+	}
+	return result, nil
+}
+
+func (s *RedisStore) Update(ctx context.Context, name string, fn func(*State)) error {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+			var st State
+			data, err := tx.HGet(ctx, redisJobsKey, name).Bytes()
+			switch {
+			case errors.Is(err, redis.Nil):
+			case err != nil:
+				return err
+			default:
+				if st, err = decodeState(data); err != nil {
+					return err
+				}
+			}
+
+			fn(&st)
+			encoded, err := json.Marshal(redisState{State: st, Disabled: st.Disabled})
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.HSet(ctx, redisJobsKey, name, encoded)
+				return nil
+			})
+			return err
+		}, redisJobsKey)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("job state for %s: too much contention", name)
+}
+
+func decodeState(data []byte) (State, error) {
+	var rs redisState
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return State{}, err
+	}
+	rs.State.Disabled = rs.Disabled
+	return rs.State, nil
+}
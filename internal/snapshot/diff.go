@@ -0,0 +1,78 @@
+package snapshot
+
+import (
+	"math"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Diff is what changed from one snapshot to another. Both should be of the
+// same search, i.e. have the same Key.
+func Diff(from, to Snapshot) models.SearchDiff {
+	d := models.SearchDiff{
+		From:          side(from),
+		To:            side(to),
+		FlightChanges: diffFlights(from.Flights, to.Flights),
+	}
+	if from.ReturnFlights != nil || to.ReturnFlights != nil {
+		changes := diffFlights(from.ReturnFlights, to.ReturnFlights)
+		d.Return = &changes
+	}
+	return d
+}
+
+func side(s Snapshot) models.DiffSearch {
+	return models.DiffSearch{SearchID: s.ID, ResultsAsOf: s.CreatedAt, TotalResults: len(s.Flights)}
+}
+
+// diffFlights lists added flights and price changes in to's order, and
+// removed flights in from's.
+func diffFlights(from, to []models.Flight) models.FlightChanges {
+	changes := models.FlightChanges{
+		Added:        []models.Flight{},
+		Removed:      []models.Flight{},
+		PriceChanges: []models.PriceChange{},
+	}
+	before := make(map[string]models.Flight, len(from))
+	for _, f := range from {
+		before[flightKey(f)] = f
+	}
+	after := make(map[string]bool, len(to))
+	for _, f := range to {
+		key := flightKey(f)
+		after[key] = true
+		old, ok := before[key]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, f)
+		case old.Price.Amount != f.Price.Amount || old.Price.Currency != f.Price.Currency:
+			changes.PriceChanges = append(changes.PriceChanges, priceChange(old, f))
+		default:
+			changes.Unchanged++
+		}
+	}
+	for _, f := range from {
+		if !after[flightKey(f)] {
+			changes.Removed = append(changes.Removed, f)
+		}
+	}
+	return changes
+}
+
+// flightKey identifies a flight across searches. IDs are only unique per
+// provider.
+func flightKey(f models.Flight) string {
+	return f.Provider + "/" + f.ID
+}
+
+func priceChange(old, f models.Flight) models.PriceChange {
+	c := models.PriceChange{Flight: f, From: old.Price, To: f.Price}
+	// A change of currency has no meaningful amount.
+	if old.Price.Currency == f.Price.Currency {
+		c.Change = f.Price.Amount - old.Price.Amount
+		if old.Price.Amount != 0 {
+			c.ChangePercent = math.Round(c.Change/old.Price.Amount*1000) / 10
+		}
+	}
+	return c
+}
@@ -0,0 +1,160 @@
+// Package snapshot keeps the flights a search returned so a client can
+// re-filter and re-sort the same results for the rest of its session, even
+// after the search cache has refreshed.
+package snapshot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/cache"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+var ErrNotFound = errorsx.New(errorsx.Expired, "search snapshot expired")
+
+type Snapshot struct {
+	ID string `json:"id"`
+	// Key is the cache key of the search, so a snapshot is only replayed
+	// for the same route, dates, cabin and passengers.
+	Key           string          `json:"key"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Flights       []models.Flight `json:"flights"`
+	ReturnFlights []models.Flight `json:"return_flights,omitempty"`
+	// Metadata holds the provider counts of the search that was captured.
+	Metadata models.SearchMetadata `json:"metadata"`
+}
+
+// New builds a snapshot whose ID is derived from its contents, so repeated
+// searches answered from the same cache entry share one snapshot.
+func New(key string, flights, returnFlights []models.Flight, meta models.SearchMetadata) Snapshot {
+	h := sha256.New()
+	h.Write([]byte(key))
+	h.Write([]byte(cache.EntryHash(flights)))
+	if returnFlights != nil {
+		h.Write([]byte(cache.EntryHash(returnFlights)))
+	}
+	return Snapshot{
+		ID:            hex.EncodeToString(h.Sum(nil)[:12]),
+		Key:           key,
+		CreatedAt:     time.Now(),
+		Flights:       flights,
+		ReturnFlights: returnFlights,
+		Metadata:      meta,
+	}
+}
+
+// Store keeps snapshots for a fixed TTL from when they were last saved.
+type Store interface {
+	Save(ctx context.Context, s Snapshot) error
+	Get(ctx context.Context, id string) (Snapshot, error)
+}
+
+type memoryEntry struct {
+	snapshot  Snapshot
+	expiresAt time.Time
+}
+
+type MemoryStore struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, entries: make(map[string]memoryEntry), lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Save(ctx context.Context, snap Snapshot) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > s.ttl {
+		for id, e := range s.entries {
+			if now.After(e.expiresAt) {
+				delete(s.entries, id)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	// Same ID means same contents: keep the stored copy, and with it the
+	// original creation time, and only extend it.
+	if e, ok := s.entries[snap.ID]; ok && now.Before(e.expiresAt) {
+		snap = e.snapshot
+	}
+	s.entries[snap.ID] = memoryEntry{snapshot: snap.clone(), expiresAt: now.Add(s.ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok || time.Now().After(e.expiresAt) {
+		return Snapshot{}, ErrNotFound
+	}
+	return e.snapshot.clone(), nil
+}
+
+// clone copies the flight lists, which callers sort and filter in place,
+// so no two requests share them, as with snapshots decoded from Redis.
+func (s Snapshot) clone() Snapshot {
+	s.Flights = slices.Clone(s.Flights)
+	s.ReturnFlights = slices.Clone(s.ReturnFlights)
+	return s
+}
+
+const redisKeyPrefix = "snapshot:"
+
+type RedisStore struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+func NewRedisStore(client redis.UniversalClient, ttl time.Duration) *RedisStore {
+	return &RedisStore{client: client, ttl: ttl}
+}
+
+func (s *RedisStore) Save(ctx context.Context, snap Snapshot) error {
+	key := redisKeyPrefix + snap.ID
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	// Same ID means same contents: keep the stored copy, and with it the
+	// original creation time, and only extend it.
+	created, err := s.client.SetNX(ctx, key, data, s.ttl).Result()
+	if err != nil || created {
+		return err
+	}
+	return s.client.Expire(ctx, key, s.ttl).Err()
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (Snapshot, error) {
+	data, err := s.client.Get(ctx, redisKeyPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Snapshot{}, ErrNotFound
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return Snapshot{}, err
+	}
+	return snap, nil
+}
@@ -0,0 +1,66 @@
+package subscriptions
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+// Fanout carries events to every replica, since a subscription's stream
+// may be open on a different replica than the one that saw the fare change.
+type Fanout interface {
+	Publish(ctx context.Context, subscriptionID string, e models.FareEvent) error
+	Listen(ctx context.Context, fn func(subscriptionID string, e models.FareEvent))
+}
+
+const redisEventsChannel = "subscriptions:events"
+
+type fanoutMessage struct {
+	SubscriptionID string           `json:"subscription_id"`
+	Event          models.FareEvent `json:"event"`
+}
+
+type RedisFanout struct {
+	client redis.UniversalClient
+}
+
+func NewRedisFanout(client redis.UniversalClient) *RedisFanout {
+	return &RedisFanout{client: client}
+}
+
+func (f *RedisFanout) Publish(ctx context.Context, subscriptionID string, e models.FareEvent) error {
+	data, err := json.Marshal(fanoutMessage{SubscriptionID: subscriptionID, Event: e})
+	if err != nil {
+		return err
+	}
+	return f.client.Publish(ctx, redisEventsChannel, data).Err()
+}
+
+// Listen blocks until ctx is done. Pub/sub is fire-and-forget: events
+// published while this replica is disconnected are not replayed.
+func (f *RedisFanout) Listen(ctx context.Context, fn func(subscriptionID string, e models.FareEvent)) {
+	sub := f.client.Subscribe(ctx, redisEventsChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var m fanoutMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &m); err != nil {
+				log.Printf("Ignoring malformed fanout message: %v", err)
+				continue
+			}
+			fn(m.SubscriptionID, m.Event)
+		}
+	}
+}
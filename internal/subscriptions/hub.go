@@ -0,0 +1,263 @@
+package subscriptions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+type seenFlight struct {
+	Provider string  `json:"provider"`
+	Seats    int     `json:"seats"`
+	Price    float64 `json:"price"`
+}
+
+type routeState struct {
+	Lowest  float64               `json:"lowest"`
+	Flights map[string]seenFlight `json:"flights"`
+}
+
+// Hub turns aggregator runs into fare-change events for the subscriptions
+// covering the searched route, and fans them out to webhooks and open
+// streams.
+type Hub struct {
+	store   Store
+	states  RouteStates
+	fanout  Fanout
+	webhook *WebhookSender
+
+	mu      sync.Mutex
+	streams map[string]map[chan models.FareEvent]struct{}
+}
+
+func NewHub(store Store, webhook *WebhookSender) *Hub {
+	return &Hub{
+		store:   store,
+		states:  NewMemoryRouteStates(),
+		webhook: webhook,
+		streams: make(map[string]map[chan models.FareEvent]struct{}),
+	}
+}
+
+// SetRouteStates replaces the in-process route state, e.g. with Redis so
+// replicas diff against the same previous run and each event is raised once.
+func (h *Hub) SetRouteStates(states RouteStates) {
+	h.states = states
+}
+
+// SetFanout routes events through f so streams opened on any replica
+// receive them. Start must be running to deliver them locally.
+func (h *Hub) SetFanout(f Fanout) {
+	h.fanout = f
+}
+
+// Start relays fanned-out events to this replica's streams until ctx is
+// done. It returns immediately when no fanout is set.
+func (h *Hub) Start(ctx context.Context) {
+	if h.fanout == nil {
+		return
+	}
+	h.fanout.Listen(ctx, h.push)
+}
+
+func (h *Hub) ObserveSearch(ctx context.Context, req models.SearchRequest, result *aggregator.Result) {
+	if req.RestrictsProviders() {
+		return
+	}
+
+	route := models.SubscriptionRouteFor(req)
+	subs, err := h.subscribers(ctx, route)
+	if err != nil {
+		log.Printf("Failed to load subscriptions: %v", err)
+		return
+	}
+
+	if len(subs) == 0 {
+		if err := h.states.Delete(ctx, route.Key()); err != nil {
+			log.Printf("Failed to clear state for %s: %v", route.Key(), err)
+		}
+		return
+	}
+
+	var events []models.FareEvent
+	err = h.states.Update(ctx, route.Key(), func(prev *routeState) *routeState {
+		var next *routeState
+		next, events = h.diff(route, prev, result)
+		return next
+	})
+	if err != nil {
+		log.Printf("Failed to update state for %s: %v", route.Key(), err)
+		return
+	}
+
+	for _, e := range events {
+		for _, sub := range subs {
+			h.deliver(ctx, sub, e)
+		}
+	}
+}
+
+// Stream registers a listener for a subscription's events. The returned
+// function must be called to unregister it.
+func (h *Hub) Stream(subscriptionID string) (<-chan models.FareEvent, func()) {
+	ch := make(chan models.FareEvent, 16)
+
+	h.mu.Lock()
+	if h.streams[subscriptionID] == nil {
+		h.streams[subscriptionID] = make(map[chan models.FareEvent]struct{})
+	}
+	h.streams[subscriptionID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.streams[subscriptionID], ch)
+		if len(h.streams[subscriptionID]) == 0 {
+			delete(h.streams, subscriptionID)
+		}
+		h.mu.Unlock()
+	}
+}
+
+func (h *Hub) subscribers(ctx context.Context, route models.SubscriptionRoute) ([]models.Subscription, error) {
+	all, err := h.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []models.Subscription
+	for _, sub := range all {
+		if sub.Covers(route) {
+			result = append(result, sub)
+		}
+	}
+	return result, nil
+}
+
+// diff compares a run with the previous one for the route and returns the
+// state to keep. The first run only seeds the state. Flights missing from a
+// provider that failed this time are left alone rather than reported as
+// sold out.
+func (h *Hub) diff(route models.SubscriptionRoute, prev *routeState, result *aggregator.Result) (*routeState, []models.FareEvent) {
+	now := time.Now()
+	failed := make(map[string]bool, len(result.FailedProviders))
+	for _, p := range result.FailedProviders {
+		failed[p] = true
+	}
+
+	current := make(map[string]seenFlight, len(result.Flights))
+	var cheapest *models.Flight
+	for i, f := range result.Flights {
+		current[f.ID] = seenFlight{Provider: f.Provider, Seats: f.AvailableSeats, Price: f.Price.Amount}
+		if f.AvailableSeats > 0 && (cheapest == nil || f.Price.Amount < cheapest.Price.Amount) {
+			cheapest = &result.Flights[i]
+		}
+	}
+
+	state := &routeState{Flights: current}
+	if cheapest != nil {
+		state.Lowest = cheapest.Price.Amount
+	}
+	if prev == nil {
+		return state, nil
+	}
+
+	var events []models.FareEvent
+	event := func(t models.FareEventType, id string, f seenFlight) models.FareEvent {
+		return models.FareEvent{
+			ID:             newEventID(),
+			Type:           t,
+			Route:          route,
+			FlightID:       id,
+			Provider:       f.Provider,
+			Price:          f.Price,
+			AvailableSeats: f.Seats,
+			OccurredAt:     now,
+		}
+	}
+
+	if cheapest != nil && (prev.Lowest == 0 || cheapest.Price.Amount < prev.Lowest) {
+		e := event(models.FareEventNewLowest, cheapest.ID, current[cheapest.ID])
+		if prev.Lowest > 0 {
+			previous := prev.Lowest
+			e.PreviousPrice = &previous
+		}
+		events = append(events, e)
+	}
+
+	for id, before := range prev.Flights {
+		now, ok := current[id]
+		if !ok {
+			if failed[before.Provider] {
+				state.Flights[id] = before
+				continue
+			}
+			now = seenFlight{Provider: before.Provider, Price: before.Price}
+			state.Flights[id] = now
+		}
+		if before.Seats > 0 && now.Seats == 0 {
+			events = append(events, event(models.FareEventSoldOut, id, now))
+		}
+		if before.Seats == 0 && now.Seats > 0 {
+			events = append(events, event(models.FareEventBackInStock, id, now))
+		}
+	}
+
+	return state, events
+}
+
+func (h *Hub) deliver(ctx context.Context, sub models.Subscription, e models.FareEvent) {
+	if h.fanout == nil {
+		h.push(sub.ID, e)
+	} else if err := h.fanout.Publish(ctx, sub.ID, e); err != nil {
+		log.Printf("Failed to fan out %s event for subscription %s: %v", e.Type, sub.ID, err)
+		h.push(sub.ID, e)
+	}
+
+	if sub.WebhookURL != "" && h.webhook != nil {
+		go func() {
+			if err := h.webhook.Send(context.Background(), sub, e); err != nil {
+				log.Printf("Subscription %s webhook failed: %v", sub.ID, err)
+			}
+		}()
+	}
+}
+
+// push hands an event to the streams open on this replica.
+func (h *Hub) push(subscriptionID string, e models.FareEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.streams[subscriptionID] {
+		select {
+		case ch <- e:
+		default:
+			log.Printf("Dropping %s event for slow stream on subscription %s", e.Type, subscriptionID)
+		}
+	}
+}
+
+func NewSubscription(req models.CreateSubscriptionRequest) models.Subscription {
+	return models.Subscription{
+		ID:         "sub_" + randomHex(),
+		Partner:    req.Partner,
+		Routes:     req.Routes,
+		WebhookURL: req.WebhookURL,
+		CreatedAt:  time.Now(),
+	}
+}
+
+func newEventID() string {
+	return "evt_" + randomHex()
+}
+
+func randomHex() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
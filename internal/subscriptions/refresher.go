@@ -0,0 +1,56 @@
+package subscriptions
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/dharmasatrya/flightsearch/internal/aggregator"
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+type Searcher interface {
+	Search(ctx context.Context, req models.SearchRequest) (*aggregator.Result, error)
+}
+
+// Refresher re-searches every subscribed route, run as a scheduled job so
+// events keep flowing even when no user is searching the route. The searches go
+// through the aggregator, which notifies the Hub like any other run.
+type Refresher struct {
+	store    Store
+	searcher Searcher
+}
+
+func NewRefresher(store Store, searcher Searcher) *Refresher {
+	return &Refresher{
+		store:    store,
+		searcher: searcher,
+	}
+}
+
+func (r *Refresher) RefreshAll(ctx context.Context) error {
+	subs, err := r.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list subscriptions: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	failed := 0
+	for _, sub := range subs {
+		for _, route := range sub.Routes {
+			if seen[route.Key()] {
+				continue
+			}
+			seen[route.Key()] = true
+
+			if _, err := r.searcher.Search(ctx, route.SearchRequest()); err != nil {
+				log.Printf("Subscription refresh for %s failed: %v", route.Key(), err)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d route refreshes failed", failed, len(seen))
+	}
+	return nil
+}
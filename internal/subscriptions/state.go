@@ -0,0 +1,97 @@
+package subscriptions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RouteStates keeps the last seen fares per route between runs. Update
+// applies fn atomically per route; fn receives nil for a route that has no
+// state yet and may be called more than once.
+type RouteStates interface {
+	Update(ctx context.Context, key string, fn func(prev *routeState) *routeState) error
+	Delete(ctx context.Context, key string) error
+}
+
+type MemoryRouteStates struct {
+	mu     sync.Mutex
+	routes map[string]*routeState
+}
+
+func NewMemoryRouteStates() *MemoryRouteStates {
+	return &MemoryRouteStates{routes: make(map[string]*routeState)}
+}
+
+func (s *MemoryRouteStates) Update(ctx context.Context, key string, fn func(prev *routeState) *routeState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[key] = fn(s.routes[key])
+	return nil
+}
+
+func (s *MemoryRouteStates) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.routes, key)
+	return nil
+}
+
+const (
+	redisRouteStatePrefix = "subscriptions:state:"
+	// Routes nobody searched for a day are re-seeded rather than diffed.
+	redisRouteStateTTL = 24 * time.Hour
+	maxUpdateAttempts  = 10
+)
+
+type RedisRouteStates struct {
+	client redis.UniversalClient
+}
+
+func NewRedisRouteStates(client redis.UniversalClient) *RedisRouteStates {
+	return &RedisRouteStates{client: client}
+}
+
+func (s *RedisRouteStates) Update(ctx context.Context, key string, fn func(prev *routeState) *routeState) error {
+	redisKey := redisRouteStatePrefix + key
+
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+			var prev *routeState
+			data, err := tx.Get(ctx, redisKey).Bytes()
+			switch {
+			case errors.Is(err, redis.Nil):
+			case err != nil:
+				return err
+			default:
+				prev = &routeState{}
+				if err := json.Unmarshal(data, prev); err != nil {
+					return err
+				}
+			}
+
+			next, err := json.Marshal(fn(prev))
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, redisKey, next, redisRouteStateTTL)
+				return nil
+			})
+			return err
+		}, redisKey)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("route state for %s: too much contention", key)
+}
+
+func (s *RedisRouteStates) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, redisRouteStatePrefix+key).Err()
+}
@@ -0,0 +1,141 @@
+package subscriptions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+var ErrNotFound = errorsx.New(errorsx.NotFound, "subscription not found")
+
+type Store interface {
+	Save(ctx context.Context, sub models.Subscription) error
+	Get(ctx context.Context, id string) (models.Subscription, error)
+	List(ctx context.Context) ([]models.Subscription, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type MemoryStore struct {
+	mu   sync.RWMutex
+	subs map[string]models.Subscription
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{subs: make(map[string]models.Subscription)}
+}
+
+func (s *MemoryStore) Save(ctx context.Context, sub models.Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[sub.ID] = sub
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (models.Subscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sub, ok := s.subs[id]
+	if !ok {
+		return models.Subscription{}, ErrNotFound
+	}
+	return sub, nil
+}
+
+func (s *MemoryStore) List(ctx context.Context) ([]models.Subscription, error) {
+	s.mu.RLock()
+	result := make([]models.Subscription, 0, len(s.subs))
+	for _, sub := range s.subs {
+		result = append(result, sub)
+	}
+	s.mu.RUnlock()
+
+	sortByCreated(result)
+	return result, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.subs, id)
+	return nil
+}
+
+const redisSubscriptionsKey = "subscriptions"
+
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Save(ctx context.Context, sub models.Subscription) error {
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, redisSubscriptionsKey, sub.ID, data).Err()
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (models.Subscription, error) {
+	data, err := s.client.HGet(ctx, redisSubscriptionsKey, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return models.Subscription{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	var sub models.Subscription
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return models.Subscription{}, err
+	}
+	return sub, nil
+}
+
+func (s *RedisStore) List(ctx context.Context) ([]models.Subscription, error) {
+	values, err := s.client.HGetAll(ctx, redisSubscriptionsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.Subscription, 0, len(values))
+	for _, v := range values {
+		var sub models.Subscription
+		if err := json.Unmarshal([]byte(v), &sub); err != nil {
+			continue
+		}
+		result = append(result, sub)
+	}
+
+	sortByCreated(result)
+	return result, nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	n, err := s.client.HDel(ctx, redisSubscriptionsKey, id).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func sortByCreated(subs []models.Subscription) {
+	sort.Slice(subs, func(i, j int) bool {
+		return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+	})
+}
@@ -0,0 +1,50 @@
+package subscriptions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+)
+
+type webhookPayload struct {
+	SubscriptionID string           `json:"subscription_id"`
+	Partner        string           `json:"partner"`
+	Event          models.FareEvent `json:"event"`
+}
+
+type WebhookSender struct {
+	client *http.Client
+}
+
+func NewWebhookSender() *WebhookSender {
+	return &WebhookSender{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (w *WebhookSender) Send(ctx context.Context, sub models.Subscription, e models.FareEvent) error {
+	body, err := json.Marshal(webhookPayload{SubscriptionID: sub.ID, Partner: sub.Partner, Event: e})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"runtime/metrics"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+const gcPausesMetric = "/sched/pauses/total/gc:seconds"
+
+var startTime = time.Now()
+
+// gcPauseProducer reports the runtime's stop-the-world GC pause histogram,
+// which the runtime instrumentation leaves out.
+type gcPauseProducer struct{}
+
+func (gcPauseProducer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	sample := []metrics.Sample{{Name: gcPausesMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindFloat64Histogram {
+		return nil, errors.New("runtime does not report " + gcPausesMetric)
+	}
+	hist := sample[0].Value.Float64Histogram()
+
+	// Runtime buckets are [lower, upper); OTel bounds are upper bounds
+	// only, with +Inf implicit.
+	bounds := hist.Buckets[1:]
+	counts := hist.Counts
+	if math.IsInf(bounds[len(bounds)-1], 1) {
+		bounds = bounds[:len(bounds)-1]
+	} else {
+		counts = append(counts, 0)
+	}
+	var count uint64
+	var sum float64
+	for i, c := range counts {
+		count += c
+		// Observations count at their bucket's lower bound, so the sum
+		// is a slight underestimate.
+		if i > 0 {
+			sum += bounds[i-1] * float64(c)
+		}
+	}
+
+	return []metricdata.ScopeMetrics{{
+		Scope: instrumentation.Scope{Name: scopeName},
+		Metrics: []metricdata.Metrics{{
+			Name:        "go.gc.pause.duration",
+			Description: "Stop-the-world pauses for garbage collection.",
+			Unit:        "s",
+			Data: metricdata.Histogram[float64]{
+				Temporality: metricdata.CumulativeTemporality,
+				DataPoints: []metricdata.HistogramDataPoint[float64]{{
+					StartTime:    startTime,
+					Time:         time.Now(),
+					Count:        count,
+					Sum:          sum,
+					Bounds:       bounds,
+					BucketCounts: counts,
+				}},
+			},
+		}},
+	}}, nil
+}
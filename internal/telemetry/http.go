@@ -0,0 +1,116 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// HTTPServer instruments the Echo server: request duration and requests
+// in flight per route, and open connections per state.
+type HTTPServer struct {
+	duration    metric.Float64Histogram
+	active      metric.Int64UpDownCounter
+	connections metric.Int64UpDownCounter
+
+	mu    sync.Mutex
+	conns map[net.Conn]http.ConnState
+}
+
+func (t *Telemetry) HTTPServer() (*HTTPServer, error) {
+	duration, err := t.meter.Float64Histogram("http.server.request.duration",
+		metric.WithDescription("Duration of HTTP server requests."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10),
+	)
+	if err != nil {
+		return nil, err
+	}
+	active, err := t.meter.Int64UpDownCounter("http.server.active_requests",
+		metric.WithDescription("Number of HTTP server requests in flight."),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	connections, err := t.meter.Int64UpDownCounter("http.server.open_connections",
+		metric.WithDescription("Number of open HTTP server connections by state."),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPServer{
+		duration:    duration,
+		active:      active,
+		connections: connections,
+		conns:       make(map[net.Conn]http.ConnState),
+	}, nil
+}
+
+// Middleware records every request under its route pattern, so path
+// parameters don't multiply the series.
+func (s *HTTPServer) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			method := attribute.String("http.request.method", c.Request().Method)
+			s.active.Add(c.Request().Context(), 1, metric.WithAttributes(method))
+			start := time.Now()
+
+			err := next(c)
+
+			ctx := c.Request().Context()
+			s.active.Add(ctx, -1, metric.WithAttributes(method))
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			s.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+				method,
+				attribute.String("http.route", route),
+				attribute.Int("http.response.status_code", responseStatus(c, err)),
+			))
+			return err
+		}
+	}
+}
+
+// responseStatus is the status the error handler will send for err, which
+// it hasn't written yet when the middleware sees it.
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
+
+// ConnState tracks connections; set it as the server's ConnState hook.
+func (s *HTTPServer) ConnState(conn net.Conn, state http.ConnState) {
+	s.mu.Lock()
+	prev, known := s.conns[conn]
+	if state == http.StateClosed || state == http.StateHijacked {
+		delete(s.conns, conn)
+	} else {
+		s.conns[conn] = state
+	}
+	s.mu.Unlock()
+
+	ctx := context.Background()
+	if known {
+		s.connections.Add(ctx, -1, metric.WithAttributes(attribute.String("http.connection.state", prev.String())))
+	}
+	if state != http.StateClosed && state != http.StateHijacked {
+		s.connections.Add(ctx, 1, metric.WithAttributes(attribute.String("http.connection.state", state.String())))
+	}
+}
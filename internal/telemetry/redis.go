@@ -0,0 +1,87 @@
+package telemetry
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ObserveRedisPool reports the connection pool of client, named pool
+// (e.g. "primary", or the region of a cache replica).
+func (t *Telemetry) ObserveRedisPool(pool string, client redis.UniversalClient) error {
+	usage, err := t.meter.Int64ObservableUpDownCounter("db.client.connection.count",
+		metric.WithDescription("Connections in the Redis pool by state."),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return err
+	}
+	limit, err := t.meter.Int64ObservableUpDownCounter("db.client.connection.max",
+		metric.WithDescription("Most connections the Redis pool opens."),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return err
+	}
+	waits, err := t.meter.Int64ObservableCounter("db.client.connection.waits",
+		metric.WithDescription("Times a command waited for a free pool connection."),
+		metric.WithUnit("{wait}"),
+	)
+	if err != nil {
+		return err
+	}
+	waitTime, err := t.meter.Float64ObservableCounter("db.client.connection.wait_time",
+		metric.WithDescription("Total time commands waited for a free pool connection."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return err
+	}
+	timeouts, err := t.meter.Int64ObservableCounter("db.client.connection.timeouts",
+		metric.WithDescription("Times a command timed out waiting for a pool connection."),
+		metric.WithUnit("{timeout}"),
+	)
+	if err != nil {
+		return err
+	}
+	misses, err := t.meter.Int64ObservableCounter("db.client.connection.misses",
+		metric.WithDescription("Times no idle connection was free and a new one was dialed or waited for."),
+		metric.WithUnit("{miss}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	name := attribute.String("db.client.connection.pool.name", pool)
+	idle := metric.WithAttributes(name, attribute.String("db.client.connection.state", "idle"))
+	used := metric.WithAttributes(name, attribute.String("db.client.connection.state", "used"))
+	attrs := metric.WithAttributes(name)
+	poolSize := int64(maxConns(client))
+
+	_, err = t.meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		s := client.PoolStats()
+		o.ObserveInt64(usage, int64(s.IdleConns), idle)
+		o.ObserveInt64(usage, int64(s.TotalConns)-int64(s.IdleConns), used)
+		o.ObserveInt64(limit, poolSize, attrs)
+		o.ObserveInt64(waits, int64(s.WaitCount), attrs)
+		o.ObserveFloat64(waitTime, float64(s.WaitDurationNs)/1e9, attrs)
+		o.ObserveInt64(timeouts, int64(s.Timeouts), attrs)
+		o.ObserveInt64(misses, int64(s.Misses), attrs)
+		return nil
+	}, usage, limit, waits, waitTime, timeouts, misses)
+	return err
+}
+
+// maxConns is the pool size of client, per node for a cluster, whose pool
+// stats add up every node's.
+func maxConns(client redis.UniversalClient) int {
+	switch c := client.(type) {
+	case *redis.Client:
+		return c.Options().PoolSize
+	case *redis.ClusterClient:
+		return c.Options().PoolSize
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+// Package telemetry exports OpenTelemetry metrics over OTLP/HTTP: Go
+// runtime, HTTP server and Redis connection pool metrics, so capacity
+// planning works from real numbers.
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/runtime"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const scopeName = "github.com/dharmasatrya/flightsearch/internal/telemetry"
+
+type Config struct {
+	// Endpoint is the collector's OTLP/HTTP metrics URL, e.g.
+	// http://otel-collector:4318/v1/metrics.
+	Endpoint string
+	Interval time.Duration
+	Service  string
+	Region   string
+}
+
+type Telemetry struct {
+	provider *sdkmetric.MeterProvider
+	meter    metric.Meter
+}
+
+// New starts exporting every Interval, beginning with the Go runtime
+// metrics; HTTP and Redis metrics are added by their instrumentation.
+func New(ctx context.Context, cfg Config) (*Telemetry, error) {
+	exporter, err := otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("service.name", cfg.Service)}
+	if cfg.Region != "" {
+		attrs = append(attrs, attribute.String("cloud.region", cfg.Region))
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attrs...))
+	if err != nil {
+		return nil, err
+	}
+
+	reader := sdkmetric.NewPeriodicReader(exporter,
+		sdkmetric.WithInterval(cfg.Interval),
+		sdkmetric.WithProducer(runtime.NewProducer()),
+		sdkmetric.WithProducer(gcPauseProducer{}),
+	)
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithResource(res))
+	if err := runtime.Start(runtime.WithMeterProvider(provider)); err != nil {
+		return nil, err
+	}
+
+	return &Telemetry{provider: provider, meter: provider.Meter(scopeName)}, nil
+}
+
+// Shutdown exports what was recorded since the last export and stops.
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	return t.provider.Shutdown(ctx)
+}
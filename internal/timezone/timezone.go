@@ -1,8 +1,15 @@
 package timezone
 
 import (
+	"errors"
+	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
+	// Imported airports name IANA zones, which minimal images don't ship.
+	_ "time/tzdata"
+
+	"github.com/dharmasatrya/flightsearch/internal/airports"
 )
 
 var (
@@ -15,81 +22,113 @@ func init() {
 	WIB = time.FixedZone("WIB", 7*60*60)
 	WITA = time.FixedZone("WITA", 8*60*60)
 	WIT = time.FixedZone("WIT", 9*60*60)
+	tzs := make(map[string]string)
+	for code, a := range airports.Builtin() {
+		tzs[code] = a.Timezone
+	}
+	if err := SetAirports(tzs); err != nil {
+		panic(err)
+	}
 }
 
-var airportTimezones = map[string]string{
-	// WIB (UTC+7) - Western Indonesia
-	"CGK": "WIB", // Jakarta - Soekarno-Hatta
-	"HLP": "WIB", // Jakarta - Halim Perdanakusuma
-	"BDO": "WIB", // Bandung - Husein Sastranegara
-	"SUB": "WIB", // Surabaya - Juanda
-	"SRG": "WIB", // Semarang - Ahmad Yani
-	"JOG": "WIB", // Yogyakarta - Adisucipto
-	"SOC": "WIB", // Solo - Adisumarmo
-	"PLM": "WIB", // Palembang - Sultan Mahmud Badaruddin II
-	"PNK": "WIB", // Pontianak - Supadio
-	"BTH": "WIB", // Batam - Hang Nadim
-	"PKU": "WIB", // Pekanbaru - Sultan Syarif Kasim II
-	"PDG": "WIB", // Padang - Minangkabau
-	"KNO": "WIB", // Medan - Kualanamu
-	"BTJ": "WIB", // Banda Aceh - Sultan Iskandar Muda
-	"TNJ": "WIB", // Tanjung Pinang - Raja Haji Fisabilillah
-
-	// WITA (UTC+8) - Central Indonesia
-	"DPS": "WITA", // Bali - Ngurah Rai
-	"LOP": "WITA", // Lombok - Lombok International
-	"UPG": "WITA", // Makassar - Sultan Hasanuddin
-	"BPN": "WITA", // Balikpapan - Sultan Aji Muhammad Sulaiman
-	"MDC": "WITA", // Manado - Sam Ratulangi
-	"KDI": "WITA", // Kendari - Haluoleo
-	"PLW": "WITA", // Palu - Mutiara SIS Al-Jufri
-	"TRK": "WITA", // Tarakan - Juwata
-
-	// WIT (UTC+9) - Eastern Indonesia
-	"DJJ": "WIT", // Jayapura - Sentani
-	"TIM": "WIT", // Timika - Mozes Kilangin
-	"BIK": "WIT", // Biak - Frans Kaisiepo
-	"MKQ": "WIT", // Merauke - Mopah
-	"SOQ": "WIT", // Sorong - Domine Eduard Osok
-	"AMQ": "WIT", // Ambon - Pattimura
+// ErrUnknownAirport is returned for airports without a known timezone.
+// Their local times can't be told, so callers must not guess one.
+var ErrUnknownAirport = errors.New("unknown airport")
+
+// zone is an airport's timezone label and location.
+type zone struct {
+	label string
+	loc   *time.Location
 }
 
-func GetTimezoneByAirport(code string) string {
-	code = strings.ToUpper(code)
-	if tz, ok := airportTimezones[code]; ok {
-		return tz
+var zones atomic.Pointer[map[string]zone]
+
+// SetAirports replaces the known airports with tzs, which maps IATA codes
+// to IANA zone names, or WIB, WITA or WIT. Indonesian airports keep those
+// labels and fixed offsets. The table is swapped at once, so
+// a search sees either the old airports or the new ones.
+func SetAirports(tzs map[string]string) error {
+	table := make(map[string]zone, len(tzs))
+	for code, name := range tzs {
+		label := Label(name)
+		loc := fixedZone(label)
+		if loc == nil {
+			var err error
+			if loc, err = time.LoadLocation(name); err != nil {
+				return fmt.Errorf("airport %s: %w", code, err)
+			}
+		}
+		table[strings.ToUpper(code)] = zone{label: label, loc: loc}
 	}
-	return "WIB"
+	zones.Store(&table)
+	return nil
 }
 
-func GetLocationByAirport(code string) *time.Location {
-	tz := GetTimezoneByAirport(code)
-	switch tz {
+// Label is the timezone label flights report for an IANA zone: WIB, WITA
+// or WIT in Indonesia, the zone name elsewhere.
+func Label(name string) string {
+	switch name {
+	case "Asia/Jakarta", "Asia/Pontianak":
+		return "WIB"
+	case "Asia/Makassar":
+		return "WITA"
+	case "Asia/Jayapura":
+		return "WIT"
+	}
+	return name
+}
+
+func fixedZone(label string) *time.Location {
+	switch label {
+	case "WIB":
+		return WIB
 	case "WITA":
 		return WITA
 	case "WIT":
 		return WIT
-	default:
-		return WIB
 	}
+	return nil
 }
 
-func GetLocationByName(name string) *time.Location {
+// GetTimezoneByAirport is the timezone label flights report for code, or
+// "" for an unknown airport.
+func GetTimezoneByAirport(code string) string {
+	if z, ok := (*zones.Load())[strings.ToUpper(code)]; ok {
+		return z.label
+	}
+	return ""
+}
+
+// LocationByAirport is code's timezone. Unknown airports return an error
+// wrapping ErrUnknownAirport.
+func LocationByAirport(code string) (*time.Location, error) {
+	if z, ok := (*zones.Load())[strings.ToUpper(code)]; ok {
+		return z.loc, nil
+	}
+	return nil, fmt.Errorf("%w %s", ErrUnknownAirport, strings.ToUpper(code))
+}
+
+// LocationByName reads WIB, WITA, WIT, their UTC+7, UTC+8 and UTC+9
+// aliases, or an IANA zone name.
+func LocationByName(name string) (*time.Location, error) {
 	switch strings.ToUpper(name) {
 	case "WITA", "UTC+8":
-		return WITA
+		return WITA, nil
 	case "WIT", "UTC+9":
-		return WIT
+		return WIT, nil
 	case "WIB", "UTC+7":
-		return WIB
-	default:
-		if loc, err := time.LoadLocation(name); err == nil {
-			return loc
-		}
-		return WIB
+		return WIB, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
 	}
+	return loc, nil
 }
 
+// ParseTimeWithOffset reads a timestamp with a UTC offset, or without one
+// as local time in tzName. Without a tzName, times without an offset are
+// read as UTC.
 func ParseTimeWithOffset(timeStr string, tzName string) (time.Time, error) {
 	formats := []string{
 		time.RFC3339,
@@ -98,8 +137,9 @@ func ParseTimeWithOffset(timeStr string, tzName string) (time.Time, error) {
 		"2006-01-02T15:04:05-0700", // Without colon
 		"2006-01-02T15:04:05+0700", // Without colon
 		"2006-01-02T15:04:05Z",
-		"2006-01-02T15:04:05",
-		"2006-01-02 15:04:05",
+	}
+	if tzName == "" {
+		formats = append(formats, "2006-01-02T15:04:05", "2006-01-02 15:04:05")
 	}
 
 	for _, format := range formats {
@@ -109,7 +149,10 @@ func ParseTimeWithOffset(timeStr string, tzName string) (time.Time, error) {
 	}
 
 	if tzName != "" {
-		loc := GetLocationByName(tzName)
+		loc, err := LocationByName(tzName)
+		if err != nil {
+			return time.Time{}, err
+		}
 		simpleFormats := []string{
 			"2006-01-02T15:04:05",
 			"2006-01-02 15:04:05",
@@ -129,7 +172,20 @@ func ParseTimeWithOffset(timeStr string, tzName string) (time.Time, error) {
 	}
 }
 
+// ConvertToTimezone is t in airportCode's timezone. t is returned as is
+// for an unknown airport, keeping whatever offset it was parsed with.
 func ConvertToTimezone(t time.Time, airportCode string) time.Time {
-	loc := GetLocationByAirport(airportCode)
+	loc, err := LocationByAirport(airportCode)
+	if err != nil {
+		return t
+	}
 	return t.In(loc)
 }
+
+// LocalDate is the calendar day, as "2006-01-02", that t falls on at
+// airportCode: the day travellers search by, which a time in UTC or
+// another airport's offset can put on the day before or after. For an
+// unknown airport it is the day in t's own offset.
+func LocalDate(t time.Time, airportCode string) string {
+	return ConvertToTimezone(t, airportCode).Format(time.DateOnly)
+}
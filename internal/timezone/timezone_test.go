@@ -0,0 +1,38 @@
+package timezone
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocalDate(t *testing.T) {
+	tests := []struct {
+		name    string
+		time    string
+		airport string
+		want    string
+	}{
+		{"late WIB departure is a day behind in UTC", "2025-12-15T16:30:00Z", "CGK", "2025-12-15"},
+		{"late WIB departure given in WIB", "2025-12-15T23:30:00+07:00", "CGK", "2025-12-15"},
+		{"early WIB departure is the day before in UTC", "2025-12-14T18:30:00Z", "CGK", "2025-12-15"},
+		{"WITA just after midnight", "2025-12-14T16:05:00Z", "DPS", "2025-12-15"},
+		{"WITA just after midnight given in WIB", "2025-12-14T23:05:00+07:00", "UPG", "2025-12-15"},
+		{"WIT just after midnight", "2025-12-14T15:10:00Z", "DJJ", "2025-12-15"},
+		{"WIT just before midnight", "2025-12-15T14:50:00Z", "DJJ", "2025-12-15"},
+		{"lowercase airport code", "2025-12-14T16:05:00Z", "dps", "2025-12-15"},
+		{"unknown airport keeps UTC", "2025-12-14T16:05:00Z", "XXX", "2025-12-14"},
+		{"unknown airport keeps the parsed offset", "2025-12-15T00:05:00+08:00", "XXX", "2025-12-15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, err := time.Parse(time.RFC3339, tt.time)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := LocalDate(at, tt.airport); got != tt.want {
+				t.Errorf("LocalDate(%s, %s) = %s, want %s", tt.time, tt.airport, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Package vcr records what providers answer to disk and replays it later,
+// so regression checks can run against real provider responses and
+// normalization problems can be debugged offline.
+package vcr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dharmasatrya/flightsearch/internal/models"
+	"github.com/dharmasatrya/flightsearch/internal/providers"
+	"github.com/dharmasatrya/flightsearch/pkg/errorsx"
+)
+
+const (
+	ModeOff = "off"
+	// ModeRecord calls the provider and saves every answer it gives.
+	ModeRecord = "record"
+	// ModeReplay answers from the saved responses without calling the
+	// provider.
+	ModeReplay = "replay"
+)
+
+var Modes = []string{ModeOff, ModeRecord, ModeReplay}
+
+// ErrNoCassette is what replaying a call that was never recorded returns.
+// It isn't retried: replaying it again gives the same answer.
+var ErrNoCassette = errorsx.New(errorsx.NotFound, "no recorded response")
+
+// Cassette is one recorded answer.
+type Cassette struct {
+	Provider      string          `json:"provider"`
+	Origin        string          `json:"origin"`
+	Destination   string          `json:"destination"`
+	DepartureDate string          `json:"departure_date"`
+	CabinClass    string          `json:"cabin_class"`
+	Passengers    int             `json:"passengers"`
+	RecordedAt    time.Time       `json:"recorded_at"`
+	LatencyMs     int64           `json:"latency_ms"`
+	Flights       []models.Flight `json:"flights"`
+}
+
+// Provider records the answers of the provider it wraps, or replays them.
+// Only successful answers are recorded, so a flaky provider doesn't leave
+// behind a failure that replays every time.
+type Provider struct {
+	providers.Provider
+	mode string
+	dir  string
+}
+
+// lockingProvider keeps a wrapped FareLocker locking fares natively. Fare
+// locks are never recorded.
+type lockingProvider struct {
+	*Provider
+	providers.FareLocker
+}
+
+// Wrap records p's answers under dir, or replays them, per mode. ModeOff
+// returns p itself.
+func Wrap(p providers.Provider, mode, dir string) providers.Provider {
+	if mode == ModeOff {
+		return p
+	}
+	v := &Provider{Provider: p, mode: mode, dir: dir}
+	if locker, ok := p.(providers.FareLocker); ok {
+		return &lockingProvider{Provider: v, FareLocker: locker}
+	}
+	return v
+}
+
+// Capabilities are the wrapped provider's, if it describes any.
+func (p *Provider) Capabilities() providers.Capabilities {
+	if d, ok := p.Provider.(providers.Describer); ok {
+		return d.Capabilities()
+	}
+	return providers.Capabilities{}
+}
+
+func (p *Provider) Search(ctx context.Context, req models.SearchRequest) ([]models.Flight, error) {
+	path := p.path(req)
+	if p.mode == ModeReplay {
+		c, err := load(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errorsx.Errorf(errorsx.NotFound, "%s %s-%s on %s: %w", p.Name(), req.Origin, req.Destination, req.DepartureDate, ErrNoCassette)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return c.Flights, nil
+	}
+
+	start := time.Now()
+	flights, err := p.Provider.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	c := Cassette{
+		Provider:      p.Name(),
+		Origin:        req.Origin,
+		Destination:   req.Destination,
+		DepartureDate: req.DepartureDate,
+		CabinClass:    req.CabinClass,
+		Passengers:    req.Passengers,
+		RecordedAt:    start,
+		LatencyMs:     time.Since(start).Milliseconds(),
+		Flights:       flights,
+	}
+	// A search must not fail because its answer couldn't be recorded.
+	if err := save(path, c); err != nil {
+		log.Printf("Failed to record %s response to %s: %v", p.Name(), path, err)
+	}
+	return flights, nil
+}
+
+// path is where the answer to req is kept: one directory per provider,
+// one file per route, date, cabin and passenger count.
+func (p *Provider) path(req models.SearchRequest) string {
+	name := fmt.Sprintf("%s-%s_%s_%s_%d.json",
+		clean(strings.ToUpper(req.Origin)), clean(strings.ToUpper(req.Destination)),
+		clean(req.DepartureDate), clean(strings.ToLower(req.CabinClass)), req.Passengers)
+	return filepath.Join(p.dir, clean(p.Name()), name)
+}
+
+// clean keeps request values from escaping the cassette directory.
+func clean(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func load(path string) (Cassette, error) {
+	var c Cassette
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// save writes through a temporary file, so concurrent searches recording
+// the same call never leave a half-written cassette.
+func save(path string, c Cassette) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cassette-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -5,7 +5,27 @@ import (
 	"math"
 )
 
+// Format formats amount in code: IDR as FormatIDR does, other currencies
+// with their code and two decimals, e.g. "USD 150.50".
+func Format(amount float64, code string) string {
+	if code == "IDR" {
+		return FormatIDR(amount)
+	}
+	return fmt.Sprintf("%s %.2f", code, amount)
+}
+
 func FormatIDR(amount float64) string {
+	return FormatIDRFor(amount, "id")
+}
+
+// FormatIDRFor formats amount with the thousands separator of locale:
+// "," for English, "." otherwise.
+func FormatIDRFor(amount float64, locale string) string {
+	sep := "."
+	if locale == "en" {
+		sep = ","
+	}
+
 	rounded := math.Round(amount)
 
 	negative := rounded < 0
@@ -14,7 +34,7 @@ func FormatIDR(amount float64) string {
 	}
 
 	intStr := fmt.Sprintf("%.0f", rounded)
-	formatted := addThousandsSeparator(intStr, ".")
+	formatted := addThousandsSeparator(intStr, sep)
 
 	result := "IDR " + formatted
 	if negative {
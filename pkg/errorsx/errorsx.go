@@ -0,0 +1,149 @@
+// Package errorsx classifies errors by kind, so a failure is reported with
+// the same HTTP status wherever it surfaces.
+package errorsx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// Kind is what went wrong, independent of where. A Kind is an error
+// itself, so errors.Is(err, errorsx.NotFound) tests an error's kind.
+type Kind uint8
+
+const (
+	// Internal is the kind of errors nobody classified.
+	Internal Kind = iota
+	// Invalid requests are malformed or fail validation.
+	Invalid
+	// Unprocessable requests are valid but can't be honoured, e.g. a hold
+	// longer than the provider allows.
+	Unprocessable
+	Unauthorized
+	NotFound
+	// Expired resources existed but are gone.
+	Expired
+	RateLimited
+	// Unavailable is a failed call to a provider or supplier.
+	Unavailable
+	// Overloaded is the service shedding load.
+	Overloaded
+	// Conflict requests clash with another still in progress.
+	Conflict
+	// Timeout is a call to a provider or supplier that didn't answer in
+	// time.
+	Timeout
+	// TooLarge requests exceed a size limit.
+	TooLarge
+)
+
+var kindNames = [...]string{"internal", "invalid", "unprocessable", "unauthorized", "not_found", "expired", "rate_limited", "unavailable", "overloaded", "conflict", "timeout", "too_large"}
+
+func (k Kind) Error() string {
+	return kindNames[k]
+}
+
+// Error is a classified error. Message describes the failure, Err is its
+// cause; either may be empty.
+type Error struct {
+	Kind Kind
+	// Code overrides the kind's error code in responses.
+	Code    types.ErrorCode
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Message
+	case e.Message == "":
+		return e.Err.Error()
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	k, ok := target.(Kind)
+	return ok && k == e.Kind
+}
+
+func (e *Error) ErrorKind() Kind {
+	return e.Kind
+}
+
+// kinded is implemented by errors that know their kind without wrapping.
+type kinded interface {
+	error
+	ErrorKind() Kind
+}
+
+func New(kind Kind, message string) error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Errorf formats like fmt.Errorf, so %w wraps a cause.
+func Errorf(kind Kind, format string, args ...any) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap adds message to err, keeping its kind. It returns nil for a nil
+// err.
+func Wrap(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: KindOf(err), Message: message, Err: err}
+}
+
+// Classify gives err kind unless it already has one, for errors crossing
+// a boundary, e.g. everything a provider returns is Unavailable unless the
+// provider said otherwise. It returns nil for a nil err.
+func Classify(err error, kind Kind) error {
+	if err == nil || KindOf(err) != Internal {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf is the kind of the outermost classified error in err's chain,
+// Internal when there is none.
+func KindOf(err error) Kind {
+	var k kinded
+	if errors.As(err, &k) {
+		return k.ErrorKind()
+	}
+	return Internal
+}
+
+// Retryable reports whether repeating a failed call may succeed.
+// Cancellation and deadlines belong to the caller and are never retried;
+// unclassified errors are assumed to be transient.
+func Retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	switch KindOf(err) {
+	case Internal, Unavailable, RateLimited, Overloaded:
+		return true
+	}
+	return false
+}
+
+// code is the first error code set in err's chain.
+func code(err error) types.ErrorCode {
+	for err != nil {
+		if e, ok := err.(*Error); ok && e.Code != "" {
+			return e.Code
+		}
+		err = errors.Unwrap(err)
+	}
+	return ""
+}
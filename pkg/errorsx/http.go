@@ -0,0 +1,53 @@
+package errorsx
+
+import (
+	"net/http"
+
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+type mapping struct {
+	status int
+	code   types.ErrorCode
+}
+
+// statuses is how every handler reports each kind. Kinds without a code
+// take the handler's, which names the operation that failed, e.g.
+// "lock_error".
+var statuses = map[Kind]mapping{
+	Internal:      {http.StatusInternalServerError, ""},
+	Invalid:       {http.StatusBadRequest, types.CodeValidationError},
+	Unprocessable: {http.StatusUnprocessableEntity, types.CodeValidationError},
+	Unauthorized:  {http.StatusUnauthorized, types.CodeUnauthorized},
+	NotFound:      {http.StatusNotFound, types.CodeNotFound},
+	Expired:       {http.StatusGone, types.CodeExpired},
+	RateLimited:   {http.StatusTooManyRequests, types.CodeRateLimited},
+	Unavailable:   {http.StatusBadGateway, ""},
+	Overloaded:    {http.StatusServiceUnavailable, types.CodeOverloaded},
+	Conflict:      {http.StatusConflict, types.CodeConflict},
+	Timeout:       {http.StatusGatewayTimeout, types.CodeTimeout},
+	TooLarge:      {http.StatusRequestEntityTooLarge, types.CodeTooLarge},
+}
+
+// HTTPStatus is the status err is reported with.
+func HTTPStatus(err error) int {
+	return statuses[KindOf(err)].status
+}
+
+// HTTPCode is the error code err is reported with: the code set on err,
+// else its kind's, else fallback.
+func HTTPCode(err error, fallback types.ErrorCode) types.ErrorCode {
+	if c := code(err); c != "" {
+		return c
+	}
+	if c := statuses[KindOf(err)].code; c != "" {
+		return c
+	}
+	return fallback
+}
+
+// ClientError reports whether err is the caller's to fix, so its message
+// stands alone rather than following the failed operation's.
+func ClientError(err error) bool {
+	return HTTPStatus(err) < http.StatusInternalServerError
+}
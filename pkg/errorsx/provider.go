@@ -0,0 +1,27 @@
+package errorsx
+
+// ProviderError attributes a failure to the provider it came from. It has
+// the kind of the error it wraps.
+type ProviderError struct {
+	Provider string
+	Err      error
+}
+
+func (e *ProviderError) Error() string {
+	return e.Provider + ": " + e.Err.Error()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ProviderError) ErrorKind() Kind {
+	return KindOf(e.Err)
+}
+
+func NewProviderError(provider string, err error) *ProviderError {
+	return &ProviderError{
+		Provider: provider,
+		Err:      err,
+	}
+}
@@ -0,0 +1,55 @@
+package errorsx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dharmasatrya/flightsearch/pkg/types"
+)
+
+// ValidationError is a single invalid-request message, for sentinels like
+// a missing required field.
+type ValidationError string
+
+func (e ValidationError) Error() string {
+	return string(e)
+}
+
+func (e ValidationError) ErrorKind() Kind {
+	return Invalid
+}
+
+type FieldError struct {
+	Field   string               `json:"field"`
+	Code    types.FieldErrorCode `json:"code"`
+	Message string               `json:"message"`
+}
+
+// ValidationErrors lists every problem with a request, so clients can fix
+// them all in one round trip.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e ValidationErrors) ErrorKind() Kind {
+	return Invalid
+}
+
+func (e *ValidationErrors) Add(field string, code types.FieldErrorCode, format string, args ...any) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns nil rather than an empty ValidationErrors, which would be a
+// non-nil error.
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
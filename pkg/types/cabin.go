@@ -0,0 +1,27 @@
+package types
+
+type CabinClass string
+
+const (
+	CabinEconomy        CabinClass = "economy"
+	CabinPremiumEconomy CabinClass = "premium_economy"
+	CabinBusiness       CabinClass = "business"
+	CabinFirst          CabinClass = "first"
+
+	// CabinAny is only valid in requests: it searches every cabin.
+	CabinAny CabinClass = "any"
+)
+
+// CabinClasses are the concrete cabins, lowest first.
+var CabinClasses = []CabinClass{CabinEconomy, CabinPremiumEconomy, CabinBusiness, CabinFirst}
+
+// Valid reports whether c is a cabin a search may request. The server
+// also accepts aliases such as "Y" or "premium", and answers with these
+// values.
+func (c CabinClass) Valid() bool {
+	switch c {
+	case CabinEconomy, CabinPremiumEconomy, CabinBusiness, CabinFirst, CabinAny:
+		return true
+	}
+	return false
+}
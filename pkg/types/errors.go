@@ -0,0 +1,58 @@
+package types
+
+// ErrorCode is the "error" of an error response.
+type ErrorCode string
+
+const (
+	CodeValidationError ErrorCode = "validation_error"
+	CodeInvalidRequest  ErrorCode = "invalid_request"
+	CodeUnauthorized    ErrorCode = "unauthorized"
+	CodeNotFound        ErrorCode = "not_found"
+	CodeExpired         ErrorCode = "expired"
+	CodeSearchExpired   ErrorCode = "search_expired"
+	CodeRateLimited     ErrorCode = "rate_limited"
+	CodeOverloaded      ErrorCode = "overloaded"
+	CodeConflict        ErrorCode = "conflict"
+	CodeTimeout         ErrorCode = "timeout"
+	CodeTooLarge        ErrorCode = "request_too_large"
+
+	// Searches that fail as a whole say why.
+	CodeAllProvidersFailed ErrorCode = "all_providers_failed"
+	CodePartialResults     ErrorCode = "partial_results"
+	CodeInvalidRoute       ErrorCode = "invalid_route"
+
+	// Failures of the server or a provider are reported by the operation
+	// that failed.
+	CodeSearchError       ErrorCode = "search_error"
+	CodeHistoryError      ErrorCode = "history_error"
+	CodeDiffError         ErrorCode = "diff_error"
+	CodeLockError         ErrorCode = "lock_error"
+	CodeOffsetError       ErrorCode = "offset_error"
+	CodeAlertError        ErrorCode = "alert_error"
+	CodeSubscriptionError ErrorCode = "subscription_error"
+	CodeRefdataError      ErrorCode = "refdata_error"
+	CodeConfigError       ErrorCode = "config_error"
+	CodeJobError          ErrorCode = "job_error"
+	CodeAnalyticsError    ErrorCode = "analytics_error"
+	CodeControlError      ErrorCode = "control_error"
+	CodeCacheError        ErrorCode = "cache_error"
+)
+
+// FieldErrorCode is the "code" of one entry in a validation_error's
+// "errors", saying what is wrong with the field.
+type FieldErrorCode string
+
+const (
+	FieldRequired              FieldErrorCode = "required"
+	FieldInvalidFormat         FieldErrorCode = "invalid_format"
+	FieldUnknownAirport        FieldErrorCode = "unknown_airport"
+	FieldSameAirport           FieldErrorCode = "same_airport"
+	FieldDateInPast            FieldErrorCode = "date_in_past"
+	FieldBeyondHorizon         FieldErrorCode = "beyond_booking_horizon"
+	FieldReturnBeforeDeparture FieldErrorCode = "return_before_departure"
+	FieldOutOfRange            FieldErrorCode = "out_of_range"
+	FieldUnknownValue          FieldErrorCode = "unknown_value"
+	FieldInvalidRange          FieldErrorCode = "invalid_range"
+	FieldMismatch              FieldErrorCode = "mismatch"
+	FieldNotPublic             FieldErrorCode = "not_public"
+)
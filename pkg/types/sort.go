@@ -0,0 +1,39 @@
+package types
+
+// SortKey is a field sort_by orders flights by. sort_by takes one or more
+// of them, comma-separated, each optionally suffixed with ":asc" or
+// ":desc".
+type SortKey string
+
+const (
+	SortPrice     SortKey = "price"
+	SortDuration  SortKey = "duration"
+	SortDeparture SortKey = "departure"
+	SortArrival   SortKey = "arrival"
+	SortBestValue SortKey = "best_value"
+	SortStops     SortKey = "stops"
+	SortEmissions SortKey = "emissions"
+)
+
+// SortKeys are every key sort_by accepts, besides "profile:<name>".
+var SortKeys = []SortKey{SortPrice, SortDuration, SortDeparture, SortArrival, SortBestValue, SortStops, SortEmissions}
+
+func (k SortKey) Valid() bool {
+	for _, key := range SortKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+func (o SortOrder) Valid() bool {
+	return o == SortAsc || o == SortDesc
+}
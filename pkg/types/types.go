@@ -0,0 +1,5 @@
+// Package types holds the enumerations of the public API: the values
+// clients send and receive as plain strings on the wire. Server
+// validation uses the same constants, so a client built against this
+// package agrees with the server on what is valid.
+package types